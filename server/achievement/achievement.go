@@ -0,0 +1,115 @@
+// Package achievement tracks the achievements accounts unlock by playing.
+//
+// Game modules register the achievements of their game types with Register, and report
+// what happens in their games with Record.
+package achievement
+
+import (
+	"cardgame/storage"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventGameEnd is the type of the event reported for every player of a finished game.
+const EventGameEnd = "game_end"
+
+// Event is something that happened to a player in a game.
+type Event struct {
+	Type      string
+	AccountId string
+	GameType  string
+	Stats     map[string]int // details of the event, defined by the game module reporting it
+}
+
+// Definition describes an achievement and how it is unlocked.
+type Definition struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	GameType    string `json:"gameType"` // only events of this game type count, or of all of them if empty
+	Goal        int    `json:"goal"`     // progress needed to unlock the achievement
+
+	// Progress returns how much an event brings the player closer to the goal.
+	Progress func(e Event) int `json:"-"`
+}
+
+var (
+	mu          sync.RWMutex
+	definitions = map[string]Definition{}
+	order       []string
+)
+
+// Register adds an achievement. It is meant to be called from the init function of a game
+// module, and panics if the id is taken or the definition is incomplete.
+func Register(d Definition) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := definitions[d.Id]; ok {
+		panic(fmt.Sprintf("achievement %q is already registered", d.Id))
+	}
+	if d.Id == "" || d.Goal < 1 || d.Progress == nil {
+		panic(fmt.Sprintf("achievement %q needs an id, a goal and a progress function", d.Id))
+	}
+	definitions[d.Id] = d
+	order = append(order, d.Id)
+}
+
+// Definitions returns every registered achievement, in the order they were registered.
+func Definitions() []Definition {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]Definition, 0, len(order))
+	for _, id := range order {
+		all = append(all, definitions[id])
+	}
+	return all
+}
+
+// Record adds the progress an event makes to the achievements of its account,
+// and returns the achievements it unlocked.
+func Record(s storage.Store, e Event) ([]Definition, error) {
+	saved, err := s.Achievements(e.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	progress := map[string]*storage.AchievementProgress{}
+	for _, p := range saved {
+		progress[p.AchievementId] = p
+	}
+
+	now := time.Now().UnixMilli()
+	changed := []*storage.AchievementProgress{}
+	unlocked := []Definition{}
+	for _, d := range Definitions() {
+		if d.GameType != "" && d.GameType != e.GameType {
+			continue
+		}
+		p, ok := progress[d.Id]
+		if !ok {
+			p = &storage.AchievementProgress{AccountId: e.AccountId, AchievementId: d.Id}
+		}
+		if p.Unlocked != 0 {
+			continue
+		}
+		n := d.Progress(e)
+		if n <= 0 {
+			continue
+		}
+
+		p.Progress += n
+		if p.Progress >= d.Goal {
+			p.Progress = d.Goal
+			p.Unlocked = now
+			unlocked = append(unlocked, d)
+		}
+		p.Updated = now
+		changed = append(changed, p)
+	}
+
+	if len(changed) == 0 {
+		return unlocked, nil
+	}
+	return unlocked, s.SaveAchievements(changed)
+}
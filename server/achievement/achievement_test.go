@@ -0,0 +1,59 @@
+package achievement
+
+import (
+	"cardgame/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	Register(Definition{
+		Id:       "test_points",
+		GameType: "test",
+		Goal:     5,
+		Progress: func(e Event) int { return e.Stats["points"] },
+	})
+	Register(Definition{
+		Id:       "test_other_game",
+		GameType: "other",
+		Goal:     1,
+		Progress: func(e Event) int { return 1 },
+	})
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		Register(Definition{Id: "test_points", Goal: 1, Progress: func(e Event) int { return 1 }})
+	})
+	assert.Panics(t, func() { Register(Definition{Id: "test_no_goal"}) })
+}
+
+func TestRecord(t *testing.T) {
+	s := storage.NewMemory()
+	event := func(points int) Event {
+		return Event{Type: EventGameEnd, AccountId: "u_1", GameType: "test", Stats: map[string]int{"points": points}}
+	}
+
+	unlocked, err := Record(s, event(3))
+	assert.NoError(t, err)
+	assert.Empty(t, unlocked)
+	progress, _ := s.Achievements("u_1")
+	if assert.Len(t, progress, 1, "achievements of other game types should not progress") {
+		assert.Equal(t, 3, progress[0].Progress)
+		assert.Zero(t, progress[0].Unlocked)
+	}
+
+	unlocked, err = Record(s, event(4))
+	assert.NoError(t, err)
+	if assert.Len(t, unlocked, 1) {
+		assert.Equal(t, "test_points", unlocked[0].Id)
+	}
+	progress, _ = s.Achievements("u_1")
+	assert.Equal(t, 5, progress[0].Progress, "progress should stop at the goal")
+	assert.NotZero(t, progress[0].Unlocked)
+
+	unlocked, err = Record(s, event(4))
+	assert.NoError(t, err)
+	assert.Empty(t, unlocked, "achievements should only unlock once")
+}
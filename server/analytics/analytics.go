@@ -0,0 +1,228 @@
+// Package analytics sends events about how the game is played, like games starting and the
+// actions taken in them, to a sink outside of the server: a file, a webhook, or Kafka in
+// servers built with the kafka tag. They are for analyzing the use of features and the
+// balance of the game without querying the production database.
+//
+// Events are sampled and scrubbed of personal data before they leave the server, as
+// configured. They are sent in batches from a goroutine of their own, and dropped rather than
+// holding up the game when the sink can't keep up.
+package analytics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cardgame/metrics"
+)
+
+// Props are the properties of an event.
+type Props map[string]any
+
+// Event is something that happened, as sent to sinks.
+type Event struct {
+	Name  string `json:"event"`
+	Time  int64  `json:"time"` // unix ms
+	Props Props  `json:"props"`
+}
+
+// identifiers are the properties identifying people, which are hashed or dropped. details are
+// the ones describing them, which are dropped unless kept.
+var (
+	identifiers = map[string]bool{"accountId": true, "playerId": true}
+	details     = map[string]bool{"name": true, "address": true}
+)
+
+// PII is what is done to the personal data of events.
+type PII string
+
+const (
+	PIIHash PII = "hash" // identifiers are hashed, so events can still be told apart by person
+	PIIDrop PII = "drop" // identifiers are dropped
+	PIIKeep PII = "keep" // everything is kept, for sinks allowed to hold personal data
+)
+
+// Config is which events are sent and what is left of them.
+type Config struct {
+	Sample map[string]float64 // chance of sending each event, by name, from 0 to 1; 1 if missing
+	PII    PII                // personal details are dropped unless PIIKeep
+	Salt   string             // hashed with identifiers, so they can't be looked up
+}
+
+// ParseSample parses a comma separated list of event names and the percentage of them to
+// send, like "action_taken=10%,game_started=100%".
+func ParseSample(s string) (map[string]float64, error) {
+	sample := map[string]float64{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(entry, "=")
+		n, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || !strings.HasSuffix(value, "%") || n < 0 || n > 100 {
+			return nil, fmt.Errorf("invalid sample rate %q of %s", value, name)
+		}
+		sample[strings.TrimSpace(name)] = n / 100
+	}
+	return sample, nil
+}
+
+// ParsePII parses what to do with personal data, "hash" if empty.
+func ParsePII(s string) (PII, error) {
+	switch PII(s) {
+	case "":
+		return PIIHash, nil
+	case PIIHash, PIIDrop, PIIKeep:
+		return PII(s), nil
+	}
+	return "", fmt.Errorf("invalid PII handling %q", s)
+}
+
+// scrub returns the properties of an event with its personal data hashed or dropped.
+func (c *Config) scrub(props Props) Props {
+	scrubbed := make(Props, len(props))
+	for k, v := range props {
+		switch {
+		case c.PII == PIIKeep:
+		case details[k]:
+			continue
+		case identifiers[k]:
+			s, ok := v.(string)
+			if c.PII == PIIDrop || !ok {
+				continue
+			}
+			if s != "" {
+				v = c.hash(s)
+			}
+		}
+		scrubbed[k] = v
+	}
+	return scrubbed
+}
+
+func (c *Config) hash(s string) string {
+	h := hmac.New(sha256.New, []byte(c.Salt))
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+var (
+	eventsSent    = metrics.NewCounter("cardgame_analytics_events_total", "Analytics events sent, by event.", "event")
+	eventsDropped = metrics.NewCounter("cardgame_analytics_events_dropped_total", "Analytics events lost because the sink was slow or failing.", "")
+)
+
+const (
+	// batchSize is the most events sent to the sink at once.
+	batchSize = 100
+	// bufferSize is the most events waiting to be sent, more are dropped.
+	bufferSize = 10 * batchSize
+)
+
+// flushInterval is how long events wait for a batch to fill up before they are sent anyway.
+var flushInterval = 5 * time.Second
+
+// pipeline sends the events emitted to a sink.
+type pipeline struct {
+	sink   Sink
+	config Config
+	events chan Event
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+// current is the running pipeline, or nil when events are not sent anywhere.
+var current atomic.Pointer[pipeline]
+
+// Start sends the events emitted from now on to a sink, until the returned function is
+// called. Stopping sends the events still waiting and closes the sink.
+func Start(sink Sink, c Config) (stop func()) {
+	p := &pipeline{
+		sink:   sink,
+		config: c,
+		events: make(chan Event, bufferSize),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	current.Store(p)
+	go p.run()
+	return func() {
+		current.CompareAndSwap(p, nil)
+		close(p.quit)
+		<-p.done
+	}
+}
+
+// Emit sends an event, if it is sampled. It never blocks: the event is dropped if the sink
+// is behind.
+func Emit(name string, props Props) {
+	p := current.Load()
+	if p == nil {
+		return
+	}
+	if rate, ok := p.config.Sample[name]; ok && rand.Float64() >= rate {
+		return
+	}
+	e := Event{Name: name, Time: time.Now().UnixMilli(), Props: p.config.scrub(props)}
+	select {
+	case p.events <- e:
+	default:
+		eventsDropped.Inc("")
+	}
+}
+
+func (p *pipeline) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.sink.Write(batch); err != nil {
+			slog.Error("failed to send analytics events", "events", len(batch), "err", err)
+			for range batch {
+				eventsDropped.Inc("")
+			}
+		} else {
+			for _, e := range batch {
+				eventsSent.Inc(e.Name)
+			}
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case e := <-p.events:
+			batch = append(batch, e)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.quit:
+			// send what was emitted before stopping
+			for len(p.events) > 0 {
+				batch = append(batch, <-p.events)
+				if len(batch) == batchSize {
+					flush()
+				}
+			}
+			flush()
+			if err := p.sink.Close(); err != nil {
+				slog.Error("failed to close analytics sink", "err", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package analytics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSink keeps the events written to it.
+type testSink struct {
+	events []Event
+	closed bool
+}
+
+func (s *testSink) Write(events []Event) error {
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestParseSample(t *testing.T) {
+	sample, err := ParseSample("action_taken=10%, game_started=100%")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"action_taken": 0.1, "game_started": 1}, sample)
+
+	sample, err = ParseSample("")
+	assert.NoError(t, err)
+	assert.Empty(t, sample)
+
+	for _, s := range []string{"action_taken", "action_taken=10", "action_taken=150%"} {
+		_, err := ParseSample(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	props := Props{"accountId": "u_1", "playerId": "", "name": "Ann", "room": "r_1"}
+
+	pii, err := ParsePII("")
+	assert.NoError(t, err)
+	hashed := (&Config{PII: pii, Salt: "salt"}).scrub(props)
+	assert.Len(t, hashed["accountId"], 16)
+	assert.NotEqual(t, "u_1", hashed["accountId"])
+	assert.Equal(t, hashed["accountId"], (&Config{PII: pii, Salt: "salt"}).scrub(props)["accountId"], "hashes should be stable")
+	assert.NotEqual(t, hashed["accountId"], (&Config{PII: pii, Salt: "other"}).scrub(props)["accountId"], "hashes should depend on the salt")
+	assert.Equal(t, "", hashed["playerId"], "guests have no account to hash")
+	assert.NotContains(t, hashed, "name")
+	assert.Equal(t, "r_1", hashed["room"])
+
+	assert.Equal(t, Props{"room": "r_1"}, (&Config{PII: PIIDrop}).scrub(props))
+	assert.Equal(t, props, (&Config{PII: PIIKeep}).scrub(props))
+
+	_, err = ParsePII("mask")
+	assert.Error(t, err)
+}
+
+func TestPipeline(t *testing.T) {
+	Emit("game_started", Props{"room": "r_1"}) // not sent anywhere yet
+
+	sink := &testSink{}
+	stop := Start(sink, Config{Sample: map[string]float64{"action_taken": 0}, PII: PIIDrop})
+	Emit("game_started", Props{"room": "r_1", "accountId": "u_1"})
+	Emit("action_taken", Props{"room": "r_1"})
+	Emit("game_finished", Props{"room": "r_1"})
+	stop()
+	Emit("game_started", Props{"room": "r_2"})
+
+	assert.True(t, sink.closed)
+	if assert.Len(t, sink.events, 2) {
+		assert.Equal(t, "game_started", sink.events[0].Name)
+		assert.Equal(t, Props{"room": "r_1"}, sink.events[0].Props)
+		assert.NotZero(t, sink.events[0].Time)
+		assert.Equal(t, "game_finished", sink.events[1].Name)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := Open("file://" + path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, sink.Write([]Event{{Name: "game_started", Time: 1, Props: Props{"room": "r_1"}}}))
+	assert.NoError(t, sink.Write([]Event{{Name: "game_finished", Time: 2, Props: Props{}}}))
+	assert.NoError(t, sink.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"event":"game_started","time":1,"props":{"room":"r_1"}}`+"\n"+
+		`{"event":"game_finished","time":2,"props":{}}`+"\n", string(data))
+}
+
+func TestWebhookSink(t *testing.T) {
+	var body []byte
+	status := 204
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := Open(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, sink.Write([]Event{{Name: "game_started", Time: 1, Props: Props{}}}))
+	var posted struct{ Events []Event }
+	assert.NoError(t, json.Unmarshal(body, &posted))
+	assert.Equal(t, "game_started", posted.Events[0].Name)
+
+	status = 500
+	assert.Error(t, sink.Write([]Event{{Name: "game_started"}}))
+}
+
+func TestOpen(t *testing.T) {
+	_, err := Open("carrier-pigeon://coop")
+	assert.ErrorContains(t, err, "unknown analytics sink")
+}
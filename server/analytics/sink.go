@@ -0,0 +1,107 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink is where events are sent. Write is called with one batch at a time.
+type Sink interface {
+	Write(events []Event) error
+	Close() error
+}
+
+// sinks open sinks by URL scheme. Sinks built with a tag register themselves here.
+var sinks = map[string]func(url string) (Sink, error){
+	"file":  openFile,
+	"http":  openWebhook,
+	"https": openWebhook,
+}
+
+// Open opens the sink at url: "file:///path" appends events to a file, one JSON object per
+// line, "http://..." and "https://..." post them to a webhook, and "kafka://..." produces them
+// to Kafka in servers built with the kafka tag.
+func Open(url string) (Sink, error) {
+	scheme, _, _ := strings.Cut(url, "://")
+	open, ok := sinks[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown analytics sink %q", scheme)
+	}
+	return open(url)
+}
+
+// fileSink appends events to a file.
+type fileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openFile(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" {
+		return nil, fmt.Errorf("analytics file %q has no path", rawURL)
+	}
+	f, err := os.OpenFile(u.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: f}, nil
+}
+
+func (s *fileSink) Write(events []Event) error {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+// webhookSink posts batches of events to a URL, as {"events": [...]}.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func openWebhook(url string) (Sink, error) {
+	return &webhookSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (s *webhookSink) Write(events []Event) error {
+	body, err := json.Marshal(map[string][]Event{"events": events})
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build kafka
+
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func init() {
+	sinks["kafka"] = openKafka
+}
+
+// kafkaSink produces events to a Kafka topic, keyed by their name.
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+// openKafka opens a sink like "kafka://broker1:9092,broker2:9092/topic".
+func openKafka(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	topic := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || topic == "" {
+		return nil, fmt.Errorf("analytics sink %q needs brokers and a topic", rawURL)
+	}
+	return &kafkaSink{writer: &kafka.Writer{
+		Addr:     kafka.TCP(strings.Split(u.Host, ",")...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+	}}, nil
+}
+
+func (s *kafkaSink) Write(events []Event) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{Key: []byte(e.Name), Value: value})
+	}
+	return s.writer.WriteMessages(context.Background(), messages...)
+}
+
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
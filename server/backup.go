@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"cardgame/config"
+	"cardgame/storage"
+)
+
+const backupUsage = `usage: cardgame-server backup [FILE]
+       cardgame-server restore [FILE]
+
+backup writes an archive of the database to FILE, or to the standard output without one.
+It holds every account, match and setting, along with the state of suspended games: the
+only room state that is stored, so games still being played aren't in it.
+restore fills an empty database with the contents of an archive read from FILE, or from
+the standard input. Archives of older releases are migrated on the way in. To replace a
+database, restore into a new one and point the server at it once that has worked.
+
+Stop the server while restoring: players still connected would keep writing to the old data,
+and cached reads would not see the new one.
+
+The database is picked with STORAGE_DRIVER and STORAGE_DSN, like when starting the server.`
+
+// backup runs the "backup" and "restore" commands, which move the data of a SQL store in and
+// out of an archive, for instance to move a server to another machine.
+// It returns the exit code of the command.
+func backup(cfg *config.Config, command string, args []string) int {
+	driver, dsn := cfg.Get("STORAGE_DRIVER"), cfg.Get("STORAGE_DSN")
+	dialect, err := storage.DriverDialect(driver)
+	if len(args) > 1 || err != nil {
+		fmt.Fprintln(os.Stderr, backupUsage)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "\nerror:", err)
+		}
+		return 2
+	}
+
+	s, err := storage.OpenSQLUnchecked(driver, dsn, dialect)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open storage:", err)
+		return 1
+	}
+	defer s.Close()
+
+	if command == "backup" {
+		out := io.WriteCloser(os.Stdout)
+		if len(args) == 1 {
+			if out, err = os.Create(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, "failed to create backup:", err)
+				return 1
+			}
+		}
+		if err := s.Backup(out); err != nil {
+			out.Close()
+			fmt.Fprintln(os.Stderr, "backup failed:", err)
+			return 1
+		}
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "backup failed:", err)
+			return 1
+		}
+		return 0
+	}
+
+	empty, err := s.Empty()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to read storage:", err)
+		return 1
+	}
+	if !empty {
+		fmt.Fprintln(os.Stderr, "restore failed: the database isn't empty, restore into a new one")
+		return 1
+	}
+
+	in := io.ReadCloser(os.Stdin)
+	if len(args) == 1 {
+		if in, err = os.Open(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to open backup:", err)
+			return 1
+		}
+	}
+	defer in.Close()
+	if err := s.Restore(in); err != nil {
+		fmt.Fprintln(os.Stderr, "restore failed:", err)
+		return 1
+	}
+	fmt.Println("restored backup")
+	return 0
+}
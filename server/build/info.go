@@ -1,7 +1,7 @@
 package build
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 	"time"
@@ -24,7 +24,7 @@ func init() {
 		cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
 		stdout, err := cmd.Output()
 		if err != nil {
-			log.Println("failed to get git branch:", err)
+			slog.Warn("failed to get git branch", "err", err)
 		} else {
 			branch = strings.Replace(string(stdout), "\n", "", -1)
 		}
@@ -37,7 +37,7 @@ func init() {
 		var err error
 		goTime, err = time.Parse(time.RFC3339, buildTime)
 		if err != nil {
-			log.Printf("[error] failed to parse build time: %v\n", err)
+			slog.Error("failed to parse build time", "err", err)
 			goTime = time.Now()
 		}
 	}
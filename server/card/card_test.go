@@ -0,0 +1,37 @@
+package card
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompatibleWith(t *testing.T) {
+	lines, waves, square := &Card{Type: Lines}, &Card{Type: Waves}, &Card{Type: Square}
+	wild := &WildCard{Types: []CardType{Waves, Lines}}
+
+	assert.True(t, lines.CompatibleWith(&Card{Type: Lines}, nil))
+	assert.False(t, lines.CompatibleWith(waves, nil))
+	assert.True(t, lines.CompatibleWith(waves, wild), "wild cards match their types both ways")
+	assert.True(t, waves.CompatibleWith(lines, wild), "wild cards match their types both ways")
+	assert.False(t, lines.CompatibleWith(square, wild))
+}
+
+// BenchmarkCompatibleWith compares every pair of top cards at a table of 8, as players do
+// looking for a card to send.
+func BenchmarkCompatibleWith(b *testing.B) {
+	tops := []*Card{}
+	for _, t := range AllCardTypes() {
+		tops = append(tops, &Card{Type: t})
+	}
+	wild := &WildCard{Types: []CardType{Lines, Waves}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, a := range tops {
+			for _, c := range tops {
+				a.CompatibleWith(c, wild)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package challenge keeps track of the daily challenges.
+//
+// Every day has a challenge per game type: a deal everyone attempts once under the same
+// conditions, ranked on a leaderboard of its own. Completing the challenges of a game type
+// on consecutive days builds a streak.
+package challenge
+
+import (
+	"cardgame/storage"
+	"errors"
+	"hash/fnv"
+	"time"
+)
+
+// dayLayout is the format of challenge days.
+const dayLayout = "2006-01-02"
+
+// Day returns the challenge day t is in. Days are UTC dates.
+func Day(t time.Time) string {
+	return t.UTC().Format(dayLayout)
+}
+
+// ParseDay parses a challenge day.
+func ParseDay(day string) (time.Time, error) {
+	return time.Parse(dayLayout, day)
+}
+
+// Seed returns the seed the deal of the challenge of a game type on a day is shuffled with.
+func Seed(day, gameType string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(day + "/" + gameType))
+	return int64(h.Sum64())
+}
+
+// Current returns the length of a streak on a day. A streak is broken when the challenge of
+// the day before is missed, so it still counts on the day after its last challenge.
+func Current(streak *storage.ChallengeStreak, day string) int {
+	if streak == nil {
+		return 0
+	}
+	if streak.LastDay == day || streak.LastDay == previous(day) {
+		return streak.Streak
+	}
+	return 0
+}
+
+// previous returns the day before a day.
+func previous(day string) string {
+	t, err := ParseDay(day)
+	if err != nil {
+		return ""
+	}
+	return Day(t.AddDate(0, 0, -1))
+}
+
+// Complete saves the score of a finished attempt, and extends the account's streak.
+func Complete(s storage.Store, result *storage.ChallengeResult) error {
+	streak, err := s.ChallengeStreak(result.AccountId, result.GameType)
+	if errors.Is(err, storage.ErrNotFound) {
+		streak = &storage.ChallengeStreak{AccountId: result.AccountId, GameType: result.GameType}
+	} else if err != nil {
+		return err
+	}
+
+	if streak.LastDay != result.Day {
+		streak.Streak = Current(streak, result.Day) + 1
+		streak.LastDay = result.Day
+		if streak.Streak > streak.LongestStreak {
+			streak.LongestStreak = streak.Streak
+		}
+	}
+	return s.SaveChallengeResult(result, streak)
+}
@@ -0,0 +1,40 @@
+package challenge
+
+import (
+	"cardgame/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDay(t *testing.T) {
+	assert.Equal(t, "2024-06-01", Day(time.Date(2024, 6, 1, 23, 59, 0, 0, time.UTC)))
+	assert.Equal(t, "2024-06-02", Day(time.Date(2024, 6, 1, 23, 0, 0, 0, time.FixedZone("", -2*60*60))), "days should be UTC dates")
+	assert.Equal(t, "2024-02-29", previous("2024-03-01"))
+
+	assert.Equal(t, Seed("2024-06-01", "classic"), Seed("2024-06-01", "classic"))
+	assert.NotEqual(t, Seed("2024-06-01", "classic"), Seed("2024-06-02", "classic"))
+}
+
+func TestComplete(t *testing.T) {
+	s := storage.NewMemory()
+	complete := func(day string) *storage.ChallengeStreak {
+		t.Helper()
+		assert.NoError(t, Complete(s, &storage.ChallengeResult{Day: day, GameType: "classic", AccountId: "u_1", Score: 1, Completed: 1}))
+		streak, err := s.ChallengeStreak("u_1", "classic")
+		assert.NoError(t, err)
+		return streak
+	}
+
+	assert.Equal(t, 1, complete("2024-06-01").Streak)
+	assert.Equal(t, 2, complete("2024-06-02").Streak)
+	assert.Equal(t, 2, complete("2024-06-02").Streak, "a day should only count once")
+	streak := complete("2024-06-05")
+	assert.Equal(t, 1, streak.Streak, "missing a day should break the streak")
+	assert.Equal(t, 2, streak.LongestStreak)
+
+	assert.Equal(t, 1, Current(streak, "2024-06-06"))
+	assert.Equal(t, 0, Current(streak, "2024-06-07"))
+	assert.Equal(t, 0, Current(nil, "2024-06-07"))
+}
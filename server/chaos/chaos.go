@@ -0,0 +1,136 @@
+// Package chaos makes a connection behave like a bad network, to test that reconnects,
+// retried messages and timers hold up before players find out they don't.
+//
+// It is only meant for test deployments: messages are delayed, dropped and swapped as they
+// pass through a channel, with rates configured like "latency=200ms,jitter=100ms,drop=1%".
+package chaos
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is how badly messages are treated.
+type Config struct {
+	Latency time.Duration // added to every message
+	Jitter  time.Duration // up to this much more is added to every message, at random
+	Drop    float64       // chance of a message being lost, from 0 to 1
+	Reorder float64       // chance of a message being held back until the one after it is delivered
+	Hold    time.Duration // longest a message is held back when nothing comes after it
+}
+
+// Parse parses a comma separated list of "latency", "jitter" and "hold" durations and "drop"
+// and "reorder" percentages, like "latency=200ms,drop=1%,reorder=5%". It returns nil for an
+// empty list, which leaves messages alone.
+func Parse(s string) (*Config, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	c := &Config{Hold: time.Second}
+	for _, entry := range strings.Split(s, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		var err error
+		switch name {
+		case "latency":
+			c.Latency, err = time.ParseDuration(value)
+		case "jitter":
+			c.Jitter, err = time.ParseDuration(value)
+		case "hold":
+			c.Hold, err = time.ParseDuration(value)
+		case "drop":
+			c.Drop, err = parsePercent(value)
+		case "reorder":
+			c.Reorder, err = parsePercent(value)
+		default:
+			return nil, fmt.Errorf("unknown chaos setting %q", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid chaos %s %q", name, value)
+		}
+	}
+	if c.Latency < 0 || c.Jitter < 0 || c.Hold < 0 {
+		return nil, fmt.Errorf("chaos durations can't be negative")
+	}
+	return c, nil
+}
+
+// parsePercent parses a percentage like "5%" to a chance like 0.05.
+func parsePercent(s string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || !strings.HasSuffix(s, "%") || n < 0 || n > 100 {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return n / 100, nil
+}
+
+// delay returns how long to delay a message.
+func (c *Config) delay() time.Duration {
+	d := c.Latency
+	if c.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(c.Jitter)))
+	}
+	return d
+}
+
+// Chan passes the messages received from in to the returned channel, delayed, dropped and
+// reordered as configured. Messages are delayed one after the other, like on a slow link.
+// The returned channel is closed once in is, after the message held back if any. Messages
+// are discarded once done is closed, when nothing reads the returned channel anymore.
+func Chan[T any](c *Config, in <-chan T, done <-chan struct{}) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		deliver := func(m T) bool {
+			select {
+			case out <- m:
+				return true
+			case <-done:
+				return false
+			}
+		}
+
+		var held *T
+		var hold <-chan time.Time
+		for {
+			select {
+			case m, ok := <-in:
+				if !ok {
+					if held != nil {
+						deliver(*held)
+					}
+					return
+				}
+				if rand.Float64() < c.Drop {
+					continue
+				}
+				if d := c.delay(); d > 0 {
+					time.Sleep(d)
+				}
+				if held == nil && rand.Float64() < c.Reorder {
+					held, hold = &m, time.After(c.Hold)
+					continue
+				}
+				if !deliver(m) {
+					return
+				}
+				if held != nil {
+					if !deliver(*held) {
+						return
+					}
+					held, hold = nil, nil
+				}
+			case <-hold:
+				if !deliver(*held) {
+					return
+				}
+				held, hold = nil, nil
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,83 @@
+package chaos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse("latency=200ms, jitter=50ms, drop=1.5%, reorder=10%")
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Latency: 200 * time.Millisecond,
+		Jitter:  50 * time.Millisecond,
+		Drop:    0.015,
+		Reorder: 0.1,
+		Hold:    time.Second,
+	}, c)
+
+	c, err = Parse("")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	for _, s := range []string{"latency=fast", "drop=0.5", "drop=101%", "jitter=-1s", "loss=1%"} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+// pass sends messages through Chan and returns the ones that came out.
+func pass(c *Config, messages ...int) []int {
+	in := make(chan int)
+	out := Chan(c, in, make(chan struct{}))
+	go func() {
+		for _, m := range messages {
+			in <- m
+		}
+		close(in)
+	}()
+	received := []int{}
+	for m := range out {
+		received = append(received, m)
+	}
+	return received
+}
+
+func TestChan(t *testing.T) {
+	assert.Equal(t, []int{1, 2, 3}, pass(&Config{}, 1, 2, 3))
+	assert.Empty(t, pass(&Config{Drop: 1}, 1, 2, 3))
+	assert.Equal(t, []int{2, 1, 4, 3, 5}, pass(&Config{Reorder: 1, Hold: time.Hour}, 1, 2, 3, 4, 5),
+		"held messages come after the next one, or when the input closes")
+
+	start := time.Now()
+	pass(&Config{Latency: 10 * time.Millisecond}, 1, 2)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestHold(t *testing.T) {
+	in := make(chan int)
+	out := Chan(&Config{Reorder: 1, Hold: 10 * time.Millisecond}, in, make(chan struct{}))
+	in <- 1
+	select {
+	case m := <-out:
+		assert.Equal(t, 1, m)
+	case <-time.After(time.Second):
+		t.Fatal("held message was never delivered")
+	}
+	close(in)
+}
+
+func TestDone(t *testing.T) {
+	in := make(chan int)
+	done := make(chan struct{})
+	out := Chan(&Config{}, in, done)
+	in <- 1
+	close(done)
+	_, ok := <-out
+	for ok {
+		_, ok = <-out
+	}
+}
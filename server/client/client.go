@@ -0,0 +1,362 @@
+// Package client connects Go programs, like bots and tools, to a game server. It speaks the
+// same protocol as the web client: it joins a room over a websocket, decodes what the server
+// sends into the message types of package game, and submits actions like game.ClientDraw.
+//
+// A client can reclaim its seat when its connection drops, like the web client does after a
+// page reload, by joining again with the token the server acknowledged the seat with.
+package client
+
+import (
+	"cardgame/game"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Options are how a client joins a room.
+type Options struct {
+	Name     string // shown to the other players, picked by the server if empty
+	Password string // of private rooms
+	Token    string // of the account to sign in as, or empty to play as a guest
+	Spectate bool   // watch the room instead of taking a seat
+
+	// Reconnects is how many times the client tries to reclaim its seat after losing its
+	// connection, waiting a little longer every time, before giving up. 0 never reconnects.
+	// Once the seat is reclaimed, the new *game.ServerAck is passed on as an event.
+	Reconnects int
+
+	Dialer *websocket.Dialer // websocket.DefaultDialer if nil
+}
+
+// Event is a message sent by the server.
+type Event struct {
+	Type    string
+	Message game.ServerMessage // like *game.ServerDraw, or nil for types this package doesn't know
+	Room    json.RawMessage    // state of the room the message was sent from
+	Raw     json.RawMessage    // the message as sent
+}
+
+// Client is a player connected to a room. Its methods are safe for concurrent use.
+type Client struct {
+	url    string // of the room's websocket
+	roomId string
+	opts   Options
+	events chan Event
+	done   chan struct{} // closed by Close
+	close  sync.Once
+
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	playerId string
+	token    string // to reclaim the seat, from the last ack
+	err      error  // why the events channel was closed
+}
+
+// ErrClosed is returned when submitting actions after the client was closed or gave up
+// reconnecting.
+var ErrClosed = errors.New("client: connection closed")
+
+// CreateRoom creates a room on the server at baseURL, like http://localhost:8080, and returns
+// its id. An empty password creates a public room.
+func CreateRoom(ctx context.Context, baseURL, password string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(baseURL, "/")+"/api/room", nil)
+	if err != nil {
+		return "", err
+	}
+	if password != "" {
+		req.Header.Set("X-Password", password)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var body struct {
+		Room struct {
+			Id string `json:"id"`
+		} `json:"room"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("client: failed to create room: %s", body.Error)
+	}
+	return body.Room.Id, nil
+}
+
+// Join connects to the server at baseURL, like http://localhost:8080, signs in if a token is
+// set, and joins a room. It returns once the room has accepted the player.
+func Join(ctx context.Context, baseURL, roomId string, opts Options) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path += "/api/ws/" + url.PathEscape(roomId)
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+
+	c := &Client{
+		url:    u.String(),
+		roomId: roomId,
+		opts:   opts,
+		events: make(chan Event, 64),
+		done:   make(chan struct{}),
+	}
+	if _, err := c.connect(ctx, ""); err != nil {
+		return nil, err
+	}
+	go c.read()
+	return c, nil
+}
+
+// connect opens a connection and joins the room, reclaiming the seat of token if set. It
+// returns the ack of the room.
+func (c *Client) connect(ctx context.Context, token string) (Event, error) {
+	// the client speaks the version of the game package it was built with
+	header := http.Header{"Sec-WebSocket-Protocol": game.Subprotocols()[:1]}
+	conn, _, err := c.opts.Dialer.DialContext(ctx, c.url, header)
+	if err != nil {
+		return Event{}, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+
+	if c.opts.Token != "" {
+		if err := write(conn, game.ClientSignIn{Token: c.opts.Token}); err != nil {
+			conn.Close()
+			return Event{}, err
+		}
+		if _, err := expect(conn, "sign_in"); err != nil {
+			conn.Close()
+			return Event{}, fmt.Errorf("client: failed to sign in: %w", err)
+		}
+	}
+
+	join := game.ClientJoin{
+		RoomId:   c.roomId,
+		Password: c.opts.Password,
+		Spectate: c.opts.Spectate,
+		Token:    token,
+		Account:  c.opts.Token,
+	}
+	if c.opts.Name != "" {
+		join.Name = &c.opts.Name
+	}
+	if err := write(conn, join); err != nil {
+		conn.Close()
+		return Event{}, err
+	}
+	ack, err := expect(conn, "ack")
+	if err != nil {
+		conn.Close()
+		return Event{}, fmt.Errorf("client: failed to join: %w", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn = conn
+	c.token = ack.Message.(*game.ServerAck).Token
+	if c.playerId == "" {
+		c.playerId = joinedId(ack.Room, c.opts.Spectate)
+	}
+	return ack, nil
+}
+
+// joinedId returns the id of the player who just joined a room, the last one seated or
+// watching in the state sent with the ack.
+func joinedId(room json.RawMessage, spectate bool) string {
+	var state struct {
+		Players    []struct{ Id string } `json:"players"`
+		Spectators []struct{ Id string } `json:"spectators"`
+	}
+	json.Unmarshal(room, &state)
+	list := state.Players
+	if spectate {
+		list = state.Spectators
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	return list[len(list)-1].Id
+}
+
+// expect reads messages until one of the given type, or fails on an error from the server.
+func expect(conn *websocket.Conn, typ string) (Event, error) {
+	for {
+		e, err := readEvent(conn)
+		if err != nil {
+			return Event{}, err
+		}
+		if e.Type == typ {
+			return e, nil
+		}
+		if m, ok := e.Message.(*game.ServerError); ok {
+			return Event{}, errors.New(m.Message)
+		}
+	}
+}
+
+// readEvent reads and decodes a message from the server.
+func readEvent(conn *websocket.Conn) (Event, error) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return Event{}, err
+	}
+	var fields struct {
+		Type string          `json:"type"`
+		Room json.RawMessage `json:"room"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Event{}, err
+	}
+	e := Event{Type: fields.Type, Room: fields.Room, Raw: data}
+	if t, ok := game.ServerMessageTypes[fields.Type]; ok {
+		m := reflect.New(reflect.TypeOf(t))
+		if err := json.Unmarshal(data, m.Interface()); err != nil {
+			return Event{}, fmt.Errorf("client: failed to decode %s: %w", fields.Type, err)
+		}
+		e.Message = m.Interface().(game.ServerMessage)
+	}
+	return e, nil
+}
+
+// write sends a message with its type.
+func write(conn *websocket.Conn, message game.ClientMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	m["type"] = message.ClientType()
+	return conn.WriteJSON(m)
+}
+
+// read passes the messages of the server on to the events channel, reconnecting when the
+// connection drops, until the client is closed or gives up.
+func (c *Client) read() {
+	defer close(c.events)
+	for {
+		c.mu.Lock()
+		conn := c.conn
+		c.mu.Unlock()
+
+		e, err := readEvent(conn)
+		if err == nil {
+			select {
+			case c.events <- e:
+			case <-c.done:
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		ack, ok := c.reconnect()
+		if !ok {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			return
+		}
+		// the new ack tells the player they are back, and with what state
+		select {
+		case c.events <- ack:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// reconnect tries to reclaim the seat over a new connection, and returns the ack of the room
+// if it did.
+func (c *Client) reconnect() (Event, bool) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token == "" {
+		return Event{}, false
+	}
+
+	for attempt := 1; attempt <= c.opts.Reconnects; attempt++ {
+		select {
+		case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+		case <-c.done:
+			return Event{}, false
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ack, err := c.connect(ctx, token)
+		cancel()
+		if err == nil {
+			return ack, true
+		}
+	}
+	return Event{}, false
+}
+
+// Events returns the messages sent by the server. It is closed when the client is closed or
+// gives up reconnecting, see Err.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Err returns why the events channel was closed, or nil if the client was closed.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// PlayerId returns the id of the client's player in the room.
+func (c *Client) PlayerId() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.playerId
+}
+
+// Submit sends an action, like game.ClientDraw{} or game.ClientSend{RecipientId: id}. The
+// Player field of actions is ignored: actions are always the client's player's.
+func (c *Client) Submit(action game.ClientMessage) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return write(c.conn, action)
+}
+
+// Close leaves the room and closes the connection.
+func (c *Client) Close() error {
+	var err error
+	c.close.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		write(c.conn, game.ClientLeave{})
+		err = c.conn.Close()
+	})
+	return err
+}
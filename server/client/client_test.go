@@ -0,0 +1,101 @@
+package client
+
+import (
+	"cardgame/game"
+	"cardgame/servertest"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// next returns the next event of type T sent to a client.
+func next[T game.ServerMessage](t *testing.T, c *Client) T {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-c.Events():
+			require.True(t, ok, "events closed: %v", c.Err())
+			if m, ok := e.Message.(T); ok {
+				return m
+			}
+		case <-timeout:
+			var zero T
+			t.Fatalf("timed out waiting for %T", zero)
+		}
+	}
+}
+
+func join(t *testing.T, s *servertest.Server, room, name string, reconnects int) *Client {
+	t.Helper()
+	c, err := Join(context.Background(), s.URL, room, Options{Name: name, Reconnects: reconnects})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestPlay(t *testing.T) {
+	s := servertest.New(t)
+	room, err := CreateRoom(context.Background(), s.URL, "")
+	require.NoError(t, err)
+	owner := join(t, s, room, "owner", 0)
+	guest := join(t, s, room, "guest", 0)
+	assert.NotEmpty(t, owner.PlayerId())
+	assert.NotEqual(t, owner.PlayerId(), guest.PlayerId())
+
+	joined := next[*game.ServerJoin](t, owner)
+	assert.Equal(t, guest.PlayerId(), joined.Id)
+	assert.Equal(t, "guest", joined.Player.Name)
+
+	require.NoError(t, owner.Submit(game.ClientChangeDetails{AddDecks: []string{servertest.DeckId}}))
+	require.NoError(t, owner.Submit(game.ClientStart{}))
+	start := next[*game.ServerStart](t, guest)
+	players := []*Client{owner, guest}
+	current := players[start.CurrentTurn]
+
+	require.NoError(t, current.Submit(game.ClientDraw{}))
+	draw := next[*game.ServerDraw](t, guest)
+	assert.Equal(t, current.PlayerId(), draw.PlayerId)
+	assert.NotNil(t, draw.Card)
+
+	require.NoError(t, owner.Close())
+	assert.ErrorIs(t, owner.Submit(game.ClientDraw{}), ErrClosed)
+	_, open := <-owner.Events()
+	for open {
+		_, open = <-owner.Events()
+	}
+	assert.NoError(t, owner.Err())
+}
+
+func TestJoinErrors(t *testing.T) {
+	s := servertest.New(t)
+	_, err := Join(context.Background(), s.URL, "r_missing", Options{})
+	assert.Error(t, err)
+}
+
+func TestReconnect(t *testing.T) {
+	s := servertest.New(t)
+	room := s.CreateRoom(t)
+	owner := join(t, s, room, "owner", 3)
+	guest := join(t, s, room, "guest", 0)
+	require.NoError(t, owner.Submit(game.ClientChangeDetails{AddDecks: []string{servertest.DeckId}}))
+	require.NoError(t, owner.Submit(game.ClientStart{}))
+	next[*game.ServerStart](t, owner)
+	id := owner.PlayerId()
+
+	// drop the connection without leaving, like a network failure
+	owner.mu.Lock()
+	owner.conn.Close()
+	owner.mu.Unlock()
+
+	reconnected := next[*game.ServerReconnect](t, guest)
+	assert.Equal(t, id, reconnected.Id)
+	next[*game.ServerAck](t, owner)
+	assert.Equal(t, id, owner.PlayerId())
+	require.NoError(t, owner.Submit(game.ClientChat{Message: "back"}))
+	chat := next[*game.ServerChat](t, guest)
+	assert.Equal(t, "back", chat.Message)
+}
@@ -0,0 +1,136 @@
+// Package clock lets code that waits on time be tested without waiting: the game reads the
+// time and starts its timers through a Clock, which tests swap for a Fake they move forward.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and runs functions after a while.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after d, unless the timer is stopped first.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a function waiting to be called.
+type Timer interface {
+	// Stop keeps the function from being called, and reports whether it did.
+	Stop() bool
+}
+
+// Real is the clock of the system.
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now() }
+
+func (Real) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// Fake is a clock that only moves when told to. It is safe for concurrent use.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	seq    int // of the last timer, to fire timers due at the same time in the order they were set
+}
+
+// NewFake returns a fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+type fakeTimer struct {
+	clock *Fake
+	at    time.Time
+	seq   int
+	f     func()
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	t := &fakeTimer{clock: f, at: f.now.Add(d), seq: f.seq, f: fn}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the clock forward by d, calling the functions of the timers due on the way in
+// the order they are due. Unlike real timers, they are called one at a time from Advance,
+// with the clock set to when they are due, so tests know they have run once Advance returns.
+// Timers set by those functions are called too if they are due before the end of d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	f.mu.Unlock()
+	for {
+		t := f.next(end)
+		if t == nil {
+			break
+		}
+		t.f()
+	}
+	f.mu.Lock()
+	f.now = end
+	f.mu.Unlock()
+}
+
+// next removes and returns the first timer due by end, moving the clock to when it is due,
+// or returns nil if there is none.
+func (f *Fake) next(end time.Time) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		a, b := f.timers[i], f.timers[j]
+		return a.at.Before(b.at) || a.at.Equal(b.at) && a.seq < b.seq
+	})
+	if len(f.timers) == 0 || f.timers[0].at.After(end) {
+		return nil
+	}
+	t := f.timers[0]
+	f.timers = f.timers[1:]
+	if t.at.After(f.now) {
+		f.now = t.at
+	}
+	return t
+}
+
+// Next returns when the next timer is due, or false if no timer is waiting.
+func (f *Fake) Next() (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var next time.Time
+	for _, t := range f.timers {
+		if next.IsZero() || t.at.Before(next) {
+			next = t.at
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Pending returns how many timers are waiting to be called.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
@@ -0,0 +1,37 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	var fired []string
+	at := func(name string) func() {
+		return func() { fired = append(fired, name+"@"+c.Now().Sub(start).String()) }
+	}
+	c.AfterFunc(2*time.Second, at("b"))
+	c.AfterFunc(time.Second, at("a"))
+	stopped := c.AfterFunc(time.Second, at("stopped"))
+	c.AfterFunc(time.Second, func() {
+		at("c")()
+		c.AfterFunc(500*time.Millisecond, at("chained"))
+	})
+	c.AfterFunc(time.Minute, at("later"))
+
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	c.Advance(2 * time.Second)
+	assert.Equal(t, []string{"a@1s", "c@1s", "chained@1.5s", "b@2s"}, fired)
+	assert.Equal(t, start.Add(2*time.Second), c.Now())
+	assert.Equal(t, 1, c.Pending())
+	next, ok := c.Next()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Minute), next)
+}
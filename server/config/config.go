@@ -0,0 +1,179 @@
+// Package config reads the settings of the server from, in order of precedence, command line
+// flags, environment variables and a YAML file.
+//
+// Settings are named like their environment variable, STORAGE_DSN for instance. The flag for
+// a setting is its name in lower case with dashes, like -storage-dsn, and its key in the file
+// is in lower case with underscores, like storage_dsn. The file is read from CONFIG_FILE or the
+// -config flag, and can be read again while the server runs to change the settings marked to
+// be reloaded.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setting is a value the server can be configured with.
+type Setting struct {
+	Name   string // of the environment variable, like STORAGE_DSN
+	Usage  string
+	Reload bool // the setting takes effect when the configuration is reloaded, not only on startup
+}
+
+// FlagName returns the name of the command line flag of the setting, like storage-dsn.
+func (s Setting) FlagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.Name), "_", "-")
+}
+
+// FileKey returns the key of the setting in configuration files, like storage_dsn.
+func (s Setting) FileKey() string {
+	return strings.ToLower(s.Name)
+}
+
+// Config holds the layers of settings. It is safe for concurrent use.
+type Config struct {
+	settings map[string]Setting
+	lookup   func(string) (string, bool) // environment variables
+	flags    map[string]string           // set on the command line
+	path     string                      // of the configuration file, or empty for none
+
+	mu   sync.RWMutex
+	file map[string]string // values read from the configuration file
+}
+
+// Load parses the command line flags of the settings in args and reads the configuration
+// file. Without a -config flag, the file is read from CONFIG_FILE, if set.
+func Load(settings []Setting, args []string) (*Config, error) {
+	return load(settings, args, os.LookupEnv)
+}
+
+func load(settings []Setting, args []string, lookup func(string) (string, bool)) (*Config, error) {
+	c := &Config{
+		settings: map[string]Setting{},
+		lookup:   lookup,
+		flags:    map[string]string{},
+		file:     map[string]string{},
+	}
+
+	flags := flag.NewFlagSet("cardgame-server", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	path, _ := lookup("CONFIG_FILE")
+	flags.StringVar(&path, "config", path, "YAML file to read settings from")
+	for _, s := range settings {
+		c.settings[s.Name] = s
+		s := s
+		flags.Func(s.FlagName(), s.Usage, func(v string) error {
+			c.flags[s.Name] = v
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	c.path = path
+	if err := c.readFile(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the value of a setting: its flag if it was set, or else its environment variable
+// if it is set, or else its value in the configuration file, or else "".
+func (c *Config) Get(name string) string {
+	if _, ok := c.settings[name]; !ok {
+		panic("config: unknown setting " + name)
+	}
+	if v, ok := c.flags[name]; ok {
+		return v
+	}
+	if v, ok := c.lookup(name); ok {
+		return v
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.file[name]
+}
+
+// Reload reads the configuration file again. It returns the names of the settings whose value
+// changed but which are only read on startup, so that the change can be reported: they keep
+// being read with their new value, but only take effect on the next restart.
+func (c *Config) Reload() (restart []string, err error) {
+	before := map[string]string{}
+	for name := range c.settings {
+		before[name] = c.Get(name)
+	}
+	if err := c.readFile(); err != nil {
+		return nil, err
+	}
+	for name, s := range c.settings {
+		if !s.Reload && c.Get(name) != before[name] {
+			restart = append(restart, name)
+		}
+	}
+	sort.Strings(restart)
+	return restart, nil
+}
+
+// readFile reads the configuration file, a YAML mapping of the keys of settings to values.
+func (c *Config) readFile() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("%s: %w", c.path, err)
+	}
+	keys := map[string]string{}
+	for name, s := range c.settings {
+		keys[s.FileKey()] = name
+	}
+	file := map[string]string{}
+	for key, node := range raw {
+		name, ok := keys[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", c.path, key)
+		}
+		if node.Kind != yaml.ScalarNode {
+			return fmt.Errorf("%s: %s must be a single value", c.path, key)
+		}
+		file[name] = node.Value
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.file = file
+	return nil
+}
+
+// Usage writes the flags of the settings, sorted by name.
+func Usage(w io.Writer, settings []Setting) {
+	sorted := append([]Setting{}, settings...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	fmt.Fprintln(w, "  -config FILE\n    \tYAML file to read settings from, or CONFIG_FILE")
+	for _, s := range sorted {
+		reload := ""
+		if s.Reload {
+			reload = " (reloaded on SIGHUP)"
+		}
+		fmt.Fprintf(w, "  -%s\n    \t%s, or %s%s\n", s.FlagName(), s.Usage, s.Name, reload)
+	}
+}
+
+// ErrHelp is returned by Load when the help flag is passed.
+var ErrHelp = flag.ErrHelp
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSettings = []Setting{
+	{Name: "STORAGE_DSN"},
+	{Name: "TURN_TIMEOUT"},
+	{Name: "LOG_LEVEL", Reload: true},
+}
+
+func testEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func writeConfig(t *testing.T, path, contents string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+}
+
+func TestLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "storage_dsn: file.db\nturn_timeout: 45\nlog_level: debug\n")
+
+	env := testEnv(map[string]string{"CONFIG_FILE": path, "TURN_TIMEOUT": "60", "LOG_LEVEL": ""})
+	c, err := load(testSettings, []string{"-storage-dsn", "flag.db"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, "flag.db", c.Get("STORAGE_DSN"))
+	assert.Equal(t, "60", c.Get("TURN_TIMEOUT"))
+	assert.Equal(t, "", c.Get("LOG_LEVEL"), "set environment variables win even when empty")
+
+	c, err = load(testSettings, nil, testEnv(map[string]string{"CONFIG_FILE": path}))
+	require.NoError(t, err)
+	assert.Equal(t, "file.db", c.Get("STORAGE_DSN"))
+	assert.Equal(t, "45", c.Get("TURN_TIMEOUT"))
+
+	assert.Panics(t, func() { c.Get("NOPE") })
+}
+
+func TestConfigFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "storage_dsn: file.db\n")
+
+	c, err := load(testSettings, []string{"-config", path}, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "file.db", c.Get("STORAGE_DSN"))
+
+	c, err = load(testSettings, nil, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "", c.Get("STORAGE_DSN"))
+}
+
+func TestInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	env := testEnv(map[string]string{"CONFIG_FILE": path})
+
+	writeConfig(t, path, "storage_dns: typo.db\n")
+	_, err := load(testSettings, nil, env)
+	assert.ErrorContains(t, err, `unknown setting "storage_dns"`)
+
+	writeConfig(t, path, "storage_dsn: [a, b]\n")
+	_, err = load(testSettings, nil, env)
+	assert.ErrorContains(t, err, "single value")
+
+	_, err = load(testSettings, []string{"-nope"}, testEnv(nil))
+	assert.Error(t, err)
+	_, err = load(testSettings, []string{"-h"}, testEnv(nil))
+	assert.ErrorIs(t, err, ErrHelp)
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "storage_dsn: file.db\nlog_level: info\n")
+	c, err := load(testSettings, nil, testEnv(map[string]string{"CONFIG_FILE": path}))
+	require.NoError(t, err)
+
+	writeConfig(t, path, "storage_dsn: other.db\nlog_level: debug\n")
+	restart, err := c.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"STORAGE_DSN"}, restart)
+	assert.Equal(t, "debug", c.Get("LOG_LEVEL"))
+
+	// a broken file keeps the settings read before
+	writeConfig(t, path, "log_level: [\n")
+	_, err = c.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "debug", c.Get("LOG_LEVEL"))
+}
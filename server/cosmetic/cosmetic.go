@@ -0,0 +1,209 @@
+// Package cosmetic keeps the catalog of cosmetic items, like card backs and table themes,
+// and the items each account has unlocked.
+//
+// The catalog is defined in data. An item is either a default everyone has, unlocked by
+// reaching a level or an achievement, or only granted, for example as a quest reward.
+package cosmetic
+
+import (
+	"cardgame/progression"
+	"cardgame/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is what a cosmetic item changes.
+type Kind string
+
+const (
+	KindCardBack   Kind = "card_back"   // the back of the player's cards
+	KindTableTheme Kind = "table_theme" // the table of the rooms the player owns
+)
+
+// Item describes a cosmetic item and how it is unlocked.
+type Item struct {
+	Id          string `yaml:"id" json:"id"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Kind        Kind   `yaml:"kind" json:"kind"`
+	Default     bool   `yaml:"default" json:"default"` // every account has it
+	Unlock      Unlock `yaml:"unlock" json:"unlock"`
+}
+
+// Unlock is the condition an item is unlocked by. Items without one are only granted.
+type Unlock struct {
+	Level       int    `yaml:"level" json:"level,omitempty"`             // reaching this level
+	Achievement string `yaml:"achievement" json:"achievement,omitempty"` // unlocking this achievement
+}
+
+// Catalog is the yaml representation of the catalog.
+type Catalog struct {
+	Items []Item `yaml:"items"`
+}
+
+var (
+	mu    sync.RWMutex
+	items []Item
+	byId  = map[string]Item{}
+)
+
+// LoadCatalog loads the catalog from a yaml file, replacing the one loaded before.
+func LoadCatalog(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var catalog Catalog
+	if err := yaml.Unmarshal(contents, &catalog); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return SetCatalog(catalog)
+}
+
+// SetCatalog replaces the catalog, after checking every item in it is complete.
+func SetCatalog(catalog Catalog) error {
+	ids := map[string]Item{}
+	for _, item := range catalog.Items {
+		if _, ok := ids[item.Id]; ok || item.Id == "" {
+			return fmt.Errorf("cosmetic %q needs an id of its own", item.Id)
+		}
+		if item.Kind != KindCardBack && item.Kind != KindTableTheme {
+			return fmt.Errorf("cosmetic %q has an invalid kind %q", item.Id, item.Kind)
+		}
+		if item.Default && (item.Unlock != Unlock{}) {
+			return fmt.Errorf("cosmetic %q is a default, so it can't have an unlock condition", item.Id)
+		}
+		ids[item.Id] = item
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	items = catalog.Items
+	byId = ids
+	return nil
+}
+
+// Items returns every item of the catalog, in the order they are defined.
+func Items() []Item {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Item{}, items...)
+}
+
+// Find returns the item with an id.
+func Find(id string) (Item, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	item, ok := byId[id]
+	return item, ok
+}
+
+// Unlocked returns when an account unlocked each of the items it has, by id.
+// Default items are unlocked from the start, at 0.
+func Unlocked(s storage.Store, accountId string) (map[string]int64, error) {
+	unlocks, err := s.Cosmetics(accountId)
+	if err != nil {
+		return nil, err
+	}
+	unlocked := map[string]int64{}
+	for _, item := range Items() {
+		if item.Default {
+			unlocked[item.Id] = 0
+		}
+	}
+	for _, u := range unlocks {
+		if _, ok := Find(u.CosmeticId); ok {
+			unlocked[u.CosmeticId] = u.Unlocked
+		}
+	}
+	return unlocked, nil
+}
+
+// CanSelect reports whether an account can select an item of a kind. An empty id selects
+// the default, which is always allowed.
+func CanSelect(s storage.Store, accountId string, kind Kind, id string) (bool, error) {
+	if id == "" {
+		return true, nil
+	}
+	item, ok := Find(id)
+	if !ok || item.Kind != kind {
+		return false, nil
+	}
+	unlocked, err := Unlocked(s, accountId)
+	if err != nil {
+		return false, err
+	}
+	_, ok = unlocked[id]
+	return ok, nil
+}
+
+// Check unlocks the items whose conditions an account meets now, and returns the ones it
+// didn't have before.
+func Check(s storage.Store, accountId string) ([]Item, error) {
+	unlocked, err := Unlocked(s, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	level := 1
+	xp, err := s.Experience(accountId)
+	if err == nil {
+		level = progression.Level(xp.XP)
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	progress, err := s.Achievements(accountId)
+	if err != nil {
+		return nil, err
+	}
+	achievements := map[string]bool{}
+	for _, p := range progress {
+		if p.Unlocked != 0 {
+			achievements[p.AchievementId] = true
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	unlocks := []*storage.CosmeticUnlock{}
+	newItems := []Item{}
+	for _, item := range Items() {
+		if _, ok := unlocked[item.Id]; ok {
+			continue
+		}
+		u := item.Unlock
+		if u == (Unlock{}) || (u.Level > 0 && level < u.Level) || (u.Achievement != "" && !achievements[u.Achievement]) {
+			continue
+		}
+		unlocks = append(unlocks, &storage.CosmeticUnlock{AccountId: accountId, CosmeticId: item.Id, Unlocked: now})
+		newItems = append(newItems, item)
+	}
+
+	if len(unlocks) == 0 {
+		return newItems, nil
+	}
+	return newItems, s.SaveCosmetics(unlocks)
+}
+
+// Grant unlocks an item for an account, whatever its condition.
+func Grant(s storage.Store, accountId, id string) error {
+	if _, ok := Find(id); !ok {
+		return fmt.Errorf("unknown cosmetic %q", id)
+	}
+	return s.SaveCosmetics([]*storage.CosmeticUnlock{{AccountId: accountId, CosmeticId: id, Unlocked: time.Now().UnixMilli()}})
+}
+
+// GrantReward is a progression.RewardHook granting the cosmetic item of a completed quest's reward.
+func GrantReward(accountId string, q progression.Quest) {
+	if q.Reward.Cosmetic == "" {
+		return
+	}
+	if err := Grant(storage.Default, accountId, q.Reward.Cosmetic); err != nil {
+		slog.Error("failed to grant quest reward", "err", err)
+	}
+}
@@ -0,0 +1,88 @@
+package cosmetic
+
+import (
+	"cardgame/progression"
+	"cardgame/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withTestCatalog(t *testing.T, catalog Catalog) {
+	t.Helper()
+	old := Catalog{Items: Items()}
+	assert.NoError(t, SetCatalog(catalog))
+	t.Cleanup(func() { SetCatalog(old) })
+}
+
+func TestCheck(t *testing.T) {
+	withTestCatalog(t, Catalog{Items: []Item{
+		{Id: "back_plain", Kind: KindCardBack, Default: true},
+		{Id: "back_level", Kind: KindCardBack, Unlock: Unlock{Level: 2}},
+		{Id: "theme_win", Kind: KindTableTheme, Unlock: Unlock{Achievement: "first_win"}},
+		{Id: "back_reward", Kind: KindCardBack},
+	}})
+	s := storage.NewMemory()
+
+	unlocked, err := Check(s, "u_1")
+	assert.NoError(t, err)
+	assert.Empty(t, unlocked)
+	ok, err := CanSelect(s, "u_1", KindCardBack, "back_plain")
+	assert.NoError(t, err)
+	assert.True(t, ok, "default items should be selectable by everyone")
+
+	assert.NoError(t, s.SaveProgression(&storage.Experience{AccountId: "u_1", XP: progression.LevelXP(2)}, nil))
+	assert.NoError(t, s.SaveAchievements([]*storage.AchievementProgress{{AccountId: "u_1", AchievementId: "first_win", Progress: 1, Unlocked: 1}}))
+	unlocked, err = Check(s, "u_1")
+	assert.NoError(t, err)
+	if assert.Len(t, unlocked, 2) {
+		assert.Equal(t, "back_level", unlocked[0].Id)
+		assert.Equal(t, "theme_win", unlocked[1].Id)
+	}
+	unlocked, err = Check(s, "u_1")
+	assert.NoError(t, err)
+	assert.Empty(t, unlocked, "items should only be unlocked once")
+
+	ok, _ = CanSelect(s, "u_1", KindCardBack, "theme_win")
+	assert.False(t, ok, "items should only be selected for their own kind")
+	ok, _ = CanSelect(s, "u_1", KindCardBack, "back_reward")
+	assert.False(t, ok)
+	old := storage.Default
+	storage.Default = s
+	defer func() { storage.Default = old }()
+	GrantReward("u_1", progression.Quest{Reward: progression.Reward{Cosmetic: "back_reward"}})
+	ok, _ = CanSelect(s, "u_1", KindCardBack, "back_reward")
+	assert.True(t, ok, "quest rewards should grant their item")
+	assert.Error(t, Grant(s, "u_1", "back_missing"))
+
+	ok, _ = CanSelect(s, "u_1", KindTableTheme, "")
+	assert.True(t, ok, "the default should always be selectable")
+}
+
+func TestSetCatalog(t *testing.T) {
+	withTestCatalog(t, Catalog{})
+	assert.Error(t, SetCatalog(Catalog{Items: []Item{{Id: "a", Kind: "hat"}}}))
+	assert.Error(t, SetCatalog(Catalog{Items: []Item{{Id: "a", Kind: KindCardBack}, {Id: "a", Kind: KindCardBack}}}))
+	assert.Error(t, SetCatalog(Catalog{Items: []Item{{Id: "a", Kind: KindCardBack, Default: true, Unlock: Unlock{Level: 2}}}}))
+}
+
+func TestDataCatalog(t *testing.T) {
+	withTestCatalog(t, Catalog{})
+	assert.NoError(t, LoadCatalog("../data/cosmetics.yaml"))
+	assert.NoError(t, progression.LoadQuests("../data/quests.yaml"))
+	t.Cleanup(func() { progression.SetQuests(progression.QuestFile{}) })
+
+	// every quest of a week is seen within a year of rotations
+	seen := map[string]bool{}
+	for day := 0; day < 365; day++ {
+		for _, q := range progression.Active(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day)) {
+			if q.Reward.Cosmetic != "" && !seen[q.Id] {
+				seen[q.Id] = true
+				_, ok := Find(q.Reward.Cosmetic)
+				assert.True(t, ok, "quest %q should reward a cosmetic from the catalog", q.Id)
+			}
+		}
+	}
+	assert.NotEmpty(t, seen)
+}
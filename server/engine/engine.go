@@ -0,0 +1,141 @@
+// Package engine holds the rules of the game, apart from how it is played: dealing decks into
+// a draw pile, drawing cards and wild cards, reshuffling the hands when the pile runs out and
+// sending cards between hands. It doesn't depend on the server, its connections or storage, so
+// it can be imported on its own, for an offline mode or tools working on decks.
+//
+// The server plays every room through a Table and the hands of its players. Game puts the two
+// together for programs that only need the cards.
+package engine
+
+import (
+	"cardgame/card"
+	"cardgame/deck"
+	"cardgame/errs"
+	"cardgame/locale"
+	"cardgame/util/slices"
+	"math/rand"
+)
+
+// The errors of moves are invalid actions, shown to players in their language.
+var (
+	ErrEmptyPile    = errs.Wrap(errs.ErrInvalidAction, locale.NewError("draw_pile_empty", nil))
+	ErrPileNotEmpty = errs.New(errs.ErrInvalidAction, "draw pile is not empty")
+	ErrEmptyHand    = errs.Wrap(errs.ErrInvalidAction, locale.NewError("cards_missing", nil))
+	ErrIncompatible = errs.Wrap(errs.ErrInvalidAction, locale.NewError("cards_incompatible", nil))
+)
+
+// Hand is the cards of a player, top is at the end.
+type Hand []*card.Card
+
+// Top returns the top card of the hand.
+func (h Hand) Top() *card.Card {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[len(h)-1]
+}
+
+// Tail returns all cards of the hand except the top card.
+func (h Hand) Tail() Hand {
+	if len(h) == 0 {
+		return Hand{}
+	}
+	return h[:len(h)-1]
+}
+
+// Table is the cards of a game outside of the hands of its players.
+type Table struct {
+	ActiveWildCard *card.WildCard   `json:"activeWildCard"` // active wild card
+	DrawPileSize   int              `json:"drawPileSize"`   // size of the draw pile
+	DrawPile       []card.BaseCard  `json:"-"`              // draw pile, top is at the start
+	UsedWildCards  []*card.WildCard `json:"-"`              // already used wild cards
+}
+
+// Deal clears the table and shuffles the cards of the decks into a new draw pile.
+func (t *Table) Deal(decks []*deck.Deck, rng *rand.Rand) {
+	t.Clear()
+	t.DrawPile = []card.BaseCard{}
+	for _, d := range decks {
+		for _, c := range d.Cards {
+			t.DrawPile = append(t.DrawPile, c)
+		}
+		for _, w := range d.WildCards {
+			t.DrawPile = append(t.DrawPile, w)
+		}
+	}
+	slices.ShuffleWith(t.DrawPile, rng)
+	t.DrawPileSize = len(t.DrawPile)
+}
+
+// Clear removes every card from the table.
+func (t *Table) Clear() {
+	*t = Table{}
+}
+
+// Draw takes the top card of the draw pile. A wild card replaces the active one right away,
+// any other card is for the player who drew it to add to their hand.
+func (t *Table) Draw() (card.BaseCard, error) {
+	if len(t.DrawPile) == 0 {
+		return nil, ErrEmptyPile
+	}
+	c := t.DrawPile[0]
+	t.DrawPile = t.DrawPile[1:]
+	t.DrawPileSize--
+
+	if wild, ok := c.(*card.WildCard); ok {
+		t.UsedWildCards = append(t.UsedWildCards, t.ActiveWildCard)
+		t.ActiveWildCard = wild
+	}
+	return c, nil
+}
+
+// Reshuffle shuffles every card of the hands but their top one into the empty draw pile, and
+// picks a new active wild card among the used ones.
+func (t *Table) Reshuffle(hands []*Hand, rng *rand.Rand) error {
+	if t.DrawPileSize != 0 {
+		return ErrPileNotEmpty
+	}
+
+	pile := []card.BaseCard{}
+	for _, h := range hands {
+		top := h.Top()
+		if top == nil {
+			// no cards
+			continue
+		}
+		for _, c := range h.Tail() {
+			pile = append(pile, c)
+		}
+		*h = Hand{top}
+	}
+	slices.ShuffleWith(pile, rng)
+	t.DrawPile = pile
+	t.DrawPileSize = len(pile)
+
+	t.UsedWildCards = append(t.UsedWildCards, t.ActiveWildCard)
+	slices.ShuffleWith(t.UsedWildCards, rng)
+	t.ActiveWildCard, t.UsedWildCards = t.UsedWildCards[0], t.UsedWildCards[1:]
+	return nil
+}
+
+// CanSend returns why the top card of a hand can't be sent onto another, or nil if it can.
+func (t *Table) CanSend(from, to Hand) error {
+	if from.Top() == nil || to.Top() == nil {
+		return ErrEmptyHand
+	}
+	if !from.Top().CompatibleWith(to.Top(), t.ActiveWildCard) {
+		return ErrIncompatible
+	}
+	return nil
+}
+
+// Send moves the top card of a hand onto another, and returns it.
+func (t *Table) Send(from, to *Hand) (*card.Card, error) {
+	if err := t.CanSend(*from, *to); err != nil {
+		return nil, err
+	}
+	c := from.Top()
+	*from = from.Tail()
+	*to = append(*to, c)
+	return c, nil
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"cardgame/card"
+	"cardgame/deck"
+	"cardgame/errs"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDeck(size int) *deck.Deck {
+	d := &deck.Deck{Id: "test"}
+	for i := 0; i < size; i++ {
+		d.Cards = append(d.Cards, &card.Card{
+			Id:       fmt.Sprintf("c_%d", i),
+			Type:     card.CardType(i % card.CardTypeCount()),
+			Category: fmt.Sprintf("category %d", i),
+		})
+	}
+	return d
+}
+
+func TestDrawWildCard(t *testing.T) {
+	first := &card.WildCard{Id: "w_1"}
+	second := &card.WildCard{Id: "w_2"}
+	table := Table{DrawPile: []card.BaseCard{first, second}, DrawPileSize: 2}
+
+	c, err := table.Draw()
+	assert.NoError(t, err)
+	assert.Same(t, first, c)
+	assert.Same(t, first, table.ActiveWildCard)
+
+	table.Draw()
+	assert.Same(t, second, table.ActiveWildCard)
+	assert.Contains(t, table.UsedWildCards, first)
+
+	_, err = table.Draw()
+	assert.ErrorIs(t, err, ErrEmptyPile)
+}
+
+func TestReshuffle(t *testing.T) {
+	d := newTestDeck(6)
+	a, b := Hand{d.Cards[0], d.Cards[1], d.Cards[2]}, Hand{d.Cards[3]}
+	table := Table{ActiveWildCard: &card.WildCard{Id: "w_1"}}
+
+	assert.NoError(t, table.Reshuffle([]*Hand{&a, &b}, rand.New(rand.NewSource(1))))
+	assert.Equal(t, Hand{d.Cards[2]}, a, "the top card should stay in the hand")
+	assert.Equal(t, Hand{d.Cards[3]}, b)
+	assert.ElementsMatch(t, []card.BaseCard{d.Cards[0], d.Cards[1]}, table.DrawPile)
+	assert.Equal(t, 2, table.DrawPileSize)
+
+	assert.ErrorIs(t, table.Reshuffle([]*Hand{&a, &b}, rand.New(rand.NewSource(1))), ErrPileNotEmpty)
+}
+
+func TestSend(t *testing.T) {
+	d := newTestDeck(2)
+	var table Table
+	from, to := Hand{}, Hand{d.Cards[1]}
+	_, err := table.Send(&from, &to)
+	assert.ErrorIs(t, err, ErrEmptyHand)
+
+	// every type is compatible with itself
+	from = Hand{&card.Card{Id: "c_x", Type: d.Cards[1].Type}}
+	c, err := table.Send(&from, &to)
+	assert.NoError(t, err)
+	assert.Equal(t, "c_x", c.Id)
+	assert.Empty(t, from)
+	assert.Same(t, c, to.Top())
+
+	from = Hand{d.Cards[0]}
+	_, err = table.Send(&from, &to)
+	assert.ErrorIs(t, err, ErrIncompatible)
+	assert.ErrorIs(t, err, errs.ErrInvalidAction)
+}
+
+func TestGame(t *testing.T) {
+	play := func() ([]Hand, int) {
+		g := NewGame([]*deck.Deck{newTestDeck(20)}, 3, 7)
+		for i := 0; i < 50; i++ {
+			_, err := g.Draw()
+			assert.NoError(t, err)
+		}
+		return g.Hands, g.DrawPileSize
+	}
+	hands, size := play()
+	assert.NotZero(t, size, "the hands should be reshuffled into the draw pile")
+	again, _ := play()
+	assert.Equal(t, hands, again, "the same seed should play the same game")
+
+	g := NewGame([]*deck.Deck{newTestDeck(20)}, 2, 1)
+	_, err := g.Send(0, 0)
+	assert.ErrorIs(t, err, ErrSameSeat)
+	_, err = g.Send(0, 2)
+	assert.ErrorIs(t, err, ErrNoSeat)
+}
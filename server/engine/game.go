@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"cardgame/card"
+	"cardgame/deck"
+	"cardgame/errs"
+	"cardgame/locale"
+	"math/rand"
+)
+
+var (
+	ErrNoSeat   = errs.New(errs.ErrInvalidAction, "seat does not exist")
+	ErrSameSeat = errs.Wrap(errs.ErrInvalidAction, locale.NewError("send_self", nil))
+)
+
+// Game is a game played without a server, by seats taking turns drawing. It follows the rules
+// rooms are played with, and deals the same cards for the same seed.
+type Game struct {
+	Table
+	Hands []Hand // of every seat
+	Turn  int    // seat drawing next
+
+	rng *rand.Rand
+}
+
+// NewGame deals the decks for a game between seats, and picks the seat starting.
+func NewGame(decks []*deck.Deck, seats int, seed int64) *Game {
+	g := &Game{Hands: make([]Hand, seats), rng: rand.New(rand.NewSource(seed))}
+	for i := range g.Hands {
+		g.Hands[i] = Hand{}
+	}
+	g.Deal(decks, g.rng)
+	g.Turn = g.rng.Intn(seats)
+	return g
+}
+
+// Draw draws a card for the seat whose turn it is. Drawing a card other than a wild card ends
+// the turn. The hands are reshuffled into the draw pile once it runs out.
+func (g *Game) Draw() (card.BaseCard, error) {
+	c, err := g.Table.Draw()
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := c.(*card.Card); ok {
+		g.Hands[g.Turn] = append(g.Hands[g.Turn], c)
+		g.Turn = (g.Turn + 1) % len(g.Hands)
+	}
+	if g.DrawPileSize == 0 {
+		hands := make([]*Hand, len(g.Hands))
+		for i := range g.Hands {
+			hands[i] = &g.Hands[i]
+		}
+		g.Reshuffle(hands, g.rng)
+	}
+	return c, nil
+}
+
+// Send sends the top card of a seat onto another's, at any time.
+func (g *Game) Send(from, to int) (*card.Card, error) {
+	if from < 0 || from >= len(g.Hands) || to < 0 || to >= len(g.Hands) {
+		return nil, ErrNoSeat
+	}
+	if from == to {
+		return nil, ErrSameSeat
+	}
+	return g.Table.Send(&g.Hands[from], &g.Hands[to])
+}
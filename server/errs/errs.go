@@ -0,0 +1,79 @@
+// Package errs sorts the errors of every part of the server into a few kinds, like
+// ErrNotFound or ErrPermission, so they can be told apart with errors.Is wherever they end
+// up. The HTTP API and the websocket protocol answer each kind with the same status and code,
+// instead of matching the text of errors.
+//
+// Errors get a kind by being created with New, or wrapped with Wrap. The kinds are errors
+// themselves, for errors which need no other text.
+package errs
+
+import "errors"
+
+// Kind is a kind of error.
+type Kind struct {
+	code   string // sent to clients, like "not_found"
+	status int    // HTTP status the API answers with
+	text   string
+}
+
+func (k *Kind) Error() string { return k.text }
+
+// Code returns the code clients are sent for the kind.
+func (k *Kind) Code() string { return k.code }
+
+// Status returns the HTTP status the API answers the kind with.
+func (k *Kind) Status() int { return k.status }
+
+var (
+	ErrInvalidInput    = &Kind{code: "invalid_input", status: 400, text: "invalid input"}
+	ErrInvalidAction   = &Kind{code: "invalid_action", status: 400, text: "invalid action"}
+	ErrUnauthorized    = &Kind{code: "unauthorized", status: 401, text: "unauthorized"}
+	ErrPermission      = &Kind{code: "permission", status: 403, text: "permission denied"}
+	ErrNotFound        = &Kind{code: "not_found", status: 404, text: "not found"}
+	ErrConflict        = &Kind{code: "conflict", status: 409, text: "conflict"}
+	ErrTooLarge        = &Kind{code: "too_large", status: 413, text: "too large"}
+	ErrUnsupportedType = &Kind{code: "unsupported_type", status: 415, text: "unsupported type"}
+	ErrRateLimited     = &Kind{code: "rate_limited", status: 429, text: "rate limited"}
+	ErrInternal        = &Kind{code: "internal", status: 500, text: "internal error"}
+	ErrUnsupported     = &Kind{code: "unsupported", status: 501, text: "unsupported"}
+	ErrUnavailable     = &Kind{code: "unavailable", status: 503, text: "unavailable"}
+)
+
+// Error is an error of a kind.
+type Error struct {
+	Kind *Kind
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns both the kind and the error, so errors.Is and errors.As find either.
+func (e *Error) Unwrap() []error { return []error{e.Kind, e.Err} }
+
+// New returns an error of a kind with a text.
+func New(kind *Kind, text string) error {
+	return &Error{Kind: kind, Err: errors.New(text)}
+}
+
+// Wrap gives an error a kind, keeping its text. Wrapping nil returns nil.
+func Wrap(kind *Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of an error, or ErrInternal for errors without one.
+func KindOf(err error) *Kind {
+	var k *Kind
+	if errors.As(err, &k) {
+		return k
+	}
+	return ErrInternal
+}
+
+// Code returns the code clients are sent for an error.
+func Code(err error) string { return KindOf(err).code }
+
+// Status returns the HTTP status the API answers an error with.
+func Status(err error) int { return KindOf(err).status }
@@ -0,0 +1,36 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKinds(t *testing.T) {
+	err := New(ErrNotFound, "room not found")
+	assert.Equal(t, "room not found", err.Error())
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrPermission)
+	assert.Equal(t, "not_found", Code(err))
+	assert.Equal(t, 404, Status(err))
+
+	wrapped := fmt.Errorf("loading replay: %w", err)
+	assert.ErrorIs(t, wrapped, ErrNotFound, "kinds should be found through other wrapping")
+	assert.Equal(t, 404, Status(wrapped))
+
+	assert.Equal(t, 404, Status(ErrNotFound), "kinds should be errors themselves")
+	assert.Equal(t, ErrInternal, KindOf(errors.New("disk on fire")), "errors without a kind should be internal")
+	assert.Equal(t, 500, Status(errors.New("disk on fire")))
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("cards are not compatible")
+	err := Wrap(ErrInvalidAction, cause)
+	assert.Equal(t, "cards are not compatible", err.Error())
+	assert.ErrorIs(t, err, cause, "the wrapped error should still be found")
+	assert.ErrorIs(t, err, ErrInvalidAction)
+	assert.Equal(t, "invalid_action", Code(err))
+	assert.Nil(t, Wrap(ErrConflict, nil))
+}
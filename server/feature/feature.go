@@ -0,0 +1,125 @@
+// Package feature decides which accounts get features that are still being rolled out.
+//
+// A flag is on for everyone, off for everyone, or on for a percentage of the accounts and an
+// allowlist of them. Accounts are picked for a percentage by hashing their id with the name of
+// the flag, so an account keeps a flag as the percentage grows, and different flags are rolled
+// out to different accounts. Guests only get flags that are on for everyone.
+//
+// Flags are configured with a list like "delta_sync=25%,binary_protocol=u_a|u_b,new_mode=on",
+// which can be changed while the server runs.
+package feature
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Flag is the rollout of a feature.
+type Flag struct {
+	Name     string          `json:"name"`
+	Percent  int             `json:"percent"`  // of the accounts the flag is on for, 100 for everyone
+	Accounts map[string]bool `json:"accounts"` // ids of accounts the flag is on for whatever the percentage
+}
+
+// On reports whether the flag is on for an account, or for guests if accountId is empty.
+func (f *Flag) On(accountId string) bool {
+	if f.Percent >= 100 {
+		return true
+	}
+	if accountId == "" {
+		return false
+	}
+	return f.Accounts[accountId] || bucket(f.Name, accountId) < f.Percent
+}
+
+// bucket returns the percentile, from 0 to 99, an account falls in for a flag.
+func bucket(name, accountId string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + "/" + accountId))
+	return int(h.Sum32() % 100)
+}
+
+// Flags are the flags in effect, by name.
+type Flags map[string]*Flag
+
+// Parse parses a comma separated list of flags, each a name, "=" and a "|" separated list of
+// "on", "off", a percentage like "25%", or account ids. Flags without a value are on.
+func Parse(s string) (Flags, error) {
+	flags := Flags{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			value = "on"
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("feature flag %q has no name", entry)
+		}
+		if _, ok := flags[name]; ok {
+			return nil, fmt.Errorf("feature flag %s is set twice", name)
+		}
+
+		f := &Flag{Name: name, Accounts: map[string]bool{}}
+		for _, v := range strings.Split(value, "|") {
+			v = strings.TrimSpace(v)
+			switch {
+			case v == "on":
+				f.Percent = 100
+			case v == "off", v == "":
+			case strings.HasSuffix(v, "%"):
+				n, err := strconv.Atoi(strings.TrimSuffix(v, "%"))
+				if err != nil || n < 0 || n > 100 {
+					return nil, fmt.Errorf("feature flag %s has an invalid percentage %q", name, v)
+				}
+				f.Percent = n
+			default:
+				f.Accounts[v] = true
+			}
+		}
+		flags[name] = f
+	}
+	return flags, nil
+}
+
+var current atomic.Pointer[Flags]
+
+// Configure replaces the flags in effect. It can be called while the server runs.
+func Configure(flags Flags) {
+	current.Store(&flags)
+}
+
+// All returns the flags in effect.
+func All() Flags {
+	if flags := current.Load(); flags != nil {
+		return *flags
+	}
+	return Flags{}
+}
+
+// Enabled reports whether a flag is on for an account, or for guests if accountId is empty.
+// Flags that aren't configured are off.
+func Enabled(name, accountId string) bool {
+	f, ok := All()[name]
+	return ok && f.On(accountId)
+}
+
+// EnabledFor returns the names of the flags on for an account, or for guests if accountId is
+// empty, sorted.
+func EnabledFor(accountId string) []string {
+	names := []string{}
+	for name, f := range All() {
+		if f.On(accountId) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
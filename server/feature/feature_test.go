@@ -0,0 +1,78 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	flags, err := Parse("delta_sync=25%, binary_protocol=u_a|u_b, new_mode, old_mode=off, mixed=10%|u_c")
+	require.NoError(t, err)
+	assert.Equal(t, 25, flags["delta_sync"].Percent)
+	assert.Equal(t, map[string]bool{"u_a": true, "u_b": true}, flags["binary_protocol"].Accounts)
+	assert.Equal(t, 100, flags["new_mode"].Percent)
+	assert.Equal(t, 0, flags["old_mode"].Percent)
+	assert.Equal(t, 10, flags["mixed"].Percent)
+	assert.True(t, flags["mixed"].Accounts["u_c"])
+
+	flags, err = Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, flags)
+
+	for _, s := range []string{"a=101%", "a=-1%", "a=x%", "=on", "a,a"} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestOn(t *testing.T) {
+	f := &Flag{Name: "f", Accounts: map[string]bool{"u_listed": true}}
+	assert.True(t, f.On("u_listed"))
+	assert.False(t, f.On("u_other"))
+	assert.False(t, f.On(""))
+
+	f.Percent = 100
+	assert.True(t, f.On(""))
+}
+
+func TestRolloutGrows(t *testing.T) {
+	f := &Flag{Name: "f"}
+	on := func() map[string]bool {
+		accounts := map[string]bool{}
+		for i := 0; i < 1000; i++ {
+			id := fmt.Sprintf("u_%d", i)
+			if f.On(id) {
+				accounts[id] = true
+			}
+		}
+		return accounts
+	}
+
+	f.Percent = 10
+	few := on()
+	assert.InDelta(t, 100, len(few), 40)
+
+	f.Percent = 50
+	more := on()
+	assert.InDelta(t, 500, len(more), 80)
+	for id := range few {
+		assert.True(t, more[id], "accounts keep the flag as the rollout grows")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Cleanup(func() { Configure(Flags{}) })
+	assert.False(t, Enabled("a", "u_1"))
+
+	flags, err := Parse("a=u_1,b=on,c=off")
+	require.NoError(t, err)
+	Configure(flags)
+	assert.True(t, Enabled("a", "u_1"))
+	assert.False(t, Enabled("a", "u_2"))
+	assert.False(t, Enabled("missing", "u_1"))
+	assert.Equal(t, []string{"a", "b"}, EnabledFor("u_1"))
+	assert.Equal(t, []string{"b"}, EnabledFor(""))
+}
@@ -0,0 +1,185 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// Filter checks text written by players before it is shown to others.
+type Filter interface {
+	Filter(text string) Result
+}
+
+// Result is the outcome of filtering a piece of text.
+type Result struct {
+	Text    string   // text to show, masked if needed
+	Blocked bool     // true if the text must not be shown at all
+	Flagged bool     // true if the text should be looked at by a moderator
+	Matches []string // words that matched
+}
+
+// Normalizer turns a word into the form used for matching, undoing tricks like "h3ll0".
+type Normalizer interface {
+	Normalize(word string) string
+}
+
+// Matcher decides whether a normalized word is objectionable.
+type Matcher interface {
+	Match(word string) bool
+}
+
+// Action is what a pipeline does with text that matched.
+type Action int
+
+const (
+	ActionMask Action = iota
+	ActionBlock
+	ActionFlag
+)
+
+// ParseAction returns the action with the given name: "mask", "block" or "flag".
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(s) {
+	case "mask", "":
+		return ActionMask, nil
+	case "block":
+		return ActionBlock, nil
+	case "flag":
+		return ActionFlag, nil
+	}
+	return ActionMask, fmt.Errorf("unknown filter action %q", s)
+}
+
+// Pipeline is a Filter that normalizes each word of the text, matches it, and applies an action to matches.
+// Words with repeated letters also match with the repeats collapsed, so "heeeck" matches "heck",
+// while words written without repeats are only matched as they are.
+type Pipeline struct {
+	Normalizer Normalizer
+	Matcher    Matcher
+	Action     Action
+}
+
+// Filter implements Filter.
+func (p *Pipeline) Filter(text string) Result {
+	result := Result{Text: text}
+	runes := []rune(text)
+	masked := false
+
+	for _, w := range words(runes) {
+		word := string(runes[w.start:w.end])
+		if !p.match(word) {
+			continue
+		}
+
+		result.Matches = append(result.Matches, word)
+		if p.Action == ActionMask {
+			for i := w.start; i < w.end; i++ {
+				runes[i] = '*'
+			}
+			masked = true
+		}
+	}
+
+	if len(result.Matches) == 0 {
+		return result
+	}
+
+	switch p.Action {
+	case ActionMask:
+		if masked {
+			result.Text = string(runes)
+		}
+	case ActionBlock:
+		result.Blocked = true
+	case ActionFlag:
+		result.Flagged = true
+	}
+
+	return result
+}
+
+// match returns true if the normalized word matches, as it is or with its repeated letters collapsed.
+func (p *Pipeline) match(word string) bool {
+	normalized := p.Normalizer.Normalize(word)
+	if p.Matcher.Match(normalized) {
+		return true
+	}
+	collapsed := collapse(normalized)
+	return collapsed != normalized && p.Matcher.Match(collapsed)
+}
+
+// collapse returns s with runs of the same rune replaced by one of it, so "heeeck" becomes "heck".
+func collapse(s string) string {
+	var b strings.Builder
+	var last rune = -1
+	for _, r := range s {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+type span struct{ start, end int }
+
+// isWordRune returns true for runes that can be part of a word, including symbols used in place of letters.
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	_, ok := leet[r]
+	return ok
+}
+
+// words splits text into words, returning their positions.
+func words(runes []rune) []span {
+	var spans []span
+	start := -1
+	for i, r := range runes {
+		if isWordRune(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		spans = append(spans, span{start, len(runes)})
+	}
+	return spans
+}
+
+type nop struct{}
+
+func (nop) Filter(text string) Result { return Result{Text: text} }
+
+// Nop is a Filter that lets everything through.
+var Nop Filter = nop{}
+
+// Swappable is a Filter passing text to another one, which can be replaced while it is in use,
+// for instance when the configuration is reloaded. The zero value lets everything through.
+type Swappable struct {
+	current atomic.Pointer[swapped]
+}
+
+type swapped struct{ Filter }
+
+// Swap replaces the filter text is passed to.
+func (s *Swappable) Swap(f Filter) {
+	s.current.Store(&swapped{f})
+}
+
+// Filter implements Filter.
+func (s *Swappable) Filter(text string) Result {
+	if f := s.current.Load(); f != nil {
+		return f.Filter.Filter(text)
+	}
+	return Nop.Filter(text)
+}
@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPipeline(action Action) *Pipeline {
+	return &Pipeline{
+		Normalizer: LeetNormalizer{},
+		Matcher:    NewWordList("darn", "heck"),
+		Action:     action,
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	n := LeetNormalizer{}
+	assert.Equal(t, "darn", n.Normalize("D4RN"))
+	assert.Equal(t, "heccckkk", n.Normalize("h3ccckkk"), "repeated letters should be kept")
+	assert.Equal(t, "as", n.Normalize("@$"))
+}
+
+func TestMask(t *testing.T) {
+	r := newTestPipeline(ActionMask).Filter("what the h3ck, d@rn it")
+	assert.Equal(t, "what the ****, **** it", r.Text)
+	assert.Equal(t, []string{"h3ck", "d@rn"}, r.Matches)
+	assert.False(t, r.Blocked)
+	assert.False(t, r.Flagged)
+}
+
+func TestRepeatedLetters(t *testing.T) {
+	r := newTestPipeline(ActionMask).Filter("h3ccckkk, daaarn")
+	assert.Equal(t, "********, ******", r.Text, "words should match with their repeats collapsed")
+
+	p := &Pipeline{Normalizer: LeetNormalizer{}, Matcher: NewWordList("ass", "boob"), Action: ActionMask}
+	r = p.Filter("as good as Bob said")
+	assert.Equal(t, "as good as Bob said", r.Text, "words without repeats should only match as they are")
+	assert.Empty(t, r.Matches)
+	assert.Equal(t, "what an ***", p.Filter("what an a$$").Text)
+}
+
+func TestWholeWords(t *testing.T) {
+	r := newTestPipeline(ActionMask).Filter("checking darnation")
+	assert.Equal(t, "checking darnation", r.Text, "only whole words should match")
+	assert.Empty(t, r.Matches)
+}
+
+func TestBlock(t *testing.T) {
+	r := newTestPipeline(ActionBlock).Filter("heck")
+	assert.True(t, r.Blocked)
+	assert.Equal(t, "heck", r.Text)
+
+	r = newTestPipeline(ActionBlock).Filter("hello")
+	assert.False(t, r.Blocked)
+}
+
+func TestFlag(t *testing.T) {
+	r := newTestPipeline(ActionFlag).Filter("oh heck")
+	assert.True(t, r.Flagged)
+	assert.False(t, r.Blocked)
+	assert.Equal(t, "oh heck", r.Text)
+}
+
+func TestUnicode(t *testing.T) {
+	r := newTestPipeline(ActionMask).Filter("héllo heck ☆")
+	assert.Equal(t, "héllo **** ☆", r.Text)
+}
+
+func TestFromConfig(t *testing.T) {
+	f, err := FromConfig("", "block")
+	assert.NoError(t, err)
+	assert.Equal(t, Nop, f)
+
+	path := filepath.Join(t.TempDir(), "words.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# test words\n\ndarn\n"), 0644))
+	f, err = FromConfig(path, "block")
+	assert.NoError(t, err)
+	assert.True(t, f.Filter("darn").Blocked)
+
+	_, err = FromConfig(path, "explode")
+	assert.Error(t, err)
+}
+
+func TestSwappable(t *testing.T) {
+	var s Swappable
+	assert.Equal(t, "oh heck", s.Filter("oh heck").Text)
+
+	s.Swap(newTestPipeline(ActionMask))
+	assert.Equal(t, "oh ****", s.Filter("oh heck").Text)
+
+	s.Swap(Nop)
+	assert.Equal(t, "oh heck", s.Filter("oh heck").Text)
+}
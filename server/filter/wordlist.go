@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+}
+
+// LeetNormalizer lowercases words and replaces common letter substitutions, so "HeLL0"
+// becomes "hello".
+type LeetNormalizer struct{}
+
+// Normalize implements Normalizer.
+func (LeetNormalizer) Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// WordList is a Matcher that matches whole words from a list.
+type WordList map[string]struct{}
+
+// NewWordList creates a word list from the given words.
+// Words are normalized with LeetNormalizer so they match normalized text.
+func NewWordList(words ...string) WordList {
+	w := WordList{}
+	for _, word := range words {
+		w[LeetNormalizer{}.Normalize(word)] = struct{}{}
+	}
+	return w
+}
+
+// LoadWordList reads a word list from a file with one word per line.
+// Empty lines and lines starting with # are ignored.
+func LoadWordList(path string) (WordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewWordList(words...), nil
+}
+
+// Match implements Matcher.
+func (w WordList) Match(word string) bool {
+	_, ok := w[word]
+	return ok
+}
+
+// FromConfig creates a word list pipeline from a word list file and action name.
+// If path is empty, Nop is returned.
+func FromConfig(path string, action string) (Filter, error) {
+	if path == "" {
+		return Nop, nil
+	}
+
+	a, err := ParseAction(action)
+	if err != nil {
+		return nil, err
+	}
+
+	words, err := LoadWordList(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pipeline{
+		Normalizer: LeetNormalizer{},
+		Matcher:    words,
+		Action:     a,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"cardgame/achievement"
+	"cardgame/storage"
+)
+
+// stats of the achievement.EventGameEnd events of classic games
+const (
+	statRank    = "rank"
+	statScore   = "score"
+	statDraws   = "draws"
+	statPlayers = "players"
+	statWinners = "winners" // players ranked first
+)
+
+// won reports whether the player of a game end event won a game against someone: alone in
+// first place, with a score, so ending a game before anyone has played doesn't count.
+func won(e achievement.Event) bool {
+	return e.Type == achievement.EventGameEnd && e.Stats[statRank] == 1 && e.Stats[statWinners] == 1 &&
+		e.Stats[statScore] > 0 && e.Stats[statPlayers] > 1
+}
+
+func init() {
+	achievement.Register(achievement.Definition{
+		Id:          "first_game",
+		Name:        "Shuffle Up",
+		Description: "Finish a game",
+		Goal:        1,
+		Progress: func(e achievement.Event) int {
+			if e.Type == achievement.EventGameEnd {
+				return 1
+			}
+			return 0
+		},
+	})
+	achievement.Register(achievement.Definition{
+		Id:          "first_win",
+		Name:        "Beginner's Luck",
+		Description: "Win a game",
+		Goal:        1,
+		Progress: func(e achievement.Event) int {
+			if won(e) {
+				return 1
+			}
+			return 0
+		},
+	})
+	achievement.Register(achievement.Definition{
+		Id:          "classic_wins_10",
+		Name:        "Old School",
+		Description: "Win 10 classic games",
+		GameType:    string(GameTypeClassic),
+		Goal:        10,
+		Progress: func(e achievement.Event) int {
+			if won(e) {
+				return 1
+			}
+			return 0
+		},
+	})
+	achievement.Register(achievement.Definition{
+		Id:          "classic_no_draw_win",
+		Name:        "Hands Off",
+		Description: "Win a classic game without drawing a card",
+		GameType:    string(GameTypeClassic),
+		Goal:        1,
+		Progress: func(e achievement.Event) int {
+			if won(e) && e.Stats[statDraws] == 0 {
+				return 1
+			}
+			return 0
+		},
+	})
+	achievement.Register(achievement.Definition{
+		Id:          "classic_cards_100",
+		Name:        "Collector",
+		Description: "Score 100 points in classic games",
+		GameType:    string(GameTypeClassic),
+		Goal:        100,
+		Progress: func(e achievement.Event) int {
+			if e.Type == achievement.EventGameEnd {
+				return e.Stats[statScore]
+			}
+			return 0
+		},
+	})
+}
+
+// recordAchievements reports the end of a game to the achievements of the players with accounts,
+// and tells them about the ones they unlocked. Seats played by bots don't count.
+func (r *Room) recordAchievements(match *storage.Match) {
+	ranks := map[string]int{}
+	winners := 0
+	for _, result := range match.Players {
+		ranks[result.Id] = result.Rank
+		if result.Rank == 1 {
+			winners++
+		}
+	}
+
+	for _, p := range r.Players {
+		if p.AccountId == "" || p.Bot {
+			continue
+		}
+
+		unlocked, err := achievement.Record(storage.Default, achievement.Event{
+			Type:      achievement.EventGameEnd,
+			AccountId: p.AccountId,
+			GameType:  match.GameType,
+			Stats: map[string]int{
+				statRank:    ranks[p.Id],
+				statScore:   p.Score,
+				statDraws:   p.draws,
+				statPlayers: len(match.Players),
+				statWinners: winners,
+			},
+		})
+		if err != nil {
+			r.logger().Error("failed to record achievements", "err", err)
+			continue
+		}
+
+		for _, d := range unlocked {
+			r.publish(&serverPayload{
+				include: set{p.Id: {}},
+				message: &ServerAchievement{
+					Id:          d.Id,
+					Name:        d.Name,
+					Description: d.Description,
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package game
+
+import (
+	"cardgame/achievement"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAchievementsUnlocked(t *testing.T) {
+	s := withTestStore(t)
+	owner, a := newTestPlayer("p_owner"), newTestPlayer("p_a")
+	owner.AccountId, a.AccountId = "u_owner", "u_a"
+	r := startTestGame(t, owner, a)
+	owner.Score, a.Score = 1, 4
+	owner.draws = 2
+
+	r.HandleEnd(ClientEnd{Player: owner})
+	receiveUntil[*ServerEnd](t, a)
+
+	unlocked := []string{}
+	for i := 0; i < 3; i++ {
+		unlocked = append(unlocked, receiveUntil[*ServerAchievement](t, a).Id)
+	}
+	assert.ElementsMatch(t, []string{"first_game", "first_win", "classic_no_draw_win"}, unlocked)
+
+	progress, err := s.Achievements("u_owner")
+	assert.NoError(t, err)
+	ids := map[string]int{}
+	for _, p := range progress {
+		ids[p.AchievementId] = p.Progress
+	}
+	assert.Contains(t, ids, "first_game")
+	assert.NotContains(t, ids, "first_win", "the loser should not progress towards wins")
+	assert.Equal(t, 1, ids["classic_cards_100"])
+}
+
+func TestWon(t *testing.T) {
+	end := func(rank, score, winners int) achievement.Event {
+		return achievement.Event{Type: achievement.EventGameEnd, Stats: map[string]int{
+			statRank: rank, statScore: score, statPlayers: 3, statWinners: winners,
+		}}
+	}
+	assert.True(t, won(end(1, 4, 1)))
+	assert.False(t, won(end(2, 1, 1)))
+	assert.False(t, won(end(1, 4, 2)), "sharing first place should not count as a win")
+	assert.False(t, won(end(1, 0, 1)), "games ended before scoring should not count as a win")
+}
@@ -0,0 +1,24 @@
+package game
+
+import "cardgame/analytics"
+
+// emit sends an analytics event about the room, with the properties every event of a room has.
+func (r *Room) emit(name string, props analytics.Props) {
+	props["room"] = r.Id
+	props["gameType"] = string(r.GameType)
+	props["kind"] = r.kind()
+	props["demo"] = r.demo != nil
+	props["challenge"] = r.challenge != nil
+	analytics.Emit(name, props)
+}
+
+// emitAction sends an action_taken event for a move of a player.
+func (r *Room) emitAction(p *Player, action string, bot bool) {
+	r.emit("action_taken", analytics.Props{
+		"action":    action,
+		"playerId":  p.Id,
+		"accountId": p.AccountId,
+		"bot":       bot || p.Bot,
+		"elapsedMs": Clock.Now().UnixMilli() - r.started,
+	})
+}
@@ -0,0 +1,42 @@
+package game
+
+import (
+	"cardgame/analytics"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// analyticsSink keeps the names and properties of the events written to it.
+type analyticsSink struct {
+	events []analytics.Event
+}
+
+func (s *analyticsSink) Write(events []analytics.Event) error {
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *analyticsSink) Close() error { return nil }
+
+func TestAnalyticsEvents(t *testing.T) {
+	withTestStore(t)
+	sink := &analyticsSink{}
+	stop := analytics.Start(sink, analytics.Config{PII: analytics.PIIKeep})
+	owner, other := newTestPlayer("p_owner"), newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+	r.CurrentTurn = 0
+	r.HandleDraw(ClientDraw{Player: owner})
+	r.HandleEnd(ClientEnd{Player: owner})
+	stop()
+
+	names := []string{}
+	for _, e := range sink.events {
+		names = append(names, e.Name)
+		assert.Equal(t, r.Id, e.Props["room"])
+	}
+	assert.Equal(t, []string{"room_joined", "room_joined", "game_started", "action_taken", "game_finished"}, names)
+	assert.Equal(t, "p_owner", sink.events[3].Props["playerId"])
+	assert.Contains(t, []any{"draw", "wild_card"}, sink.events[3].Props["action"])
+	assert.Equal(t, 2, sink.events[4].Props["players"])
+}
@@ -0,0 +1,106 @@
+package game
+
+import (
+	"cardgame/errs"
+	"cardgame/locale"
+	"cardgame/storage"
+	"cardgame/util"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxReasonLength is the maximum length of the reason given for a moderation action, in runes.
+const maxReasonLength = 200
+
+// ErrRoomNotFound is returned when there is no room with an id.
+var ErrRoomNotFound = errs.New(errs.ErrNotFound, "room not found")
+
+// CleanReason validates the reason given for a moderation action.
+func CleanReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return "", errs.Wrap(errs.ErrInvalidInput, locale.NewError("reason_too_long", locale.Params{"max": strconv.Itoa(maxReasonLength)}))
+	}
+	return reason, nil
+}
+
+// auditId returns the id a player is recorded as in the audit log: their account, or the
+// player itself for guests.
+func auditId(p *Player) string {
+	if p.AccountId != "" {
+		return p.AccountId
+	}
+	return p.Id
+}
+
+// Audit saves an entry to the audit log. Failing to do so doesn't stop the action.
+func Audit(e *storage.AuditEntry) {
+	e.Id = util.IdFrom("a", util.Token())
+	e.Created = Clock.Now().UnixMilli()
+	if err := storage.Default.SaveAuditEntry(e); err != nil {
+		slog.Error("failed to save audit entry", "err", err)
+	}
+}
+
+// auditKick records that a player was kicked from the room by another.
+func (r *Room) auditKick(action storage.AuditAction, actor, target *Player, reason string) {
+	Audit(&storage.AuditEntry{
+		Action:     action,
+		ActorId:    auditId(actor),
+		ActorName:  actor.Name,
+		TargetId:   auditId(target),
+		TargetName: target.Name,
+		RoomId:     r.Id,
+		Reason:     reason,
+	})
+}
+
+// CloseRoom removes a room from the hub and everyone from the room, on behalf of an admin, or
+// of the server itself if admin is nil. A game in progress is voided.
+func (h *Hub) CloseRoom(id string, admin *storage.Account, reason string) error {
+	r, ok := h.takeRoom(id)
+	if !ok || !r.post(clientClose{Admin: admin, Reason: reason}) {
+		// already closed by someone else, or collected
+		return ErrRoomNotFound
+	}
+	return nil
+}
+
+// HandleClose removes everyone from a room an admin closed.
+func (r *Room) HandleClose(message clientClose) {
+	if r.Vote != nil {
+		r.Vote.timer.Stop()
+		r.Vote = nil
+	}
+	if r.GamePhase == GamePhasePlaying {
+		r.void()
+	}
+
+	r.mu.Lock()
+	everyone := append(append([]*Player{}, r.Players...), r.Spectators...)
+	r.Players = []*Player{}
+	r.Spectators = []*Player{}
+	r.mu.Unlock()
+	r.closing = true
+	for _, p := range everyone {
+		p.setRoom(nil)
+		p.send(&ServerRoomClosed{Reason: message.Reason})
+	}
+	if r.demo != nil {
+		r.demo.timer.Stop()
+	}
+
+	if message.Admin == nil {
+		return
+	}
+	Audit(&storage.AuditEntry{
+		Action:     storage.AuditCloseRoom,
+		ActorId:    message.Admin.Id,
+		ActorName:  message.Admin.Name,
+		TargetId:   r.Id,
+		TargetName: r.Name,
+		Reason:     message.Reason,
+	})
+}
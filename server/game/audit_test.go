@@ -0,0 +1,69 @@
+package game
+
+import (
+	"cardgame/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKicksAudited(t *testing.T) {
+	s := withTestStore(t)
+	r := newTestRoom(t)
+	owner, a, b, c := newTestPlayer("p_owner"), newTestPlayer("p_a"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	owner.AccountId = "u_owner"
+	for _, p := range []*Player{owner, a, b, c} {
+		joinTestRoom(t, r, p, false)
+	}
+
+	r.HandleKick(ClientKick{Player: owner, Id: a.Id, Reason: "  spamming chat "})
+	receiveUntil[*ServerKick](t, a)
+	r.HandleLeave(ClientLeave{a})
+
+	r.HandleVoteKick(ClientVoteKick{Player: owner, Id: b.Id, Reason: "afk"})
+	r.HandleVote(ClientVote{Player: c, Yes: true})
+	assert.True(t, receiveUntil[*ServerVoteResult](t, owner).Passed)
+
+	entries, err := s.AuditLog(storage.AuditQuery{ActorId: "u_owner"})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		actions := []storage.AuditAction{entries[0].Action, entries[1].Action}
+		assert.ElementsMatch(t, []storage.AuditAction{storage.AuditKick, storage.AuditVoteKick}, actions)
+	}
+	kicks, err := s.AuditLog(storage.AuditQuery{TargetId: a.Id})
+	assert.NoError(t, err)
+	if assert.Len(t, kicks, 1) {
+		assert.Equal(t, "spamming chat", kicks[0].Reason)
+		assert.Equal(t, r.Id, kicks[0].RoomId)
+	}
+}
+
+func TestCloseRoom(t *testing.T) {
+	s := withTestStore(t)
+	owner, a, spectator := newTestPlayer("p_owner"), newTestPlayer("p_a"), newTestPlayer("p_spectator")
+	r := newTestRoom(t)
+	r.Decks = append(r.Decks, newTestDeck(20))
+	joinTestRoom(t, r, owner, false)
+	joinTestRoom(t, r, a, false)
+	joinTestRoom(t, r, spectator, true)
+	r.HandleStart(ClientStart{Player: owner})
+	admin := &storage.Account{Id: "u_admin", Name: "admin"}
+
+	r.HandleClose(clientClose{Admin: admin, Reason: "offensive room name"})
+	receiveUntil[*ServerVoid](t, owner)
+	for _, p := range []*Player{owner, a, spectator} {
+		assert.Equal(t, "offensive room name", receiveUntil[*ServerRoomClosed](t, p).Reason)
+		assert.Nil(t, p.currentRoom())
+	}
+	assert.Empty(t, r.Players)
+	assert.Empty(t, r.Spectators)
+
+	entries, err := s.AuditLog(storage.AuditQuery{Action: storage.AuditCloseRoom})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "u_admin", entries[0].ActorId)
+		assert.Equal(t, r.Id, entries[0].TargetId)
+	}
+
+	assert.ErrorIs(t, HubMain.CloseRoom("r_missing", admin, ""), ErrRoomNotFound)
+}
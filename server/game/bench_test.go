@@ -0,0 +1,131 @@
+package game
+
+import (
+	"cardgame/card"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// benchRoom returns a room of 8 players outside of the hub, with two decks of 200 cards and
+// 10 wild cards.
+func benchRoom(b *testing.B) *Room {
+	b.Helper()
+	g := &Golden{Seed: 1}
+	for i := 0; i < 8; i++ {
+		g.Players = append(g.Players, GoldenPlayer{Id: fmt.Sprintf("p_%d", i), Name: fmt.Sprint(i)})
+	}
+	symbols := []string{}
+	for _, t := range card.AllCardTypes() {
+		symbols = append(symbols, t.String())
+	}
+	for d := 0; d < 2; d++ {
+		gd := GoldenDeck{}
+		for i := 0; i < 200; i++ {
+			gd.Cards = append(gd.Cards, fmt.Sprintf("%s|category %d", symbols[i%len(symbols)], i))
+		}
+		for i := 0; i < 10; i++ {
+			gd.WildCards = append(gd.WildCards, symbols[i%len(symbols)]+"|"+symbols[(i+1)%len(symbols)])
+		}
+		g.Decks = append(g.Decks, gd)
+	}
+	r, err := g.room()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return r
+}
+
+// drain empties the queues of the room and its players, as the hub would.
+func drain(r *Room) {
+	for len(r.outbound) > 0 {
+		<-r.outbound
+	}
+	for _, p := range r.Players {
+		for len(p.outbound) > 0 {
+			<-p.outbound
+		}
+	}
+}
+
+// dealHands draws until every player has a hand of n cards.
+func dealHands(r *Room, n int) {
+	for _, p := range r.Players {
+		for len(p.Hand) < n {
+			r.HandleDraw(ClientDraw{Player: r.currentPlayer()})
+			drain(r)
+		}
+	}
+}
+
+func BenchmarkShuffle(b *testing.B) {
+	r := benchRoom(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Deal(r.Decks, r.rng)
+	}
+}
+
+func BenchmarkDeal(b *testing.B) {
+	r := benchRoom(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.start(int64(i))
+		drain(r)
+	}
+}
+
+// BenchmarkDraw draws cards in turn, reshuffling the hands into the draw pile once it runs out.
+func BenchmarkDraw(b *testing.B) {
+	r := benchRoom(b)
+	r.start(1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.HandleDraw(ClientDraw{Player: r.currentPlayer()})
+		drain(r)
+	}
+}
+
+// BenchmarkSend checks the top cards of two players and sends a card between them, then puts
+// the card back so every send is legal.
+func BenchmarkSend(b *testing.B) {
+	r := benchRoom(b)
+	r.start(1)
+	dealHands(r, 20)
+	var from, to *Player
+	for _, p := range r.Players {
+		for _, q := range r.Players {
+			if p != q && p.Hand.Top().CompatibleWith(q.Hand.Top(), r.ActiveWildCard) {
+				from, to = p, q
+			}
+		}
+	}
+	if from == nil {
+		b.Fatal("no two players can send to each other")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.HandleSend(ClientSend{Player: from, RecipientId: to.Id})
+		from.Hand = append(from.Hand, to.Hand.Top())
+		to.Hand = to.Hand.Tail()
+		drain(r)
+	}
+}
+
+// BenchmarkProjectState encodes the state of a room in the middle of a game, as sent with
+// every message.
+func BenchmarkProjectState(b *testing.B) {
+	r := benchRoom(b)
+	r.start(1)
+	dealHands(r, 20)
+	message := &ServerResync{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encodeMessage(io.Discard, message, r)
+	}
+}
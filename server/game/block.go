@@ -0,0 +1,65 @@
+package game
+
+import (
+	"cardgame/storage"
+	"sync"
+)
+
+// blockList is the set of accounts an account has blocked. Each connection loads it when
+// signing in, and Hub.SetBlocked keeps it up to date.
+type blockList struct {
+	mu  sync.RWMutex
+	ids set
+}
+
+// loadBlocks loads the block list of an account from storage.Default.
+func loadBlocks(accountId string) (*blockList, error) {
+	blocks, err := storage.Default.Blocks(accountId)
+	if err != nil {
+		return nil, err
+	}
+	l := &blockList{ids: set{}}
+	for _, b := range blocks {
+		l.ids[b.BlockedId] = struct{}{}
+	}
+	return l, nil
+}
+
+// has reports whether an account is on the list. Guests are never blocked, and a nil
+// list blocks no one.
+func (l *blockList) has(accountId string) bool {
+	if l == nil || accountId == "" {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.ids[accountId]
+	return ok
+}
+
+func (l *blockList) set(accountId string, blocked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if blocked {
+		l.ids[accountId] = struct{}{}
+	} else {
+		delete(l.ids, accountId)
+	}
+}
+
+// SetBlocked updates the block list of every connection signed in to an account,
+// after a block was added or removed in storage.
+func (h *Hub) SetBlocked(accountId, blockedId string, blocked bool) {
+	h.onlineMu.RLock()
+	defer h.onlineMu.RUnlock()
+	for p := range h.online[accountId] {
+		p.blocks.set(blockedId, blocked)
+	}
+}
+
+// ignores reports whether chat from a player is hidden from p, because p muted the
+// player or blocked their account.
+func (p *Player) ignores(sender *Player) bool {
+	from := sender.identity()
+	return p.hasMuted(from.id) || p.identity().blocks.has(from.accountId)
+}
@@ -0,0 +1,44 @@
+package game
+
+import (
+	"cardgame/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocks(t *testing.T) {
+	s := withTestStore(t)
+	saveTestAccount(s, &storage.Account{Id: "u_a", Name: "alice", Invites: storage.InvitesEveryone}, "t_a")
+	saveTestAccount(s, &storage.Account{Id: "u_b", Name: "bob"}, "t_b")
+	s.SaveBlock(&storage.Block{AccountId: "u_a", BlockedId: "u_b"})
+
+	h := newTestHub()
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	h.handleSignIn(ClientSignIn{Player: a, Token: "t_a"})
+	h.handleSignIn(ClientSignIn{Player: b, Token: "t_b"})
+
+	h.handleChannelJoin(ClientChannelJoin{Player: b, Channel: LobbyChannel})
+	h.handleChannelChat(ClientChannelChat{Player: b, Channel: LobbyChannel, Message: "blocked"})
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+	assert.Empty(t, receiveUntil[*ServerChannelJoin](t, a).History, "history should hide blocked accounts")
+
+	r := newTestRoom(t)
+	joinTestRoom(t, r, a, false)
+	b.setRoom(r)
+	r.HandleJoin(ClientJoin{Player: b, RoomId: r.Id, Spectate: true})
+	assert.Equal(t, "You cannot join this room", receiveUntil[*ServerError](t, b).Message)
+	assert.Nil(t, b.currentRoom())
+
+	b.setRoom(a.currentRoom())
+	h.handleInvite(ClientInvite{Player: b, AccountId: "u_a"})
+	assert.Equal(t, "alice is not accepting invites from you", receiveUntil[*ServerError](t, b).Message)
+
+	h.SetBlocked("u_a", "u_b", false)
+	r.HandleJoin(ClientJoin{Player: b, RoomId: r.Id})
+	receiveUntil[*ServerAck](t, b)
+	h.SetBlocked("u_a", "u_b", true)
+	r.HandleChat(ClientChat{Player: b, Message: "blocked"})
+	r.HandleChat(ClientChat{Player: a, Message: "not blocked"})
+	assert.Equal(t, "not blocked", receiveUntil[*ServerChat](t, a).Message, "chat from blocked accounts should be hidden")
+}
@@ -0,0 +1,75 @@
+package game
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPooledBuffer is the capacity above which buffers are left to the garbage collector
+// instead of pooled, so that a few huge messages don't keep memory around.
+const maxPooledBuffer = 64 << 10
+
+// bufferPool holds the buffers messages are encoded into before being written to sockets.
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+// sharedMessage is a message broadcast to several players, encoded once for all of them.
+// Each recipient releases it once written; the last one returns the buffer to the pool.
+// Recipients that drop it without releasing it leave the buffer to the garbage collector.
+type sharedMessage struct {
+	ServerMessage
+	buf  *bytes.Buffer
+	refs atomic.Int32
+}
+
+// shareMessage encodes a message sent from a room for n recipients.
+func shareMessage(message ServerMessage, room *Room, n int) (*sharedMessage, error) {
+	buf := getBuffer()
+	if err := encodeMessage(buf, message, room); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	m := &sharedMessage{ServerMessage: message, buf: buf}
+	m.refs.Store(int32(n))
+	return m, nil
+}
+
+func (m *sharedMessage) release() {
+	if m.refs.Add(-1) == 0 {
+		putBuffer(m.buf)
+	}
+}
+
+// encoded returns a message encoded in the format sent over the socket, and a function to
+// call once the bytes have been written.
+func encoded(message ServerMessage, room *Room) ([]byte, func(), error) {
+	if m, ok := message.(*sharedMessage); ok {
+		return m.buf.Bytes(), m.release, nil
+	}
+	buf := getBuffer()
+	if err := encodeMessage(buf, message, room); err != nil {
+		putBuffer(buf)
+		return nil, nil, err
+	}
+	return buf.Bytes(), func() { putBuffer(buf) }, nil
+}
+
+// unshare returns the message a shared message was encoded from, or the message itself.
+func unshare(message ServerMessage) ServerMessage {
+	if m, ok := message.(*sharedMessage); ok {
+		return m.ServerMessage
+	}
+	return message
+}
@@ -0,0 +1,62 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastEncodesOnce(t *testing.T) {
+	r := newTestRoom(t)
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	joinTestRoom(t, r, a, false)
+	joinTestRoom(t, r, b, false)
+	for len(a.outbound) > 0 {
+		<-a.outbound
+	}
+
+	r.broadcast(&serverPayload{message: &ServerTurn{PlayerId: a.Id}})
+	sentA, sentB := <-a.outbound, <-b.outbound
+	require.IsType(t, &sharedMessage{}, sentA)
+	assert.Same(t, sentA, sentB, "every recipient should get the same encoded message")
+
+	var want bytes.Buffer
+	require.NoError(t, encodeMessage(&want, &ServerTurn{PlayerId: a.Id}, r))
+	data, release, err := encoded(sentA, a.currentRoom())
+	require.NoError(t, err)
+	assert.JSONEq(t, want.String(), string(data))
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "turn", decoded["type"])
+
+	shared := sentA.(*sharedMessage)
+	release()
+	assert.EqualValues(t, 1, shared.refs.Load())
+	shared.release()
+	assert.EqualValues(t, 0, shared.refs.Load())
+}
+
+func BenchmarkBroadcast(b *testing.B) {
+	r := HubMain.NewRoom("")
+	b.Cleanup(func() { HubMain.RemoveRoom(r.Id) })
+	players := []*Player{}
+	for i := 0; i < 8; i++ {
+		p := newTestPlayer(fmt.Sprintf("p_%d", i))
+		r.Players = append(r.Players, p)
+		players = append(players, p)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.broadcast(&serverPayload{message: &ServerTurn{PlayerId: players[0].Id}})
+		for _, p := range players {
+			_, release, _ := encoded(<-p.outbound, r)
+			release()
+		}
+	}
+}
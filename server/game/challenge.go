@@ -0,0 +1,190 @@
+package game
+
+import (
+	"cardgame/challenge"
+	"cardgame/deck"
+	"cardgame/errs"
+	"cardgame/locale"
+	"cardgame/storage"
+	"cardgame/util"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// challengeBots is the number of bots a daily challenge is played against.
+const challengeBots = 3
+
+// ErrChallengeAttempted is returned when an account has already attempted a daily challenge.
+var ErrChallengeAttempted = errs.New(errs.ErrConflict, "today's challenge has already been attempted")
+
+// dailyAttempt is an account's attempt at a daily challenge.
+type dailyAttempt struct {
+	day       string
+	accountId string
+	started   int64 // unix ms, 0 until the game starts
+}
+
+// DailyChallenge opens a private room for an account's attempt at today's challenge of a
+// game type. The game starts as soon as the account takes its seat, against bots, with the
+// deal everyone gets that day. If a room is already waiting for the attempt, it is returned.
+func (h *Hub) DailyChallenge(a *storage.Account, gameType GameType) (*Room, error) {
+	day := challenge.Day(Clock.Now())
+	for _, r := range h.AllRooms() {
+		if r.challenge != nil && r.challenge.accountId == a.Id && r.challenge.day == day &&
+			r.GameType == gameType && r.challenge.started == 0 {
+			return r, nil
+		}
+	}
+
+	_, err := storage.Default.ChallengeResult(day, string(gameType), a.Id)
+	if err == nil {
+		return nil, ErrChallengeAttempted
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	// an unguessable password, so only the account's own player can join
+	r := h.newRoom(util.Token())
+	r.Name = "Daily challenge " + day
+	r.GameType = gameType
+	r.MaxPlayers = 1 + challengeBots
+	r.Decks = challengeDecks()
+	r.invited = set{a.Id: {}}
+	r.challenge = &dailyAttempt{day: day, accountId: a.Id}
+
+	go r.read()
+	return r, nil
+}
+
+// challengeDecks returns every deck in the same order, so every attempt is dealt the same cards.
+func challengeDecks() []*deck.Deck {
+	decks := []*deck.Deck{}
+	for _, d := range deck.Decks() {
+		decks = append(decks, d)
+	}
+	sort.Slice(decks, func(i, j int) bool { return decks[i].Id < decks[j].Id })
+	return decks
+}
+
+// checkChallengeSeat reports whether a player may take the seat of a daily challenge.
+// If not, the player is told why.
+func (r *Room) checkChallengeSeat(p *Player) bool {
+	a := r.challenge
+	if p.AccountId != a.accountId {
+		p.send(&ServerError{Id: "challenge_wrong_account"})
+		return false
+	}
+	if a.started != 0 || len(r.Players) > 0 {
+		p.send(&ServerError{Id: "challenge_attempted"})
+		return false
+	}
+
+	// another room could have been opened for the same attempt
+	_, err := storage.Default.ChallengeResult(a.day, string(r.GameType), a.accountId)
+	if err == nil {
+		p.send(&ServerError{Id: "challenge_attempted"})
+		return false
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		r.logger().Error("failed to load challenge result", "err", err)
+		p.send(&ServerError{Id: "challenge_start_failed"})
+		return false
+	}
+	return true
+}
+
+// startChallenge fills the room with bots and deals the day's challenge. The attempt is
+// saved first, so the deal can only be seen once.
+func (r *Room) startChallenge() {
+	p := r.Players[0]
+	a := r.challenge
+	a.started = Clock.Now().UnixMilli()
+	err := storage.Default.SaveChallengeResult(&storage.ChallengeResult{
+		Day:       a.day,
+		GameType:  string(r.GameType),
+		AccountId: a.accountId,
+		Name:      p.Name,
+		Started:   a.started,
+	}, nil)
+	if err != nil {
+		r.logger().Error("failed to save challenge attempt", "err", err)
+		p.send(&ServerError{Id: "challenge_start_failed"})
+		return
+	}
+
+	bots := []*Player{}
+	for i := 1; i <= challengeBots; i++ {
+		bots = append(bots, newBot(r, i))
+	}
+	r.Players = append(r.Players, bots...)
+	for _, bot := range bots {
+		r.publish(&serverPayload{
+			message: &ServerJoin{
+				Id:        bot.Id,
+				Player:    *bot,
+				narration: narrate("event_join", locale.Params{"player": bot.Name}),
+			},
+		})
+	}
+	r.start(challenge.Seed(a.day, string(r.GameType)))
+	r.scheduleBot()
+}
+
+// newBot creates a bot to fill a seat of a room played against bots.
+func newBot(r *Room, n int) *Player {
+	done := make(chan struct{})
+	close(done)
+
+	return &Player{
+		Id:           util.IdFrom("p", fmt.Sprintf("%s bot %d", r.Id, n)),
+		Name:         fmt.Sprintf("Bot %d", n),
+		Hand:         PlayerHand{},
+		Disconnected: true,
+		Bot:          true,
+		room:         r,
+		outbound:     make(chan ServerMessage),
+		done:         done,
+	}
+}
+
+// recordChallenge saves the score of the daily challenge that just ended, and tells the
+// player how they did. A challenge is scored by the cards the player sent, since the bots
+// never send any.
+func (r *Room) recordChallenge() {
+	a := r.challenge
+	var p *Player
+	for _, player := range r.Players {
+		if player.AccountId == a.accountId {
+			p = player
+		}
+	}
+	if p == nil {
+		// the player left before the end, so the attempt stays unfinished
+		return
+	}
+
+	result := &storage.ChallengeResult{
+		Day:       a.day,
+		GameType:  string(r.GameType),
+		AccountId: a.accountId,
+		Name:      p.Name,
+		Score:     p.sends,
+		Started:   a.started,
+		Completed: Clock.Now().UnixMilli(),
+	}
+	if err := challenge.Complete(storage.Default, result); err != nil {
+		r.logger().Error("failed to save challenge result", "err", err)
+		return
+	}
+
+	streak, err := storage.Default.ChallengeStreak(a.accountId, string(r.GameType))
+	if err != nil {
+		r.logger().Error("failed to load challenge streak", "err", err)
+		return
+	}
+	p.send(&ServerChallenge{
+		Day:    a.day,
+		Score:  result.Score,
+		Streak: streak.Streak,
+	})
+}
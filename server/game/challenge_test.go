@@ -0,0 +1,76 @@
+package game
+
+import (
+	"cardgame/challenge"
+	"cardgame/deck"
+	"cardgame/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyChallenge(t *testing.T) {
+	s := withTestStore(t)
+	oldBotDelay := botDelay
+	botDelay = time.Hour
+	t.Cleanup(func() { botDelay = oldBotDelay })
+	h := newTestHub()
+	testDeck := newTestDeck(20)
+
+	attempt := func(accountId string) (*Room, *Player) {
+		t.Helper()
+		r, err := h.DailyChallenge(&storage.Account{Id: accountId}, GameTypeClassic)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		r.Decks = []*deck.Deck{testDeck}
+		p := newTestPlayer("p_" + accountId)
+		p.AccountId = accountId
+		joinTestRoom(t, r, p, false)
+		receiveUntil[*ServerStart](t, p)
+		return r, p
+	}
+
+	guest := newTestPlayer("p_guest")
+	r, err := h.DailyChallenge(&storage.Account{Id: "u_1"}, GameTypeClassic)
+	assert.NoError(t, err)
+	guest.setRoom(r)
+	r.HandleJoin(ClientJoin{Player: guest, RoomId: r.Id})
+	receiveUntil[*ServerError](t, guest)
+	guest.setRoom(r)
+	r.HandleJoin(ClientJoin{Player: guest, RoomId: r.Id, Spectate: true})
+	assert.Equal(t, "Daily challenges can't be watched", receiveUntil[*ServerError](t, guest).Message)
+
+	a, alice := attempt("u_1")
+	assert.Same(t, r, a, "the room waiting for the attempt should be reused")
+	assert.Len(t, a.Players, 1+challengeBots)
+	assert.True(t, a.Players[1].Bot)
+	_, err = h.DailyChallenge(&storage.Account{Id: "u_1"}, GameTypeClassic)
+	assert.ErrorIs(t, err, ErrChallengeAttempted, "a challenge should only be attempted once")
+
+	b, _ := attempt("u_2")
+	day := challenge.Day(time.Now())
+	assert.Equal(t, challenge.Seed(day, "classic"), a.seed)
+	assert.Equal(t, a.CurrentTurn, b.CurrentTurn, "every attempt should get the same deal")
+	assert.Equal(t, a.DrawPile, b.DrawPile)
+
+	// the challenge ends with the deal instead of reshuffling
+	a.DrawPile = a.DrawPile[:1]
+	a.DrawPileSize = 1
+	a.CurrentTurn = 0
+	alice.sends = 2
+	a.HandleDraw(ClientDraw{Player: alice})
+	receiveUntil[*ServerEnd](t, alice)
+	result := receiveUntil[*ServerChallenge](t, alice)
+	assert.Equal(t, 2, result.Score)
+	assert.Equal(t, 1, result.Streak)
+	a.HandleStart(ClientStart{Player: alice})
+	assert.Equal(t, "daily challenges start by themselves", receiveUntil[*ServerError](t, alice).Message, "a challenge should not be played again")
+
+	results, err := s.ChallengeResults(day, "classic", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1, "unfinished attempts should not be ranked") {
+		assert.Equal(t, "u_1", results[0].AccountId)
+	}
+}
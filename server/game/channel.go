@@ -0,0 +1,182 @@
+package game
+
+import (
+	"cardgame/filter"
+	"cardgame/locale"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// LobbyChannel is the name of the global lobby chat channel.
+const LobbyChannel = "lobby"
+
+// channelHistorySize is the number of messages a channel keeps for players joining it.
+const channelHistorySize = 50
+
+// maxChatLength is the maximum length of a chat message, in runes.
+const maxChatLength = 500
+
+// ChatFilter is applied to chat messages and display names before they are shown to other players.
+var ChatFilter filter.Filter = filter.Nop
+
+// chatRate is how many chat messages a player can send per interval.
+type chatRate struct {
+	n        int
+	interval time.Duration
+}
+
+// currentChatRate is the chat rate given to players when they connect.
+var currentChatRate atomic.Pointer[chatRate]
+
+func init() {
+	SetChatRate(5, 10*time.Second)
+}
+
+// SetChatRate changes how many chat messages a player can send per interval. It can be called
+// while the server runs, and applies to players connecting afterwards.
+func SetChatRate(n int, interval time.Duration) {
+	currentChatRate.Store(&chatRate{n, interval})
+}
+
+// Channel is a chat channel that exists independently of rooms, like the global lobby.
+type Channel struct {
+	Name    string
+	members map[*Player]struct{}
+	history []channelChat // oldest first
+}
+
+// channelChat is a message kept in a channel's history.
+type channelChat struct {
+	*ServerChannelChat
+	accountId string // account of the sender, to hide the message from players who blocked it
+}
+
+func newChannel(name string) *Channel {
+	return &Channel{
+		Name:    name,
+		members: make(map[*Player]struct{}),
+	}
+}
+
+// GameTypeChannel returns the name of the chat channel for players of a game type.
+func GameTypeChannel(t GameType) string {
+	return "game:" + string(t)
+}
+
+func (h *Hub) handleChannelJoin(msg ClientChannelJoin) {
+	p := msg.Player
+	c, ok := h.Channels[msg.Channel]
+	if !ok {
+		p.notify(&ServerError{Id: "channel_not_found"})
+		return
+	}
+
+	c.members[p] = struct{}{}
+
+	blocks := p.identity().blocks
+	history := []*ServerChannelChat{}
+	for _, m := range c.history {
+		if !p.hasMuted(m.PlayerId) && !blocks.has(m.accountId) {
+			history = append(history, m.ServerChannelChat)
+		}
+	}
+	p.notify(&ServerChannelJoin{
+		Channel: c.Name,
+		History: history,
+	})
+}
+
+func (h *Hub) handleChannelLeave(msg ClientChannelLeave) {
+	if c, ok := h.Channels[msg.Channel]; ok {
+		delete(c.members, msg.Player)
+	}
+}
+
+func (h *Hub) handleChannelChat(msg ClientChannelChat) {
+	p := msg.Player
+	c, ok := h.Channels[msg.Channel]
+	if !ok {
+		p.notify(&ServerError{Id: "channel_not_found"})
+		return
+	}
+
+	if _, ok := c.members[p]; !ok {
+		p.notify(&ServerError{Id: "not_in_channel"})
+		return
+	}
+
+	if msg.Message == "" {
+		return
+	}
+
+	if !p.allowChat() {
+		p.notify(&ServerError{Id: "chat_rate_limited"})
+		return
+	}
+
+	text, rejected := filterChat(p, msg.Message)
+	if rejected != nil {
+		p.notify(rejected)
+		return
+	}
+
+	from := p.identity()
+	m := &ServerChannelChat{
+		Channel:   c.Name,
+		Timestamp: fmt.Sprint(Clock.Now().UnixMilli()),
+		PlayerId:  from.id,
+		Name:      from.name,
+		Message:   text,
+	}
+
+	c.history = append(c.history, channelChat{m, from.accountId})
+	if len(c.history) > channelHistorySize {
+		c.history = c.history[len(c.history)-channelHistorySize:]
+	}
+
+	for member := range c.members {
+		if member.ignores(p) {
+			continue
+		}
+		member.notify(m)
+	}
+}
+
+// filterChat checks the length of a chat message and runs it through ChatFilter.
+// If the message is rejected, the error to tell the sender is returned.
+func filterChat(p *Player, text string) (string, *ServerError) {
+	if utf8.RuneCountInString(text) > maxChatLength {
+		return "", &ServerError{Id: "chat_too_long", Params: locale.Params{"max": strconv.Itoa(maxChatLength)}}
+	}
+
+	result := ChatFilter.Filter(text)
+	if result.Flagged {
+		from := p.identity()
+		slog.Info("chat message flagged by filter", "player", from.id, "account", from.accountId, "message", text)
+	}
+	if result.Blocked {
+		return "", &ServerError{Id: "chat_blocked"}
+	}
+	return result.Text, nil
+}
+
+func (h *Hub) handleMute(msg ClientMute) {
+	p := msg.Player
+	if msg.Id == p.identity().id {
+		p.notify(&ServerError{Id: "mute_self"})
+		return
+	}
+
+	p.setMuted(msg.Id, msg.Mute)
+}
+
+func (h *Hub) handleDisconnect(msg clientDisconnect) {
+	for _, c := range h.Channels {
+		delete(c.members, msg.Player)
+	}
+	h.signOut(msg.Player)
+}
@@ -0,0 +1,160 @@
+package game
+
+import (
+	"cardgame/filter"
+	"cardgame/util/ratelimit"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHub() *Hub {
+	return &Hub{
+		Channels: map[string]*Channel{LobbyChannel: newChannel(LobbyChannel)},
+		rooms:    make(map[string]*Room),
+		resuming: make(map[string]*Room),
+	}
+}
+
+func TestChannelChat(t *testing.T) {
+	h := newTestHub()
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+	assert.Empty(t, receiveUntil[*ServerChannelJoin](t, a).History)
+
+	h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: "anyone up for a game?"})
+	assert.Equal(t, "anyone up for a game?", receiveUntil[*ServerChannelChat](t, a).Message)
+
+	h.handleChannelJoin(ClientChannelJoin{Player: b, Channel: LobbyChannel})
+	join := receiveUntil[*ServerChannelJoin](t, b)
+	assert.Len(t, join.History, 1, "history should be sent on join")
+	assert.Equal(t, a.Id, join.History[0].PlayerId)
+
+	h.handleChannelLeave(ClientChannelLeave{Player: b, Channel: LobbyChannel})
+	h.handleChannelChat(ClientChannelChat{Player: b, Channel: LobbyChannel, Message: "hi"})
+	receiveUntil[*ServerError](t, b)
+}
+
+func TestChannelNotFound(t *testing.T) {
+	h := newTestHub()
+	a := newTestPlayer("p_a")
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: "nope"})
+	assert.Equal(t, "Channel not found", receiveUntil[*ServerError](t, a).Message)
+}
+
+func TestChannelHistoryLimit(t *testing.T) {
+	h := newTestHub()
+	a := newTestPlayer("p_a")
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+	for i := 0; i < channelHistorySize+10; i++ {
+		h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: fmt.Sprint(i)})
+		<-a.outbound
+	}
+	history := h.Channels[LobbyChannel].history
+	assert.Len(t, history, channelHistorySize)
+	assert.Equal(t, fmt.Sprint(channelHistorySize+9), history[len(history)-1].Message)
+}
+
+func TestChannelRateLimit(t *testing.T) {
+	h := newTestHub()
+	a := newTestPlayer("p_a")
+	a.chatLimit = ratelimit.New(2, time.Minute)
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+
+	for i := 0; i < 3; i++ {
+		h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: "spam"})
+	}
+	err := receiveUntil[*ServerError](t, a)
+	assert.Equal(t, "you are sending messages too quickly", err.Message)
+	assert.Len(t, h.Channels[LobbyChannel].history, 2)
+}
+
+func TestChannelMute(t *testing.T) {
+	h := newTestHub()
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+	h.handleChannelJoin(ClientChannelJoin{Player: b, Channel: LobbyChannel})
+	receiveUntil[*ServerChannelJoin](t, b)
+
+	h.handleMute(ClientMute{Player: b, Id: a.Id, Mute: true})
+	h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: "muted"})
+	h.handleMute(ClientMute{Player: b, Id: a.Id, Mute: false})
+	h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: "unmuted"})
+	assert.Equal(t, "unmuted", receiveUntil[*ServerChannelChat](t, b).Message, "muted messages should not be delivered")
+}
+
+func TestRoomChatMute(t *testing.T) {
+	r := newTestRoom(t)
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	joinTestRoom(t, r, a, false)
+	joinTestRoom(t, r, b, false)
+	b.setMuted(a.Id, true)
+
+	r.HandleChat(ClientChat{Player: a, Message: "muted"})
+	r.HandleChat(ClientChat{Player: b, Message: "not muted"})
+	assert.Equal(t, "not muted", receiveUntil[*ServerChat](t, b).Message)
+}
+
+func withTestFilter(t *testing.T, action filter.Action) {
+	t.Helper()
+	ChatFilter = &filter.Pipeline{
+		Normalizer: filter.LeetNormalizer{},
+		Matcher:    filter.NewWordList("heck"),
+		Action:     action,
+	}
+	t.Cleanup(func() { ChatFilter = filter.Nop })
+}
+
+func TestChatFilter(t *testing.T) {
+	withTestFilter(t, filter.ActionMask)
+	r := newTestRoom(t)
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	joinTestRoom(t, r, a, false)
+	joinTestRoom(t, r, b, false)
+
+	r.HandleChat(ClientChat{Player: a, Message: "what the heck"})
+	assert.Equal(t, "what the ****", receiveUntil[*ServerChat](t, b).Message)
+
+	h := newTestHub()
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+	h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: "h3ck"})
+	assert.Equal(t, "****", receiveUntil[*ServerChannelChat](t, a).Message)
+}
+
+func TestChatFilterBlock(t *testing.T) {
+	withTestFilter(t, filter.ActionBlock)
+	h := newTestHub()
+	a := newTestPlayer("p_a")
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+	h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: "heck"})
+	assert.Equal(t, "message blocked by chat filter", receiveUntil[*ServerError](t, a).Message)
+	assert.Empty(t, h.Channels[LobbyChannel].history)
+}
+
+func TestChatTooLong(t *testing.T) {
+	h := newTestHub()
+	a := newTestPlayer("p_a")
+	h.handleChannelJoin(ClientChannelJoin{Player: a, Channel: LobbyChannel})
+	h.handleChannelChat(ClientChannelChat{Player: a, Channel: LobbyChannel, Message: strings.Repeat("a", maxChatLength+1)})
+	receiveUntil[*ServerError](t, a)
+	assert.Empty(t, h.Channels[LobbyChannel].history)
+}
+
+func TestCleanName(t *testing.T) {
+	withTestFilter(t, filter.ActionMask)
+
+	name, err := CleanName("  card shark ")
+	assert.NoError(t, err)
+	assert.Equal(t, "card shark", name)
+
+	_, err = CleanName("")
+	assert.Error(t, err)
+	_, err = CleanName("this name is far too long to be shown anywhere")
+	assert.Error(t, err)
+	_, err = CleanName("heck")
+	assert.Error(t, err, "filtered names should be rejected instead of masked")
+}
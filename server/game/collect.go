@@ -0,0 +1,159 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	// CasualRoomTTL is how long a public room is kept once no one is connected to it, or 0 to
+	// keep it forever, set on startup.
+	CasualRoomTTL = 10 * time.Minute
+	// PrivateRoomTTL is how long a private room is kept once no one is connected to it, so
+	// friends have time to come back to it, or 0 to keep it forever, set on startup.
+	PrivateRoomTTL = 30 * time.Minute
+	// RankedRoomTTL is how long a ranked room is kept once no one is connected to it, or 0 to
+	// keep it forever, set on startup.
+	RankedRoomTTL = time.Hour
+)
+
+// kind is what rooms are told apart by for their TTL: "ranked", "private" or "casual".
+func (r *Room) kind() string {
+	switch {
+	case r.Ranked:
+		return "ranked"
+	case r.private:
+		return "private"
+	}
+	return "casual"
+}
+
+// ttl returns how long the room is kept once no one is connected to it.
+func (r *Room) ttl() time.Duration {
+	switch r.kind() {
+	case "ranked":
+		return RankedRoomTTL
+	case "private":
+		return PrivateRoomTTL
+	}
+	return CasualRoomTTL
+}
+
+// occupied returns true if a player or spectator is connected to the room. Bots and players
+// who lost their connection don't count.
+func (r *Room) occupied() bool {
+	for _, p := range r.Players {
+		if !p.Disconnected {
+			return true
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Spectators) > 0
+}
+
+// HandleSweep closes the room if it has been abandoned for longer than its TTL, and tells
+// whether it did. A game still in progress is suspended first, when its players can resume
+// it, so nothing is lost. Deciding and closing happen in one message, so no one can join the
+// room in between and be removed with it.
+func (r *Room) HandleSweep(message clientSweep) bool {
+	if r.demo != nil {
+		// demos are closed by themselves
+		return false
+	}
+	if r.occupied() {
+		r.idleSince = 0
+		return false
+	}
+	if r.idleSince == 0 {
+		r.idleSince = message.now
+	}
+	ttl := r.ttl()
+	if ttl <= 0 || message.now-r.idleSince < ttl.Milliseconds() {
+		return false
+	}
+
+	if r.GamePhase == GamePhasePlaying && r.suspendable() {
+		r.suspend()
+		if r.GamePhase == GamePhasePlaying {
+			// the game couldn't be saved, try again on the next sweep
+			return false
+		}
+		roomsCollectedSaved.Inc(r.kind())
+	}
+	r.hub.RemoveRoom(r.Id)
+	r.HandleClose(clientClose{Reason: "The room was abandoned"})
+	return true
+}
+
+// suspendable returns true if every player of the game has an account to resume it with.
+func (r *Room) suspendable() bool {
+	for _, p := range r.Players {
+		if p.AccountId == "" {
+			return false
+		}
+	}
+	return len(r.Players) > 0
+}
+
+// CollectRooms closes the rooms no one has been connected to for longer than their TTL, and
+// returns how many it closed.
+func (h *Hub) CollectRooms() int {
+	rooms := h.AllRooms()
+	now := Clock.Now().UnixMilli()
+	collected := 0
+	for _, r := range rooms {
+		done := make(chan bool, 1)
+		if !r.post(clientSweep{now: now, done: done}) || !<-done {
+			continue
+		}
+		roomsCollected.Inc(r.kind())
+		collected++
+	}
+	return collected
+}
+
+// sweepsMissed is how many sweeps can be missed before collecting rooms is considered stuck.
+const sweepsMissed = 3
+
+// KeepCollecting collects abandoned rooms every interval, until stop is called.
+func (h *Hub) KeepCollecting(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	h.lastSweep.Store(time.Now().UnixMilli())
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+
+			if n := h.CollectRooms(); n > 0 {
+				slog.Info("collected abandoned rooms", "count", n, "open", h.RoomCount())
+			}
+			h.lastSweep.Store(time.Now().UnixMilli())
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+		h.lastSweep.Store(0)
+	}
+}
+
+// CheckCollecting returns an error unless abandoned rooms are collected every interval, as
+// KeepCollecting does. A sweep stuck on a room that no longer handles messages fails it.
+func (h *Hub) CheckCollecting(interval time.Duration) error {
+	last := h.lastSweep.Load()
+	if last == 0 {
+		return errors.New("rooms are not being collected")
+	}
+	if since := time.Since(time.UnixMilli(last)); since > sweepsMissed*interval {
+		return fmt.Errorf("last swept for abandoned rooms %s ago", since.Round(time.Second))
+	}
+	return nil
+}
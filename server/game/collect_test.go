@@ -0,0 +1,66 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectRooms(t *testing.T) {
+	c := withTestClock(t)
+	h := newTestHub()
+	casual, private, occupied := h.NewRoom(""), h.NewRoom("secret"), h.NewRoom("")
+	joinTestRoom(t, occupied, newTestPlayer("p_a"), false)
+
+	assert.Equal(t, 0, h.CollectRooms(), "rooms should be kept for their TTL")
+	c.Advance(CasualRoomTTL)
+	assert.Equal(t, 1, h.CollectRooms())
+	assert.ElementsMatch(t, []*Room{private, occupied}, h.AllRooms(), "private rooms and rooms someone is connected to should be kept")
+
+	c.Advance(PrivateRoomTTL)
+	assert.Equal(t, 1, h.CollectRooms())
+	assert.Equal(t, []*Room{occupied}, h.AllRooms())
+
+	assert.Error(t, casual.Ping(time.Second), "collected rooms should stop handling messages")
+	assert.False(t, casual.post(ClientChat{Message: "anyone?"}))
+}
+
+func TestCollectSuspendsGame(t *testing.T) {
+	s := withTestStore(t)
+	c := withTestClock(t)
+	h := newTestHub()
+	r := h.NewRoom("")
+	r.Decks = append(r.Decks, newTestDeck(20))
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	a.AccountId, b.AccountId = "u_a", "u_b"
+	joinTestRoom(t, r, a, false)
+	joinTestRoom(t, r, b, false)
+	r.HandleStart(ClientStart{Player: a})
+	for _, p := range []*Player{a, b} {
+		close(p.done)
+		r.HandleDisconnect(clientDisconnect{p})
+	}
+
+	assert.Equal(t, 0, h.CollectRooms())
+	c.Advance(CasualRoomTTL)
+	assert.Equal(t, 1, h.CollectRooms())
+	assert.Empty(t, h.AllRooms())
+
+	games, err := s.SuspendedGames("u_a")
+	assert.NoError(t, err)
+	assert.Len(t, games, 1, "the game should be saved to be resumed")
+}
+
+func TestCheckCollecting(t *testing.T) {
+	h := newTestHub()
+	assert.Error(t, h.CheckCollecting(time.Minute), "rooms aren't collected yet")
+
+	stop := h.KeepCollecting(time.Minute)
+	assert.NoError(t, h.CheckCollecting(time.Minute))
+	h.lastSweep.Store(time.Now().Add(-sweepsMissed * time.Minute).Add(-time.Second).UnixMilli())
+	assert.ErrorContains(t, h.CheckCollecting(time.Minute), "last swept")
+
+	stop()
+	assert.Error(t, h.CheckCollecting(time.Minute))
+}
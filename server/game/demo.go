@@ -0,0 +1,86 @@
+package game
+
+import (
+	"cardgame/clock"
+	"cardgame/errs"
+	"cardgame/locale"
+	"time"
+)
+
+// demoBots is the number of bots a demo is played against.
+const demoBots = 3
+
+var (
+	// DemoSessionTTL is how long a demo room stays open before it is closed.
+	DemoSessionTTL = 10 * time.Minute
+	// MaxDemoRooms is the most demo rooms open at once, or 0 for no limit.
+	MaxDemoRooms = 100
+)
+
+// ErrTooManyDemos is returned when MaxDemoRooms demo rooms are open already.
+var ErrTooManyDemos = errs.New(errs.ErrUnavailable, "the demo is busy, try again later")
+
+// demoSession is a demo played in a room.
+type demoSession struct {
+	timer clock.Timer // fires when the session is over
+}
+
+// DemoRoom opens a room for a guest to try the game in. The game starts as soon as the guest
+// takes their seat, against bots. Nothing about the game is stored, and the room is closed
+// after DemoSessionTTL or once the guest leaves.
+func (h *Hub) DemoRoom() (*Room, error) {
+	if MaxDemoRooms > 0 {
+		open := 0
+		for _, r := range h.AllRooms() {
+			if r.demo != nil {
+				open++
+			}
+		}
+		if open >= MaxDemoRooms {
+			return nil, ErrTooManyDemos
+		}
+	}
+
+	r := h.newRoom("")
+	r.Name = "Demo"
+	r.MaxPlayers = 1 + demoBots
+	r.Decks = challengeDecks()
+	r.AfkPolicy = AfkPolicyBotFill
+	r.demo = &demoSession{}
+	r.demo.timer = Clock.AfterFunc(DemoSessionTTL, func() {
+		h.CloseRoom(r.Id, nil, "The demo is over")
+	})
+
+	go r.read()
+	return r, nil
+}
+
+// startDemo fills the room with bots and deals a game.
+func (r *Room) startDemo() {
+	bots := []*Player{}
+	for i := 1; i <= demoBots; i++ {
+		bots = append(bots, newBot(r, i))
+	}
+	r.Players = append(r.Players, bots...)
+	for _, bot := range bots {
+		r.publish(&serverPayload{
+			message: &ServerJoin{
+				Id:        bot.Id,
+				Player:    *bot,
+				narration: narrate("event_join", locale.Params{"player": bot.Name}),
+			},
+		})
+	}
+	r.start(Clock.Now().UnixNano())
+	r.scheduleBot()
+}
+
+// endDemo closes the room once its guest has left, instead of letting the bots play on.
+func (r *Room) endDemo() {
+	if !r.demo.timer.Stop() {
+		// the room is being closed already
+		return
+	}
+	// closing goes through the room's inbound channel, which this goroutine reads
+	go r.hub.CloseRoom(r.Id, nil, "The demo is over")
+}
@@ -0,0 +1,94 @@
+package game
+
+import (
+	"cardgame/clock"
+	"cardgame/deck"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withTestClock replaces the clock of the game with a fake one for the test.
+func withTestClock(t *testing.T) *clock.Fake {
+	t.Helper()
+	old := Clock
+	c := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	Clock = c
+	t.Cleanup(func() { Clock = old })
+	return c
+}
+
+func TestDemo(t *testing.T) {
+	s := withTestStore(t)
+	c := withTestClock(t)
+	oldBotDelay := botDelay
+	botDelay = time.Hour
+	t.Cleanup(func() { botDelay = oldBotDelay })
+	h := newTestHub()
+
+	r, err := h.DemoRoom()
+	assert.NoError(t, err)
+	r.Decks = []*deck.Deck{newTestDeck(20)}
+
+	watcher := newTestPlayer("p_watcher")
+	watcher.setRoom(r)
+	r.HandleJoin(ClientJoin{Player: watcher, RoomId: r.Id, Spectate: true})
+	assert.Equal(t, "Demos can't be watched", receiveUntil[*ServerError](t, watcher).Message)
+
+	guest := newTestPlayer("p_guest")
+	joinTestRoom(t, r, guest, false)
+	receiveUntil[*ServerStart](t, guest)
+	assert.Len(t, r.Players, 1+demoBots)
+	assert.True(t, r.IsFull(), "nobody else should be able to join a demo")
+
+	r.end()
+	end := receiveUntil[*ServerEnd](t, guest)
+	_, err = s.Match(end.MatchId)
+	assert.Error(t, err, "demos shouldn't be stored")
+
+	// the guest can play again against the same bots
+	r.HandleStart(ClientStart{Player: guest})
+	receiveUntil[*ServerStart](t, guest)
+
+	// the room is closed once the session is over
+	c.Advance(DemoSessionTTL)
+	_, ok := h.Room(r.Id)
+	assert.False(t, ok)
+	receiveUntil[*ServerRoomClosed](t, guest)
+}
+
+func TestDemoEndsWhenGuestLeaves(t *testing.T) {
+	withTestStore(t)
+	withTestClock(t)
+	h := newTestHub()
+
+	r, err := h.DemoRoom()
+	assert.NoError(t, err)
+	r.Decks = []*deck.Deck{newTestDeck(20)}
+	guest := newTestPlayer("p_guest")
+	joinTestRoom(t, r, guest, false)
+	receiveUntil[*ServerStart](t, guest)
+
+	r.inbound <- ClientLeave{Player: guest}
+	select {
+	case <-r.closed:
+	case <-time.After(time.Second):
+		t.Fatal("the demo should be closed once the guest leaves")
+	}
+	_, ok := h.Room(r.Id)
+	assert.False(t, ok)
+}
+
+func TestMaxDemoRooms(t *testing.T) {
+	withTestClock(t)
+	old := MaxDemoRooms
+	MaxDemoRooms = 1
+	t.Cleanup(func() { MaxDemoRooms = old })
+	h := newTestHub()
+
+	_, err := h.DemoRoom()
+	assert.NoError(t, err)
+	_, err = h.DemoRoom()
+	assert.ErrorIs(t, err, ErrTooManyDemos)
+}
@@ -0,0 +1,258 @@
+package game
+
+import (
+	"cardgame/locale"
+	"cardgame/storage"
+	"time"
+)
+
+// DefaultDisconnectGrace is the number of seconds new rooms wait for a disconnected player,
+// set on startup.
+var DefaultDisconnectGrace = 60
+
+// botDelay is how long a bot waits before taking its turn.
+var botDelay = time.Second
+
+// HandleDisconnect keeps the seat of a player whose connection was lost mid-game and pauses the game.
+// Outside of a game, a disconnect is the same as leaving.
+func (r *Room) HandleDisconnect(message clientDisconnect) {
+	p := message.Player
+
+	if r.GamePhase != GamePhasePlaying || r.getPlayer(p.Id) != p {
+		r.HandleLeave(ClientLeave{p})
+		return
+	}
+
+	p.Disconnected = true
+	if r.OwnerId == p.Id {
+		r.transferOwnership()
+	}
+
+	if !r.Paused {
+		r.Paused = true
+		r.stopTurnTimer()
+		r.pauseTimer = Clock.AfterFunc(time.Duration(r.DisconnectGrace)*time.Second, func() {
+			r.post(clientPauseExpired{})
+		})
+	}
+
+	r.publish(&serverPayload{
+		message: &ServerPause{
+			PlayerId:  p.Id,
+			Grace:     r.DisconnectGrace,
+			narration: narrate("event_pause", locale.Params{"player": p.Name}),
+		},
+	})
+}
+
+// HandlePauseExpired tells the room that the owner has to decide how to continue.
+func (r *Room) HandlePauseExpired(message clientPauseExpired) {
+	if !r.Paused {
+		return
+	}
+
+	if r.demo != nil {
+		// the guest isn't coming back
+		r.endDemo()
+		return
+	}
+
+	r.publish(&serverPayload{
+		message: &ServerPauseExpired{},
+	})
+}
+
+func (r *Room) HandleResume(message ClientResume) {
+	p := message.Player
+
+	if p.Id != r.OwnerId {
+		r.logger().Warn("player is not owner")
+		p.send(&ServerError{Id: "not_owner"})
+		return
+	}
+
+	if !r.Paused {
+		r.logger().Warn("game is not paused")
+		p.send(&ServerError{Id: "game_not_paused"})
+		return
+	}
+
+	switch message.Mode {
+	case ResumeModeBot:
+		for _, player := range r.Players {
+			if player.Disconnected {
+				player.Bot = true
+			}
+		}
+		r.resume()
+		r.scheduleBot()
+	case ResumeModeVoid:
+		r.void()
+	default:
+		r.logger().Warn("invalid resume mode")
+		p.send(&ServerError{Id: "invalid_resume_mode"})
+	}
+}
+
+// reconnect hands the seat matching the token over to a new connection.
+func (r *Room) reconnect(p *Player, token string) {
+	seat := -1
+	for i, player := range r.Players {
+		if player.token == token && player.Disconnected {
+			seat = i
+			break
+		}
+	}
+
+	if seat == -1 {
+		p.setRoom(nil)
+		r.logger().Warn("no seat to reclaim")
+		p.send(&ServerError{Id: "no_seat_to_reclaim"})
+		return
+	}
+
+	old := r.Players[seat]
+	// the hub may be reading who the player is, for channels and mutes
+	identityMu.Lock()
+	p.Id = old.Id
+	p.AccountId = old.AccountId
+	p.Name = old.Name
+	if p.blocks == nil {
+		p.blocks = old.blocks
+	}
+	identityMu.Unlock()
+	p.Avatar = old.Avatar
+	p.AvatarUrl = old.AvatarUrl
+	p.CardBack = old.CardBack
+	p.TableTheme = old.TableTheme
+	p.Score = old.Score
+	p.Hand = old.Hand
+	p.draws = old.draws
+	p.sends = old.sends
+	p.token = old.token
+	r.Players[seat] = p
+
+	p.send(&ServerAck{Token: p.token})
+	r.publish(&serverPayload{
+		exclude: set{p.Id: {}},
+		message: &ServerReconnect{
+			Id:        p.Id,
+			narration: narrate("event_reconnect", locale.Params{"player": p.Name}),
+		},
+	})
+
+	if r.Paused && !r.waitingForPlayers() {
+		r.resume()
+	}
+}
+
+// waitingForPlayers returns true if a disconnected seat has not been taken over by a bot.
+func (r *Room) waitingForPlayers() bool {
+	for _, p := range r.Players {
+		if p.Disconnected && !p.Bot {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Room) resume() {
+	if r.pauseTimer != nil {
+		r.pauseTimer.Stop()
+		r.pauseTimer = nil
+	}
+	r.Paused = false
+	r.startTurnTimer()
+
+	r.publish(&serverPayload{
+		message: &ServerResume{
+			narration: narrate("event_resume", nil),
+		},
+	})
+}
+
+// void ends a paused game without a result, removes disconnected players and returns to the lobby.
+func (r *Room) void() {
+	if r.pauseTimer != nil {
+		r.pauseTimer.Stop()
+		r.pauseTimer = nil
+	}
+	r.Paused = false
+	r.stopTurnTimer()
+	r.recordMatch(storage.OutcomeVoid)
+	r.mu.Lock()
+	r.replay = nil
+	r.mu.Unlock()
+
+	players := []*Player{}
+	for _, p := range r.Players {
+		if p.Disconnected {
+			p.setRoom(nil)
+			continue
+		}
+		p.Hand = PlayerHand{}
+		p.Score = 0
+		players = append(players, p)
+	}
+	r.Players = players
+
+	r.GamePhase = GamePhaseLobby
+	r.CurrentTurn = 0
+	r.Clear()
+
+	r.publish(&serverPayload{
+		message: &ServerVoid{},
+	})
+}
+
+// transferOwnership makes the first connected player the owner of the room.
+func (r *Room) transferOwnership() {
+	for _, p := range r.Players {
+		if !p.Disconnected {
+			r.OwnerId = p.Id
+			return
+		}
+	}
+}
+
+// botFrom creates a bot to take over the seat of a player.
+func botFrom(p *Player) *Player {
+	done := make(chan struct{})
+	close(done)
+
+	return &Player{
+		Id:           p.Id,
+		AccountId:    p.AccountId,
+		Avatar:       p.Avatar,
+		AvatarUrl:    p.AvatarUrl,
+		CardBack:     p.CardBack,
+		TableTheme:   p.TableTheme,
+		Name:         p.Name,
+		Score:        p.Score,
+		Hand:         p.Hand,
+		draws:        p.draws,
+		sends:        p.sends,
+		blocks:       p.blocks,
+		Disconnected: true,
+		Bot:          true,
+		room:         p.currentRoom(),
+		outbound:     make(chan ServerMessage),
+		done:         done,
+	}
+}
+
+// scheduleBot lets a bot draw a card if it holds the current seat.
+func (r *Room) scheduleBot() {
+	if r.GamePhase != GamePhasePlaying || r.Paused {
+		return
+	}
+
+	p := r.currentPlayer()
+	if p == nil || !p.Bot {
+		return
+	}
+
+	Clock.AfterFunc(botDelay, func() {
+		r.post(ClientDraw{Player: p, bot: true})
+	})
+}
@@ -0,0 +1,158 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startTestGame(t *testing.T, players ...*Player) *Room {
+	t.Helper()
+	r := newTestRoom(t)
+	r.Decks = append(r.Decks, newTestDeck(20))
+	for _, p := range players {
+		joinTestRoom(t, r, p, false)
+	}
+	r.HandleStart(ClientStart{Player: players[0]})
+	for _, p := range players {
+		receiveUntil[*ServerStart](t, p)
+	}
+	return r
+}
+
+func TestDisconnectPause(t *testing.T) {
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+
+	close(other.done)
+	r.HandleDisconnect(clientDisconnect{other})
+
+	pause := receiveUntil[*ServerPause](t, owner)
+	assert.Equal(t, other.Id, pause.PlayerId)
+	assert.True(t, r.Paused, "room should be paused")
+	assert.Contains(t, r.Players, other, "disconnected player should keep their seat")
+
+	r.CurrentTurn = 0
+	r.HandleDraw(ClientDraw{Player: owner})
+	err := receiveUntil[*ServerError](t, owner)
+	assert.Equal(t, "game is paused", err.Message)
+
+	reconnected := newTestPlayer("p_reconnected")
+	reconnected.setRoom(r)
+	r.HandleJoin(ClientJoin{Player: reconnected, RoomId: r.Id, Token: other.token})
+
+	ack := receiveUntil[*ServerAck](t, reconnected)
+	assert.Equal(t, other.token, ack.Token)
+	assert.Equal(t, other.Id, reconnected.Id, "reconnected player should take over the seat")
+	assert.Equal(t, other.Id, receiveUntil[*ServerReconnect](t, owner).Id)
+	receiveUntil[*ServerResume](t, owner)
+	assert.False(t, r.Paused, "room should resume once everyone is back")
+	assert.Contains(t, r.Players, reconnected)
+}
+
+func TestReconnectWhileChatting(t *testing.T) {
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+	close(other.done)
+	r.HandleDisconnect(clientDisconnect{other})
+
+	h := newTestHub()
+	reconnected := newTestPlayer("p_reconnected")
+	h.handleChannelJoin(ClientChannelJoin{Player: reconnected, Channel: LobbyChannel})
+	reconnected.setRoom(r)
+
+	// the room hands the seat over while the hub handles the player's chat
+	done := make(chan struct{})
+	go func() {
+		r.HandleJoin(ClientJoin{Player: reconnected, RoomId: r.Id, Token: other.token})
+		close(done)
+	}()
+	for i := 0; i < 5; i++ {
+		h.handleChannelChat(ClientChannelChat{Player: reconnected, Channel: LobbyChannel, Message: "back"})
+		h.handleMute(ClientMute{Player: reconnected, Id: "p_owner", Mute: false})
+	}
+	<-done
+
+	h.handleChannelChat(ClientChannelChat{Player: reconnected, Channel: LobbyChannel, Message: "back"})
+	history := h.Channels[LobbyChannel].history
+	assert.Equal(t, other.Id, history[len(history)-1].PlayerId, "chat should come from the reclaimed seat")
+}
+
+func TestDisconnectBadToken(t *testing.T) {
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+
+	intruder := newTestPlayer("p_intruder")
+	intruder.setRoom(r)
+	r.HandleJoin(ClientJoin{Player: intruder, RoomId: r.Id, Token: other.token})
+	receiveUntil[*ServerError](t, intruder)
+	assert.Nil(t, intruder.currentRoom(), "connected seats cannot be reclaimed")
+}
+
+func TestResumeWithBot(t *testing.T) {
+	botDelay = time.Millisecond
+
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+
+	close(other.done)
+	r.HandleDisconnect(clientDisconnect{other})
+	receiveUntil[*ServerPause](t, owner)
+
+	r.HandlePauseExpired(clientPauseExpired{})
+	receiveUntil[*ServerPauseExpired](t, owner)
+
+	r.CurrentTurn = 1
+	r.HandleResume(ClientResume{Player: owner, Mode: ResumeModeBot})
+	receiveUntil[*ServerResume](t, owner)
+	assert.True(t, other.Bot, "disconnected seat should be taken by a bot")
+
+	draw := receiveUntil[*ServerDraw](t, owner)
+	assert.Equal(t, other.Id, draw.PlayerId, "bot should take its turn")
+}
+
+func TestResumeVoid(t *testing.T) {
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+
+	close(other.done)
+	r.HandleDisconnect(clientDisconnect{other})
+	receiveUntil[*ServerPause](t, owner)
+
+	r.HandleResume(ClientResume{Player: other, Mode: ResumeModeVoid})
+	assert.True(t, r.Paused, "only the owner can resume")
+
+	r.HandleResume(ClientResume{Player: owner, Mode: ResumeModeVoid})
+	receiveUntil[*ServerVoid](t, owner)
+	assert.False(t, r.Paused)
+	assert.Equal(t, GamePhaseLobby, r.GamePhase)
+	assert.NotContains(t, r.Players, other, "disconnected players should be removed")
+}
+
+func TestDisconnectOwner(t *testing.T) {
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+
+	close(owner.done)
+	r.HandleDisconnect(clientDisconnect{owner})
+	receiveUntil[*ServerPause](t, other)
+	assert.Equal(t, other.Id, r.OwnerId, "ownership should move to a connected player")
+}
+
+func TestDisconnectLobby(t *testing.T) {
+	r := newTestRoom(t)
+	owner := newTestPlayer("p_owner")
+	joinTestRoom(t, r, owner, false)
+
+	close(owner.done)
+	r.HandleDisconnect(clientDisconnect{owner})
+	assert.NotContains(t, r.Players, owner, "disconnecting in the lobby leaves the room")
+	assert.False(t, r.Paused)
+}
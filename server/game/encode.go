@@ -0,0 +1,80 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// fieldAppender is implemented by the messages sent often enough, like turns, to be worth
+// encoding by hand rather than through reflection. appendFields appends the message's JSON
+// fields, sorted by key like encoding/json sorts map keys, without braces.
+type fieldAppender interface {
+	ServerMessage
+	appendFields(b []byte) []byte
+}
+
+func (s ServerTurn) appendFields(b []byte) []byte {
+	b = append(b, `"playerId":`...)
+	return appendString(b, s.PlayerId)
+}
+
+func (s ServerStart) appendFields(b []byte) []byte {
+	b = append(b, `"currentTurn":`...)
+	return strconv.AppendInt(b, int64(s.CurrentTurn), 10)
+}
+
+func (s ServerTurnTimeout) appendFields(b []byte) []byte {
+	b = append(b, `"missed":`...)
+	b = strconv.AppendInt(b, int64(s.Missed), 10)
+	b = append(b, `,"playerId":`...)
+	return appendString(b, s.PlayerId)
+}
+
+func (s ServerAfk) appendFields(b []byte) []byte {
+	b = append(b, `"afk":`...)
+	b = strconv.AppendBool(b, s.Afk)
+	b = append(b, `,"playerId":`...)
+	return appendString(b, s.PlayerId)
+}
+
+// appendString appends s as a JSON string, escaped the way encoding/json escapes it.
+func appendString(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			// rare for the ids and names sent in these messages
+			escaped, _ := json.Marshal(s)
+			return append(b, escaped...)
+		}
+	}
+	b = append(b, '"')
+	b = append(b, s...)
+	return append(b, '"')
+}
+
+// encodeFields writes a message encoded by hand, in the same format as encodeMessage, its
+// fields followed by the room and the type.
+func encodeFields(w io.Writer, message fieldAppender, room *Room) error {
+	// the room is encoded into a pooled buffer too, which json.Marshal can't do
+	s := getBuffer()
+	defer putBuffer(s)
+	if err := json.NewEncoder(s).Encode(room); err != nil {
+		return err
+	}
+	state := bytes.TrimSuffix(s.Bytes(), []byte("\n"))
+
+	b := getBuffer()
+	defer putBuffer(b)
+	b.Grow(len(state) + 128)
+	data := b.AvailableBuffer()
+	data = append(data, '{')
+	data = message.appendFields(data)
+	data = append(data, `,"room":`...)
+	data = append(data, state...)
+	data = append(data, `,"type":`...)
+	data = appendString(data, message.ServerType())
+	data = append(data, "}\n"...)
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,58 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeReflect encodes a message the general way, for comparison with the hand-written encoders.
+func encodeReflect(w io.Writer, message ServerMessage, room *Room) error {
+	m := messageMap(message)
+	m["room"] = room
+	return json.NewEncoder(w).Encode(m)
+}
+
+func TestEncodeFields(t *testing.T) {
+	r := newTestRoom(t)
+	p := newTestPlayer("p_a")
+	joinTestRoom(t, r, p, false)
+
+	for _, message := range []fieldAppender{
+		&ServerTurn{PlayerId: "p_a"},
+		&ServerTurn{PlayerId: "<\"odd\" & \\ é\n>"},
+		&ServerStart{CurrentTurn: 3},
+		&ServerTurnTimeout{PlayerId: "p_a", Missed: 2},
+		&ServerAfk{PlayerId: "p_a", Afk: true},
+	} {
+		var want, got bytes.Buffer
+		require.NoError(t, encodeReflect(&want, message, r))
+		require.NoError(t, encodeMessage(&got, message, r))
+		assert.Equal(t, want.String(), got.String(), "%T", message)
+	}
+
+	var got bytes.Buffer
+	require.NoError(t, encodeMessage(&got, &ServerTurn{PlayerId: "p_a"}, nil))
+	assert.JSONEq(t, `{"playerId":"p_a","room":null,"type":"turn"}`, got.String())
+}
+
+func benchmarkEncode(b *testing.B, encode func(io.Writer, ServerMessage, *Room) error) {
+	r := HubMain.NewRoom("")
+	b.Cleanup(func() { HubMain.RemoveRoom(r.Id) })
+	for i := 0; i < 8; i++ {
+		r.Players = append(r.Players, newTestPlayer("p_"+string(rune('a'+i))))
+	}
+	message := &ServerTurn{PlayerId: "p_a"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encode(io.Discard, message, r)
+	}
+}
+
+func BenchmarkEncodeTurn(b *testing.B)        { benchmarkEncode(b, encodeMessage) }
+func BenchmarkEncodeTurnReflect(b *testing.B) { benchmarkEncode(b, encodeReflect) }
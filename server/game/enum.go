@@ -1,5 +1,15 @@
 package game
 
+// GameType identifies the rules a room is played with.
+type GameType string
+
+const (
+	GameTypeClassic GameType = "classic"
+)
+
+// AllGameTypes lists every game type the server can host.
+var AllGameTypes = []GameType{GameTypeClassic}
+
 type PlayMode int
 
 const (
@@ -33,3 +43,43 @@ var TSAllGamePhases = []struct {
 	{GamePhasePlaying, "Playing"},
 	{GamePhaseEnd, "End"},
 }
+
+// AfkPolicy decides what happens to the seat of a player removed from a game in progress.
+type AfkPolicy int
+
+const (
+	AfkPolicySeatOpen AfkPolicy = iota
+	AfkPolicyBotFill
+)
+
+var TSAllAfkPolicies = []struct {
+	Value  AfkPolicy
+	TSName string
+}{
+	{AfkPolicySeatOpen, "SeatOpen"},
+	{AfkPolicyBotFill, "BotFill"},
+}
+
+// VoteKind is what a vote decides.
+type VoteKind string
+
+const (
+	VoteKindKick    VoteKind = "kick"    // remove a player from the room
+	VoteKindSuspend VoteKind = "suspend" // save the game to resume it later
+)
+
+// ResumeMode is the owner's choice for how to continue a game paused by a disconnect.
+type ResumeMode int
+
+const (
+	ResumeModeBot ResumeMode = iota
+	ResumeModeVoid
+)
+
+var TSAllResumeModes = []struct {
+	Value  ResumeMode
+	TSName string
+}{
+	{ResumeModeBot, "Bot"},
+	{ResumeModeVoid, "Void"},
+}
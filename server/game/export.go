@@ -0,0 +1,214 @@
+package game
+
+import (
+	"cardgame/locale"
+	"cardgame/storage"
+	"encoding/json"
+	"fmt"
+)
+
+// ReplayExport is a replay put into words, with the table after every event, so it can be
+// watched without the game: shared as a page, or looked through for its key moments.
+type ReplayExport struct {
+	MatchId string                `json:"matchId"`
+	Results []storage.MatchPlayer `json:"results"` // ordered by rank
+	Frames  []ReplayFrame         `json:"frames"`
+}
+
+// ReplayFrame is an event of a replay and the table after it.
+type ReplayFrame struct {
+	Time        int64                 `json:"time"` // ms since the game started
+	Type        string                `json:"type"` // of the event
+	Description string                `json:"description"`
+	Key         bool                  `json:"key"`                // the start, wild cards, sends and the end
+	Turn        string                `json:"turn,omitempty"`     // id of the player whose turn it is
+	WildCard    string                `json:"wildCard,omitempty"` // the active wild card
+	Hands       map[string]ReplayHand `json:"hands"`              // by player id
+}
+
+// ReplayHand is what can be seen of a player's hand.
+type ReplayHand struct {
+	Name   string `json:"name"`
+	Top    string `json:"top,omitempty"`    // top card
+	Symbol string `json:"symbol,omitempty"` // of the type of the top card
+	Score  int    `json:"score"`
+}
+
+// ExportReplay puts the events of a replay into words in a language. Replays only hold what
+// everyone in the room could see, so the hands are their top cards and the scores.
+func ExportReplay(replay *storage.Replay, lang string) (*ReplayExport, error) {
+	var events []struct {
+		Time    int64           `json:"time"`
+		Message json.RawMessage `json:"message"`
+	}
+	if err := json.Unmarshal(replay.Events, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode replay: %w", err)
+	}
+
+	e := &ReplayExport{MatchId: replay.MatchId, Results: []storage.MatchPlayer{}, Frames: []ReplayFrame{}}
+	types := make([]string, len(events))
+	for i, event := range events {
+		var m struct {
+			Type    string                `json:"type"`
+			Results []storage.MatchPlayer `json:"results"`
+		}
+		if err := json.Unmarshal(event.Message, &m); err != nil {
+			return nil, fmt.Errorf("failed to decode event %d: %w", i, err)
+		}
+		types[i] = m.Type
+		if m.Type == "end" {
+			e.Results = m.Results
+		}
+	}
+
+	// the players are only named in the results, and when they join
+	hands := map[string]ReplayHand{}
+	for _, p := range e.Results {
+		hands[p.Id] = ReplayHand{Name: p.Name}
+	}
+	name := func(id string) string {
+		if h, ok := hands[id]; ok && h.Name != "" {
+			return h.Name
+		}
+		return id
+	}
+
+	turn, wild := "", ""
+	for i, event := range events {
+		var n *narration
+		key := false
+		switch types[i] {
+		case "join":
+			m := &ServerJoin{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			if !m.Spectator {
+				hands[m.Id] = ReplayHand{Name: m.Player.Name}
+				n = narrate("event_join", locale.Params{"player": m.Player.Name})
+			}
+		case "leave":
+			m := &ServerLeave{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			n = narrate("event_leave", locale.Params{"player": name(m.Id)})
+		case "start":
+			// the start only has the index of the first player, who is the first to act
+			for _, next := range events[i+1:] {
+				var m struct {
+					PlayerId string `json:"playerId"`
+				}
+				if json.Unmarshal(next.Message, &m) == nil && m.PlayerId != "" {
+					turn = m.PlayerId
+					break
+				}
+			}
+			key = true
+			n = narrate("event_start", locale.Params{"player": name(turn)})
+		case "turn":
+			m := &ServerTurn{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			turn = m.PlayerId
+			n = narrate("event_turn", locale.Params{"player": name(m.PlayerId)})
+		case "draw":
+			m := &ServerDraw{}
+			if err := json.Unmarshal(event.Message, m); err != nil || m.Card == nil {
+				break
+			}
+			h := hands[m.PlayerId]
+			h.Top, h.Symbol = describeCard(lang, m.Card), m.Card.Type.String()
+			hands[m.PlayerId] = h
+			n = narrate("event_draw", locale.Params{"player": name(m.PlayerId)}).withCard(m.Card)
+		case "wild_card":
+			m := &ServerWildCard{}
+			if err := json.Unmarshal(event.Message, m); err != nil || m.Card == nil || len(m.Card.Types) != 2 {
+				break
+			}
+			wild, key = describeCard(lang, m.Card), true
+			n = narrate("event_wild_card", locale.Params{"player": name(m.PlayerId)}).withCard(m.Card)
+		case "send":
+			m := &ServerSend{}
+			if err := json.Unmarshal(event.Message, m); err != nil || m.Card == nil {
+				break
+			}
+			h := hands[m.RecipientId]
+			h.Top, h.Symbol = describeCard(lang, m.Card), m.Card.Type.String()
+			h.Score++
+			hands[m.RecipientId] = h
+			key = true
+			n = narrate("event_send", locale.Params{
+				"sender":    name(m.SenderId),
+				"recipient": name(m.RecipientId),
+			}).withCard(m.Card)
+		case "resync":
+			// the sender's new top card is only known from the resync after a send
+			m := &ServerResync{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			for id, c := range m.TopCards {
+				h := hands[id]
+				h.Top, h.Symbol = "", ""
+				if c != nil {
+					h.Top, h.Symbol = describeCard(lang, c), c.Type.String()
+				}
+				hands[id] = h
+			}
+		case "turn_timeout":
+			m := &ServerTurnTimeout{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			n = narrate("event_turn_timeout", locale.Params{"player": name(m.PlayerId)})
+		case "pause":
+			m := &ServerPause{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			n = narrate("event_pause", locale.Params{"player": name(m.PlayerId)})
+		case "reconnect":
+			m := &ServerReconnect{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			n = narrate("event_reconnect", locale.Params{"player": name(m.Id)})
+		case "resume":
+			n = narrate("event_resume", nil)
+		case "afk":
+			m := &ServerAfk{}
+			if err := json.Unmarshal(event.Message, m); err != nil {
+				break
+			}
+			if m.Afk {
+				n = narrate("event_afk", locale.Params{"player": name(m.PlayerId)})
+			} else {
+				n = narrate("event_back", locale.Params{"player": name(m.PlayerId)})
+			}
+		case "end":
+			key = true
+			n = narrateEnd(e.Results)
+		}
+		if n == nil {
+			// nothing happened to describe
+			continue
+		}
+
+		frame := ReplayFrame{
+			Time:        event.Time,
+			Type:        types[i],
+			Description: n.describe(lang),
+			Key:         key,
+			Turn:        turn,
+			WildCard:    wild,
+			Hands:       map[string]ReplayHand{},
+		}
+		for id, h := range hands {
+			frame.Hands[id] = h
+		}
+		e.Frames = append(e.Frames, frame)
+	}
+	return e, nil
+}
@@ -0,0 +1,60 @@
+package game
+
+import (
+	"cardgame/card"
+	"cardgame/storage"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportReplay(t *testing.T) {
+	s := withTestStore(t)
+	owner, other := newTestPlayer("p_owner"), newTestPlayer("p_other")
+	owner.Name, other.Name = "Ann", "Bob"
+	r := startTestGame(t, owner, other)
+
+	r.CurrentTurn = 0
+	r.HandleDraw(ClientDraw{Player: owner})
+	receiveUntil[*ServerTurn](t, owner)
+	owner.Hand = PlayerHand{{Id: "c_1", Type: card.Star, Category: "Mountain Range"}}
+	other.Hand = PlayerHand{{Id: "c_2", Type: card.Star, Category: "River"}}
+	r.HandleSend(ClientSend{Player: owner, RecipientId: other.Id})
+	receiveUntil[*ServerSend](t, owner)
+	r.HandleEnd(ClientEnd{Player: owner})
+	end := receiveUntil[*ServerEnd](t, owner)
+
+	replay, err := s.Replay(end.MatchId)
+	if !assert.NoError(t, err) {
+		return
+	}
+	e, err := ExportReplay(replay, "en")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, end.MatchId, e.MatchId)
+	assert.Len(t, e.Results, 2)
+
+	descriptions := []string{}
+	key := []string{}
+	for _, f := range e.Frames {
+		descriptions = append(descriptions, f.Description)
+		if f.Key {
+			key = append(key, f.Type)
+		}
+	}
+	assert.Contains(t, descriptions, "The game started, Ann goes first")
+	assert.Contains(t, descriptions, "Ann sent Mountain Range (star) to Bob")
+	assert.Equal(t, "The game is over, Bob won", descriptions[len(descriptions)-1])
+	assert.Contains(t, key, "start")
+	assert.Contains(t, key, "send")
+	assert.NotContains(t, key, "turn", "turns are not key moments")
+
+	last := e.Frames[len(e.Frames)-1]
+	assert.Equal(t, ReplayHand{Name: "Bob", Top: "Mountain Range (star)", Symbol: "☆", Score: 1}, last.Hands[other.Id])
+	assert.Equal(t, "Ann", last.Hands[owner.Id].Name)
+
+	_, err = ExportReplay(&storage.Replay{Events: json.RawMessage(`not json`)}, "en")
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package game
+
+import (
+	"cardgame/locale"
+	"cardgame/storage"
+	"errors"
+	"log/slog"
+)
+
+// signIn links a player to the account of a session's token and shows the account as online.
+// If the token is invalid, the player is told and false is returned.
+func (h *Hub) signIn(p *Player, token string) bool {
+	session, err := storage.Default.SessionByToken(storage.HashToken(token))
+	var a *storage.Account
+	if err == nil {
+		a, err = storage.Default.Account(session.AccountId)
+	}
+	if err != nil {
+		slog.Error("failed to load account", "err", err)
+		p.notify(&ServerError{Id: "account_not_found"})
+		return false
+	}
+	if a.Deleted != 0 {
+		p.notify(&ServerError{Id: "account_deleted"})
+		return false
+	}
+
+	if p.AccountId != a.Id {
+		h.signOut(p)
+	}
+	p.AccountId = a.Id
+	p.Name = a.Name
+	p.Avatar = AvatarConfig(a.Avatar)
+	p.AvatarUrl = AvatarUrl(a)
+	p.CardBack = a.CardBack
+	p.TableTheme = a.TableTheme
+	blocks, err := loadBlocks(a.Id)
+	if err != nil {
+		// chat still works, only without hiding anyone
+		slog.Error("failed to load blocks", "err", err)
+		blocks = &blockList{ids: set{}}
+	}
+	p.blocks = blocks
+
+	h.onlineMu.Lock()
+	if h.online == nil {
+		h.online = make(map[string]map[*Player]struct{})
+	}
+	connections, ok := h.online[a.Id]
+	if !ok {
+		connections = make(map[*Player]struct{})
+		h.online[a.Id] = connections
+	}
+	connections[p] = struct{}{}
+	p.sessionId = session.Id
+	h.onlineMu.Unlock()
+
+	if !ok {
+		h.notifyFriends(a.Id, &ServerPresence{AccountId: a.Id, Online: true})
+	}
+	return true
+}
+
+// signOut stops counting a player's connection towards their account being online.
+func (h *Hub) signOut(p *Player) {
+	if p.AccountId == "" {
+		return
+	}
+
+	h.onlineMu.Lock()
+	connections := h.online[p.AccountId]
+	_, ok := connections[p]
+	delete(connections, p)
+	last := ok && len(connections) == 0
+	if last {
+		delete(h.online, p.AccountId)
+	}
+	h.onlineMu.Unlock()
+
+	if last {
+		h.notifyFriends(p.AccountId, &ServerPresence{AccountId: p.AccountId, Online: false})
+	}
+}
+
+// Online reports whether an account is signed in on any connection.
+func (h *Hub) Online(accountId string) bool {
+	h.onlineMu.RLock()
+	defer h.onlineMu.RUnlock()
+	return len(h.online[accountId]) > 0
+}
+
+// Notify sends a message to every connection an account is signed in on,
+// and reports whether there were any.
+func (h *Hub) Notify(accountId string, message ServerMessage) bool {
+	h.onlineMu.RLock()
+	connections := make([]*Player, 0, len(h.online[accountId]))
+	for p := range h.online[accountId] {
+		connections = append(connections, p)
+	}
+	h.onlineMu.RUnlock()
+
+	for _, p := range connections {
+		p.notify(message)
+	}
+	return len(connections) > 0
+}
+
+// friends returns the ids of the accounts that accepted friendships with an account.
+func friends(accountId string) ([]string, error) {
+	friendships, err := storage.Default.Friendships(accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, f := range friendships {
+		if f.Accepted == 0 {
+			continue
+		}
+		if f.AccountId == accountId {
+			ids = append(ids, f.FriendId)
+		} else {
+			ids = append(ids, f.AccountId)
+		}
+	}
+	return ids, nil
+}
+
+func (h *Hub) notifyFriends(accountId string, message ServerMessage) {
+	ids, err := friends(accountId)
+	if err != nil {
+		slog.Error("failed to load friends", "err", err)
+		return
+	}
+	for _, id := range ids {
+		h.Notify(id, message)
+	}
+}
+
+func (h *Hub) handleSignIn(msg ClientSignIn) {
+	p := msg.Player
+	if p.currentRoom() != nil {
+		// the room's goroutine reads the player's account, so it can only change between rooms
+		p.notify(&ServerError{Id: "sign_in_room"})
+		return
+	}
+	if !h.signIn(p, msg.Token) {
+		return
+	}
+
+	ids, err := friends(p.AccountId)
+	if err != nil {
+		slog.Error("failed to load friends", "err", err)
+	}
+	online := []string{}
+	for _, id := range ids {
+		if h.Online(id) {
+			online = append(online, id)
+		}
+	}
+	p.notify(&ServerSignIn{AccountId: p.AccountId, Online: online})
+}
+
+// canInvite reports whether an account accepts invites from another.
+func canInvite(from string, to *storage.Account) (bool, error) {
+	switch to.Invites {
+	case storage.InvitesEveryone:
+		return true, nil
+	case storage.InvitesNobody:
+		return false, nil
+	}
+
+	f, err := storage.Default.Friendship(from, to.Id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return f.Accepted != 0, nil
+}
+
+func (h *Hub) handleInvite(msg ClientInvite) {
+	p := msg.Player
+	accountId := p.identity().accountId
+
+	if accountId == "" {
+		p.notify(&ServerError{Id: "invite_sign_in"})
+		return
+	}
+	if p.currentRoom() == nil {
+		p.notify(&ServerError{Id: "not_in_room"})
+		return
+	}
+	if msg.AccountId == accountId {
+		p.notify(&ServerError{Id: "invite_self"})
+		return
+	}
+
+	to, err := storage.Default.Account(msg.AccountId)
+	if errors.Is(err, storage.ErrNotFound) {
+		p.notify(&ServerError{Id: "account_not_found"})
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load account", "err", err)
+		p.notify(&ServerError{Id: "invite_failed"})
+		return
+	}
+
+	if blocked, err := storage.Default.Blocked(to.Id, accountId); err != nil || blocked {
+		if err != nil {
+			slog.Error("failed to load block", "err", err)
+		}
+		p.notify(&ServerError{Id: "invite_refused", Params: locale.Params{"name": to.Name}})
+		return
+	}
+
+	ok, err := canInvite(accountId, to)
+	if err != nil {
+		slog.Error("failed to load friendship", "err", err)
+		p.notify(&ServerError{Id: "invite_failed"})
+		return
+	}
+	if !ok {
+		p.notify(&ServerError{Id: "invite_refused", Params: locale.Params{"name": to.Name}})
+		return
+	}
+
+	if r := p.currentRoom(); r == nil || !r.post(clientInvite{Player: p, To: to, hub: h}) {
+		p.notify(&ServerError{Id: "not_in_room"})
+	}
+}
+
+// HandleInvite sends an invite into the room. Accounts invited by the owner can join without
+// the password; the others are only told about the room, so players can't let others past
+// the password the owner set.
+func (r *Room) HandleInvite(message clientInvite) {
+	p, to := message.Player, message.To
+
+	if p.Id == r.OwnerId {
+		r.mu.Lock()
+		if r.invited == nil {
+			r.invited = set{}
+		}
+		r.invited[to.Id] = struct{}{}
+		r.mu.Unlock()
+	}
+
+	sent := message.hub.Notify(to.Id, &ServerInvite{
+		RoomId:    r.Id,
+		RoomName:  r.Name,
+		AccountId: p.AccountId,
+		Name:      p.Name,
+	})
+	if !sent {
+		p.send(&ServerError{Id: "invite_offline", Params: locale.Params{"name": to.Name}})
+	}
+}
+
+// isInvited reports whether a player's account was invited into the room.
+func (r *Room) isInvited(p *Player) bool {
+	if p.AccountId == "" {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.invited[p.AccountId]
+	return ok
+}
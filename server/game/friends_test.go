@@ -0,0 +1,126 @@
+package game
+
+import (
+	"cardgame/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFriendPresenceAndInvites(t *testing.T) {
+	s := withTestStore(t)
+	saveTestAccount(s, &storage.Account{Id: "u_a", Name: "alice"}, "t_a")
+	saveTestAccount(s, &storage.Account{Id: "u_b", Name: "bob"}, "t_b")
+	saveTestAccount(s, &storage.Account{Id: "u_c", Name: "carol", Invites: storage.InvitesNobody}, "t_c")
+	s.SaveFriendship(&storage.Friendship{AccountId: "u_a", FriendId: "u_b", Accepted: 1})
+
+	h := newTestHub()
+	a, b, c := newTestPlayer("p_a"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	h.handleSignIn(ClientSignIn{Player: a, Token: "t_a"})
+	assert.Empty(t, receiveUntil[*ServerSignIn](t, a).Online)
+	h.handleSignIn(ClientSignIn{Player: b, Token: "t_b"})
+	assert.Equal(t, []string{"u_a"}, receiveUntil[*ServerSignIn](t, b).Online)
+	presence := receiveUntil[*ServerPresence](t, a)
+	assert.Equal(t, "u_b", presence.AccountId)
+	assert.True(t, presence.Online)
+	h.handleSignIn(ClientSignIn{Player: c, Token: "t_c"})
+	receiveUntil[*ServerSignIn](t, c)
+
+	h.handleSignIn(ClientSignIn{Player: newTestPlayer("p_x"), Token: "t_wrong"})
+	assert.False(t, h.Online(""))
+
+	r := newTestRoom(t)
+	r.SetPassword("secret")
+	h.AddRoom(r)
+	h.handleJoin(ClientJoin{Player: a, RoomId: r.Id, Password: "secret"})
+	receiveUntil[*ServerAck](t, a)
+
+	h.handleInvite(ClientInvite{Player: a, AccountId: "u_c"})
+	assert.Equal(t, "carol is not accepting invites from you", receiveUntil[*ServerError](t, a).Message)
+
+	h.handleInvite(ClientInvite{Player: a, AccountId: "u_b"})
+	invite := receiveUntil[*ServerInvite](t, b)
+	assert.Equal(t, r.Id, invite.RoomId)
+	assert.Equal(t, "alice", invite.Name)
+
+	h.handleJoin(ClientJoin{Player: b, RoomId: r.Id})
+	receiveUntil[*ServerAck](t, b)
+	assert.Contains(t, r.Players, b, "invited players should not need the password")
+
+	h.handleDisconnect(clientDisconnect{b})
+	assert.False(t, receiveUntil[*ServerPresence](t, a).Online)
+	assert.False(t, h.Online("u_b"))
+}
+
+func TestInviteNotOwner(t *testing.T) {
+	s := withTestStore(t)
+	saveTestAccount(s, &storage.Account{Id: "u_a", Name: "alice"}, "t_a")
+	saveTestAccount(s, &storage.Account{Id: "u_b", Name: "bob"}, "t_b")
+	saveTestAccount(s, &storage.Account{Id: "u_c", Name: "carol", Invites: storage.InvitesEveryone}, "t_c")
+
+	h := newTestHub()
+	owner, b, c := newTestPlayer("p_owner"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	h.handleSignIn(ClientSignIn{Player: b, Token: "t_b"})
+	h.handleSignIn(ClientSignIn{Player: c, Token: "t_c"})
+
+	r := newTestRoom(t)
+	r.SetPassword("secret")
+	h.AddRoom(r)
+	h.handleJoin(ClientJoin{Player: owner, RoomId: r.Id, Password: "secret"})
+	receiveUntil[*ServerAck](t, owner)
+	h.handleJoin(ClientJoin{Player: b, RoomId: r.Id, Password: "secret"})
+	receiveUntil[*ServerAck](t, b)
+
+	h.handleInvite(ClientInvite{Player: b, AccountId: "u_c"})
+	assert.Equal(t, r.Id, receiveUntil[*ServerInvite](t, c).RoomId, "other players should still be able to tell others about the room")
+	h.handleJoin(ClientJoin{Player: c, RoomId: r.Id})
+	assert.Equal(t, "Incorrect password", receiveUntil[*ServerError](t, c).Message, "only invites from the owner should skip the password")
+}
+
+func TestInviteNotFriends(t *testing.T) {
+	s := withTestStore(t)
+	saveTestAccount(s, &storage.Account{Id: "u_a", Name: "alice"}, "t_a")
+	saveTestAccount(s, &storage.Account{Id: "u_b", Name: "bob"}, "t_b")
+
+	h := newTestHub()
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	h.handleSignIn(ClientSignIn{Player: a, Token: "t_a"})
+	h.handleSignIn(ClientSignIn{Player: b, Token: "t_b"})
+	a.setRoom(newTestRoom(t))
+
+	h.handleInvite(ClientInvite{Player: a, AccountId: "u_b"})
+	assert.Equal(t, "bob is not accepting invites from you", receiveUntil[*ServerError](t, a).Message,
+		"only friends should be able to invite by default")
+
+	saveTestAccount(s, &storage.Account{Id: "u_b", Name: "bob", Invites: storage.InvitesEveryone}, "t_b")
+	h.handleInvite(ClientInvite{Player: a, AccountId: "u_b"})
+	receiveUntil[*ServerInvite](t, b)
+}
+
+func TestSignInDeletedAccount(t *testing.T) {
+	s := withTestStore(t)
+	saveTestAccount(s, &storage.Account{Id: "u_a", Name: "alice", Deleted: 1}, "t_a")
+
+	h := newTestHub()
+	a := newTestPlayer("p_a")
+	h.handleSignIn(ClientSignIn{Player: a, Token: "t_a"})
+	assert.Equal(t, "Account was deleted", receiveUntil[*ServerError](t, a).Message)
+	assert.False(t, h.Online("u_a"))
+}
+
+func TestSignInRoom(t *testing.T) {
+	s := withTestStore(t)
+	saveTestAccount(s, &storage.Account{Id: "u_a", Name: "alice"}, "t_a")
+
+	h := newTestHub()
+	r := newTestRoom(t)
+	h.AddRoom(r)
+	a := newTestPlayer("p_a")
+	h.handleJoin(ClientJoin{Player: a, RoomId: r.Id})
+	receiveUntil[*ServerAck](t, a)
+
+	h.handleSignIn(ClientSignIn{Player: a, Token: "t_a"})
+	assert.Equal(t, "Leave the room before signing in", receiveUntil[*ServerError](t, a).Message)
+	assert.False(t, h.Online("u_a"))
+	assert.Empty(t, a.AccountId, "the room's goroutine reads the account of its players")
+}
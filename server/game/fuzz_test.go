@@ -0,0 +1,217 @@
+package game
+
+import (
+	"cardgame/deck"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fuzzPlayers is the number of connections the dispatch fuzzer plays with.
+const fuzzPlayers = 3
+
+// fuzzSeeds are message sequences the fuzzers start from. Each line is a player
+// index followed by the raw message that player sends. Lines starting with "!"
+// inject the room's internal events instead.
+var fuzzSeeds = []string{
+	`0{"type":"join","roomId":"r_fuzz"}
+1{"type":"join","roomId":"r_fuzz"}
+2{"type":"join","roomId":"r_fuzz","spectate":true}
+0{"type":"start"}
+0{"type":"draw"}
+1{"type":"draw"}
+0{"type":"send","recipientId":"p_1"}
+1{"type":"chat","message":"gg"}
+0{"type":"end"}
+0{"type":"start"}`,
+	`0{"type":"send","recipientId":"p_1"}
+0{"type":"draw"}
+0{"type":"start"}
+0{"type":"vote"}
+0{"type":"resume","mode":1}
+0{"type":"leave"}`,
+	`0{"type":"join","roomId":"r_fuzz"}
+1{"type":"join","roomId":"r_fuzz"}
+0{"type":"start"}
+!disconnect 1
+0{"type":"draw"}
+1{"type":"join","roomId":"r_fuzz"}
+!pause_expired
+0{"type":"resume","mode":0}
+!turn_timeout
+0{"type":"vote_kick","id":"p_1"}
+!vote_expired`,
+	`0{"type":"join","roomId":"r_fuzz"}
+1{"type":"join","roomId":"r_fuzz"}
+2{"type":"join","roomId":"r_fuzz"}
+0{"type":"change_details","maxPlayers":-1,"turnTimeout":-5,"afkTurns":1,"afkPolicy":1,"addDecks":["nope"]}
+0{"type":"start"}
+1{"type":"vote_kick","id":"p_0"}
+2{"type":"vote","yes":true}
+!turn_timeout
+!turn_timeout
+1{"type":"kick","id":"p_2"}`,
+	`0{"type":"channel_join","channel":"lobby"}
+0{"type":"channel_chat","channel":"lobby","message":"hi"}
+1{"type":"mute","id":"p_0","mute":true}
+1{"type":"channel_leave","channel":"nope"}
+0{"type":"join","roomId":"missing"}`,
+	`0{"type":"join","roomId":"r_fuzz"}
+1{"type":"join","roomId":"r_fuzz","spectate":true}
+1{"type":"join","roomId":"r_fuzz","spectate":true}
+0{"type":"start"}
+0{"type":"leave"}
+1{"type":"draw"}
+1{"type":"send","recipientId":"p_1"}
+!turn_timeout
+1{"type":"vote_kick","id":"p_0"}`,
+	`0{"type":"join","roomId":"r_fuzz"}
+0{"type":"leave"}
+0{"type":"join","roomId":"r_fuzz","spectate":true}
+0{"type":"start"}`,
+	`0{"type":"draw"`,
+	`0{"type":42}`,
+	`0{"type":"chat","message":{"nested":[1,2,3]}}`,
+	`0{"type":"join","roomId":"r_fuzz","name":"` + strings.Repeat("x", 1<<12) + `"}`,
+	`0{"type":"join","roomId":"r_fuzz"}
+0{"type":"chat","message":"` + strings.Repeat("spam ", 1<<12) + `"}`,
+	`0{"type":"disconnect"}
+0{"type":"turn_timeout"}
+0null
+0[]
+0`,
+}
+
+func FuzzClientMessageFromJson(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		for _, line := range strings.Split(seed, "\n") {
+			if len(line) > 0 {
+				f.Add([]byte(line[1:]))
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := newTestPlayer("p_fuzz")
+		msg, err := p.ClientMessageFromJson(data)
+		if (msg == nil) == (err == nil) {
+			t.Fatalf("expected exactly one of a message or an error, got %#v and %v", msg, err)
+		}
+		if msg != nil && reflect.ValueOf(msg).FieldByName("Player").Interface() != p {
+			t.Fatalf("decoded %T is not attributed to its sender", msg)
+		}
+	})
+}
+
+// FuzzDispatch plays adversarial message sequences through a hub and room, and checks
+// that nothing panics and every message sent back to the players can be encoded.
+func FuzzDispatch(f *testing.F) {
+	oldBotDelay := botDelay
+	botDelay = time.Hour
+	f.Cleanup(func() { botDelay = oldBotDelay })
+
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, script string) {
+		h := newTestHub()
+		h.inbound = make(chan *hubMessage, 64)
+
+		r := h.newRoom("")
+		h.RemoveRoom(r.Id)
+		r.Id = "r_fuzz"
+		h.AddRoom(r)
+		r.inbound = make(chan ClientMessage, 64)
+		r.outbound = make(chan *serverPayload, 64)
+		r.TurnTimeout = 0
+		r.Decks = []*deck.Deck{newTestDeck(4)}
+
+		players := make([]*Player, fuzzPlayers)
+		for i := range players {
+			players[i] = newTestPlayer("p_" + string(rune('0'+i)))
+			players[i].token = "t_" + string(rune('0'+i))
+		}
+
+		// settle handles queued messages until the hub and room have nothing left to do,
+		// the same way their goroutines would, but one at a time.
+		settle := func() {
+			for {
+				select {
+				case m := <-h.inbound:
+					h.handle(m)
+				case m := <-r.inbound:
+					r.HandleMessage(m)
+				case payload := <-r.outbound:
+					r.broadcast(payload)
+				default:
+					return
+				}
+			}
+		}
+
+		// drain checks every message queued for the players.
+		drain := func() {
+			for _, p := range players {
+				for len(p.outbound) > 0 {
+					message := <-p.outbound
+					if message == nil {
+						t.Fatalf("nil message sent to %s", p.Id)
+					}
+					if err := encodeMessage(io.Discard, message, p.currentRoom()); err != nil {
+						t.Fatalf("cannot encode %T sent to %s: %v", message, p.Id, err)
+					}
+					if e, ok := unshare(message).(*ServerError); ok && e.Message == "" {
+						t.Fatalf("empty error sent to %s", p.Id)
+					}
+				}
+			}
+		}
+
+		for _, line := range strings.Split(script, "\n") {
+			if line == "" {
+				continue
+			}
+
+			if line[0] == '!' {
+				event, arg, _ := strings.Cut(line[1:], " ")
+				switch event {
+				case "disconnect":
+					if arg == "" {
+						break
+					}
+					if p := players[int(arg[0])%fuzzPlayers]; p.currentRoom() != nil {
+						p.currentRoom().inbound <- clientDisconnect{p}
+					}
+				case "pause_expired":
+					r.inbound <- clientPauseExpired{}
+				case "turn_timeout":
+					r.inbound <- clientTurnTimeout{Seq: r.turnTimerSeq}
+				case "vote_expired":
+					r.inbound <- clientVoteExpired{Vote: r.Vote}
+				}
+			} else {
+				p := players[int(line[0])%fuzzPlayers]
+				h.dispatch(p, []byte(line[1:]))
+			}
+
+			settle()
+			drain()
+		}
+
+		stopTimers(r)
+	})
+}
+
+// stopTimers stops a fuzzed room's timers, so they can't fire into a room no one reads.
+func stopTimers(r *Room) {
+	r.stopTurnTimer()
+	if r.pauseTimer != nil {
+		r.pauseTimer.Stop()
+	}
+	if r.Vote != nil {
+		r.Vote.timer.Stop()
+	}
+}
@@ -1,18 +1,25 @@
 package game
 
 import (
+	"cardgame/analytics"
 	"cardgame/card"
 	"cardgame/deck"
+	"cardgame/locale"
+	"cardgame/storage"
 	"cardgame/util/slices"
 	"math/rand"
-	"time"
 
 	"fmt"
-	"log"
 )
 
 func (r *Room) HandleMessage(message ClientMessage) {
-	fmt.Printf("room<- %#v\n", message)
+	r.actions++
+	r.actionLog = r.logger().With("action", r.actions, "type", message.ClientType())
+	if p := messagePlayer(message); p != nil {
+		r.actionLog = r.actionLog.With("player", p.Id, "account", p.AccountId)
+	}
+	defer func() { r.actionLog = nil }()
+	r.logger().Debug("handling message")
 
 	switch m := message.(type) {
 	case ClientJoin:
@@ -25,35 +32,127 @@ func (r *Room) HandleMessage(message ClientMessage) {
 		r.HandleKick(m)
 	case ClientStart:
 		r.HandleStart(m)
+	case ClientEnd:
+		r.HandleEnd(m)
 	case ClientDraw:
 		r.HandleDraw(m)
 	case ClientSend:
 		r.HandleSend(m)
 	case ClientChat:
 		r.HandleChat(m)
+	case ClientResume:
+		r.HandleResume(m)
+	case clientDisconnect:
+		r.HandleDisconnect(m)
+	case clientPauseExpired:
+		r.HandlePauseExpired(m)
+	case ClientVoteKick:
+		r.HandleVoteKick(m)
+	case ClientVoteSuspend:
+		r.HandleVoteSuspend(m)
+	case ClientVote:
+		r.HandleVote(m)
+	case ClientPredict:
+		r.HandlePredict(m)
+	case clientVoteExpired:
+		r.HandleVoteExpired(m)
+	case clientTurnTimeout:
+		r.HandleTurnTimeout(m)
+	case clientClose:
+		r.HandleClose(m)
+	case clientInspect:
+		m.fn()
+		close(m.done)
+	case clientCatchUp:
+		r.HandleCatchUp(m)
+	case clientInvite:
+		r.HandleInvite(m)
+	case clientPing:
+		close(m.done)
+	case clientSweep:
+		m.done <- r.HandleSweep(m)
+	case ClientReport:
+		r.HandleReport(m)
 	default:
-		fmt.Printf("[error] unhandled message type %T\n", m)
+		r.logger().Error("unhandled message type", "message", fmt.Sprintf("%T", m))
 	}
 }
 
 func (r *Room) HandleJoin(message ClientJoin) {
 	p := message.Player
-	if r != p.room {
-		log.Println("[error] player is in another room")
-		p.outbound <- &ServerError{"player is in another room"}
+	if r != p.currentRoom() {
+		r.logger().Warn("player is in another room")
+		p.send(&ServerError{Id: "player_in_other_room"})
 		return
 	}
 
 	for _, player := range r.Players {
 		if player.Id == p.Id {
-			log.Println("[error] player is already in room")
-			p.outbound <- &ServerError{"player is already in room"}
+			r.logger().Warn("player is already in room")
+			p.send(&ServerError{Id: "player_in_room"})
 			return
 		}
 	}
 
+	if r.isSpectator(p) {
+		r.logger().Warn("player is already in room")
+		p.send(&ServerError{Id: "player_in_room"})
+		return
+	}
+
+	if message.Token != "" {
+		r.reconnect(p, message.Token)
+		return
+	}
+
+	if owner := r.getPlayer(r.OwnerId); owner != nil && owner.blocks.has(p.AccountId) {
+		p.setRoom(nil)
+		p.send(&ServerError{Id: "join_blocked"})
+		return
+	}
+
+	if message.Spectate && r.challenge != nil {
+		// watching would give the deal away
+		p.setRoom(nil)
+		p.send(&ServerError{Id: "challenge_no_spectators"})
+		return
+	}
+
+	if message.Spectate && r.demo != nil {
+		p.setRoom(nil)
+		p.send(&ServerError{Id: "demo_no_spectators"})
+		return
+	}
+
+	if message.Spectate {
+		p.send(&ServerAck{})
+		r.addSpectator(p)
+		r.emit("room_joined", analytics.Props{"playerId": p.Id, "accountId": p.AccountId, "spectator": true})
+		r.publish(&serverPayload{
+			exclude: set{p.Id: {}},
+			message: &ServerJoin{
+				Id:        p.Id,
+				Player:    *p,
+				Spectator: true,
+				narration: narrate("event_spectate", locale.Params{"player": p.Name}),
+			},
+		})
+		return
+	}
+
 	if r.IsFull() {
-		p.outbound <- &ServerError{"Room is full"}
+		p.setRoom(nil)
+		p.send(&ServerError{Id: "room_full"})
+		return
+	}
+
+	if r.resuming != nil && !r.checkResumingSeat(p) {
+		p.setRoom(nil)
+		return
+	}
+
+	if r.challenge != nil && !r.checkChallengeSeat(p) {
+		p.setRoom(nil)
 		return
 	}
 
@@ -64,26 +163,48 @@ func (r *Room) HandleJoin(message ClientJoin) {
 		r.OwnerId = p.Id
 	}
 
-	p.outbound <- &ServerAck{}
-	r.outbound <- &serverPayload{
+	p.send(&ServerAck{Token: p.token})
+	r.emit("room_joined", analytics.Props{"playerId": p.Id, "accountId": p.AccountId, "spectator": false})
+	r.publish(&serverPayload{
 		exclude: set{p.Id: {}},
 		message: &ServerJoin{
-			Id:     p.Id,
-			Player: *p,
+			Id:        p.Id,
+			Player:    *p,
+			narration: narrate("event_join", locale.Params{"player": p.Name}),
 		},
-	}
+	})
 
+	if r.resuming != nil {
+		r.resumeIfReady()
+	}
+	if r.challenge != nil {
+		r.startChallenge()
+	}
+	if r.demo != nil {
+		r.startDemo()
+	}
 }
 
 func (r *Room) HandleLeave(message ClientLeave) {
 	p := message.Player
 
-	slices.Remove(r.Players, p)
+	r.Players = slices.Remove(r.Players, p)
+	r.removeSpectator(p)
+	p.setRoom(nil)
 
-	r.outbound <- &serverPayload{
+	if r.CurrentTurn >= len(r.Players) {
+		r.CurrentTurn = 0
+	}
+
+	r.publish(&serverPayload{
 		message: &ServerLeave{
-			Id: p.Id,
+			Id:        p.Id,
+			narration: narrate("event_leave", locale.Params{"player": p.Name}),
 		},
+	})
+
+	if r.demo != nil {
+		r.endDemo()
 	}
 }
 
@@ -91,8 +212,14 @@ func (r *Room) HandleChangeDetails(message ClientChangeDetails) {
 	p := message.Player
 
 	if p.Id != r.OwnerId {
-		log.Println("[error] player is not owner")
-		p.outbound <- &ServerError{"player is not owner"}
+		r.logger().Warn("player is not owner")
+		p.send(&ServerError{Id: "not_owner"})
+		return
+	}
+
+	if r.demo != nil {
+		r.logger().Warn("demo rooms can't be changed")
+		p.send(&ServerError{Id: "demo_locked"})
 		return
 	}
 
@@ -111,6 +238,21 @@ func (r *Room) HandleChangeDetails(message ClientChangeDetails) {
 	if message.PlayMode != nil {
 		r.PlayMode = *message.PlayMode
 	}
+	if message.DisconnectGrace != nil {
+		r.DisconnectGrace = *message.DisconnectGrace
+	}
+	if message.AfkPolicy != nil {
+		r.AfkPolicy = *message.AfkPolicy
+	}
+	if message.TurnTimeout != nil {
+		r.TurnTimeout = *message.TurnTimeout
+	}
+	if message.AfkTurns != nil {
+		r.AfkTurns = *message.AfkTurns
+	}
+	if message.Ranked != nil && r.GamePhase == GamePhaseLobby {
+		r.Ranked = *message.Ranked
+	}
 	if len(message.AddDecks) > 0 {
 		toAdd := []*deck.Deck{}
 		for _, deckId := range message.AddDecks {
@@ -134,20 +276,27 @@ func (r *Room) HandleKick(message ClientKick) {
 	p := message.Player
 
 	if p.Id != r.OwnerId {
-		log.Println("[error] player is not owner")
-		p.outbound <- &ServerError{"player is not owner"}
+		r.logger().Warn("player is not owner")
+		p.send(&ServerError{Id: "not_owner"})
 		return
 	}
 
 	if message.Id == p.Id {
-		log.Println("[error] player cannot kick themselves")
-		p.outbound <- &ServerError{"player cannot kick themselves"}
+		r.logger().Warn("player cannot kick themselves")
+		p.send(&ServerError{Id: "kick_self"})
+		return
+	}
+
+	reason, err := CleanReason(message.Reason)
+	if err != nil {
+		p.send(serverError(err))
 		return
 	}
 
 	for _, player := range r.Players {
 		if player.Id == message.Id {
-			player.outbound <- &ServerKick{}
+			player.send(&ServerKick{})
+			r.auditKick(storage.AuditKick, p, player, reason)
 			return
 		}
 	}
@@ -157,125 +306,220 @@ func (r *Room) HandleStart(message ClientStart) {
 	p := message.Player
 
 	if p.Id != r.OwnerId {
-		log.Println("[error] player is not owner")
-		p.outbound <- &ServerError{"player is not owner"}
+		r.logger().Warn("player is not owner")
+		p.send(&ServerError{Id: "not_owner"})
+		return
+	}
+
+	if r.GamePhase == GamePhasePlaying {
+		r.logger().Warn("game has already started")
+		p.send(&ServerError{Id: "game_started"})
+		return
+	}
+
+	if r.resuming != nil {
+		r.logger().Warn("room is waiting to resume a suspended game")
+		p.send(&ServerError{Id: "room_resuming"})
+		return
+	}
+
+	if r.challenge != nil {
+		r.logger().Warn("daily challenges start by themselves")
+		p.send(&ServerError{Id: "challenge_starts_itself"})
 		return
 	}
 
-	if r.GamePhase != GamePhaseLobby {
-		log.Println("[error] game has already started")
-		p.outbound <- &ServerError{"game has already started"}
+	if len(r.Players) == 0 {
+		r.logger().Warn("no players to start the game with")
+		p.send(&ServerError{Id: "no_players"})
 		return
 	}
 
+	r.start(Clock.Now().UnixNano())
+}
+
+// start deals a new game, shuffled with the given seed.
+func (r *Room) start(seed int64) {
+	r.seed = seed
+	r.rng = rand.New(rand.NewSource(r.seed))
+
+	r.mu.Lock()
+	r.started = Clock.Now().UnixMilli()
+	r.history = nil
+	r.predictions = nil
+	r.replay = []replayEvent{}
+	if r.demo != nil {
+		r.replay = nil
+	}
+	r.mu.Unlock()
+
+	// a finished game can be played again
+	for _, player := range r.Players {
+		player.Hand = PlayerHand{}
+		player.Score = 0
+		player.draws = 0
+		player.sends = 0
+	}
+	r.Deal(r.Decks, r.rng)
 	r.GamePhase = GamePhasePlaying
 	// pick random player to start
-	r.CurrentTurn = rand.Intn(len(r.Players))
-	r.outbound <- &serverPayload{
+	r.CurrentTurn = r.rng.Intn(len(r.Players))
+	r.emit("game_started", analytics.Props{
+		"players": len(r.Players),
+		"ranked":  r.Ranked,
+		"decks":   len(r.Decks),
+		"cards":   r.DrawPileSize,
+	})
+	r.publish(&serverPayload{
 		message: &ServerStart{
 			CurrentTurn: r.CurrentTurn,
+			narration:   narrate("event_start", locale.Params{"player": r.Players[r.CurrentTurn].Name}),
 		},
-	}
+	})
+	r.startTurnTimer()
 }
 
 func (r *Room) HandleDraw(message ClientDraw) {
 	p := message.Player
 
 	if r.GamePhase != GamePhasePlaying {
-		log.Println("[error] game is not in playing phase")
-		p.outbound <- &ServerError{"game is not in playing phase"}
+		r.logger().Warn("game is not in playing phase")
+		p.send(&ServerError{Id: "game_not_playing"})
+		return
+	}
+
+	if !message.bot {
+		r.returnControl(p)
+	}
+
+	if r.Paused {
+		r.logger().Warn("game is paused")
+		p.send(&ServerError{Id: "game_paused"})
 		return
 	}
 
-	if p.Id != r.Players[r.CurrentTurn].Id {
-		log.Println("[error] player is not current turn")
-		p.outbound <- &ServerError{"player is not current turn"}
+	if current := r.currentPlayer(); current == nil || p.Id != current.Id {
+		r.logger().Warn("player is not current turn")
+		p.send(&ServerError{Id: "not_your_turn"})
 		return
 	}
 
-	c, err := r.drawCard()
+	c, err := r.Draw()
 	if err != nil {
 		// error occurs when there are no cards left
 		// should never happen as we replenish the deck after each draw
-		log.Println("[error]", err)
-		p.outbound <- &ServerError{err.Error()}
+		r.logger().Error("failed to draw a card", "err", err)
+		p.send(serverError(err))
 		return
 	}
+	p.draws++
 
 	if wild, ok := c.(*card.WildCard); ok {
-		r.usedWildCards = append(r.usedWildCards, r.ActiveWildCard)
-		r.ActiveWildCard = wild
-		r.outbound <- &serverPayload{
+		r.emitAction(p, "wild_card", message.bot)
+		r.publish(&serverPayload{
 			message: &ServerWildCard{
-				PlayerId: p.Id,
-				Card:     wild,
+				PlayerId:  p.Id,
+				Card:      wild,
+				narration: narrate("event_wild_card", locale.Params{"player": p.Name}).withCard(wild),
 			},
-		}
+		})
 	} else {
-		r.outbound <- &serverPayload{
+		r.emitAction(p, "draw", message.bot)
+		p.Hand = append(p.Hand, c.(*card.Card))
+		r.publish(&serverPayload{
 			message: &ServerDraw{
-				PlayerId: p.Id,
-				Card:     c.(*card.Card),
+				PlayerId:  p.Id,
+				Card:      c.(*card.Card),
+				narration: narrate("event_draw", locale.Params{"player": p.Name}).withCard(c),
 			},
-		}
+		})
 
 		r.CurrentTurn = (r.CurrentTurn + 1) % len(r.Players)
 		r.resync()
-		r.outbound <- &serverPayload{
-			message: &ServerTurn{
-				PlayerId: r.Players[r.CurrentTurn].Id,
-			},
-		}
+		r.publish(&serverPayload{
+			message: r.turnMessage(),
+		})
+	}
+
+	if r.DrawPileSize == 0 && (r.challenge != nil || r.Ranked) {
+		// a challenge is a single deal, so every attempt lasts as many draws, and so is a
+		// ranked game, so no one gets to pick when it ends
+		r.end()
+		return
 	}
 
 	if r.DrawPileSize == 0 {
 		// reshuffle
-		r.recreateDrawPile()
+		r.reshuffle()
 		for _, player := range r.Players {
-			player.outbound <- &ServerReshuffle{
+			player.send(&ServerReshuffle{
 				Player: player,
-			}
+			})
 		}
 	}
+
+	r.startTurnTimer()
+	r.scheduleBot()
 }
 
 func (r *Room) HandleSend(message ClientSend) {
 	p := message.Player
 
 	if r.GamePhase != GamePhasePlaying {
-		log.Println("[error] game is not in playing phase")
-		p.outbound <- &ServerError{"game is not in playing phase"}
+		r.logger().Warn("game is not in playing phase")
+		p.send(&ServerError{Id: "game_not_playing"})
+		return
 	}
 
-	target := r.getPlayer(message.RecipientId)
-	if target == nil {
-		log.Println("[error] target player not found")
-		p.outbound <- &ServerError{"target player not found"}
-	}
+	r.returnControl(p)
 
-	senderTop := p.Hand.top()
-	targetTop := target.Hand.top()
+	if r.Paused {
+		r.logger().Warn("game is paused")
+		p.send(&ServerError{Id: "game_paused"})
+		return
+	}
 
-	if !senderTop.CompatibleWith(targetTop, r.ActiveWildCard) {
-		log.Println("[error] cards are not compatible")
-		p.outbound <- &ServerError{"cards are not compatible"}
+	if r.isSpectator(p) {
+		r.logger().Warn("spectators cannot send cards")
+		p.send(&ServerError{Id: "spectator_send"})
+		return
 	}
 
-	p.Hand = p.Hand.tail()
-	target.Hand = append(target.Hand, senderTop)
-	target.Score++
+	target := r.getPlayer(message.RecipientId)
+	if target == nil {
+		r.logger().Warn("target player not found")
+		p.send(&ServerError{Id: "target_not_found"})
+		return
+	}
 
-	p.outbound <- &ServerSend{
-		SenderId:    p.Id,
-		RecipientId: target.Id,
-		Card:        senderTop,
+	if target == p {
+		r.logger().Warn("player cannot send cards to themselves")
+		p.send(&ServerError{Id: "send_self"})
+		return
 	}
 
-	target.outbound <- &ServerSend{
-		SenderId:    p.Id,
-		RecipientId: target.Id,
-		Card:        senderTop,
+	senderTop, err := r.Send(&p.Hand, &target.Hand)
+	if err != nil {
+		r.logger().Warn(err.Error())
+		p.send(serverError(err))
+		return
 	}
+	p.sends++
+	target.Score++
+	r.emitAction(p, "send", false)
+
+	r.publish(&serverPayload{
+		include: set{p.Id: {}, target.Id: {}},
+		message: &ServerSend{
+			SenderId:    p.Id,
+			RecipientId: target.Id,
+			Card:        senderTop,
+			narration: narrate("event_send", locale.Params{
+				"sender":    p.Name,
+				"recipient": target.Name,
+			}).withCard(senderTop),
+		},
+	})
 
 	r.resync()
 }
@@ -285,27 +529,51 @@ func (r *Room) HandleChat(message ClientChat) {
 		return
 	}
 
+	if !message.Player.allowChat() {
+		message.Player.send(&ServerError{Id: "chat_rate_limited"})
+		return
+	}
+
+	text, rejected := filterChat(message.Player, message.Message)
+	if rejected != nil {
+		message.Player.send(rejected)
+		return
+	}
+
 	if message.RecipientId != nil {
 		recipient := r.getPlayer(*message.RecipientId)
 		if recipient == nil {
-			message.Player.outbound <- &ServerError{"player not found"}
+			message.Player.send(&ServerError{Id: "player_not_found"})
 			return
 		}
-		recipient.outbound <- &ServerChat{
-			Timestamp: fmt.Sprint(time.Now().UnixMilli()),
+		if recipient.ignores(message.Player) {
+			return
+		}
+		r.logChat(message.Player, recipient, text)
+		recipient.send(&ServerChat{
+			Timestamp: fmt.Sprint(Clock.Now().UnixMilli()),
 			PlayerId:  message.Player.Id,
-			Message:   message.Message,
+			Message:   text,
 			Private:   true,
-		}
+		})
 		return
 	}
 
-	r.outbound <- &serverPayload{
+	r.logChat(message.Player, nil, text)
+	muting := set{}
+	for _, p := range append(append([]*Player{}, r.Players...), r.Spectators...) {
+		if p.ignores(message.Player) {
+			muting[p.Id] = struct{}{}
+		}
+	}
+
+	r.publish(&serverPayload{
+		exclude: muting,
 		message: &ServerChat{
-			Timestamp: fmt.Sprint(time.Now().UnixMilli()),
+			Timestamp: fmt.Sprint(Clock.Now().UnixMilli()),
 			PlayerId:  message.Player.Id,
-			Message:   message.Message,
+			Message:   text,
 			Private:   false,
 		},
-	}
+	})
 }
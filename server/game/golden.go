@@ -0,0 +1,239 @@
+package game
+
+import (
+	"cardgame/card"
+	"cardgame/deck"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// Golden is a recorded game used to check that changes to the rules don't change how games
+// play out: the same seed, decks and actions have to produce the same events.
+type Golden struct {
+	GameType GameType         `json:"gameType"`
+	Seed     int64            `json:"seed"`
+	Decks    []GoldenDeck     `json:"decks"`
+	Players  []GoldenPlayer   `json:"players"` // seated in turn order, the first one owns the room
+	Actions  []GoldenAction   `json:"actions"`
+	Events   []map[string]any `json:"events"` // server messages of the game, as in replays
+}
+
+// GoldenDeck is a deck in the format of deck files, like "=|category" for cards and "=|≈" for
+// wild cards.
+type GoldenDeck struct {
+	Cards     []string `json:"cards"`
+	WildCards []string `json:"wildCards"`
+}
+
+// GoldenPlayer is a player seated in a golden game.
+type GoldenPlayer struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GoldenAction is something a player did during a golden game.
+type GoldenAction struct {
+	Type        string `json:"type"` // "draw", "send", "end", or "timeout" when the player's turn timed out
+	PlayerId    string `json:"playerId"`
+	RecipientId string `json:"recipientId,omitempty"` // of sends
+}
+
+// Play plays the game with the current rules and returns the events it produced, without
+// the parts that differ between runs: card ids and match ids. Along with the messages sent to
+// the room, the errors sent to players whose action was rejected are events, with a "to"
+// field naming the player.
+//
+// Golden games are played without turn timers, bots or chat, which are driven by time rather
+// than by the actions.
+func (g *Golden) Play() ([]map[string]any, error) {
+	r, err := g.room()
+	if err != nil {
+		return nil, err
+	}
+
+	events := []map[string]any{}
+	add := func(message ServerMessage, to string) error {
+		m := messageMap(message)
+		if to != "" {
+			m["to"] = to
+		}
+		e, err := normalizeEvent(m)
+		if err != nil {
+			return err
+		}
+		events = append(events, e)
+		return nil
+	}
+	drain := func() error {
+		for len(r.outbound) > 0 {
+			payload := <-r.outbound
+			if _, ok := payload.message.(*ServerChat); ok {
+				continue
+			}
+			if err := add(payload.message, ""); err != nil {
+				return err
+			}
+		}
+		// rejected actions are part of the game too
+		for _, p := range r.Players {
+			for len(p.outbound) > 0 {
+				if message, ok := unshare(<-p.outbound).(*ServerError); ok {
+					if err := add(message, p.Id); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	r.start(g.Seed)
+	if err := drain(); err != nil {
+		return nil, err
+	}
+	for i, a := range g.Actions {
+		p := r.getPlayer(a.PlayerId)
+		if p == nil {
+			return nil, fmt.Errorf("action %d: unknown player %q", i, a.PlayerId)
+		}
+		switch a.Type {
+		case "draw":
+			r.HandleDraw(ClientDraw{Player: p})
+		case "send":
+			r.HandleSend(ClientSend{Player: p, RecipientId: a.RecipientId})
+		case "end":
+			r.HandleEnd(ClientEnd{Player: p})
+		case "timeout":
+			if current := r.currentPlayer(); current != p {
+				return nil, fmt.Errorf("action %d: it isn't the turn of %q", i, a.PlayerId)
+			}
+			r.HandleTurnTimeout(clientTurnTimeout{Seq: r.turnTimerSeq})
+		default:
+			return nil, fmt.Errorf("action %d: unknown type %q", i, a.Type)
+		}
+		if err := drain(); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// Verify plays the game and compares the events with the recorded ones. It returns an error
+// describing the first one that differs.
+func (g *Golden) Verify() error {
+	events, err := g.Play()
+	if err != nil {
+		return err
+	}
+	for i, want := range g.Events {
+		want, err := normalizeEvent(want)
+		if err != nil {
+			return err
+		}
+		if i >= len(events) {
+			return fmt.Errorf("event %d: expected %s, got nothing", i, encodeEvent(want))
+		}
+		if got := encodeEvent(events[i]); got != encodeEvent(want) {
+			return fmt.Errorf("event %d: expected %s, got %s", i, encodeEvent(want), got)
+		}
+	}
+	if len(events) > len(g.Events) {
+		return fmt.Errorf("event %d: expected nothing, got %s", len(g.Events), encodeEvent(events[len(g.Events)]))
+	}
+	return nil
+}
+
+// room creates a room seating the players of the game, outside of the hub.
+func (g *Golden) room() (*Room, error) {
+	if len(g.Players) == 0 {
+		return nil, errors.New("a golden game needs players")
+	}
+	r := &Room{
+		Id:         "r_golden",
+		GameType:   g.GameType,
+		Players:    []*Player{},
+		Decks:      []*deck.Deck{},
+		MaxPlayers: len(g.Players),
+		OwnerId:    g.Players[0].Id,
+		rng:        rand.New(rand.NewSource(g.Seed)),
+		inbound:    make(chan ClientMessage),
+		outbound:   make(chan *serverPayload, 64),
+		closed:     make(chan struct{}),
+	}
+	if r.GameType == "" {
+		r.GameType = GameTypeClassic
+	}
+
+	for _, p := range g.Players {
+		r.Players = append(r.Players, &Player{
+			Id:       p.Id,
+			Name:     p.Name,
+			Hand:     PlayerHand{},
+			outbound: make(chan ServerMessage, 64),
+			done:     make(chan struct{}),
+		})
+	}
+
+	for i, gd := range g.Decks {
+		d := &deck.Deck{Id: fmt.Sprintf("d_golden%d", i)}
+		for _, s := range gd.Cards {
+			c, err := card.CardFromString(s)
+			if err != nil {
+				return nil, err
+			}
+			d.Cards = append(d.Cards, &c)
+		}
+		for _, s := range gd.WildCards {
+			w, err := card.WildCardFromString(s)
+			if err != nil {
+				return nil, err
+			}
+			d.WildCards = append(d.WildCards, &w)
+		}
+		r.Decks = append(r.Decks, d)
+	}
+	return r, nil
+}
+
+// normalizeEvent converts an event to plain JSON values, and drops the card ids and match ids
+// which are different every time a game is played.
+func normalizeEvent(e map[string]any) (map[string]any, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	dropIds(normalized)
+	return normalized, nil
+}
+
+func dropIds(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		delete(v, "matchId")
+		_, isCard := v["category"]
+		_, isWildCard := v["types"]
+		if isCard || isWildCard {
+			delete(v, "id")
+		}
+		for _, child := range v {
+			dropIds(child)
+		}
+	case []any:
+		for _, child := range v {
+			dropIds(child)
+		}
+	}
+}
+
+// encodeEvent encodes a normalized event for comparisons and error messages. Map keys are
+// sorted, so equal events encode the same.
+func encodeEvent(e map[string]any) string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGolden() *Golden {
+	return &Golden{
+		Seed: 42,
+		Decks: []GoldenDeck{{
+			Cards:     []string{"=|fruit", "≈|fruit", "■|fruit", "=|animal", "≈|animal", "■|animal"},
+			WildCards: []string{"=|≈"},
+		}},
+		Players: []GoldenPlayer{{"p_a", "a"}, {"p_b", "b"}},
+	}
+}
+
+func TestGoldenVerify(t *testing.T) {
+	withTestStore(t)
+	g := newTestGolden()
+	events, err := g.Play()
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, "start", events[0]["type"])
+
+	// draw on every turn, then end
+	current := events[0]["currentTurn"].(float64)
+	for i := 0; i < 8; i++ {
+		g.Actions = append(g.Actions, GoldenAction{Type: "draw", PlayerId: g.Players[(int(current)+i)%2].Id})
+	}
+	g.Actions = append(g.Actions, GoldenAction{Type: "end", PlayerId: "p_a"})
+	g.Events, err = g.Play()
+	require.NoError(t, err)
+	assert.Equal(t, "end", g.Events[len(g.Events)-1]["type"])
+	assert.NotContains(t, g.Events[len(g.Events)-1], "matchId")
+
+	// card ids are different every time, but the games are the same
+	assert.NoError(t, g.Verify())
+
+	changed := *g
+	changed.Seed = 43
+	assert.Error(t, changed.Verify())
+
+	changed = *g
+	changed.Events = g.Events[:len(g.Events)-1]
+	assert.ErrorContains(t, changed.Verify(), "expected nothing")
+
+	changed = *g
+	changed.Actions = g.Actions[:len(g.Actions)-1]
+	assert.ErrorContains(t, changed.Verify(), "got nothing")
+}
+
+func TestGoldenUnknownPlayer(t *testing.T) {
+	withTestStore(t)
+	g := newTestGolden()
+	g.Actions = []GoldenAction{{Type: "draw", PlayerId: "p_nobody"}}
+	_, err := g.Play()
+	assert.ErrorContains(t, err, "unknown player")
+}
+
+func TestGoldenRejectedAction(t *testing.T) {
+	withTestStore(t)
+	g := newTestGolden()
+	g.Actions = []GoldenAction{{Type: "send", PlayerId: "p_a", RecipientId: "p_a"}}
+	events, err := g.Play()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"type": "error", "to": "p_a", "message": "player cannot send cards to themselves", "id": "send_self"}, events[len(events)-1])
+}
@@ -2,11 +2,21 @@ package game
 
 import (
 	"cardgame/deck"
+	"cardgame/errs"
+	"cardgame/locale"
+	"cardgame/storage"
 	"cardgame/util"
 	"cardgame/words"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 type hubMessage struct {
@@ -18,34 +28,124 @@ type Hub struct {
 	RegionCode string
 	Version    string
 
-	Rooms map[string]*Room // RoomId -> Room
+	Channels map[string]*Channel // channel name -> Channel
+
+	roomsMu  sync.RWMutex
+	rooms    map[string]*Room // RoomId -> Room
+	resuming map[string]*Room // suspended game id -> room waiting for its players, guarded by roomsMu
 
 	inbound chan *hubMessage // incoming client messages
+
+	onlineMu sync.RWMutex
+	online   map[string]map[*Player]struct{} // account id -> signed in connections
+
+	lastSweep atomic.Int64 // unix ms of the last sweep for abandoned rooms, 0 if they aren't collected
 }
 
+// NewRoom creates a room and starts handling its messages.
 func (h *Hub) NewRoom(password string) *Room {
+	r := h.newRoom(password)
+
+	go r.read()
+
+	return r
+}
+
+// newRoom creates a room without starting the goroutine handling its messages.
+func (h *Hub) newRoom(password string) *Room {
+	r := h.makeRoom(password)
+	h.AddRoom(r)
+	return r
+}
+
+// makeRoom creates a room without adding it to the hub.
+func (h *Hub) makeRoom(password string) *Room {
 	id := util.IdFrom("r", time.Now().String())
 	r := Room{
-		Id:         id,
-		Name:       strings.Join(words.Words(words.English, 4), " "),
-		Timstamp:   time.Now().UnixMilli(),
-		Players:    []*Player{},
-		Decks:      []*deck.Deck{},
-		MaxPlayers: 4,
-		inbound:    make(chan ClientMessage),
-		outbound:   make(chan *serverPayload),
-	}
-	h.Rooms[r.Id] = &r
+		Id:              id,
+		Name:            strings.Join(words.Words(words.English, 4), " "),
+		GameType:        GameTypeClassic,
+		Timstamp:        Clock.Now().UnixMilli(),
+		Players:         []*Player{},
+		Decks:           []*deck.Deck{},
+		MaxPlayers:      4,
+		DisconnectGrace: DefaultDisconnectGrace,
+		TurnTimeout:     DefaultTurnTimeout,
+		AfkTurns:        DefaultAfkTurns,
+		rng:             rand.New(rand.NewSource(Clock.Now().UnixNano())),
+		inbound:         make(chan ClientMessage),
+		closed:          make(chan struct{}),
+		hub:             h,
+	}
 	if password != "" {
 		r.SetPassword(password)
 	}
 
-	go r.read()
-	go r.write()
-
 	return &r
 }
 
+// Room returns the room with an id, if it is open.
+func (h *Hub) Room(id string) (*Room, bool) {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+	r, ok := h.rooms[id]
+	return r, ok
+}
+
+// AllRooms returns the rooms open on the hub, in no particular order.
+func (h *Hub) AllRooms() []*Room {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, r := range h.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
+
+// RoomCount returns the number of rooms open on the hub.
+func (h *Hub) RoomCount() int {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+	return len(h.rooms)
+}
+
+// AddRoom adds a room to the hub, replacing any room with the same id.
+func (h *Hub) AddRoom(r *Room) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	h.rooms[r.Id] = r
+}
+
+// RemoveRoom removes a room from the hub, without closing it.
+func (h *Hub) RemoveRoom(id string) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	delete(h.rooms, id)
+	h.forgetResuming(id)
+}
+
+// takeRoom removes the room with an id from the hub and returns it, if it was open. Of
+// several callers taking the same room, only one gets it.
+func (h *Hub) takeRoom(id string) (*Room, bool) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	r, ok := h.rooms[id]
+	delete(h.rooms, id)
+	h.forgetResuming(id)
+	return r, ok
+}
+
+// forgetResuming stops finding the room with an id by the suspended game it waits for, once
+// it is removed. roomsMu has to be held.
+func (h *Hub) forgetResuming(roomId string) {
+	for gameId, r := range h.resuming {
+		if r.Id == roomId {
+			delete(h.resuming, gameId)
+		}
+	}
+}
+
 func (h *Hub) read() {
 	for {
 		select {
@@ -54,44 +154,182 @@ func (h *Hub) read() {
 				return
 			}
 
-			switch clientMessage := msg.clientMessage.(type) {
-			case ClientJoin:
-				h.handleJoin(clientMessage)
-			case ClientLeave:
-				h.handleLeave(clientMessage)
-			default:
-				log.Printf("[error] bad message type %T sent to hub\n", clientMessage)
-			}
+			h.handle(msg)
 		}
 	}
 }
 
+// dispatch decodes a message read from a player's connection and passes it on
+// to the hub or the player's room. Malformed messages are answered with an error.
+func (h *Hub) dispatch(p *Player, data []byte) {
+	data, err := p.upgrade(data)
+	if err != nil {
+		slog.Warn("malformed message", "player", p.Id, "err", err)
+		p.notify(serverError(errs.Wrap(errs.ErrInvalidInput, err)))
+		return
+	}
+	msg, err := p.ClientMessageFromJson(data)
+	if err != nil {
+		slog.Warn("malformed message", "player", p.Id, "err", err)
+		p.notify(serverError(errs.Wrap(errs.ErrInvalidInput, err)))
+		return
+	}
+	messagesHandled.Inc(msg.ClientType())
+
+	if r := p.currentRoom(); r == nil || hubHandles(msg) {
+		h.inbound <- &hubMessage{
+			clientMessage: msg,
+			player:        p,
+		}
+	} else if !r.post(msg) {
+		p.notify(&ServerError{Id: "room_not_found"})
+	}
+}
+
+func (h *Hub) handle(msg *hubMessage) {
+	switch m := msg.clientMessage.(type) {
+	case ClientJoin:
+		h.handleJoin(m)
+	case ClientLeave:
+		h.handleLeave(m)
+	case ClientChannelJoin:
+		h.handleChannelJoin(m)
+	case ClientChannelLeave:
+		h.handleChannelLeave(m)
+	case ClientChannelChat:
+		h.handleChannelChat(m)
+	case ClientMute:
+		h.handleMute(m)
+	case ClientReplay:
+		h.handleReplay(m)
+	case ClientSignIn:
+		h.handleSignIn(m)
+	case ClientInvite:
+		h.handleInvite(m)
+	case clientDisconnect:
+		h.handleDisconnect(m)
+	case clientPing:
+		close(m.done)
+	default:
+		slog.Error("bad message type sent to hub", "message", fmt.Sprintf("%T", m))
+		msg.player.notify(&ServerError{Id: "not_in_room"})
+	}
+}
+
 func (h *Hub) handleJoin(msg ClientJoin) {
 	p := msg.Player
-	r, ok := h.Rooms[msg.RoomId]
+	r, ok := h.Room(msg.RoomId)
 
-	if p.room != nil {
-		p.outbound <- &ServerError{"You are already in a room"}
+	if p.currentRoom() != nil {
+		p.notify(&ServerError{Id: "already_in_room"})
 		return
 	}
 
 	if r == nil || !ok {
-		p.outbound <- &ServerError{"Room not found"}
+		p.notify(&ServerError{Id: "room_not_found"})
+		return
+	}
+
+	if msg.Account != "" && !h.signIn(p, msg.Account) {
 		return
 	}
 
-	if r.IsPrivate() && !r.CheckPassword(msg.Password) {
-		p.outbound <- &ServerError{"Incorrect password"}
+	if r.IsPrivate() && !r.isInvited(p) && !r.CheckPassword(msg.Password) {
+		p.notify(&ServerError{Id: "incorrect_password"})
 		return
 	}
 
-	r.inbound <- msg
+	if msg.Name != nil {
+		name, err := CleanName(*msg.Name)
+		if err != nil {
+			p.notify(serverError(err))
+			return
+		}
+		p.Name = name
+	}
+
+	p.setRoom(r)
+	if !r.post(msg) {
+		// closed in the meantime
+		p.setRoom(nil)
+		p.notify(&ServerError{Id: "room_not_found"})
+	}
 }
 
 func (h *Hub) handleLeave(msg ClientLeave) {
-	if msg.Player.room != nil {
-		msg.Player.room.inbound <- msg
-		msg.Player.room = nil
+	if r := msg.Player.currentRoom(); r != nil {
+		r.post(msg)
+		msg.Player.setRoom(nil)
+	}
+}
+
+// maxNameLength is the maximum length of a display name, in runes.
+const maxNameLength = 32
+
+// CleanName validates a display name chosen by a player and checks it against ChatFilter.
+func CleanName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", errs.Wrap(errs.ErrInvalidInput, locale.NewError("name_length", locale.Params{"max": strconv.Itoa(maxNameLength)}))
+	}
+
+	// names are rejected instead of masked, so no one ends up called "****"
+	if result := ChatFilter.Filter(name); len(result.Matches) > 0 || result.Blocked {
+		return "", errs.Wrap(errs.ErrInvalidInput, locale.NewError("name_not_allowed", nil))
+	}
+	return name, nil
+}
+
+// maxBioLength is the maximum length of a profile bio, in runes.
+const maxBioLength = 300
+
+// CleanBio validates a profile bio and masks it with ChatFilter.
+func CleanBio(bio string) (string, error) {
+	bio = strings.TrimSpace(bio)
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return "", errs.Wrap(errs.ErrInvalidInput, locale.NewError("bio_too_long", locale.Params{"max": strconv.Itoa(maxBioLength)}))
+	}
+
+	result := ChatFilter.Filter(bio)
+	if result.Blocked {
+		return "", errs.Wrap(errs.ErrInvalidInput, locale.NewError("bio_not_allowed", nil))
+	}
+	return result.Text, nil
+}
+
+// AvatarUrl returns the path an account's uploaded avatar image is served at,
+// or an empty string if it has none.
+func AvatarUrl(a *storage.Account) string {
+	if a.AvatarImage == 0 {
+		return ""
+	}
+	// the upload time changes the url, so the image can be cached until it is replaced
+	return fmt.Sprintf("/api/user/%s/avatar?v=%d", a.Id, a.AvatarImage)
+}
+
+// hubHandles returns true if a message is handled by the hub even when the player is in a room.
+func hubHandles(msg ClientMessage) bool {
+	switch msg.(type) {
+	case ClientChannelJoin, ClientChannelLeave, ClientChannelChat, ClientMute, ClientReplay, ClientSignIn, ClientInvite:
+		return true
+	}
+	return false
+}
+
+// Ping checks the hub still handles messages, waiting up to timeout for it to.
+func (h *Hub) Ping(timeout time.Duration) error {
+	done := make(chan struct{})
+	deadline := time.After(timeout)
+	select {
+	case h.inbound <- &hubMessage{clientMessage: clientPing{done: done}}:
+	case <-deadline:
+		return errors.New("hub is not reading messages")
+	}
+	select {
+	case <-done:
+		return nil
+	case <-deadline:
+		return errors.New("hub did not handle the ping")
 	}
 }
 
@@ -101,8 +339,15 @@ func init() {
 	HubMain = &Hub{
 		RegionCode: "global",
 
-		Rooms:   make(map[string]*Room),
-		inbound: make(chan *hubMessage),
+		Channels: make(map[string]*Channel),
+		rooms:    make(map[string]*Room),
+		resuming: make(map[string]*Room),
+		inbound:  make(chan *hubMessage),
+	}
+	HubMain.Channels[LobbyChannel] = newChannel(LobbyChannel)
+	for _, t := range AllGameTypes {
+		name := GameTypeChannel(t)
+		HubMain.Channels[name] = newChannel(name)
 	}
 	go HubMain.read()
 }
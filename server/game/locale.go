@@ -0,0 +1,98 @@
+package game
+
+import (
+	"cardgame/errs"
+	"cardgame/locale"
+	"errors"
+)
+
+// localized is implemented by messages with text in the language of each player. They are
+// translated when sent to a player, and only shared between players speaking the same language.
+type localized interface {
+	ServerMessage
+	localize(lang string) ServerMessage
+}
+
+func (s ServerError) localize(lang string) ServerMessage {
+	if s.Id != "" {
+		s.Message = locale.Translate(lang, s.Id, s.Params)
+	}
+	return &s
+}
+
+// Game events are described in the language of each player.
+
+func (s ServerJoin) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerLeave) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerStart) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerDraw) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerWildCard) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerSend) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerTurn) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerPause) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerReconnect) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerResume) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerTurnTimeout) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerAfk) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+func (s ServerEnd) localize(lang string) ServerMessage {
+	s.Description = s.narration.describe(lang)
+	return &s
+}
+
+// serverError returns the error message telling a player about an error, with the code of
+// its kind. Errors of package locale are translated, others are sent as they are.
+func serverError(err error) *ServerError {
+	var e *locale.Error
+	if errors.As(err, &e) {
+		return &ServerError{Id: e.Id, Params: e.Params, Code: errs.Code(err)}
+	}
+	return &ServerError{Message: err.Error(), Code: errs.Code(err)}
+}
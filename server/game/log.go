@@ -0,0 +1,30 @@
+package game
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+// logger returns the logger for the room. While a message is being handled, it also carries
+// the number of the action, its type and who sent it, so every line about it can be found.
+// It is only used from the room's read goroutine.
+func (r *Room) logger() *slog.Logger {
+	if r.actionLog != nil {
+		return r.actionLog
+	}
+	return slog.With("room", r.Id, "gameType", r.GameType)
+}
+
+// messagePlayer returns the player who sent a message, or nil if it has no player.
+func messagePlayer(message ClientMessage) *Player {
+	v := reflect.ValueOf(message)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName("Player")
+	if !f.IsValid() {
+		return nil
+	}
+	p, _ := f.Interface().(*Player)
+	return p
+}
@@ -0,0 +1,26 @@
+package game
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionLog(t *testing.T) {
+	var b bytes.Buffer
+	old := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(old) })
+	slog.SetDefault(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	r := newTestRoom(t)
+	p := newTestPlayer("p_a")
+	p.AccountId = "u_a"
+	r.HandleMessage(ClientStart{Player: p})
+
+	assert.Contains(t, b.String(), "room="+r.Id)
+	assert.Contains(t, b.String(), "action=1 type=start player=p_a account=u_a")
+	assert.Nil(t, r.actionLog, "the action logger should only be kept while handling a message")
+	assert.Nil(t, messagePlayer(clientTurnTimeout{}))
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+	"cardgame/analytics"
+	"cardgame/leaderboard"
+	"cardgame/rating"
+	"cardgame/stats"
+	"cardgame/storage"
+	"cardgame/util"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+)
+
+// matchRules are the room settings recorded with a match.
+type matchRules struct {
+	PlayMode        PlayMode  `json:"playMode"`
+	DisconnectGrace int       `json:"disconnectGrace"`
+	AfkPolicy       AfkPolicy `json:"afkPolicy"`
+	TurnTimeout     int       `json:"turnTimeout"`
+	AfkTurns        int       `json:"afkTurns"`
+	Decks           []string  `json:"decks"`
+}
+
+func (r *Room) HandleEnd(message ClientEnd) {
+	p := message.Player
+
+	if p.Id != r.OwnerId {
+		r.logger().Warn("player is not owner")
+		p.send(&ServerError{Id: "not_owner"})
+		return
+	}
+
+	if r.GamePhase != GamePhasePlaying {
+		r.logger().Warn("game is not in playing phase")
+		p.send(&ServerError{Id: "game_not_playing"})
+		return
+	}
+
+	// the owner could otherwise end a ranked game whenever they are ahead
+	if r.Ranked {
+		r.logger().Warn("ranked games end with the deal")
+		p.send(&ServerError{Id: "ranked_end"})
+		return
+	}
+
+	r.end()
+}
+
+// end finishes the game in progress and records the results.
+func (r *Room) end() {
+	if r.pauseTimer != nil {
+		r.pauseTimer.Stop()
+		r.pauseTimer = nil
+	}
+	r.Paused = false
+	r.stopTurnTimer()
+	if r.Vote != nil {
+		r.endVote(false)
+	}
+
+	r.GamePhase = GamePhaseEnd
+	match := r.recordMatch(storage.OutcomeCompleted)
+	r.emit("game_finished", analytics.Props{
+		"match":      match.Id,
+		"players":    len(match.Players),
+		"ranked":     match.Ranked,
+		"durationMs": match.Ended - match.Started,
+	})
+	if r.Ranked {
+		updateRatings(match)
+	}
+	if err := stats.Record(storage.Default, match); err != nil {
+		r.logger().Error("failed to update stats", "err", err)
+	}
+
+	r.publish(&serverPayload{
+		message: &ServerEnd{
+			MatchId:   match.Id,
+			Results:   match.Players,
+			narration: narrateEnd(match.Players),
+		},
+	})
+	r.resolvePredictions(match.Players)
+	r.recordAchievements(match)
+	r.recordProgression(match)
+	if r.challenge != nil {
+		r.recordChallenge()
+	}
+}
+
+// results ranks the seated players by score. Players with the same score share a rank.
+func (r *Room) results() []storage.MatchPlayer {
+	results := make([]storage.MatchPlayer, 0, len(r.Players))
+	for _, p := range r.Players {
+		results = append(results, storage.MatchPlayer{
+			Id:        p.Id,
+			AccountId: p.AccountId,
+			Name:      p.Name,
+			Score:     p.Score,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	for i := range results {
+		if i > 0 && results[i].Score == results[i-1].Score {
+			results[i].Rank = results[i-1].Rank
+		} else {
+			results[i].Rank = i + 1
+		}
+	}
+	return results
+}
+
+// rules returns the current settings of the room.
+func (r *Room) rules() matchRules {
+	decks := []string{}
+	for _, d := range r.Decks {
+		decks = append(decks, d.Id)
+	}
+	return matchRules{
+		PlayMode:        r.PlayMode,
+		DisconnectGrace: r.DisconnectGrace,
+		AfkPolicy:       r.AfkPolicy,
+		TurnTimeout:     r.TurnTimeout,
+		AfkTurns:        r.AfkTurns,
+		Decks:           decks,
+	}
+}
+
+// recordMatch saves the game in progress to storage.Default. Failing to save is logged,
+// and doesn't stop the game from ending.
+func (r *Room) recordMatch(outcome storage.Outcome) *storage.Match {
+	rules, _ := json.Marshal(r.rules())
+
+	match := &storage.Match{
+		Id:       util.IdFrom("m", util.Token()),
+		RoomId:   r.Id,
+		GameType: string(r.GameType),
+		Ranked:   r.Ranked,
+		Rules:    rules,
+		Outcome:  outcome,
+		Players:  r.results(),
+		Started:  r.started,
+		Ended:    Clock.Now().UnixMilli(),
+	}
+	if r.demo != nil {
+		// nothing about demos is kept
+		return match
+	}
+	if err := storage.Default.SaveMatch(match); err != nil {
+		r.logger().Error("failed to save match", "err", err)
+	}
+	return match
+}
+
+// updateRatings updates the ratings of the players of a ranked match for its game type.
+// Guests don't have a rating and are left out, and players whose rating is still
+// provisional only join the leaderboards once their placement games are over.
+func updateRatings(match *storage.Match) {
+	seen := set{}
+	stored := []*storage.PlayerRating{}
+	ratings := []rating.Rating{}
+	ranks := []int{}
+	provisional := []bool{}
+	for _, p := range match.Players {
+		if _, ok := seen[p.AccountId]; ok || p.AccountId == "" {
+			continue
+		}
+		seen[p.AccountId] = struct{}{}
+
+		pr, err := storage.Default.Rating(p.AccountId, match.GameType)
+		if errors.Is(err, storage.ErrNotFound) {
+			d := rating.Default()
+			pr = &storage.PlayerRating{
+				AccountId:  p.AccountId,
+				GameType:   match.GameType,
+				Rating:     d.Rating,
+				Deviation:  d.Deviation,
+				Volatility: d.Volatility,
+			}
+		} else if err != nil {
+			slog.Error("failed to load rating", "err", err)
+			return
+		}
+
+		stored = append(stored, pr)
+		ratings = append(ratings, rating.Rating{Rating: pr.Rating, Deviation: pr.Deviation, Volatility: pr.Volatility})
+		ranks = append(ranks, p.Rank)
+		provisional = append(provisional, rating.Provisional(pr.Games))
+	}
+	if len(stored) < 2 {
+		return
+	}
+
+	changes := make([]*storage.RatingChange, len(stored))
+	placed := []*storage.RatingChange{}
+	for i, r := range rating.UpdatePlacement(ratings, ranks, provisional) {
+		pr := stored[i]
+		changes[i] = &storage.RatingChange{
+			AccountId: pr.AccountId,
+			GameType:  pr.GameType,
+			MatchId:   match.Id,
+			Before:    pr.Rating,
+			After:     r.Rating,
+			Deviation: r.Deviation,
+			Created:   match.Ended,
+		}
+		pr.Rating, pr.Deviation, pr.Volatility = r.Rating, r.Deviation, r.Volatility
+		pr.Games++
+		pr.Updated = match.Ended
+		if !rating.Provisional(pr.Games) {
+			placed = append(placed, changes[i])
+		}
+	}
+
+	if err := storage.Default.SaveRatings(stored, changes); err != nil {
+		slog.Error("failed to save ratings", "err", err)
+		return
+	}
+	if err := leaderboard.Record(storage.Default, match, placed); err != nil {
+		slog.Error("failed to update leaderboards", "err", err)
+	}
+}
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"cardgame/rating"
+	"cardgame/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withTestStore(t *testing.T) *storage.Memory {
+	t.Helper()
+	old := storage.Default
+	s := storage.NewMemory()
+	storage.Default = s
+	t.Cleanup(func() { storage.Default = old })
+	return s
+}
+
+// saveTestAccount saves an account with a session signed in with token.
+func saveTestAccount(s *storage.Memory, a *storage.Account, token string) {
+	s.SaveAccount(a)
+	s.SaveSession(&storage.Session{Id: "s_" + a.Id, AccountId: a.Id, TokenHash: storage.HashToken(token)})
+}
+
+func TestEndRecordsMatch(t *testing.T) {
+	s := withTestStore(t)
+	owner := newTestPlayer("p_owner")
+	owner.AccountId = "u_owner"
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	r := startTestGame(t, owner, a, b)
+	owner.Score, a.Score, b.Score = 2, 5, 2
+
+	r.HandleEnd(ClientEnd{Player: a})
+	assert.Equal(t, "player is not owner", receiveUntil[*ServerError](t, a).Message)
+
+	r.HandleEnd(ClientEnd{Player: owner})
+	end := receiveUntil[*ServerEnd](t, owner)
+	assert.Equal(t, GamePhaseEnd, r.GamePhase)
+	if assert.Len(t, end.Results, 3) {
+		assert.Equal(t, a.Id, end.Results[0].Id, "highest score should win")
+		assert.Equal(t, 1, end.Results[0].Rank)
+		assert.Equal(t, 2, end.Results[1].Rank)
+		assert.Equal(t, 2, end.Results[2].Rank, "tied players should share a rank")
+	}
+
+	m, err := s.Match(end.MatchId)
+	assert.NoError(t, err)
+	assert.Equal(t, storage.OutcomeCompleted, m.Outcome)
+	assert.Equal(t, r.Id, m.RoomId)
+	assert.NotZero(t, m.Started)
+
+	history, err := s.Matches(storage.MatchQuery{AccountId: "u_owner"})
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	r.HandleStart(ClientStart{Player: owner})
+	receiveUntil[*ServerStart](t, owner)
+	assert.Zero(t, a.Score, "scores should reset for the next game")
+}
+
+func TestVoidRecordsMatch(t *testing.T) {
+	s := withTestStore(t)
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+
+	close(other.done)
+	r.HandleDisconnect(clientDisconnect{other})
+	receiveUntil[*ServerPause](t, owner)
+	r.HandleResume(ClientResume{Player: owner, Mode: ResumeModeVoid})
+	receiveUntil[*ServerVoid](t, owner)
+
+	matches, err := s.Matches(storage.MatchQuery{RoomId: r.Id})
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, storage.OutcomeVoid, matches[0].Outcome)
+		assert.Len(t, matches[0].Players, 2, "disconnected players should be recorded")
+	}
+}
+
+// drawLastCard has the current player of a game draw the last card of the deal.
+func drawLastCard(r *Room) {
+	r.DrawPile = r.DrawPile[:1]
+	r.DrawPileSize = 1
+	r.HandleDraw(ClientDraw{Player: r.currentPlayer()})
+}
+
+func TestRankedEndUpdatesRatings(t *testing.T) {
+	s := withTestStore(t)
+	defer func(n int) { rating.PlacementGames = n }(rating.PlacementGames)
+	rating.PlacementGames = 0
+	owner, a, guest := newTestPlayer("p_owner"), newTestPlayer("p_a"), newTestPlayer("p_guest")
+	owner.AccountId, a.AccountId = "u_owner", "u_a"
+	r := startTestGame(t, owner, a, guest)
+	r.Ranked = true
+	owner.Score, a.Score, guest.Score = 1, 4, 9
+
+	r.HandleEnd(ClientEnd{Player: owner})
+	assert.Equal(t, "Ranked games end when the draw pile runs out", receiveUntil[*ServerError](t, owner).Message)
+	assert.Equal(t, GamePhasePlaying, r.GamePhase, "owners should not end ranked games")
+	drawLastCard(r)
+	end := receiveUntil[*ServerEnd](t, owner)
+
+	winner, err := s.Rating("u_a", string(r.GameType))
+	assert.NoError(t, err)
+	loser, err := s.Rating("u_owner", string(r.GameType))
+	assert.NoError(t, err)
+	assert.Greater(t, winner.Rating, rating.DefaultRating)
+	assert.Less(t, loser.Rating, rating.DefaultRating)
+	assert.Equal(t, 1, winner.Games)
+
+	history, err := s.RatingHistory(storage.RatingQuery{AccountId: "u_a", GameType: string(r.GameType)})
+	assert.NoError(t, err)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, end.MatchId, history[0].MatchId)
+		assert.Equal(t, rating.DefaultRating, history[0].Before)
+	}
+	_, err = s.Rating("", string(r.GameType))
+	assert.ErrorIs(t, err, storage.ErrNotFound, "guests should not be rated")
+
+	e, err := s.LeaderboardEntry(0, string(r.GameType), "u_a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, e.Rank)
+	assert.Equal(t, winner.Rating, e.Score)
+}
+
+func TestPlacementGamesStayOffLeaderboards(t *testing.T) {
+	s := withTestStore(t)
+	defer func(n int) { rating.PlacementGames = n }(rating.PlacementGames)
+	rating.PlacementGames = 2
+	assert.NoError(t, s.SaveRatings([]*storage.PlayerRating{
+		{AccountId: "u_owner", GameType: string(GameTypeClassic), Rating: 1800, Deviation: 60, Volatility: rating.DefaultVolatility, Games: 30},
+	}, nil))
+	owner, a := newTestPlayer("p_owner"), newTestPlayer("p_a")
+	owner.AccountId, a.AccountId = "u_owner", "u_a"
+	r := startTestGame(t, owner, a)
+	r.Ranked = true
+	owner.Score, a.Score = 1, 4
+
+	drawLastCard(r)
+	receiveUntil[*ServerEnd](t, owner)
+
+	settled, err := s.Rating("u_owner", string(r.GameType))
+	assert.NoError(t, err)
+	assert.Equal(t, 1800.0, settled.Rating, "provisional players should not move settled ratings")
+	placed, err := s.Rating("u_a", string(r.GameType))
+	assert.NoError(t, err)
+	assert.Greater(t, placed.Rating, rating.DefaultRating)
+
+	e, err := s.LeaderboardEntry(0, string(r.GameType), "u_owner")
+	assert.NoError(t, err)
+	assert.Equal(t, 1800.0, e.Score)
+	_, err = s.LeaderboardEntry(0, string(r.GameType), "u_a")
+	assert.ErrorIs(t, err, storage.ErrNotFound, "provisional players should stay off the leaderboard")
+}
+
+func TestUnrankedEndKeepsRatings(t *testing.T) {
+	s := withTestStore(t)
+	owner, a := newTestPlayer("p_owner"), newTestPlayer("p_a")
+	owner.AccountId, a.AccountId = "u_owner", "u_a"
+	r := startTestGame(t, owner, a)
+
+	r.HandleEnd(ClientEnd{Player: owner})
+	receiveUntil[*ServerEnd](t, owner)
+
+	ratings, err := s.Ratings("u_a")
+	assert.NoError(t, err)
+	assert.Empty(t, ratings)
+}
@@ -0,0 +1,53 @@
+package game
+
+import (
+	"cardgame/metrics"
+)
+
+var (
+	connectionsOpen     = metrics.NewGauge("cardgame_connections", "Open websocket connections.", "")
+	connectionsProtocol = metrics.NewCounter("cardgame_connections_protocol_total", "Websocket connections opened, by protocol version.", "version")
+	messagesHandled     = metrics.NewCounter("cardgame_messages_total", "Client messages received, by type.", "type")
+	messageBytes        = metrics.NewHistogram("cardgame_message_bytes", "Size of the messages sent to each connection, in bytes.",
+		metrics.ExponentialBuckets(64, 4, 7))
+	turnTimeouts = metrics.NewCounter("cardgame_turn_timeouts_total", "Turns that ran out of time.", "")
+
+	roomsCollected      = metrics.NewCounter("cardgame_rooms_collected_total", "Abandoned rooms closed, by kind.", "kind")
+	roomsCollectedSaved = metrics.NewCounter("cardgame_rooms_collected_saved_total", "Games suspended as their abandoned room was closed, by kind.", "kind")
+
+	roomsOpen = metrics.NewGaugeFunc("cardgame_rooms", "Open rooms, by game type.", "game_type", func() map[string]float64 {
+		rooms := map[string]float64{}
+		if HubMain == nil {
+			return rooms
+		}
+		for _, r := range HubMain.AllRooms() {
+			rooms[string(r.GameType)]++
+		}
+		return rooms
+	})
+)
+
+// Stats are counts about the running hub, for debugging.
+type Stats struct {
+	Rooms       int `json:"rooms"`
+	Players     int `json:"players"`    // seated in rooms, bots included
+	Spectators  int `json:"spectators"` // watching rooms
+	Connections int `json:"connections"`
+	Online      int `json:"online"` // accounts signed in on at least one connection
+}
+
+// Stats counts the rooms, players and connections of the hub.
+func (h *Hub) Stats() Stats {
+	rooms := h.AllRooms()
+	s := Stats{Rooms: len(rooms), Connections: int(connectionsOpen.Value(""))}
+	for _, r := range rooms {
+		r.mu.Lock()
+		s.Players += len(r.Players)
+		s.Spectators += len(r.Spectators)
+		r.mu.Unlock()
+	}
+	h.onlineMu.RLock()
+	s.Online = len(h.online)
+	h.onlineMu.RUnlock()
+	return s
+}
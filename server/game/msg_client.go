@@ -1,10 +1,10 @@
 package game
 
 import (
+	"cardgame/storage"
 	"cardgame/util/slices"
 	"encoding/json"
 	"errors"
-	"log"
 	"reflect"
 )
 
@@ -29,13 +29,23 @@ type (
 		RemoveDecks []string  `json:"removeDecks"` // IDs of decks to remove
 		PlayMode    *PlayMode `json:"playMode"`    // new play mode
 		HubDeviceId *string   `json:"hubDeviceId"` // ID of the hub device to use
+
+		DisconnectGrace *int       `json:"disconnectGrace"` // seconds to wait for a disconnected player
+		AfkPolicy       *AfkPolicy `json:"afkPolicy"`       // what happens to the seat of a removed player
+		TurnTimeout     *int       `json:"turnTimeout"`     // seconds a player has to draw, or 0 for no limit
+		AfkTurns        *int       `json:"afkTurns"`        // consecutive timed out turns before a player is AFK, or 0 to never
+		Ranked          *bool      `json:"ranked"`          // ranked rooms don't substitute AFK players
 	}
 	// ClientJoin is sent to the hub by a new player joining a room.
 	ClientJoin struct {
 		Player *Player `json:"-"`
 
-		RoomId   string `json:"roomId"`
-		Password string `json:"password"`
+		RoomId   string  `json:"roomId"`
+		Password string  `json:"password"`
+		Spectate bool    `json:"spectate"` // join as a spectator instead of taking a seat
+		Token    string  `json:"token"`    // token from a previous ack, set to reclaim a seat after a disconnect
+		Name     *string `json:"name"`     // display name to use in the room
+		Account  string  `json:"account"`  // account token, set to record the game on the player's profile
 	}
 	// ClientLeave is sent by a player leaving the room.
 	ClientLeave struct {
@@ -45,15 +55,22 @@ type (
 	ClientKick struct {
 		Player *Player `json:"-"`
 
-		Id string `json:"id"`
+		Id     string `json:"id"`
+		Reason string `json:"reason"` // kept in the audit log, optional
 	}
 	// ClientStart is sent by the room owner to start the game.
 	ClientStart struct {
 		Player *Player `json:"-"`
 	}
+	// ClientEnd is sent by the room owner to end the game and record the results.
+	ClientEnd struct {
+		Player *Player `json:"-"`
+	}
 	// ClientDraw is sent by a player to draw a card.
 	ClientDraw struct {
 		Player *Player `json:"-"`
+
+		bot bool // true if the draw is made on the player's behalf
 	}
 	// ClientSend is sent by a player to send a card to another player.
 	ClientSend struct {
@@ -68,6 +85,144 @@ type (
 		Message     string  `json:"message"`
 		RecipientId *string `json:"recipient"` // RecipientId is set if the message is a private message.
 	}
+	// ClientResume is sent by the room owner to continue a game paused by a disconnect.
+	ClientResume struct {
+		Player *Player `json:"-"`
+
+		Mode ResumeMode `json:"mode"`
+	}
+
+	// ClientReport is sent by a player to report another player in the room to the moderators.
+	// The recent chat and the last moments of the game are attached to the report.
+	ClientReport struct {
+		Player *Player `json:"-"`
+
+		Id      string               `json:"id"`
+		Reason  storage.ReportReason `json:"reason"`
+		Details string               `json:"details"` // optional
+	}
+
+	// ClientVoteKick is sent by a player to start a vote to remove another player.
+	ClientVoteKick struct {
+		Player *Player `json:"-"`
+
+		Id     string `json:"id"`
+		Reason string `json:"reason"` // kept in the audit log if the vote passes, optional
+	}
+	// ClientVoteSuspend is sent by a player to start a vote to suspend the game, so it can be resumed later.
+	ClientVoteSuspend struct {
+		Player *Player `json:"-"`
+	}
+	// ClientVote is sent by a player to vote on the active vote.
+	ClientVote struct {
+		Player *Player `json:"-"`
+
+		Yes bool `json:"yes"`
+	}
+
+	// ClientPredict is sent by a spectator to predict which player wins the game.
+	ClientPredict struct {
+		Player *Player `json:"-"`
+
+		Id string `json:"id"` // player predicted to win
+	}
+
+	// ClientChannelJoin is sent to the hub by a player joining a chat channel.
+	ClientChannelJoin struct {
+		Player *Player `json:"-"`
+
+		Channel string `json:"channel"`
+	}
+	// ClientChannelLeave is sent to the hub by a player leaving a chat channel.
+	ClientChannelLeave struct {
+		Player *Player `json:"-"`
+
+		Channel string `json:"channel"`
+	}
+	// ClientChannelChat is sent to the hub by a player sending a message to a chat channel.
+	ClientChannelChat struct {
+		Player *Player `json:"-"`
+
+		Channel string `json:"channel"`
+		Message string `json:"message"`
+	}
+	// ClientMute is sent to the hub by a player to hide or show chat from another player.
+	ClientMute struct {
+		Player *Player `json:"-"`
+
+		Id   string `json:"id"`
+		Mute bool   `json:"mute"`
+	}
+
+	// ClientReplay is sent to the hub by a player asking for the replay of a match.
+	ClientReplay struct {
+		Player *Player `json:"-"`
+
+		MatchId string `json:"matchId"`
+	}
+
+	// ClientSignIn is sent to the hub by a player signing in to their account, to be shown as online to their friends.
+	// Players in a room have to leave it first.
+	ClientSignIn struct {
+		Player *Player `json:"-"`
+
+		Token string `json:"token"` // the account's token
+	}
+
+	// ClientInvite is sent to the hub by a signed in player inviting an account into their room.
+	ClientInvite struct {
+		Player *Player `json:"-"`
+
+		AccountId string `json:"accountId"`
+	}
+
+	// clientDisconnect is sent internally when a player's connection is lost.
+	clientDisconnect struct {
+		Player *Player
+	}
+	// clientPauseExpired is sent internally when the disconnect grace period is over.
+	clientPauseExpired struct {
+	}
+	// clientTurnTimeout is sent internally when a player runs out of time for their turn.
+	clientTurnTimeout struct {
+		Seq int
+	}
+	// clientVoteExpired is sent internally when a vote times out.
+	clientVoteExpired struct {
+		Vote *Vote
+	}
+	// clientPing is sent internally to the hub or a room to check it still handles messages.
+	clientPing struct {
+		done chan struct{}
+	}
+	// clientClose is sent internally when an admin or the server closes the room.
+	clientClose struct {
+		Admin  *storage.Account // nil when the server closes the room
+		Reason string
+	}
+	// clientSweep is sent internally to a room to check whether it has been abandoned.
+	clientSweep struct {
+		now  int64     // unix ms of the sweep
+		done chan bool // receives whether the room is to be collected
+	}
+	// clientInspect is sent internally to run a function on a room's goroutine.
+	clientInspect struct {
+		fn   func()
+		done chan struct{}
+	}
+	// clientInvite is sent internally to the room of a player inviting an account into it,
+	// once the hub has checked the account takes invites from them.
+	clientInvite struct {
+		Player *Player
+		To     *storage.Account
+		hub    *Hub // where the account is signed in
+	}
+	// clientCatchUp is sent internally to a room to replay the next event to a spectator
+	// joining mid-game.
+	clientCatchUp struct {
+		Player *Player
+		next   int // index of the event in the room's history
+	}
 )
 
 func (c ClientChangeDetails) ClientType() string { return "change_details" }
@@ -75,9 +230,34 @@ func (c ClientJoin) ClientType() string          { return "join" }
 func (c ClientLeave) ClientType() string         { return "leave" }
 func (c ClientKick) ClientType() string          { return "kick" }
 func (c ClientStart) ClientType() string         { return "start" }
+func (c ClientEnd) ClientType() string           { return "end" }
 func (c ClientDraw) ClientType() string          { return "draw" }
 func (c ClientSend) ClientType() string          { return "send" }
 func (c ClientChat) ClientType() string          { return "chat" }
+func (c ClientResume) ClientType() string        { return "resume" }
+func (c ClientReport) ClientType() string        { return "report" }
+func (c ClientVoteKick) ClientType() string      { return "vote_kick" }
+func (c ClientVoteSuspend) ClientType() string   { return "vote_suspend" }
+func (c ClientVote) ClientType() string          { return "vote" }
+func (c ClientPredict) ClientType() string       { return "predict" }
+func (c ClientChannelJoin) ClientType() string   { return "channel_join" }
+func (c ClientChannelLeave) ClientType() string  { return "channel_leave" }
+func (c ClientChannelChat) ClientType() string   { return "channel_chat" }
+func (c ClientMute) ClientType() string          { return "mute" }
+func (c ClientReplay) ClientType() string        { return "replay" }
+func (c ClientSignIn) ClientType() string        { return "sign_in" }
+func (c ClientInvite) ClientType() string        { return "invite" }
+
+func (c clientDisconnect) ClientType() string   { return "disconnect" }
+func (c clientPauseExpired) ClientType() string { return "pause_expired" }
+func (c clientVoteExpired) ClientType() string  { return "vote_expired" }
+func (c clientTurnTimeout) ClientType() string  { return "turn_timeout" }
+func (c clientClose) ClientType() string        { return "close" }
+func (c clientPing) ClientType() string         { return "ping" }
+func (c clientSweep) ClientType() string        { return "sweep" }
+func (c clientCatchUp) ClientType() string      { return "catch_up" }
+func (c clientInvite) ClientType() string       { return "room_invite" }
+func (c clientInspect) ClientType() string      { return "inspect" }
 
 var ClientMessageTypes = slices.AssociateReverseBy([]ClientMessage{
 	ClientChangeDetails{},
@@ -85,17 +265,31 @@ var ClientMessageTypes = slices.AssociateReverseBy([]ClientMessage{
 	ClientLeave{},
 	ClientKick{},
 	ClientStart{},
+	ClientEnd{},
 	ClientDraw{},
 	ClientSend{},
 	ClientChat{},
+	ClientResume{},
+	ClientReport{},
+	ClientVoteKick{},
+	ClientVoteSuspend{},
+	ClientVote{},
+	ClientPredict{},
+	ClientChannelJoin{},
+	ClientChannelLeave{},
+	ClientChannelChat{},
+	ClientMute{},
+	ClientReplay{},
+	ClientSignIn{},
+	ClientInvite{},
 }, func(t ClientMessage) string { return t.ClientType() })
 
 // ClientMessageFromJson converts a byte slice into a ClientMessage.
+//
 //go:todo avoid unmarshalling twice?
 func (p *Player) ClientMessageFromJson(data []byte) (msg ClientMessage, err error) {
 	var payload clientPayload
 	if err := json.Unmarshal(data, &payload); err != nil {
-		log.Printf("error: %v", err)
 		return nil, err
 	}
 
@@ -103,7 +297,6 @@ func (p *Player) ClientMessageFromJson(data []byte) (msg ClientMessage, err erro
 		if payload.Type == name {
 			c := reflect.New(reflect.TypeOf(typeVal))
 			if err := json.Unmarshal(data, c.Interface()); err != nil {
-				log.Printf("error: %v", err)
 				return nil, err
 			}
 			c.Elem().FieldByName("Player").Set(reflect.ValueOf(p))
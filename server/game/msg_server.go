@@ -2,6 +2,10 @@ package game
 
 import (
 	"cardgame/card"
+	"cardgame/cosmetic"
+	"cardgame/locale"
+	"cardgame/progression"
+	"cardgame/storage"
 	"cardgame/util/slices"
 )
 
@@ -25,43 +29,61 @@ type (
 	}
 	// ServerJoin is sent to all players when a new player joins the room.
 	ServerJoin struct {
-		Id     string `json:"id"`
-		Player Player `json:"player"`
+		Id          string `json:"id"`
+		Player      Player `json:"player"`
+		Spectator   bool   `json:"spectator"`
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
 	}
 	// ServerAck is sent to a player when they join the room.
 	ServerAck struct {
+		Token string `json:"token"` // token to reclaim the seat after a disconnect, empty for spectators
 	}
 	// ServerLeave is sent to all players when a player leaves the room.
 	ServerLeave struct {
-		Id string `json:"id"`
+		Id          string `json:"id"`
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
 	}
 	// ServerKick is sent to a player when they are kicked from the room.
 	ServerKick struct {
 	}
+	// ServerRoomClosed is sent to everyone in a room when an admin closes it.
+	ServerRoomClosed struct {
+		Reason string `json:"reason"`
+	}
 	// ServerStart is sent to all players when the game starts.
 	ServerStart struct {
-		CurrentTurn int `json:"currentTurn"`
+		CurrentTurn int    `json:"currentTurn"`
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
 	}
 	// ServerDraw is sent to all players when a player draws a card.
 	ServerDraw struct {
-		PlayerId string     `json:"playerId"`
-		Card     *card.Card `json:"card"`
+		PlayerId    string     `json:"playerId"`
+		Card        *card.Card `json:"card"`
+		Description string     `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
 	}
 	// ServerWildCard is sent to all players when a player draws a wild card.
 	ServerWildCard struct {
-		PlayerId string         `json:"playerId"`
-		Card     *card.WildCard `json:"card"`
+		PlayerId    string         `json:"playerId"`
+		Card        *card.WildCard `json:"card"`
+		Description string         `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
 	}
 	// ServerReshuffle is sent to a player when the deck is reshuffled.
 	// This event is sent individually to each player to update their own deck.
 	ServerReshuffle struct {
-		Player *Player
+		Player *Player `json:"player"`
 	}
 	// ServerSend is sent to the sender and the recipient when a card is sent.
 	ServerSend struct {
 		SenderId    string     `json:"senderId"`
 		RecipientId string     `json:"recipientId"`
 		Card        *card.Card `json:"card"`
+		Description string     `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
 	}
 	// ServerChat is sent to all players when a chat message is sent.
 	ServerChat struct {
@@ -76,28 +98,247 @@ type (
 	}
 	// ServerTurn is sent to all players when a player's turn begins.
 	ServerTurn struct {
+		PlayerId    string `json:"playerId"`
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
+	}
+	// ServerCatchUpStart is sent to a spectator joining mid-game before the events of the game so far are replayed.
+	ServerCatchUpStart struct {
+		Events int `json:"events"` // number of events that will be replayed
+	}
+	// ServerCatchUpEnd is sent to a spectator once the replayed events are over and live events follow.
+	ServerCatchUpEnd struct {
+	}
+	// ServerPause is sent to all players when the game is paused because a player disconnected.
+	ServerPause struct {
+		PlayerId    string `json:"playerId"`              // disconnected player
+		Grace       int    `json:"grace"`                 // seconds the player has to reconnect
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
+	}
+	// ServerPauseExpired is sent to all players when the grace period is over and the owner has to decide how to continue.
+	ServerPauseExpired struct {
+	}
+	// ServerReconnect is sent to all players when a disconnected player reclaims their seat.
+	ServerReconnect struct {
+		Id          string `json:"id"`
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
+	}
+	// ServerResume is sent to all players when a paused game continues.
+	ServerResume struct {
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
+	}
+	// ServerVoid is sent to all players when the owner voids a paused game and the room returns to the lobby.
+	ServerVoid struct {
+	}
+	// ServerVoteKick is sent to all players when a vote to remove a player starts.
+	ServerVoteKick struct {
+		InitiatorId string `json:"initiatorId"`
+		TargetId    string `json:"targetId"`
+		Timeout     int    `json:"timeout"` // seconds until the vote fails
+	}
+	// ServerVoteSuspend is sent to all players when a vote to suspend the game starts.
+	ServerVoteSuspend struct {
+		InitiatorId string `json:"initiatorId"`
+		Timeout     int    `json:"timeout"` // seconds until the vote fails
+	}
+	// ServerVote is sent to all players when a player votes on the active vote.
+	ServerVote struct {
 		PlayerId string `json:"playerId"`
+		Yes      bool   `json:"yes"`
+	}
+	// ServerVoteResult is sent to all players when the active vote is decided.
+	ServerVoteResult struct {
+		Kind     VoteKind `json:"kind"`
+		TargetId string   `json:"targetId"`
+		Passed   bool     `json:"passed"`
+	}
+	// ServerPrediction is sent to the spectators when one of them predicts the winner of the game.
+	ServerPrediction struct {
+		PlayerId string `json:"playerId"` // spectator who made the prediction
+		Id       string `json:"id"`       // player predicted to win
+	}
+	// ServerPredictionResult is sent to all players when a game the spectators made predictions on ends.
+	ServerPredictionResult struct {
+		Winners    []string          `json:"winners"`    // ids of the players ranked first
+		Scoreboard []PredictionScore `json:"scoreboard"` // of every spectator who made a prediction in the room, best first
+	}
+	// ServerSuspend is sent to all players when the game is suspended and the room returns to the lobby.
+	ServerSuspend struct {
+		GameId string `json:"gameId"` // id to resume the game with
+	}
+	// ServerResumeGame is sent to all players when every player of a suspended game is back and it continues.
+	ServerResumeGame struct {
+		GameId      string `json:"gameId"`
+		CurrentTurn int    `json:"currentTurn"`
+	}
+	// ServerTurnTimeout is sent to all players when a player runs out of time and draws automatically.
+	ServerTurnTimeout struct {
+		PlayerId    string `json:"playerId"`
+		Missed      int    `json:"missed"`                // consecutive turns the player has missed
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
+	}
+	// ServerAfk is sent to all players when a bot takes over for an AFK player, or when they return.
+	ServerAfk struct {
+		PlayerId    string `json:"playerId"`
+		Afk         bool   `json:"afk"`
+		Description string `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
+	}
+	// ServerChannelJoin is sent to a player joining a chat channel, with the most recent messages.
+	ServerChannelJoin struct {
+		Channel string               `json:"channel"`
+		History []*ServerChannelChat `json:"history"` // oldest first
+	}
+	// ServerChannelChat is sent to the members of a chat channel when a message is sent to it.
+	ServerChannelChat struct {
+		Channel   string `json:"channel"`
+		Timestamp string `json:"timestamp"`
+		PlayerId  string `json:"player"`
+		Name      string `json:"name"`
+		Message   string `json:"message"`
+	}
+	// ServerEnd is sent to all players when the game is over, with the results ordered by rank.
+	ServerEnd struct {
+		MatchId     string                `json:"matchId"`
+		Results     []storage.MatchPlayer `json:"results"`
+		Description string                `json:"description,omitempty"` // of the event, for screen readers
+		narration   *narration
+	}
+	// ServerReplayStart is sent to a player before the events of a replay they asked for.
+	ServerReplayStart struct {
+		MatchId string `json:"matchId"`
+		Seed    int64  `json:"seed"`
+		Events  int    `json:"events"` // number of ServerReplayEvent messages that follow
+	}
+	// ServerReplayEvent is a message sent during the replayed game.
+	ServerReplayEvent struct {
+		MatchId string                 `json:"matchId"`
+		Time    int64                  `json:"time"` // ms since the game started
+		Event   map[string]interface{} `json:"event"`
+	}
+	// ServerReplayEnd is sent to a player after the last event of a replay.
+	ServerReplayEnd struct {
+		MatchId string `json:"matchId"`
+	}
+	// ServerAchievement is sent to a player when they unlock an achievement.
+	ServerAchievement struct {
+		Id          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	// ServerExperience is sent to a player when a game earns them experience.
+	ServerExperience struct {
+		Earned  int  `json:"earned"`
+		XP      int  `json:"xp"` // experience after the game
+		Level   int  `json:"level"`
+		LevelUp bool `json:"levelUp"`
+	}
+	// ServerQuest is sent to a player when they complete a quest.
+	ServerQuest struct {
+		Id     string             `json:"id"`
+		Name   string             `json:"name"`
+		Reward progression.Reward `json:"reward"`
+	}
+	// ServerCosmetic is sent to a player when they unlock a cosmetic item.
+	ServerCosmetic struct {
+		Id   string        `json:"id"`
+		Name string        `json:"name"`
+		Kind cosmetic.Kind `json:"kind"`
+	}
+	// ServerChallenge is sent to a player when they finish a daily challenge.
+	ServerChallenge struct {
+		Day    string `json:"day"`
+		Score  int    `json:"score"`  // cards sent
+		Streak int    `json:"streak"` // challenges completed in a row
+	}
+	// ServerSignIn is sent to a player when they signed in to their account.
+	ServerSignIn struct {
+		AccountId string   `json:"accountId"`
+		Online    []string `json:"online"` // account ids of the friends who are online
+	}
+	// ServerPresence is sent to the online friends of an account when it comes online or goes offline.
+	ServerPresence struct {
+		AccountId string `json:"accountId"`
+		Online    bool   `json:"online"`
+	}
+	// ServerFriend is sent to a player when they get a friend request, or a request they sent is accepted.
+	ServerFriend struct {
+		AccountId string `json:"accountId"`
+		Name      string `json:"name"`
+		Status    string `json:"status"` // "incoming" for a new request, "accepted" once it is accepted
+	}
+	// ServerInvite is sent to a player when someone invites them into their room.
+	ServerInvite struct {
+		RoomId    string `json:"roomId"`
+		RoomName  string `json:"roomName"`
+		AccountId string `json:"accountId"` // account of the player who sent the invite
+		Name      string `json:"name"`
+	}
+	// ServerReported is sent to a player when their report was received.
+	ServerReported struct {
+		ReportId string `json:"reportId"`
 	}
 	// ServerError is sent to a player when an error occurs.
 	ServerError struct {
-		Message string `json:"message"`
+		Message string        `json:"message"`          // in the language of the player
+		Id      string        `json:"id,omitempty"`     // of the message, for clients to translate it themselves
+		Params  locale.Params `json:"params,omitempty"` // filled into the message
+		Code    string        `json:"code,omitempty"`   // kind of the error, see package errs
 	}
 )
 
-func (s ServerChangeDetails) ServerType() string { return "change_details" }
-func (s ServerJoin) ServerType() string          { return "join" }
-func (s ServerAck) ServerType() string           { return "ack" }
-func (s ServerLeave) ServerType() string         { return "leave" }
-func (s ServerKick) ServerType() string          { return "kick" }
-func (s ServerStart) ServerType() string         { return "start" }
-func (s ServerDraw) ServerType() string          { return "draw" }
-func (s ServerWildCard) ServerType() string      { return "wild_card" }
-func (s ServerReshuffle) ServerType() string     { return "reshuffle" }
-func (s ServerSend) ServerType() string          { return "send" }
-func (s ServerChat) ServerType() string          { return "chat" }
-func (s ServerResync) ServerType() string        { return "resync" }
-func (s ServerTurn) ServerType() string          { return "turn" }
-func (s ServerError) ServerType() string         { return "error" }
+func (s ServerChangeDetails) ServerType() string    { return "change_details" }
+func (s ServerJoin) ServerType() string             { return "join" }
+func (s ServerAck) ServerType() string              { return "ack" }
+func (s ServerLeave) ServerType() string            { return "leave" }
+func (s ServerKick) ServerType() string             { return "kick" }
+func (s ServerRoomClosed) ServerType() string       { return "room_closed" }
+func (s ServerStart) ServerType() string            { return "start" }
+func (s ServerDraw) ServerType() string             { return "draw" }
+func (s ServerWildCard) ServerType() string         { return "wild_card" }
+func (s ServerReshuffle) ServerType() string        { return "reshuffle" }
+func (s ServerSend) ServerType() string             { return "send" }
+func (s ServerChat) ServerType() string             { return "chat" }
+func (s ServerResync) ServerType() string           { return "resync" }
+func (s ServerTurn) ServerType() string             { return "turn" }
+func (s ServerCatchUpStart) ServerType() string     { return "catch_up_start" }
+func (s ServerCatchUpEnd) ServerType() string       { return "catch_up_end" }
+func (s ServerPause) ServerType() string            { return "pause" }
+func (s ServerPauseExpired) ServerType() string     { return "pause_expired" }
+func (s ServerReconnect) ServerType() string        { return "reconnect" }
+func (s ServerResume) ServerType() string           { return "resume" }
+func (s ServerVoid) ServerType() string             { return "void" }
+func (s ServerEnd) ServerType() string              { return "end" }
+func (s ServerReplayStart) ServerType() string      { return "replay_start" }
+func (s ServerReplayEvent) ServerType() string      { return "replay_event" }
+func (s ServerReplayEnd) ServerType() string        { return "replay_end" }
+func (s ServerVoteKick) ServerType() string         { return "vote_kick" }
+func (s ServerVoteSuspend) ServerType() string      { return "vote_suspend" }
+func (s ServerVote) ServerType() string             { return "vote" }
+func (s ServerVoteResult) ServerType() string       { return "vote_result" }
+func (s ServerPrediction) ServerType() string       { return "prediction" }
+func (s ServerPredictionResult) ServerType() string { return "prediction_result" }
+func (s ServerSuspend) ServerType() string          { return "suspend" }
+func (s ServerResumeGame) ServerType() string       { return "resume_game" }
+func (s ServerTurnTimeout) ServerType() string      { return "turn_timeout" }
+func (s ServerAfk) ServerType() string              { return "afk" }
+func (s ServerChannelJoin) ServerType() string      { return "channel_join" }
+func (s ServerChannelChat) ServerType() string      { return "channel_chat" }
+func (s ServerAchievement) ServerType() string      { return "achievement" }
+func (s ServerExperience) ServerType() string       { return "experience" }
+func (s ServerQuest) ServerType() string            { return "quest" }
+func (s ServerCosmetic) ServerType() string         { return "cosmetic" }
+func (s ServerChallenge) ServerType() string        { return "challenge" }
+func (s ServerSignIn) ServerType() string           { return "sign_in" }
+func (s ServerPresence) ServerType() string         { return "presence" }
+func (s ServerFriend) ServerType() string           { return "friend" }
+func (s ServerInvite) ServerType() string           { return "invite" }
+func (s ServerReported) ServerType() string         { return "reported" }
+func (s ServerError) ServerType() string            { return "error" }
 
 var ServerMessageTypes = slices.AssociateReverseBy([]ServerMessage{
 	ServerChangeDetails{},
@@ -105,12 +346,47 @@ var ServerMessageTypes = slices.AssociateReverseBy([]ServerMessage{
 	ServerAck{},
 	ServerLeave{},
 	ServerKick{},
+	ServerRoomClosed{},
 	ServerStart{},
 	ServerDraw{},
 	ServerWildCard{},
 	ServerReshuffle{},
+	ServerSend{},
 	ServerChat{},
 	ServerResync{},
 	ServerTurn{},
+	ServerCatchUpStart{},
+	ServerCatchUpEnd{},
+	ServerPause{},
+	ServerPauseExpired{},
+	ServerReconnect{},
+	ServerResume{},
+	ServerVoid{},
+	ServerEnd{},
+	ServerReplayStart{},
+	ServerReplayEvent{},
+	ServerReplayEnd{},
+	ServerVoteKick{},
+	ServerVoteSuspend{},
+	ServerVote{},
+	ServerVoteResult{},
+	ServerPrediction{},
+	ServerPredictionResult{},
+	ServerSuspend{},
+	ServerResumeGame{},
+	ServerTurnTimeout{},
+	ServerAfk{},
+	ServerChannelJoin{},
+	ServerChannelChat{},
+	ServerAchievement{},
+	ServerExperience{},
+	ServerQuest{},
+	ServerCosmetic{},
+	ServerChallenge{},
+	ServerSignIn{},
+	ServerPresence{},
+	ServerFriend{},
+	ServerInvite{},
+	ServerReported{},
 	ServerError{},
 }, func(t ServerMessage) string { return t.ServerType() })
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"cardgame/card"
+	"cardgame/locale"
+	"cardgame/storage"
+	"strings"
+)
+
+// narration describes a game event in words, for players using a screen reader, so their
+// clients don't have to work out what happened from the state of the room. It is the id of
+// a message of package locale and its parameters, and is put into words in the language of
+// each player when the event is sent.
+type narration struct {
+	id     string
+	params locale.Params
+	card   card.BaseCard // filled into the message as {card}, if any
+}
+
+func narrate(id string, params locale.Params) *narration {
+	return &narration{id: id, params: params}
+}
+
+// withCard sets the card the event is about.
+func (n *narration) withCard(c card.BaseCard) *narration {
+	n.card = c
+	return n
+}
+
+// describe returns the narration in a language, or nothing for events without one.
+func (n *narration) describe(lang string) string {
+	if n == nil {
+		return ""
+	}
+	params := n.params
+	if n.card != nil {
+		params = locale.Params{"card": describeCard(lang, n.card)}
+		for k, v := range n.params {
+			params[k] = v
+		}
+	}
+	return locale.Translate(lang, n.id, params)
+}
+
+// cardTypeIds are the messages naming each card type.
+var cardTypeIds = [...]string{
+	card.Lines:  "card_lines",
+	card.Waves:  "card_waves",
+	card.Square: "card_square",
+	card.Dots:   "card_dots",
+	card.Hash:   "card_hash",
+	card.Circle: "card_circle",
+	card.Plus:   "card_plus",
+	card.Star:   "card_star",
+}
+
+func describeCardType(lang string, t card.CardType) string {
+	if t < 0 || int(t) >= len(cardTypeIds) {
+		return t.String()
+	}
+	return locale.Translate(lang, cardTypeIds[t], nil)
+}
+
+// describeCard puts a card into words, like "Mountain Range (star)".
+func describeCard(lang string, c card.BaseCard) string {
+	switch c := c.(type) {
+	case *card.Card:
+		return locale.Translate(lang, "card", locale.Params{
+			"category": c.Category,
+			"type":     describeCardType(lang, c.Type),
+		})
+	case *card.WildCard:
+		return locale.Translate(lang, "wild_card", locale.Params{
+			"first":  describeCardType(lang, c.Types[0]),
+			"second": describeCardType(lang, c.Types[1]),
+		})
+	}
+	return ""
+}
+
+// narrateEnd describes the end of a game by its winners, the players ranked first.
+func narrateEnd(results []storage.MatchPlayer) *narration {
+	winners := []string{}
+	for _, p := range results {
+		if p.Rank == 1 {
+			winners = append(winners, p.Name)
+		}
+	}
+	return narrate("event_end", locale.Params{"winners": strings.Join(winners, ", ")})
+}
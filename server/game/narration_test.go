@@ -0,0 +1,42 @@
+package game
+
+import (
+	"cardgame/card"
+	"cardgame/locale"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNarration(t *testing.T) {
+	assert.NoError(t, locale.Load("../data/locales"))
+	alice, bob := newTestPlayer("p_alice"), newTestPlayer("p_bob")
+	alice.Name, bob.Name = "Alice", "Bob"
+	bob.locale = "es"
+	r := startTestGame(t, alice, bob)
+	r.CurrentTurn = 0
+	r.DrawPile = []card.BaseCard{
+		&card.Card{Id: "c_1", Type: card.Star, Category: "Mountain Range"},
+		&card.WildCard{Id: "w_1", Types: []card.CardType{card.Lines, card.Waves}},
+		&card.Card{Id: "c_2", Type: card.Star, Category: "Cell Phone Brand"},
+	}
+	r.DrawPileSize = len(r.DrawPile)
+
+	r.HandleDraw(ClientDraw{Player: alice})
+	assert.Equal(t, "Alice drew Mountain Range (star)", receiveUntil[*ServerDraw](t, alice).Description)
+	assert.Equal(t, "It's Bob's turn", receiveUntil[*ServerTurn](t, alice).Description)
+	assert.Equal(t, "Alice robó Mountain Range (estrella)", receiveUntil[*ServerDraw](t, bob).Description,
+		"events should be described in the language of each player")
+	assert.Equal(t, "Es el turno de Bob", receiveUntil[*ServerTurn](t, bob).Description)
+
+	r.HandleDraw(ClientDraw{Player: bob})
+	assert.Equal(t, "Bob drew a wild card: lines and waves match", receiveUntil[*ServerWildCard](t, alice).Description)
+	r.HandleDraw(ClientDraw{Player: bob})
+
+	r.CurrentTurn = 0
+	r.HandleSend(ClientSend{Player: bob, RecipientId: alice.Id})
+	assert.Equal(t, "Bob sent Cell Phone Brand (star) to Alice", receiveUntil[*ServerSend](t, alice).Description)
+
+	r.HandleLeave(ClientLeave{Player: bob})
+	assert.Equal(t, "Bob left the room", receiveUntil[*ServerLeave](t, alice).Description)
+}
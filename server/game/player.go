@@ -1,13 +1,18 @@
 package game
 
 import (
-	"cardgame/card"
+	"cardgame/chaos"
+	"cardgame/engine"
 	"cardgame/util"
+	"cardgame/util/ratelimit"
 	"cardgame/words"
 	"encoding/json"
-	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/structs"
@@ -28,41 +33,189 @@ const (
 	maxMessageSize = 1 << 20
 )
 
-type Player struct {
-	Id       string       `json:"id"`
-	Avatar   AvatarConfig `json:"avatar"`
-	Name     string       `json:"name"`
-	Score    int          `json:"score"`
-	Hand     PlayerHand   `json:"cards"` // Player's hand, top is at the end
-	socket   *websocket.Conn
-	room     *Room
-	outbound chan ServerMessage // outgoing server messages
-}
-
-type PlayerHand []*card.Card
-
-// top returns the top card of the player's hand.
-func (h PlayerHand) top() *card.Card {
-	if len(h) == 0 {
-		return nil
-	}
-	return h[len(h)-1]
+// currentChaos is how badly connections opened now are treated, or nil to leave them alone.
+var currentChaos atomic.Pointer[chaos.Config]
+
+// SetChaos makes the connections opened afterwards delay, drop and reorder messages, to test
+// how the game holds up on bad networks. A nil config leaves them alone.
+func SetChaos(c *chaos.Config) {
+	currentChaos.Store(c)
 }
 
-// tail returns all cards from the player's hand except the top card.
-func (h PlayerHand) tail() PlayerHand {
-	if len(h) == 0 {
-		return PlayerHand{}
-	}
-	return h[:len(h)-1]
+type Player struct {
+	Id           string             `json:"id"`
+	AccountId    string             `json:"accountId"` // id of the player's account, or empty for guests
+	Avatar       AvatarConfig       `json:"avatar"`
+	AvatarUrl    string             `json:"avatarUrl"`  // uploaded avatar image shown instead of Avatar, if set
+	CardBack     string             `json:"cardBack"`   // cosmetic the player's cards are shown with, empty for the default
+	TableTheme   string             `json:"tableTheme"` // cosmetic the table of the player's rooms is shown with, empty for the default
+	Name         string             `json:"name"`
+	Score        int                `json:"score"`
+	Hand         PlayerHand         `json:"cards"`        // Player's hand, top is at the end
+	Disconnected bool               `json:"disconnected"` // true while the player has lost their connection mid-game
+	Bot          bool               `json:"bot"`          // true if a bot has taken over the player's seat
+	Afk          bool               `json:"afk"`          // true if the player missed too many turns and a bot is playing for them
+	missedTurns  int                // consecutive turns that timed out
+	draws        int                // cards drawn in the current game
+	sends        int                // cards sent in the current game
+	muted        set                // ids of players whose chat is hidden from this player
+	chaos        *chaos.Config      // how badly the connection's messages are treated, or nil
+	blocks       *blockList         // accounts blocked by the player's account, nil for guests
+	chatLimit    *ratelimit.Limiter // limits how often the player can chat
+	socket       *websocket.Conn
+	userAgent    string // browser or app the connection was opened from
+	protocol     int    // version of the messages the connection speaks, see ProtocolVersion
+	locale       string // language of the text sent to the connection
+	sessionId    string // session of the account the connection signed in with, guarded by Hub.onlineMu
+	room         *Room
+	token        string             // secret used to reclaim the seat after a disconnect
+	outbound     chan ServerMessage // outgoing server messages
+	done         chan struct{}      // closed once nothing reads outbound anymore
 }
 
+// PlayerHand is the cards of a player, top is at the end.
+type PlayerHand = engine.Hand
+
 type AvatarConfig struct {
 	Eyes  int `json:"eyes"`
 	Mouth int `json:"mouth"`
 	Color int `json:"color"`
 }
 
+// roomMu guards Player.room, the room of every player, which the hub, their room and their
+// connection all need to know.
+var roomMu sync.RWMutex
+
+// currentRoom returns the room the player is in, or nil.
+func (p *Player) currentRoom() *Room {
+	roomMu.RLock()
+	defer roomMu.RUnlock()
+	return p.room
+}
+
+func (p *Player) setRoom(r *Room) {
+	roomMu.Lock()
+	defer roomMu.Unlock()
+	p.room = r
+}
+
+// send queues a message for the player from their room's goroutine. If the connection is
+// gone, the message is dropped. The message is encoded right away, with the state of the room
+// as it is now, which only the room's goroutine may read.
+func (p *Player) send(message ServerMessage) {
+	p.queue(message, p.currentRoom())
+}
+
+// notify queues a message for the player from outside their room, like the hub and the API
+// do. It is sent without the state of the room.
+func (p *Player) notify(message ServerMessage) {
+	p.queue(message, nil)
+}
+
+func (p *Player) queue(message ServerMessage, room *Room) {
+	if m, ok := message.(localized); ok {
+		message = m.localize(p.locale)
+	}
+	select {
+	case <-p.done:
+		if m, ok := message.(*sharedMessage); ok {
+			m.release()
+		}
+		return
+	default:
+	}
+	if _, ok := message.(*sharedMessage); !ok {
+		shared, err := shareMessage(message, room, 1)
+		if err != nil {
+			slog.Error("failed to encode message", "player", p.Id, "type", message.ServerType(), "err", err)
+			return
+		}
+		message = shared
+	}
+	select {
+	case p.outbound <- message:
+	case <-p.done:
+		if m, ok := message.(*sharedMessage); ok {
+			m.release()
+		}
+	}
+}
+
+// allowChat reports whether the player may send another chat message right now.
+func (p *Player) allowChat() bool {
+	return p.chatLimit == nil || p.chatLimit.Allow()
+}
+
+// mutedMu guards Player.muted, which the hub changes while rooms filter chat with it.
+var mutedMu sync.RWMutex
+
+// hasMuted returns true if the player has hidden chat from the player with the given id.
+func (p *Player) hasMuted(id string) bool {
+	mutedMu.RLock()
+	defer mutedMu.RUnlock()
+	_, ok := p.muted[id]
+	return ok
+}
+
+// setMuted hides or shows chat from the player with the given id.
+func (p *Player) setMuted(id string, muted bool) {
+	mutedMu.Lock()
+	defer mutedMu.Unlock()
+	if p.muted == nil {
+		p.muted = set{}
+	}
+	if muted {
+		p.muted[id] = struct{}{}
+	} else {
+		delete(p.muted, id)
+	}
+}
+
+// identityMu guards who every player is: Player.Id, AccountId, Name and blocks. Reconnecting
+// hands a seat's identity over to a new connection from the room's goroutine, while the hub
+// reads it for channels and mutes.
+var identityMu sync.RWMutex
+
+// playerIdentity is who a player is, as read from outside their room.
+type playerIdentity struct {
+	id        string
+	accountId string
+	name      string
+	blocks    *blockList
+}
+
+// identity returns who the player is. The room's goroutine can read the fields directly.
+func (p *Player) identity() playerIdentity {
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+	return playerIdentity{p.Id, p.AccountId, p.Name, p.blocks}
+}
+
+// encodeMessage writes a server message as JSON in the format sent over the socket,
+// with its type and the state of the room it was sent from.
+func encodeMessage(w io.Writer, message ServerMessage, room *Room) error {
+	if m, ok := message.(*sharedMessage); ok {
+		_, err := w.Write(m.buf.Bytes())
+		return err
+	}
+	if m, ok := message.(fieldAppender); ok {
+		return encodeFields(w, m, room)
+	}
+	m := messageMap(message)
+	m["room"] = room
+
+	return json.NewEncoder(w).Encode(m)
+}
+
+// messageMap converts a server message to a map of its JSON fields and its type.
+func messageMap(message ServerMessage) map[string]interface{} {
+	s := structs.New(message)
+	s.TagName = "json"
+	m := s.Map()
+	m["type"] = message.ServerType()
+	return m
+}
+
 // read pumps messages from the websocket connection to the room.
 //
 // The application runs read in a per-connection goroutine. The application
@@ -70,9 +223,34 @@ type AvatarConfig struct {
 // reads from this goroutine.
 func (p *Player) read() {
 	defer func() {
-		p.room.inbound <- ClientLeave{p}
+		if r := p.currentRoom(); r != nil {
+			r.post(clientDisconnect{p})
+		}
+		HubMain.inbound <- &hubMessage{
+			clientMessage: clientDisconnect{p},
+			player:        p,
+		}
 		p.socket.Close()
+		connectionsOpen.Add("", -1)
 	}()
+	dispatch := func(data []byte) { HubMain.dispatch(p, data) }
+	if p.chaos != nil {
+		in := make(chan []byte)
+		out := chaos.Chan(p.chaos, in, nil)
+		dispatched := make(chan struct{})
+		go func() {
+			for data := range out {
+				HubMain.dispatch(p, data)
+			}
+			close(dispatched)
+		}()
+		dispatch = func(data []byte) { in <- data }
+		// messages still on their way are dispatched before the disconnect
+		defer func() {
+			close(in)
+			<-dispatched
+		}()
+	}
 	p.socket.SetReadLimit(maxMessageSize)
 	p.socket.SetReadDeadline(time.Now().Add(pongWait))
 	p.socket.SetPongHandler(func(string) error { p.socket.SetReadDeadline(time.Now().Add(pongWait)); return nil })
@@ -80,28 +258,12 @@ func (p *Player) read() {
 		_, mesageData, err := p.socket.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				slog.Warn("connection closed unexpectedly", "player", p.Id, "err", err)
 			}
 			break
 		}
 
-		msg, err := p.ClientMessageFromJson(mesageData)
-		if err != nil {
-			log.Printf("error: %v", err)
-			p.outbound <- ServerError{
-				Message: err.Error(),
-			}
-			return
-		}
-
-		if p.room == nil {
-			HubMain.inbound <- &hubMessage{
-				clientMessage: msg,
-				player:        p,
-			}
-		} else {
-			p.room.inbound <- msg
-		}
+		dispatch(mesageData)
 	}
 }
 
@@ -112,13 +274,20 @@ func (p *Player) read() {
 // executing all writes from this goroutine.
 func (p *Player) write() {
 	ticker := time.NewTicker(pingPeriod)
+	outbound := (<-chan ServerMessage)(p.outbound)
+	stop := make(chan struct{})
+	if p.chaos != nil {
+		outbound = chaos.Chan(p.chaos, outbound, stop)
+	}
 	defer func() {
 		ticker.Stop()
 		p.socket.Close()
+		close(stop)
+		close(p.done)
 	}()
 	for {
 		select {
-		case message, ok := <-p.outbound:
+		case message, ok := <-outbound:
 			p.socket.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// room closed
@@ -126,25 +295,22 @@ func (p *Player) write() {
 				return
 			}
 
-			fmt.Println("player->", message)
+			slog.Debug("sending message", "player", p.Id, "type", message.ServerType())
 
-			w, err := p.socket.NextWriter(websocket.TextMessage)
-			if err != nil {
-				log.Printf("error: %v\n", err)
-				return
+			// messages are encoded as they are sent, by the goroutine owning their room
+			data, release, err := encoded(message, nil)
+			if err == nil {
+				data, err = p.downgrade(data)
 			}
-
-			s := structs.New(message)
-			s.TagName = "json"
-			m := s.Map()
-			m["type"] = message.ServerType()
-			m["room"] = p.room
-
-			if err := json.NewEncoder(w).Encode(m); err != nil {
+			if err != nil {
+				slog.Warn("failed to encode message", "player", p.Id, "type", message.ServerType(), "err", err)
 				return
 			}
-
-			if err := w.Close(); err != nil {
+			messageBytes.Observe(float64(len(data)))
+			err = p.socket.WriteMessage(websocket.TextMessage, data)
+			release()
+			if err != nil {
+				slog.Warn("failed to write message", "player", p.Id, "err", err)
 				return
 			}
 		case <-ticker.C:
@@ -156,15 +322,26 @@ func (p *Player) write() {
 	}
 }
 
-func NewPlayer(socket *websocket.Conn) *Player {
+func NewPlayer(socket *websocket.Conn, userAgent, language string) *Player {
+	rate := currentChatRate.Load()
 	p := &Player{
-		Id:       util.IdFrom("p", socket.RemoteAddr().String()),
-		Name:     strings.Join(words.Words(words.English, 2), " "),
-		socket:   socket,
-		Hand:     PlayerHand{},
-		outbound: make(chan ServerMessage),
+		Id:        util.IdFrom("p", socket.RemoteAddr().String()),
+		Name:      strings.Join(words.Words(words.English, 2), " "),
+		socket:    socket,
+		userAgent: userAgent,
+		protocol:  protocolVersion(socket.Subprotocol()),
+		locale:    language,
+		Hand:      PlayerHand{},
+		token:     util.Token(),
+		muted:     set{},
+		chatLimit: ratelimit.New(rate.n, rate.interval),
+		chaos:     currentChaos.Load(),
+		outbound:  make(chan ServerMessage),
+		done:      make(chan struct{}),
 	}
 
+	connectionsOpen.Add("", 1)
+	connectionsProtocol.Inc(strconv.Itoa(p.protocol))
 	go p.read()
 	go p.write()
 
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"cardgame/storage"
+	"cardgame/util/slices"
+	"sort"
+)
+
+// PredictionWindow is the number of seconds spectators have to predict the winner once a game
+// starts, or 0 to take predictions until the end, set on startup.
+var PredictionWindow = 120
+
+// PredictionScore is how well a spectator predicted the winners of the games of a room.
+type PredictionScore struct {
+	PlayerId    string `json:"playerId"`
+	Name        string `json:"name"`
+	Correct     int    `json:"correct"`     // predictions of a winner
+	Predictions int    `json:"predictions"` // games predicted
+}
+
+// HandlePredict takes a spectator's prediction of who wins the game. Predictions are just for
+// fun: one per spectator and game, shown to the other spectators only, and scored on the
+// room's scoreboard once the game ends.
+func (r *Room) HandlePredict(message ClientPredict) {
+	p := message.Player
+
+	if !r.isSpectator(p) {
+		r.logger().Warn("only spectators can predict")
+		p.send(&ServerError{Id: "prediction_spectator"})
+		return
+	}
+
+	if r.GamePhase != GamePhasePlaying {
+		r.logger().Warn("game is not in playing phase")
+		p.send(&ServerError{Id: "game_not_playing"})
+		return
+	}
+
+	if PredictionWindow > 0 && Clock.Now().UnixMilli()-r.started > int64(PredictionWindow)*1000 {
+		r.logger().Warn("predictions are closed")
+		p.send(&ServerError{Id: "prediction_closed"})
+		return
+	}
+
+	if _, ok := r.predictions[p.Id]; ok {
+		r.logger().Warn("spectator has already predicted")
+		p.send(&ServerError{Id: "prediction_made"})
+		return
+	}
+
+	if r.getPlayer(message.Id) == nil {
+		r.logger().Warn("target player not found")
+		p.send(&ServerError{Id: "target_not_found"})
+		return
+	}
+
+	if r.predictions == nil {
+		r.predictions = map[string]string{}
+	}
+	r.predictions[p.Id] = message.Id
+
+	// players shouldn't be swayed by what the audience thinks
+	players := set{}
+	for _, player := range r.Players {
+		players[player.Id] = struct{}{}
+	}
+	r.publish(&serverPayload{
+		exclude: players,
+		message: &ServerPrediction{PlayerId: p.Id, Id: message.Id},
+	})
+}
+
+// resolvePredictions scores the predictions of the game that just ended, and tells the room
+// how the spectators did.
+func (r *Room) resolvePredictions(results []storage.MatchPlayer) {
+	if len(r.predictions) == 0 {
+		return
+	}
+
+	winners := []string{}
+	for _, p := range results {
+		if p.Rank == 1 {
+			winners = append(winners, p.Id)
+		}
+	}
+	if r.predictionScores == nil {
+		r.predictionScores = map[string]*PredictionScore{}
+	}
+	r.mu.Lock()
+	spectators := append([]*Player{}, r.Spectators...)
+	r.mu.Unlock()
+	for spectatorId, id := range r.predictions {
+		score, ok := r.predictionScores[spectatorId]
+		if !ok {
+			score = &PredictionScore{PlayerId: spectatorId}
+			r.predictionScores[spectatorId] = score
+		}
+		for _, s := range spectators {
+			if s.Id == spectatorId {
+				score.Name = s.Name
+			}
+		}
+		score.Predictions++
+		if slices.Contains(winners, id) {
+			score.Correct++
+		}
+	}
+	r.predictions = nil
+
+	r.publish(&serverPayload{
+		message: &ServerPredictionResult{
+			Winners:    winners,
+			Scoreboard: r.predictionScoreboard(),
+		},
+	})
+}
+
+// predictionScoreboard returns the scores of the spectators of the room, best first.
+func (r *Room) predictionScoreboard() []PredictionScore {
+	scoreboard := make([]PredictionScore, 0, len(r.predictionScores))
+	for _, s := range r.predictionScores {
+		scoreboard = append(scoreboard, *s)
+	}
+	sort.Slice(scoreboard, func(i, j int) bool {
+		a, b := scoreboard[i], scoreboard[j]
+		if a.Correct != b.Correct {
+			return a.Correct > b.Correct
+		}
+		if a.Predictions != b.Predictions {
+			return a.Predictions < b.Predictions
+		}
+		return a.PlayerId < b.PlayerId
+	})
+	return scoreboard
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"cardgame/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredictions(t *testing.T) {
+	withTestStore(t)
+	c := withTestClock(t)
+	owner, other := newTestPlayer("p_owner"), newTestPlayer("p_other")
+	right, wrong, late := newTestPlayer("p_right"), newTestPlayer("p_wrong"), newTestPlayer("p_late")
+	right.Name = "Right"
+
+	r := newTestRoom(t)
+	r.Decks = append(r.Decks, newTestDeck(20))
+	r.TurnTimeout = 0
+	for _, p := range []*Player{owner, other} {
+		joinTestRoom(t, r, p, false)
+	}
+	for _, p := range []*Player{right, wrong, late} {
+		joinTestRoom(t, r, p, true)
+	}
+
+	r.HandlePredict(ClientPredict{Player: right, Id: other.Id})
+	assert.Equal(t, "game is not in playing phase", receiveUntil[*ServerError](t, right).Message)
+
+	r.HandleStart(ClientStart{Player: owner})
+	receiveUntil[*ServerStart](t, right)
+
+	r.HandlePredict(ClientPredict{Player: owner, Id: owner.Id})
+	assert.Equal(t, "Only spectators can make predictions", receiveUntil[*ServerError](t, owner).Message)
+	r.HandlePredict(ClientPredict{Player: right, Id: "p_missing"})
+	assert.Equal(t, "target player not found", receiveUntil[*ServerError](t, right).Message)
+
+	r.HandlePredict(ClientPredict{Player: right, Id: other.Id})
+	assert.Equal(t, &ServerPrediction{PlayerId: right.Id, Id: other.Id}, receiveUntil[*ServerPrediction](t, wrong))
+	r.HandlePredict(ClientPredict{Player: right, Id: owner.Id})
+	assert.Equal(t, "You have already made a prediction for this game", receiveUntil[*ServerError](t, right).Message)
+	r.HandlePredict(ClientPredict{Player: wrong, Id: owner.Id})
+
+	c.Advance(time.Duration(PredictionWindow+1) * time.Second)
+	r.HandlePredict(ClientPredict{Player: late, Id: other.Id})
+	assert.Equal(t, "Predictions are closed for this game", receiveUntil[*ServerError](t, late).Message)
+
+	other.Score = 3
+	r.HandleEnd(ClientEnd{Player: owner})
+	var result *ServerPredictionResult
+	for result == nil {
+		switch m := receive(t, owner).(type) {
+		case *ServerPrediction:
+			t.Error("players should not see predictions")
+		case *ServerPredictionResult:
+			result = m
+		}
+	}
+	assert.Equal(t, []string{other.Id}, result.Winners)
+	assert.Equal(t, []PredictionScore{
+		{PlayerId: right.Id, Name: "Right", Correct: 1, Predictions: 1},
+		{PlayerId: wrong.Id, Correct: 0, Predictions: 1},
+	}, result.Scoreboard)
+
+	replay, err := storage.Default.Replay(receiveUntil[*ServerEnd](t, right).MatchId)
+	if assert.NoError(t, err) {
+		assert.NotContains(t, string(replay.Events), "prediction")
+	}
+}
@@ -0,0 +1,119 @@
+package game
+
+import (
+	"cardgame/deck"
+	"cardgame/errs"
+	"fmt"
+)
+
+// maxRoomPlayers is the most players a room configured from settings can seat.
+const maxRoomPlayers = 16
+
+// RoomSettings are the settings of a room that can be saved in a preset, to create rooms
+// configured the same way.
+type RoomSettings struct {
+	GameType        GameType  `json:"gameType"`
+	Description     string    `json:"description"`
+	MaxPlayers      int       `json:"maxPlayers"`
+	Private         bool      `json:"private"` // rooms are created with a password
+	Ranked          bool      `json:"ranked"`
+	PlayMode        PlayMode  `json:"playMode"`
+	DisconnectGrace int       `json:"disconnectGrace"` // seconds to wait for a disconnected player
+	AfkPolicy       AfkPolicy `json:"afkPolicy"`
+	TurnTimeout     int       `json:"turnTimeout"` // seconds a player has to draw, or 0 for no limit
+	AfkTurns        int       `json:"afkTurns"`    // consecutive timed out turns before a player is AFK, or 0 to never
+	Decks           []string  `json:"decks"`       // ids of the decks in use
+}
+
+// DefaultRoomSettings returns the settings new rooms start with.
+func DefaultRoomSettings() RoomSettings {
+	return RoomSettings{
+		GameType:        GameTypeClassic,
+		MaxPlayers:      4,
+		DisconnectGrace: DefaultDisconnectGrace,
+		TurnTimeout:     DefaultTurnTimeout,
+		AfkTurns:        DefaultAfkTurns,
+		Decks:           []string{},
+	}
+}
+
+// Validate checks the settings can be used to create a room. Its errors are of the kind
+// errs.ErrInvalidInput.
+func (s RoomSettings) Validate() error {
+	known := false
+	for _, t := range AllGameTypes {
+		known = known || t == s.GameType
+	}
+	if !known {
+		return errs.New(errs.ErrInvalidInput, "unknown game type")
+	}
+	if s.MaxPlayers < 1 || s.MaxPlayers > maxRoomPlayers {
+		return errs.Wrap(errs.ErrInvalidInput, fmt.Errorf("max players must be between 1 and %d", maxRoomPlayers))
+	}
+	if s.DisconnectGrace < 0 || s.TurnTimeout < 0 || s.AfkTurns < 0 {
+		return errs.New(errs.ErrInvalidInput, "timers can't be negative")
+	}
+	if s.PlayMode < PlayModePlayersOnly || s.PlayMode > PlayModeHubOnly {
+		return errs.New(errs.ErrInvalidInput, "unknown play mode")
+	}
+	if s.AfkPolicy != AfkPolicySeatOpen && s.AfkPolicy != AfkPolicyBotFill {
+		return errs.New(errs.ErrInvalidInput, "unknown AFK policy")
+	}
+	for _, id := range s.Decks {
+		if _, ok := deck.Decks()[id]; !ok {
+			return errs.Wrap(errs.ErrInvalidInput, fmt.Errorf("unknown deck %q", id))
+		}
+	}
+	return nil
+}
+
+// Settings returns the current settings of the room.
+func (r *Room) Settings() RoomSettings {
+	rules := r.rules()
+	return RoomSettings{
+		GameType:        r.GameType,
+		Description:     r.Description,
+		MaxPlayers:      r.MaxPlayers,
+		Private:         r.private,
+		Ranked:          r.Ranked,
+		PlayMode:        rules.PlayMode,
+		DisconnectGrace: rules.DisconnectGrace,
+		AfkPolicy:       rules.AfkPolicy,
+		TurnTimeout:     rules.TurnTimeout,
+		AfkTurns:        rules.AfkTurns,
+		Decks:           rules.Decks,
+	}
+}
+
+// NewRoomWithSettings creates a room configured with the given settings and starts handling
+// its messages. Private settings need a password.
+func (h *Hub) NewRoomWithSettings(s RoomSettings, password string) (*Room, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if s.Private && password == "" {
+		return nil, errs.New(errs.ErrInvalidInput, "private rooms need a password")
+	}
+	if !s.Private {
+		password = ""
+	}
+
+	r := h.newRoom(password)
+	r.GameType = s.GameType
+	r.Description = s.Description
+	r.MaxPlayers = s.MaxPlayers
+	r.Ranked = s.Ranked
+	r.PlayMode = s.PlayMode
+	r.DisconnectGrace = s.DisconnectGrace
+	r.AfkPolicy = s.AfkPolicy
+	r.TurnTimeout = s.TurnTimeout
+	r.AfkTurns = s.AfkTurns
+	for _, id := range s.Decks {
+		if d, ok := deck.Decks()[id]; ok {
+			r.Decks = append(r.Decks, d)
+		}
+	}
+
+	go r.read()
+	return r, nil
+}
@@ -0,0 +1,42 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRoomWithSettings(t *testing.T) {
+	h := newTestHub()
+	s := DefaultRoomSettings()
+	s.Description = "quick games"
+	s.MaxPlayers = 2
+	s.TurnTimeout = 10
+	s.AfkPolicy = AfkPolicyBotFill
+	s.Ranked = true
+
+	r, err := h.NewRoomWithSettings(s, "secret")
+	if assert.NoError(t, err) {
+		assert.False(t, r.IsPrivate(), "passwords should only be used for private settings")
+		assert.Equal(t, s, r.Settings())
+		open, _ := h.Room(r.Id)
+		assert.Same(t, r, open)
+	}
+
+	s.Private = true
+	_, err = h.NewRoomWithSettings(s, "")
+	assert.Error(t, err, "private rooms should need a password")
+	r, err = h.NewRoomWithSettings(s, "secret")
+	if assert.NoError(t, err) {
+		assert.True(t, r.CheckPassword("secret"))
+	}
+
+	s.MaxPlayers = 0
+	assert.Error(t, s.Validate())
+	s.MaxPlayers = 2
+	s.Decks = []string{"d_missing"}
+	assert.Error(t, s.Validate())
+	s.Decks = nil
+	s.GameType = "poker"
+	assert.Error(t, s.Validate())
+}
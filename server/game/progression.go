@@ -0,0 +1,72 @@
+package game
+
+import (
+	"cardgame/cosmetic"
+	"cardgame/progression"
+	"cardgame/storage"
+	"time"
+)
+
+// recordProgression adds the end of a game to the experience and quests of the players with
+// accounts, and tells them what they earned, including the cosmetics they unlocked. Seats
+// played by bots don't count.
+func (r *Room) recordProgression(match *storage.Match) {
+	ranks := map[string]int{}
+	for _, result := range match.Players {
+		ranks[result.Id] = result.Rank
+	}
+
+	for _, p := range r.Players {
+		if p.AccountId == "" || p.Bot {
+			continue
+		}
+
+		result, err := progression.Record(storage.Default, progression.Game{
+			AccountId: p.AccountId,
+			GameType:  match.GameType,
+			Won:       ranks[p.Id] == 1 && len(match.Players) > 1,
+			Score:     p.Score,
+			Ended:     time.UnixMilli(match.Ended),
+		})
+		if err != nil {
+			r.logger().Error("failed to record progression", "err", err)
+			continue
+		}
+
+		r.publish(&serverPayload{
+			include: set{p.Id: {}},
+			message: &ServerExperience{
+				Earned:  result.XP,
+				XP:      result.Total,
+				Level:   result.Level,
+				LevelUp: result.LevelUp,
+			},
+		})
+		for _, q := range result.Completed {
+			r.publish(&serverPayload{
+				include: set{p.Id: {}},
+				message: &ServerQuest{
+					Id:     q.Id,
+					Name:   q.Name,
+					Reward: q.Reward,
+				},
+			})
+		}
+
+		unlocked, err := cosmetic.Check(storage.Default, p.AccountId)
+		if err != nil {
+			r.logger().Error("failed to check cosmetics", "err", err)
+			continue
+		}
+		for _, item := range unlocked {
+			r.publish(&serverPayload{
+				include: set{p.Id: {}},
+				message: &ServerCosmetic{
+					Id:   item.Id,
+					Name: item.Name,
+					Kind: item.Kind,
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"cardgame/cosmetic"
+	"cardgame/progression"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressionRecorded(t *testing.T) {
+	s := withTestStore(t)
+	owner, a, guest := newTestPlayer("p_owner"), newTestPlayer("p_a"), newTestPlayer("p_guest")
+	owner.AccountId, a.AccountId = "u_owner", "u_a"
+	r := startTestGame(t, owner, a, guest)
+	owner.Score, a.Score = 1, 4
+
+	r.HandleEnd(ClientEnd{Player: owner})
+	xp := receiveUntil[*ServerExperience](t, a)
+	assert.Equal(t, progression.GameXP+progression.WinXP, xp.Earned)
+	assert.Equal(t, 1, xp.Level)
+	assert.Equal(t, progression.GameXP, receiveUntil[*ServerExperience](t, owner).Earned)
+
+	saved, err := s.Experience("u_owner")
+	if assert.NoError(t, err) {
+		assert.Equal(t, progression.GameXP, saved.XP)
+	}
+	_, err = s.Experience("")
+	assert.Error(t, err, "guests should not earn experience")
+}
+
+func TestCosmeticUnlocked(t *testing.T) {
+	s := withTestStore(t)
+	old := cosmetic.Catalog{Items: cosmetic.Items()}
+	assert.NoError(t, cosmetic.SetCatalog(cosmetic.Catalog{Items: []cosmetic.Item{
+		{Id: "back_plain", Kind: cosmetic.KindCardBack, Default: true},
+		{Id: "back_first", Name: "First", Kind: cosmetic.KindCardBack, Unlock: cosmetic.Unlock{Level: 1}},
+	}}))
+	t.Cleanup(func() { cosmetic.SetCatalog(old) })
+
+	owner, guest := newTestPlayer("p_owner"), newTestPlayer("p_guest")
+	owner.AccountId = "u_owner"
+	r := startTestGame(t, owner, guest)
+
+	r.HandleEnd(ClientEnd{Player: owner})
+	unlocked := receiveUntil[*ServerCosmetic](t, owner)
+	assert.Equal(t, "back_first", unlocked.Id)
+	assert.Equal(t, cosmetic.KindCardBack, unlocked.Kind)
+
+	saved, err := s.Cosmetics("u_owner")
+	if assert.NoError(t, err) && assert.Len(t, saved, 1) {
+		assert.Equal(t, "back_first", saved[0].CosmeticId)
+	}
+}
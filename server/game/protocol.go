@@ -0,0 +1,93 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the messages the server speaks. Clients ask for a version
+// when they connect, with the websocket subprotocol "cardgame.v<version>". Clients which don't
+// ask speak version 1, the version from before versions were negotiated.
+//
+// Changing the schema of a message bumps the version and adds a migration to it, so clients
+// one version behind keep working until they have all been updated. Once they have, the
+// migration is removed.
+var ProtocolVersion = 1
+
+const protocolPrefix = "cardgame.v"
+
+// migration translates the messages of the version before the one it is registered for.
+type migration struct {
+	// up rewrites a message sent by a client into the newer version.
+	up func(message map[string]any)
+	// down rewrites a message sent by the server into the older version.
+	down func(message map[string]any)
+}
+
+// migrations are keyed by the version they migrate to. Only the migration to ProtocolVersion
+// is used.
+var migrations = map[int]migration{}
+
+// Subprotocols returns the websocket subprotocols of the versions the server speaks, the
+// current one first.
+func Subprotocols() []string {
+	protocols := []string{protocolPrefix + strconv.Itoa(ProtocolVersion)}
+	if _, ok := migrations[ProtocolVersion]; ok {
+		protocols = append(protocols, protocolPrefix+strconv.Itoa(ProtocolVersion-1))
+	}
+	return protocols
+}
+
+// protocolVersion returns the version negotiated for a connection from its subprotocol.
+// Clients older than the last version are spoken to in the current one, which is the best the
+// server can do for them.
+func protocolVersion(subprotocol string) int {
+	v := 1
+	if n, err := strconv.Atoi(strings.TrimPrefix(subprotocol, protocolPrefix)); err == nil && strings.HasPrefix(subprotocol, protocolPrefix) {
+		v = n
+	}
+	if _, ok := migrations[ProtocolVersion]; ok && v == ProtocolVersion-1 {
+		return v
+	}
+	return ProtocolVersion
+}
+
+// translate rewrites a message of a connection speaking an older version, with the up or the
+// down function of the migration to the current version. Messages are left as they are if the
+// migration doesn't change that direction.
+func translate(data []byte, rewrite func(message map[string]any)) ([]byte, error) {
+	if rewrite == nil {
+		return data, nil
+	}
+	// numbers are kept as they were sent rather than converted to floats
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	m := map[string]any{}
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	rewrite(m)
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// upgrade translates a message sent by the player into the current version.
+func (p *Player) upgrade(data []byte) ([]byte, error) {
+	if p.protocol == ProtocolVersion {
+		return data, nil
+	}
+	return translate(data, migrations[ProtocolVersion].up)
+}
+
+// downgrade translates a message sent to the player into the version they speak.
+func (p *Player) downgrade(data []byte) ([]byte, error) {
+	if p.protocol == ProtocolVersion {
+		return data, nil
+	}
+	return translate(data, migrations[ProtocolVersion].down)
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withTestMigration bumps the protocol to version 2, which renames the recipientId of sends
+// to "to" and the playerId of turns to "player".
+func withTestMigration(t *testing.T) {
+	oldVersion, oldMigrations := ProtocolVersion, migrations
+	t.Cleanup(func() { ProtocolVersion, migrations = oldVersion, oldMigrations })
+	ProtocolVersion = 2
+	migrations = map[int]migration{2: {
+		up: func(m map[string]any) {
+			if m["type"] == "send" {
+				m["recipientId"] = m["to"]
+				delete(m, "to")
+			}
+		},
+		down: func(m map[string]any) {
+			if m["type"] == "turn" {
+				m["player"] = m["playerId"]
+				delete(m, "playerId")
+			}
+		},
+	}}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	assert.Equal(t, []string{"cardgame.v1"}, Subprotocols())
+	assert.Equal(t, 1, protocolVersion(""))
+
+	withTestMigration(t)
+	assert.Equal(t, []string{"cardgame.v2", "cardgame.v1"}, Subprotocols())
+	assert.Equal(t, 2, protocolVersion("cardgame.v2"))
+	assert.Equal(t, 1, protocolVersion("cardgame.v1"))
+	assert.Equal(t, 1, protocolVersion(""), "clients which don't negotiate speak version 1")
+
+	ProtocolVersion = 3
+	migrations[3] = migration{}
+	assert.Equal(t, 3, protocolVersion(""), "clients older than the last version get the current one")
+}
+
+func TestProtocolMigration(t *testing.T) {
+	withTestMigration(t)
+	current, old := newTestPlayer("p_1"), newTestPlayer("p_2")
+	current.protocol, old.protocol = 2, 1
+
+	data, err := old.upgrade([]byte(`{"type":"send","to":"p_1"}`))
+	assert.NoError(t, err)
+	msg, err := old.ClientMessageFromJson(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "p_1", msg.(ClientSend).RecipientId)
+
+	sent := []byte(`{"playerId":"p_1","room":{"timestamp":1700000000000},"type":"turn"}` + "\n")
+	data, err = current.downgrade(sent)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, data, "connections speaking the current version should get messages as they are")
+	data, err = old.downgrade(sent)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"player":"p_1","room":{"timestamp":1700000000000},"type":"turn"}`, string(data))
+
+	_, err = old.upgrade([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package game
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"errors"
+	"log/slog"
+)
+
+// replayEvent is a server message sent during a game, as stored in its replay.
+type replayEvent struct {
+	Time    int64                  `json:"time"` // ms since the game started
+	Message map[string]interface{} `json:"message"`
+}
+
+// recordReplay adds a message to the replay of the current game, and saves the replay
+// once the game is over. It must be called with r.mu held.
+func (r *Room) recordReplay(message ServerMessage) {
+	if r.replay == nil {
+		return
+	}
+	switch message.(type) {
+	case *ServerChat:
+		// chat is not part of the game, and private messages have to stay private
+		return
+	case *ServerPrediction, *ServerPredictionResult:
+		// neither are the predictions of the spectators
+		return
+	}
+
+	r.replay = append(r.replay, replayEvent{
+		Time:    Clock.Now().UnixMilli() - r.started,
+		Message: messageMap(message),
+	})
+
+	if end, ok := message.(*ServerEnd); ok {
+		r.saveReplay(end.MatchId)
+		r.replay = nil
+	}
+}
+
+func (r *Room) saveReplay(matchId string) {
+	events, err := json.Marshal(r.replay)
+	if err != nil {
+		r.logger().Error("failed to encode replay", "err", err)
+		return
+	}
+
+	err = storage.Default.SaveReplay(&storage.Replay{
+		MatchId: matchId,
+		Seed:    r.seed,
+		Events:  events,
+		Created: Clock.Now().UnixMilli(),
+	})
+	if err != nil {
+		r.logger().Error("failed to save replay", "err", err)
+	}
+}
+
+func (h *Hub) handleReplay(msg ClientReplay) {
+	p := msg.Player
+
+	replay, err := storage.Default.Replay(msg.MatchId)
+	if errors.Is(err, storage.ErrNotFound) {
+		p.notify(&ServerError{Id: "replay_not_found"})
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load replay", "err", err)
+		p.notify(&ServerError{Id: "replay_load_failed"})
+		return
+	}
+
+	var events []replayEvent
+	if err := json.Unmarshal(replay.Events, &events); err != nil {
+		slog.Error("failed to decode replay", "err", err)
+		p.notify(&ServerError{Id: "replay_load_failed"})
+		return
+	}
+
+	// streamed separately, so a long replay doesn't hold up the hub
+	go func() {
+		p.notify(&ServerReplayStart{
+			MatchId: replay.MatchId,
+			Seed:    replay.Seed,
+			Events:  len(events),
+		})
+		for _, e := range events {
+			p.notify(&ServerReplayEvent{
+				MatchId: replay.MatchId,
+				Time:    e.Time,
+				Event:   e.Message,
+			})
+		}
+		p.notify(&ServerReplayEnd{MatchId: replay.MatchId})
+	}()
+}
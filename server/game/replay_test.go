@@ -0,0 +1,61 @@
+package game
+
+import (
+	"cardgame/card"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayRecorded(t *testing.T) {
+	s := withTestStore(t)
+	owner := newTestPlayer("p_owner")
+	other := newTestPlayer("p_other")
+	r := startTestGame(t, owner, other)
+
+	r.HandleChat(ClientChat{Player: owner, Message: "good luck"})
+	receiveUntil[*ServerChat](t, owner)
+	r.CurrentTurn = 0
+	r.HandleDraw(ClientDraw{Player: owner})
+	receiveUntil[*ServerTurn](t, owner)
+	r.HandleEnd(ClientEnd{Player: owner})
+	end := receiveUntil[*ServerEnd](t, owner)
+
+	replay, err := s.Replay(end.MatchId)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, r.seed, replay.Seed)
+	assert.Contains(t, string(replay.Events), `"type":"start"`)
+	assert.Contains(t, string(replay.Events), `"type":"end"`)
+	assert.NotContains(t, string(replay.Events), "good luck", "chat should not be recorded")
+
+	h := newTestHub()
+	viewer := newTestPlayer("p_viewer")
+	h.handleReplay(ClientReplay{Player: viewer, MatchId: end.MatchId})
+	start := receiveUntil[*ServerReplayStart](t, viewer)
+	assert.Equal(t, replay.Seed, start.Seed)
+	for i := 0; i < start.Events; i++ {
+		assert.IsType(t, &ServerReplayEvent{}, receive(t, viewer))
+	}
+	assert.IsType(t, &ServerReplayEnd{}, receive(t, viewer))
+
+	h.handleReplay(ClientReplay{Player: viewer, MatchId: "m_missing"})
+	assert.Equal(t, "Replay not found", receiveUntil[*ServerError](t, viewer).Message)
+}
+
+func TestSeededDrawPile(t *testing.T) {
+	d := newTestDeck(20)
+	order := func() []string {
+		r := &Room{rng: rand.New(rand.NewSource(42))}
+		r.Decks = append(r.Decks, d)
+		r.Deal(r.Decks, r.rng)
+		ids := []string{}
+		for _, c := range r.DrawPile {
+			ids = append(ids, c.(*card.Card).Id)
+		}
+		return ids
+	}
+	assert.Equal(t, order(), order(), "the same seed should deal the same cards")
+}
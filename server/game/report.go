@@ -0,0 +1,151 @@
+package game
+
+import (
+	"cardgame/storage"
+	"cardgame/util"
+	"encoding/json"
+	"time"
+)
+
+// reportChatLines is the number of recent chat messages a room keeps to attach to reports.
+const reportChatLines = 50
+
+// reportReplayWindow is how much of the game leading up to a report is attached to it.
+var reportReplayWindow = 2 * time.Minute
+
+// loggedChat is a chat message kept by the room, to be attached to reports.
+type loggedChat struct {
+	storage.ReportChat
+	recipientId string // empty for public messages
+}
+
+// logChat keeps a chat message sent in the room, dropping the oldest ones past reportChatLines.
+func (r *Room) logChat(sender *Player, recipient *Player, text string) {
+	line := loggedChat{
+		ReportChat: storage.ReportChat{
+			PlayerId: sender.Id,
+			Name:     sender.Name,
+			Message:  text,
+			Private:  recipient != nil,
+			Time:     Clock.Now().UnixMilli(),
+		},
+	}
+	if recipient != nil {
+		line.recipientId = recipient.Id
+	}
+	r.chatLog = append(r.chatLog, line)
+	if len(r.chatLog) > reportChatLines {
+		r.chatLog = r.chatLog[len(r.chatLog)-reportChatLines:]
+	}
+}
+
+// reportChat returns the chat a reporter saw: every public message, and the private ones
+// they exchanged with the player they report.
+func (r *Room) reportChat(reporter, target *Player) []storage.ReportChat {
+	chat := []storage.ReportChat{}
+	for _, line := range r.chatLog {
+		if line.Private && !(line.PlayerId == target.Id && line.recipientId == reporter.Id) &&
+			!(line.PlayerId == reporter.Id && line.recipientId == target.Id) {
+			continue
+		}
+		chat = append(chat, line.ReportChat)
+	}
+	return chat
+}
+
+// reportEvents returns the replay events of the game in progress from the last
+// reportReplayWindow, or nil if no game is being recorded.
+func (r *Room) reportEvents() json.RawMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.replay == nil {
+		return nil
+	}
+
+	since := Clock.Now().UnixMilli() - r.started - reportReplayWindow.Milliseconds()
+	events := []replayEvent{}
+	for _, e := range r.replay {
+		if e.Time >= since {
+			events = append(events, e)
+		}
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		r.logger().Error("failed to encode report events", "err", err)
+		return nil
+	}
+	return data
+}
+
+// findPlayer returns the player or spectator with an id, or nil if there is none.
+func (r *Room) findPlayer(id string) *Player {
+	if p := r.getPlayer(id); p != nil {
+		return p
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.Spectators {
+		if p.Id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (r *Room) HandleReport(message ClientReport) {
+	p := message.Player
+
+	target := r.findPlayer(message.Id)
+	if target == nil {
+		p.send(&ServerError{Id: "player_not_found"})
+		return
+	}
+	if target == p {
+		p.send(&ServerError{Id: "report_self"})
+		return
+	}
+	switch message.Reason {
+	case storage.ReportCheating, storage.ReportAbuse, storage.ReportAfk:
+	default:
+		p.send(&ServerError{Id: "unknown_report_reason"})
+		return
+	}
+	details, err := CleanReason(message.Details)
+	if err != nil {
+		p.send(serverError(err))
+		return
+	}
+
+	key := auditId(p) + "/" + auditId(target)
+	if _, ok := r.reported[key]; ok {
+		p.send(&ServerError{Id: "report_duplicate"})
+		return
+	}
+
+	report := &storage.Report{
+		Id:           util.IdFrom("rep", util.Token()),
+		Reason:       message.Reason,
+		ReporterId:   auditId(p),
+		ReporterName: p.Name,
+		TargetId:     auditId(target),
+		TargetName:   target.Name,
+		RoomId:       r.Id,
+		GameType:     string(r.GameType),
+		Details:      details,
+		Chat:         r.reportChat(p, target),
+		Events:       r.reportEvents(),
+		Status:       storage.ReportOpen,
+		Created:      Clock.Now().UnixMilli(),
+	}
+	if err := storage.Default.SaveReport(report); err != nil {
+		r.logger().Error("failed to save report", "err", err)
+		p.send(&ServerError{Id: "report_failed"})
+		return
+	}
+	if r.reported == nil {
+		r.reported = set{}
+	}
+	r.reported[key] = struct{}{}
+
+	p.send(&ServerReported{ReportId: report.Id})
+}
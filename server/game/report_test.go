@@ -0,0 +1,57 @@
+package game
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	s := withTestStore(t)
+	r := newTestRoom(t)
+	r.Decks = append(r.Decks, newTestDeck(20))
+	alice, bob, carol := newTestPlayer("p_alice"), newTestPlayer("p_bob"), newTestPlayer("p_carol")
+	alice.AccountId = "u_alice"
+	for _, p := range []*Player{alice, bob, carol} {
+		joinTestRoom(t, r, p, false)
+	}
+	r.HandleStart(ClientStart{Player: alice})
+
+	bobId, carolId := bob.Id, carol.Id
+	r.HandleChat(ClientChat{Player: bob, Message: "hello everyone"})
+	r.HandleChat(ClientChat{Player: bob, Message: "you are terrible", RecipientId: &alice.Id})
+	r.HandleChat(ClientChat{Player: carol, Message: "psst", RecipientId: &bobId})
+	r.HandleChat(ClientChat{Player: alice, Message: "hi carol", RecipientId: &carolId})
+
+	r.HandleReport(ClientReport{Player: alice, Id: alice.Id, Reason: storage.ReportAbuse})
+	assert.Equal(t, "You can't report yourself", receiveUntil[*ServerError](t, alice).Message)
+	r.HandleReport(ClientReport{Player: alice, Id: bob.Id, Reason: "rudeness"})
+	receiveUntil[*ServerError](t, alice)
+
+	r.HandleReport(ClientReport{Player: alice, Id: bob.Id, Reason: storage.ReportAbuse, Details: " insults in chat "})
+	id := receiveUntil[*ServerReported](t, alice).ReportId
+	report, err := s.Report(id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "u_alice", report.ReporterId)
+		assert.Equal(t, bob.Id, report.TargetId, "guests should be reported by their player id")
+		assert.Equal(t, "insults in chat", report.Details)
+		assert.Equal(t, storage.ReportOpen, report.Status)
+		messages := []string{}
+		for _, c := range report.Chat {
+			messages = append(messages, c.Message)
+		}
+		assert.Equal(t, []string{"hello everyone", "you are terrible"}, messages,
+			"only public chat and private messages between the reporter and the target should be attached")
+
+		var events []replayEvent
+		assert.NoError(t, json.Unmarshal(report.Events, &events))
+		if assert.NotEmpty(t, events) {
+			assert.Equal(t, "start", events[0].Message["type"])
+		}
+	}
+
+	r.HandleReport(ClientReport{Player: alice, Id: bob.Id, Reason: storage.ReportCheating})
+	assert.Equal(t, "You have already reported this player", receiveUntil[*ServerError](t, alice).Message)
+}
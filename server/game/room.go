@@ -2,36 +2,77 @@ package game
 
 import (
 	"cardgame/card"
+	"cardgame/clock"
 	"cardgame/deck"
+	"cardgame/engine"
 	"cardgame/util/slices"
-	"fmt"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
 )
 
+// catchUpInterval is the delay between replayed events sent to a spectator joining mid-game.
+var catchUpInterval = 100 * time.Millisecond
+
 // Room represents a game room.
 type Room struct {
-	Id             string           `json:"id"`             // internal room id
-	Timstamp       int64            `json:"timestamp"`      // creation timestamp
-	Name           string           `json:"name"`           // public-facing room name
-	Description    string           `json:"description"`    // room description
-	MaxPlayers     int              `json:"maxPlayers"`     // maximum number of players
-	OwnerId        string           `json:"ownerId"`        // owner's player id
-	Players        []*Player        `json:"players"`        // players in the room, including the owner
-	Decks          []*deck.Deck     `json:"decks"`          // decks in use
-	PlayMode       PlayMode         `json:"playMode"`       // play mode
-	HubDeviceId    string           `json:"hubDeviceId"`    // hub device id
-	CurrentTurn    int              `json:"currentTurn"`    // index of the current player
-	GamePhase      GamePhase        `json:"gamePhase"`      // game phase
-	ActiveWildCard *card.WildCard   `json:"activeWildCard"` // active wild card
-	DrawPileSize   int              `json:"drawPileSize"`   // size of the draw pile
-	drawPile       []card.BaseCard  // draw pile
-	usedWildCards  []*card.WildCard // already used wild cards
-
-	private      bool   // true if the room is private
-	passwordHash string // password hash for private rooms
+	Id              string           `json:"id"`          // internal room id
+	Timstamp        int64            `json:"timestamp"`   // creation timestamp
+	Name            string           `json:"name"`        // public-facing room name
+	GameType        GameType         `json:"gameType"`    // rules the room is played with
+	Description     string           `json:"description"` // room description
+	MaxPlayers      int              `json:"maxPlayers"`  // maximum number of players
+	OwnerId         string           `json:"ownerId"`     // owner's player id
+	Players         []*Player        `json:"players"`     // players in the room, including the owner
+	Spectators      []*Player        `json:"spectators"`  // spectators watching the room
+	Decks           []*deck.Deck     `json:"decks"`       // decks in use
+	PlayMode        PlayMode         `json:"playMode"`    // play mode
+	HubDeviceId     string           `json:"hubDeviceId"` // hub device id
+	CurrentTurn     int              `json:"currentTurn"` // index of the current player
+	GamePhase       GamePhase        `json:"gamePhase"`   // game phase
+	engine.Table                     // draw pile and wild cards
+	Paused          bool             `json:"paused"`          // true while waiting for a disconnected player
+	DisconnectGrace int              `json:"disconnectGrace"` // seconds to wait for a disconnected player
+	AfkPolicy       AfkPolicy        `json:"afkPolicy"`       // what happens to the seat of a removed player
+	Vote            *Vote            `json:"vote"`            // active vote, if any
+	TurnTimeout     int              `json:"turnTimeout"`     // seconds a player has to draw, or 0 for no limit
+	AfkTurns        int              `json:"afkTurns"`        // consecutive timed out turns before a player is AFK, or 0 to never
+	Ranked          bool             `json:"ranked"`          // ranked rooms don't substitute AFK players
+	pauseTimer      clock.Timer      // fires when the disconnect grace period is over
+	lastVoteKick    map[string]int64 // playerId -> unix ms of the last vote-kick they started
+	chatLog         []loggedChat     // recent chat, attached to reports
+	reported        set              // "reporter/target" ids of the reports filed in the room
+	actions         int              // client messages handled, numbering them in the logs
+	actionLog       *slog.Logger     // logger for the message being handled
+	turnTimer       clock.Timer      // fires when the current player runs out of time
+	turnTimerSeq    int              // incremented for every turn timer, so stale timers are ignored
+	started         int64            // unix ms when the current game started
+	seed            int64            // seed of rng for the current game, stored with its replay
+	rng             *rand.Rand       // source of randomness for shuffling and picking turns
+
+	history []ServerMessage // events broadcast to the whole room since the game started
+	replay  []replayEvent   // every event of the current game, or nil when not recording
+	mu      sync.Mutex      // guards history, replay, Spectators, the password and invites
+
+	private      bool          // true if the room is private
+	passwordHash string        // password hash for private rooms
+	invited      set           // ids of accounts invited into the room, who don't need the password
+	resuming     *resumingGame // suspended game waiting for its players to come back, if any
+	challenge    *dailyAttempt // daily challenge played in the room, if any
+	demo         *demoSession  // demo played in the room, if any
+
+	predictions      map[string]string           // spectator id -> id of the player they predict wins the current game
+	predictionScores map[string]*PredictionScore // by spectator id, over every game of the room
+
+	idleSince int64 // unix ms since no one has been connected to the room, or 0 while someone is
 
 	hub      *Hub                // hub instance
 	inbound  chan ClientMessage  // incoming client messages
-	outbound chan *serverPayload // outgoing server messages
+	outbound chan *serverPayload // queues payloads instead of broadcasting them, for rooms run by hand like golden games
+	closed   chan struct{}       // closed once the room is closed and stops reading inbound
+	closing  bool                // set once everyone has been removed, for the room to stop reading
 }
 
 func (r *Room) getPlayer(id string) *Player {
@@ -43,85 +84,101 @@ func (r *Room) getPlayer(id string) *Player {
 	return nil
 }
 
-func (r *Room) createDrawPile() {
-	r.drawPile = []card.BaseCard{}
-	for _, d := range r.Decks {
-		for _, c := range d.Cards {
-			r.drawPile = append(r.drawPile, c)
-		}
-		for _, w := range d.WildCards {
-			r.drawPile = append(r.drawPile, w)
-		}
+// currentPlayer returns the player whose turn it is, or nil if no one is seated.
+func (r *Room) currentPlayer() *Player {
+	if r.CurrentTurn < 0 || r.CurrentTurn >= len(r.Players) {
+		return nil
 	}
-	slices.Shuffle(r.drawPile)
-	r.DrawPileSize = len(r.drawPile)
+	return r.Players[r.CurrentTurn]
 }
 
-func (r *Room) drawCard() (card.BaseCard, error) {
-	if len(r.drawPile) == 0 {
-		return nil, fmt.Errorf("draw pile is empty")
-	}
-	c := r.drawPile[0]
-	r.drawPile = r.drawPile[1:]
-	r.DrawPileSize--
-	return c, nil
+func (r *Room) isSpectator(p *Player) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Contains(r.Spectators, p)
+}
+
+func (r *Room) removeSpectator(p *Player) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Spectators = slices.Remove(r.Spectators, p)
 }
 
-func (r *Room) recreateDrawPile() error {
-	if r.DrawPileSize != 0 {
-		return fmt.Errorf("draw pile is not empty")
+// addSpectator adds a spectator to the room. If the game is underway,
+// the events so far are replayed to them first.
+func (r *Room) addSpectator(p *Player) {
+	r.mu.Lock()
+	events := len(r.history)
+	if events == 0 {
+		r.Spectators = append(r.Spectators, p)
 	}
+	r.mu.Unlock()
 
-	newDrawPile := []card.BaseCard{}
-	for _, p := range r.Players {
-		// take every card except the top one
+	if events > 0 {
+		p.send(&ServerCatchUpStart{Events: events})
+		r.HandleCatchUp(clientCatchUp{Player: p})
+	}
+}
 
-		top := p.Hand.top()
-		if top == nil {
-			// no cards
-			continue
-		}
-		reusable := p.Hand.tail()
-		for _, c := range reusable {
-			newDrawPile = append(newDrawPile, c)
-		}
-		p.Hand = make(PlayerHand, 1)
-		p.Hand[0] = top
+// HandleCatchUp replays the next event of the game so far to a spectator, and adds them to
+// the room once they are caught up, so they receive live events only from then on. The room
+// goes on handling other messages between events.
+func (r *Room) HandleCatchUp(message clientCatchUp) {
+	p := message.Player
+	r.mu.Lock()
+	if message.next >= len(r.history) {
+		// events broadcast after this point are sent live
+		r.Spectators = append(r.Spectators, p)
+		r.mu.Unlock()
+		p.send(&ServerCatchUpEnd{})
+		return
 	}
-	slices.Shuffle(newDrawPile)
-	r.drawPile = newDrawPile
+	event := r.history[message.next]
+	r.mu.Unlock()
 
-	// choose new wild card
-	r.usedWildCards = append(r.usedWildCards, r.ActiveWildCard)
-	slices.Shuffle(r.usedWildCards)
-	r.ActiveWildCard, r.usedWildCards = r.usedWildCards[0], r.usedWildCards[1:]
+	p.send(event)
+	time.AfterFunc(catchUpInterval, func() {
+		r.post(clientCatchUp{Player: p, next: message.next + 1})
+	})
+}
 
-	return nil
+// reshuffle shuffles the hands of the players into the empty draw pile.
+func (r *Room) reshuffle() error {
+	hands := make([]*engine.Hand, len(r.Players))
+	for i, p := range r.Players {
+		hands[i] = &p.Hand
+	}
+	return r.Reshuffle(hands, r.rng)
 }
 
 func (r *Room) resync() {
 	topCards := make(map[string]*card.Card)
 
 	for _, p := range r.Players {
-		topCards[p.Id] = p.Hand.top()
+		topCards[p.Id] = p.Hand.Top()
 	}
 
-	r.outbound <- &serverPayload{
+	r.publish(&serverPayload{
 		message: &ServerResync{
 			TopCards: topCards,
 		},
-	}
+	})
 
 }
 
 // IsPrivate returns true if the room is private.
 func (r *Room) IsPrivate() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.private
 }
 
 // CheckPassword returns true if the password is correct.
 func (r *Room) CheckPassword(password string) bool {
-	return checkPassword(r.passwordHash, password)
+	r.mu.Lock()
+	hash := r.passwordHash
+	r.mu.Unlock()
+	return checkPassword(hash, password)
 }
 
 // IsFull returns true if the room is full.
@@ -132,64 +189,146 @@ func (r *Room) IsFull() bool {
 // SetPassword sets the password and privacy of the room.
 // If the password is empty, the room is made public.
 func (r *Room) SetPassword(password string) {
-	if password == "" {
-		r.private = false
-		r.passwordHash = ""
-	} else {
-		r.private = true
-		r.passwordHash = hashPassword(password)
+	hash := ""
+	if password != "" {
+		hash = hashPassword(password)
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.private = password != ""
+	r.passwordHash = hash
 }
 
 func (r *Room) read() {
 	for {
 		message, ok := <-r.inbound
 		if !ok {
-			fmt.Println("room<- closed")
+			slog.Debug("room stopped reading", "room", r.Id)
 			// room closed
 			return
 		}
-		go r.HandleMessage(message)
-	}
-}
+		// messages are handled one at a time, so actions can't interleave
+		r.HandleMessage(message)
 
-func (r *Room) write() {
-	for {
-		payload, ok := <-r.outbound
-		if !ok {
-			fmt.Println("room-> closed")
-			// room closed
+		if r.closing {
+			// everyone is gone, so the room can be garbage collected along with its goroutine
+			close(r.closed)
+			slog.Debug("room stopped reading", "room", r.Id)
 			return
 		}
+	}
+}
 
-		included := []*Player{}
-		other := []*Player{}
+// post sends a message for the room to handle, and reports whether it will. Messages sent to
+// a closed room are dropped.
+func (r *Room) post(message ClientMessage) bool {
+	select {
+	case r.inbound <- message:
+		return true
+	case <-r.closed:
+		return false
+	}
+}
 
-		for _, p := range r.Players {
-			if _, ok := payload.include[p.Id]; ok {
-				included = append(included, p)
-				continue
-			}
-			if _, ok := payload.exclude[p.Id]; ok {
-				continue
-			}
+// Inspect runs fn on the room's goroutine, where the state of the room can be read safely,
+// and waits for it to return. It fails if the room is closed.
+func (r *Room) Inspect(fn func()) error {
+	done := make(chan struct{})
+	if !r.post(clientInspect{fn: fn, done: done}) {
+		return ErrRoomNotFound
+	}
+	<-done
+	return nil
+}
 
-			other = append(other, p)
-		}
+// Ping waits, up to timeout, for the room to handle the messages sent to it so far.
+func (r *Room) Ping(timeout time.Duration) error {
+	done := make(chan struct{})
+	deadline := time.After(timeout)
+	select {
+	case r.inbound <- clientPing{done: done}:
+	case <-r.closed:
+		return errors.New("room is closed")
+	case <-deadline:
+		return errors.New("room is not reading messages")
+	}
+	select {
+	case <-done:
+		return nil
+	case <-deadline:
+		return errors.New("room did not handle the ping")
+	}
+}
+
+// publish sends a payload to the room. It is broadcast right away, on the room's goroutine,
+// so it is encoded with the room as it is when sent rather than as another goroutine finds it.
+func (r *Room) publish(payload *serverPayload) {
+	if r.outbound != nil {
+		r.outbound <- payload
+		return
+	}
+	r.broadcast(payload)
+}
+
+// broadcast sends a payload to the players and spectators it is meant for.
+func (r *Room) broadcast(payload *serverPayload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// only events everyone can see are replayed to spectators
+	if len(payload.include) == 0 && len(payload.exclude) == 0 && r.GamePhase == GamePhasePlaying {
+		r.history = append(r.history, payload.message)
+	}
+	r.recordReplay(payload.message)
+
+	included := []*Player{}
+	other := []*Player{}
 
-		var toSend []*Player
-		if len(included) > 0 {
-			toSend = included
-		} else if len(other) > 0 {
-			toSend = other
-		} else {
+	recipients := append(append([]*Player{}, r.Players...), r.Spectators...)
+	for _, p := range recipients {
+		if _, ok := payload.include[p.Id]; ok {
+			included = append(included, p)
+			continue
+		}
+		if _, ok := payload.exclude[p.Id]; ok {
 			continue
 		}
 
-		fmt.Println("room->", payload.message)
+		other = append(other, p)
+	}
+
+	var toSend []*Player
+	if len(included) > 0 {
+		toSend = included
+	} else if len(other) > 0 {
+		toSend = other
+	} else {
+		return
+	}
+
+	slog.Debug("broadcasting message", "room", r.Id, "type", payload.message.ServerType(), "recipients", len(toSend))
+	if m, ok := payload.message.(localized); ok {
+		byLocale := map[string][]*Player{}
 		for _, p := range toSend {
-			fmt.Println("room->    sending to", p.Id)
-			p.outbound <- payload.message
+			byLocale[p.locale] = append(byLocale[p.locale], p)
 		}
+		for lang, players := range byLocale {
+			r.share(m.localize(lang), players)
+		}
+		return
+	}
+	r.share(payload.message, toSend)
+}
+
+// share sends a message to players, encoded once with the room as it is now rather than by
+// every recipient.
+func (r *Room) share(message ServerMessage, players []*Player) {
+	shared, err := shareMessage(message, r, len(players))
+	if err != nil {
+		slog.Error("failed to encode message", "room", r.Id, "type", message.ServerType(), "err", err)
+		return
+	}
+	for _, p := range players {
+		p.send(shared)
 	}
 }
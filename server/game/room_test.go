@@ -0,0 +1,136 @@
+package game
+
+import (
+	"cardgame/card"
+	"cardgame/deck"
+	"cardgame/util"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPlayer(id string) *Player {
+	return &Player{
+		Id:       id,
+		Hand:     PlayerHand{},
+		token:    util.Token(),
+		outbound: make(chan ServerMessage, 64),
+		done:     make(chan struct{}),
+	}
+}
+
+func newTestDeck(size int) *deck.Deck {
+	d := &deck.Deck{Id: "d_test"}
+	for i := 0; i < size; i++ {
+		d.Cards = append(d.Cards, &card.Card{
+			Id:       card.NextId("c"),
+			Type:     card.CardType(i % card.CardTypeCount()),
+			Category: fmt.Sprintf("category %d", i),
+		})
+	}
+	return d
+}
+
+func newTestRoom(t *testing.T) *Room {
+	t.Helper()
+	r := HubMain.NewRoom("")
+	t.Cleanup(func() { HubMain.RemoveRoom(r.Id) })
+	return r
+}
+
+func joinTestRoom(t *testing.T, r *Room, p *Player, spectate bool) {
+	t.Helper()
+	p.setRoom(r)
+	r.HandleJoin(ClientJoin{Player: p, RoomId: r.Id, Spectate: spectate})
+	assert.IsType(t, &ServerAck{}, receive(t, p), "should acknowledge join")
+}
+
+// receive waits for the next message sent to a test player.
+func receive(t *testing.T, p *Player) ServerMessage {
+	t.Helper()
+	select {
+	case m := <-p.outbound:
+		return unshare(m)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for message to %s", p.Id)
+		return nil
+	}
+}
+
+// receiveUntil skips messages sent to a test player until one of type T arrives.
+func receiveUntil[T ServerMessage](t *testing.T, p *Player) T {
+	t.Helper()
+	for {
+		if m, ok := receive(t, p).(T); ok {
+			return m
+		}
+	}
+}
+
+func TestSpectatorCatchUp(t *testing.T) {
+	catchUpInterval = time.Millisecond
+
+	r := newTestRoom(t)
+	owner := newTestPlayer("p_owner")
+	joinTestRoom(t, r, owner, false)
+
+	r.HandleStart(ClientStart{Player: owner})
+	assert.IsType(t, &ServerStart{}, receive(t, owner))
+	r.publish(&serverPayload{message: &ServerTurn{PlayerId: owner.Id}})
+	assert.IsType(t, &ServerTurn{}, receive(t, owner))
+
+	spectator := newTestPlayer("p_spectator")
+	joinTestRoom(t, r, spectator, true)
+	assert.IsType(t, &ServerJoin{}, receive(t, owner), "owner should see spectator join")
+
+	start, ok := receive(t, spectator).(*ServerCatchUpStart)
+	assert.True(t, ok, "spectator should be told a catch up is starting")
+	assert.Equal(t, 2, start.Events)
+	assert.IsType(t, &ServerStart{}, receive(t, spectator))
+	assert.IsType(t, &ServerTurn{}, receive(t, spectator))
+	assert.IsType(t, &ServerCatchUpEnd{}, receive(t, spectator))
+
+	r.publish(&serverPayload{message: &ServerTurn{PlayerId: owner.Id}})
+	assert.IsType(t, &ServerTurn{}, receive(t, spectator), "spectator should receive live events")
+	assert.Contains(t, r.Spectators, spectator)
+	assert.NotContains(t, r.Players, spectator)
+}
+
+func TestSpectatorCatchUpPrivateEvents(t *testing.T) {
+	catchUpInterval = time.Millisecond
+
+	r := newTestRoom(t)
+	owner := newTestPlayer("p_owner")
+	joinTestRoom(t, r, owner, false)
+
+	r.HandleStart(ClientStart{Player: owner})
+	assert.IsType(t, &ServerStart{}, receive(t, owner))
+	r.publish(&serverPayload{include: set{owner.Id: {}}, message: &ServerTurn{PlayerId: owner.Id}})
+	assert.IsType(t, &ServerTurn{}, receive(t, owner))
+
+	spectator := newTestPlayer("p_spectator")
+	joinTestRoom(t, r, spectator, true)
+
+	start, ok := receive(t, spectator).(*ServerCatchUpStart)
+	assert.True(t, ok)
+	assert.Equal(t, 1, start.Events, "targeted events should not be replayed")
+}
+
+func TestSpectatorLobby(t *testing.T) {
+	r := newTestRoom(t)
+	spectator := newTestPlayer("p_spectator")
+	joinTestRoom(t, r, spectator, true)
+
+	r.publish(&serverPayload{message: &ServerTurn{}})
+	assert.IsType(t, &ServerTurn{}, receive(t, spectator), "no catch up should happen in the lobby")
+}
+
+func TestHubPing(t *testing.T) {
+	assert.NoError(t, HubMain.Ping(time.Second))
+
+	stuck := newTestHub()
+	stuck.inbound = make(chan *hubMessage) // nothing reads it
+	assert.Error(t, stuck.Ping(10*time.Millisecond))
+}
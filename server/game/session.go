@@ -0,0 +1,66 @@
+package game
+
+import "cardgame/storage"
+
+// Session is a device signed in to an account, as listed for its owner.
+type Session struct {
+	Id          string `json:"id"`
+	UserAgent   string `json:"userAgent"`   // browser or app the session was created from
+	Created     int64  `json:"created"`     // unix ms
+	Connections int    `json:"connections"` // connections signed in with the session's token
+}
+
+// Sessions returns the sessions of an account, oldest first, with the connections signed in
+// with each of them.
+func (h *Hub) Sessions(accountId string) ([]Session, error) {
+	stored, err := storage.Default.Sessions(accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	connections := map[string]int{}
+	h.onlineMu.RLock()
+	for p := range h.online[accountId] {
+		connections[p.sessionId]++
+	}
+	h.onlineMu.RUnlock()
+
+	sessions := make([]Session, len(stored))
+	for i, s := range stored {
+		sessions[i] = Session{Id: s.Id, UserAgent: s.UserAgent, Created: s.Created, Connections: connections[s.Id]}
+	}
+	return sessions, nil
+}
+
+// EndSession disconnects the connections signed in with a session of an account, and returns
+// how many there were. The session has to be deleted first, so they can't sign in again.
+func (h *Hub) EndSession(accountId, sessionId string) int {
+	return h.endConnections(accountId, func(p *Player) bool { return p.sessionId == sessionId })
+}
+
+// EndSessions disconnects every connection an account is signed in on, and returns how many
+// there were.
+func (h *Hub) EndSessions(accountId string) int {
+	return h.endConnections(accountId, func(p *Player) bool { return true })
+}
+
+// endConnections disconnects the connections signed in to an account that match. They stop
+// counting as signed in right away, instead of once their socket notices.
+func (h *Hub) endConnections(accountId string, match func(p *Player) bool) int {
+	h.onlineMu.RLock()
+	ended := []*Player{}
+	for p := range h.online[accountId] {
+		if match(p) {
+			ended = append(ended, p)
+		}
+	}
+	h.onlineMu.RUnlock()
+
+	for _, p := range ended {
+		h.signOut(p)
+		if p.socket != nil {
+			p.socket.Close()
+		}
+	}
+	return len(ended)
+}
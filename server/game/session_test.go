@@ -0,0 +1,38 @@
+package game
+
+import (
+	"cardgame/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	s := withTestStore(t)
+	s.SaveAccount(&storage.Account{Id: "u_a", Name: "alice"})
+	s.SaveSession(&storage.Session{Id: "s_phone", AccountId: "u_a", TokenHash: storage.HashToken("t_phone"), UserAgent: "phone", Created: 2})
+	s.SaveSession(&storage.Session{Id: "s_laptop", AccountId: "u_a", TokenHash: storage.HashToken("t_laptop"), Created: 1})
+
+	h := newTestHub()
+	phone, laptop, tab := newTestPlayer("p_phone"), newTestPlayer("p_laptop"), newTestPlayer("p_tab")
+	h.handleSignIn(ClientSignIn{Player: phone, Token: "t_phone"})
+	h.handleSignIn(ClientSignIn{Player: laptop, Token: "t_laptop"})
+	h.handleSignIn(ClientSignIn{Player: tab, Token: "t_laptop"})
+
+	sessions, err := h.Sessions("u_a")
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, "s_laptop", sessions[0].Id, "sessions should be ordered oldest first")
+		assert.Equal(t, 2, sessions[0].Connections)
+		assert.Equal(t, "phone", sessions[1].UserAgent)
+		assert.Equal(t, 1, sessions[1].Connections)
+	}
+	sessions, err = h.Sessions("u_missing")
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	assert.Equal(t, 2, h.EndSession("u_a", "s_laptop"))
+	assert.True(t, h.Online("u_a"), "the other sessions should stay signed in")
+	assert.Equal(t, 1, h.EndSessions("u_a"))
+	assert.False(t, h.Online("u_a"))
+}
@@ -0,0 +1,359 @@
+package game
+
+import (
+	"cardgame/card"
+	"cardgame/deck"
+	"cardgame/storage"
+	"cardgame/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// suspendedState is what is saved of a suspended game, to resume it where it left off.
+type suspendedState struct {
+	Rules          matchRules       `json:"rules"`
+	Ranked         bool             `json:"ranked"`
+	MaxPlayers     int              `json:"maxPlayers"`
+	Seats          []suspendedSeat  `json:"seats"`
+	CurrentTurn    int              `json:"currentTurn"`
+	DrawPile       []savedCard      `json:"drawPile"`
+	ActiveWildCard *card.WildCard   `json:"activeWildCard"`
+	UsedWildCards  []*card.WildCard `json:"usedWildCards"`
+	Elapsed        int64            `json:"elapsed"` // ms played before the game was suspended
+	Seed           int64            `json:"seed"`
+	Replay         []replayEvent    `json:"replay"`
+}
+
+// suspendedSeat is a seat of a suspended game, which only its account can take back.
+type suspendedSeat struct {
+	AccountId string     `json:"accountId"`
+	Score     int        `json:"score"`
+	Hand      PlayerHand `json:"hand"`
+	Draws     int        `json:"draws"`
+}
+
+// savedCard is a card of the draw pile of a suspended game, holding either kind of card.
+type savedCard struct {
+	Card *card.Card     `json:"card,omitempty"`
+	Wild *card.WildCard `json:"wild,omitempty"`
+}
+
+// resumingGame is a suspended game loaded into a room, waiting for its players.
+type resumingGame struct {
+	id    string
+	state suspendedState
+}
+
+// seat returns the index of an account's seat, or -1 if it has none.
+func (g *resumingGame) seat(accountId string) int {
+	for i, s := range g.state.Seats {
+		if accountId != "" && s.AccountId == accountId {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Room) HandleVoteSuspend(message ClientVoteSuspend) {
+	p := message.Player
+
+	if !r.canVote(p) {
+		r.logger().Warn("player cannot vote")
+		p.send(&ServerError{Id: "cannot_vote"})
+		return
+	}
+
+	if r.Vote != nil {
+		r.logger().Warn("a vote is already in progress")
+		p.send(&ServerError{Id: "vote_in_progress"})
+		return
+	}
+
+	if r.GamePhase != GamePhasePlaying {
+		r.logger().Warn("game is not in playing phase")
+		p.send(&ServerError{Id: "game_not_playing"})
+		return
+	}
+
+	// seats are given back by account, so every seat needs one of its own
+	accounts := set{}
+	for _, player := range r.Players {
+		if _, ok := accounts[player.AccountId]; ok || player.AccountId == "" {
+			r.logger().Warn("not every player is signed in")
+			p.send(&ServerError{Id: "suspend_sign_in"})
+			return
+		}
+		accounts[player.AccountId] = struct{}{}
+	}
+
+	r.startVote(&Vote{
+		Kind:        VoteKindSuspend,
+		InitiatorId: p.Id,
+		Votes:       map[string]bool{p.Id: true},
+		Deadline:    Clock.Now().Add(voteKickTimeout).UnixMilli(),
+	})
+
+	r.publish(&serverPayload{
+		message: &ServerVoteSuspend{
+			InitiatorId: p.Id,
+			Timeout:     int(voteKickTimeout / time.Second),
+		},
+	})
+
+	r.tallyVote()
+}
+
+// suspend saves the game in progress so its players can resume it later, and returns
+// the room to the lobby. If the game can't be saved, it goes on.
+func (r *Room) suspend() {
+	r.mu.Lock()
+	replay := append([]replayEvent{}, r.replay...)
+	r.mu.Unlock()
+
+	state := suspendedState{
+		Rules:          r.rules(),
+		Ranked:         r.Ranked,
+		MaxPlayers:     r.MaxPlayers,
+		CurrentTurn:    r.CurrentTurn,
+		ActiveWildCard: r.ActiveWildCard,
+		UsedWildCards:  r.UsedWildCards,
+		Elapsed:        Clock.Now().UnixMilli() - r.started,
+		Seed:           r.seed,
+		Replay:         replay,
+	}
+	for _, p := range r.Players {
+		state.Seats = append(state.Seats, suspendedSeat{
+			AccountId: p.AccountId,
+			Score:     p.Score,
+			Hand:      p.Hand,
+			Draws:     p.draws,
+		})
+	}
+	for _, c := range r.DrawPile {
+		switch c := c.(type) {
+		case *card.Card:
+			state.DrawPile = append(state.DrawPile, savedCard{Card: c})
+		case *card.WildCard:
+			state.DrawPile = append(state.DrawPile, savedCard{Wild: c})
+		}
+	}
+
+	// standings are listed in seat order, so players can tell the game apart by who was in it
+	ranks := map[string]int{}
+	for _, result := range r.results() {
+		ranks[result.Id] = result.Rank
+	}
+	standings := []storage.MatchPlayer{}
+	for _, p := range r.Players {
+		standings = append(standings, storage.MatchPlayer{
+			Id:        p.Id,
+			AccountId: p.AccountId,
+			Name:      p.Name,
+			Score:     p.Score,
+			Rank:      ranks[p.Id],
+		})
+	}
+
+	id := util.IdFrom("g", util.Token())
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = storage.Default.SaveSuspendedGame(&storage.SuspendedGame{
+			Id:        id,
+			RoomName:  r.Name,
+			GameType:  string(r.GameType),
+			Players:   standings,
+			State:     data,
+			Started:   r.started,
+			Suspended: Clock.Now().UnixMilli(),
+		})
+	}
+	if err != nil {
+		r.logger().Error("failed to save suspended game", "err", err)
+		r.publish(&serverPayload{message: &ServerError{Id: "suspend_failed"}})
+		return
+	}
+	if r.pauseTimer != nil {
+		r.pauseTimer.Stop()
+		r.pauseTimer = nil
+	}
+	r.Paused = false
+	r.stopTurnTimer()
+
+	r.mu.Lock()
+	r.history = nil
+	r.replay = nil
+	r.mu.Unlock()
+
+	players := []*Player{}
+	for _, p := range r.Players {
+		if p.Disconnected {
+			p.setRoom(nil)
+			continue
+		}
+		p.Hand = PlayerHand{}
+		p.Score = 0
+		p.draws = 0
+		players = append(players, p)
+	}
+	r.Players = players
+
+	r.GamePhase = GamePhaseLobby
+	r.CurrentTurn = 0
+	r.Clear()
+
+	r.publish(&serverPayload{
+		message: &ServerSuspend{GameId: id},
+	})
+}
+
+// ResumeGame loads a suspended game into a new private room its players are invited to,
+// which resumes the game once all of them have joined. If the game is already waiting in
+// a room, that room is returned.
+func (h *Hub) ResumeGame(g *storage.SuspendedGame) (*Room, error) {
+	if r, ok := h.resumingRoom(g.Id); ok {
+		return r, nil
+	}
+
+	var state suspendedState
+	if err := json.Unmarshal(g.State, &state); err != nil {
+		return nil, fmt.Errorf("decoding suspended game: %w", err)
+	}
+	if len(state.Seats) == 0 {
+		return nil, errors.New("suspended game has no players")
+	}
+
+	// an unguessable password, so only the invited players can join
+	r := h.makeRoom(util.Token())
+	r.Name = g.RoomName
+	r.GameType = GameType(g.GameType)
+	r.MaxPlayers = len(state.Seats)
+	if state.MaxPlayers > r.MaxPlayers {
+		r.MaxPlayers = state.MaxPlayers
+	}
+	r.Ranked = state.Ranked
+	r.PlayMode = state.Rules.PlayMode
+	r.DisconnectGrace = state.Rules.DisconnectGrace
+	r.AfkPolicy = state.Rules.AfkPolicy
+	r.TurnTimeout = state.Rules.TurnTimeout
+	r.AfkTurns = state.Rules.AfkTurns
+	for _, id := range state.Rules.Decks {
+		if d, ok := deck.Decks()[id]; ok {
+			r.Decks = append(r.Decks, d)
+		}
+	}
+	r.invited = set{}
+	for _, s := range state.Seats {
+		r.invited[s.AccountId] = struct{}{}
+	}
+	r.resuming = &resumingGame{id: g.Id, state: state}
+
+	// the game may have been loaded by someone else meanwhile, so the room is only added if not
+	h.roomsMu.Lock()
+	if waiting, ok := h.resuming[g.Id]; ok {
+		h.roomsMu.Unlock()
+		return waiting, nil
+	}
+	h.rooms[r.Id] = r
+	h.resuming[g.Id] = r
+	h.roomsMu.Unlock()
+
+	go r.read()
+	return r, nil
+}
+
+// resumingRoom returns the room waiting for the players of a suspended game, if there is one.
+func (h *Hub) resumingRoom(gameId string) (*Room, bool) {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+	r, ok := h.resuming[gameId]
+	return r, ok
+}
+
+// checkResumingSeat reports whether a player may take a seat while the room waits for
+// the players of a suspended game. If not, the player is told why.
+func (r *Room) checkResumingSeat(p *Player) bool {
+	if r.resuming.seat(p.AccountId) < 0 {
+		p.send(&ServerError{Id: "suspended_wrong_account"})
+		return false
+	}
+	for _, player := range r.Players {
+		if player.AccountId == p.AccountId {
+			p.send(&ServerError{Id: "account_seated"})
+			return false
+		}
+	}
+	return true
+}
+
+// resumeIfReady resumes the suspended game the room is waiting for, once every one of
+// its players has a seat.
+func (r *Room) resumeIfReady() {
+	g := r.resuming
+	players := make([]*Player, len(g.state.Seats))
+	for _, p := range r.Players {
+		if i := g.seat(p.AccountId); i >= 0 {
+			players[i] = p
+		}
+	}
+	for _, p := range players {
+		if p == nil {
+			return
+		}
+	}
+
+	state := g.state
+	for i, p := range players {
+		seat := state.Seats[i]
+		p.Score = seat.Score
+		p.Hand = seat.Hand
+		if p.Hand == nil {
+			p.Hand = PlayerHand{}
+		}
+		p.draws = seat.Draws
+	}
+	r.Players = players
+	r.CurrentTurn = state.CurrentTurn
+	r.DrawPile = []card.BaseCard{}
+	for _, c := range state.DrawPile {
+		if c.Card != nil {
+			r.DrawPile = append(r.DrawPile, c.Card)
+		} else if c.Wild != nil {
+			r.DrawPile = append(r.DrawPile, c.Wild)
+		}
+	}
+	r.DrawPileSize = len(r.DrawPile)
+	r.ActiveWildCard = state.ActiveWildCard
+	r.UsedWildCards = state.UsedWildCards
+
+	// the state of the old rng isn't saved, so only the seed of the first deal is kept
+	r.seed = state.Seed
+	r.rng = rand.New(rand.NewSource(Clock.Now().UnixNano()))
+	r.mu.Lock()
+	r.started = Clock.Now().UnixMilli() - state.Elapsed
+	r.history = nil
+	r.replay = state.Replay
+	if r.replay == nil {
+		r.replay = []replayEvent{}
+	}
+	r.mu.Unlock()
+
+	r.resuming = nil
+	r.hub.roomsMu.Lock()
+	delete(r.hub.resuming, g.id)
+	r.hub.roomsMu.Unlock()
+	r.GamePhase = GamePhasePlaying
+	if err := storage.Default.DeleteSuspendedGame(g.id); err != nil {
+		r.logger().Error("failed to delete suspended game", "err", err)
+	}
+
+	r.publish(&serverPayload{
+		message: &ServerResumeGame{
+			GameId:      g.id,
+			CurrentTurn: r.CurrentTurn,
+		},
+	})
+	r.startTurnTimer()
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuspendAndResume(t *testing.T) {
+	s := withTestStore(t)
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	a.AccountId, b.AccountId = "u_a", "u_b"
+	r := startTestGame(t, a, b)
+	current := r.currentPlayer()
+	r.HandleDraw(ClientDraw{Player: current})
+	receiveUntil[*ServerResync](t, a)
+	scores := map[string]int{"u_a": 3, "u_b": 1}
+	a.Score, b.Score = scores["u_a"], scores["u_b"]
+	hands := map[string]int{"u_a": len(a.Hand), "u_b": len(b.Hand)}
+	pile, turn := r.DrawPileSize, r.CurrentTurn
+
+	r.HandleVoteSuspend(ClientVoteSuspend{Player: a})
+	receiveUntil[*ServerVoteSuspend](t, b)
+	assert.NotNil(t, r.Vote, "suspending should need every player")
+	r.HandleVote(ClientVote{Player: b, Yes: true})
+	suspended := receiveUntil[*ServerSuspend](t, a)
+	assert.Equal(t, GamePhaseLobby, r.GamePhase)
+
+	games, err := s.SuspendedGames("u_b")
+	assert.NoError(t, err)
+	if !assert.Len(t, games, 1) {
+		return
+	}
+	assert.Equal(t, suspended.GameId, games[0].Id)
+
+	resumed, err := HubMain.ResumeGame(games[0])
+	assert.NoError(t, err)
+	t.Cleanup(func() { HubMain.RemoveRoom(resumed.Id) })
+	again, _ := HubMain.ResumeGame(games[0])
+	assert.Equal(t, resumed, again, "a game should only be waiting in one room")
+
+	stranger := newTestPlayer("p_c")
+	stranger.AccountId = "u_c"
+	stranger.setRoom(resumed)
+	resumed.HandleJoin(ClientJoin{Player: stranger, RoomId: resumed.Id})
+	assert.Equal(t, "Only the players of the suspended game can take a seat", receiveUntil[*ServerError](t, stranger).Message)
+
+	// new connections, joining in a different order
+	b2, a2 := newTestPlayer("p_b2"), newTestPlayer("p_a2")
+	b2.AccountId, a2.AccountId = "u_b", "u_a"
+	joinTestRoom(t, resumed, b2, false)
+	assert.Equal(t, GamePhaseLobby, resumed.GamePhase, "the game should wait for every player")
+	joinTestRoom(t, resumed, a2, false)
+	resume := receiveUntil[*ServerResumeGame](t, b2)
+	assert.Equal(t, suspended.GameId, resume.GameId)
+
+	assert.Equal(t, GamePhasePlaying, resumed.GamePhase)
+	assert.Equal(t, []*Player{a2, b2}, resumed.Players, "players should get their old seats back")
+	assert.Equal(t, turn, resumed.CurrentTurn)
+	assert.Equal(t, pile, resumed.DrawPileSize)
+	for _, p := range resumed.Players {
+		assert.Equal(t, scores[p.AccountId], p.Score)
+		assert.Len(t, p.Hand, hands[p.AccountId])
+	}
+	_, err = s.SuspendedGame(suspended.GameId)
+	assert.Error(t, err, "a resumed game should be removed from the list")
+	_, waiting := HubMain.resumingRoom(suspended.GameId)
+	assert.False(t, waiting, "a resumed game should stop waiting for its players")
+}
+
+func TestResumeGameOnce(t *testing.T) {
+	state, _ := json.Marshal(suspendedState{Seats: []suspendedSeat{{AccountId: "u_a"}, {AccountId: "u_b"}}})
+	g := &storage.SuspendedGame{Id: "sg_once", State: state}
+
+	rooms := make([]*Room, 8)
+	var wg sync.WaitGroup
+	for i := range rooms {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rooms[i], _ = HubMain.ResumeGame(g)
+		}(i)
+	}
+	wg.Wait()
+	for _, r := range rooms {
+		assert.Same(t, rooms[0], r, "a game should only be waiting in one room")
+	}
+
+	HubMain.CloseRoom(rooms[0].Id, nil, "")
+	_, waiting := HubMain.resumingRoom(g.Id)
+	assert.False(t, waiting, "a closed room should stop waiting for the game")
+	again, err := HubMain.ResumeGame(g)
+	assert.NoError(t, err)
+	assert.NotSame(t, rooms[0], again, "the game should be loaded again once its room is closed")
+	HubMain.CloseRoom(again.Id, nil, "")
+}
+
+func TestSuspendNeedsAccounts(t *testing.T) {
+	withTestStore(t)
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	a.AccountId = "u_a"
+	r := startTestGame(t, a, b)
+
+	r.HandleVoteSuspend(ClientVoteSuspend{Player: a})
+	assert.Equal(t, "every player has to be signed in to suspend the game", receiveUntil[*ServerError](t, a).Message)
+	assert.Nil(t, r.Vote)
+}
@@ -0,0 +1,120 @@
+package game
+
+import (
+	"cardgame/clock"
+	"cardgame/locale"
+	"time"
+)
+
+// Clock is what the game reads the time and starts its timers with. Tests replace it with a
+// fake clock to play out timeouts without waiting for them.
+var Clock clock.Clock = clock.Real{}
+
+var (
+	// DefaultTurnTimeout is the number of seconds a player has to draw in new rooms, set on startup.
+	DefaultTurnTimeout = 30
+	// DefaultAfkTurns is the number of consecutive timed out turns before a player is considered AFK
+	// in new rooms, set on startup.
+	DefaultAfkTurns = 3
+)
+
+// turnMessage tells the players whose turn it is now.
+func (r *Room) turnMessage() *ServerTurn {
+	p := r.Players[r.CurrentTurn]
+	return &ServerTurn{
+		PlayerId:  p.Id,
+		narration: narrate("event_turn", locale.Params{"player": p.Name}),
+	}
+}
+
+// startTurnTimer (re)starts the timer for the current player's turn.
+func (r *Room) startTurnTimer() {
+	r.stopTurnTimer()
+	r.turnTimerSeq++
+
+	if r.TurnTimeout <= 0 || r.GamePhase != GamePhasePlaying || r.Paused {
+		return
+	}
+
+	seq := r.turnTimerSeq
+	r.turnTimer = Clock.AfterFunc(time.Duration(r.TurnTimeout)*time.Second, func() {
+		r.post(clientTurnTimeout{seq})
+	})
+}
+
+func (r *Room) stopTurnTimer() {
+	if r.turnTimer != nil {
+		r.turnTimer.Stop()
+		r.turnTimer = nil
+	}
+}
+
+// HandleTurnTimeout draws on behalf of a player who ran out of time,
+// and hands their seat to a bot if they keep missing turns.
+func (r *Room) HandleTurnTimeout(message clientTurnTimeout) {
+	if message.Seq != r.turnTimerSeq || r.GamePhase != GamePhasePlaying || r.Paused {
+		// timer was stopped or a new turn started in the meantime
+		return
+	}
+
+	p := r.currentPlayer()
+	if p == nil {
+		return
+	}
+	turnTimeouts.Inc("")
+	if !p.Bot {
+		p.missedTurns++
+		r.publish(&serverPayload{
+			message: &ServerTurnTimeout{
+				PlayerId:  p.Id,
+				Missed:    p.missedTurns,
+				narration: narrate("event_turn_timeout", locale.Params{"player": p.Name}),
+			},
+		})
+
+		if r.AfkTurns > 0 && !r.Ranked && p.missedTurns >= r.AfkTurns {
+			if r.AfkPolicy == AfkPolicySeatOpen {
+				r.removePlayer(p)
+				if len(r.Players) > 0 {
+					r.publish(&serverPayload{
+						message: r.turnMessage(),
+					})
+				}
+				r.startTurnTimer()
+				return
+			}
+
+			p.Afk = true
+			p.Bot = true
+			r.publish(&serverPayload{
+				message: &ServerAfk{
+					PlayerId:  p.Id,
+					Afk:       true,
+					narration: narrate("event_afk", locale.Params{"player": p.Name}),
+				},
+			})
+		}
+	}
+
+	r.HandleDraw(ClientDraw{Player: p, bot: true})
+}
+
+// returnControl is called when a player acts themselves, resetting their missed turns
+// and taking their seat back from the bot if they were AFK.
+func (r *Room) returnControl(p *Player) {
+	p.missedTurns = 0
+
+	if !p.Afk {
+		return
+	}
+
+	p.Afk = false
+	p.Bot = false
+	r.publish(&serverPayload{
+		message: &ServerAfk{
+			PlayerId:  p.Id,
+			Afk:       false,
+			narration: narrate("event_back", locale.Params{"player": p.Name}),
+		},
+	})
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTurnTimeoutDraws(t *testing.T) {
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	r := startTestGame(t, a, b)
+	r.CurrentTurn = 0
+
+	r.HandleTurnTimeout(clientTurnTimeout{r.turnTimerSeq})
+	timeout := receiveUntil[*ServerTurnTimeout](t, b)
+	assert.Equal(t, a.Id, timeout.PlayerId)
+	assert.Equal(t, 1, timeout.Missed)
+	assert.Equal(t, a.Id, receiveUntil[*ServerDraw](t, b).PlayerId, "should draw for the player")
+	assert.Equal(t, 1, r.CurrentTurn)
+
+	seq := r.turnTimerSeq
+	r.HandleTurnTimeout(clientTurnTimeout{seq - 1})
+	assert.Equal(t, 1, r.CurrentTurn, "stale timers should be ignored")
+}
+
+func TestAfkBotFill(t *testing.T) {
+	withTestClock(t) // so the bot doesn't play on its own while the test does
+	a := newTestPlayer("p_a")
+	r := startTestGame(t, a)
+	r.AfkTurns = 2
+	r.AfkPolicy = AfkPolicyBotFill
+
+	r.HandleTurnTimeout(clientTurnTimeout{r.turnTimerSeq})
+	assert.False(t, a.Afk)
+	r.HandleTurnTimeout(clientTurnTimeout{r.turnTimerSeq})
+	afk := receiveUntil[*ServerAfk](t, a)
+	assert.True(t, afk.Afk)
+	assert.True(t, a.Bot, "bot should play for an AFK player")
+
+	r.HandleChat(ClientChat{Player: a, Message: "still here"})
+	assert.True(t, a.Afk, "chatting is not taking a turn")
+
+	r.HandleDraw(ClientDraw{Player: a})
+	afk = receiveUntil[*ServerAfk](t, a)
+	assert.False(t, afk.Afk)
+	assert.False(t, a.Bot, "player should get their seat back after acting")
+	assert.Equal(t, 0, a.missedTurns)
+}
+
+func TestAfkSeatOpen(t *testing.T) {
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	r := startTestGame(t, a, b)
+	r.AfkTurns = 1
+	r.CurrentTurn = 0
+
+	r.HandleTurnTimeout(clientTurnTimeout{r.turnTimerSeq})
+	receiveUntil[*ServerKick](t, a)
+	assert.NotContains(t, r.Players, a)
+	assert.Equal(t, b.Id, receiveUntil[*ServerTurn](t, b).PlayerId)
+}
+
+func TestAfkRanked(t *testing.T) {
+	a := newTestPlayer("p_a")
+	r := startTestGame(t, a)
+	r.AfkTurns = 1
+	r.Ranked = true
+	r.AfkPolicy = AfkPolicyBotFill
+
+	r.HandleTurnTimeout(clientTurnTimeout{r.turnTimerSeq})
+	r.HandleTurnTimeout(clientTurnTimeout{r.turnTimerSeq})
+	assert.False(t, a.Afk, "ranked rooms should not substitute AFK players")
+	assert.False(t, a.Bot)
+	assert.Equal(t, 2, a.missedTurns)
+}
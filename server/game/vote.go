@@ -0,0 +1,254 @@
+package game
+
+import (
+	"cardgame/clock"
+	"cardgame/locale"
+	"cardgame/storage"
+	"strconv"
+	"time"
+)
+
+var (
+	// voteKickTimeout is how long players have to vote before a vote-kick, or a vote to suspend the game, fails.
+	voteKickTimeout = 30 * time.Second
+	// voteKickCooldown is how long a player has to wait between starting vote-kicks.
+	voteKickCooldown = 2 * time.Minute
+)
+
+// minKickVoters is the number of players besides the target who have to be able to vote for a
+// kick vote to be held, so no one can kick the only other player by voting alone.
+const minKickVoters = 2
+
+// Vote is a vote to remove a player from the room, or to suspend the game.
+type Vote struct {
+	Kind        VoteKind        `json:"kind"`
+	InitiatorId string          `json:"initiatorId"`
+	TargetId    string          `json:"targetId"` // player to remove by a kick vote
+	Votes       map[string]bool `json:"votes"`    // playerId -> yes
+	Deadline    int64           `json:"deadline"` // unix ms when the vote fails
+	reason      string          // why the initiator started a kick vote, for the audit log
+	timer       clock.Timer
+}
+
+func (r *Room) HandleVoteKick(message ClientVoteKick) {
+	p := message.Player
+
+	if !r.canVote(p) {
+		r.logger().Warn("player cannot vote")
+		p.send(&ServerError{Id: "cannot_vote"})
+		return
+	}
+
+	if r.Vote != nil {
+		r.logger().Warn("a vote is already in progress")
+		p.send(&ServerError{Id: "vote_in_progress"})
+		return
+	}
+
+	if message.Id == p.Id {
+		r.logger().Warn("player cannot vote to kick themselves")
+		p.send(&ServerError{Id: "vote_kick_self"})
+		return
+	}
+
+	if r.getPlayer(message.Id) == nil {
+		r.logger().Warn("target player not found")
+		p.send(&ServerError{Id: "target_not_found"})
+		return
+	}
+
+	if r.kickVoters(message.Id) < minKickVoters {
+		r.logger().Warn("too few players to vote-kick")
+		p.send(&ServerError{Id: "vote_kick_players", Params: locale.Params{"min": strconv.Itoa(minKickVoters + 1)}})
+		return
+	}
+
+	reason, err := CleanReason(message.Reason)
+	if err != nil {
+		p.send(serverError(err))
+		return
+	}
+
+	now := Clock.Now()
+	if last, ok := r.lastVoteKick[p.Id]; ok && now.Sub(time.UnixMilli(last)) < voteKickCooldown {
+		r.logger().Warn("player started a vote-kick too recently")
+		p.send(&ServerError{Id: "vote_kick_cooldown"})
+		return
+	}
+	if r.lastVoteKick == nil {
+		r.lastVoteKick = make(map[string]int64)
+	}
+	r.lastVoteKick[p.Id] = now.UnixMilli()
+
+	vote := &Vote{
+		Kind:        VoteKindKick,
+		InitiatorId: p.Id,
+		TargetId:    message.Id,
+		Votes:       map[string]bool{p.Id: true},
+		Deadline:    now.Add(voteKickTimeout).UnixMilli(),
+		reason:      reason,
+	}
+	r.startVote(vote)
+
+	r.publish(&serverPayload{
+		message: &ServerVoteKick{
+			InitiatorId: p.Id,
+			TargetId:    message.Id,
+			Timeout:     int(voteKickTimeout / time.Second),
+		},
+	})
+
+	r.tallyVote()
+}
+
+// startVote makes a vote the active one, failing it once voteKickTimeout has passed.
+func (r *Room) startVote(vote *Vote) {
+	vote.timer = Clock.AfterFunc(voteKickTimeout, func() {
+		r.post(clientVoteExpired{vote})
+	})
+	r.Vote = vote
+}
+
+func (r *Room) HandleVote(message ClientVote) {
+	p := message.Player
+
+	if r.Vote == nil {
+		r.logger().Warn("no vote in progress")
+		p.send(&ServerError{Id: "no_vote"})
+		return
+	}
+
+	if !r.canVote(p) || p.Id == r.Vote.TargetId {
+		r.logger().Warn("player cannot vote")
+		p.send(&ServerError{Id: "cannot_vote"})
+		return
+	}
+
+	if _, ok := r.Vote.Votes[p.Id]; ok {
+		r.logger().Warn("player has already voted")
+		p.send(&ServerError{Id: "already_voted"})
+		return
+	}
+
+	r.Vote.Votes[p.Id] = message.Yes
+	r.publish(&serverPayload{
+		message: &ServerVote{
+			PlayerId: p.Id,
+			Yes:      message.Yes,
+		},
+	})
+
+	r.tallyVote()
+}
+
+func (r *Room) HandleVoteExpired(message clientVoteExpired) {
+	if r.Vote == nil || r.Vote != message.Vote {
+		// vote was already decided
+		return
+	}
+
+	r.endVote(false)
+}
+
+// canVote returns true if the player holds a seat they are controlling themselves.
+func (r *Room) canVote(p *Player) bool {
+	return r.getPlayer(p.Id) == p && !p.Disconnected && !p.Bot
+}
+
+// kickVoters returns the number of players who can vote on kicking a player.
+func (r *Room) kickVoters(targetId string) int {
+	n := 0
+	for _, p := range r.Players {
+		if p.Id != targetId && r.canVote(p) {
+			n++
+		}
+	}
+	return n
+}
+
+// tallyVote ends the active vote once enough of the eligible players have voted for it,
+// or once that can no longer happen. Kick votes need a majority of at least minKickVoters,
+// while suspending the game needs everyone, as everyone has to come back to resume it.
+func (r *Room) tallyVote() {
+	eligible, yes, no := 0, 0, 0
+	for _, p := range r.Players {
+		if p.Id == r.Vote.TargetId || !r.canVote(p) {
+			continue
+		}
+		eligible++
+		if v, ok := r.Vote.Votes[p.Id]; ok {
+			if v {
+				yes++
+			} else {
+				no++
+			}
+		}
+	}
+
+	needed := max(eligible/2+1, minKickVoters)
+	if r.Vote.Kind == VoteKindSuspend {
+		needed = eligible
+	}
+	if yes >= needed {
+		r.endVote(true)
+	} else if eligible-no < needed {
+		r.endVote(false)
+	}
+}
+
+func (r *Room) endVote(passed bool) {
+	vote := r.Vote
+	vote.timer.Stop()
+	r.Vote = nil
+
+	r.publish(&serverPayload{
+		message: &ServerVoteResult{
+			Kind:     vote.Kind,
+			TargetId: vote.TargetId,
+			Passed:   passed,
+		},
+	})
+
+	if !passed {
+		return
+	}
+	switch vote.Kind {
+	case VoteKindKick:
+		if target := r.getPlayer(vote.TargetId); target != nil {
+			initiator := r.getPlayer(vote.InitiatorId)
+			if initiator == nil {
+				// the initiator left before the vote passed
+				initiator = &Player{Id: vote.InitiatorId}
+			}
+			r.auditKick(storage.AuditVoteKick, initiator, target, vote.reason)
+			r.removePlayer(target)
+		}
+	case VoteKindSuspend:
+		r.suspend()
+	}
+}
+
+// removePlayer kicks a player out of the room. If the game is in progress,
+// the seat is handled according to the room's AFK policy.
+func (r *Room) removePlayer(p *Player) {
+	p.send(&ServerKick{})
+
+	if r.GamePhase == GamePhasePlaying && r.AfkPolicy == AfkPolicyBotFill {
+		for i, player := range r.Players {
+			if player == p {
+				r.Players[i] = botFrom(p)
+			}
+		}
+		p.setRoom(nil)
+		if r.OwnerId == p.Id {
+			r.transferOwnership()
+		}
+		r.scheduleBot()
+		return
+	}
+
+	r.HandleLeave(ClientLeave{p})
+	if r.OwnerId == p.Id {
+		r.transferOwnership()
+	}
+}
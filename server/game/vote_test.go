@@ -0,0 +1,105 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoteKickPasses(t *testing.T) {
+	r := newTestRoom(t)
+	a, b, c := newTestPlayer("p_a"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	for _, p := range []*Player{a, b, c} {
+		joinTestRoom(t, r, p, false)
+	}
+
+	r.HandleVoteKick(ClientVoteKick{Player: a, Id: c.Id})
+	assert.Equal(t, c.Id, receiveUntil[*ServerVoteKick](t, b).TargetId)
+	assert.NotNil(t, r.Vote, "one vote out of two should not decide the vote")
+
+	r.HandleVote(ClientVote{Player: c, Yes: false})
+	receiveUntil[*ServerError](t, c)
+
+	r.HandleVote(ClientVote{Player: b, Yes: true})
+	result := receiveUntil[*ServerVoteResult](t, a)
+	assert.True(t, result.Passed)
+	assert.Nil(t, r.Vote)
+	receiveUntil[*ServerKick](t, c)
+	assert.NotContains(t, r.Players, c)
+	assert.Nil(t, c.currentRoom())
+}
+
+func TestVoteKickFails(t *testing.T) {
+	r := newTestRoom(t)
+	a, b, c := newTestPlayer("p_a"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	for _, p := range []*Player{a, b, c} {
+		joinTestRoom(t, r, p, false)
+	}
+
+	r.HandleVoteKick(ClientVoteKick{Player: a, Id: c.Id})
+	r.HandleVote(ClientVote{Player: b, Yes: false})
+	result := receiveUntil[*ServerVoteResult](t, a)
+	assert.False(t, result.Passed)
+	assert.Contains(t, r.Players, c)
+
+	r.HandleVoteKick(ClientVoteKick{Player: a, Id: c.Id})
+	err := receiveUntil[*ServerError](t, a)
+	assert.Equal(t, "player started a vote-kick too recently", err.Message)
+	assert.Nil(t, r.Vote)
+}
+
+func TestVoteKickExpires(t *testing.T) {
+	r := newTestRoom(t)
+	a, b, c := newTestPlayer("p_a"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	for _, p := range []*Player{a, b, c} {
+		joinTestRoom(t, r, p, false)
+	}
+
+	r.HandleVoteKick(ClientVoteKick{Player: a, Id: c.Id})
+	r.HandleVoteExpired(clientVoteExpired{r.Vote})
+	assert.False(t, receiveUntil[*ServerVoteResult](t, b).Passed)
+	assert.Contains(t, r.Players, c)
+}
+
+func TestVoteKickTwoPlayers(t *testing.T) {
+	r := newTestRoom(t)
+	a, b := newTestPlayer("p_a"), newTestPlayer("p_b")
+	for _, p := range []*Player{a, b} {
+		joinTestRoom(t, r, p, false)
+	}
+
+	r.HandleVoteKick(ClientVoteKick{Player: a, Id: b.Id})
+	assert.Equal(t, "Kick votes need at least 3 players", receiveUntil[*ServerError](t, a).Message)
+	assert.Nil(t, r.Vote, "no one should kick the only other player alone")
+	assert.Contains(t, r.Players, b)
+}
+
+func TestVoteKickVoterLeaves(t *testing.T) {
+	r := newTestRoom(t)
+	a, b, c := newTestPlayer("p_a"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	for _, p := range []*Player{a, b, c} {
+		joinTestRoom(t, r, p, false)
+	}
+
+	r.HandleVoteKick(ClientVoteKick{Player: a, Id: c.Id})
+	r.HandleLeave(ClientLeave{b})
+	r.tallyVote()
+	assert.False(t, receiveUntil[*ServerVoteResult](t, a).Passed, "the initiator alone should not pass a kick")
+	assert.Contains(t, r.Players, c)
+}
+
+func TestVoteKickBotFill(t *testing.T) {
+	a, b, c := newTestPlayer("p_a"), newTestPlayer("p_b"), newTestPlayer("p_c")
+	r := startTestGame(t, a, b, c)
+	r.AfkPolicy = AfkPolicyBotFill
+
+	r.HandleVoteKick(ClientVoteKick{Player: a, Id: b.Id})
+	r.HandleVote(ClientVote{Player: c, Yes: true})
+	assert.True(t, receiveUntil[*ServerVoteResult](t, a).Passed)
+	receiveUntil[*ServerKick](t, b)
+
+	seat := r.getPlayer(b.Id)
+	assert.NotNil(t, seat, "seat should be kept")
+	assert.NotSame(t, b, seat, "kicked player should no longer control the seat")
+	assert.True(t, seat.Bot)
+}
@@ -0,0 +1,132 @@
+// Package health checks the subsystems the server depends on, and serves their status for
+// orchestrators to probe.
+//
+// Liveness checks tell whether the server is stuck and should be restarted, like the hub no
+// longer handling messages. Readiness checks tell whether it can serve players, like the
+// database being reachable, and include the liveness checks.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Timeout is how long a check can take before it fails.
+var Timeout = 2 * time.Second
+
+// Check returns an error if a subsystem is not working.
+type Check func() error
+
+type check struct {
+	name  string
+	check Check
+	live  bool
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]check{}
+)
+
+func register(c check) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[c.name]; ok {
+		panic("health: " + c.name + " is registered twice")
+	}
+	registry[c.name] = c
+}
+
+// Live registers a check the server has to pass to be alive, and so ready.
+func Live(name string, c Check) {
+	register(check{name, c, true})
+}
+
+// Ready registers a check the server has to pass to be ready.
+func Ready(name string, c Check) {
+	register(check{name, c, false})
+}
+
+// Status is the result of a check.
+type Status struct {
+	Status string `json:"status"` // "ok" or "failing"
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the result of the checks.
+type Report struct {
+	Status string            `json:"status"` // "ok" if every check passed, "failing" otherwise
+	Checks map[string]Status `json:"checks"`
+}
+
+// OK reports whether every check passed.
+func (r Report) OK() bool {
+	return r.Status == "ok"
+}
+
+// Run runs the liveness checks, and the readiness checks too if ready is true, concurrently.
+// Checks still running after Timeout fail.
+func Run(ready bool) Report {
+	registryMu.Lock()
+	var checks []check
+	for _, c := range registry {
+		if c.live || ready {
+			checks = append(checks, c)
+		}
+	}
+	registryMu.Unlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	results := make([]chan error, len(checks))
+	for i, c := range checks {
+		results[i] = make(chan error, 1)
+		go func(c check, result chan error) {
+			defer func() {
+				if r := recover(); r != nil {
+					result <- fmt.Errorf("panic: %v", r)
+				}
+			}()
+			result <- c.check()
+		}(c, results[i])
+	}
+
+	report := Report{Status: "ok", Checks: map[string]Status{}}
+	for i, c := range checks {
+		var err error
+		select {
+		case err = <-results[i]:
+		case <-ctx.Done():
+			err = fmt.Errorf("timed out after %s", Timeout)
+		}
+		if err != nil {
+			report.Status = "failing"
+			report.Checks[c.name] = Status{Status: "failing", Error: err.Error()}
+		} else {
+			report.Checks[c.name] = Status{Status: "ok"}
+		}
+	}
+	return report
+}
+
+// Handler serves the report of the liveness checks, or of the readiness checks too if ready
+// is true, as JSON, with status 200 if they all passed and 503 otherwise.
+func Handler(ready bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Run(ready)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.OK() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	registry = map[string]check{}
+	Live("hub", func() error { return nil })
+	Ready("storage", func() error { return errors.New("connection refused") })
+
+	report := Run(false)
+	assert.True(t, report.OK())
+	assert.Equal(t, map[string]Status{"hub": {Status: "ok"}}, report.Checks)
+
+	report = Run(true)
+	assert.False(t, report.OK())
+	assert.Equal(t, map[string]Status{
+		"hub":     {Status: "ok"},
+		"storage": {Status: "failing", Error: "connection refused"},
+	}, report.Checks)
+
+	assert.Panics(t, func() { Ready("hub", nil) })
+}
+
+func TestTimeout(t *testing.T) {
+	registry = map[string]check{}
+	t.Cleanup(func() { Timeout = 2 * time.Second })
+	Timeout = 10 * time.Millisecond
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	Live("stuck", func() error { <-block; return nil })
+	Live("panics", func() error { panic("oops") })
+
+	report := Run(false)
+	assert.Equal(t, "failing", report.Checks["stuck"].Status)
+	assert.Contains(t, report.Checks["stuck"].Error, "timed out")
+	assert.Equal(t, Status{Status: "failing", Error: "panic: oops"}, report.Checks["panics"])
+}
+
+func TestHandler(t *testing.T) {
+	registry = map[string]check{}
+	Live("hub", func() error { return nil })
+	failing := errors.New("unreachable")
+	Ready("cache", func() error { return failing })
+
+	w := httptest.NewRecorder()
+	Handler(false).ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	Handler(true).ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, 503, w.Code)
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "failing", report.Status)
+	assert.Equal(t, "unreachable", report.Checks["cache"].Error)
+}
@@ -0,0 +1,84 @@
+package leaderboard
+
+import (
+	"cardgame/storage"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Hook is called with the final standings of a leaderboard when its season is archived,
+// for example to hand out rewards.
+type Hook func(season int, gameType string, standings []*storage.LeaderboardEntry)
+
+var hooks []Hook
+
+// OnArchive adds a hook to run when a season is archived. Hooks are added on startup,
+// before archiving starts.
+func OnArchive(h Hook) {
+	hooks = append(hooks, h)
+}
+
+// Archive archives the seasons of the schedule that have ended by now and aren't archived yet,
+// running the hooks for each of their leaderboards. The leaderboards themselves are kept.
+func Archive(s storage.Store, schedule Schedule, now time.Time) (int, error) {
+	archived := 0
+	for season := 0; season < schedule.Season(now); season++ {
+		_, err := s.SeasonArchive(season)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return archived, err
+		}
+
+		gameTypes, err := s.LeaderboardGameTypes(season)
+		if err != nil {
+			return archived, err
+		}
+		for _, gameType := range gameTypes {
+			standings, err := s.Leaderboard(storage.LeaderboardQuery{Season: season, GameType: gameType})
+			if err != nil {
+				return archived, err
+			}
+			for _, h := range hooks {
+				h(season, gameType, standings)
+			}
+		}
+
+		if err := s.SaveSeasonArchive(&storage.SeasonArchive{Season: season, Archived: now.UnixMilli()}); err != nil {
+			return archived, err
+		}
+		archived++
+	}
+	return archived, nil
+}
+
+// KeepArchived archives the seasons of Seasons in s as they end, checking every interval until stop is called.
+// On a cluster, only the storage.Leader runs it.
+func KeepArchived(s storage.Store, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			if storage.Leader() {
+				if n, err := Archive(s, Seasons, time.Now()); err != nil {
+					slog.Error("failed to archive seasons", "err", err)
+				} else if n > 0 {
+					slog.Info("archived seasons", "count", n)
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,92 @@
+package leaderboard
+
+import (
+	"cardgame/rating"
+	"cardgame/storage"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Decay applies inactivity decay to the ratings that haven't been played for a while, and
+// moves their accounts down the leaderboards of the current season by the rating they lost.
+// It returns the number of ratings that decayed.
+func Decay(s storage.Store, decay rating.Decay, now time.Time) (int, error) {
+	if decay.After <= 0 || decay.Period <= 0 {
+		return 0, nil
+	}
+	inactive, err := s.InactiveRatings(now.Add(-decay.After).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+
+	season := Seasons.Season(now)
+	decayed := 0
+	for _, pr := range inactive {
+		periods, end := decay.Periods(time.UnixMilli(pr.Updated), time.UnixMilli(pr.Decayed), now)
+		if periods == 0 {
+			continue
+		}
+		r := decay.Apply(rating.Rating{Rating: pr.Rating, Deviation: pr.Deviation, Volatility: pr.Volatility}, periods)
+		lost := pr.Rating - r.Rating
+		pr.Rating, pr.Deviation, pr.Volatility = r.Rating, r.Deviation, r.Volatility
+		pr.Decayed = end.UnixMilli()
+		if err := s.SaveRatings([]*storage.PlayerRating{pr}, nil); err != nil {
+			return decayed, err
+		}
+		decayed++
+
+		if lost == 0 {
+			continue
+		}
+		entries := []*storage.LeaderboardEntry{}
+		for _, gameType := range []string{pr.GameType, Global} {
+			e, err := s.LeaderboardEntry(season, gameType, pr.AccountId)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return decayed, err
+			}
+			if gameType == Global {
+				e.Score -= lost
+			} else {
+				e.Score = pr.Rating
+			}
+			entries = append(entries, e)
+		}
+		if err := s.SaveLeaderboardEntries(entries); err != nil {
+			return decayed, err
+		}
+	}
+	return decayed, nil
+}
+
+// KeepDecaying applies rating.Inactivity to the ratings in s, checking every interval until stop is called.
+// On a cluster, only the storage.Leader runs it.
+func KeepDecaying(s storage.Store, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			if storage.Leader() {
+				if n, err := Decay(s, rating.Inactivity, time.Now()); err != nil {
+					slog.Error("failed to decay ratings", "err", err)
+				} else if n > 0 {
+					slog.Info("decayed ratings", "count", n)
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
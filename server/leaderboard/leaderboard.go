@@ -0,0 +1,157 @@
+// Package leaderboard ranks accounts by their ranked games, per season.
+//
+// Every game type has a leaderboard of its own, scored by the players' ratings for it.
+// The global leaderboard covers every game type, and is scored by the rating points
+// won (or lost) over the season.
+package leaderboard
+
+import (
+	"cardgame/storage"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Global is the game type of the global leaderboard.
+const Global = ""
+
+// Schedule is the times seasons start, in order. Season n lasts from the nth boundary
+// until the next one; season 0 is everything before the first boundary, and the last
+// season doesn't end.
+type Schedule []time.Time
+
+// Seasons is the season schedule used by the server. It is set on startup from the configuration.
+var Seasons Schedule
+
+// ParseSchedule parses a comma separated list of season boundaries, each either a date like
+// "2024-06-01" (midnight UTC) or an RFC 3339 time.
+func ParseSchedule(s string) (Schedule, error) {
+	schedule := Schedule{}
+	if strings.TrimSpace(s) == "" {
+		return schedule, nil
+	}
+
+	for _, b := range strings.Split(s, ",") {
+		b = strings.TrimSpace(b)
+		t, err := time.Parse(time.RFC3339, b)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", b); err != nil {
+				return nil, fmt.Errorf("invalid season boundary %q", b)
+			}
+		}
+		if len(schedule) > 0 && !t.After(schedule[len(schedule)-1]) {
+			return nil, fmt.Errorf("season boundary %q is not after the one before it", b)
+		}
+		schedule = append(schedule, t)
+	}
+	return schedule, nil
+}
+
+// Season returns the season t is in.
+func (s Schedule) Season(t time.Time) int {
+	season := 0
+	for season < len(s) && !t.Before(s[season]) {
+		season++
+	}
+	return season
+}
+
+// Bounds returns when a season starts and ends. The start of season 0 and the end of
+// the last season are zero.
+func (s Schedule) Bounds(season int) (start, end time.Time) {
+	if season > 0 && season <= len(s) {
+		start = s[season-1]
+	}
+	if season < len(s) {
+		end = s[season]
+	}
+	return start, end
+}
+
+// Record adds the rating changes of a ranked match to the leaderboards of the season it ended in.
+func Record(s storage.Store, match *storage.Match, changes []*storage.RatingChange) error {
+	season := Seasons.Season(time.UnixMilli(match.Ended))
+	names := map[string]string{}
+	for _, p := range match.Players {
+		names[p.AccountId] = p.Name
+	}
+
+	entries := []*storage.LeaderboardEntry{}
+	for _, c := range changes {
+		e, err := entry(s, season, c.GameType, c.AccountId)
+		if err != nil {
+			return err
+		}
+		e.Score = c.After
+		global, err := entry(s, season, Global, c.AccountId)
+		if err != nil {
+			return err
+		}
+		global.Score += c.After - c.Before
+
+		for _, e := range []*storage.LeaderboardEntry{e, global} {
+			e.Name = names[c.AccountId]
+			e.Games++
+			e.Updated = match.Ended
+			entries = append(entries, e)
+		}
+	}
+	return s.SaveLeaderboardEntries(entries)
+}
+
+// entry loads an account's entry on a leaderboard, or starts a new one.
+func entry(s storage.Store, season int, gameType, accountId string) (*storage.LeaderboardEntry, error) {
+	e, err := s.LeaderboardEntry(season, gameType, accountId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &storage.LeaderboardEntry{Season: season, GameType: gameType, AccountId: accountId}, nil
+	}
+	return e, err
+}
+
+// Around returns the entries of a leaderboard within n places of an account, including its own.
+func Around(s storage.Store, season int, gameType, accountId string, n int) ([]*storage.LeaderboardEntry, error) {
+	e, err := s.LeaderboardEntry(season, gameType, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the rank is where the account's score starts, so without ties that is its position,
+	// and with them it is somewhere after
+	offset := e.Rank - 1 - n
+	if offset < 0 {
+		offset = 0
+	}
+	limit := 2*n + 1
+	window := []*storage.LeaderboardEntry{}
+	found := -1
+	for {
+		page, err := s.Leaderboard(storage.LeaderboardQuery{Season: season, GameType: gameType, Offset: offset, Limit: limit})
+		if err != nil {
+			return nil, err
+		}
+		offset += len(page)
+
+		for _, other := range page {
+			window = append(window, other)
+			if found >= 0 {
+				continue
+			}
+			if other.AccountId == accountId {
+				found = len(window) - 1
+			} else if len(window) > n {
+				window = window[1:]
+			}
+		}
+		if found >= 0 && len(window) > found+n || len(page) < limit {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, storage.ErrNotFound
+	}
+	if len(window) > found+n+1 {
+		window = window[:found+n+1]
+	}
+	return window, nil
+}
@@ -0,0 +1,176 @@
+package leaderboard
+
+import (
+	"cardgame/rating"
+	"cardgame/storage"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	s, err := ParseSchedule("2024-01-01, 2024-04-01T12:00:00Z")
+	assert.NoError(t, err)
+	assert.Len(t, s, 2)
+
+	assert.Equal(t, 0, s.Season(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 1, s.Season(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), "a season should start at its boundary")
+	assert.Equal(t, 2, s.Season(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	start, end := s.Bounds(1)
+	assert.Equal(t, s[0], start)
+	assert.Equal(t, s[1], end)
+	start, _ = s.Bounds(0)
+	assert.True(t, start.IsZero())
+	_, end = s.Bounds(2)
+	assert.True(t, end.IsZero())
+
+	_, err = ParseSchedule("2024-04-01,2024-01-01")
+	assert.Error(t, err, "boundaries should be in order")
+	_, err = ParseSchedule("next tuesday")
+	assert.Error(t, err)
+
+	s, err = ParseSchedule("")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, s.Season(time.Now()))
+}
+
+func TestRecord(t *testing.T) {
+	s := storage.NewMemory()
+	match := &storage.Match{
+		Id:      "m_1",
+		Players: []storage.MatchPlayer{{AccountId: "u_1", Name: "a"}, {AccountId: "u_2", Name: "b"}},
+		Ended:   1,
+	}
+	assert.NoError(t, Record(s, match, []*storage.RatingChange{
+		{AccountId: "u_1", GameType: "classic", Before: 1500, After: 1600},
+		{AccountId: "u_2", GameType: "classic", Before: 1500, After: 1400},
+	}))
+	match.Ended = 2
+	assert.NoError(t, Record(s, match, []*storage.RatingChange{
+		{AccountId: "u_1", GameType: "teams", Before: 1500, After: 1550},
+	}))
+
+	e, err := s.LeaderboardEntry(0, "classic", "u_1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1600.0, e.Score, "game type leaderboards should be scored by rating")
+	assert.Equal(t, "a", e.Name)
+
+	global, err := s.LeaderboardEntry(0, Global, "u_1")
+	assert.NoError(t, err)
+	assert.Equal(t, 150.0, global.Score, "the global leaderboard should be scored by rating won")
+	assert.Equal(t, 2, global.Games)
+	global, err = s.LeaderboardEntry(0, Global, "u_2")
+	assert.NoError(t, err)
+	assert.Equal(t, -100.0, global.Score)
+}
+
+func TestAround(t *testing.T) {
+	s := storage.NewMemory()
+	entries := []*storage.LeaderboardEntry{}
+	for i := 0; i < 10; i++ {
+		// u_3 to u_6 are tied
+		score := float64(100 - 10*i)
+		if i >= 3 && i <= 6 {
+			score = 50
+		}
+		entries = append(entries, &storage.LeaderboardEntry{AccountId: fmt.Sprintf("u_%d", i), Score: score, Updated: int64(i)})
+	}
+	s.SaveLeaderboardEntries(entries)
+
+	ids := func(entries []*storage.LeaderboardEntry) []string {
+		ids := []string{}
+		for _, e := range entries {
+			ids = append(ids, e.AccountId)
+		}
+		return ids
+	}
+
+	around, err := Around(s, 0, Global, "u_6", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u_4", "u_5", "u_6", "u_7", "u_8"}, ids(around))
+	assert.Equal(t, 4, around[2].Rank)
+
+	around, err = Around(s, 0, Global, "u_0", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u_0", "u_1", "u_2"}, ids(around))
+
+	around, err = Around(s, 0, Global, "u_9", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u_8", "u_9"}, ids(around))
+
+	_, err = Around(s, 0, Global, "u_missing", 1)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestArchive(t *testing.T) {
+	s := storage.NewMemory()
+	schedule := Schedule{time.UnixMilli(10), time.UnixMilli(20)}
+	s.SaveLeaderboardEntries([]*storage.LeaderboardEntry{
+		{Season: 1, GameType: "classic", AccountId: "u_1", Score: 1600},
+		{Season: 1, GameType: Global, AccountId: "u_1", Score: 100},
+	})
+
+	rewarded := map[string]int{}
+	old := hooks
+	t.Cleanup(func() { hooks = old })
+	OnArchive(func(season int, gameType string, standings []*storage.LeaderboardEntry) {
+		if season == 1 {
+			rewarded[gameType] = len(standings)
+		}
+	})
+
+	n, err := Archive(s, schedule, time.UnixMilli(15))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "only season 0 should have ended")
+	assert.Empty(t, rewarded)
+
+	n, err = Archive(s, schedule, time.UnixMilli(25))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, map[string]int{"classic": 1, Global: 1}, rewarded)
+
+	n, err = Archive(s, schedule, time.UnixMilli(30))
+	assert.NoError(t, err)
+	assert.Zero(t, n, "seasons should only be archived once")
+}
+
+func TestDecay(t *testing.T) {
+	s := storage.NewMemory()
+	defer func(s Schedule) { Seasons = s }(Seasons)
+	Seasons = Schedule{}
+	day := 24 * time.Hour
+	now := time.Unix(0, 0).Add(100 * day)
+	s.SaveRatings([]*storage.PlayerRating{
+		{AccountId: "u_1", GameType: "classic", Rating: 1600, Deviation: 50, Volatility: 0.06, Games: 10, Updated: now.Add(-30 * day).UnixMilli()},
+		{AccountId: "u_2", GameType: "classic", Rating: 1580, Deviation: 50, Volatility: 0.06, Games: 10, Updated: now.Add(-day).UnixMilli()},
+	}, nil)
+	s.SaveLeaderboardEntries([]*storage.LeaderboardEntry{
+		{GameType: "classic", AccountId: "u_1", Score: 1600},
+		{GameType: Global, AccountId: "u_1", Score: 100},
+		{GameType: "classic", AccountId: "u_2", Score: 1580},
+	})
+	decay := rating.Decay{After: 14 * day, Period: 7 * day, Points: 15}
+
+	n, err := Decay(s, decay, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "only inactive ratings should decay")
+	r, err := s.Rating("u_1", "classic")
+	assert.NoError(t, err)
+	assert.Equal(t, 1570.0, r.Rating)
+	assert.Equal(t, now.Add(-2*day).UnixMilli(), r.Decayed)
+
+	e, err := s.LeaderboardEntry(0, "classic", "u_1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1570.0, e.Score)
+	assert.Equal(t, 2, e.Rank, "inactive players should fall behind active ones")
+	e, err = s.LeaderboardEntry(0, Global, "u_1")
+	assert.NoError(t, err)
+	assert.Equal(t, 70.0, e.Score)
+
+	n, err = Decay(s, decay, now.Add(day))
+	assert.NoError(t, err)
+	assert.Zero(t, n, "ratings should decay once per period")
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"cardgame/client"
+	"cardgame/game"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const loadtestUsage = `usage: cardgame-server loadtest [flags] URL
+
+loadtest plays games on the server at URL (like http://localhost:8080) with simulated
+players speaking the real protocol. Every room is created through the API, its players
+join over websockets, and they draw cards on their turns until the room has drawn
+enough, then the owner ends the game. It reports how long draws took to come back and
+how many requests failed.
+
+flags:`
+
+// loadtestConfig is the size of a load test.
+type loadtestConfig struct {
+	url     *url.URL
+	rooms   int
+	players int           // per room
+	draws   int           // per player
+	timeout time.Duration // for a whole room to play its game
+}
+
+// loadtestResults are what the simulated players saw, collected from every room.
+type loadtestResults struct {
+	mu        sync.Mutex
+	latencies []time.Duration // from sending a draw to seeing it broadcast
+	errors    []string        // failed requests and error messages sent by the server
+}
+
+func (r *loadtestResults) draw(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies = append(r.latencies, d)
+}
+
+func (r *loadtestResults) fail(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// loadtest runs the "loadtest" command. It returns the exit code of the command, which is 1
+// if anything failed.
+func loadtest(args []string) int {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	c := loadtestConfig{}
+	flags.IntVar(&c.rooms, "rooms", 10, "rooms to play in at the same time")
+	flags.IntVar(&c.players, "players", 4, "players in every room")
+	flags.IntVar(&c.draws, "draws", 20, "cards every player draws before the game ends")
+	flags.DurationVar(&c.timeout, "timeout", 2*time.Minute, "how long a room has to finish its game")
+	usage := func() {
+		fmt.Fprintln(os.Stderr, loadtestUsage)
+		flags.SetOutput(os.Stderr)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		usage()
+		return 2
+	}
+	u, err := url.Parse(strings.TrimSuffix(flags.Arg(0), "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || c.rooms < 1 || c.players < 1 || c.draws < 1 {
+		usage()
+		return 2
+	}
+	c.url = u
+
+	deck, err := loadtestDeck(c.url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load decks:", err)
+		return 1
+	}
+
+	results := &loadtestResults{}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < c.rooms; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			playRoom(c, i, deck, results)
+		}(i)
+	}
+	wg.Wait()
+
+	report(os.Stdout, c, results, time.Since(start))
+	if len(results.errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadtestDeck returns the id of a deck of the server, to play with.
+func loadtestDeck(u *url.URL) (string, error) {
+	var body struct {
+		Decks map[string]json.RawMessage `json:"decks"`
+	}
+	if err := apiRequest("GET", u.String()+"/api/decks", &body); err != nil {
+		return "", err
+	}
+	ids := []string{}
+	for id := range body.Decks {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return "", errors.New("the server has no decks")
+	}
+	sort.Strings(ids)
+	return ids[0], nil
+}
+
+// apiRequest makes a request to the API and decodes its JSON response into v.
+func apiRequest(method, url string, v any) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("%s %s: %s", method, url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// loadtestPlayer is a simulated player, connected to a room.
+type loadtestPlayer struct {
+	name   string
+	client *client.Client
+	sent   time.Time // when the draw waiting to be seen was sent, zero if there is none
+}
+
+// playRoom creates a room, seats simulated players in it and plays a game, adding what
+// they saw to the results.
+func playRoom(c loadtestConfig, room int, deck string, results *loadtestResults) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	roomId, err := client.CreateRoom(ctx, c.url.String(), "")
+	if err != nil {
+		results.fail("room %d: failed to create room: %v", room, err)
+		return
+	}
+
+	players := []*loadtestPlayer{}
+	defer func() {
+		for _, p := range players {
+			p.client.Close()
+		}
+	}()
+	for i := 0; i < c.players; i++ {
+		p := &loadtestPlayer{name: fmt.Sprintf("load %d-%d", room, i)}
+		p.client, err = client.Join(ctx, c.url.String(), roomId, client.Options{Name: p.name})
+		if err != nil {
+			results.fail("room %d: %s failed to join: %v", room, p.name, err)
+			return
+		}
+		players = append(players, p)
+	}
+
+	owner := players[0]
+	if err := owner.client.Submit(game.ClientChangeDetails{AddDecks: []string{deck}}); err != nil {
+		results.fail("room %d: failed to add a deck: %v", room, err)
+		return
+	}
+	if err := owner.client.Submit(game.ClientStart{}); err != nil {
+		results.fail("room %d: failed to start: %v", room, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range players {
+		wg.Add(1)
+		go func(p *loadtestPlayer) {
+			defer wg.Done()
+			if err := p.play(ctx, c.players*c.draws, p == owner, results); err != nil {
+				results.fail("room %d: %s: %v", room, p.name, err)
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// play draws on the player's turns until the room has drawn total cards, and returns once
+// the game is over. Every player counts the draws broadcast to the room, so they agree on
+// when to stop, and the owner ends the game then.
+func (p *loadtestPlayer) play(ctx context.Context, total int, owner bool, results *loadtestResults) error {
+	id := p.client.PlayerId()
+	drawn := 0
+	draw := func() error {
+		if drawn >= total {
+			return nil
+		}
+		p.sent = time.Now()
+		return p.client.Submit(game.ClientDraw{})
+	}
+	drew := func(playerId string) error {
+		drawn++
+		if playerId == id && !p.sent.IsZero() {
+			results.draw(time.Since(p.sent))
+			p.sent = time.Time{}
+		}
+		if owner && drawn == total {
+			return p.client.Submit(game.ClientEnd{})
+		}
+		return nil
+	}
+
+	for {
+		var e client.Event
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-p.client.Events():
+			if !ok {
+				return fmt.Errorf("disconnected: %v", p.client.Err())
+			}
+			e = event
+		}
+
+		var err error
+		switch m := e.Message.(type) {
+		case *game.ServerStart:
+			var room struct {
+				Players []struct {
+					Id string `json:"id"`
+				} `json:"players"`
+			}
+			json.Unmarshal(e.Room, &room)
+			if m.CurrentTurn < len(room.Players) && room.Players[m.CurrentTurn].Id == id {
+				err = draw()
+			}
+		case *game.ServerTurn:
+			if m.PlayerId == id {
+				err = draw()
+			}
+		case *game.ServerDraw:
+			err = drew(m.PlayerId)
+		case *game.ServerWildCard:
+			mine := m.PlayerId == id && !p.sent.IsZero()
+			err = drew(m.PlayerId)
+			if err == nil && mine {
+				// wild cards don't end the turn
+				err = draw()
+			}
+		case *game.ServerError:
+			results.fail("%s: server error: %s", p.name, m.Message)
+			p.sent = time.Time{}
+		case *game.ServerEnd:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// report writes a summary of the results.
+func report(w io.Writer, c loadtestConfig, r *loadtestResults, elapsed time.Duration) {
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	percentile := func(p float64) time.Duration {
+		if len(r.latencies) == 0 {
+			return 0
+		}
+		return r.latencies[int(p*float64(len(r.latencies)-1))]
+	}
+
+	requests := len(r.latencies) + len(r.errors)
+	rate := 0.0
+	if requests > 0 {
+		rate = 100 * float64(len(r.errors)) / float64(requests)
+	}
+	fmt.Fprintf(w, "rooms: %d, players: %d, draws: %d in %s (%.1f/s)\n",
+		c.rooms, c.rooms*c.players, len(r.latencies), elapsed.Round(time.Millisecond),
+		float64(len(r.latencies))/elapsed.Seconds())
+	fmt.Fprintf(w, "draw latency: p50 %s, p90 %s, p99 %s, max %s\n",
+		percentile(0.5), percentile(0.9), percentile(0.99), percentile(1))
+	fmt.Fprintf(w, "errors: %d (%.2f%%)\n", len(r.errors), rate)
+	for i, e := range r.errors {
+		if i == 10 {
+			fmt.Fprintf(w, "  and %d more\n", len(r.errors)-i)
+			break
+		}
+		fmt.Fprintln(w, " ", e)
+	}
+}
@@ -0,0 +1,114 @@
+package locale
+
+// english are the messages built into the server, by id.
+var english = map[string]string{
+	"account_deleted":         "Account was deleted",
+	"account_not_found":       "Account not found",
+	"account_seated":          "Your account already has a seat in this game",
+	"already_in_room":         "You are already in a room",
+	"already_voted":           "player has already voted",
+	"bio_not_allowed":         "Bio is not allowed",
+	"bio_too_long":            "Bio must be at most {max} characters",
+	"cannot_vote":             "player cannot vote",
+	"card":                    "{category} ({type})",
+	"card_circle":             "circle",
+	"card_dots":               "dots",
+	"card_hash":               "hash",
+	"card_lines":              "lines",
+	"card_plus":               "plus",
+	"card_square":             "square",
+	"card_star":               "star",
+	"card_waves":              "waves",
+	"cards_incompatible":      "cards are not compatible",
+	"cards_missing":           "both players need a card to compare",
+	"challenge_attempted":     "You have already attempted today's challenge",
+	"challenge_no_spectators": "Daily challenges can't be watched",
+	"challenge_start_failed":  "failed to start the daily challenge",
+	"challenge_starts_itself": "daily challenges start by themselves",
+	"challenge_wrong_account": "Only the account the challenge is for can take a seat",
+	"channel_not_found":       "Channel not found",
+	"chat_blocked":            "message blocked by chat filter",
+	"chat_rate_limited":       "you are sending messages too quickly",
+	"chat_too_long":           "message is longer than {max} characters",
+	"demo_locked":             "demo rooms can't be changed",
+	"demo_no_spectators":      "Demos can't be watched",
+	"draw_pile_empty":         "draw pile is empty",
+	"email_reset_body":        "Hi {name},\n\nOpen this link to choose a new password:\n{link}\n\nIt works for {hours} hours. If you didn't ask for it, ignore this email and your password stays the same.",
+	"email_reset_subject":     "Reset your password",
+	"email_verify_body":       "Hi {name},\n\nOpen this link to verify your email:\n{link}\n\nIt works for {hours} hours. If you didn't sign up with this address, ignore this email.",
+	"email_verify_subject":    "Verify your email",
+	"event_afk":               "A bot is playing for {player}",
+	"event_back":              "{player} is back",
+	"event_draw":              "{player} drew {card}",
+	"event_end":               "The game is over, {winners} won",
+	"event_join":              "{player} joined the room",
+	"event_leave":             "{player} left the room",
+	"event_pause":             "The game is paused until {player} reconnects",
+	"event_reconnect":         "{player} reconnected",
+	"event_resume":            "The game continues",
+	"event_send":              "{sender} sent {card} to {recipient}",
+	"event_spectate":          "{player} is watching",
+	"event_start":             "The game started, {player} goes first",
+	"event_turn":              "It's {player}'s turn",
+	"event_turn_timeout":      "{player} ran out of time and drew a card",
+	"event_wild_card":         "{player} drew a wild card: {card}",
+	"game_not_paused":         "game is not paused",
+	"game_not_playing":        "game is not in playing phase",
+	"game_paused":             "game is paused",
+	"game_started":            "game has already started",
+	"incorrect_password":      "Incorrect password",
+	"invalid_resume_mode":     "invalid resume mode",
+	"invite_failed":           "Failed to send invite",
+	"invite_offline":          "{name} is not online",
+	"invite_refused":          "{name} is not accepting invites from you",
+	"invite_self":             "You cannot invite yourself",
+	"invite_sign_in":          "Sign in to invite players",
+	"join_blocked":            "You cannot join this room",
+	"kick_self":               "player cannot kick themselves",
+	"mute_self":               "You cannot mute yourself",
+	"name_length":             "Name must be between 1 and {max} characters",
+	"name_not_allowed":        "Name is not allowed",
+	"no_players":              "no players to start the game with",
+	"no_seat_to_reclaim":      "no seat to reclaim",
+	"no_vote":                 "no vote in progress",
+	"not_in_channel":          "You are not in this channel",
+	"not_in_room":             "You are not in a room",
+	"not_owner":               "player is not owner",
+	"not_your_turn":           "player is not current turn",
+	"player_in_other_room":    "player is in another room",
+	"player_in_room":          "player is already in room",
+	"player_not_found":        "player not found",
+	"prediction_closed":       "Predictions are closed for this game",
+	"prediction_made":         "You have already made a prediction for this game",
+	"prediction_spectator":    "Only spectators can make predictions",
+	"reason_too_long":         "Reason must be at most {max} characters",
+	"ranked_end":              "Ranked games end when the draw pile runs out",
+	"replay_export_title":     "Replay of {players}",
+	"replay_highlights":       "Key moments only",
+	"replay_load_failed":      "Failed to load replay",
+	"replay_next":             "Next",
+	"replay_not_found":        "Replay not found",
+	"replay_pause":            "Pause",
+	"replay_play":             "Play",
+	"replay_previous":         "Previous",
+	"replay_score":            "{score} cards",
+	"report_duplicate":        "You have already reported this player",
+	"report_failed":           "failed to send the report",
+	"report_self":             "You can't report yourself",
+	"room_full":               "Room is full",
+	"room_not_found":          "Room not found",
+	"room_resuming":           "room is waiting to resume a suspended game",
+	"send_self":               "player cannot send cards to themselves",
+	"sign_in_room":            "Leave the room before signing in",
+	"spectator_send":          "spectators cannot send cards",
+	"suspend_failed":          "failed to suspend the game",
+	"suspend_sign_in":         "every player has to be signed in to suspend the game",
+	"suspended_wrong_account": "Only the players of the suspended game can take a seat",
+	"target_not_found":        "target player not found",
+	"unknown_report_reason":   "unknown report reason",
+	"vote_in_progress":        "a vote is already in progress",
+	"vote_kick_cooldown":      "player started a vote-kick too recently",
+	"vote_kick_players":       "Kick votes need at least {min} players",
+	"vote_kick_self":          "player cannot vote to kick themselves",
+	"wild_card":               "{first} and {second} match",
+}
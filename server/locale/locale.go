@@ -0,0 +1,139 @@
+// Package locale translates the text the server generates, like the errors shown to players,
+// into the language of each connection. Messages are identified by an id which is the same in
+// every language, and are sent with their id and parameters so clients can translate them too.
+//
+// English is built into the server. Other languages are loaded from YAML files mapping ids to
+// text, with parameters written like {name}. Messages missing from a language are in English.
+package locale
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Default is the language of the messages built into the server.
+const Default = "en"
+
+// Params are the values filled into a message.
+type Params map[string]string
+
+// catalogs are the messages of every language, by id. They are loaded on startup, and only
+// read afterwards.
+var catalogs = map[string]map[string]string{Default: english}
+
+// Languages returns the languages there are messages in, sorted.
+func Languages() []string {
+	languages := []string{}
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Translate returns a message in a language, with its parameters filled in. Ids without a
+// message are returned as they are.
+func Translate(lang, id string, params Params) string {
+	text, ok := catalogs[lang][id]
+	if !ok {
+		if text, ok = english[id]; !ok {
+			return id
+		}
+	}
+	if len(params) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// placeholder matches the parameters of a message.
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// Load reads the <language>.yaml files of a directory, like es.yaml. Every message has to be
+// one of the English messages, with the same parameters.
+func Load(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		lang := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		messages := map[string]string{}
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for id, text := range messages {
+			en, ok := english[id]
+			if !ok {
+				return fmt.Errorf("%s: unknown message %q", file, id)
+			}
+			if !sameParams(en, text) {
+				return fmt.Errorf("%s: message %q should have the parameters of %q", file, id, en)
+			}
+		}
+		catalogs[lang] = messages
+	}
+	return nil
+}
+
+// sameParams reports whether two messages have the same parameters.
+func sameParams(a, b string) bool {
+	pa, pb := placeholder.FindAllString(a, -1), placeholder.FindAllString(b, -1)
+	sort.Strings(pa)
+	sort.Strings(pb)
+	return strings.Join(pa, ",") == strings.Join(pb, ",")
+}
+
+// Match picks the language to speak to a client from its Accept-Language header, like
+// "fr-CH, fr;q=0.9, en;q=0.8": the one it prefers most among those there are messages in.
+func Match(acceptLanguage string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := strings.TrimSpace(part), 1.0
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(tag[i+1:]), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+			tag = strings.TrimSpace(tag[:i])
+		}
+		// regional variants get the messages of their language
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := catalogs[lang]; ok && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Error is an error shown to users, in the language of each of them.
+type Error struct {
+	Id     string
+	Params Params
+}
+
+// NewError returns an error with the message of an id.
+func NewError(id string, params Params) *Error {
+	return &Error{Id: id, Params: params}
+}
+
+// Error returns the message in English.
+func (e *Error) Error() string {
+	return Translate(Default, e.Id, e.Params)
+}
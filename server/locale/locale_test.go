@@ -0,0 +1,57 @@
+package locale
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withTestCatalogs loads the locales of the repository, and forgets them after the test.
+func withTestCatalogs(t *testing.T) {
+	old := catalogs
+	t.Cleanup(func() { catalogs = old })
+	catalogs = map[string]map[string]string{Default: english}
+	assert.NoError(t, Load("../data/locales"))
+}
+
+func TestTranslate(t *testing.T) {
+	withTestCatalogs(t)
+	assert.Equal(t, []string{"en", "es"}, Languages())
+
+	assert.Equal(t, "Room is full", Translate("en", "room_full", nil))
+	assert.Equal(t, "La sala está llena", Translate("es", "room_full", nil))
+	assert.Equal(t, "Bob no está conectado", Translate("es", "invite_offline", Params{"name": "Bob"}))
+	assert.Equal(t, "Replay not found", Translate("es", "replay_not_found", nil), "missing messages should be in English")
+	assert.Equal(t, "Room is full", Translate("xx", "room_full", nil), "unknown languages should be in English")
+	assert.Equal(t, "no_such_message", Translate("es", "no_such_message", nil))
+
+	err := NewError("chat_too_long", Params{"max": "200"})
+	assert.Equal(t, "message is longer than 200 characters", err.Error())
+}
+
+func TestMatch(t *testing.T) {
+	withTestCatalogs(t)
+	assert.Equal(t, "en", Match(""))
+	assert.Equal(t, "es", Match("es"))
+	assert.Equal(t, "es", Match("es-MX,es;q=0.9"))
+	assert.Equal(t, "en", Match("fr-CH, fr;q=0.9, en;q=0.8"))
+	assert.Equal(t, "es", Match("fr-CH, fr;q=0.9, en;q=0.5, es;q=0.8"))
+	assert.Equal(t, "en", Match("de"))
+}
+
+func TestLoadInvalid(t *testing.T) {
+	withTestCatalogs(t)
+	for name, data := range map[string]string{
+		"unknown id":    "no_such_message: hola",
+		"missing param": "invite_offline: no está conectado",
+		"renamed param": "chat_too_long: más de {limit} caracteres",
+		"not a mapping": "- room_full",
+	} {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "fr.yaml"), []byte(data), 0o644))
+		assert.Error(t, Load(dir), name)
+	}
+	assert.Equal(t, []string{"en", "es"}, Languages(), "invalid locales shouldn't be loaded")
+}
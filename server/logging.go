@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// logLevel is the level of the lines logged, which can change while the server runs.
+var logLevel = new(slog.LevelVar)
+
+// configureLogging makes slog, and the log package through it, write lines at or above a
+// level ("debug", "info", "warn" or "error", default "info") in a format ("text" or "json",
+// default "text").
+func configureLogging(w io.Writer, level, format string) error {
+	if err := setLogLevel(level); err != nil {
+		return err
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	var h slog.Handler
+	switch format {
+	case "", "text":
+		h = slog.NewTextHandler(w, options)
+	case "json":
+		h = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q", format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// setLogLevel changes the level of the lines logged, "info" if level is empty.
+func setLogLevel(level string) error {
+	var l slog.Level
+	if level != "" {
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %q", level)
+		}
+	}
+	logLevel.Set(l)
+	return nil
+}
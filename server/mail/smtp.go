@@ -0,0 +1,88 @@
+package mail
+
+import (
+	"crypto/tls"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"net/url"
+	"time"
+)
+
+// smtpMailer sends emails through an SMTP server.
+type smtpMailer struct {
+	addr   string // host:port
+	host   string
+	tls    bool // connect over TLS rather than upgrading with STARTTLS
+	auth   smtp.Auth
+	from   string // as written in the From header, maybe with a name
+	sender string // only the address, which the server is told the email is from
+}
+
+func openSMTP(u *url.URL, from string) (Mailer, error) {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, err
+	}
+	m := &smtpMailer{host: u.Hostname(), tls: u.Scheme == "smtps", from: from, sender: sender.Address}
+	port := u.Port()
+	if port == "" {
+		port = "587"
+		if m.tls {
+			port = "465"
+		}
+	}
+	m.addr = net.JoinHostPort(m.host, port)
+	if u.User != nil {
+		password, _ := u.User.Password()
+		m.auth = smtp.PlainAuth("", u.User.Username(), password, m.host)
+	}
+	return m, nil
+}
+
+func (m *smtpMailer) Send(msg Message) error {
+	data, err := compose(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	if !m.tls {
+		return smtp.SendMail(m.addr, m.auth, m.sender, []string{to.Address}, data)
+	}
+
+	conn, err := tls.Dial("tcp", m.addr, &tls.Config{ServerName: m.host})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if m.auth != nil {
+		if err := c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.sender); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -1,13 +1,33 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
 	"cardgame/build"
+	"cardgame/config"
+	"cardgame/cosmetic"
 	"cardgame/deck"
 	"cardgame/game"
+	"cardgame/health"
+	"cardgame/leaderboard"
+	"cardgame/locale"
+	"cardgame/mail"
+	"cardgame/metrics"
+	"cardgame/progression"
+	"cardgame/rating"
+	"cardgame/storage"
+	"cardgame/util"
 	"cardgame/web"
 )
 
@@ -18,12 +38,211 @@ func main() {
 		godotenv.Load()
 
 		room := game.HubMain.NewRoom("")
-		delete(game.HubMain.Rooms, room.Id)
+		game.HubMain.RemoveRoom(room.Id)
 		room.Id = "r_debug"
-		game.HubMain.Rooms[room.Id] = room
+		game.HubMain.AddRoom(room)
+	}
+
+	// subcommands have flags of their own, the server's flags are its settings
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = nil
+	}
+	cfg, err := config.Load(settings, args)
+	if errors.Is(err, config.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "usage: cardgame-server [flags]\n\nflags:")
+		config.Usage(os.Stderr, settings)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalln("[error] invalid configuration:", err)
+	}
+
+	if err := configureLogging(os.Stderr, cfg.Get("LOG_LEVEL"), cfg.Get("LOG_FORMAT")); err != nil {
+		log.Fatalln("[error]", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(cfg, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "backup" || os.Args[1] == "restore") {
+		os.Exit(backup(cfg, os.Args[1], os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		os.Exit(loadtest(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "play" {
+		os.Exit(play(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(verify(os.Args[2:]))
 	}
 
 	deck.InitDecks("./data/decks")
+	if err := progression.LoadQuests("./data/quests.yaml"); err != nil {
+		log.Fatalln("[error] failed to load quests:", err)
+	}
+	if err := cosmetic.LoadCatalog("./data/cosmetics.yaml"); err != nil {
+		log.Fatalln("[error] failed to load cosmetics:", err)
+	}
+	if err := locale.Load("./data/locales"); err != nil {
+		log.Fatalln("[error] failed to load locales:", err)
+	}
+	progression.OnReward(cosmetic.GrantReward)
+
+	if err := applySettings(cfg); err != nil {
+		log.Fatalln("[error]", err)
+	}
+	game.ChatFilter = chatFilter
+	if err := applyGameDefaults(cfg); err != nil {
+		log.Fatalln("[error]", err)
+	}
+	if err := applyRoomTTLs(cfg); err != nil {
+		log.Fatalln("[error]", err)
+	}
+	reloadOnHangup(cfg)
+	demo, err := applyDemo(cfg)
+	if err != nil {
+		log.Fatalln("[error]", err)
+	}
+
+	switch m := cfg.Get("STORAGE_MIGRATE"); m {
+	case "", "auto":
+	case "manual":
+		// the server refuses to start until "cardgame-server migrate up" has been run
+		storage.AutoMigrate = false
+	default:
+		log.Fatalln("[error] invalid STORAGE_MIGRATE:", m)
+	}
+	driver, dsn, cacheURL := cfg.Get("STORAGE_DRIVER"), cfg.Get("STORAGE_DSN"), cfg.Get("CACHE_URL")
+	if demo {
+		// the demo never touches the database of the game, even if configured with it
+		if driver != "" || cacheURL != "" {
+			slog.Warn("the demo keeps everything in memory, ignoring STORAGE_DRIVER and CACHE_URL")
+		}
+		driver, dsn, cacheURL = "", "", ""
+		slog.Info("serving the public demo")
+	}
+	store, err := storage.Open(driver, dsn)
+	if err != nil {
+		log.Fatalln("[error] failed to open storage:", err)
+	}
+	defer store.Close()
+	cache, err := storage.OpenCache(cacheURL)
+	if err != nil {
+		log.Fatalln("[error] failed to open cache:", err)
+	}
+	if cache != nil {
+		store = storage.Cached(store, cache, storage.DefaultCacheTTL)
+	}
+	storage.Default = store
+
+	if l, ok := cache.(storage.Leaser); ok {
+		// servers sharing the cache elect one of them to run the jobs below
+		lease := 30 * time.Second
+		if secs := cfg.Get("LEADER_LEASE_SECONDS"); secs != "" {
+			n, err := strconv.Atoi(secs)
+			if err != nil || n < 3 {
+				log.Fatalln("[error] invalid LEADER_LEASE_SECONDS:", secs)
+			}
+			lease = time.Duration(n) * time.Second
+		}
+		host, _ := os.Hostname()
+		elector, stop := storage.Elect(l, host+"-"+util.Token()[:8], lease)
+		defer stop()
+		storage.Leader = elector.Leading
+	}
+
+	if days := cfg.Get("REPLAY_RETENTION_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			log.Fatalln("[error] invalid REPLAY_RETENTION_DAYS:", days)
+		}
+		if n > 0 {
+			defer storage.KeepReplaysFor(store, time.Duration(n)*24*time.Hour, time.Hour)()
+		}
+	}
+
+	if days := cfg.Get("ACCOUNT_RECOVERY_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			log.Fatalln("[error] invalid ACCOUNT_RECOVERY_DAYS:", days)
+		}
+		web.RecoveryWindow = time.Duration(n) * 24 * time.Hour
+	}
+	if web.RecoveryWindow > 0 {
+		defer storage.KeepDeletedAccountsFor(store, web.RecoveryWindow, time.Hour)()
+	}
+
+	for _, id := range strings.Split(cfg.Get("ADMIN_ACCOUNTS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			web.Admins[id] = true
+		}
+	}
+
+	if mailURL := cfg.Get("MAIL_URL"); mailURL != "" {
+		mailer, err := mail.Open(mailURL, cfg.Get("MAIL_FROM"))
+		if err != nil {
+			log.Fatalln("[error] invalid MAIL_URL or MAIL_FROM:", err)
+		}
+		if cfg.Get("PUBLIC_URL") == "" {
+			log.Fatalln("[error] PUBLIC_URL is needed to link to the game in emails")
+		}
+		web.Mailer = mailer
+		web.PublicURL = strings.TrimSuffix(cfg.Get("PUBLIC_URL"), "/")
+	}
+
+	if issuer := cfg.Get("TOTP_ISSUER"); issuer != "" {
+		web.TotpIssuer = issuer
+	}
+
+	if debug := cfg.Get("DEBUG_ENDPOINTS"); debug != "" {
+		enabled, err := strconv.ParseBool(debug)
+		if err != nil {
+			log.Fatalln("[error] invalid DEBUG_ENDPOINTS:", debug)
+		}
+		web.DebugEndpoints = enabled
+	}
+
+	seasons, err := leaderboard.ParseSchedule(cfg.Get("SEASON_BOUNDARIES"))
+	if err != nil {
+		log.Fatalln("[error] invalid SEASON_BOUNDARIES:", err)
+	}
+	leaderboard.Seasons = seasons
+	defer leaderboard.KeepArchived(store, time.Hour)()
+
+	if games := cfg.Get("RATING_PLACEMENT_GAMES"); games != "" {
+		n, err := strconv.Atoi(games)
+		if err != nil || n < 0 {
+			log.Fatalln("[error] invalid RATING_PLACEMENT_GAMES:", games)
+		}
+		rating.PlacementGames = n
+	}
+	if days := cfg.Get("RATING_DECAY_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			log.Fatalln("[error] invalid RATING_DECAY_DAYS:", days)
+		}
+		rating.Inactivity.After = time.Duration(n) * 24 * time.Hour
+	}
+	if points := cfg.Get("RATING_DECAY_POINTS"); points != "" {
+		n, err := strconv.ParseFloat(points, 64)
+		if err != nil || n < 0 {
+			log.Fatalln("[error] invalid RATING_DECAY_POINTS:", points)
+		}
+		rating.Inactivity.Points = n
+	}
+	if rating.Inactivity.After > 0 {
+		defer leaderboard.KeepDecaying(store, time.Hour)()
+	}
+	collectInterval := time.Minute
+	defer game.HubMain.KeepCollecting(collectInterval)()
+
+	stopAnalytics, err := applyAnalytics(cfg)
+	if err != nil {
+		log.Fatalln("[error]", err)
+	}
+	defer stopAnalytics()
 
 	gin.SetMode(gin.ReleaseMode)
 
@@ -32,14 +251,31 @@ func main() {
 
 	c := cors.DefaultConfig()
 	c.AllowAllOrigins = true
-	c.AllowHeaders = []string{"Origin", "Content-Type", "X-Password"}
+	c.AllowHeaders = []string{"Origin", "Content-Type", "X-Password", "Authorization"}
 	r.Use(cors.New(c))
 
 	web.InitApi(r.Group("/api"))
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	health.Live("hub", func() error { return game.HubMain.Ping(health.Timeout) })
+	health.Live("room_gc", func() error { return game.HubMain.CheckCollecting(collectInterval) })
+	health.Ready("storage", store.Ping)
+	if cache != nil {
+		health.Ready("cache", func() error {
+			_, _, err := cache.Get("health")
+			return err
+		})
+	}
+	r.GET("/healthz", gin.WrapH(health.Handler(false)))
+	r.GET("/readyz", gin.WrapH(health.Handler(true)))
 
 	r.Use(func(c *gin.Context) {
 		c.AbortWithStatusJSON(404, gin.H{"error": "not found"})
 	})
 
-	r.Run()
+	port := cfg.Get("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	r.Run(":" + port)
 }
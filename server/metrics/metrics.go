@@ -0,0 +1,292 @@
+// Package metrics keeps counters, gauges and histograms about the running server, and
+// serves them in the Prometheus text format for operators to scrape.
+//
+// Metrics have at most one label, which is enough for splitting them by game type or
+// message type.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// metric is a registered metric, able to write its samples.
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]metric{}
+)
+
+// register adds a metric to the ones served. Metrics are registered on startup, and names
+// have to be unique.
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[m.name()]; ok {
+		panic("metrics: " + m.name() + " is registered twice")
+	}
+	registry[m.name()] = m
+}
+
+// Write writes every registered metric in the Prometheus text format, ordered by name.
+func Write(w io.Writer) {
+	registryMu.Lock()
+	metrics := make([]metric, 0, len(registry))
+	for _, m := range registry {
+		metrics = append(metrics, m)
+	}
+	registryMu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the registered metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		Write(w)
+	})
+}
+
+// desc is the name, help text and label shared by every kind of metric.
+type desc struct {
+	Name  string
+	Help  string
+	Label string // name of the label splitting the metric, or empty for none
+}
+
+func (d desc) name() string { return d.Name }
+
+func (d desc) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.Name, d.Help, d.Name, kind)
+}
+
+// labels formats the label of a sample, like {type="join"}, or nothing if the metric has no label.
+func (d desc) labels(value string) string {
+	if d.Label == "" {
+		return ""
+	}
+	return "{" + d.Label + "=" + strconv.Quote(value) + "}"
+}
+
+// values is a set of samples by label value, safe for concurrent use.
+type values struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (v *values) add(label string, n float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.values == nil {
+		v.values = map[string]float64{}
+	}
+	v.values[label] += n
+}
+
+func (v *values) set(label string, n float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.values == nil {
+		v.values = map[string]float64{}
+	}
+	v.values[label] = n
+}
+
+func (v *values) get(label string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[label]
+}
+
+func (v *values) snapshot() map[string]float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c := make(map[string]float64, len(v.values))
+	for k, n := range v.values {
+		c[k] = n
+	}
+	return c
+}
+
+// writeSamples writes samples sorted by label value.
+func writeSamples(w io.Writer, d desc, samples map[string]float64) {
+	keys := make([]string, 0, len(samples))
+	for k := range samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", d.Name, d.labels(k), formatValue(samples[k]))
+	}
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter is a count that only goes up, like the number of messages handled.
+type Counter struct {
+	desc
+	values values
+}
+
+// NewCounter registers a counter. label names what the counter is split by, or is empty.
+func NewCounter(name, help, label string) *Counter {
+	c := &Counter{desc: desc{name, help, label}}
+	register(c)
+	return c
+}
+
+// Inc adds one to the count for a label value. Counters without a label use "".
+func (c *Counter) Inc(label string) {
+	c.values.add(label, 1)
+}
+
+// Value returns the count for a label value.
+func (c *Counter) Value(label string) float64 {
+	return c.values.get(label)
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.header(w, "counter")
+	samples := c.values.snapshot()
+	if c.Label == "" && len(samples) == 0 {
+		samples[""] = 0
+	}
+	writeSamples(w, c.desc, samples)
+}
+
+// Gauge is a value that goes up and down, like the number of open connections.
+type Gauge struct {
+	desc
+	values values
+}
+
+// NewGauge registers a gauge. label names what the gauge is split by, or is empty.
+func NewGauge(name, help, label string) *Gauge {
+	g := &Gauge{desc: desc{name, help, label}}
+	register(g)
+	return g
+}
+
+// Add adds n, which can be negative, to the value for a label value.
+func (g *Gauge) Add(label string, n float64) {
+	g.values.add(label, n)
+}
+
+// Set sets the value for a label value.
+func (g *Gauge) Set(label string, n float64) {
+	g.values.set(label, n)
+}
+
+// Value returns the value for a label value.
+func (g *Gauge) Value(label string) float64 {
+	return g.values.get(label)
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.header(w, "gauge")
+	samples := g.values.snapshot()
+	if g.Label == "" && len(samples) == 0 {
+		samples[""] = 0
+	}
+	writeSamples(w, g.desc, samples)
+}
+
+// GaugeFunc is a gauge whose values are computed when the metrics are scraped, for values
+// that are cheaper to count than to keep track of, like the number of rooms.
+type GaugeFunc struct {
+	desc
+	collect func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge computed by collect, which returns the value for every
+// label value. Gauges without a label return their value under "".
+func NewGaugeFunc(name, help, label string, collect func() map[string]float64) *GaugeFunc {
+	g := &GaugeFunc{desc{name, help, label}, collect}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.header(w, "gauge")
+	writeSamples(w, g.desc, g.collect())
+}
+
+// Histogram counts observed values, like message sizes, in cumulative buckets.
+type Histogram struct {
+	desc
+	buckets []float64 // upper bounds, in increasing order
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative; the last one is +Inf
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with buckets of the given upper bounds, in increasing order.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{desc: desc{Name: name, Help: help}, buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+	register(h)
+	return h
+}
+
+// Observe adds a value to the histogram.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += v
+}
+
+// Count returns the number of values observed.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	counts := append([]uint64{}, h.counts...)
+	count, sum := h.count, h.sum
+	h.mu.Unlock()
+
+	h.header(w, "histogram")
+	cumulative := uint64(0)
+	for i, le := range append(append([]float64{}, h.buckets...), math.Inf(1)) {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", h.Name, formatValue(le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_sum %s\n", h.Name, formatValue(sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.Name, count)
+}
+
+// ExponentialBuckets returns n bucket upper bounds, starting at start and each factor times
+// the one before.
+func ExponentialBuckets(start, factor float64, n int) []float64 {
+	buckets := make([]float64, n)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
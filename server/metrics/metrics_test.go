@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	registry = map[string]metric{}
+	messages := NewCounter("test_messages_total", "Messages.", "type")
+	connections := NewGauge("test_connections", "Connections.", "")
+	NewGaugeFunc("test_rooms", "Rooms.", "game_type", func() map[string]float64 {
+		return map[string]float64{"classic": 2}
+	})
+	sizes := NewHistogram("test_bytes", "Sizes.", []float64{10, 100})
+
+	messages.Inc("join")
+	messages.Inc("join")
+	messages.Inc("chat")
+	connections.Add("", 3)
+	connections.Add("", -1)
+	sizes.Observe(5)
+	sizes.Observe(10)
+	sizes.Observe(500)
+
+	var b bytes.Buffer
+	Write(&b)
+	assert.Equal(t, `# HELP test_bytes Sizes.
+# TYPE test_bytes histogram
+test_bytes_bucket{le="10"} 2
+test_bytes_bucket{le="100"} 2
+test_bytes_bucket{le="+Inf"} 3
+test_bytes_sum 515
+test_bytes_count 3
+# HELP test_connections Connections.
+# TYPE test_connections gauge
+test_connections 2
+# HELP test_messages_total Messages.
+# TYPE test_messages_total counter
+test_messages_total{type="chat"} 1
+test_messages_total{type="join"} 2
+# HELP test_rooms Rooms.
+# TYPE test_rooms gauge
+test_rooms{game_type="classic"} 2
+`, b.String())
+
+	assert.Panics(t, func() { NewCounter("test_rooms", "Again.", "") }, "names should be unique")
+}
+
+func TestHandler(t *testing.T) {
+	registry = map[string]metric{}
+	NewCounter("test_total", "Things.", "")
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), "test_total 0\n", "unlabeled metrics should be written before anything is counted")
+}
+
+func TestExponentialBuckets(t *testing.T) {
+	assert.Equal(t, []float64{1, 4, 16}, ExponentialBuckets(1, 4, 3))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"cardgame/config"
+	"cardgame/storage"
+)
+
+const migrateUsage = `usage: cardgame-server migrate <command>
+
+commands:
+  status      show the schema version of the database and the one this server expects
+  up          apply every migration the database is missing
+  to VERSION  migrate up or down to a version, 0 for an empty schema
+
+The database is picked with STORAGE_DRIVER and STORAGE_DSN, like when starting the server.`
+
+// migrate runs the "migrate" command, which manages the schema of a SQL store by hand.
+// It returns the exit code of the command.
+func migrate(cfg *config.Config, args []string) int {
+	driver, dsn := cfg.Get("STORAGE_DRIVER"), cfg.Get("STORAGE_DSN")
+	dialect, err := storage.DriverDialect(driver)
+	if len(args) == 0 || err != nil {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		if err != nil && len(args) > 0 {
+			fmt.Fprintln(os.Stderr, "\nerror:", err)
+		}
+		return 2
+	}
+
+	target := storage.LatestSchemaVersion()
+	switch args[0] {
+	case "status", "up":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+	case "to":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if target, err = strconv.Atoi(args[1]); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid version:", args[1])
+			return 2
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	s, err := storage.OpenSQLUnchecked(driver, dsn, dialect)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open storage:", err)
+		return 1
+	}
+	defer s.Close()
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to read schema version:", err)
+		return 1
+	}
+	if args[0] == "status" {
+		fmt.Printf("database schema version: %d\nserver schema version:   %d\n", version, storage.LatestSchemaVersion())
+		return 0
+	}
+
+	if err := s.Migrate(target); err != nil {
+		fmt.Fprintln(os.Stderr, "migration failed:", err)
+		return 1
+	}
+	fmt.Printf("migrated from version %d to %d\n", version, target)
+	return 0
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+
+	"cardgame/card"
+	"cardgame/game"
+	"cardgame/storage"
+)
+
+const playUsage = `usage: cardgame-server play [flags] URL [ROOM]
+
+play joins a room of the server at URL (like http://localhost:8080) from the terminal,
+speaking the same protocol as the web client. Without a room id, it creates a room.
+Type commands to play, and anything else to chat:
+
+  /decks        list the decks of the server
+  /deck ID      add a deck to the room
+  /start        start the game
+  /draw         draw a card
+  /send N       send your top card to player N
+  /end          end the game
+  /table        show the players and their hands
+  /raw JSON     send a message as is, like {"type":"resume"}
+  /quit         leave the room
+
+flags:`
+
+// playMessage is the part of the server messages the terminal client shows.
+type playMessage struct {
+	Type        string                `json:"type"`
+	Message     string                `json:"message"` // of errors and chat
+	Id          string                `json:"id"`      // of leaving and reconnecting players
+	PlayerId    string                `json:"playerId"`
+	Player      any                   `json:"player"` // of joins, or the sender of chat
+	Private     bool                  `json:"private"`
+	SenderId    string                `json:"senderId"`
+	RecipientId string                `json:"recipientId"`
+	Card        any                   `json:"card"`
+	Reason      string                `json:"reason"`
+	Results     []storage.MatchPlayer `json:"results"`
+	Room        *playState            `json:"room"`
+}
+
+// playState is the state of the room sent along every message.
+type playState struct {
+	Id             string         `json:"id"`
+	Name           string         `json:"name"`
+	OwnerId        string         `json:"ownerId"`
+	Players        []*playPlayer  `json:"players"`
+	Decks          []*playDeck    `json:"decks"`
+	CurrentTurn    int            `json:"currentTurn"`
+	GamePhase      game.GamePhase `json:"gamePhase"`
+	ActiveWildCard *card.WildCard `json:"activeWildCard"`
+	DrawPileSize   int            `json:"drawPileSize"`
+}
+
+type playPlayer struct {
+	Id    string       `json:"id"`
+	Name  string       `json:"name"`
+	Score int          `json:"score"`
+	Cards []*card.Card `json:"cards"` // top is at the end
+}
+
+type playDeck struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// playClient is a terminal client connected to a room.
+type playClient struct {
+	out  io.Writer
+	conn *websocket.Conn
+	name string
+
+	mu   sync.Mutex
+	room *playState // latest state of the room
+
+	quit atomic.Bool // set once the player quits, and the connection is closed on purpose
+}
+
+// play runs the "play" command. It returns the exit code of the command.
+func play(args []string) int {
+	flags := flag.NewFlagSet("play", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	name := flags.String("name", "terminal", "name to play under")
+	password := flags.String("password", "", "password of a private room")
+	usage := func() {
+		fmt.Fprintln(os.Stderr, playUsage)
+		flags.SetOutput(os.Stderr)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 || flags.NArg() > 2 {
+		usage()
+		return 2
+	}
+	u, err := url.Parse(strings.TrimSuffix(flags.Arg(0), "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		usage()
+		return 2
+	}
+
+	roomId := flags.Arg(1)
+	if roomId == "" {
+		var created struct {
+			Room struct {
+				Id string `json:"id"`
+			} `json:"room"`
+		}
+		if err := apiRequest("POST", u.String()+"/api/room", &created); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to create a room:", err)
+			return 1
+		}
+		roomId = created.Room.Id
+		fmt.Println("created room", roomId)
+	}
+
+	ws := *u
+	ws.Scheme = strings.Replace(ws.Scheme, "http", "ws", 1)
+	ws.Path += "/api/ws/" + roomId
+	conn, _, err := websocket.DefaultDialer.Dial(ws.String(), nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect:", err)
+		return 1
+	}
+	defer conn.Close()
+
+	c := &playClient{out: os.Stdout, conn: conn, name: *name}
+	if err := c.send(map[string]any{"type": "join", "roomId": roomId, "name": *name, "password": *password}); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to join:", err)
+		return 1
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		c.read()
+	}()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return 0
+		case line, ok := <-lines:
+			if !ok || !c.command(u, strings.TrimSpace(line)) {
+				c.quit.Store(true)
+				c.send(map[string]any{"type": "leave"})
+				return 0
+			}
+		}
+	}
+}
+
+func (c *playClient) send(message map[string]any) error {
+	return c.conn.WriteJSON(message)
+}
+
+// read shows the messages of the server until the connection is closed.
+func (c *playClient) read() {
+	for {
+		var m playMessage
+		if err := c.conn.ReadJSON(&m); err != nil {
+			if !c.quit.Load() {
+				fmt.Fprintln(c.out, "! disconnected:", err)
+			}
+			return
+		}
+		c.mu.Lock()
+		before := c.room
+		if m.Room != nil {
+			c.room = m.Room
+		}
+		c.show(&m, before)
+		c.mu.Unlock()
+	}
+}
+
+// command runs a line typed by the player. It returns false once the player quits.
+func (c *playClient) command(u *url.URL, line string) bool {
+	if line == "" {
+		return true
+	}
+	if !strings.HasPrefix(line, "/") {
+		c.send(map[string]any{"type": "chat", "message": line})
+		return true
+	}
+
+	command, arg, _ := strings.Cut(line[1:], " ")
+	arg = strings.TrimSpace(arg)
+	var err error
+	switch command {
+	case "quit", "leave":
+		return false
+	case "start", "draw", "end":
+		err = c.send(map[string]any{"type": command})
+	case "deck":
+		err = c.send(map[string]any{"type": "change_details", "addDecks": []string{arg}})
+	case "decks":
+		var body struct {
+			Decks map[string]*playDeck `json:"decks"`
+		}
+		if err = apiRequest("GET", u.String()+"/api/decks", &body); err == nil {
+			ids := []string{}
+			for id := range body.Decks {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			for _, id := range ids {
+				fmt.Fprintf(c.out, "  %s  %s\n", id, body.Decks[id].Name)
+			}
+		}
+	case "send":
+		c.mu.Lock()
+		n, convErr := strconv.Atoi(arg)
+		if convErr != nil || c.room == nil || n < 1 || n > len(c.room.Players) {
+			c.mu.Unlock()
+			fmt.Fprintln(c.out, "! usage: /send N, with N the number of a player in /table")
+			return true
+		}
+		id := c.room.Players[n-1].Id
+		c.mu.Unlock()
+		err = c.send(map[string]any{"type": "send", "recipientId": id})
+	case "table":
+		c.mu.Lock()
+		c.showTable()
+		c.mu.Unlock()
+	case "raw":
+		err = c.conn.WriteMessage(websocket.TextMessage, []byte(arg))
+	default:
+		fmt.Fprintln(c.out, "! unknown command, see the usage with -h")
+	}
+	if err != nil {
+		fmt.Fprintln(c.out, "!", err)
+	}
+	return true
+}
+
+// playerName returns the name of a player in the first of the room states they are in, or
+// the id if they are in none. Passing the state from before a message names players who left.
+func playerName(id string, rooms ...*playState) string {
+	for _, r := range rooms {
+		if r == nil {
+			continue
+		}
+		for _, p := range r.Players {
+			if p.Id == id {
+				return p.Name
+			}
+		}
+	}
+	return id
+}
+
+// show describes a message of the server. Messages the terminal client doesn't know are
+// shown by type, to help while developing new ones.
+func (c *playClient) show(m *playMessage, before *playState) {
+	name := func(id string) string { return playerName(id, c.room, before) }
+	switch m.Type {
+	case "ack":
+		fmt.Fprintf(c.out, "* joined as %s, type /table to see the players\n", c.name)
+	case "join":
+		if p, ok := m.Player.(map[string]any); ok {
+			fmt.Fprintf(c.out, "* %v joined\n", p["name"])
+		}
+	case "leave":
+		fmt.Fprintf(c.out, "* %s left\n", name(m.Id))
+	case "change_details":
+		decks := []string{}
+		for _, d := range c.room.Decks {
+			decks = append(decks, d.Name)
+		}
+		fmt.Fprintf(c.out, "* room details changed, decks: %s\n", strings.Join(decks, ", "))
+	case "start":
+		fmt.Fprintln(c.out, "* the game started")
+		c.showTable()
+	case "turn":
+		fmt.Fprintf(c.out, "* %s's turn\n", name(m.PlayerId))
+	case "draw":
+		fmt.Fprintf(c.out, "* %s drew %s\n", name(m.PlayerId), showCard(m.Card))
+	case "wild_card":
+		fmt.Fprintf(c.out, "* %s drew the wild card %s\n", name(m.PlayerId), showCard(m.Card))
+	case "send":
+		fmt.Fprintf(c.out, "* %s sent %s to %s\n", name(m.SenderId), showCard(m.Card), name(m.RecipientId))
+	case "chat":
+		sender, _ := m.Player.(string)
+		if m.Private {
+			fmt.Fprintf(c.out, "<%s (private)> %s\n", name(sender), m.Message)
+		} else {
+			fmt.Fprintf(c.out, "<%s> %s\n", name(sender), m.Message)
+		}
+	case "end":
+		fmt.Fprintln(c.out, "* the game ended")
+		for _, r := range m.Results {
+			fmt.Fprintf(c.out, "  %d. %s, %d points\n", r.Rank, r.Name, r.Score)
+		}
+	case "kick":
+		fmt.Fprintln(c.out, "* you were kicked from the room")
+	case "room_closed":
+		fmt.Fprintln(c.out, "* the room was closed:", m.Reason)
+	case "error":
+		fmt.Fprintln(c.out, "!", m.Message)
+	case "resync", "catch_up_start", "catch_up_end", "experience", "quest":
+	default:
+		fmt.Fprintf(c.out, "* %s\n", m.Type)
+	}
+}
+
+// showTable shows the players of the room, their score and their hand, top card last.
+func (c *playClient) showTable() {
+	if c.room == nil {
+		return
+	}
+	for i, p := range c.room.Players {
+		turn := " "
+		if c.room.GamePhase == game.GamePhasePlaying && i == c.room.CurrentTurn {
+			turn = ">"
+		}
+		cards := []string{}
+		for _, cd := range p.Cards {
+			cards = append(cards, cd.String())
+		}
+		fmt.Fprintf(c.out, "%s %d. %s (%d): %s\n", turn, i+1, p.Name, p.Score, strings.Join(cards, " "))
+	}
+	if w := c.room.ActiveWildCard; w != nil {
+		fmt.Fprintln(c.out, "  wild card:", w)
+	}
+	fmt.Fprintln(c.out, "  draw pile:", c.room.DrawPileSize)
+}
+
+// showCard formats a card sent by the server, as type|category or typeA|typeB for wild cards.
+func showCard(v any) string {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return "a card"
+	}
+	typeName := func(v any) string {
+		if n, ok := v.(float64); ok {
+			return card.CardType(n).String()
+		}
+		return "?"
+	}
+	if types, ok := m["types"].([]any); ok && len(types) == 2 {
+		return typeName(types[0]) + "|" + typeName(types[1])
+	}
+	return fmt.Sprintf("%s|%v", typeName(m["type"]), m["category"])
+}
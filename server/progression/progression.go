@@ -0,0 +1,139 @@
+// Package progression levels accounts up with the experience they earn by playing, and
+// keeps track of their quests.
+//
+// Every finished game is worth experience, and more when it is won. Quests are defined in
+// data and rotate every day or week; completing one grants its reward.
+package progression
+
+import (
+	"cardgame/storage"
+	"errors"
+	"time"
+)
+
+const (
+	GameXP = 20 // experience for finishing a game
+	WinXP  = 30 // experience for winning it, on top of GameXP
+)
+
+// levelXP is the experience needed to go from level 1 to level 2.
+// Every level after that needs levelXP more than the one before.
+const levelXP = 100
+
+// Level returns the level reached with an amount of experience. Accounts start at level 1.
+func Level(xp int) int {
+	level := 1
+	for xp >= LevelXP(level+1) {
+		level++
+	}
+	return level
+}
+
+// LevelXP returns the experience needed to reach a level.
+func LevelXP(level int) int {
+	return levelXP * level * (level - 1) / 2
+}
+
+// Game is how a player did in a finished game.
+type Game struct {
+	AccountId string
+	GameType  string
+	Won       bool
+	Score     int
+	Ended     time.Time
+}
+
+// Result is what a game earned an account.
+type Result struct {
+	XP        int     // experience earned, including the rewards of completed quests
+	Total     int     // experience after the game
+	Level     int     // level after the game
+	LevelUp   bool    // true if the game took the account to a new level
+	Completed []Quest // quests the game completed
+}
+
+// Record adds a finished game to the experience and quests of its account, and grants the
+// rewards of the quests it completed.
+func Record(s storage.Store, g Game) (*Result, error) {
+	xp, err := s.Experience(g.AccountId)
+	if errors.Is(err, storage.ErrNotFound) {
+		xp = &storage.Experience{AccountId: g.AccountId}
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := &Result{XP: GameXP}
+	if g.Won {
+		result.XP += WinXP
+	}
+
+	now := g.Ended.UnixMilli()
+	progress := map[string]map[string]*storage.QuestProgress{} // period -> quest id -> progress
+	changed := []*storage.QuestProgress{}
+	for _, a := range Active(g.Ended) {
+		q := a.Quest
+		if q.GameType != "" && q.GameType != g.GameType {
+			continue
+		}
+		n := q.progress(g)
+		if n <= 0 {
+			continue
+		}
+
+		if _, ok := progress[a.Key]; !ok {
+			saved, err := s.Quests(g.AccountId, a.Key)
+			if err != nil {
+				return nil, err
+			}
+			progress[a.Key] = map[string]*storage.QuestProgress{}
+			for _, p := range saved {
+				progress[a.Key][p.QuestId] = p
+			}
+		}
+		p, ok := progress[a.Key][q.Id]
+		if !ok {
+			p = &storage.QuestProgress{AccountId: g.AccountId, QuestId: q.Id, Period: a.Key}
+		}
+		if p.Completed != 0 {
+			continue
+		}
+
+		p.Progress += n
+		if p.Progress >= q.Goal {
+			p.Progress = q.Goal
+			p.Completed = now
+			result.XP += q.Reward.XP
+			result.Completed = append(result.Completed, q)
+		}
+		p.Updated = now
+		changed = append(changed, p)
+	}
+
+	before := Level(xp.XP)
+	xp.XP += result.XP
+	xp.Updated = now
+	if err := s.SaveProgression(xp, changed); err != nil {
+		return nil, err
+	}
+	result.Total = xp.XP
+	result.Level = Level(xp.XP)
+	result.LevelUp = result.Level > before
+
+	for _, q := range result.Completed {
+		for _, h := range rewardHooks {
+			h(g.AccountId, q)
+		}
+	}
+	return result, nil
+}
+
+// RewardHook is called when an account completes a quest, to grant the parts of its reward
+// kept outside of this package, like cosmetics.
+type RewardHook func(accountId string, q Quest)
+
+var rewardHooks []RewardHook
+
+// OnReward adds a hook to run when a quest is completed. Hooks are added on startup.
+func OnReward(h RewardHook) {
+	rewardHooks = append(rewardHooks, h)
+}
@@ -0,0 +1,100 @@
+package progression
+
+import (
+	"cardgame/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withTestQuests(t *testing.T, file QuestFile) {
+	t.Helper()
+	mu.RLock()
+	old := QuestFile{Rotation: rotation, Quests: quests}
+	mu.RUnlock()
+	assert.NoError(t, SetQuests(file))
+	t.Cleanup(func() { SetQuests(old) })
+}
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, 1, Level(0))
+	assert.Equal(t, 1, Level(99))
+	assert.Equal(t, 2, Level(100))
+	assert.Equal(t, 3, Level(300))
+	assert.Equal(t, 600, LevelXP(4))
+}
+
+func TestRecord(t *testing.T) {
+	withTestQuests(t, QuestFile{Quests: []Quest{
+		{Id: "play_2", Period: PeriodDaily, Counts: CountGames, Goal: 2, Reward: Reward{XP: 10, Cosmetic: "hat"}},
+		{Id: "win_classic", Period: PeriodWeekly, GameType: "classic", Counts: CountWins, Goal: 1, Reward: Reward{XP: 5}},
+		{Id: "score_10", Period: PeriodWeekly, Counts: CountScore, Goal: 10, Reward: Reward{XP: 5}},
+	}})
+	rewarded := []string{}
+	rewardHooks = []RewardHook{func(accountId string, q Quest) { rewarded = append(rewarded, q.Id) }}
+	t.Cleanup(func() { rewardHooks = nil })
+
+	s := storage.NewMemory()
+	monday := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	result, err := Record(s, Game{AccountId: "u_1", GameType: "teams", Won: true, Score: 4, Ended: monday})
+	assert.NoError(t, err)
+	assert.Equal(t, GameXP+WinXP, result.XP)
+	assert.Empty(t, result.Completed, "quests of other game types should not count")
+
+	result, err = Record(s, Game{AccountId: "u_1", GameType: "classic", Score: 6, Ended: monday})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Completed, 2) {
+		assert.Equal(t, "play_2", result.Completed[0].Id)
+		assert.Equal(t, "score_10", result.Completed[1].Id)
+	}
+	assert.Equal(t, GameXP+10+5, result.XP)
+	assert.Equal(t, 2*GameXP+WinXP+15, result.Total)
+	assert.Equal(t, []string{"play_2", "score_10"}, rewarded, "hooks should grant the rest of the rewards")
+
+	// the next day brings new daily quests, but the week goes on
+	result, err = Record(s, Game{AccountId: "u_1", GameType: "classic", Won: true, Ended: monday.AddDate(0, 0, 1)})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Completed, 1) {
+		assert.Equal(t, "win_classic", result.Completed[0].Id)
+	}
+	assert.True(t, result.LevelUp)
+	assert.Equal(t, 2, result.Level)
+
+	daily, err := s.Quests("u_1", "2024-06-04")
+	assert.NoError(t, err)
+	if assert.Len(t, daily, 1) {
+		assert.Equal(t, 1, daily[0].Progress)
+	}
+}
+
+func TestActive(t *testing.T) {
+	withTestQuests(t, QuestFile{
+		Rotation: map[Period]int{PeriodDaily: 1},
+		Quests: []Quest{
+			{Id: "a", Period: PeriodDaily, Counts: CountGames, Goal: 1},
+			{Id: "b", Period: PeriodDaily, Counts: CountGames, Goal: 1},
+			{Id: "c", Period: PeriodWeekly, Counts: CountGames, Goal: 1},
+		},
+	})
+
+	sunday := time.Date(2024, 6, 9, 23, 0, 0, 0, time.UTC)
+	active := Active(sunday)
+	if assert.Len(t, active, 2, "rotations should limit the quests of their period") {
+		assert.Equal(t, "2024-06-09", active[0].Key)
+		assert.Equal(t, sunday.Add(time.Hour), active[0].Ends)
+		assert.Equal(t, "2024-W23", active[1].Key)
+		assert.Equal(t, sunday.Add(time.Hour), active[1].Ends, "weeks should end on Sunday night")
+	}
+	assert.Equal(t, active, Active(sunday.Add(-time.Hour)), "everyone should get the same quests")
+
+	assert.Error(t, SetQuests(QuestFile{Quests: []Quest{{Id: "x", Period: "monthly", Counts: CountGames, Goal: 1}}}))
+	assert.Error(t, SetQuests(QuestFile{Quests: []Quest{{Id: "x", Period: PeriodDaily, Counts: "draws", Goal: 1}}}))
+	assert.Error(t, SetQuests(QuestFile{Quests: []Quest{{Id: "x", Period: PeriodDaily, Counts: CountGames}}}))
+}
+
+func TestLoadQuests(t *testing.T) {
+	withTestQuests(t, QuestFile{})
+	assert.NoError(t, LoadQuests("../data/quests.yaml"))
+	assert.NotEmpty(t, Active(time.Now()))
+}
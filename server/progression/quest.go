@@ -0,0 +1,162 @@
+package progression
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Period is how long a quest is active for before it rotates out.
+type Period string
+
+const (
+	PeriodDaily  Period = "daily"
+	PeriodWeekly Period = "weekly"
+)
+
+// What a quest counts towards its goal.
+const (
+	CountGames = "games" // finished games
+	CountWins  = "wins"  // won games
+	CountScore = "score" // points scored over all games
+)
+
+// Quest describes a quest and how it is completed.
+type Quest struct {
+	Id          string `yaml:"id" json:"id"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Period      Period `yaml:"period" json:"period"`
+	GameType    string `yaml:"game_type" json:"gameType"` // only games of this type count, or of all of them if empty
+	Counts      string `yaml:"counts" json:"counts"`
+	Goal        int    `yaml:"goal" json:"goal"`
+	Reward      Reward `yaml:"reward" json:"reward"`
+}
+
+// Reward is what completing a quest grants.
+type Reward struct {
+	XP       int    `yaml:"xp" json:"xp"`
+	Cosmetic string `yaml:"cosmetic" json:"cosmetic,omitempty"` // item granted by the reward hooks, if any
+}
+
+// progress returns how much a game brings the player closer to completing the quest.
+func (q Quest) progress(g Game) int {
+	switch q.Counts {
+	case CountGames:
+		return 1
+	case CountWins:
+		if g.Won {
+			return 1
+		}
+	case CountScore:
+		return g.Score
+	}
+	return 0
+}
+
+// QuestFile is the yaml representation of the quests.
+type QuestFile struct {
+	// Rotation is how many quests of each period are active at once. Every quest of a
+	// period without a limit is always active.
+	Rotation map[Period]int `yaml:"rotation"`
+	Quests   []Quest        `yaml:"quests"`
+}
+
+var (
+	mu       sync.RWMutex
+	rotation = map[Period]int{}
+	quests   []Quest
+)
+
+// LoadQuests loads the quests from a yaml file, replacing the ones loaded before.
+func LoadQuests(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file QuestFile
+	if err := yaml.Unmarshal(contents, &file); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return SetQuests(file)
+}
+
+// SetQuests replaces the quests, after checking every one of them is complete.
+func SetQuests(file QuestFile) error {
+	ids := map[string]bool{}
+	for _, q := range file.Quests {
+		if q.Id == "" || ids[q.Id] {
+			return fmt.Errorf("quest %q needs an id of its own", q.Id)
+		}
+		ids[q.Id] = true
+		if q.Period != PeriodDaily && q.Period != PeriodWeekly {
+			return fmt.Errorf("quest %q has an invalid period %q", q.Id, q.Period)
+		}
+		if q.Counts != CountGames && q.Counts != CountWins && q.Counts != CountScore {
+			return fmt.Errorf("quest %q counts an invalid %q", q.Id, q.Counts)
+		}
+		if q.Goal < 1 {
+			return fmt.Errorf("quest %q needs a goal", q.Id)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	rotation = file.Rotation
+	quests = file.Quests
+	return nil
+}
+
+// ActiveQuest is a quest in one of the periods it is active.
+type ActiveQuest struct {
+	Quest
+	Key  string    `json:"key"` // of the period, like "2024-06-01" or "2024-W23"
+	Ends time.Time `json:"ends"`
+}
+
+// Active returns the quests active at t. The quests of a period are picked from the ones
+// defined for it, the same way for everyone.
+func Active(t time.Time) []ActiveQuest {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	active := []ActiveQuest{}
+	for _, period := range []Period{PeriodDaily, PeriodWeekly} {
+		key, ends := periodOf(period, t)
+		pool := []Quest{}
+		for _, q := range quests {
+			if q.Period == period {
+				pool = append(pool, q)
+			}
+		}
+		if n := rotation[period]; n > 0 && n < len(pool) {
+			h := fnv.New64a()
+			h.Write([]byte(key))
+			rng := rand.New(rand.NewSource(int64(h.Sum64())))
+			rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+			pool = pool[:n]
+		}
+		for _, q := range pool {
+			active = append(active, ActiveQuest{Quest: q, Key: key, Ends: ends})
+		}
+	}
+	return active
+}
+
+// periodOf returns the key of the period t is in, and when it ends. Periods follow UTC,
+// weeks start on Monday.
+func periodOf(period Period, t time.Time) (string, time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == PeriodDaily {
+		return day.Format("2006-01-02"), day.AddDate(0, 0, 1)
+	}
+	year, week := t.ISOWeek()
+	weekday := (int(day.Weekday()) + 6) % 7 // days since Monday
+	return fmt.Sprintf("%d-W%02d", year, week), day.AddDate(0, 0, 7-weekday)
+}
@@ -0,0 +1,57 @@
+package rating
+
+import (
+	"math"
+	"time"
+)
+
+// PlacementGames is the number of rated games a player's rating is provisional for.
+// It is set on startup from the configuration.
+var PlacementGames = 5
+
+// Provisional reports whether a rating is still provisional after a number of rated games.
+func Provisional(games int) bool {
+	return games < PlacementGames
+}
+
+// Decay is how the ratings of inactive players decay, so the top of the ladder is held by
+// players who still play.
+type Decay struct {
+	After  time.Duration // inactivity before a rating starts to decay, or 0 to never decay
+	Period time.Duration // how often a decaying rating loses Points
+	Points float64       // rating lost every period
+}
+
+// Inactivity is the decay of the ratings of inactive players. It is set on startup from the configuration.
+var Inactivity = Decay{Period: 7 * 24 * time.Hour, Points: 15}
+
+// Periods returns the number of decay periods that are over at now, for a rating last played
+// at played and last decayed at decayed (0 if it never did), and when the last of them ended.
+func (d Decay) Periods(played, decayed, now time.Time) (int, time.Time) {
+	if d.After <= 0 || d.Period <= 0 {
+		return 0, decayed
+	}
+	start := played.Add(d.After)
+	if decayed.After(start) {
+		start = decayed
+	}
+	if !now.After(start) {
+		return 0, decayed
+	}
+	n := int(now.Sub(start) / d.Period)
+	return n, start.Add(time.Duration(n) * d.Period)
+}
+
+// Apply returns a rating after decaying for a number of periods. It loses Points every period,
+// but never drops below DefaultRating, and its deviation grows as if it sat out that many
+// rating periods.
+func (d Decay) Apply(r Rating, periods int) Rating {
+	for i := 0; i < periods; i++ {
+		r = Update(r, nil)
+	}
+	r.Deviation = math.Min(r.Deviation, DefaultDeviation)
+	if r.Rating > DefaultRating {
+		r.Rating = math.Max(DefaultRating, r.Rating-d.Points*float64(periods))
+	}
+	return r
+}
@@ -0,0 +1,149 @@
+// Package rating implements the Glicko-2 rating system, extended to games with more than two players
+// by treating a finished game as a set of head-to-head results between every pair of players.
+//
+// See http://www.glicko.net/glicko/glicko2.pdf for the algorithm.
+package rating
+
+import "math"
+
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	// tau limits how much the volatility can change in one game.
+	tau = 0.5
+	// scale converts between the Glicko and Glicko-2 scales.
+	scale = 173.7178
+	// epsilon is the convergence tolerance of the volatility iteration.
+	epsilon = 0.000001
+)
+
+// Rating is a player's skill estimate.
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"` // uncertainty of the rating
+	Volatility float64 `json:"volatility"`
+}
+
+// Default returns the rating of a player who has not played yet.
+func Default() Rating {
+	return Rating{DefaultRating, DefaultDeviation, DefaultVolatility}
+}
+
+// Result is a game against one opponent.
+type Result struct {
+	Opponent Rating
+	Score    float64 // 1 for a win, 0.5 for a draw and 0 for a loss
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muJ, phiJ float64) float64 {
+	return 1 / (1 + math.Exp(-g(phiJ)*(mu-muJ)))
+}
+
+// Update returns a player's rating after a rating period with the given results.
+func Update(r Rating, results []Result) Rating {
+	mu := (r.Rating - DefaultRating) / scale
+	phi := r.Deviation / scale
+
+	if len(results) == 0 {
+		// only the uncertainty grows when a player doesn't play
+		phi = math.Sqrt(phi*phi + r.Volatility*r.Volatility)
+		return Rating{r.Rating, phi * scale, r.Volatility}
+	}
+
+	vInv, sum := 0.0, 0.0
+	for _, res := range results {
+		muJ := (res.Opponent.Rating - DefaultRating) / scale
+		phiJ := res.Opponent.Deviation / scale
+		e := expected(mu, muJ, phiJ)
+		vInv += g(phiJ) * g(phiJ) * e * (1 - e)
+		sum += g(phiJ) * (res.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	sigma := volatility(phi, r.Volatility, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu = mu + phi*phi*sum
+
+	return Rating{mu*scale + DefaultRating, phi * scale, sigma}
+}
+
+// volatility finds the new volatility with the Illinois algorithm (step 5 of the paper).
+func volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
+
+// UpdateRanked returns the new ratings of the players of a game, given their ratings
+// and finishing ranks (1 is first, equal ranks are ties). Every player is scored
+// against every other player: a win against those ranked lower, a loss against those
+// ranked higher and a draw against those with the same rank.
+func UpdateRanked(ratings []Rating, ranks []int) []Rating {
+	return UpdatePlacement(ratings, ranks, make([]bool, len(ratings)))
+}
+
+// UpdatePlacement is UpdateRanked for games with players still playing their placement games,
+// whose ratings are provisional. They are scored against everyone, but settled players are
+// only scored against each other, so new players can't move the ladder. Settled players with
+// no settled opponents keep their rating.
+func UpdatePlacement(ratings []Rating, ranks []int, provisional []bool) []Rating {
+	updated := make([]Rating, len(ratings))
+	for i := range ratings {
+		results := []Result{}
+		for j := range ratings {
+			if i == j || (provisional[j] && !provisional[i]) {
+				continue
+			}
+			score := 0.5
+			if ranks[i] < ranks[j] {
+				score = 1
+			} else if ranks[i] > ranks[j] {
+				score = 0
+			}
+			results = append(results, Result{ratings[j], score})
+		}
+		if len(results) == 0 {
+			updated[i] = ratings[i]
+			continue
+		}
+		updated[i] = Update(ratings[i], results)
+	}
+	return updated
+}
@@ -0,0 +1,83 @@
+package rating
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdatePaperExample(t *testing.T) {
+	// the worked example from the Glicko-2 paper
+	r := Update(Rating{1500, 200, 0.06}, []Result{
+		{Rating{1400, 30, 0.06}, 1},
+		{Rating{1550, 100, 0.06}, 0},
+		{Rating{1700, 300, 0.06}, 0},
+	})
+
+	assert.InDelta(t, 1464.06, r.Rating, 0.01)
+	assert.InDelta(t, 151.52, r.Deviation, 0.01)
+	assert.InDelta(t, 0.05999, r.Volatility, 0.00001)
+}
+
+func TestUpdateNoGames(t *testing.T) {
+	r := Update(Rating{1500, 200, 0.06}, nil)
+	assert.Equal(t, 1500.0, r.Rating)
+	assert.Greater(t, r.Deviation, 200.0, "uncertainty should grow")
+}
+
+func TestUpdateRanked(t *testing.T) {
+	ratings := []Rating{Default(), Default(), Default(), Default()}
+	updated := UpdateRanked(ratings, []int{1, 2, 2, 4})
+
+	assert.Greater(t, updated[0].Rating, DefaultRating, "winner should gain")
+	assert.InDelta(t, updated[1].Rating, updated[2].Rating, 0.001, "tied players should move the same")
+	assert.Less(t, updated[3].Rating, DefaultRating, "last place should lose")
+	assert.InDelta(t, DefaultRating, updated[1].Rating, 0.001, "the middle of an even field should stay put")
+	for _, r := range updated {
+		assert.Less(t, r.Deviation, DefaultDeviation, "uncertainty should shrink after playing")
+	}
+}
+
+func TestUpdatePlacement(t *testing.T) {
+	ratings := []Rating{{1800, 60, 0.06}, {1700, 60, 0.06}, Default()}
+	updated := UpdatePlacement(ratings, []int{3, 2, 1}, []bool{false, false, true})
+
+	assert.Less(t, updated[0].Rating, 1800.0)
+	assert.Greater(t, updated[1].Rating, 1700.0)
+	assert.Greater(t, updated[2].Rating, DefaultRating, "provisional players should be scored against everyone")
+
+	alone := UpdatePlacement(ratings[:1:1], []int{2}, []bool{false})
+	assert.Equal(t, ratings[0], alone[0])
+	lost := UpdatePlacement([]Rating{ratings[0], Default()}, []int{2, 1}, []bool{false, true})
+	assert.Equal(t, ratings[0], lost[0], "provisional players should not move settled ratings")
+}
+
+func TestDecayPeriods(t *testing.T) {
+	d := Decay{After: 10 * time.Hour, Period: time.Hour, Points: 15}
+	played := time.Unix(0, 0).Add(100 * time.Hour)
+
+	n, _ := d.Periods(played, time.Time{}, played.Add(10*time.Hour))
+	assert.Zero(t, n, "ratings should not decay before the inactivity period is over")
+	n, end := d.Periods(played, time.Time{}, played.Add(12*time.Hour+30*time.Minute))
+	assert.Equal(t, 2, n)
+	assert.Equal(t, played.Add(12*time.Hour), end)
+	n, _ = d.Periods(played, end, played.Add(13*time.Hour+30*time.Minute))
+	assert.Equal(t, 1, n, "periods already applied should not decay again")
+
+	n, _ = Decay{Period: time.Hour, Points: 15}.Periods(played, time.Time{}, played.Add(1000*time.Hour))
+	assert.Zero(t, n, "decay should be off without an inactivity period")
+}
+
+func TestDecayApply(t *testing.T) {
+	d := Decay{After: time.Hour, Period: time.Hour, Points: 15}
+
+	r := d.Apply(Rating{1600, 50, 0.06}, 2)
+	assert.Equal(t, 1570.0, r.Rating)
+	assert.Greater(t, r.Deviation, 50.0, "uncertainty should grow")
+	r = d.Apply(Rating{1510, 50, 0.06}, 2)
+	assert.Equal(t, DefaultRating, r.Rating, "ratings should not decay below the default")
+	r = d.Apply(Rating{1400, 340, 0.06}, 100)
+	assert.Equal(t, 1400.0, r.Rating)
+	assert.Equal(t, DefaultDeviation, r.Deviation)
+}
@@ -0,0 +1,218 @@
+// Package servertest runs the server in-process for integration tests, on a fake clock, so
+// that turn timers, disconnect grace periods and AFK detection play out as soon as a test
+// moves the clock forward instead of after real sleeps.
+//
+// Games are dealt the same way every run: rooms seed their shuffles from the clock, which
+// starts at the same time in every test. The server runs on the shared hub, so tests using it
+// shouldn't run in parallel.
+package servertest
+
+import (
+	"cardgame/card"
+	"cardgame/clock"
+	"cardgame/deck"
+	"cardgame/game"
+	"cardgame/storage"
+	"cardgame/web"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Start is the time the fake clock of every server starts at.
+var Start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// DeckId is the id of the deck of numbered cards every server has.
+const DeckId = "servertest"
+
+// Timeout is how long Expect waits for a message, in real time.
+var Timeout = 5 * time.Second
+
+// Server is the API and game server running in-process.
+type Server struct {
+	URL   string
+	Clock *clock.Fake
+	Store *storage.Memory
+
+	rooms []string // ids of the rooms created by the test
+}
+
+// New starts a server with an empty store, stopped when the test ends.
+func New(t *testing.T) *Server {
+	t.Helper()
+	s := &Server{Clock: clock.NewFake(Start), Store: storage.NewMemory()}
+
+	oldClock, oldStore := game.Clock, storage.Default
+	game.Clock, storage.Default = s.Clock, s.Store
+	if _, ok := deck.Decks()[DeckId]; !ok {
+		deck.Decks()[DeckId] = testDeck(60)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	web.InitApi(e.Group("/api"))
+	srv := httptest.NewServer(e)
+	s.URL = srv.URL
+
+	existing := map[*game.Room]bool{}
+	for _, r := range game.HubMain.AllRooms() {
+		existing[r] = true
+	}
+	t.Cleanup(func() {
+		srv.Close()
+		// rooms left running would keep using the clock and store of the test
+		for _, r := range game.HubMain.AllRooms() {
+			if !existing[r] && game.HubMain.CloseRoom(r.Id, nil, "The test is over") == nil {
+				r.Ping(Timeout) // returns once the room has stopped
+			}
+		}
+		game.Clock, storage.Default = oldClock, oldStore
+	})
+	return s
+}
+
+// testDeck returns a deck of size cards of every type in turn.
+func testDeck(size int) *deck.Deck {
+	d := &deck.Deck{Id: DeckId, Name: "servertest"}
+	for i := 0; i < size; i++ {
+		d.Cards = append(d.Cards, &card.Card{
+			Id:       card.NextId("c"),
+			Type:     card.CardType(i % card.CardTypeCount()),
+			Category: fmt.Sprintf("category %d", i),
+		})
+	}
+	return d
+}
+
+// Advance moves the clock forward, firing the timers due on the way. Before each timer fires,
+// it waits for the rooms to handle what they were sent, so that timers started in reaction to
+// earlier timers or messages fire too. Messages sent by players only count once the server
+// has read them, so tests should expect the server's reply to a message before advancing.
+func (s *Server) Advance(t *testing.T, d time.Duration) {
+	t.Helper()
+	end := s.Clock.Now().Add(d)
+	for {
+		s.sync(t)
+		next, ok := s.Clock.Next()
+		if !ok || next.After(end) {
+			break
+		}
+		s.Clock.Advance(next.Sub(s.Clock.Now()))
+	}
+	s.Clock.Advance(end.Sub(s.Clock.Now()))
+	s.sync(t)
+}
+
+// sync waits for the rooms created by the test to handle the messages sent to them so far.
+func (s *Server) sync(t *testing.T) {
+	t.Helper()
+	for _, id := range s.rooms {
+		r, ok := game.HubMain.Room(id)
+		if !ok {
+			continue
+		}
+		if err := r.Ping(Timeout); err != nil {
+			t.Fatalf("room %s: %v", id, err)
+		}
+	}
+}
+
+// CreateRoom creates a room and returns its id.
+func (s *Server) CreateRoom(t *testing.T) string {
+	t.Helper()
+	res, err := http.Post(s.URL+"/api/room", "application/json", nil)
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+	defer res.Body.Close()
+	var body struct {
+		Room struct {
+			Id string `json:"id"`
+		} `json:"room"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+	s.rooms = append(s.rooms, body.Room.Id)
+	return body.Room.Id
+}
+
+// Conn is a player connected to the server.
+type Conn struct {
+	t    *testing.T
+	ws   *websocket.Conn
+	Id   string // of the player
+	Name string
+}
+
+// Message is a message sent by the server, with its type and the state of the room.
+type Message map[string]any
+
+// Type returns the type of the message.
+func (m Message) Type() string {
+	s, _ := m["type"].(string)
+	return s
+}
+
+// Join connects a player to a room and waits for the room to accept them.
+func (s *Server) Join(t *testing.T, roomId, name string) *Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/ws/" + roomId
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	c := &Conn{t: t, ws: ws, Name: name}
+	c.Send("join", Message{"roomId": roomId, "name": name})
+	// the player who just joined is the last one seated in the state sent with the ack
+	ack := c.Expect("ack")
+	room, _ := ack["room"].(map[string]any)
+	players, _ := room["players"].([]any)
+	if len(players) == 0 {
+		t.Fatalf("%s joined a room without players", name)
+	}
+	c.Id, _ = players[len(players)-1].(map[string]any)["id"].(string)
+	return c
+}
+
+// Send sends a message of a type with the given fields.
+func (c *Conn) Send(typ string, fields Message) {
+	c.t.Helper()
+	m := Message{"type": typ}
+	for k, v := range fields {
+		m[k] = v
+	}
+	if err := c.ws.WriteJSON(m); err != nil {
+		c.t.Fatalf("%s failed to send %s: %v", c.Name, typ, err)
+	}
+}
+
+// Expect reads messages until one of the given type, and returns it. It fails the test if
+// none comes within Timeout.
+func (c *Conn) Expect(typ string) Message {
+	c.t.Helper()
+	c.ws.SetReadDeadline(time.Now().Add(Timeout))
+	for {
+		var m Message
+		if err := c.ws.ReadJSON(&m); err != nil {
+			c.t.Fatalf("%s expected %s: %v", c.Name, typ, err)
+		}
+		if m.Type() == typ {
+			return m
+		}
+	}
+}
+
+// Close disconnects the player.
+func (c *Conn) Close() {
+	c.ws.Close()
+}
@@ -0,0 +1,60 @@
+package servertest
+
+import (
+	"cardgame/game"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startGame(t *testing.T, s *Server, settings Message) (*Conn, *Conn) {
+	t.Helper()
+	room := s.CreateRoom(t)
+	owner := s.Join(t, room, "owner")
+	guest := s.Join(t, room, "guest")
+
+	settings["addDecks"] = []string{DeckId}
+	owner.Send("change_details", settings)
+	owner.Send("start", nil)
+	owner.Expect("start")
+	guest.Expect("start")
+	return owner, guest
+}
+
+func TestTurnTimeout(t *testing.T) {
+	s := New(t)
+	owner, guest := startGame(t, s, Message{"turnTimeout": 30, "afkTurns": 2, "afkPolicy": game.AfkPolicyBotFill})
+
+	s.Advance(t, 29*time.Second)
+	s.Advance(t, time.Second)
+	timeout := owner.Expect("turn_timeout")
+	assert.EqualValues(t, 1, timeout["missed"])
+	guest.Expect("turn_timeout")
+
+	// three more turns time out, the second of each player's making them AFK
+	s.Advance(t, 90*time.Second)
+	afk := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		m := owner.Expect("afk")
+		afk[m["playerId"].(string)] = true
+	}
+	assert.Equal(t, map[string]bool{owner.Id: true, guest.Id: true}, afk)
+}
+
+func TestSameDeal(t *testing.T) {
+	deal := func() any {
+		s := New(t)
+		owner, guest := startGame(t, s, Message{"turnTimeout": 30})
+		s.Advance(t, 30*time.Second)
+		owner.Expect("turn_timeout")
+		draw := guest.Expect("draw")
+		return draw["card"].(map[string]any)["category"]
+	}
+	first := deal()
+	require.NotNil(t, first)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, first, deal(), "games on the fake clock are dealt the same way")
+	}
+}
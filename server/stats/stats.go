@@ -0,0 +1,104 @@
+// Package stats keeps aggregate statistics of the games each account has played.
+//
+// The stats are updated as every game ends, so reading them never scans the match history.
+// Every account has stats per game type, and overall stats covering every game type.
+package stats
+
+import (
+	"cardgame/storage"
+	"errors"
+)
+
+// Overall is the game type of the stats covering every game type.
+const Overall = ""
+
+// Record adds a completed match to the stats of its players. Guests don't have stats and are
+// left out, and an account seated more than once only counts its best seat.
+func Record(s storage.Store, match *storage.Match) error {
+	players := []storage.MatchPlayer{}
+	seen := map[string]bool{}
+	for _, p := range match.Players {
+		// results are ordered by rank, so the first seat of an account is its best
+		if p.AccountId == "" || seen[p.AccountId] {
+			continue
+		}
+		seen[p.AccountId] = true
+		players = append(players, p)
+	}
+
+	stats := []*storage.PlayerStats{}
+	opponents := []*storage.OpponentStats{}
+	for _, p := range players {
+		for _, gameType := range []string{match.GameType, Overall} {
+			st, err := load(s, p.AccountId, gameType)
+			if err != nil {
+				return err
+			}
+			st.Games++
+			st.TotalScore += p.Score
+			if p.Rank == 1 {
+				st.Wins++
+				st.Streak++
+				if st.Streak > st.LongestStreak {
+					st.LongestStreak = st.Streak
+				}
+			} else {
+				st.Streak = 0
+			}
+			st.Updated = match.Ended
+			stats = append(stats, st)
+		}
+
+		for _, o := range players {
+			if o.AccountId == p.AccountId {
+				continue
+			}
+			vs, err := s.Opponent(p.AccountId, o.AccountId)
+			if errors.Is(err, storage.ErrNotFound) {
+				vs = &storage.OpponentStats{AccountId: p.AccountId, OpponentId: o.AccountId}
+			} else if err != nil {
+				return err
+			}
+			vs.Name = o.Name
+			vs.Games++
+			if p.Rank < o.Rank {
+				vs.Wins++
+			}
+			vs.Updated = match.Ended
+			opponents = append(opponents, vs)
+		}
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+	return s.SaveStats(stats, opponents)
+}
+
+func load(s storage.Store, accountId, gameType string) (*storage.PlayerStats, error) {
+	all, err := s.Stats(accountId)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range all {
+		if st.GameType == gameType {
+			return st, nil
+		}
+	}
+	return &storage.PlayerStats{AccountId: accountId, GameType: gameType}, nil
+}
+
+// WinRate returns the share of games won, from 0 to 1.
+func WinRate(st *storage.PlayerStats) float64 {
+	if st.Games == 0 {
+		return 0
+	}
+	return float64(st.Wins) / float64(st.Games)
+}
+
+// AverageScore returns the average score per game.
+func AverageScore(st *storage.PlayerStats) float64 {
+	if st.Games == 0 {
+		return 0
+	}
+	return float64(st.TotalScore) / float64(st.Games)
+}
@@ -0,0 +1,59 @@
+package stats
+
+import (
+	"cardgame/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	s := storage.NewMemory()
+	match := func(gameType string, ended int64, players ...storage.MatchPlayer) *storage.Match {
+		return &storage.Match{GameType: gameType, Players: players, Ended: ended}
+	}
+	alice := func(score, rank int) storage.MatchPlayer {
+		return storage.MatchPlayer{AccountId: "u_1", Name: "alice", Score: score, Rank: rank}
+	}
+	bob := func(score, rank int) storage.MatchPlayer {
+		return storage.MatchPlayer{AccountId: "u_2", Name: "bob", Score: score, Rank: rank}
+	}
+	guest := storage.MatchPlayer{Name: "guest", Rank: 3}
+
+	assert.NoError(t, Record(s, match("classic", 1, alice(5, 1), bob(2, 2), guest)))
+	assert.NoError(t, Record(s, match("classic", 2, alice(4, 1), bob(4, 1))))
+	assert.NoError(t, Record(s, match("teams", 3, bob(3, 1), alice(0, 2))))
+	assert.NoError(t, Record(s, match("teams", 4, alice(6, 1), bob(1, 2))))
+
+	stats, err := s.Stats("u_1")
+	assert.NoError(t, err)
+	if assert.Len(t, stats, 3) {
+		overall := stats[0]
+		assert.Equal(t, Overall, overall.GameType)
+		assert.Equal(t, 4, overall.Games)
+		assert.Equal(t, 3, overall.Wins)
+		assert.Equal(t, 0.75, WinRate(overall))
+		assert.Equal(t, 3.75, AverageScore(overall))
+		assert.Equal(t, 2, overall.LongestStreak, "a shared first place should count as a win")
+		assert.Equal(t, 1, overall.Streak)
+		assert.Equal(t, int64(4), overall.Updated)
+
+		classic := stats[1]
+		assert.Equal(t, "classic", classic.GameType)
+		assert.Equal(t, 2, classic.Wins)
+	}
+
+	opponents, err := s.Opponents("u_1", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, opponents, 1, "guests should not be counted as opponents") {
+		assert.Equal(t, "bob", opponents[0].Name)
+		assert.Equal(t, 4, opponents[0].Games)
+		assert.Equal(t, 2, opponents[0].Wins, "a tie should not count as a win")
+	}
+
+	assert.NoError(t, Record(s, match("classic", 5, guest)))
+	stats, err = s.Stats("")
+	assert.NoError(t, err)
+	assert.Empty(t, stats, "guests should not have stats")
+	assert.Equal(t, 0.0, WinRate(&storage.PlayerStats{}))
+}
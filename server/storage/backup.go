@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"cardgame/errs"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// backupFormat is the version of the archive layout written by Backup, bumped whenever
+// it changes in a way older releases can't read.
+const backupFormat = 1
+
+// backupChunkRows is the maximum number of rows in each chunk of a backup.
+const backupChunkRows = 500
+
+// ErrBackupUnsupported is returned when backing up a store that isn't a database.
+var ErrBackupUnsupported = errs.New(errs.ErrUnsupported, "only SQL stores can be backed up")
+
+// ErrBackupFormat is returned when restoring something that isn't a backup this server can read.
+var ErrBackupFormat = errs.New(errs.ErrInvalidInput, "unsupported backup")
+
+// ErrRestoreNotEmpty is returned when restoring a backup into a database that already has
+// data, when that isn't safe.
+var ErrRestoreNotEmpty = errs.New(errs.ErrConflict, "the database isn't empty")
+
+// identifier matches the table and column names a backup may refer to.
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// backupHeader opens a backup archive. It is followed by the chunks of every table.
+type backupHeader struct {
+	Format        int
+	SchemaVersion int
+	Created       int64
+}
+
+// backupChunk holds some of the rows of a table. Every table has at least one chunk,
+// even when it is empty.
+type backupChunk struct {
+	Table   string
+	Columns []string
+	Rows    [][]any
+}
+
+// Backup writes an archive of everything in s to w. Suspended games are the only state of
+// rooms that is stored, so games still being played aren't in it. Only SQL stores, cached or
+// not, can be backed up.
+func Backup(s Store, w io.Writer) error {
+	if c, ok := s.(*cachedStore); ok {
+		s = c.Store
+	}
+	db, ok := s.(*SQL)
+	if !ok {
+		return ErrBackupUnsupported
+	}
+	return db.Backup(w)
+}
+
+// Backup writes an archive of every table to w: a gzipped gob stream of a header followed
+// by the rows of each table. The rows are read in a single transaction, so the archive is
+// consistent even while the server keeps writing. With sqlite, writes wait for it to finish.
+func (s *SQL) Backup(w io.Writer) error {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	opts := &sql.TxOptions{}
+	if s.dialect == DialectPostgres {
+		opts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+	tx, err := s.db.BeginTx(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables, err := s.tables(tx)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := gob.NewEncoder(zw)
+	header := backupHeader{Format: backupFormat, SchemaVersion: version, Created: time.Now().UnixMilli()}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := backupTable(tx, enc, table); err != nil {
+			return fmt.Errorf("backing up %s: %w", table, err)
+		}
+	}
+	return zw.Close()
+}
+
+func backupTable(tx *sql.Tx, enc *gob.Encoder, table string) error {
+	rows, err := tx.Query(`SELECT * FROM ` + table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	sent := false
+	chunk := backupChunk{Table: table, Columns: columns}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		chunk.Rows = append(chunk.Rows, values)
+
+		if len(chunk.Rows) == backupChunkRows {
+			if err := enc.Encode(chunk); err != nil {
+				return err
+			}
+			sent = true
+			chunk.Rows = nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(chunk.Rows) > 0 || !sent {
+		return enc.Encode(chunk)
+	}
+	return nil
+}
+
+// Restore replaces everything in the database with a backup written by Backup. The rows are
+// replaced in a single transaction, so if that fails the database keeps its data.
+//
+// Backups of other schema versions can only be restored into an empty database: its schema is
+// first migrated to the version of the backup, which may drop tables and columns, then up to
+// the latest one once the rows are in. If migrating up fails, the rows restored are kept at the
+// version of the backup, to be migrated again.
+func (s *SQL) Restore(r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBackupFormat, err)
+	}
+	dec := gob.NewDecoder(zr)
+
+	var header backupHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("%w: %v", ErrBackupFormat, err)
+	}
+	if header.Format != backupFormat {
+		return fmt.Errorf("%w: format %d", ErrBackupFormat, header.Format)
+	}
+	if header.SchemaVersion > LatestSchemaVersion() {
+		return fmt.Errorf("%w: the backup is at version %d, newer than this server", ErrSchemaVersion, header.SchemaVersion)
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version != header.SchemaVersion {
+		empty, err := s.Empty()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("%w: the backup is at schema version %d, the database at %d", ErrRestoreNotEmpty, header.SchemaVersion, version)
+		}
+		if err := s.Migrate(header.SchemaVersion); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables, err := s.tables(tx)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, table := range tables {
+		known[table] = true
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return fmt.Errorf("clearing %s: %w", table, err)
+		}
+	}
+
+	for {
+		var chunk backupChunk
+		err := dec.Decode(&chunk)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBackupFormat, err)
+		}
+		if err := s.restoreChunk(tx, known, chunk); err != nil {
+			return fmt.Errorf("restoring %s: %w", chunk.Table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return s.Migrate(LatestSchemaVersion())
+}
+
+// Empty returns whether none of the tables of the database have any rows.
+func (s *SQL) Empty() (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	tables, err := s.tables(tx)
+	if err != nil {
+		return false, err
+	}
+	for _, table := range tables {
+		var n int
+		err := tx.QueryRow(`SELECT 1 FROM ` + table + ` LIMIT 1`).Scan(&n)
+		if err == nil {
+			return false, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("reading %s: %w", table, err)
+		}
+	}
+	return true, nil
+}
+
+func (s *SQL) restoreChunk(tx *sql.Tx, known map[string]bool, chunk backupChunk) error {
+	if !known[chunk.Table] {
+		return fmt.Errorf("%w: there is no table %q in the schema of the backup", ErrBackupFormat, chunk.Table)
+	}
+	for _, column := range chunk.Columns {
+		if !identifier.MatchString(column) {
+			return fmt.Errorf("%w: invalid column %q", ErrBackupFormat, column)
+		}
+	}
+	if len(chunk.Rows) == 0 {
+		return nil
+	}
+
+	query := s.dialect.rebind(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`,
+		chunk.Table, strings.Join(chunk.Columns, ", "), placeholders(len(chunk.Columns))))
+	statement, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, row := range chunk.Rows {
+		if len(row) != len(chunk.Columns) {
+			return fmt.Errorf("%w: row has %d values for %d columns", ErrBackupFormat, len(row), len(chunk.Columns))
+		}
+		if _, err := statement.Exec(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tables returns the names of the tables holding data, in order. The schema version is left
+// out, as it belongs to the database rather than to its contents.
+func (s *SQL) tables(tx *sql.Tx) ([]string, error) {
+	query := `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`
+	if s.dialect == DialectPostgres {
+		query = `SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name`
+	}
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		if table != "schema_version" && identifier.MatchString(table) {
+			tables = append(tables, table)
+		}
+	}
+	return tables, rows.Err()
+}
+
+// placeholders returns n comma separated "?" placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid"
+)
+
+// DefaultCacheTTL is how long cached reads are kept, unless a write invalidates them first.
+const DefaultCacheTTL = time.Minute
+
+// Cache keeps values for a while, shared by every server using it. Missing keys are not an error.
+type Cache interface {
+	Get(key string) (value []byte, ok bool, err error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
+}
+
+// cacheDrivers open caches by URL scheme. Drivers built with a tag register themselves here.
+var cacheDrivers = map[string]func(url string) (Cache, error){
+	"memory": func(string) (Cache, error) { return NewMemoryCache(), nil },
+}
+
+// OpenCache opens the cache at url: "memory" keeps it in the process, and "redis://..." uses
+// Redis in servers built with the redis tag. An empty url means no cache, and nil is returned.
+func OpenCache(url string) (Cache, error) {
+	if url == "" {
+		return nil, nil
+	}
+	scheme, _, _ := strings.Cut(url, "://")
+	open, ok := cacheDrivers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown cache %q", scheme)
+	}
+	return open(url)
+}
+
+// Cached puts a cache in front of the hot reads of a store: accounts by id, and leaderboard
+// pages and entries. Writes through the returned store invalidate what they change, while
+// everything else is passed on to s.
+func Cached(s Store, c Cache, ttl time.Duration) Store {
+	return &cachedStore{Store: s, cache: c, ttl: ttl}
+}
+
+type cachedStore struct {
+	Store
+	cache Cache
+	ttl   time.Duration
+}
+
+func accountCacheKey(id string) string {
+	return "account:" + id
+}
+
+// boardCacheKey is the key of the generation of a leaderboard. Pages and entries of the
+// leaderboard are cached under its generation, so replacing it invalidates all of them at once.
+func boardCacheKey(season int, gameType string) string {
+	return fmt.Sprintf("leaderboard:%d:%s", season, gameType)
+}
+
+// load returns the value cached at key, or loads it and caches it. A cache that fails is
+// only logged, and the value is loaded as if nothing was cached.
+func load[T any](s *cachedStore, key string, loadValue func() (T, error)) (T, error) {
+	data, ok, err := s.cache.Get(key)
+	if err != nil {
+		slog.Error("failed to read cache", "err", err)
+	}
+	if ok {
+		var v T
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err == nil {
+			return v, nil
+		}
+		slog.Error("failed to decode cached value", "err", err)
+	}
+
+	v, err := loadValue()
+	if err != nil {
+		return v, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		slog.Error("failed to encode cached value", "err", err)
+		return v, nil
+	}
+	if err := s.cache.Set(key, buf.Bytes(), s.ttl); err != nil {
+		slog.Error("failed to write cache", "err", err)
+	}
+	return v, nil
+}
+
+// invalidate deletes cached keys after a write.
+func (s *cachedStore) invalidate(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := s.cache.Delete(keys...); err != nil {
+		slog.Error("failed to invalidate cache", "err", err)
+	}
+}
+
+// generation returns the current generation of a leaderboard, starting a new one if there is none.
+func (s *cachedStore) generation(season int, gameType string) string {
+	key := boardCacheKey(season, gameType)
+	gen, ok, err := s.cache.Get(key)
+	if err != nil {
+		slog.Error("failed to read cache", "err", err)
+	}
+	if ok {
+		return string(gen)
+	}
+	gen = []byte(gonanoid.MustID(8))
+	// a page can't outlive its generation, so the generation can expire with it
+	if err := s.cache.Set(key, gen, s.ttl); err != nil {
+		slog.Error("failed to write cache", "err", err)
+	}
+	return string(gen)
+}
+
+func (s *cachedStore) Account(id string) (*Account, error) {
+	return load(s, accountCacheKey(id), func() (*Account, error) { return s.Store.Account(id) })
+}
+
+func (s *cachedStore) SaveAccount(a *Account) error {
+	if err := s.Store.SaveAccount(a); err != nil {
+		return err
+	}
+	s.invalidate(accountCacheKey(a.Id))
+	return nil
+}
+
+func (s *cachedStore) DeleteAccount(id string) error {
+	entries, err := s.Store.LeaderboardEntries(id)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.DeleteAccount(id); err != nil {
+		return err
+	}
+	keys := []string{accountCacheKey(id)}
+	for _, e := range entries {
+		keys = append(keys, boardCacheKey(e.Season, e.GameType))
+	}
+	s.invalidate(keys...)
+	return nil
+}
+
+func (s *cachedStore) LeaderboardEntry(season int, gameType, accountId string) (*LeaderboardEntry, error) {
+	key := fmt.Sprintf("%s:%s:entry:%s", boardCacheKey(season, gameType), s.generation(season, gameType), accountId)
+	return load(s, key, func() (*LeaderboardEntry, error) { return s.Store.LeaderboardEntry(season, gameType, accountId) })
+}
+
+func (s *cachedStore) Leaderboard(q LeaderboardQuery) ([]*LeaderboardEntry, error) {
+	key := fmt.Sprintf("%s:%s:page:%d:%d", boardCacheKey(q.Season, q.GameType), s.generation(q.Season, q.GameType), q.Offset, q.Limit)
+	entries, err := load(s, key, func() ([]*LeaderboardEntry, error) { return s.Store.Leaderboard(q) })
+	if entries == nil && err == nil {
+		// gob doesn't tell empty slices from nil ones
+		entries = []*LeaderboardEntry{}
+	}
+	return entries, err
+}
+
+func (s *cachedStore) SaveLeaderboardEntries(entries []*LeaderboardEntry) error {
+	if err := s.Store.SaveLeaderboardEntries(entries); err != nil {
+		return err
+	}
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if key := boardCacheKey(e.Season, e.GameType); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	s.invalidate(keys...)
+	return nil
+}
+
+// MemoryCache is a Cache kept in the memory of a single server.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	sets    int
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// memoryCacheSweep is how many values are set between sweeps of the expired ones.
+const memoryCacheSweep = 1000
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.entries[key] = memoryCacheEntry{value: append([]byte{}, value...), expires: now.Add(ttl)}
+
+	c.sets++
+	if c.sets >= memoryCacheSweep {
+		c.sets = 0
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build redis
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	open := func(url string) (Cache, error) {
+		opts, err := redis.ParseURL(url)
+		if err != nil {
+			return nil, err
+		}
+		return &redisCache{client: redis.NewClient(opts)}, nil
+	}
+	cacheDrivers["redis"] = open
+	cacheDrivers["rediss"] = open
+}
+
+// redisCache is a Cache kept in Redis, so every server shares it.
+type redisCache struct {
+	client *redis.Client
+}
+
+func (c *redisCache) Get(key string) ([]byte, bool, error) {
+	value, err := c.client.Get(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *redisCache) Set(key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(context.Background(), key, value, ttl).Err()
+}
+
+func (c *redisCache) Delete(keys ...string) error {
+	return c.client.Del(context.Background(), keys...).Err()
+}
+
+// leaseScript takes or renews a lease, atomically so two servers can't both hold it.
+var leaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0`)
+
+// unleaseScript deletes a lease, if it is still held by the same holder.
+var unleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func (c *redisCache) Lease(key, holder string, ttl time.Duration) (bool, error) {
+	n, err := leaseScript.Run(context.Background(), c.client, []string{key}, holder, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (c *redisCache) Unlease(key, holder string) error {
+	return unleaseScript.Run(context.Background(), c.client, []string{key}, holder).Err()
+}
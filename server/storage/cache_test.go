@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedStore(t *testing.T) {
+	testStore(t, Cached(NewMemory(), NewMemoryCache(), time.Minute))
+}
+
+func TestCachedReads(t *testing.T) {
+	base := NewMemory()
+	s := Cached(base, NewMemoryCache(), time.Minute)
+
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_1", Name: "alice", NameChanged: 7}))
+	a, err := s.Account("u_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(7), a.NameChanged, "cached accounts should keep every field")
+	}
+	assert.NoError(t, base.SaveAccount(&Account{Id: "u_1", Name: "changed behind the cache"}))
+	a, _ = s.Account("u_1")
+	assert.Equal(t, "alice", a.Name, "reads should be served from the cache")
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_1", Name: "alicia"}))
+	a, _ = s.Account("u_1")
+	assert.Equal(t, "alicia", a.Name, "saving should invalidate the account")
+
+	board, err := s.Leaderboard(LeaderboardQuery{Season: 1, GameType: "classic", Limit: 10})
+	assert.NoError(t, err)
+	assert.NotNil(t, board)
+	assert.Empty(t, board)
+	board, _ = s.Leaderboard(LeaderboardQuery{Season: 1, GameType: "classic", Limit: 10})
+	assert.NotNil(t, board, "empty cached pages should stay empty slices")
+	assert.NoError(t, s.SaveLeaderboardEntries([]*LeaderboardEntry{{Season: 1, GameType: "classic", AccountId: "u_1", Name: "alicia", Score: 1500}}))
+	board, _ = s.Leaderboard(LeaderboardQuery{Season: 1, GameType: "classic", Limit: 10})
+	assert.Len(t, board, 1, "saving entries should invalidate every page of the leaderboard")
+	e, err := s.LeaderboardEntry(1, "classic", "u_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, e.Rank)
+	}
+
+	assert.NoError(t, s.DeleteAccount("u_1"))
+	_, err = s.Account("u_1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	board, _ = s.Leaderboard(LeaderboardQuery{Season: 1, GameType: "classic", Limit: 10})
+	assert.Empty(t, board, "deleting an account should invalidate its leaderboards")
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	c := NewMemoryCache()
+	assert.NoError(t, c.Set("a", []byte("1"), time.Hour))
+	assert.NoError(t, c.Set("b", []byte("2"), -time.Second))
+	v, ok, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+	_, ok, _ = c.Get("b")
+	assert.False(t, ok, "expired values should be gone")
+	assert.NoError(t, c.Delete("a", "missing"))
+	_, ok, _ = c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestOpenCache(t *testing.T) {
+	c, err := OpenCache("")
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+	c, err = OpenCache("memory")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+	_, err = OpenCache("memcached://localhost")
+	assert.Error(t, err)
+}
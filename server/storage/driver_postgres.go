@@ -0,0 +1,6 @@
+//go:build postgres
+
+package storage
+
+// registers the "postgres" database/sql driver
+import _ "github.com/lib/pq"
@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package storage
+
+// registers the "sqlite" database/sql driver
+import _ "modernc.org/sqlite"
@@ -0,0 +1,6 @@
+//go:build !sqlite
+
+package storage
+
+// registers the "sqlite" database/sql driver for the tests of servers built without it
+import _ "modernc.org/sqlite"
@@ -0,0 +1,108 @@
+package storage
+
+import "errors"
+
+// AccountData is everything stored about an account, as exported for its owner.
+type AccountData struct {
+	Account          *Account               `json:"account"`
+	Email            string                 `json:"email"` // left out of the account, which is also shown to others
+	Totp             bool                   `json:"totp"`  // whether two-factor authentication is on, without its secrets
+	Sessions         []*Session             `json:"sessions"`
+	Friendships      []*Friendship          `json:"friendships"`
+	Blocks           []*Block               `json:"blocks"`
+	Matches          []*Match               `json:"matches"`
+	Ratings          []*PlayerRating        `json:"ratings"`
+	RatingChanges    []*RatingChange        `json:"ratingChanges"`
+	Leaderboards     []*LeaderboardEntry    `json:"leaderboards"`
+	Achievements     []*AchievementProgress `json:"achievements"`
+	Stats            []*PlayerStats         `json:"stats"`
+	Opponents        []*OpponentStats       `json:"opponents"`
+	Cosmetics        []*CosmeticUnlock      `json:"cosmetics"`
+	Experience       *Experience            `json:"experience"` // nil if the account has none
+	Quests           []*QuestProgress       `json:"quests"`
+	Challenges       []*ChallengeResult     `json:"challenges"`
+	ChallengeStreaks []*ChallengeStreak     `json:"challengeStreaks"`
+	SuspendedGames   []*SuspendedGame       `json:"suspendedGames"`
+	RoomPresets      []*RoomPreset          `json:"roomPresets"`
+	Reports          []*Report              `json:"reports"`  // filed by the account
+	AuditLog         []*AuditEntry          `json:"auditLog"` // actions taken by or on the account
+}
+
+// Export collects everything a store holds about an account. Avatar images are left out,
+// since they are served on their own.
+func Export(s Store, accountId string) (*AccountData, error) {
+	var d AccountData
+	var err error
+	if d.Account, err = s.Account(accountId); err != nil {
+		return nil, err
+	}
+	d.Email, d.Totp = d.Account.Email, d.Account.TotpEnabled != 0
+	if d.Sessions, err = s.Sessions(accountId); err != nil {
+		return nil, err
+	}
+	if d.Friendships, err = s.Friendships(accountId); err != nil {
+		return nil, err
+	}
+	if d.Blocks, err = s.Blocks(accountId); err != nil {
+		return nil, err
+	}
+	if d.Matches, err = s.Matches(MatchQuery{AccountId: accountId}); err != nil {
+		return nil, err
+	}
+	if d.Ratings, err = s.Ratings(accountId); err != nil {
+		return nil, err
+	}
+	d.RatingChanges = []*RatingChange{}
+	for _, r := range d.Ratings {
+		changes, err := s.RatingHistory(RatingQuery{AccountId: accountId, GameType: r.GameType})
+		if err != nil {
+			return nil, err
+		}
+		d.RatingChanges = append(d.RatingChanges, changes...)
+	}
+	if d.Leaderboards, err = s.LeaderboardEntries(accountId); err != nil {
+		return nil, err
+	}
+	if d.Achievements, err = s.Achievements(accountId); err != nil {
+		return nil, err
+	}
+	if d.Stats, err = s.Stats(accountId); err != nil {
+		return nil, err
+	}
+	if d.Opponents, err = s.Opponents(accountId, 0); err != nil {
+		return nil, err
+	}
+	if d.Cosmetics, err = s.Cosmetics(accountId); err != nil {
+		return nil, err
+	}
+	if d.Experience, err = s.Experience(accountId); err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if d.Quests, err = s.Quests(accountId, ""); err != nil {
+		return nil, err
+	}
+	if d.Challenges, err = s.ChallengeHistory(accountId); err != nil {
+		return nil, err
+	}
+	if d.ChallengeStreaks, err = s.ChallengeStreaks(accountId); err != nil {
+		return nil, err
+	}
+	if d.SuspendedGames, err = s.SuspendedGames(accountId); err != nil {
+		return nil, err
+	}
+	if d.RoomPresets, err = s.RoomPresets(accountId); err != nil {
+		return nil, err
+	}
+	if d.Reports, err = s.Reports(ReportQuery{ReporterId: accountId}); err != nil {
+		return nil, err
+	}
+	if d.AuditLog, err = s.AuditLog(AuditQuery{ActorId: accountId}); err != nil {
+		return nil, err
+	}
+	targeted, err := s.AuditLog(AuditQuery{TargetId: accountId})
+	if err != nil {
+		return nil, err
+	}
+	d.AuditLog = append(d.AuditLog, targeted...)
+	return &d, nil
+}
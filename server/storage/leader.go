@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Leaser is implemented by caches that can elect one of the servers sharing them to run the
+// jobs which must only run once in a cluster, like archiving seasons or purging accounts.
+type Leaser interface {
+	// Lease takes the lease of key for holder until ttl has passed, unless someone else holds
+	// it, and reports whether holder holds it now. Holding it already renews it.
+	Lease(key, holder string, ttl time.Duration) (bool, error)
+	// Unlease gives the lease of key up, if holder holds it.
+	Unlease(key, holder string) error
+}
+
+// Leader reports whether this server runs the jobs only one server of a cluster runs. It is
+// set on startup when servers share a cache, and a single server is always the leader.
+var Leader = func() bool { return true }
+
+// LeaderKey is the key of the lease held by the leader.
+const LeaderKey = "leader"
+
+// Elector keeps trying to become the leader of the servers sharing a Leaser. The leader
+// renews its lease while it runs; once it stops, or dies and its lease runs out, another
+// server takes over.
+type Elector struct {
+	leaser  Leaser
+	id      string
+	ttl     time.Duration
+	leading atomic.Bool
+}
+
+// Elect starts electing a leader among the servers sharing l, this one being id, until stop is
+// called. Leases last ttl, and are renewed or claimed every third of it.
+func Elect(l Leaser, id string, ttl time.Duration) (e *Elector, stop func()) {
+	e = &Elector{leaser: l, id: id, ttl: ttl}
+	// the jobs check for the leader as soon as they start
+	e.campaign()
+	repeatStop := repeat(ttl/3, e.campaign)
+	return e, func() {
+		repeatStop()
+		if e.leading.Swap(false) {
+			// let another server take over without waiting for the lease to run out
+			if err := l.Unlease(LeaderKey, id); err != nil {
+				slog.Error("failed to give up leadership", "err", err)
+			}
+		}
+	}
+}
+
+// campaign claims or renews the lease of the leader.
+func (e *Elector) campaign() {
+	leading, err := e.leaser.Lease(LeaderKey, e.id, e.ttl)
+	if err != nil {
+		// the lease can't be renewed, so another server may take over any time
+		slog.Error("failed to renew leadership", "err", err)
+		leading = false
+	}
+	if was := e.leading.Swap(leading); was != leading {
+		slog.Info("leadership changed", "server", e.id, "leading", leading)
+	}
+}
+
+// Leading reports whether this server is the leader.
+func (e *Elector) Leading() bool {
+	return e.leading.Load()
+}
+
+func (c *MemoryCache) Lease(key, holder string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) && string(e.value) != holder {
+		return false, nil
+	}
+	c.entries[key] = memoryCacheEntry{value: []byte(holder), expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (c *MemoryCache) Unlease(key, holder string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && string(e.value) == holder {
+		delete(c.entries, key)
+	}
+	return nil
+}
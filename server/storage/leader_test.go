@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCacheLease(t *testing.T) {
+	c := NewMemoryCache()
+	ok, err := c.Lease("leader", "a", 20*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = c.Lease("leader", "b", time.Minute)
+	assert.False(t, ok, "a lease should have one holder")
+	ok, _ = c.Lease("leader", "a", 20*time.Millisecond)
+	assert.True(t, ok, "the holder should be able to renew the lease")
+
+	time.Sleep(30 * time.Millisecond)
+	ok, _ = c.Lease("leader", "b", time.Minute)
+	assert.True(t, ok, "an expired lease should be free to take")
+
+	assert.NoError(t, c.Unlease("leader", "a"))
+	ok, _ = c.Lease("leader", "a", time.Minute)
+	assert.False(t, ok, "only the holder should be able to give a lease up")
+	assert.NoError(t, c.Unlease("leader", "b"))
+	ok, _ = c.Lease("leader", "a", time.Minute)
+	assert.True(t, ok)
+}
+
+func TestElect(t *testing.T) {
+	c := NewMemoryCache()
+	a, stopA := Elect(c, "a", 30*time.Millisecond)
+	b, stopB := Elect(c, "b", 30*time.Millisecond)
+	defer stopB()
+	assert.True(t, a.Leading(), "the first server should lead right away")
+	assert.False(t, b.Leading())
+
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, a.Leading(), "the leader should keep renewing its lease")
+	assert.False(t, b.Leading())
+
+	stopA()
+	assert.False(t, a.Leading())
+	assert.Eventually(t, b.Leading, time.Second, 5*time.Millisecond, "another server should take over")
+}
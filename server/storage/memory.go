@@ -0,0 +1,1290 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is a store that keeps everything in memory. It is lost when the server stops.
+type Memory struct {
+	mu        sync.RWMutex
+	accounts  map[string]*Account
+	sessions  map[string]*Session
+	mailed    map[string]*EmailToken // by token hash
+	avatars   map[string]*AvatarImage
+	friends   map[friendKey]*Friendship
+	blocks    map[string]map[string]*Block // by blocking account, then blocked account
+	matches   map[string]*Match
+	replays   map[string]*Replay
+	stats     map[ratingKey]*PlayerStats
+	opponents map[string]map[string]*OpponentStats // by account, then opponent
+	cosmetics map[string]map[string]*CosmeticUnlock
+	xp        map[string]*Experience
+	quests    map[questKey]*QuestProgress
+	results   map[challengeKey]*ChallengeResult
+	streaks   map[ratingKey]*ChallengeStreak
+	audit     map[string]*AuditEntry
+	reports   map[string]*Report
+	suspended map[string]*SuspendedGame
+	presets   map[string]*RoomPreset
+	ratings   map[ratingKey]*PlayerRating
+	changes   []*RatingChange
+	boards    map[boardKey]*board
+	seasons   map[int]*SeasonArchive
+	progress  map[string]map[string]*AchievementProgress // by account, then achievement
+}
+
+type ratingKey struct{ accountId, gameType string }
+
+type challengeKey struct{ day, gameType, accountId string }
+
+type questKey struct{ accountId, questId, period string }
+
+// friendKey is the key of the friendship of two accounts, the same whichever sent the request.
+type friendKey struct{ a, b string }
+
+func newFriendKey(a, b string) friendKey {
+	if a > b {
+		a, b = b, a
+	}
+	return friendKey{a, b}
+}
+
+type boardKey struct {
+	season   int
+	gameType string
+}
+
+// board is a leaderboard, kept sorted so pages and ranks can be found with binary searches.
+type board struct {
+	sorted   []*LeaderboardEntry
+	accounts map[string]*LeaderboardEntry
+}
+
+// ahead reports whether a is placed before b on a leaderboard.
+func ahead(a, b *LeaderboardEntry) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Updated != b.Updated {
+		return a.Updated < b.Updated
+	}
+	return a.AccountId < b.AccountId
+}
+
+// search returns the position of e on the board, or where it would be inserted.
+func (b *board) search(e *LeaderboardEntry) int {
+	return sort.Search(len(b.sorted), func(i int) bool { return !ahead(b.sorted[i], e) })
+}
+
+// rank returns the rank of the entry at position i.
+func (b *board) rank(i int) int {
+	score := b.sorted[i].Score
+	return sort.Search(i, func(j int) bool { return b.sorted[j].Score <= score }) + 1
+}
+
+func (b *board) entry(i int) *LeaderboardEntry {
+	c := *b.sorted[i]
+	c.Rank = b.rank(i)
+	return &c
+}
+
+func (b *board) save(e *LeaderboardEntry) {
+	b.remove(e.AccountId)
+	c := *e
+	i := b.search(&c)
+	b.sorted = append(b.sorted, nil)
+	copy(b.sorted[i+1:], b.sorted[i:])
+	b.sorted[i] = &c
+	b.accounts[e.AccountId] = &c
+}
+
+func (b *board) remove(accountId string) {
+	if old, ok := b.accounts[accountId]; ok {
+		i := b.search(old)
+		b.sorted = append(b.sorted[:i], b.sorted[i+1:]...)
+		delete(b.accounts, accountId)
+	}
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		accounts:  make(map[string]*Account),
+		sessions:  make(map[string]*Session),
+		mailed:    make(map[string]*EmailToken),
+		avatars:   make(map[string]*AvatarImage),
+		friends:   make(map[friendKey]*Friendship),
+		blocks:    make(map[string]map[string]*Block),
+		matches:   make(map[string]*Match),
+		replays:   make(map[string]*Replay),
+		stats:     make(map[ratingKey]*PlayerStats),
+		opponents: make(map[string]map[string]*OpponentStats),
+		cosmetics: make(map[string]map[string]*CosmeticUnlock),
+		xp:        make(map[string]*Experience),
+		quests:    make(map[questKey]*QuestProgress),
+		results:   make(map[challengeKey]*ChallengeResult),
+		streaks:   make(map[ratingKey]*ChallengeStreak),
+		audit:     make(map[string]*AuditEntry),
+		reports:   make(map[string]*Report),
+		suspended: make(map[string]*SuspendedGame),
+		presets:   make(map[string]*RoomPreset),
+		ratings:   make(map[ratingKey]*PlayerRating),
+		boards:    make(map[boardKey]*board),
+		seasons:   make(map[int]*SeasonArchive),
+		progress:  make(map[string]map[string]*AchievementProgress),
+	}
+}
+
+func (s *Memory) Account(id string) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.accounts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyAccount(a), nil
+}
+
+func (s *Memory) AccountByName(name string) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, a := range s.accounts {
+		if nameKey(a.Name) == nameKey(name) {
+			return copyAccount(a), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Memory) AccountByEmail(email string) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, a := range s.accounts {
+		if a.Email != "" && a.Email == strings.ToLower(email) {
+			return copyAccount(a), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Memory) SaveAccount(a *Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.accounts {
+		if other.Id != a.Id && a.Name != "" && nameKey(other.Name) == nameKey(a.Name) {
+			return ErrNameTaken
+		}
+		if other.Id != a.Id && a.Email != "" && other.Email == strings.ToLower(a.Email) {
+			return ErrEmailTaken
+		}
+	}
+	c := copyAccount(a)
+	c.Email = strings.ToLower(a.Email)
+	s.accounts[a.Id] = c
+	return nil
+}
+
+// copyAccount copies an account, so the store and its callers don't share recovery codes.
+func copyAccount(a *Account) *Account {
+	c := *a
+	c.RecoveryCodes = append([]string(nil), a.RecoveryCodes...)
+	return &c
+}
+
+func (s *Memory) DeletedAccounts(before int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	deleted := []*Account{}
+	for _, a := range s.accounts {
+		if a.Deleted > 0 && a.Deleted < before {
+			deleted = append(deleted, a)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		if deleted[i].Deleted != deleted[j].Deleted {
+			return deleted[i].Deleted < deleted[j].Deleted
+		}
+		return deleted[i].Id < deleted[j].Id
+	})
+
+	ids := make([]string, len(deleted))
+	for i, a := range deleted {
+		ids[i] = a.Id
+	}
+	return ids, nil
+}
+
+func (s *Memory) SessionByToken(tokenHash string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, session := range s.sessions {
+		if session.TokenHash == tokenHash {
+			c := *session
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Memory) Sessions(accountId string) ([]*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sessions := []*Session{}
+	for _, session := range s.sessions {
+		if session.AccountId == accountId {
+			c := *session
+			sessions = append(sessions, &c)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Created != sessions[j].Created {
+			return sessions[i].Created < sessions[j].Created
+		}
+		return sessions[i].Id < sessions[j].Id
+	})
+	return sessions, nil
+}
+
+func (s *Memory) SaveSession(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *session
+	s.sessions[session.Id] = &c
+	return nil
+}
+
+func (s *Memory) DeleteSession(accountId, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[id]; !ok || session.AccountId != accountId {
+		return ErrNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *Memory) DeleteSessions(accountId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if session.AccountId == accountId {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *Memory) EmailToken(tokenHash string) (*EmailToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.mailed[tokenHash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+func (s *Memory) SaveEmailToken(t *EmailToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *t
+	s.mailed[t.TokenHash] = &c
+	return nil
+}
+
+func (s *Memory) DeleteEmailTokens(accountId string, purpose EmailPurpose) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, t := range s.mailed {
+		if t.AccountId == accountId && t.Purpose == purpose {
+			delete(s.mailed, hash)
+		}
+	}
+	return nil
+}
+
+func (s *Memory) DeleteAccount(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.accounts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.accounts, id)
+	for sessionId, session := range s.sessions {
+		if session.AccountId == id {
+			delete(s.sessions, sessionId)
+		}
+	}
+	for hash, t := range s.mailed {
+		if t.AccountId == id {
+			delete(s.mailed, hash)
+		}
+	}
+	delete(s.avatars, id)
+	for key := range s.friends {
+		if key.a == id || key.b == id {
+			delete(s.friends, key)
+		}
+	}
+	delete(s.blocks, id)
+	for _, blocks := range s.blocks {
+		delete(blocks, id)
+	}
+	for key := range s.stats {
+		if key.accountId == id {
+			delete(s.stats, key)
+		}
+	}
+	delete(s.opponents, id)
+	for _, opponents := range s.opponents {
+		delete(opponents, id)
+	}
+	delete(s.cosmetics, id)
+	delete(s.xp, id)
+	for key := range s.quests {
+		if key.accountId == id {
+			delete(s.quests, key)
+		}
+	}
+	for key := range s.results {
+		if key.accountId == id {
+			delete(s.results, key)
+		}
+	}
+	for key := range s.streaks {
+		if key.accountId == id {
+			delete(s.streaks, key)
+		}
+	}
+	for gameId, g := range s.suspended {
+		if suspendedIn(g, id) {
+			delete(s.suspended, gameId)
+		}
+	}
+	for presetId, p := range s.presets {
+		if p.AccountId == id {
+			delete(s.presets, presetId)
+		}
+	}
+	for key := range s.ratings {
+		if key.accountId == id {
+			delete(s.ratings, key)
+		}
+	}
+	changes := []*RatingChange{}
+	for _, c := range s.changes {
+		if c.AccountId != id {
+			changes = append(changes, c)
+		}
+	}
+	s.changes = changes
+	for _, b := range s.boards {
+		b.remove(id)
+	}
+	delete(s.progress, id)
+	for _, m := range s.matches {
+		anonymize(m.Players, id)
+	}
+	for _, e := range s.audit {
+		if e.ActorId == id {
+			e.ActorName = DeletedName
+		}
+		if e.TargetId == id {
+			e.TargetName = DeletedName
+		}
+	}
+	for _, r := range s.reports {
+		if r.ReporterId == id {
+			r.ReporterName = DeletedName
+		}
+		if r.TargetId == id {
+			r.TargetName = DeletedName
+		}
+	}
+	return nil
+}
+
+func (s *Memory) Blocks(accountId string) ([]*Block, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	blocks := []*Block{}
+	for _, b := range s.blocks[accountId] {
+		c := *b
+		blocks = append(blocks, &c)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Created < blocks[j].Created })
+	return blocks, nil
+}
+
+func (s *Memory) Blocked(accountId, blockedId string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.blocks[accountId][blockedId]
+	return ok, nil
+}
+
+func (s *Memory) SaveBlock(b *Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blocks, ok := s.blocks[b.AccountId]
+	if !ok {
+		blocks = make(map[string]*Block)
+		s.blocks[b.AccountId] = blocks
+	}
+	c := *b
+	blocks[b.BlockedId] = &c
+	return nil
+}
+
+func (s *Memory) DeleteBlock(accountId, blockedId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.blocks[accountId][blockedId]; !ok {
+		return ErrNotFound
+	}
+	delete(s.blocks[accountId], blockedId)
+	return nil
+}
+
+func (s *Memory) Friendship(a, b string) (*Friendship, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.friends[newFriendKey(a, b)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *f
+	return &c, nil
+}
+
+func (s *Memory) Friendships(accountId string) ([]*Friendship, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	friendships := []*Friendship{}
+	for key, f := range s.friends {
+		if key.a == accountId || key.b == accountId {
+			c := *f
+			friendships = append(friendships, &c)
+		}
+	}
+	sort.Slice(friendships, func(i, j int) bool { return friendships[i].Created < friendships[j].Created })
+	return friendships, nil
+}
+
+func (s *Memory) SaveFriendship(f *Friendship) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *f
+	s.friends[newFriendKey(f.AccountId, f.FriendId)] = &c
+	return nil
+}
+
+func (s *Memory) DeleteFriendship(a, b string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := newFriendKey(a, b)
+	if _, ok := s.friends[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.friends, key)
+	return nil
+}
+
+func (s *Memory) AvatarImage(accountId string) (*AvatarImage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	img, ok := s.avatars[accountId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *img
+	c.Data = append([]byte{}, img.Data...)
+	return &c, nil
+}
+
+func (s *Memory) SaveAvatarImage(img *AvatarImage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *img
+	c.Data = append([]byte{}, img.Data...)
+	s.avatars[img.AccountId] = &c
+	return nil
+}
+
+func (s *Memory) DeleteAvatarImage(accountId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.avatars[accountId]; !ok {
+		return ErrNotFound
+	}
+	delete(s.avatars, accountId)
+	return nil
+}
+
+func (s *Memory) Match(id string) (*Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.matches[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *m
+	c.Players = append([]MatchPlayer{}, m.Players...)
+	return &c, nil
+}
+
+func (s *Memory) Matches(q MatchQuery) ([]*Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches := []*Match{}
+	for _, m := range s.matches {
+		if q.RoomId != "" && m.RoomId != q.RoomId {
+			continue
+		}
+		if q.Before != 0 && m.Ended >= q.Before && (m.Ended > q.Before || q.BeforeId == "" || m.Id <= q.BeforeId) {
+			continue
+		}
+		if q.AccountId != "" && !playedIn(m, q.AccountId) {
+			continue
+		}
+		c := *m
+		c.Players = append([]MatchPlayer{}, m.Players...)
+		matches = append(matches, &c)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Ended != matches[j].Ended {
+			return matches[i].Ended > matches[j].Ended
+		}
+		return matches[i].Id < matches[j].Id
+	})
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	return matches, nil
+}
+
+func playedIn(m *Match, accountId string) bool {
+	for _, p := range m.Players {
+		if p.AccountId == accountId {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Memory) SaveMatch(m *Match) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *m
+	c.Players = append([]MatchPlayer{}, m.Players...)
+	s.matches[m.Id] = &c
+	return nil
+}
+
+func (s *Memory) Replay(matchId string) (*Replay, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.replays[matchId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *r
+	return &c, nil
+}
+
+func (s *Memory) Replays(q ReplayQuery) ([]*Replay, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	replays := []*Replay{}
+	for _, r := range s.replays {
+		if q.Before != 0 && r.Created >= q.Before && (r.Created > q.Before || q.BeforeId == "" || r.MatchId <= q.BeforeId) {
+			continue
+		}
+		c := *r
+		c.Events = nil
+		replays = append(replays, &c)
+	}
+	sort.Slice(replays, func(i, j int) bool {
+		if replays[i].Created != replays[j].Created {
+			return replays[i].Created > replays[j].Created
+		}
+		return replays[i].MatchId < replays[j].MatchId
+	})
+	if q.Limit > 0 && len(replays) > q.Limit {
+		replays = replays[:q.Limit]
+	}
+	return replays, nil
+}
+
+func (s *Memory) DeleteReplaysBefore(created int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, r := range s.replays {
+		if r.Created < created {
+			delete(s.replays, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Memory) SaveReplay(r *Replay) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *r
+	s.replays[r.MatchId] = &c
+	return nil
+}
+
+func (s *Memory) Rating(accountId, gameType string) (*PlayerRating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.ratings[ratingKey{accountId, gameType}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *r
+	return &c, nil
+}
+
+func (s *Memory) Ratings(accountId string) ([]*PlayerRating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ratings := []*PlayerRating{}
+	for key, r := range s.ratings {
+		if key.accountId == accountId {
+			c := *r
+			ratings = append(ratings, &c)
+		}
+	}
+	sort.Slice(ratings, func(i, j int) bool { return ratings[i].GameType < ratings[j].GameType })
+	return ratings, nil
+}
+
+func (s *Memory) InactiveRatings(before int64) ([]*PlayerRating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ratings := []*PlayerRating{}
+	for _, r := range s.ratings {
+		if r.Updated < before {
+			c := *r
+			ratings = append(ratings, &c)
+		}
+	}
+	sort.Slice(ratings, func(i, j int) bool {
+		if ratings[i].Updated != ratings[j].Updated {
+			return ratings[i].Updated < ratings[j].Updated
+		}
+		if ratings[i].AccountId != ratings[j].AccountId {
+			return ratings[i].AccountId < ratings[j].AccountId
+		}
+		return ratings[i].GameType < ratings[j].GameType
+	})
+	return ratings, nil
+}
+
+func (s *Memory) RatingHistory(q RatingQuery) ([]*RatingChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	changes := []*RatingChange{}
+	for _, change := range s.changes {
+		if change.AccountId != q.AccountId || change.GameType != q.GameType {
+			continue
+		}
+		if q.Before != 0 && change.Created >= q.Before && (change.Created > q.Before || q.BeforeId == "" || change.MatchId <= q.BeforeId) {
+			continue
+		}
+		c := *change
+		changes = append(changes, &c)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Created != changes[j].Created {
+			return changes[i].Created > changes[j].Created
+		}
+		return changes[i].MatchId < changes[j].MatchId
+	})
+	if q.Limit > 0 && len(changes) > q.Limit {
+		changes = changes[:q.Limit]
+	}
+	return changes, nil
+}
+
+func (s *Memory) SaveRatings(ratings []*PlayerRating, changes []*RatingChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range ratings {
+		c := *r
+		s.ratings[ratingKey{r.AccountId, r.GameType}] = &c
+	}
+	for _, change := range changes {
+		c := *change
+		s.changes = append(s.changes, &c)
+	}
+	return nil
+}
+
+func (s *Memory) LeaderboardEntry(season int, gameType, accountId string) (*LeaderboardEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.boards[boardKey{season, gameType}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	e, ok := b.accounts[accountId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return b.entry(b.search(e)), nil
+}
+
+func (s *Memory) LeaderboardEntries(accountId string) ([]*LeaderboardEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []*LeaderboardEntry{}
+	for _, b := range s.boards {
+		if e, ok := b.accounts[accountId]; ok {
+			entries = append(entries, b.entry(b.search(e)))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Season != entries[j].Season {
+			return entries[i].Season < entries[j].Season
+		}
+		return entries[i].GameType < entries[j].GameType
+	})
+	return entries, nil
+}
+
+func (s *Memory) Leaderboard(q LeaderboardQuery) ([]*LeaderboardEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []*LeaderboardEntry{}
+	b, ok := s.boards[boardKey{q.Season, q.GameType}]
+	if !ok {
+		return entries, nil
+	}
+	for i := q.Offset; i < len(b.sorted); i++ {
+		if q.Limit > 0 && len(entries) == q.Limit {
+			break
+		}
+		entries = append(entries, b.entry(i))
+	}
+	return entries, nil
+}
+
+func (s *Memory) LeaderboardGameTypes(season int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	gameTypes := []string{}
+	for key := range s.boards {
+		if key.season == season {
+			gameTypes = append(gameTypes, key.gameType)
+		}
+	}
+	sort.Strings(gameTypes)
+	return gameTypes, nil
+}
+
+func (s *Memory) SaveLeaderboardEntries(entries []*LeaderboardEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		key := boardKey{e.Season, e.GameType}
+		b, ok := s.boards[key]
+		if !ok {
+			b = &board{accounts: make(map[string]*LeaderboardEntry)}
+			s.boards[key] = b
+		}
+		b.save(e)
+	}
+	return nil
+}
+
+func (s *Memory) SeasonArchive(season int) (*SeasonArchive, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.seasons[season]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *a
+	return &c, nil
+}
+
+func (s *Memory) SaveSeasonArchive(a *SeasonArchive) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *a
+	s.seasons[a.Season] = &c
+	return nil
+}
+
+func (s *Memory) Achievements(accountId string) ([]*AchievementProgress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	progress := []*AchievementProgress{}
+	for _, p := range s.progress[accountId] {
+		c := *p
+		progress = append(progress, &c)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].AchievementId < progress[j].AchievementId })
+	return progress, nil
+}
+
+func (s *Memory) SaveAchievements(progress []*AchievementProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range progress {
+		account, ok := s.progress[p.AccountId]
+		if !ok {
+			account = make(map[string]*AchievementProgress)
+			s.progress[p.AccountId] = account
+		}
+		c := *p
+		account[p.AchievementId] = &c
+	}
+	return nil
+}
+
+func (s *Memory) Stats(accountId string) ([]*PlayerStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := []*PlayerStats{}
+	for key, st := range s.stats {
+		if key.accountId == accountId {
+			c := *st
+			stats = append(stats, &c)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].GameType < stats[j].GameType })
+	return stats, nil
+}
+
+func (s *Memory) Opponent(accountId, opponentId string) (*OpponentStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.opponents[accountId][opponentId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+func (s *Memory) Opponents(accountId string, limit int) ([]*OpponentStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	opponents := []*OpponentStats{}
+	for _, o := range s.opponents[accountId] {
+		c := *o
+		opponents = append(opponents, &c)
+	}
+	sort.Slice(opponents, func(i, j int) bool {
+		if opponents[i].Games != opponents[j].Games {
+			return opponents[i].Games > opponents[j].Games
+		}
+		return opponents[i].OpponentId < opponents[j].OpponentId
+	})
+	if limit > 0 && len(opponents) > limit {
+		opponents = opponents[:limit]
+	}
+	return opponents, nil
+}
+
+func (s *Memory) SaveStats(stats []*PlayerStats, opponents []*OpponentStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range stats {
+		c := *st
+		s.stats[ratingKey{st.AccountId, st.GameType}] = &c
+	}
+	for _, o := range opponents {
+		account, ok := s.opponents[o.AccountId]
+		if !ok {
+			account = make(map[string]*OpponentStats)
+			s.opponents[o.AccountId] = account
+		}
+		c := *o
+		account[o.OpponentId] = &c
+	}
+	return nil
+}
+
+func (s *Memory) Cosmetics(accountId string) ([]*CosmeticUnlock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	unlocks := []*CosmeticUnlock{}
+	for _, u := range s.cosmetics[accountId] {
+		c := *u
+		unlocks = append(unlocks, &c)
+	}
+	sort.Slice(unlocks, func(i, j int) bool {
+		if unlocks[i].Unlocked != unlocks[j].Unlocked {
+			return unlocks[i].Unlocked < unlocks[j].Unlocked
+		}
+		return unlocks[i].CosmeticId < unlocks[j].CosmeticId
+	})
+	return unlocks, nil
+}
+
+func (s *Memory) SaveCosmetics(unlocks []*CosmeticUnlock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range unlocks {
+		account, ok := s.cosmetics[u.AccountId]
+		if !ok {
+			account = make(map[string]*CosmeticUnlock)
+			s.cosmetics[u.AccountId] = account
+		}
+		if _, ok := account[u.CosmeticId]; ok {
+			continue
+		}
+		c := *u
+		account[u.CosmeticId] = &c
+	}
+	return nil
+}
+
+func (s *Memory) Experience(accountId string) (*Experience, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	xp, ok := s.xp[accountId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *xp
+	return &c, nil
+}
+
+func (s *Memory) Quests(accountId, period string) ([]*QuestProgress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	quests := []*QuestProgress{}
+	for key, q := range s.quests {
+		if key.accountId == accountId && (period == "" || key.period == period) {
+			c := *q
+			quests = append(quests, &c)
+		}
+	}
+	sort.Slice(quests, func(i, j int) bool {
+		if quests[i].Period != quests[j].Period {
+			return quests[i].Period < quests[j].Period
+		}
+		return quests[i].QuestId < quests[j].QuestId
+	})
+	return quests, nil
+}
+
+func (s *Memory) SaveProgression(xp *Experience, quests []*QuestProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *xp
+	s.xp[xp.AccountId] = &c
+	for _, q := range quests {
+		c := *q
+		s.quests[questKey{q.AccountId, q.QuestId, q.Period}] = &c
+	}
+	return nil
+}
+
+func (s *Memory) ChallengeResult(day, gameType, accountId string) (*ChallengeResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.results[challengeKey{day, gameType, accountId}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *r
+	return &c, nil
+}
+
+func (s *Memory) ChallengeResults(day, gameType string, limit int) ([]*ChallengeResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results := []*ChallengeResult{}
+	for key, r := range s.results {
+		if key.day == day && key.gameType == gameType && r.Completed != 0 {
+			c := *r
+			results = append(results, &c)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Completed < results[j].Completed
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *Memory) ChallengeHistory(accountId string) ([]*ChallengeResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results := []*ChallengeResult{}
+	for key, r := range s.results {
+		if key.accountId == accountId {
+			c := *r
+			results = append(results, &c)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Day != results[j].Day {
+			return results[i].Day > results[j].Day
+		}
+		return results[i].GameType < results[j].GameType
+	})
+	return results, nil
+}
+
+func (s *Memory) ChallengeStreaks(accountId string) ([]*ChallengeStreak, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	streaks := []*ChallengeStreak{}
+	for key, st := range s.streaks {
+		if key.accountId == accountId {
+			c := *st
+			streaks = append(streaks, &c)
+		}
+	}
+	sort.Slice(streaks, func(i, j int) bool { return streaks[i].GameType < streaks[j].GameType })
+	return streaks, nil
+}
+
+func (s *Memory) ChallengeStreak(accountId, gameType string) (*ChallengeStreak, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st, ok := s.streaks[ratingKey{accountId, gameType}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *st
+	return &c, nil
+}
+
+func (s *Memory) SaveChallengeResult(result *ChallengeResult, streak *ChallengeStreak) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *result
+	s.results[challengeKey{result.Day, result.GameType, result.AccountId}] = &c
+	if streak != nil {
+		c := *streak
+		s.streaks[ratingKey{streak.AccountId, streak.GameType}] = &c
+	}
+	return nil
+}
+
+func (s *Memory) AuditLog(q AuditQuery) ([]*AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []*AuditEntry{}
+	for _, e := range s.audit {
+		if (q.ActorId != "" && e.ActorId != q.ActorId) || (q.TargetId != "" && e.TargetId != q.TargetId) ||
+			(q.Action != "" && e.Action != q.Action) ||
+			(q.Before != 0 && e.Created >= q.Before && (e.Created > q.Before || q.BeforeId == "" || e.Id <= q.BeforeId)) {
+			continue
+		}
+		c := *e
+		entries = append(entries, &c)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Created != entries[j].Created {
+			return entries[i].Created > entries[j].Created
+		}
+		return entries[i].Id < entries[j].Id
+	})
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+func (s *Memory) SaveAuditEntry(e *AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *e
+	s.audit[e.Id] = &c
+	return nil
+}
+
+func (s *Memory) Report(id string) (*Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.reports[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *r
+	c.Chat = append([]ReportChat{}, r.Chat...)
+	return &c, nil
+}
+
+func (s *Memory) Reports(q ReportQuery) ([]*Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reports := []*Report{}
+	for _, r := range s.reports {
+		if (q.Status != "" && r.Status != q.Status) || (q.ReporterId != "" && r.ReporterId != q.ReporterId) ||
+			(q.TargetId != "" && r.TargetId != q.TargetId) ||
+			(q.Before != 0 && r.Created >= q.Before && (r.Created > q.Before || q.BeforeId == "" || r.Id <= q.BeforeId)) {
+			continue
+		}
+		c := *r
+		c.Chat = nil
+		c.Events = nil
+		reports = append(reports, &c)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Created != reports[j].Created {
+			return reports[i].Created > reports[j].Created
+		}
+		return reports[i].Id < reports[j].Id
+	})
+	if q.Limit > 0 && len(reports) > q.Limit {
+		reports = reports[:q.Limit]
+	}
+	return reports, nil
+}
+
+func (s *Memory) SaveReport(r *Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *r
+	c.Chat = append([]ReportChat{}, r.Chat...)
+	s.reports[r.Id] = &c
+	return nil
+}
+
+func (s *Memory) SuspendedGame(id string) (*SuspendedGame, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g, ok := s.suspended[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *g
+	c.Players = append([]MatchPlayer{}, g.Players...)
+	return &c, nil
+}
+
+func (s *Memory) SuspendedGames(accountId string) ([]*SuspendedGame, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	games := []*SuspendedGame{}
+	for _, g := range s.suspended {
+		if !suspendedIn(g, accountId) {
+			continue
+		}
+		c := *g
+		c.Players = append([]MatchPlayer{}, g.Players...)
+		games = append(games, &c)
+	}
+	sort.Slice(games, func(i, j int) bool {
+		if games[i].Suspended != games[j].Suspended {
+			return games[i].Suspended > games[j].Suspended
+		}
+		return games[i].Id < games[j].Id
+	})
+	return games, nil
+}
+
+func suspendedIn(g *SuspendedGame, accountId string) bool {
+	for _, p := range g.Players {
+		if p.AccountId == accountId {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Memory) SaveSuspendedGame(g *SuspendedGame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *g
+	c.Players = append([]MatchPlayer{}, g.Players...)
+	s.suspended[g.Id] = &c
+	return nil
+}
+
+func (s *Memory) DeleteSuspendedGame(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.suspended[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.suspended, id)
+	return nil
+}
+
+func (s *Memory) RoomPreset(id string) (*RoomPreset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.presets[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *p
+	return &c, nil
+}
+
+func (s *Memory) RoomPresetByCode(code string) (*RoomPreset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.presets {
+		if p.Code == code {
+			c := *p
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Memory) RoomPresets(accountId string) ([]*RoomPreset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	presets := []*RoomPreset{}
+	for _, p := range s.presets {
+		if p.AccountId == accountId {
+			c := *p
+			presets = append(presets, &c)
+		}
+	}
+	sort.Slice(presets, func(i, j int) bool {
+		if presets[i].Created != presets[j].Created {
+			return presets[i].Created < presets[j].Created
+		}
+		return presets[i].Id < presets[j].Id
+	})
+	return presets, nil
+}
+
+func (s *Memory) SaveRoomPreset(p *RoomPreset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *p
+	s.presets[p.Id] = &c
+	return nil
+}
+
+func (s *Memory) DeleteRoomPreset(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.presets[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.presets, id)
+	return nil
+}
+
+func (s *Memory) Ping() error {
+	return nil
+}
+
+func (s *Memory) Close() error {
+	return nil
+}
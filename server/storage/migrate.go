@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles hold the schema migrations of the SQL stores, as pairs of files named like
+// "0001_create_accounts.up.sql" and "0001_create_accounts.down.sql". Migrations are applied
+// in order, once each, so released files must never be changed; add new ones instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a versioned change to the schema and the statements undoing it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrations are the migrations built into the server, in order.
+var migrations = mustLoadMigrations(migrationFiles)
+
+// AutoMigrate decides whether OpenSQL brings out of date schemas up to date itself.
+// Self-hosters who would rather back up first turn it off and run the migrations by hand.
+var AutoMigrate = true
+
+// ErrSchemaVersion is returned when a database's schema is not at the version the server expects.
+var ErrSchemaVersion = errors.New("unexpected schema version")
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// loadMigrations reads the migrations in the "migrations" directory of a file system.
+// Every version from 1 up needs both an up and a down file.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		data, err := fs.ReadFile(fsys, path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d is named both %q and %q", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i, m := range all {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", m.Version)
+		}
+	}
+	return all, nil
+}
+
+func mustLoadMigrations(fsys fs.FS) []Migration {
+	all, err := loadMigrations(fsys)
+	if err != nil {
+		panic("invalid schema migrations: " + err.Error())
+	}
+	return all
+}
+
+// statements splits a migration file into the statements in it, which end with a semicolon
+// at the end of a line. Comments on lines of their own are left out.
+func statements(script string) []string {
+	all := []string{}
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line + "\n")
+		if strings.HasSuffix(trimmed, ";") {
+			all = append(all, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		all = append(all, rest)
+	}
+	return all
+}
+
+// LatestSchemaVersion returns the version of the schema the server is built for.
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// SchemaVersion returns the version of the database's schema, 0 if it is empty.
+func (s *SQL) SchemaVersion() (int, error) {
+	if _, err := s.exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return 0, err
+	}
+
+	version := 0
+	err := s.queryRow(`SELECT version FROM schema_version`).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = s.exec(`INSERT INTO schema_version (version) VALUES (0)`)
+	}
+	return version, err
+}
+
+// checkSchema makes sure the schema is at the latest version, migrating it up if AutoMigrate is on.
+func (s *SQL) checkSchema() error {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest := LatestSchemaVersion()
+	if version > latest {
+		return fmt.Errorf("%w: the database is at version %d, newer than the %d this server knows, so it was probably used by a newer release",
+			ErrSchemaVersion, version, latest)
+	}
+	if version < latest && !AutoMigrate {
+		return fmt.Errorf("%w: the database is at version %d and needs migrating to %d", ErrSchemaVersion, version, latest)
+	}
+	return s.Migrate(latest)
+}
+
+// Migrate applies the up or down migrations needed to bring the schema to a version.
+// Every migration runs in a transaction of its own, so a failed one leaves the schema
+// at the version before it.
+func (s *SQL) Migrate(target int) error {
+	if target < 0 || target > LatestSchemaVersion() {
+		return fmt.Errorf("%w: there is no version %d", ErrSchemaVersion, target)
+	}
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > LatestSchemaVersion() {
+		return fmt.Errorf("%w: the database is at version %d, newer than this server", ErrSchemaVersion, version)
+	}
+
+	for version != target {
+		var script string
+		next := version + 1
+		if target > version {
+			script = migrations[version].Up
+		} else {
+			script = migrations[version-1].Down
+			next = version - 1
+		}
+		if err := s.applyMigration(script, next); err != nil {
+			return fmt.Errorf("migrating from version %d to %d: %w", version, next, err)
+		}
+		version = next
+	}
+	return nil
+}
+
+func (s *SQL) applyMigration(script string, version int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements(script) {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`UPDATE schema_version SET version = ?`), version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
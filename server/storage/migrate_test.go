@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := loadMigrations(migrationFiles)
+	assert.NoError(t, err)
+	assert.Equal(t, len(all), LatestSchemaVersion())
+	for _, m := range all {
+		assert.NotEmpty(t, statements(m.Up), "migration %d should change something", m.Version)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+
+	all, err := loadMigrations(fstest.MapFS{
+		"migrations/0002_b.up.sql":   file("CREATE TABLE b (id TEXT);"),
+		"migrations/0002_b.down.sql": file("DROP TABLE b;"),
+		"migrations/0001_a.up.sql":   file("CREATE TABLE a (id TEXT);"),
+		"migrations/0001_a.down.sql": file("DROP TABLE a;"),
+	})
+	if assert.NoError(t, err) && assert.Len(t, all, 2) {
+		assert.Equal(t, "a", all[0].Name)
+		assert.Equal(t, "DROP TABLE b;", all[1].Down)
+	}
+
+	_, err = loadMigrations(fstest.MapFS{
+		"migrations/0002_b.up.sql":   file("CREATE TABLE b (id TEXT);"),
+		"migrations/0002_b.down.sql": file("DROP TABLE b;"),
+	})
+	assert.EqualError(t, err, "migration 1 is missing")
+
+	_, err = loadMigrations(fstest.MapFS{"migrations/0001_a.up.sql": file("CREATE TABLE a (id TEXT);")})
+	assert.EqualError(t, err, "migration 1 needs both an up and a down file")
+
+	_, err = loadMigrations(fstest.MapFS{"migrations/a.sql": file("")})
+	assert.Error(t, err)
+}
+
+func TestStatements(t *testing.T) {
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n\tid TEXT\n);",
+		"CREATE INDEX a_id ON a (id);",
+	}, statements("-- two statements\nCREATE TABLE a (\n\tid TEXT\n);\nCREATE INDEX a_id ON a (id);\n"))
+	assert.Empty(t, statements("-- nothing to do\n"))
+	assert.Equal(t, []string{"UPDATE a SET id = ';'"}, statements("UPDATE a SET id = ';'"), "statements don't need a semicolon")
+}
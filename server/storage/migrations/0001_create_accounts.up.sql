@@ -0,0 +1,8 @@
+CREATE TABLE accounts (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	avatar     TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	created    BIGINT NOT NULL,
+	updated    BIGINT NOT NULL
+);
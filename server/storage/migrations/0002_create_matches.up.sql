@@ -0,0 +1,8 @@
+CREATE TABLE matches (
+	id        TEXT PRIMARY KEY,
+	room_id   TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	players   TEXT NOT NULL,
+	started   BIGINT NOT NULL,
+	ended     BIGINT NOT NULL
+);
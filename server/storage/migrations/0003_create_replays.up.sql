@@ -0,0 +1,6 @@
+CREATE TABLE replays (
+	match_id TEXT PRIMARY KEY,
+	seed     BIGINT NOT NULL,
+	events   TEXT NOT NULL,
+	created  BIGINT NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE matches ADD COLUMN ranked BOOLEAN NOT NULL DEFAULT FALSE;
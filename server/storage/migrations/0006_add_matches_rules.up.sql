@@ -0,0 +1 @@
+ALTER TABLE matches ADD COLUMN rules TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE matches ADD COLUMN outcome TEXT NOT NULL DEFAULT 'completed';
@@ -0,0 +1 @@
+DROP INDEX matches_room;
@@ -0,0 +1 @@
+CREATE INDEX matches_room ON matches (room_id, ended);
@@ -0,0 +1,6 @@
+CREATE TABLE match_players (
+	match_id   TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	ended      BIGINT NOT NULL,
+	PRIMARY KEY (match_id, account_id)
+);
@@ -0,0 +1 @@
+DROP INDEX match_players_account;
@@ -0,0 +1 @@
+CREATE INDEX match_players_account ON match_players (account_id, ended);
@@ -0,0 +1 @@
+DROP INDEX replays_created;
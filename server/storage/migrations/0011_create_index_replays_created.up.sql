@@ -0,0 +1 @@
+CREATE INDEX replays_created ON replays (created);
@@ -0,0 +1,10 @@
+CREATE TABLE ratings (
+	account_id TEXT NOT NULL,
+	game_type  TEXT NOT NULL,
+	rating     DOUBLE PRECISION NOT NULL,
+	deviation  DOUBLE PRECISION NOT NULL,
+	volatility DOUBLE PRECISION NOT NULL,
+	games      INTEGER NOT NULL,
+	updated    BIGINT NOT NULL,
+	PRIMARY KEY (account_id, game_type)
+);
@@ -0,0 +1 @@
+DROP TABLE rating_changes;
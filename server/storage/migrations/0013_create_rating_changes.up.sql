@@ -0,0 +1,10 @@
+CREATE TABLE rating_changes (
+	account_id    TEXT NOT NULL,
+	game_type     TEXT NOT NULL,
+	match_id      TEXT NOT NULL,
+	rating_before DOUBLE PRECISION NOT NULL,
+	rating_after  DOUBLE PRECISION NOT NULL,
+	deviation     DOUBLE PRECISION NOT NULL,
+	created       BIGINT NOT NULL,
+	PRIMARY KEY (account_id, game_type, match_id)
+);
@@ -0,0 +1 @@
+DROP INDEX rating_changes_created;
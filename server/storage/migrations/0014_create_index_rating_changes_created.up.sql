@@ -0,0 +1 @@
+CREATE INDEX rating_changes_created ON rating_changes (account_id, game_type, created);
@@ -0,0 +1,10 @@
+CREATE TABLE leaderboard (
+	season     INTEGER NOT NULL,
+	game_type  TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	score      DOUBLE PRECISION NOT NULL,
+	games      INTEGER NOT NULL,
+	updated    BIGINT NOT NULL,
+	PRIMARY KEY (season, game_type, account_id)
+);
@@ -0,0 +1 @@
+DROP INDEX leaderboard_score;
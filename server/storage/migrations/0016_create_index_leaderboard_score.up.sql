@@ -0,0 +1 @@
+CREATE INDEX leaderboard_score ON leaderboard (season, game_type, score DESC, updated, account_id);
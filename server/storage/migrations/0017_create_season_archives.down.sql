@@ -0,0 +1 @@
+DROP TABLE season_archives;
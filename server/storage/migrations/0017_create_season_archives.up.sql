@@ -0,0 +1,4 @@
+CREATE TABLE season_archives (
+	season   INTEGER PRIMARY KEY,
+	archived BIGINT NOT NULL
+);
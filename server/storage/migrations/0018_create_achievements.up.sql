@@ -0,0 +1,8 @@
+CREATE TABLE achievements (
+	account_id     TEXT NOT NULL,
+	achievement_id TEXT NOT NULL,
+	progress       INTEGER NOT NULL,
+	unlocked       BIGINT NOT NULL,
+	updated        BIGINT NOT NULL,
+	PRIMARY KEY (account_id, achievement_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN avatar_image BIGINT NOT NULL DEFAULT 0;
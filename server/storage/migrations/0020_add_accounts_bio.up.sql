@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN bio TEXT NOT NULL DEFAULT '';
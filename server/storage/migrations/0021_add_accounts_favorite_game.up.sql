@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN favorite_game TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN name_changed BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN name_key TEXT NOT NULL DEFAULT '';
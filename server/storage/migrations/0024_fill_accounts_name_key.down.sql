@@ -0,0 +1 @@
+-- nothing to undo, the column is dropped by the migration before this one
@@ -0,0 +1 @@
+UPDATE accounts SET name_key = lower(name);
@@ -0,0 +1 @@
+CREATE INDEX accounts_name ON accounts (name_key);
@@ -0,0 +1 @@
+DROP TABLE avatar_images;
@@ -0,0 +1,6 @@
+CREATE TABLE avatar_images (
+	account_id   TEXT PRIMARY KEY,
+	content_type TEXT NOT NULL,
+	data         BYTEA NOT NULL,
+	updated      BIGINT NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN invites TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+CREATE TABLE friendships (
+	account_id TEXT NOT NULL,
+	friend_id  TEXT NOT NULL,
+	accepted   BIGINT NOT NULL,
+	created    BIGINT NOT NULL,
+	PRIMARY KEY (account_id, friend_id)
+);
@@ -0,0 +1 @@
+DROP INDEX friendships_friend;
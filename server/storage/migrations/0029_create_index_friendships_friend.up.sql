@@ -0,0 +1 @@
+CREATE INDEX friendships_friend ON friendships (friend_id);
@@ -0,0 +1,6 @@
+CREATE TABLE blocks (
+	account_id TEXT NOT NULL,
+	blocked_id TEXT NOT NULL,
+	created    BIGINT NOT NULL,
+	PRIMARY KEY (account_id, blocked_id)
+);
@@ -0,0 +1 @@
+CREATE INDEX blocks_blocked ON blocks (blocked_id);
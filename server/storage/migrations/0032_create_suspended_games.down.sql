@@ -0,0 +1 @@
+DROP TABLE suspended_games;
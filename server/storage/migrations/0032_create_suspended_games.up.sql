@@ -0,0 +1,9 @@
+CREATE TABLE suspended_games (
+	id        TEXT PRIMARY KEY,
+	room_name TEXT NOT NULL,
+	game_type TEXT NOT NULL,
+	players   TEXT NOT NULL,
+	state     TEXT NOT NULL,
+	started   BIGINT NOT NULL,
+	suspended BIGINT NOT NULL
+);
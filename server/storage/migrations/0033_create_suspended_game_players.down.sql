@@ -0,0 +1 @@
+DROP TABLE suspended_game_players;
@@ -0,0 +1,6 @@
+CREATE TABLE suspended_game_players (
+	game_id    TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	suspended  BIGINT NOT NULL,
+	PRIMARY KEY (game_id, account_id)
+);
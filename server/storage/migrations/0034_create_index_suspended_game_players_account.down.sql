@@ -0,0 +1 @@
+DROP INDEX suspended_game_players_account;
@@ -0,0 +1 @@
+CREATE INDEX suspended_game_players_account ON suspended_game_players (account_id, suspended);
@@ -0,0 +1,11 @@
+CREATE TABLE player_stats (
+	account_id     TEXT NOT NULL,
+	game_type      TEXT NOT NULL,
+	games          INTEGER NOT NULL,
+	wins           INTEGER NOT NULL,
+	total_score    BIGINT NOT NULL,
+	streak         INTEGER NOT NULL,
+	longest_streak INTEGER NOT NULL,
+	updated        BIGINT NOT NULL,
+	PRIMARY KEY (account_id, game_type)
+);
@@ -0,0 +1,2 @@
+DROP INDEX opponent_stats_games;
+DROP TABLE opponent_stats;
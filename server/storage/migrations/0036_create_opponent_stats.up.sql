@@ -0,0 +1,11 @@
+CREATE TABLE opponent_stats (
+	account_id  TEXT NOT NULL,
+	opponent_id TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	games       INTEGER NOT NULL,
+	wins        INTEGER NOT NULL,
+	updated     BIGINT NOT NULL,
+	PRIMARY KEY (account_id, opponent_id)
+);
+
+CREATE INDEX opponent_stats_games ON opponent_stats (account_id, games DESC);
@@ -0,0 +1,2 @@
+DROP INDEX challenge_results_score;
+DROP TABLE challenge_results;
@@ -0,0 +1,12 @@
+CREATE TABLE challenge_results (
+	day        TEXT NOT NULL,
+	game_type  TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	score      INTEGER NOT NULL,
+	started    BIGINT NOT NULL,
+	completed  BIGINT NOT NULL,
+	PRIMARY KEY (day, game_type, account_id)
+);
+
+CREATE INDEX challenge_results_score ON challenge_results (day, game_type, score DESC, completed);
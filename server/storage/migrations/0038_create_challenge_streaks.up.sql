@@ -0,0 +1,8 @@
+CREATE TABLE challenge_streaks (
+	account_id     TEXT NOT NULL,
+	game_type      TEXT NOT NULL,
+	streak         INTEGER NOT NULL,
+	longest_streak INTEGER NOT NULL,
+	last_day       TEXT NOT NULL,
+	PRIMARY KEY (account_id, game_type)
+);
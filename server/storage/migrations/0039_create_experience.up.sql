@@ -0,0 +1,5 @@
+CREATE TABLE experience (
+	account_id TEXT PRIMARY KEY,
+	xp         INTEGER NOT NULL,
+	updated    BIGINT NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE quest_progress;
@@ -0,0 +1,9 @@
+CREATE TABLE quest_progress (
+	account_id TEXT NOT NULL,
+	quest_id   TEXT NOT NULL,
+	period     TEXT NOT NULL,
+	progress   INTEGER NOT NULL,
+	completed  BIGINT NOT NULL,
+	updated    BIGINT NOT NULL,
+	PRIMARY KEY (account_id, quest_id, period)
+);
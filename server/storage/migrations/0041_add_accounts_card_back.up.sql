@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN card_back TEXT NOT NULL DEFAULT '';
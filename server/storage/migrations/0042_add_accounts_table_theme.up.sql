@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN table_theme TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP TABLE cosmetic_unlocks;
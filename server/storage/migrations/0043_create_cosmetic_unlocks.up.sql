@@ -0,0 +1,6 @@
+CREATE TABLE cosmetic_unlocks (
+	account_id  TEXT NOT NULL,
+	cosmetic_id TEXT NOT NULL,
+	unlocked    BIGINT NOT NULL,
+	PRIMARY KEY (account_id, cosmetic_id)
+);
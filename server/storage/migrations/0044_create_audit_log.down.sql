@@ -0,0 +1,2 @@
+DROP INDEX audit_log_created;
+DROP TABLE audit_log;
@@ -0,0 +1,13 @@
+CREATE TABLE audit_log (
+	id          TEXT PRIMARY KEY,
+	action      TEXT NOT NULL,
+	actor_id    TEXT NOT NULL,
+	actor_name  TEXT NOT NULL,
+	target_id   TEXT NOT NULL,
+	target_name TEXT NOT NULL,
+	room_id     TEXT NOT NULL,
+	reason      TEXT NOT NULL,
+	created     BIGINT NOT NULL
+);
+
+CREATE INDEX audit_log_created ON audit_log (created DESC, id);
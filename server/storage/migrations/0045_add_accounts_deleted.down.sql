@@ -0,0 +1,2 @@
+DROP INDEX accounts_deleted;
+ALTER TABLE accounts DROP COLUMN deleted;
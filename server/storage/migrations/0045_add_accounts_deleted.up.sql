@@ -0,0 +1,2 @@
+ALTER TABLE accounts ADD COLUMN deleted BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX accounts_deleted ON accounts (deleted) WHERE deleted > 0;
@@ -0,0 +1,2 @@
+DROP INDEX room_presets_account;
+DROP TABLE room_presets;
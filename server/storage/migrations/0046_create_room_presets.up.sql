@@ -0,0 +1,11 @@
+CREATE TABLE room_presets (
+	id         TEXT PRIMARY KEY,
+	account_id TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	code       TEXT NOT NULL UNIQUE,
+	settings   TEXT NOT NULL,
+	created    BIGINT NOT NULL,
+	updated    BIGINT NOT NULL
+);
+
+CREATE INDEX room_presets_account ON room_presets (account_id, created, id);
@@ -0,0 +1,2 @@
+DROP INDEX reports_status;
+DROP TABLE reports;
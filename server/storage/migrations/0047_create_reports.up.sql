@@ -0,0 +1,20 @@
+CREATE TABLE reports (
+	id            TEXT PRIMARY KEY,
+	reason        TEXT NOT NULL,
+	reporter_id   TEXT NOT NULL,
+	reporter_name TEXT NOT NULL,
+	target_id     TEXT NOT NULL,
+	target_name   TEXT NOT NULL,
+	room_id       TEXT NOT NULL,
+	game_type     TEXT NOT NULL,
+	details       TEXT NOT NULL,
+	chat          TEXT NOT NULL,
+	events        TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	resolution    TEXT NOT NULL,
+	resolver_id   TEXT NOT NULL,
+	created       BIGINT NOT NULL,
+	resolved      BIGINT NOT NULL
+);
+
+CREATE INDEX reports_status ON reports (status, created DESC, id);
@@ -0,0 +1,2 @@
+DROP INDEX ratings_updated;
+ALTER TABLE ratings DROP COLUMN decayed;
@@ -0,0 +1,2 @@
+ALTER TABLE ratings ADD COLUMN decayed BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX ratings_updated ON ratings (updated);
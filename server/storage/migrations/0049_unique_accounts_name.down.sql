@@ -0,0 +1,2 @@
+DROP INDEX accounts_name;
+CREATE INDEX accounts_name ON accounts (name_key);
@@ -0,0 +1,6 @@
+UPDATE accounts SET name = name || '_' || id, name_key = name_key || '_' || lower(id)
+	WHERE name_key <> '' AND EXISTS (
+		SELECT 1 FROM accounts older WHERE older.name_key = accounts.name_key
+			AND (older.created < accounts.created OR (older.created = accounts.created AND older.id < accounts.id)));
+DROP INDEX accounts_name;
+CREATE UNIQUE INDEX accounts_name ON accounts (name_key) WHERE name_key <> '';
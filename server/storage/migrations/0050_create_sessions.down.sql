@@ -0,0 +1,30 @@
+CREATE TABLE accounts_old (
+	id            TEXT PRIMARY KEY,
+	name          TEXT NOT NULL,
+	avatar        TEXT NOT NULL,
+	token_hash    TEXT NOT NULL UNIQUE,
+	created       BIGINT NOT NULL,
+	updated       BIGINT NOT NULL,
+	avatar_image  BIGINT NOT NULL DEFAULT 0,
+	bio           TEXT NOT NULL DEFAULT '',
+	favorite_game TEXT NOT NULL DEFAULT '',
+	name_changed  BIGINT NOT NULL DEFAULT 0,
+	name_key      TEXT NOT NULL DEFAULT '',
+	invites       TEXT NOT NULL DEFAULT '',
+	card_back     TEXT NOT NULL DEFAULT '',
+	table_theme   TEXT NOT NULL DEFAULT '',
+	deleted       BIGINT NOT NULL DEFAULT 0
+);
+
+-- accounts keep the token of their oldest session, and accounts without one get a token hash no token has
+INSERT INTO accounts_old (id, name, avatar, token_hash, created, updated, avatar_image, bio, favorite_game, name_changed, name_key, invites, card_back, table_theme, deleted)
+	SELECT id, name, avatar,
+		COALESCE((SELECT s.token_hash FROM sessions s WHERE s.account_id = accounts.id ORDER BY s.created, s.id LIMIT 1), 'revoked_' || id),
+		created, updated, avatar_image, bio, favorite_game, name_changed, name_key, invites, card_back, table_theme, deleted
+	FROM accounts;
+
+DROP TABLE accounts;
+ALTER TABLE accounts_old RENAME TO accounts;
+CREATE UNIQUE INDEX accounts_name ON accounts (name_key) WHERE name_key <> '';
+CREATE INDEX accounts_deleted ON accounts (deleted) WHERE deleted > 0;
+DROP TABLE sessions;
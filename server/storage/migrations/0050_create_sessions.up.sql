@@ -0,0 +1,39 @@
+CREATE TABLE sessions (
+	id         TEXT PRIMARY KEY,
+	account_id TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	user_agent TEXT NOT NULL,
+	created    BIGINT NOT NULL
+);
+
+CREATE INDEX sessions_account ON sessions (account_id);
+
+-- the token every account had becomes its first session
+INSERT INTO sessions (id, account_id, token_hash, user_agent, created)
+	SELECT 's_' || id, id, token_hash, '', created FROM accounts;
+
+-- sqlite can't drop a unique column, so the accounts are copied into a table without it
+CREATE TABLE accounts_new (
+	id            TEXT PRIMARY KEY,
+	name          TEXT NOT NULL,
+	avatar        TEXT NOT NULL,
+	avatar_image  BIGINT NOT NULL DEFAULT 0,
+	bio           TEXT NOT NULL DEFAULT '',
+	favorite_game TEXT NOT NULL DEFAULT '',
+	invites       TEXT NOT NULL DEFAULT '',
+	card_back     TEXT NOT NULL DEFAULT '',
+	table_theme   TEXT NOT NULL DEFAULT '',
+	name_changed  BIGINT NOT NULL DEFAULT 0,
+	name_key      TEXT NOT NULL DEFAULT '',
+	deleted       BIGINT NOT NULL DEFAULT 0,
+	created       BIGINT NOT NULL,
+	updated       BIGINT NOT NULL
+);
+
+INSERT INTO accounts_new (id, name, avatar, avatar_image, bio, favorite_game, invites, card_back, table_theme, name_changed, name_key, deleted, created, updated)
+	SELECT id, name, avatar, avatar_image, bio, favorite_game, invites, card_back, table_theme, name_changed, name_key, deleted, created, updated FROM accounts;
+
+DROP TABLE accounts;
+ALTER TABLE accounts_new RENAME TO accounts;
+CREATE UNIQUE INDEX accounts_name ON accounts (name_key) WHERE name_key <> '';
+CREATE INDEX accounts_deleted ON accounts (deleted) WHERE deleted > 0;
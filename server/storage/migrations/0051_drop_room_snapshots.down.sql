@@ -0,0 +1,5 @@
+CREATE TABLE room_snapshots (
+	room_id TEXT PRIMARY KEY,
+	data    TEXT NOT NULL,
+	updated BIGINT NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP TABLE email_tokens;
+DROP INDEX accounts_email;
+ALTER TABLE accounts DROP COLUMN password_hash;
+ALTER TABLE accounts DROP COLUMN email_verified;
+ALTER TABLE accounts DROP COLUMN email;
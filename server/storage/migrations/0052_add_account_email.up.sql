@@ -0,0 +1,16 @@
+ALTER TABLE accounts ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN email_verified BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX accounts_email ON accounts (email) WHERE email <> '';
+
+CREATE TABLE email_tokens (
+	token_hash TEXT PRIMARY KEY,
+	account_id TEXT NOT NULL,
+	purpose    TEXT NOT NULL,
+	email      TEXT NOT NULL,
+	created    BIGINT NOT NULL,
+	expires    BIGINT NOT NULL
+);
+
+CREATE INDEX email_tokens_account ON email_tokens (account_id, purpose);
@@ -0,0 +1,4 @@
+ALTER TABLE accounts DROP COLUMN recovery_codes;
+ALTER TABLE accounts DROP COLUMN totp_step;
+ALTER TABLE accounts DROP COLUMN totp_enabled;
+ALTER TABLE accounts DROP COLUMN totp_secret;
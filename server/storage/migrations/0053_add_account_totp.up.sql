@@ -0,0 +1,4 @@
+ALTER TABLE accounts ADD COLUMN totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN totp_enabled BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN totp_step BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN recovery_codes TEXT NOT NULL DEFAULT 'null';
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// PruneReplays deletes the replays in s that are older than retention.
+func PruneReplays(s Store, retention time.Duration) (int, error) {
+	return s.DeleteReplaysBefore(time.Now().Add(-retention).UnixMilli())
+}
+
+// KeepReplaysFor prunes replays older than retention from s every interval, until stop is called.
+func KeepReplaysFor(s Store, retention, interval time.Duration) (stop func()) {
+	return repeatLeading(interval, func() {
+		if n, err := PruneReplays(s, retention); err != nil {
+			slog.Error("failed to prune replays", "err", err)
+		} else if n > 0 {
+			slog.Info("pruned replays", "count", n)
+		}
+	})
+}
+
+// PurgeDeletedAccounts deletes the accounts in s whose owners deleted them more than window
+// ago, along with everything stored about them.
+func PurgeDeletedAccounts(s Store, window time.Duration) (int, error) {
+	ids, err := s.DeletedAccounts(time.Now().Add(-window).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	for i, id := range ids {
+		if err := s.DeleteAccount(id); err != nil && !errors.Is(err, ErrNotFound) {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// KeepDeletedAccountsFor purges accounts deleted more than window ago from s every interval,
+// until stop is called. Until then, their owners can recover them.
+func KeepDeletedAccountsFor(s Store, window, interval time.Duration) (stop func()) {
+	return repeatLeading(interval, func() {
+		if n, err := PurgeDeletedAccounts(s, window); err != nil {
+			slog.Error("failed to purge deleted accounts", "err", err)
+		} else if n > 0 {
+			slog.Info("purged deleted accounts", "count", n)
+		}
+	})
+}
+
+// repeatLeading is repeat for the jobs only the Leader runs, which other servers skip.
+func repeatLeading(interval time.Duration, f func()) (stop func()) {
+	return repeat(interval, func() {
+		if Leader() {
+			f()
+		}
+	})
+}
+
+// repeat calls f right away, then every interval until stop is called.
+func repeat(interval time.Duration, f func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			f()
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,1546 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the flavour of SQL spoken by a database.
+type Dialect int
+
+const (
+	DialectSQLite Dialect = iota
+	DialectPostgres
+)
+
+// rebind rewrites the ? placeholders in a query into the form the dialect expects.
+func (d Dialect) rebind(query string) string {
+	if d != DialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// uniqueViolation reports whether err is the database refusing a duplicate in a unique index,
+// which SQLite reports by column and Postgres by index name. The drivers are only built in
+// with tags, so their errors are told apart by message.
+func uniqueViolation(err error, index, column string) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "UNIQUE constraint failed: "+column) ||
+		strings.Contains(message, `violates unique constraint "`+index+`"`)
+}
+
+// SQL is a store backed by a database/sql database.
+type SQL struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// OpenSQL opens a database with a registered database/sql driver and brings its schema up to date,
+// unless AutoMigrate is off. It fails if the schema is newer than the server, or is out of date
+// and can't be migrated.
+// The driver is only available if the server is built with the matching build tag, like "sqlite".
+func OpenSQL(driver, dsn string, dialect Dialect) (*SQL, error) {
+	s, err := OpenSQLUnchecked(driver, dsn, dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSchema(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenSQLUnchecked opens a database like OpenSQL, whatever version its schema is at.
+// It is meant for tools that migrate the schema by hand.
+func OpenSQLUnchecked(driver, dsn string, dialect Dialect) (*SQL, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dialect == DialectSQLite {
+		// sqlite only allows one writer, and every connection to ":memory:" is a separate database
+		db.SetMaxOpenConns(1)
+	}
+	return &SQL{db: db, dialect: dialect}, nil
+}
+
+func (s *SQL) exec(query string, args ...any) (sql.Result, error) {
+	return s.db.Exec(s.dialect.rebind(query), args...)
+}
+
+func (s *SQL) queryRow(query string, args ...any) *sql.Row {
+	return s.db.QueryRow(s.dialect.rebind(query), args...)
+}
+
+func (s *SQL) query(query string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(s.dialect.rebind(query), args...)
+}
+
+// notFound turns sql.ErrNoRows into ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+const accountColumns = `id, name, avatar, avatar_image, bio, favorite_game, invites, card_back, table_theme, name_changed, deleted, created, updated, email, email_verified, password_hash, totp_secret, totp_enabled, totp_step, recovery_codes`
+
+func (s *SQL) scanAccount(row *sql.Row) (*Account, error) {
+	var a Account
+	var avatar, recoveryCodes string
+	err := row.Scan(&a.Id, &a.Name, &avatar, &a.AvatarImage, &a.Bio, &a.FavoriteGame, &a.Invites, &a.CardBack, &a.TableTheme, &a.NameChanged, &a.Deleted, &a.Created, &a.Updated,
+		&a.Email, &a.EmailVerified, &a.PasswordHash, &a.TotpSecret, &a.TotpEnabled, &a.TotpStep, &recoveryCodes)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := json.Unmarshal([]byte(avatar), &a.Avatar); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(recoveryCodes), &a.RecoveryCodes); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *SQL) Account(id string) (*Account, error) {
+	return s.scanAccount(s.queryRow(`SELECT `+accountColumns+` FROM accounts WHERE id = ?`, id))
+}
+
+func (s *SQL) AccountByName(name string) (*Account, error) {
+	return s.scanAccount(s.queryRow(`SELECT `+accountColumns+` FROM accounts WHERE name_key = ?`, nameKey(name)))
+}
+
+func (s *SQL) AccountByEmail(email string) (*Account, error) {
+	return s.scanAccount(s.queryRow(`SELECT `+accountColumns+` FROM accounts WHERE email = ? AND email <> ''`, strings.ToLower(email)))
+}
+
+func (s *SQL) SaveAccount(a *Account) error {
+	avatar, err := json.Marshal(a.Avatar)
+	if err != nil {
+		return err
+	}
+	recoveryCodes, err := json.Marshal(a.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(`INSERT INTO accounts (`+accountColumns+`, name_key) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, avatar = excluded.avatar, avatar_image = excluded.avatar_image,
+		bio = excluded.bio, favorite_game = excluded.favorite_game, invites = excluded.invites,
+		card_back = excluded.card_back, table_theme = excluded.table_theme,
+		name_changed = excluded.name_changed, deleted = excluded.deleted, updated = excluded.updated,
+		email = excluded.email, email_verified = excluded.email_verified, password_hash = excluded.password_hash,
+		totp_secret = excluded.totp_secret, totp_enabled = excluded.totp_enabled, totp_step = excluded.totp_step,
+		recovery_codes = excluded.recovery_codes, name_key = excluded.name_key`,
+		a.Id, a.Name, string(avatar), a.AvatarImage, a.Bio, a.FavoriteGame, a.Invites, a.CardBack, a.TableTheme, a.NameChanged, a.Deleted, a.Created, a.Updated,
+		strings.ToLower(a.Email), a.EmailVerified, a.PasswordHash, a.TotpSecret, a.TotpEnabled, a.TotpStep, string(recoveryCodes), nameKey(a.Name))
+	if uniqueViolation(err, "accounts_name", "accounts.name_key") {
+		return ErrNameTaken
+	}
+	if uniqueViolation(err, "accounts_email", "accounts.email") {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func (s *SQL) DeletedAccounts(before int64) ([]string, error) {
+	rows, err := s.query(`SELECT id FROM accounts WHERE deleted > 0 AND deleted < ? ORDER BY deleted, id`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const sessionColumns = `id, account_id, token_hash, user_agent, created`
+
+// scanSession reads a row selected with sessionColumns.
+func scanSession(row interface{ Scan(...any) error }) (*Session, error) {
+	var session Session
+	err := row.Scan(&session.Id, &session.AccountId, &session.TokenHash, &session.UserAgent, &session.Created)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (s *SQL) SessionByToken(tokenHash string) (*Session, error) {
+	return scanSession(s.queryRow(`SELECT `+sessionColumns+` FROM sessions WHERE token_hash = ?`, tokenHash))
+}
+
+func (s *SQL) Sessions(accountId string) ([]*Session, error) {
+	rows, err := s.query(`SELECT `+sessionColumns+` FROM sessions WHERE account_id = ? ORDER BY created, id`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQL) SaveSession(session *Session) error {
+	_, err := s.exec(`INSERT INTO sessions (`+sessionColumns+`) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET token_hash = excluded.token_hash, user_agent = excluded.user_agent`,
+		session.Id, session.AccountId, session.TokenHash, session.UserAgent, session.Created)
+	return err
+}
+
+func (s *SQL) DeleteSession(accountId, id string) error {
+	res, err := s.exec(`DELETE FROM sessions WHERE account_id = ? AND id = ?`, accountId, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQL) DeleteSessions(accountId string) error {
+	_, err := s.exec(`DELETE FROM sessions WHERE account_id = ?`, accountId)
+	return err
+}
+
+func (s *SQL) EmailToken(tokenHash string) (*EmailToken, error) {
+	var t EmailToken
+	err := s.queryRow(`SELECT token_hash, account_id, purpose, email, created, expires FROM email_tokens WHERE token_hash = ?`, tokenHash).
+		Scan(&t.TokenHash, &t.AccountId, &t.Purpose, &t.Email, &t.Created, &t.Expires)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &t, nil
+}
+
+func (s *SQL) SaveEmailToken(t *EmailToken) error {
+	_, err := s.exec(`INSERT INTO email_tokens (token_hash, account_id, purpose, email, created, expires) VALUES (?, ?, ?, ?, ?, ?)`,
+		t.TokenHash, t.AccountId, t.Purpose, t.Email, t.Created, t.Expires)
+	return err
+}
+
+func (s *SQL) DeleteEmailTokens(accountId string, purpose EmailPurpose) error {
+	_, err := s.exec(`DELETE FROM email_tokens WHERE account_id = ? AND purpose = ?`, accountId, purpose)
+	return err
+}
+
+func (s *SQL) DeleteAccount(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(s.dialect.rebind(`DELETE FROM accounts WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM avatar_images WHERE account_id = ?`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM friendships WHERE account_id = ? OR friend_id = ?`), id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM blocks WHERE account_id = ? OR blocked_id = ?`), id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM player_stats WHERE account_id = ?`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM opponent_stats WHERE account_id = ? OR opponent_id = ?`), id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM cosmetic_unlocks WHERE account_id = ?`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM experience WHERE account_id = ?`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM quest_progress WHERE account_id = ?`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM challenge_results WHERE account_id = ?`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM challenge_streaks WHERE account_id = ?`), id); err != nil {
+		return err
+	}
+	// a suspended game can't be resumed without all of its players
+	_, err = tx.Exec(s.dialect.rebind(`DELETE FROM suspended_games
+		WHERE id IN (SELECT game_id FROM suspended_game_players WHERE account_id = ?)`), id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM suspended_game_players WHERE game_id NOT IN (SELECT id FROM suspended_games)`)
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{"sessions", "email_tokens", "room_presets", "ratings", "rating_changes", "leaderboard", "achievements"} {
+		if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM `+table+` WHERE account_id = ?`), id); err != nil {
+			return err
+		}
+	}
+	if err := s.anonymizeMatches(tx, id); err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.rebind(`UPDATE audit_log SET actor_name = ? WHERE actor_id = ?`), DeletedName, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.rebind(`UPDATE audit_log SET target_name = ? WHERE target_id = ?`), DeletedName, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.rebind(`UPDATE reports SET reporter_name = ? WHERE reporter_id = ?`), DeletedName, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.rebind(`UPDATE reports SET target_name = ? WHERE target_id = ?`), DeletedName, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// anonymizeMatches removes a deleted account from the matches it played.
+func (s *SQL) anonymizeMatches(tx *sql.Tx, accountId string) error {
+	rows, err := tx.Query(s.dialect.rebind(`SELECT m.id, m.players FROM matches m
+		JOIN match_players mp ON mp.match_id = m.id WHERE mp.account_id = ?`), accountId)
+	if err != nil {
+		return err
+	}
+	players := map[string][]MatchPlayer{}
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			rows.Close()
+			return err
+		}
+		var p []MatchPlayer
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			rows.Close()
+			return err
+		}
+		players[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, p := range players {
+		anonymize(p, accountId)
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(s.dialect.rebind(`UPDATE matches SET players = ? WHERE id = ?`), string(data), id); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(s.dialect.rebind(`DELETE FROM match_players WHERE account_id = ?`), accountId)
+	return err
+}
+
+func (s *SQL) Friendship(a, b string) (*Friendship, error) {
+	var f Friendship
+	err := s.queryRow(`SELECT account_id, friend_id, accepted, created FROM friendships
+		WHERE (account_id = ? AND friend_id = ?) OR (account_id = ? AND friend_id = ?)`, a, b, b, a).
+		Scan(&f.AccountId, &f.FriendId, &f.Accepted, &f.Created)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &f, nil
+}
+
+func (s *SQL) Friendships(accountId string) ([]*Friendship, error) {
+	rows, err := s.query(`SELECT account_id, friend_id, accepted, created FROM friendships
+		WHERE account_id = ? OR friend_id = ? ORDER BY created`, accountId, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	friendships := []*Friendship{}
+	for rows.Next() {
+		var f Friendship
+		if err := rows.Scan(&f.AccountId, &f.FriendId, &f.Accepted, &f.Created); err != nil {
+			return nil, err
+		}
+		friendships = append(friendships, &f)
+	}
+	return friendships, rows.Err()
+}
+
+func (s *SQL) SaveFriendship(f *Friendship) error {
+	_, err := s.exec(`INSERT INTO friendships (account_id, friend_id, accepted, created) VALUES (?, ?, ?, ?)
+		ON CONFLICT (account_id, friend_id) DO UPDATE SET accepted = excluded.accepted`,
+		f.AccountId, f.FriendId, f.Accepted, f.Created)
+	return err
+}
+
+func (s *SQL) DeleteFriendship(a, b string) error {
+	res, err := s.exec(`DELETE FROM friendships WHERE (account_id = ? AND friend_id = ?) OR (account_id = ? AND friend_id = ?)`, a, b, b, a)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQL) Blocks(accountId string) ([]*Block, error) {
+	rows, err := s.query(`SELECT account_id, blocked_id, created FROM blocks WHERE account_id = ? ORDER BY created`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []*Block{}
+	for rows.Next() {
+		var b Block
+		if err := rows.Scan(&b.AccountId, &b.BlockedId, &b.Created); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &b)
+	}
+	return blocks, rows.Err()
+}
+
+func (s *SQL) Blocked(accountId, blockedId string) (bool, error) {
+	var n int
+	err := s.queryRow(`SELECT COUNT(*) FROM blocks WHERE account_id = ? AND blocked_id = ?`, accountId, blockedId).Scan(&n)
+	return n > 0, err
+}
+
+func (s *SQL) SaveBlock(b *Block) error {
+	_, err := s.exec(`INSERT INTO blocks (account_id, blocked_id, created) VALUES (?, ?, ?)
+		ON CONFLICT (account_id, blocked_id) DO NOTHING`, b.AccountId, b.BlockedId, b.Created)
+	return err
+}
+
+func (s *SQL) DeleteBlock(accountId, blockedId string) error {
+	res, err := s.exec(`DELETE FROM blocks WHERE account_id = ? AND blocked_id = ?`, accountId, blockedId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQL) AvatarImage(accountId string) (*AvatarImage, error) {
+	var img AvatarImage
+	err := s.queryRow(`SELECT account_id, content_type, data, updated FROM avatar_images WHERE account_id = ?`, accountId).
+		Scan(&img.AccountId, &img.ContentType, &img.Data, &img.Updated)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &img, nil
+}
+
+func (s *SQL) SaveAvatarImage(img *AvatarImage) error {
+	_, err := s.exec(`INSERT INTO avatar_images (account_id, content_type, data, updated) VALUES (?, ?, ?, ?)
+		ON CONFLICT (account_id) DO UPDATE SET content_type = excluded.content_type, data = excluded.data,
+		updated = excluded.updated`, img.AccountId, img.ContentType, img.Data, img.Updated)
+	return err
+}
+
+func (s *SQL) DeleteAvatarImage(accountId string) error {
+	res, err := s.exec(`DELETE FROM avatar_images WHERE account_id = ?`, accountId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const matchColumns = `m.id, m.room_id, m.game_type, m.ranked, m.rules, m.outcome, m.players, m.started, m.ended`
+
+// scanMatch reads a row selected with matchColumns.
+func scanMatch(row interface{ Scan(...any) error }) (*Match, error) {
+	var m Match
+	var rules, players string
+	err := row.Scan(&m.Id, &m.RoomId, &m.GameType, &m.Ranked, &rules, &m.Outcome, &players, &m.Started, &m.Ended)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	m.Rules = json.RawMessage(rules)
+	if err := json.Unmarshal([]byte(players), &m.Players); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *SQL) Match(id string) (*Match, error) {
+	return scanMatch(s.queryRow(`SELECT `+matchColumns+` FROM matches m WHERE m.id = ?`, id))
+}
+
+func (s *SQL) Matches(q MatchQuery) ([]*Match, error) {
+	query := `SELECT ` + matchColumns + ` FROM matches m`
+	args := []any{}
+	if q.AccountId != "" {
+		query += ` JOIN match_players mp ON mp.match_id = m.id AND mp.account_id = ?`
+		args = append(args, q.AccountId)
+	}
+	query += ` WHERE 1 = 1`
+	if q.RoomId != "" {
+		query += ` AND m.room_id = ?`
+		args = append(args, q.RoomId)
+	}
+	if q.Before != 0 && q.BeforeId != "" {
+		query += ` AND (m.ended < ? OR m.ended = ? AND m.id > ?)`
+		args = append(args, q.Before, q.Before, q.BeforeId)
+	} else if q.Before != 0 {
+		query += ` AND m.ended < ?`
+		args = append(args, q.Before)
+	}
+	query += ` ORDER BY m.ended DESC, m.id`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []*Match{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+func (s *SQL) SaveMatch(m *Match) error {
+	players, err := json.Marshal(m.Players)
+	if err != nil {
+		return err
+	}
+	rules := string(m.Rules)
+	if rules == "" {
+		rules = "{}"
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.dialect.rebind(`INSERT INTO matches (id, room_id, game_type, ranked, rules, outcome, players, started, ended)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET room_id = excluded.room_id, game_type = excluded.game_type,
+		ranked = excluded.ranked, rules = excluded.rules, outcome = excluded.outcome,
+		players = excluded.players, started = excluded.started, ended = excluded.ended`),
+		m.Id, m.RoomId, m.GameType, m.Ranked, rules, string(m.Outcome), string(players), m.Started, m.Ended)
+	if err != nil {
+		return err
+	}
+
+	// match_players indexes matches by the accounts that played in them
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM match_players WHERE match_id = ?`), m.Id); err != nil {
+		return err
+	}
+	for _, p := range m.Players {
+		if p.AccountId == "" {
+			continue
+		}
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO match_players (match_id, account_id, ended) VALUES (?, ?, ?)
+			ON CONFLICT (match_id, account_id) DO NOTHING`), m.Id, p.AccountId, m.Ended)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) Replay(matchId string) (*Replay, error) {
+	var r Replay
+	var events string
+	err := s.queryRow(`SELECT match_id, seed, events, created FROM replays WHERE match_id = ?`, matchId).
+		Scan(&r.MatchId, &r.Seed, &events, &r.Created)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	r.Events = json.RawMessage(events)
+	return &r, nil
+}
+
+func (s *SQL) Replays(q ReplayQuery) ([]*Replay, error) {
+	query := `SELECT match_id, seed, created FROM replays`
+	args := []any{}
+	if q.Before != 0 && q.BeforeId != "" {
+		query += ` WHERE (created < ? OR created = ? AND match_id > ?)`
+		args = append(args, q.Before, q.Before, q.BeforeId)
+	} else if q.Before != 0 {
+		query += ` WHERE created < ?`
+		args = append(args, q.Before)
+	}
+	query += ` ORDER BY created DESC, match_id`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replays := []*Replay{}
+	for rows.Next() {
+		var r Replay
+		if err := rows.Scan(&r.MatchId, &r.Seed, &r.Created); err != nil {
+			return nil, err
+		}
+		replays = append(replays, &r)
+	}
+	return replays, rows.Err()
+}
+
+func (s *SQL) DeleteReplaysBefore(created int64) (int, error) {
+	res, err := s.exec(`DELETE FROM replays WHERE created < ?`, created)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQL) SaveReplay(r *Replay) error {
+	_, err := s.exec(`INSERT INTO replays (match_id, seed, events, created) VALUES (?, ?, ?, ?)
+		ON CONFLICT (match_id) DO UPDATE SET seed = excluded.seed, events = excluded.events, created = excluded.created`,
+		r.MatchId, r.Seed, string(r.Events), r.Created)
+	return err
+}
+
+const ratingColumns = `account_id, game_type, rating, deviation, volatility, games, updated, decayed`
+
+func scanRating(row interface{ Scan(...any) error }) (*PlayerRating, error) {
+	var r PlayerRating
+	err := row.Scan(&r.AccountId, &r.GameType, &r.Rating, &r.Deviation, &r.Volatility, &r.Games, &r.Updated, &r.Decayed)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &r, nil
+}
+
+func (s *SQL) Rating(accountId, gameType string) (*PlayerRating, error) {
+	return scanRating(s.queryRow(`SELECT `+ratingColumns+` FROM ratings WHERE account_id = ? AND game_type = ?`, accountId, gameType))
+}
+
+func (s *SQL) Ratings(accountId string) ([]*PlayerRating, error) {
+	return s.queryRatings(`SELECT `+ratingColumns+` FROM ratings WHERE account_id = ? ORDER BY game_type`, accountId)
+}
+
+func (s *SQL) InactiveRatings(before int64) ([]*PlayerRating, error) {
+	return s.queryRatings(`SELECT `+ratingColumns+` FROM ratings WHERE updated < ? ORDER BY updated, account_id, game_type`, before)
+}
+
+func (s *SQL) queryRatings(query string, args ...any) ([]*PlayerRating, error) {
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []*PlayerRating{}
+	for rows.Next() {
+		r, err := scanRating(rows)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
+
+func (s *SQL) RatingHistory(q RatingQuery) ([]*RatingChange, error) {
+	query := `SELECT account_id, game_type, match_id, rating_before, rating_after, deviation, created
+		FROM rating_changes WHERE account_id = ? AND game_type = ?`
+	args := []any{q.AccountId, q.GameType}
+	if q.Before != 0 && q.BeforeId != "" {
+		query += ` AND (created < ? OR created = ? AND match_id > ?)`
+		args = append(args, q.Before, q.Before, q.BeforeId)
+	} else if q.Before != 0 {
+		query += ` AND created < ?`
+		args = append(args, q.Before)
+	}
+	query += ` ORDER BY created DESC, match_id`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*RatingChange{}
+	for rows.Next() {
+		var c RatingChange
+		if err := rows.Scan(&c.AccountId, &c.GameType, &c.MatchId, &c.Before, &c.After, &c.Deviation, &c.Created); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &c)
+	}
+	return changes, rows.Err()
+}
+
+func (s *SQL) SaveRatings(ratings []*PlayerRating, changes []*RatingChange) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range ratings {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO ratings (`+ratingColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (account_id, game_type) DO UPDATE SET rating = excluded.rating, deviation = excluded.deviation,
+			volatility = excluded.volatility, games = excluded.games, updated = excluded.updated, decayed = excluded.decayed`),
+			r.AccountId, r.GameType, r.Rating, r.Deviation, r.Volatility, r.Games, r.Updated, r.Decayed)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range changes {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO rating_changes (account_id, game_type, match_id, rating_before, rating_after, deviation, created)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			c.AccountId, c.GameType, c.MatchId, c.Before, c.After, c.Deviation, c.Created)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// leaderboardColumns selects an entry with its rank, which the leaderboard_score index
+// makes a range count of the entries with a higher score.
+const leaderboardColumns = `l.season, l.game_type, l.account_id, l.name, l.score, l.games, l.updated,
+	(SELECT COUNT(*) FROM leaderboard h
+		WHERE h.season = l.season AND h.game_type = l.game_type AND h.score > l.score) + 1`
+
+func scanLeaderboardEntry(row interface{ Scan(...any) error }) (*LeaderboardEntry, error) {
+	var e LeaderboardEntry
+	err := row.Scan(&e.Season, &e.GameType, &e.AccountId, &e.Name, &e.Score, &e.Games, &e.Updated, &e.Rank)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *SQL) LeaderboardEntry(season int, gameType, accountId string) (*LeaderboardEntry, error) {
+	e, err := scanLeaderboardEntry(s.queryRow(`SELECT `+leaderboardColumns+` FROM leaderboard l
+		WHERE l.season = ? AND l.game_type = ? AND l.account_id = ?`, season, gameType, accountId))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return e, nil
+}
+
+func (s *SQL) LeaderboardEntries(accountId string) ([]*LeaderboardEntry, error) {
+	rows, err := s.query(`SELECT `+leaderboardColumns+` FROM leaderboard l
+		WHERE l.account_id = ? ORDER BY l.season, l.game_type`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*LeaderboardEntry{}
+	for rows.Next() {
+		e, err := scanLeaderboardEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQL) Leaderboard(q LeaderboardQuery) ([]*LeaderboardEntry, error) {
+	query := `SELECT ` + leaderboardColumns + ` FROM leaderboard l
+		WHERE l.season = ? AND l.game_type = ?
+		ORDER BY l.score DESC, l.updated, l.account_id`
+	args := []any{q.Season, q.GameType}
+	if q.Limit > 0 || q.Offset > 0 {
+		limit := q.Limit
+		if limit == 0 {
+			// sqlite only allows an offset after a limit
+			limit = math.MaxInt32
+		}
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, q.Offset)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*LeaderboardEntry{}
+	for rows.Next() {
+		e, err := scanLeaderboardEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQL) LeaderboardGameTypes(season int) ([]string, error) {
+	rows, err := s.query(`SELECT DISTINCT game_type FROM leaderboard WHERE season = ? ORDER BY game_type`, season)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gameTypes := []string{}
+	for rows.Next() {
+		var gameType string
+		if err := rows.Scan(&gameType); err != nil {
+			return nil, err
+		}
+		gameTypes = append(gameTypes, gameType)
+	}
+	return gameTypes, rows.Err()
+}
+
+func (s *SQL) SaveLeaderboardEntries(entries []*LeaderboardEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range entries {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO leaderboard (season, game_type, account_id, name, score, games, updated)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (season, game_type, account_id) DO UPDATE SET name = excluded.name, score = excluded.score,
+			games = excluded.games, updated = excluded.updated`),
+			e.Season, e.GameType, e.AccountId, e.Name, e.Score, e.Games, e.Updated)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) SeasonArchive(season int) (*SeasonArchive, error) {
+	var a SeasonArchive
+	err := s.queryRow(`SELECT season, archived FROM season_archives WHERE season = ?`, season).Scan(&a.Season, &a.Archived)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &a, nil
+}
+
+func (s *SQL) SaveSeasonArchive(a *SeasonArchive) error {
+	_, err := s.exec(`INSERT INTO season_archives (season, archived) VALUES (?, ?)
+		ON CONFLICT (season) DO UPDATE SET archived = excluded.archived`, a.Season, a.Archived)
+	return err
+}
+
+func (s *SQL) Achievements(accountId string) ([]*AchievementProgress, error) {
+	rows, err := s.query(`SELECT account_id, achievement_id, progress, unlocked, updated
+		FROM achievements WHERE account_id = ? ORDER BY achievement_id`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := []*AchievementProgress{}
+	for rows.Next() {
+		var p AchievementProgress
+		if err := rows.Scan(&p.AccountId, &p.AchievementId, &p.Progress, &p.Unlocked, &p.Updated); err != nil {
+			return nil, err
+		}
+		progress = append(progress, &p)
+	}
+	return progress, rows.Err()
+}
+
+func (s *SQL) SaveAchievements(progress []*AchievementProgress) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, p := range progress {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO achievements (account_id, achievement_id, progress, unlocked, updated)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (account_id, achievement_id) DO UPDATE SET progress = excluded.progress,
+			unlocked = excluded.unlocked, updated = excluded.updated`),
+			p.AccountId, p.AchievementId, p.Progress, p.Unlocked, p.Updated)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) Stats(accountId string) ([]*PlayerStats, error) {
+	rows, err := s.query(`SELECT account_id, game_type, games, wins, total_score, streak, longest_streak, updated
+		FROM player_stats WHERE account_id = ? ORDER BY game_type`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*PlayerStats{}
+	for rows.Next() {
+		var st PlayerStats
+		err := rows.Scan(&st.AccountId, &st.GameType, &st.Games, &st.Wins, &st.TotalScore, &st.Streak, &st.LongestStreak, &st.Updated)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, &st)
+	}
+	return stats, rows.Err()
+}
+
+const opponentColumns = `account_id, opponent_id, name, games, wins, updated`
+
+func scanOpponent(row interface{ Scan(...any) error }) (*OpponentStats, error) {
+	var o OpponentStats
+	if err := row.Scan(&o.AccountId, &o.OpponentId, &o.Name, &o.Games, &o.Wins, &o.Updated); err != nil {
+		return nil, notFound(err)
+	}
+	return &o, nil
+}
+
+func (s *SQL) Opponent(accountId, opponentId string) (*OpponentStats, error) {
+	return scanOpponent(s.queryRow(`SELECT `+opponentColumns+` FROM opponent_stats
+		WHERE account_id = ? AND opponent_id = ?`, accountId, opponentId))
+}
+
+func (s *SQL) Opponents(accountId string, limit int) ([]*OpponentStats, error) {
+	query := `SELECT ` + opponentColumns + ` FROM opponent_stats WHERE account_id = ? ORDER BY games DESC, opponent_id`
+	args := []any{accountId}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	opponents := []*OpponentStats{}
+	for rows.Next() {
+		o, err := scanOpponent(rows)
+		if err != nil {
+			return nil, err
+		}
+		opponents = append(opponents, o)
+	}
+	return opponents, rows.Err()
+}
+
+func (s *SQL) SaveStats(stats []*PlayerStats, opponents []*OpponentStats) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, st := range stats {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO player_stats (account_id, game_type, games, wins, total_score, streak, longest_streak, updated)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (account_id, game_type) DO UPDATE SET games = excluded.games, wins = excluded.wins,
+			total_score = excluded.total_score, streak = excluded.streak, longest_streak = excluded.longest_streak,
+			updated = excluded.updated`),
+			st.AccountId, st.GameType, st.Games, st.Wins, st.TotalScore, st.Streak, st.LongestStreak, st.Updated)
+		if err != nil {
+			return err
+		}
+	}
+	for _, o := range opponents {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO opponent_stats (`+opponentColumns+`) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (account_id, opponent_id) DO UPDATE SET name = excluded.name, games = excluded.games,
+			wins = excluded.wins, updated = excluded.updated`),
+			o.AccountId, o.OpponentId, o.Name, o.Games, o.Wins, o.Updated)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) Cosmetics(accountId string) ([]*CosmeticUnlock, error) {
+	rows, err := s.query(`SELECT account_id, cosmetic_id, unlocked FROM cosmetic_unlocks
+		WHERE account_id = ? ORDER BY unlocked, cosmetic_id`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unlocks := []*CosmeticUnlock{}
+	for rows.Next() {
+		var u CosmeticUnlock
+		if err := rows.Scan(&u.AccountId, &u.CosmeticId, &u.Unlocked); err != nil {
+			return nil, err
+		}
+		unlocks = append(unlocks, &u)
+	}
+	return unlocks, rows.Err()
+}
+
+func (s *SQL) SaveCosmetics(unlocks []*CosmeticUnlock) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, u := range unlocks {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO cosmetic_unlocks (account_id, cosmetic_id, unlocked) VALUES (?, ?, ?)
+			ON CONFLICT (account_id, cosmetic_id) DO NOTHING`),
+			u.AccountId, u.CosmeticId, u.Unlocked)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) Experience(accountId string) (*Experience, error) {
+	var xp Experience
+	err := s.queryRow(`SELECT account_id, xp, updated FROM experience WHERE account_id = ?`, accountId).
+		Scan(&xp.AccountId, &xp.XP, &xp.Updated)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &xp, nil
+}
+
+func (s *SQL) Quests(accountId, period string) ([]*QuestProgress, error) {
+	query := `SELECT account_id, quest_id, period, progress, completed, updated FROM quest_progress WHERE account_id = ?`
+	args := []any{accountId}
+	if period != "" {
+		query += ` AND period = ?`
+		args = append(args, period)
+	}
+	rows, err := s.query(query+` ORDER BY period, quest_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quests := []*QuestProgress{}
+	for rows.Next() {
+		var q QuestProgress
+		if err := rows.Scan(&q.AccountId, &q.QuestId, &q.Period, &q.Progress, &q.Completed, &q.Updated); err != nil {
+			return nil, err
+		}
+		quests = append(quests, &q)
+	}
+	return quests, rows.Err()
+}
+
+func (s *SQL) SaveProgression(xp *Experience, quests []*QuestProgress) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.dialect.rebind(`INSERT INTO experience (account_id, xp, updated) VALUES (?, ?, ?)
+		ON CONFLICT (account_id) DO UPDATE SET xp = excluded.xp, updated = excluded.updated`),
+		xp.AccountId, xp.XP, xp.Updated)
+	if err != nil {
+		return err
+	}
+	for _, q := range quests {
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO quest_progress (account_id, quest_id, period, progress, completed, updated)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (account_id, quest_id, period) DO UPDATE SET progress = excluded.progress,
+			completed = excluded.completed, updated = excluded.updated`),
+			q.AccountId, q.QuestId, q.Period, q.Progress, q.Completed, q.Updated)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const challengeColumns = `day, game_type, account_id, name, score, started, completed`
+
+func scanChallengeResult(row interface{ Scan(...any) error }) (*ChallengeResult, error) {
+	var r ChallengeResult
+	if err := row.Scan(&r.Day, &r.GameType, &r.AccountId, &r.Name, &r.Score, &r.Started, &r.Completed); err != nil {
+		return nil, notFound(err)
+	}
+	return &r, nil
+}
+
+func (s *SQL) ChallengeResult(day, gameType, accountId string) (*ChallengeResult, error) {
+	return scanChallengeResult(s.queryRow(`SELECT `+challengeColumns+` FROM challenge_results
+		WHERE day = ? AND game_type = ? AND account_id = ?`, day, gameType, accountId))
+}
+
+func (s *SQL) ChallengeResults(day, gameType string, limit int) ([]*ChallengeResult, error) {
+	query := `SELECT ` + challengeColumns + ` FROM challenge_results
+		WHERE day = ? AND game_type = ? AND completed <> 0 ORDER BY score DESC, completed`
+	args := []any{day, gameType}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*ChallengeResult{}
+	for rows.Next() {
+		r, err := scanChallengeResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *SQL) ChallengeHistory(accountId string) ([]*ChallengeResult, error) {
+	rows, err := s.query(`SELECT `+challengeColumns+` FROM challenge_results
+		WHERE account_id = ? ORDER BY day DESC, game_type`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*ChallengeResult{}
+	for rows.Next() {
+		r, err := scanChallengeResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *SQL) ChallengeStreaks(accountId string) ([]*ChallengeStreak, error) {
+	rows, err := s.query(`SELECT account_id, game_type, streak, longest_streak, last_day FROM challenge_streaks
+		WHERE account_id = ? ORDER BY game_type`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	streaks := []*ChallengeStreak{}
+	for rows.Next() {
+		var st ChallengeStreak
+		if err := rows.Scan(&st.AccountId, &st.GameType, &st.Streak, &st.LongestStreak, &st.LastDay); err != nil {
+			return nil, err
+		}
+		streaks = append(streaks, &st)
+	}
+	return streaks, rows.Err()
+}
+
+func (s *SQL) ChallengeStreak(accountId, gameType string) (*ChallengeStreak, error) {
+	var st ChallengeStreak
+	err := s.queryRow(`SELECT account_id, game_type, streak, longest_streak, last_day FROM challenge_streaks
+		WHERE account_id = ? AND game_type = ?`, accountId, gameType).
+		Scan(&st.AccountId, &st.GameType, &st.Streak, &st.LongestStreak, &st.LastDay)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &st, nil
+}
+
+func (s *SQL) SaveChallengeResult(result *ChallengeResult, streak *ChallengeStreak) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.dialect.rebind(`INSERT INTO challenge_results (`+challengeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (day, game_type, account_id) DO UPDATE SET name = excluded.name, score = excluded.score,
+		completed = excluded.completed`),
+		result.Day, result.GameType, result.AccountId, result.Name, result.Score, result.Started, result.Completed)
+	if err != nil {
+		return err
+	}
+	if streak != nil {
+		_, err = tx.Exec(s.dialect.rebind(`INSERT INTO challenge_streaks (account_id, game_type, streak, longest_streak, last_day)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (account_id, game_type) DO UPDATE SET streak = excluded.streak,
+			longest_streak = excluded.longest_streak, last_day = excluded.last_day`),
+			streak.AccountId, streak.GameType, streak.Streak, streak.LongestStreak, streak.LastDay)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const suspendedColumns = `g.id, g.room_name, g.game_type, g.players, g.state, g.started, g.suspended`
+
+// scanSuspendedGame reads a row selected with suspendedColumns.
+func scanSuspendedGame(row interface{ Scan(...any) error }) (*SuspendedGame, error) {
+	var g SuspendedGame
+	var players, state string
+	err := row.Scan(&g.Id, &g.RoomName, &g.GameType, &players, &state, &g.Started, &g.Suspended)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	g.State = json.RawMessage(state)
+	if err := json.Unmarshal([]byte(players), &g.Players); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+const auditColumns = `id, action, actor_id, actor_name, target_id, target_name, room_id, reason, created`
+
+func (s *SQL) AuditLog(q AuditQuery) ([]*AuditEntry, error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE 1 = 1`
+	args := []any{}
+	if q.ActorId != "" {
+		query += ` AND actor_id = ?`
+		args = append(args, q.ActorId)
+	}
+	if q.TargetId != "" {
+		query += ` AND target_id = ?`
+		args = append(args, q.TargetId)
+	}
+	if q.Action != "" {
+		query += ` AND action = ?`
+		args = append(args, q.Action)
+	}
+	if q.Before != 0 && q.BeforeId != "" {
+		query += ` AND (created < ? OR created = ? AND id > ?)`
+		args = append(args, q.Before, q.Before, q.BeforeId)
+	} else if q.Before != 0 {
+		query += ` AND created < ?`
+		args = append(args, q.Before)
+	}
+	query += ` ORDER BY created DESC, id`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		err := rows.Scan(&e.Id, &e.Action, &e.ActorId, &e.ActorName, &e.TargetId, &e.TargetName, &e.RoomId, &e.Reason, &e.Created)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQL) SaveAuditEntry(e *AuditEntry) error {
+	_, err := s.exec(`INSERT INTO audit_log (`+auditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Id, e.Action, e.ActorId, e.ActorName, e.TargetId, e.TargetName, e.RoomId, e.Reason, e.Created)
+	return err
+}
+
+const reportColumns = `id, reason, reporter_id, reporter_name, target_id, target_name, room_id, game_type, details,
+	status, resolution, resolver_id, created, resolved`
+
+func (s *SQL) Report(id string) (*Report, error) {
+	var r Report
+	var chat, events string
+	err := s.queryRow(`SELECT `+reportColumns+`, chat, events FROM reports WHERE id = ?`, id).
+		Scan(&r.Id, &r.Reason, &r.ReporterId, &r.ReporterName, &r.TargetId, &r.TargetName, &r.RoomId, &r.GameType, &r.Details,
+			&r.Status, &r.Resolution, &r.ResolverId, &r.Created, &r.Resolved, &chat, &events)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := json.Unmarshal([]byte(chat), &r.Chat); err != nil {
+		return nil, err
+	}
+	if events != "" {
+		r.Events = json.RawMessage(events)
+	}
+	return &r, nil
+}
+
+func (s *SQL) Reports(q ReportQuery) ([]*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE 1 = 1`
+	args := []any{}
+	if q.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, q.Status)
+	}
+	if q.ReporterId != "" {
+		query += ` AND reporter_id = ?`
+		args = append(args, q.ReporterId)
+	}
+	if q.TargetId != "" {
+		query += ` AND target_id = ?`
+		args = append(args, q.TargetId)
+	}
+	if q.Before != 0 && q.BeforeId != "" {
+		query += ` AND (created < ? OR created = ? AND id > ?)`
+		args = append(args, q.Before, q.Before, q.BeforeId)
+	} else if q.Before != 0 {
+		query += ` AND created < ?`
+		args = append(args, q.Before)
+	}
+	query += ` ORDER BY created DESC, id`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		var r Report
+		err := rows.Scan(&r.Id, &r.Reason, &r.ReporterId, &r.ReporterName, &r.TargetId, &r.TargetName, &r.RoomId, &r.GameType, &r.Details,
+			&r.Status, &r.Resolution, &r.ResolverId, &r.Created, &r.Resolved)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, &r)
+	}
+	return reports, rows.Err()
+}
+
+func (s *SQL) SaveReport(r *Report) error {
+	chat, err := json.Marshal(r.Chat)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(`INSERT INTO reports (`+reportColumns+`, chat, events) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET reporter_name = excluded.reporter_name, target_name = excluded.target_name,
+		status = excluded.status, resolution = excluded.resolution, resolver_id = excluded.resolver_id, resolved = excluded.resolved`,
+		r.Id, r.Reason, r.ReporterId, r.ReporterName, r.TargetId, r.TargetName, r.RoomId, r.GameType, r.Details,
+		r.Status, r.Resolution, r.ResolverId, r.Created, r.Resolved, string(chat), string(r.Events))
+	return err
+}
+
+func (s *SQL) SuspendedGame(id string) (*SuspendedGame, error) {
+	return scanSuspendedGame(s.queryRow(`SELECT `+suspendedColumns+` FROM suspended_games g WHERE g.id = ?`, id))
+}
+
+func (s *SQL) SuspendedGames(accountId string) ([]*SuspendedGame, error) {
+	rows, err := s.query(`SELECT `+suspendedColumns+` FROM suspended_games g
+		JOIN suspended_game_players gp ON gp.game_id = g.id AND gp.account_id = ?
+		ORDER BY g.suspended DESC, g.id`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []*SuspendedGame{}
+	for rows.Next() {
+		g, err := scanSuspendedGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
+
+func (s *SQL) SaveSuspendedGame(g *SuspendedGame) error {
+	players, err := json.Marshal(g.Players)
+	if err != nil {
+		return err
+	}
+	state := string(g.State)
+	if state == "" {
+		state = "{}"
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.dialect.rebind(`INSERT INTO suspended_games (id, room_name, game_type, players, state, started, suspended)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET room_name = excluded.room_name, game_type = excluded.game_type,
+		players = excluded.players, state = excluded.state, started = excluded.started, suspended = excluded.suspended`),
+		g.Id, g.RoomName, g.GameType, string(players), state, g.Started, g.Suspended)
+	if err != nil {
+		return err
+	}
+
+	// suspended_game_players indexes suspended games by the accounts that can resume them
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM suspended_game_players WHERE game_id = ?`), g.Id); err != nil {
+		return err
+	}
+	for _, p := range g.Players {
+		if p.AccountId == "" {
+			continue
+		}
+		_, err := tx.Exec(s.dialect.rebind(`INSERT INTO suspended_game_players (game_id, account_id, suspended) VALUES (?, ?, ?)
+			ON CONFLICT (game_id, account_id) DO NOTHING`), g.Id, p.AccountId, g.Suspended)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) DeleteSuspendedGame(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(s.dialect.rebind(`DELETE FROM suspended_games WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(s.dialect.rebind(`DELETE FROM suspended_game_players WHERE game_id = ?`), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const presetColumns = `id, account_id, name, code, settings, created, updated`
+
+// scanRoomPreset reads a row selected with presetColumns.
+func scanRoomPreset(row interface{ Scan(...any) error }) (*RoomPreset, error) {
+	var p RoomPreset
+	var settings string
+	err := row.Scan(&p.Id, &p.AccountId, &p.Name, &p.Code, &settings, &p.Created, &p.Updated)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	p.Settings = json.RawMessage(settings)
+	return &p, nil
+}
+
+func (s *SQL) RoomPreset(id string) (*RoomPreset, error) {
+	return scanRoomPreset(s.queryRow(`SELECT `+presetColumns+` FROM room_presets WHERE id = ?`, id))
+}
+
+func (s *SQL) RoomPresetByCode(code string) (*RoomPreset, error) {
+	return scanRoomPreset(s.queryRow(`SELECT `+presetColumns+` FROM room_presets WHERE code = ?`, code))
+}
+
+func (s *SQL) RoomPresets(accountId string) ([]*RoomPreset, error) {
+	rows, err := s.query(`SELECT `+presetColumns+` FROM room_presets WHERE account_id = ? ORDER BY created, id`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	presets := []*RoomPreset{}
+	for rows.Next() {
+		p, err := scanRoomPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, p)
+	}
+	return presets, rows.Err()
+}
+
+func (s *SQL) SaveRoomPreset(p *RoomPreset) error {
+	settings := string(p.Settings)
+	if settings == "" {
+		settings = "{}"
+	}
+	_, err := s.exec(`INSERT INTO room_presets (`+presetColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, code = excluded.code,
+		settings = excluded.settings, updated = excluded.updated`,
+		p.Id, p.AccountId, p.Name, p.Code, settings, p.Created, p.Updated)
+	return err
+}
+
+func (s *SQL) DeleteRoomPreset(id string) error {
+	res, err := s.exec(`DELETE FROM room_presets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQL) Ping() error {
+	return s.db.Ping()
+}
+
+func (s *SQL) Close() error {
+	return s.db.Close()
+}
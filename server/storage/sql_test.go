@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLite(t *testing.T) {
+	s, err := OpenSQL("sqlite", ":memory:", DialectSQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	testStore(t, s)
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	s, err := OpenSQL("sqlite", ":memory:", DialectSQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	assert.NoError(t, s.Migrate(0), "every migration should be reversible")
+	version, err := s.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.NoError(t, s.Migrate(LatestSchemaVersion()))
+
+	_, err = s.exec(`UPDATE schema_version SET version = ?`, LatestSchemaVersion()+1)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.checkSchema(), ErrSchemaVersion, "newer schemas should be refused")
+
+	AutoMigrate = false
+	defer func() { AutoMigrate = true }()
+	_, err = s.exec(`UPDATE schema_version SET version = 0`)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.checkSchema(), ErrSchemaVersion, "older schemas should be refused without migrating")
+}
+
+func TestSQLiteUniqueNames(t *testing.T) {
+	s, err := OpenSQL("sqlite", ":memory:", DialectSQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	// names were made unique by migration 49
+	assert.NoError(t, s.Migrate(48))
+	for i, name := range []string{"Ann", "ann", "", ""} {
+		_, err := s.exec(`INSERT INTO accounts (id, name, name_key, avatar, token_hash, created, updated) VALUES (?, ?, ?, '{}', ?, ?, ?)`,
+			fmt.Sprintf("u_%d", i+1), name, nameKey(name), fmt.Sprintf("h%d", i+1), i+1, i+1)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.Migrate(LatestSchemaVersion()))
+
+	a, err := s.AccountByName("ann")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "u_1", a.Id, "the oldest account should keep a name taken twice")
+	}
+	a, err = s.Account("u_2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ann_u_2", a.Name)
+	}
+	a.Name = "ANN"
+	assert.ErrorIs(t, s.SaveAccount(a), ErrNameTaken)
+}
+
+func TestSQLiteBackup(t *testing.T) {
+	src, err := OpenSQL("sqlite", ":memory:", DialectSQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer src.Close()
+	dst, err := OpenSQL("sqlite", ":memory:", DialectSQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer dst.Close()
+
+	assert.NoError(t, src.SaveAccount(&Account{Id: "u_1", Name: "alice", Created: 1, Updated: 1}))
+	assert.NoError(t, src.SaveAccount(&Account{Id: "u_2", Name: "bob", Created: 2, Updated: 2}))
+	assert.NoError(t, src.SaveSession(&Session{Id: "s_1", AccountId: "u_1", TokenHash: "h1", UserAgent: "phone", Created: 1}))
+	assert.NoError(t, src.SaveFriendship(&Friendship{AccountId: "u_1", FriendId: "u_2", Accepted: 3, Created: 3}))
+	assert.NoError(t, src.SaveMatch(&Match{Id: "m_1", GameType: "classic", Rules: json.RawMessage(`{}`), Outcome: OutcomeCompleted, Players: []MatchPlayer{
+		{Id: "p_1", AccountId: "u_1", Name: "alice", Score: 10, Rank: 1},
+		{Id: "p_2", AccountId: "u_2", Name: "bob", Score: 5, Rank: 2},
+	}, Started: 4, Ended: 5}))
+	assert.NoError(t, src.SaveSuspendedGame(&SuspendedGame{Id: "sg_1", RoomName: "table", GameType: "classic", Players: []MatchPlayer{{Id: "p_1", AccountId: "u_1", Name: "alice"}}, State: json.RawMessage(`{"seats":[]}`), Started: 6, Suspended: 7}))
+	for i := 0; i < 2*backupChunkRows+1; i++ {
+		assert.NoError(t, src.SaveAuditEntry(&AuditEntry{Id: fmt.Sprintf("a_%d", i), Action: AuditKick, ActorId: "u_1", TargetId: "u_2", Created: int64(i)}))
+	}
+	assert.NoError(t, dst.SaveAccount(&Account{Id: "u_9", Name: "carol", Created: 1, Updated: 1}))
+
+	var archive bytes.Buffer
+	assert.NoError(t, Backup(src, &archive))
+	assert.NoError(t, dst.Restore(bytes.NewReader(archive.Bytes())))
+
+	for _, id := range []string{"u_1", "u_2"} {
+		want, err := Export(src, id)
+		assert.NoError(t, err)
+		got, err := Export(dst, id)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "restored accounts should have all their data")
+	}
+	entries, err := dst.AuditLog(AuditQuery{Limit: 5000})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2*backupChunkRows+1, "tables larger than a chunk should be restored whole")
+	suspended, err := dst.SuspendedGame("sg_1")
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"seats":[]}`, string(suspended.State))
+	}
+	_, err = dst.Account("u_9")
+	assert.ErrorIs(t, err, ErrNotFound, "restoring should replace what was in the database")
+
+	assert.ErrorIs(t, dst.Restore(bytes.NewReader(archive.Bytes()[:archive.Len()/2])), ErrBackupFormat)
+	_, err = dst.Account("u_1")
+	assert.NoError(t, err, "a failed restore should leave the data alone")
+	assert.ErrorIs(t, Backup(NewMemory(), &archive), ErrBackupUnsupported)
+}
+
+func TestSQLiteRestoreOlderSchema(t *testing.T) {
+	old, err := OpenSQLUnchecked("sqlite", ":memory:", DialectSQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer old.Close()
+	assert.NoError(t, old.Migrate(1))
+	_, err = old.exec(`INSERT INTO accounts (id, name, avatar, token_hash, created, updated) VALUES ('u_1', 'alice', '{}', 'h1', 1, 1)`)
+	assert.NoError(t, err)
+
+	var archive bytes.Buffer
+	assert.NoError(t, old.Backup(&archive))
+
+	s, err := OpenSQL("sqlite", ":memory:", DialectSQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_9", Name: "carol", Created: 1, Updated: 1}))
+	assert.ErrorIs(t, s.Restore(bytes.NewReader(archive.Bytes())), ErrRestoreNotEmpty, "older backups should only be restored into empty databases")
+	version, err := s.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version, "refused restores should not migrate")
+	_, err = s.Account("u_9")
+	assert.NoError(t, err, "refused restores should leave the data alone")
+
+	_, err = s.exec(`DELETE FROM accounts`)
+	assert.NoError(t, err)
+	empty, err := s.Empty()
+	assert.NoError(t, err)
+	assert.True(t, empty)
+	assert.NoError(t, s.Restore(&archive))
+	version, err = s.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version, "restored backups should be migrated up")
+	a, err := s.Account("u_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "alice", a.Name)
+	}
+	session, err := s.SessionByToken("h1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "u_1", session.AccountId, "the token of an account should become its first session")
+	}
+}
+
+func TestDriverDialect(t *testing.T) {
+	dialect, err := DriverDialect("sqlite")
+	assert.NoError(t, err)
+	assert.Equal(t, DialectSQLite, dialect)
+	_, err = DriverDialect("mysql")
+	assert.ErrorContains(t, err, "unknown storage driver")
+	if !driverBuiltIn("postgres") {
+		_, err = DriverDialect("postgres")
+		assert.ErrorContains(t, err, "-tags postgres", "a driver left out of the build should say how to build it in")
+	}
+}
@@ -0,0 +1,593 @@
+package storage
+
+import (
+	"cardgame/errs"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrNotFound is returned when a record does not exist.
+var ErrNotFound = errs.ErrNotFound
+
+// ErrNameTaken is returned when saving an account with the name of another one.
+var ErrNameTaken = errs.New(errs.ErrConflict, "name is taken")
+
+// ErrEmailTaken is returned when saving an account with the email of another one.
+var ErrEmailTaken = errs.New(errs.ErrConflict, "email is taken")
+
+type (
+	// Account is a persistent player identity.
+	Account struct {
+		Id           string  `json:"id"`
+		Name         string  `json:"name"`
+		Avatar       Avatar  `json:"avatar"`
+		AvatarImage  int64   `json:"avatarImage"` // unix ms when an avatar image was uploaded, 0 if there is none
+		Bio          string  `json:"bio"`
+		FavoriteGame string  `json:"favoriteGame"`
+		Invites      Invites `json:"invites"`     // who can invite the account into their room
+		CardBack     string  `json:"cardBack"`    // id of the selected card back, empty for the default
+		TableTheme   string  `json:"tableTheme"`  // id of the selected table theme, empty for the default
+		NameChanged  int64   `json:"nameChanged"` // unix ms, 0 if the name was never changed
+		Deleted      int64   `json:"deleted"`     // unix ms when the owner deleted the account, 0 unless it is waiting to be purged
+		Created      int64   `json:"created"`     // unix ms
+		Updated      int64   `json:"updated"`     // unix ms
+
+		// Only ever shown to the owner.
+		Email         string `json:"-"` // lowercase, empty if the account has none
+		EmailVerified int64  `json:"-"` // unix ms when the email was verified, 0 until it is
+		PasswordHash  string `json:"-"` // bcrypt, empty if the account can't sign in with a password
+
+		// Two-factor authentication, with the codes of an authenticator app.
+		TotpSecret    string   `json:"-"` // base32, empty unless it is on or being set up
+		TotpEnabled   int64    `json:"-"` // unix ms when it was turned on, 0 while it is being set up
+		TotpStep      int64    `json:"-"` // time step of the last code accepted, which isn't accepted again
+		RecoveryCodes []string `json:"-"` // hashes of the unused codes which stand in for the app's
+	}
+
+	// Session is a device signed in to an account. Every session has a token of its own, so
+	// one can be revoked without signing out the others.
+	Session struct {
+		Id        string `json:"id"`
+		AccountId string `json:"accountId"`
+		TokenHash string `json:"-"`         // hash of the secret the session is accessed with
+		UserAgent string `json:"userAgent"` // browser or app the session was created from
+		Created   int64  `json:"created"`   // unix ms
+	}
+
+	// EmailToken is a secret mailed to an account's address, which proves the owner reads it.
+	EmailToken struct {
+		TokenHash string // hash of the secret in the mailed link
+		AccountId string
+		Purpose   EmailPurpose
+		Email     string // address the token was mailed to
+		Created   int64  // unix ms
+		Expires   int64  // unix ms
+	}
+
+	// Friendship is a friend request, which makes both accounts friends once it is accepted.
+	Friendship struct {
+		AccountId string `json:"accountId"` // who sent the request
+		FriendId  string `json:"friendId"`  // who it was sent to
+		Accepted  int64  `json:"accepted"`  // unix ms, 0 while the request is pending
+		Created   int64  `json:"created"`   // unix ms
+	}
+
+	// Block is an account blocking another, which hides the other's chat and keeps it out of its rooms.
+	Block struct {
+		AccountId string `json:"accountId"`
+		BlockedId string `json:"blockedId"`
+		Created   int64  `json:"created"` // unix ms
+	}
+
+	// AvatarImage is an avatar image uploaded for an account.
+	AvatarImage struct {
+		AccountId   string
+		ContentType string
+		Data        []byte
+		Updated     int64 // unix ms
+	}
+
+	// Avatar is the stored avatar of an account.
+	Avatar struct {
+		Eyes  int `json:"eyes"`
+		Mouth int `json:"mouth"`
+		Color int `json:"color"`
+	}
+
+	// Match is the result of a completed game.
+	Match struct {
+		Id       string          `json:"id"`
+		RoomId   string          `json:"roomId"`
+		GameType string          `json:"gameType"`
+		Ranked   bool            `json:"ranked"`
+		Rules    json.RawMessage `json:"rules"` // settings the game was played with
+		Outcome  Outcome         `json:"outcome"`
+		Players  []MatchPlayer   `json:"players"`
+		Started  int64           `json:"started"` // unix ms
+		Ended    int64           `json:"ended"`   // unix ms
+	}
+
+	// MatchPlayer is a participant in a match.
+	MatchPlayer struct {
+		Id        string `json:"id"`
+		AccountId string `json:"accountId"` // empty for guests
+		Name      string `json:"name"`
+		Score     int    `json:"score"`
+		Rank      int    `json:"rank"` // 1 for the winner, players with the same score share a rank
+	}
+
+	// MatchQuery selects a page of matches, newest first.
+	MatchQuery struct {
+		AccountId string // only matches the account played in
+		RoomId    string // only matches played in the room
+		Before    int64  // only matches that ended before this unix ms time, if set
+		BeforeId  string // with Before, also the matches that ended at Before and sort after this id
+		Limit     int
+	}
+
+	// Replay is everything needed to play back a match.
+	Replay struct {
+		MatchId string          `json:"matchId"`
+		Seed    int64           `json:"seed"`
+		Events  json.RawMessage `json:"events"`
+		Created int64           `json:"created"` // unix ms
+	}
+
+	// ReplayQuery selects a page of replays, newest first.
+	ReplayQuery struct {
+		Before   int64  // only replays created before this unix ms time, if set
+		BeforeId string // with Before, also the replays created at Before and sort after this match id
+		Limit    int
+	}
+
+	// PlayerRating is an account's rating for a game type.
+	PlayerRating struct {
+		AccountId  string  `json:"accountId"`
+		GameType   string  `json:"gameType"`
+		Rating     float64 `json:"rating"`
+		Deviation  float64 `json:"deviation"`
+		Volatility float64 `json:"volatility"`
+		Games      int     `json:"games"`   // number of rated games played
+		Updated    int64   `json:"updated"` // unix ms of the last rated game
+		Decayed    int64   `json:"decayed"` // unix ms up to which inactivity decay was applied, 0 if it never decayed
+	}
+
+	// RatingChange is the change of a rating after a match.
+	RatingChange struct {
+		AccountId string  `json:"accountId"`
+		GameType  string  `json:"gameType"`
+		MatchId   string  `json:"matchId"`
+		Before    float64 `json:"before"`
+		After     float64 `json:"after"`
+		Deviation float64 `json:"deviation"` // deviation after the match
+		Created   int64   `json:"created"`   // unix ms
+	}
+
+	// RatingQuery selects a page of an account's rating changes for a game type, newest first.
+	RatingQuery struct {
+		AccountId string
+		GameType  string
+		Before    int64  // only changes created before this unix ms time, if set
+		BeforeId  string // with Before, also the changes created at Before and sort after this match id
+		Limit     int
+	}
+
+	// LeaderboardEntry is an account's standing on a leaderboard in a season.
+	LeaderboardEntry struct {
+		Season    int     `json:"season"`
+		GameType  string  `json:"gameType"` // "" for the global leaderboard
+		AccountId string  `json:"accountId"`
+		Name      string  `json:"name"`
+		Score     float64 `json:"score"`
+		Games     int     `json:"games"`   // number of ranked games played in the season
+		Updated   int64   `json:"updated"` // unix ms
+		Rank      int     `json:"rank"`    // 1 is first, equal scores share a rank; set when loaded
+	}
+
+	// LeaderboardQuery selects a page of a leaderboard, highest score first.
+	// Equal scores are ordered by who reached them first.
+	LeaderboardQuery struct {
+		Season   int
+		GameType string
+		Offset   int
+		Limit    int // all entries, if not set
+	}
+
+	// SeasonArchive records that a finished season has been archived.
+	SeasonArchive struct {
+		Season   int   `json:"season"`
+		Archived int64 `json:"archived"` // unix ms
+	}
+
+	// AchievementProgress is how far an account is towards unlocking an achievement.
+	AchievementProgress struct {
+		AccountId     string `json:"accountId"`
+		AchievementId string `json:"achievementId"`
+		Progress      int    `json:"progress"`
+		Unlocked      int64  `json:"unlocked"` // unix ms, 0 while locked
+		Updated       int64  `json:"updated"`  // unix ms
+	}
+
+	// PlayerStats are an account's results over all of its games of a game type,
+	// or of every game type if GameType is empty.
+	PlayerStats struct {
+		AccountId     string `json:"accountId"`
+		GameType      string `json:"gameType"`
+		Games         int    `json:"games"`
+		Wins          int    `json:"wins"`
+		TotalScore    int    `json:"totalScore"`
+		Streak        int    `json:"streak"` // wins in a row, up to the last game
+		LongestStreak int    `json:"longestStreak"`
+		Updated       int64  `json:"updated"` // unix ms
+	}
+
+	// OpponentStats are an account's results against another account it played with.
+	OpponentStats struct {
+		AccountId  string `json:"accountId"`
+		OpponentId string `json:"opponentId"`
+		Name       string `json:"name"` // the opponent's name in the last game
+		Games      int    `json:"games"`
+		Wins       int    `json:"wins"`    // games the account placed above the opponent
+		Updated    int64  `json:"updated"` // unix ms
+	}
+
+	// CosmeticUnlock records that an account unlocked a cosmetic item.
+	CosmeticUnlock struct {
+		AccountId  string `json:"accountId"`
+		CosmeticId string `json:"cosmeticId"`
+		Unlocked   int64  `json:"unlocked"` // unix ms
+	}
+
+	// Experience is the experience points an account has earned by playing.
+	Experience struct {
+		AccountId string `json:"accountId"`
+		XP        int    `json:"xp"`
+		Updated   int64  `json:"updated"` // unix ms
+	}
+
+	// QuestProgress is how far an account is towards completing a quest in one of the periods it is active.
+	QuestProgress struct {
+		AccountId string `json:"accountId"`
+		QuestId   string `json:"questId"`
+		Period    string `json:"period"` // the day or week the quest is active in, like "2024-06-01" or "2024-W23"
+		Progress  int    `json:"progress"`
+		Completed int64  `json:"completed"` // unix ms, 0 until completed
+		Updated   int64  `json:"updated"`   // unix ms
+	}
+
+	// ChallengeResult is an account's attempt at the daily challenge of a game type.
+	ChallengeResult struct {
+		Day       string `json:"day"` // UTC date of the challenge, like "2024-06-01"
+		GameType  string `json:"gameType"`
+		AccountId string `json:"accountId"`
+		Name      string `json:"name"`
+		Score     int    `json:"score"`
+		Started   int64  `json:"started"`   // unix ms
+		Completed int64  `json:"completed"` // unix ms, 0 while the attempt is unfinished
+	}
+
+	// ChallengeStreak is how many daily challenges of a game type an account completed in a row.
+	ChallengeStreak struct {
+		AccountId     string `json:"accountId"`
+		GameType      string `json:"gameType"`
+		Streak        int    `json:"streak"` // days in a row, up to LastDay
+		LongestStreak int    `json:"longestStreak"`
+		LastDay       string `json:"lastDay"` // day of the last completed challenge
+	}
+
+	// AuditEntry records an admin or moderation action. Actors and targets are accounts, or
+	// players for guests, and rooms for actions taken on a whole room.
+	AuditEntry struct {
+		Id         string      `json:"id"`
+		Action     AuditAction `json:"action"`
+		ActorId    string      `json:"actorId"`
+		ActorName  string      `json:"actorName"`
+		TargetId   string      `json:"targetId"`
+		TargetName string      `json:"targetName"`
+		RoomId     string      `json:"roomId"` // room the action was taken in, if any
+		Reason     string      `json:"reason"`
+		Created    int64       `json:"created"` // unix ms
+	}
+
+	// AuditQuery selects a page of audit entries, newest first.
+	AuditQuery struct {
+		ActorId  string      // only actions taken by the actor
+		TargetId string      // only actions taken on the target
+		Action   AuditAction // only actions of the kind
+		Before   int64       // only actions taken before this unix ms time, if set
+		BeforeId string      // with Before, also the actions taken at Before and sort after this id
+		Limit    int
+	}
+
+	// SuspendedGame is an unfinished game its players voted to suspend, to be resumed later.
+	SuspendedGame struct {
+		Id        string          `json:"id"`
+		RoomName  string          `json:"roomName"`
+		GameType  string          `json:"gameType"`
+		Players   []MatchPlayer   `json:"players"`   // standings when the game was suspended, in seat order
+		State     json.RawMessage `json:"-"`         // everything the game needs to resume, as saved by it
+		Started   int64           `json:"started"`   // unix ms
+		Suspended int64           `json:"suspended"` // unix ms
+	}
+
+	// Report is a player's report of another player's behavior in a room, waiting in the
+	// moderators' review queue until it is resolved. Reporters and targets are accounts, or
+	// players for guests, like in the audit log.
+	Report struct {
+		Id           string          `json:"id"`
+		Reason       ReportReason    `json:"reason"`
+		ReporterId   string          `json:"reporterId"`
+		ReporterName string          `json:"reporterName"`
+		TargetId     string          `json:"targetId"`
+		TargetName   string          `json:"targetName"`
+		RoomId       string          `json:"roomId"`
+		GameType     string          `json:"gameType"`
+		Details      string          `json:"details"`
+		Chat         []ReportChat    `json:"chat"`   // the room's chat leading up to the report, oldest first
+		Events       json.RawMessage `json:"events"` // replay events of the game leading up to the report, if one was being played
+		Status       ReportStatus    `json:"status"`
+		Resolution   string          `json:"resolution"` // the moderator's note
+		ResolverId   string          `json:"resolverId"`
+		Created      int64           `json:"created"`  // unix ms
+		Resolved     int64           `json:"resolved"` // unix ms, 0 while open
+	}
+
+	// ReportChat is a chat message kept with a report.
+	ReportChat struct {
+		PlayerId string `json:"playerId"`
+		Name     string `json:"name"`
+		Message  string `json:"message"`
+		Private  bool   `json:"private"` // sent to the reporter only
+		Time     int64  `json:"time"`    // unix ms
+	}
+
+	// ReportQuery selects a page of reports, newest first.
+	ReportQuery struct {
+		Status     ReportStatus // only reports with the status
+		ReporterId string       // only reports filed by the reporter
+		TargetId   string       // only reports of the target
+		Before     int64        // only reports filed before this unix ms time, if set
+		BeforeId   string       // with Before, also the reports filed at Before and sort after this id
+		Limit      int
+	}
+
+	// RoomPreset is a room configuration an account saved, to create rooms from in one go.
+	RoomPreset struct {
+		Id        string          `json:"id"`
+		AccountId string          `json:"accountId"`
+		Name      string          `json:"name"`
+		Code      string          `json:"code"`     // shares the preset with other players
+		Settings  json.RawMessage `json:"settings"` // room settings, as saved by the game
+		Created   int64           `json:"created"`  // unix ms
+		Updated   int64           `json:"updated"`  // unix ms
+	}
+)
+
+// Store persists accounts, sessions, friendships, blocks, match results, replays, ratings, leaderboards, achievements, stats, daily challenges, experience, quests, cosmetics, the audit log, player reports, suspended games and room presets.
+// Lookups of missing records return ErrNotFound.
+type Store interface {
+	Account(id string) (*Account, error)
+	AccountByName(name string) (*Account, error) // ignoring case
+	SaveAccount(a *Account) error
+	DeleteAccount(id string) error                  // with everything stored about it, leaving it anonymized in other players' matches, the audit log and reports
+	DeletedAccounts(before int64) ([]string, error) // ids of the accounts their owners deleted before a time, oldest first
+
+	SessionByToken(tokenHash string) (*Session, error)
+	Sessions(accountId string) ([]*Session, error) // oldest first
+	SaveSession(s *Session) error
+	DeleteSession(accountId, id string) error
+	DeleteSessions(accountId string) error
+
+	AccountByEmail(email string) (*Account, error) // ignoring case
+	EmailToken(tokenHash string) (*EmailToken, error)
+	SaveEmailToken(t *EmailToken) error
+	DeleteEmailTokens(accountId string, purpose EmailPurpose) error
+
+	Friendship(a, b string) (*Friendship, error) // sent by either account to the other
+	Friendships(accountId string) ([]*Friendship, error)
+	SaveFriendship(f *Friendship) error
+	DeleteFriendship(a, b string) error
+
+	Blocks(accountId string) ([]*Block, error)
+	Blocked(accountId, blockedId string) (bool, error)
+	SaveBlock(b *Block) error
+	DeleteBlock(accountId, blockedId string) error
+
+	AvatarImage(accountId string) (*AvatarImage, error)
+	SaveAvatarImage(img *AvatarImage) error
+	DeleteAvatarImage(accountId string) error
+
+	Match(id string) (*Match, error)
+	Matches(q MatchQuery) ([]*Match, error)
+	SaveMatch(m *Match) error
+
+	Replay(matchId string) (*Replay, error)
+	Replays(q ReplayQuery) ([]*Replay, error) // without their events
+	SaveReplay(r *Replay) error
+	DeleteReplaysBefore(created int64) (int, error)
+
+	Rating(accountId, gameType string) (*PlayerRating, error)
+	Ratings(accountId string) ([]*PlayerRating, error)     // for every game type the account has played
+	InactiveRatings(before int64) ([]*PlayerRating, error) // last played before a time, oldest first
+	RatingHistory(q RatingQuery) ([]*RatingChange, error)
+	SaveRatings(ratings []*PlayerRating, changes []*RatingChange) error // saved together
+
+	LeaderboardEntry(season int, gameType, accountId string) (*LeaderboardEntry, error)
+	LeaderboardEntries(accountId string) ([]*LeaderboardEntry, error) // of every leaderboard the account is on
+	Leaderboard(q LeaderboardQuery) ([]*LeaderboardEntry, error)
+	LeaderboardGameTypes(season int) ([]string, error) // of every leaderboard of the season, including the global one
+	SaveLeaderboardEntries(entries []*LeaderboardEntry) error
+	SeasonArchive(season int) (*SeasonArchive, error)
+	SaveSeasonArchive(a *SeasonArchive) error
+
+	Achievements(accountId string) ([]*AchievementProgress, error)
+	SaveAchievements(progress []*AchievementProgress) error
+
+	Stats(accountId string) ([]*PlayerStats, error) // by game type, the overall stats first
+	Opponent(accountId, opponentId string) (*OpponentStats, error)
+	Opponents(accountId string, limit int) ([]*OpponentStats, error) // most games together first
+	SaveStats(stats []*PlayerStats, opponents []*OpponentStats) error
+
+	Cosmetics(accountId string) ([]*CosmeticUnlock, error) // in the order they were unlocked
+	SaveCosmetics(unlocks []*CosmeticUnlock) error         // items already unlocked keep their unlock time
+
+	Experience(accountId string) (*Experience, error)
+	Quests(accountId, period string) ([]*QuestProgress, error)     // of every period if period is empty
+	SaveProgression(xp *Experience, quests []*QuestProgress) error // saved together
+
+	ChallengeResult(day, gameType, accountId string) (*ChallengeResult, error)
+	ChallengeResults(day, gameType string, limit int) ([]*ChallengeResult, error) // completed ones, best score first
+	ChallengeHistory(accountId string) ([]*ChallengeResult, error)                // newest first
+	ChallengeStreak(accountId, gameType string) (*ChallengeStreak, error)
+	ChallengeStreaks(accountId string) ([]*ChallengeStreak, error)
+	SaveChallengeResult(result *ChallengeResult, streak *ChallengeStreak) error // the streak is optional
+
+	AuditLog(q AuditQuery) ([]*AuditEntry, error)
+	SaveAuditEntry(e *AuditEntry) error
+
+	Report(id string) (*Report, error)
+	Reports(q ReportQuery) ([]*Report, error) // without their chat and events
+	SaveReport(r *Report) error
+
+	SuspendedGame(id string) (*SuspendedGame, error)
+	SuspendedGames(accountId string) ([]*SuspendedGame, error) // newest first
+	SaveSuspendedGame(g *SuspendedGame) error
+	DeleteSuspendedGame(id string) error
+
+	RoomPreset(id string) (*RoomPreset, error)
+	RoomPresetByCode(code string) (*RoomPreset, error)
+	RoomPresets(accountId string) ([]*RoomPreset, error) // oldest first
+	SaveRoomPreset(p *RoomPreset) error
+	DeleteRoomPreset(id string) error
+
+	Ping() error // checks the store can be reached
+	Close() error
+}
+
+// DeletedName replaces the name of a deleted account wherever it stays on record.
+const DeletedName = "Deleted player"
+
+// anonymize removes an account from the players of a match, and reports if it played in it.
+func anonymize(players []MatchPlayer, accountId string) bool {
+	found := false
+	for i := range players {
+		if players[i].AccountId == accountId {
+			players[i].AccountId = ""
+			players[i].Name = DeletedName
+			found = true
+		}
+	}
+	return found
+}
+
+// Invites is who can invite an account into their room.
+type Invites string
+
+const (
+	InvitesFriends  Invites = "" // only friends, the default
+	InvitesEveryone Invites = "everyone"
+	InvitesNobody   Invites = "nobody"
+)
+
+// EmailPurpose is what an email token proves the owner of an address wants.
+type EmailPurpose string
+
+const (
+	EmailVerify        EmailPurpose = "verify_email"   // the address is the account's
+	EmailResetPassword EmailPurpose = "reset_password" // the password is to be replaced
+)
+
+// Outcome is how a match ended.
+type Outcome string
+
+const (
+	OutcomeCompleted Outcome = "completed" // the game was played to the end
+	OutcomeVoid      Outcome = "void"      // the game was abandoned and doesn't count
+)
+
+// AuditAction is the kind of an audited action.
+type AuditAction string
+
+const (
+	AuditKick      AuditAction = "kick"       // a room owner kicked a player
+	AuditVoteKick  AuditAction = "vote_kick"  // a player was kicked by a vote, which the actor started
+	AuditCloseRoom AuditAction = "close_room" // an admin closed a room, removing everyone in it
+	AuditBackup    AuditAction = "backup"     // an admin downloaded a backup of the database
+	AuditReport    AuditAction = "report"     // an admin resolved a report, the target is the report
+)
+
+// ReportReason is what a player is reported for.
+type ReportReason string
+
+const (
+	ReportCheating ReportReason = "cheating"
+	ReportAbuse    ReportReason = "abuse"
+	ReportAfk      ReportReason = "afk"
+)
+
+// ReportStatus is where a report is in the review queue.
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"      // waiting for a moderator
+	ReportActioned  ReportStatus = "actioned"  // a moderator took action against the target
+	ReportDismissed ReportStatus = "dismissed" // a moderator found nothing to act on
+)
+
+// nameKey is what display names are compared by when checking they are unique.
+func nameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// HashToken returns the hash a session's or email token's secret is stored as.
+func HashToken(token string) string {
+	return fmt.Sprintf("%x", sha3.Sum256([]byte(token)))
+}
+
+// Default is the store used by the server. It is replaced on startup by the configured store.
+var Default Store = NewMemory()
+
+// Open opens a store using the named driver: "memory" (or "") keeps everything in memory,
+// "sqlite" and "postgres" use a database at dsn.
+func Open(driver, dsn string) (Store, error) {
+	if driver == "" || driver == "memory" {
+		return NewMemory(), nil
+	}
+	dialect, err := DriverDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	return OpenSQL(driver, dsn, dialect)
+}
+
+// DriverDialect returns the dialect of a SQL storage driver. Drivers are only built in with
+// the build tag of the same name, so a server built without it says which tag it needs.
+func DriverDialect(driver string) (Dialect, error) {
+	var dialect Dialect
+	switch driver {
+	case "sqlite":
+		dialect = DialectSQLite
+	case "postgres":
+		dialect = DialectPostgres
+	default:
+		return 0, fmt.Errorf("unknown storage driver %q", driver)
+	}
+	if !driverBuiltIn(driver) {
+		return 0, fmt.Errorf("storage driver %q isn't built in, build the server with -tags %s", driver, driver)
+	}
+	return dialect, nil
+}
+
+// driverBuiltIn returns whether a database/sql driver is registered.
+func driverBuiltIn(driver string) bool {
+	for _, d := range sql.Drivers() {
+		if d == driver {
+			return true
+		}
+	}
+	return false
+}
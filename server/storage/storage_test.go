@@ -0,0 +1,701 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testStore checks the behaviour every store has to share.
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	defer s.Close()
+
+	_, err := s.Account("u_missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	a := &Account{Id: "u_1", Name: "card shark", Avatar: Avatar{Eyes: 1, Mouth: 2, Color: 3}, Created: 1, Updated: 1}
+	assert.NoError(t, s.SaveAccount(a))
+	a.Name = "changed after saving"
+
+	got, err := s.Account("u_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "card shark", got.Name, "store should keep its own copy")
+	assert.Equal(t, Avatar{Eyes: 1, Mouth: 2, Color: 3}, got.Avatar)
+
+	got.Name = "renamed"
+	got.Updated = 2
+	assert.NoError(t, s.SaveAccount(got))
+	got, err = s.Account("u_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", got.Name)
+
+	got.Bio, got.FavoriteGame, got.NameChanged = "hi", "classic", 2
+	assert.NoError(t, s.SaveAccount(got))
+	got, err = s.AccountByName("RENAMED")
+	assert.NoError(t, err)
+	assert.Equal(t, "u_1", got.Id, "names should be looked up ignoring case")
+	assert.Equal(t, "hi", got.Bio)
+	assert.Equal(t, int64(2), got.NameChanged)
+	_, err = s.AccountByName("card shark")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, s.SaveAccount(&Account{Id: "u_taken", Name: "Renamed"}), ErrNameTaken, "names should be unique ignoring case")
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_unnamed"}))
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_unnamed_2"}), "accounts without names should not clash")
+
+	assert.NoError(t, s.SaveSession(&Session{Id: "s_2", AccountId: "u_1", TokenHash: "h2", UserAgent: "phone", Created: 2}))
+	assert.NoError(t, s.SaveSession(&Session{Id: "s_1", AccountId: "u_1", TokenHash: "h1", UserAgent: "laptop", Created: 1}))
+	assert.NoError(t, s.SaveSession(&Session{Id: "s_3", AccountId: "u_3", TokenHash: "h3", Created: 3}))
+	session, err := s.SessionByToken("h2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "s_2", session.Id)
+		assert.Equal(t, "u_1", session.AccountId)
+		assert.Equal(t, "phone", session.UserAgent)
+	}
+	sessions, err := s.Sessions("u_1")
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, "s_1", sessions[0].Id, "sessions should be listed oldest first")
+	}
+	assert.ErrorIs(t, s.DeleteSession("u_3", "s_1"), ErrNotFound, "sessions should only be revoked by their account")
+	assert.NoError(t, s.DeleteSession("u_1", "s_1"))
+	_, err = s.SessionByToken("h1")
+	assert.ErrorIs(t, err, ErrNotFound, "revoked tokens should stop working")
+	_, err = s.SessionByToken("h2")
+	assert.NoError(t, err, "revoking a session should keep the others")
+	assert.NoError(t, s.DeleteSessions("u_3"))
+	_, err = s.SessionByToken("h3")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	got.Email, got.EmailVerified, got.PasswordHash = "Shark@Example.com", 3, "hash"
+	assert.NoError(t, s.SaveAccount(got))
+	got, err = s.AccountByEmail("SHARK@example.com")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "u_1", got.Id, "emails should be looked up ignoring case")
+		assert.Equal(t, "shark@example.com", got.Email)
+		assert.Equal(t, int64(3), got.EmailVerified)
+		assert.Equal(t, "hash", got.PasswordHash)
+	}
+	got.TotpSecret, got.TotpEnabled, got.TotpStep, got.RecoveryCodes = "SECRET", 4, 5, []string{"c1", "c2"}
+	assert.NoError(t, s.SaveAccount(got))
+	got.RecoveryCodes[0] = "changed after saving"
+	got, err = s.Account("u_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SECRET", got.TotpSecret)
+		assert.Equal(t, int64(4), got.TotpEnabled)
+		assert.Equal(t, int64(5), got.TotpStep)
+		assert.Equal(t, []string{"c1", "c2"}, got.RecoveryCodes)
+	}
+	_, err = s.AccountByEmail("")
+	assert.ErrorIs(t, err, ErrNotFound, "accounts without an email should not be found by it")
+	assert.ErrorIs(t, s.SaveAccount(&Account{Id: "u_unnamed", Email: "shark@EXAMPLE.com"}), ErrEmailTaken, "emails should be unique ignoring case")
+
+	assert.NoError(t, s.SaveEmailToken(&EmailToken{TokenHash: "m1", AccountId: "u_1", Purpose: EmailVerify, Email: "shark@example.com", Created: 3, Expires: 4}))
+	assert.NoError(t, s.SaveEmailToken(&EmailToken{TokenHash: "m2", AccountId: "u_1", Purpose: EmailResetPassword, Email: "shark@example.com", Created: 3, Expires: 4}))
+	mailed, err := s.EmailToken("m1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "u_1", mailed.AccountId)
+		assert.Equal(t, EmailVerify, mailed.Purpose)
+		assert.Equal(t, "shark@example.com", mailed.Email)
+		assert.Equal(t, int64(4), mailed.Expires)
+	}
+	assert.NoError(t, s.DeleteEmailTokens("u_1", EmailVerify))
+	_, err = s.EmailToken("m1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.EmailToken("m2")
+	assert.NoError(t, err, "deleting tokens should keep the ones mailed for something else")
+
+	_, err = s.AvatarImage("u_1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.SaveAvatarImage(&AvatarImage{AccountId: "u_1", ContentType: "image/png", Data: []byte{1, 2, 3}, Updated: 2}))
+	img, err := s.AvatarImage("u_1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, img.Data)
+	assert.Equal(t, "image/png", img.ContentType)
+
+	got.Invites = InvitesEveryone
+	got.CardBack, got.TableTheme = "back_gold", "theme_felt"
+	assert.NoError(t, s.SaveAccount(got))
+	got, _ = s.Account("u_1")
+	assert.Equal(t, InvitesEveryone, got.Invites)
+	assert.Equal(t, "back_gold", got.CardBack)
+	assert.Equal(t, "theme_felt", got.TableTheme)
+
+	_, err = s.Friendship("u_1", "u_2")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.SaveFriendship(&Friendship{AccountId: "u_1", FriendId: "u_2", Created: 1}))
+	assert.NoError(t, s.SaveFriendship(&Friendship{AccountId: "u_3", FriendId: "u_1", Accepted: 3, Created: 2}))
+	f, err := s.Friendship("u_2", "u_1")
+	assert.NoError(t, err, "friendships should be found from either side")
+	assert.Equal(t, "u_1", f.AccountId)
+	f.Accepted = 4
+	assert.NoError(t, s.SaveFriendship(f))
+	friendships, err := s.Friendships("u_1")
+	assert.NoError(t, err)
+	if assert.Len(t, friendships, 2) {
+		assert.Equal(t, int64(4), friendships[0].Accepted)
+		assert.Equal(t, "u_3", friendships[1].AccountId)
+	}
+	assert.NoError(t, s.DeleteFriendship("u_2", "u_1"))
+	assert.ErrorIs(t, s.DeleteFriendship("u_1", "u_2"), ErrNotFound)
+
+	assert.NoError(t, s.SaveBlock(&Block{AccountId: "u_1", BlockedId: "u_2", Created: 1}))
+	assert.NoError(t, s.SaveBlock(&Block{AccountId: "u_1", BlockedId: "u_3", Created: 2}))
+	assert.NoError(t, s.SaveBlock(&Block{AccountId: "u_1", BlockedId: "u_2", Created: 3}), "blocking twice should not fail")
+	blocked, err := s.Blocked("u_1", "u_2")
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+	blocked, err = s.Blocked("u_2", "u_1")
+	assert.NoError(t, err)
+	assert.False(t, blocked, "blocks should only go one way")
+	assert.NoError(t, s.DeleteBlock("u_1", "u_3"))
+	assert.ErrorIs(t, s.DeleteBlock("u_1", "u_3"), ErrNotFound)
+	blocks, err := s.Blocks("u_1")
+	assert.NoError(t, err)
+	if assert.Len(t, blocks, 1) {
+		assert.Equal(t, "u_2", blocks[0].BlockedId)
+	}
+
+	assert.NoError(t, s.DeleteAccount("u_1"))
+	assert.ErrorIs(t, s.DeleteAccount("u_1"), ErrNotFound)
+	blocks, err = s.Blocks("u_1")
+	assert.NoError(t, err)
+	assert.Empty(t, blocks, "blocks should be deleted with the account")
+	friendships, err = s.Friendships("u_3")
+	assert.NoError(t, err)
+	assert.Empty(t, friendships, "friendships should be deleted with the account")
+	_, err = s.AvatarImage("u_1")
+	assert.ErrorIs(t, err, ErrNotFound, "the avatar image should be deleted with the account")
+	_, err = s.EmailToken("m2")
+	assert.ErrorIs(t, err, ErrNotFound, "email tokens should be deleted with the account")
+	_, err = s.SessionByToken("h2")
+	assert.ErrorIs(t, err, ErrNotFound, "sessions should be deleted with the account")
+
+	m := &Match{
+		Id: "m_1", RoomId: "r_1", GameType: "classic", Ranked: true, Rules: json.RawMessage(`{"turnTimeout":30}`), Outcome: OutcomeCompleted,
+		Players: []MatchPlayer{{Id: "p_1", AccountId: "u_1", Name: "a", Score: 3, Rank: 1}, {Id: "p_2", Name: "b", Rank: 2}},
+		Started: 10, Ended: 20,
+	}
+	assert.NoError(t, s.SaveMatch(m))
+	gotMatch, err := s.Match("m_1")
+	assert.NoError(t, err)
+	assert.Equal(t, m, gotMatch)
+	_, err = s.Match("m_missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, s.SaveMatch(&Match{Id: "m_2", RoomId: "r_1", Outcome: OutcomeVoid, Players: []MatchPlayer{{Id: "p_2"}}, Ended: 30}))
+	assert.NoError(t, s.SaveMatch(&Match{Id: "m_3", RoomId: "r_2", Outcome: OutcomeCompleted, Players: []MatchPlayer{{Id: "p_1", AccountId: "u_1"}}, Ended: 40}))
+
+	matches, err := s.Matches(MatchQuery{RoomId: "r_1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m_2", "m_1"}, matchIds(matches), "should be newest first")
+
+	matches, err = s.Matches(MatchQuery{AccountId: "u_1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m_3", "m_1"}, matchIds(matches))
+
+	matches, err = s.Matches(MatchQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m_3", "m_2"}, matchIds(matches))
+	matches, err = s.Matches(MatchQuery{Before: matches[1].Ended, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m_1"}, matchIds(matches), "should continue after the cursor")
+	assert.NoError(t, s.SaveMatch(&Match{Id: "m_4", RoomId: "r_3", Outcome: OutcomeCompleted, Players: []MatchPlayer{{Id: "p_1"}}, Ended: 30}))
+	matches, err = s.Matches(MatchQuery{Before: 30, BeforeId: "m_2", Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m_4", "m_1"}, matchIds(matches), "matches that ended with the cursor should be on the next page")
+
+	r := &Replay{MatchId: "m_1", Seed: 42, Events: json.RawMessage(`[{"type":"draw"}]`), Created: 20}
+	assert.NoError(t, s.SaveReplay(r))
+	gotReplay, err := s.Replay("m_1")
+	assert.NoError(t, err)
+	assert.Equal(t, r, gotReplay)
+
+	assert.NoError(t, s.SaveReplay(&Replay{MatchId: "m_2", Seed: 7, Events: json.RawMessage(`[]`), Created: 30}))
+	replays, err := s.Replays(ReplayQuery{Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, replays, 1) {
+		assert.Equal(t, "m_2", replays[0].MatchId, "should be newest first")
+		assert.Empty(t, replays[0].Events, "listing should leave out events")
+	}
+	replays, err = s.Replays(ReplayQuery{Before: 30})
+	assert.NoError(t, err)
+	assert.Len(t, replays, 1)
+	assert.NoError(t, s.SaveReplay(&Replay{MatchId: "m_3", Seed: 8, Events: json.RawMessage(`[]`), Created: 30}))
+	replays, err = s.Replays(ReplayQuery{Before: 30, BeforeId: "m_2"})
+	assert.NoError(t, err)
+	if assert.Len(t, replays, 2) {
+		assert.Equal(t, "m_3", replays[0].MatchId, "replays created with the cursor should be on the next page")
+	}
+
+	n, err := s.DeleteReplaysBefore(25)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = s.Replay("m_1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = s.Rating("u_1", "classic")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.SaveRatings(
+		[]*PlayerRating{{AccountId: "u_1", GameType: "classic", Rating: 1600, Deviation: 200, Volatility: 0.06, Games: 1, Updated: 1}},
+		[]*RatingChange{{AccountId: "u_1", GameType: "classic", MatchId: "m_1", Before: 1500, After: 1600, Deviation: 200, Created: 1}},
+	))
+	assert.NoError(t, s.SaveRatings(
+		[]*PlayerRating{
+			{AccountId: "u_1", GameType: "classic", Rating: 1650, Deviation: 180, Volatility: 0.06, Games: 2, Updated: 2},
+			{AccountId: "u_1", GameType: "teams", Rating: 1400, Deviation: 300, Volatility: 0.06, Games: 1, Updated: 2},
+		},
+		[]*RatingChange{
+			{AccountId: "u_1", GameType: "classic", MatchId: "m_2", Before: 1600, After: 1650, Deviation: 180, Created: 2},
+			{AccountId: "u_1", GameType: "teams", MatchId: "m_2", Before: 1500, After: 1400, Deviation: 300, Created: 2},
+		},
+	))
+	pr, err := s.Rating("u_1", "classic")
+	assert.NoError(t, err)
+	assert.Equal(t, 1650.0, pr.Rating)
+	assert.Equal(t, 2, pr.Games)
+	ratings, err := s.Ratings("u_1")
+	assert.NoError(t, err)
+	if assert.Len(t, ratings, 2) {
+		assert.Equal(t, "classic", ratings[0].GameType)
+	}
+	inactive, err := s.InactiveRatings(2)
+	assert.NoError(t, err)
+	assert.Empty(t, inactive)
+	assert.NoError(t, s.SaveRatings([]*PlayerRating{{AccountId: "u_1", GameType: "teams", Rating: 1390, Deviation: 310, Volatility: 0.06, Games: 1, Updated: 2, Decayed: 5}}, nil))
+	inactive, err = s.InactiveRatings(3)
+	assert.NoError(t, err)
+	if assert.Len(t, inactive, 2) {
+		assert.Equal(t, "teams", inactive[1].GameType)
+		assert.Equal(t, int64(5), inactive[1].Decayed)
+	}
+	history, err := s.RatingHistory(RatingQuery{AccountId: "u_1", GameType: "classic", Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, "m_2", history[0].MatchId)
+		assert.Equal(t, 1600.0, history[0].Before)
+	}
+	history, err = s.RatingHistory(RatingQuery{AccountId: "u_1", GameType: "classic", Before: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, "m_1", history[0].MatchId)
+	}
+	assert.NoError(t, s.SaveRatings(nil, []*RatingChange{{AccountId: "u_1", GameType: "classic", MatchId: "m_3", Before: 1650, After: 1660, Deviation: 170, Created: 2}}))
+	history, err = s.RatingHistory(RatingQuery{AccountId: "u_1", GameType: "classic", Before: 2, BeforeId: "m_2"})
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, "m_3", history[0].MatchId, "changes created with the cursor should be on the next page")
+	}
+
+	assert.NoError(t, s.SaveLeaderboardEntries([]*LeaderboardEntry{
+		{Season: 1, GameType: "classic", AccountId: "u_1", Name: "a", Score: 1600, Games: 1, Updated: 1},
+		{Season: 1, GameType: "classic", AccountId: "u_2", Name: "b", Score: 1700, Games: 1, Updated: 1},
+		{Season: 1, GameType: "classic", AccountId: "u_3", Name: "c", Score: 1600, Games: 1, Updated: 2},
+		{Season: 1, GameType: "", AccountId: "u_1", Name: "a", Score: 100, Games: 1, Updated: 1},
+		{Season: 2, GameType: "classic", AccountId: "u_1", Name: "a", Score: 1500, Games: 1, Updated: 3},
+	}))
+	assert.NoError(t, s.SaveLeaderboardEntries([]*LeaderboardEntry{
+		{Season: 1, GameType: "classic", AccountId: "u_2", Name: "b", Score: 1550, Games: 2, Updated: 3},
+	}))
+	board, err := s.Leaderboard(LeaderboardQuery{Season: 1, GameType: "classic"})
+	assert.NoError(t, err)
+	if assert.Len(t, board, 3) {
+		assert.Equal(t, []string{"u_1", "u_3", "u_2"}, []string{board[0].AccountId, board[1].AccountId, board[2].AccountId},
+			"equal scores should be ordered by who reached them first")
+		assert.Equal(t, []int{1, 1, 3}, []int{board[0].Rank, board[1].Rank, board[2].Rank})
+	}
+	board, err = s.Leaderboard(LeaderboardQuery{Season: 1, GameType: "classic", Offset: 1, Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, board, 1) {
+		assert.Equal(t, "u_3", board[0].AccountId)
+		assert.Equal(t, 1, board[0].Rank)
+	}
+	e, err := s.LeaderboardEntry(1, "classic", "u_2")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, e.Rank)
+	assert.Equal(t, 2, e.Games)
+	_, err = s.LeaderboardEntry(2, "classic", "u_2")
+	assert.ErrorIs(t, err, ErrNotFound)
+	gameTypes, err := s.LeaderboardGameTypes(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "classic"}, gameTypes)
+
+	_, err = s.SeasonArchive(1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.SaveSeasonArchive(&SeasonArchive{Season: 1, Archived: 5}))
+	archive, err := s.SeasonArchive(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), archive.Archived)
+
+	assert.NoError(t, s.SaveAchievements([]*AchievementProgress{
+		{AccountId: "u_1", AchievementId: "wins", Progress: 3, Updated: 1},
+		{AccountId: "u_1", AchievementId: "first", Progress: 1, Unlocked: 1, Updated: 1},
+		{AccountId: "u_2", AchievementId: "wins", Progress: 1, Updated: 1},
+	}))
+	assert.NoError(t, s.SaveAchievements([]*AchievementProgress{
+		{AccountId: "u_1", AchievementId: "wins", Progress: 4, Updated: 2},
+	}))
+	progress, err := s.Achievements("u_1")
+	assert.NoError(t, err)
+	if assert.Len(t, progress, 2) {
+		assert.Equal(t, "first", progress[0].AchievementId)
+		assert.Equal(t, int64(1), progress[0].Unlocked)
+		assert.Equal(t, 4, progress[1].Progress)
+	}
+
+	suspended := &SuspendedGame{
+		Id:        "s_1",
+		RoomName:  "long game",
+		GameType:  "classic",
+		Players:   []MatchPlayer{{Id: "p_1", AccountId: "u_2", Name: "bob", Score: 3}, {Id: "p_2", AccountId: "u_3", Name: "carol"}},
+		State:     json.RawMessage(`{"currentTurn":1}`),
+		Started:   1,
+		Suspended: 2,
+	}
+	assert.NoError(t, s.SaveSuspendedGame(suspended))
+	suspended.Id, suspended.Suspended = "s_2", 3
+	suspended.Players = suspended.Players[1:]
+	assert.NoError(t, s.SaveSuspendedGame(suspended))
+	games, err := s.SuspendedGames("u_3")
+	assert.NoError(t, err)
+	if assert.Len(t, games, 2) {
+		assert.Equal(t, "s_2", games[0].Id, "newest games should come first")
+	}
+	games, err = s.SuspendedGames("u_2")
+	assert.NoError(t, err)
+	assert.Len(t, games, 1)
+	g, err := s.SuspendedGame("s_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "bob", g.Players[0].Name)
+		assert.JSONEq(t, `{"currentTurn":1}`, string(g.State))
+	}
+	assert.NoError(t, s.DeleteSuspendedGame("s_2"))
+	assert.ErrorIs(t, s.DeleteSuspendedGame("s_2"), ErrNotFound)
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_2", Name: "bob"}))
+	assert.NoError(t, s.DeleteAccount("u_2"))
+	_, err = s.SuspendedGame("s_1")
+	assert.ErrorIs(t, err, ErrNotFound, "games should be deleted with the accounts they can't be resumed without")
+
+	report := &Report{
+		Id:         "rep_1",
+		Reason:     ReportAbuse,
+		ReporterId: "u_2",
+		TargetId:   "u_3",
+		TargetName: "carol",
+		Chat:       []ReportChat{{PlayerId: "p_2", Name: "carol", Message: "rude", Time: 1}},
+		Events:     json.RawMessage(`[{"time":1}]`),
+		Status:     ReportOpen,
+		Created:    1,
+	}
+	assert.NoError(t, s.SaveReport(report))
+	report.Id, report.Reason, report.Created = "rep_2", ReportAfk, 2
+	assert.NoError(t, s.SaveReport(report))
+	report.Status, report.Resolution, report.Resolved = ReportDismissed, "nothing wrong", 3
+	assert.NoError(t, s.SaveReport(report))
+	reports, err := s.Reports(ReportQuery{Status: ReportOpen})
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, "rep_1", reports[0].Id)
+		assert.Empty(t, reports[0].Chat, "pages of reports should leave out the evidence")
+	}
+	reports, err = s.Reports(ReportQuery{TargetId: "u_3", Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, "rep_2", reports[0].Id, "newest reports should come first")
+		assert.Equal(t, "nothing wrong", reports[0].Resolution)
+	}
+	report.Id = "rep_3"
+	assert.NoError(t, s.SaveReport(report))
+	reports, err = s.Reports(ReportQuery{Before: 2, BeforeId: "rep_2"})
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 2) {
+		assert.Equal(t, "rep_3", reports[0].Id, "reports filed with the cursor should be on the next page")
+	}
+	filed, err := s.Report("rep_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "rude", filed.Chat[0].Message)
+		assert.JSONEq(t, `[{"time":1}]`, string(filed.Events))
+	}
+
+	preset := &RoomPreset{Id: "rp_1", AccountId: "u_3", Name: "quick", Code: "pabc", Settings: json.RawMessage(`{"maxPlayers":2}`), Created: 1, Updated: 1}
+	assert.NoError(t, s.SaveRoomPreset(preset))
+	preset.Id, preset.Name, preset.Code, preset.Created = "rp_2", "slow", "pdef", 2
+	assert.NoError(t, s.SaveRoomPreset(preset))
+	preset.Name, preset.Updated = "slower", 3
+	assert.NoError(t, s.SaveRoomPreset(preset))
+	presets, err := s.RoomPresets("u_3")
+	assert.NoError(t, err)
+	if assert.Len(t, presets, 2) {
+		assert.Equal(t, "rp_1", presets[0].Id, "oldest presets should come first")
+		assert.Equal(t, "slower", presets[1].Name)
+	}
+	p, err := s.RoomPresetByCode("pabc")
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"maxPlayers":2}`, string(p.Settings))
+	}
+	assert.NoError(t, s.DeleteRoomPreset("rp_1"))
+	assert.ErrorIs(t, s.DeleteRoomPreset("rp_1"), ErrNotFound)
+	_, err = s.RoomPresetByCode("pabc")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_3", Name: "carol"}))
+	assert.NoError(t, s.DeleteAccount("u_3"))
+	_, err = s.RoomPreset("rp_2")
+	assert.ErrorIs(t, err, ErrNotFound, "presets should be deleted with their account")
+	filed, err = s.Report("rep_1")
+	if assert.NoError(t, err, "reports should be kept when their target is deleted") {
+		assert.Equal(t, DeletedName, filed.TargetName)
+	}
+
+	stats, err := s.Stats("u_4")
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+	assert.NoError(t, s.SaveStats(
+		[]*PlayerStats{
+			{AccountId: "u_4", GameType: "classic", Games: 2, Wins: 1, TotalScore: 9, Streak: 1, LongestStreak: 1, Updated: 1},
+			{AccountId: "u_4", GameType: "", Games: 2, Wins: 1, TotalScore: 9, Streak: 1, LongestStreak: 1, Updated: 1},
+		},
+		[]*OpponentStats{
+			{AccountId: "u_4", OpponentId: "u_5", Name: "eve", Games: 1, Updated: 1},
+			{AccountId: "u_4", OpponentId: "u_6", Name: "frank", Games: 2, Wins: 1, Updated: 1},
+		},
+	))
+	assert.NoError(t, s.SaveStats([]*PlayerStats{{AccountId: "u_4", GameType: "classic", Games: 3, Wins: 2, TotalScore: 12, Streak: 2, LongestStreak: 2, Updated: 2}}, nil))
+	stats, err = s.Stats("u_4")
+	assert.NoError(t, err)
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, "", stats[0].GameType, "overall stats should come first")
+		assert.Equal(t, 3, stats[1].Games)
+		assert.Equal(t, 2, stats[1].LongestStreak)
+	}
+	opponents, err := s.Opponents("u_4", 1)
+	assert.NoError(t, err)
+	if assert.Len(t, opponents, 1) {
+		assert.Equal(t, "frank", opponents[0].Name, "opponents with the most games should come first")
+	}
+	o, err := s.Opponent("u_4", "u_5")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, o.Games)
+	}
+	_, err = s.Opponent("u_5", "u_4")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_4", Name: "dave"}))
+	assert.NoError(t, s.DeleteAccount("u_4"))
+	stats, err = s.Stats("u_4")
+	assert.NoError(t, err)
+	assert.Empty(t, stats, "stats should be deleted with the account")
+	_, err = s.Opponent("u_4", "u_6")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, s.SaveChallengeResult(&ChallengeResult{Day: "2024-06-01", GameType: "classic", AccountId: "u_5", Name: "eve", Started: 1}, nil))
+	assert.NoError(t, s.SaveChallengeResult(&ChallengeResult{Day: "2024-06-01", GameType: "classic", AccountId: "u_6", Name: "frank", Score: 3, Started: 1, Completed: 3}, nil))
+	assert.NoError(t, s.SaveChallengeResult(&ChallengeResult{Day: "2024-06-01", GameType: "classic", AccountId: "u_7", Name: "grace", Score: 3, Started: 1, Completed: 2}, nil))
+	assert.NoError(t, s.SaveChallengeResult(
+		&ChallengeResult{Day: "2024-06-01", GameType: "classic", AccountId: "u_5", Name: "eve", Score: 5, Started: 1, Completed: 4},
+		&ChallengeStreak{AccountId: "u_5", GameType: "classic", Streak: 1, LongestStreak: 1, LastDay: "2024-06-01"},
+	))
+	results, err := s.ChallengeResults("2024-06-01", "classic", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, "eve", results[0].Name)
+		assert.Equal(t, "grace", results[1].Name, "equal scores should be ordered by who completed the challenge first")
+	}
+	results, err = s.ChallengeResults("2024-06-02", "classic", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	result, err := s.ChallengeResult("2024-06-01", "classic", "u_5")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 5, result.Score)
+	}
+	_, err = s.ChallengeResult("2024-06-01", "teams", "u_5")
+	assert.ErrorIs(t, err, ErrNotFound)
+	streak, err := s.ChallengeStreak("u_5", "classic")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "2024-06-01", streak.LastDay)
+	}
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_5", Name: "eve"}))
+	assert.NoError(t, s.DeleteAccount("u_5"))
+	_, err = s.ChallengeResult("2024-06-01", "classic", "u_5")
+	assert.ErrorIs(t, err, ErrNotFound, "challenge results should be deleted with the account")
+	_, err = s.ChallengeStreak("u_5", "classic")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, s.SaveCosmetics([]*CosmeticUnlock{
+		{AccountId: "u_9", CosmeticId: "theme_felt", Unlocked: 2},
+		{AccountId: "u_9", CosmeticId: "back_gold", Unlocked: 1},
+	}))
+	assert.NoError(t, s.SaveCosmetics([]*CosmeticUnlock{{AccountId: "u_9", CosmeticId: "back_gold", Unlocked: 3}}))
+	unlocks, err := s.Cosmetics("u_9")
+	assert.NoError(t, err)
+	if assert.Len(t, unlocks, 2) {
+		assert.Equal(t, "back_gold", unlocks[0].CosmeticId)
+		assert.Equal(t, int64(1), unlocks[0].Unlocked, "unlocking an item again should keep the first unlock time")
+	}
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_9", Name: "ivan"}))
+	assert.NoError(t, s.DeleteAccount("u_9"))
+	unlocks, err = s.Cosmetics("u_9")
+	assert.NoError(t, err)
+	assert.Empty(t, unlocks, "cosmetics should be deleted with the account")
+
+	_, err = s.Experience("u_8")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.SaveProgression(&Experience{AccountId: "u_8", XP: 40, Updated: 1}, []*QuestProgress{
+		{AccountId: "u_8", QuestId: "win_weekly", Period: "2024-W23", Progress: 1, Updated: 1},
+		{AccountId: "u_8", QuestId: "play_daily", Period: "2024-06-03", Progress: 2, Completed: 1, Updated: 1},
+	}))
+	assert.NoError(t, s.SaveProgression(&Experience{AccountId: "u_8", XP: 60, Updated: 2}, []*QuestProgress{
+		{AccountId: "u_8", QuestId: "win_weekly", Period: "2024-W23", Progress: 2, Updated: 2},
+	}))
+	xp, err := s.Experience("u_8")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 60, xp.XP)
+	}
+	quests, err := s.Quests("u_8", "2024-W23")
+	assert.NoError(t, err)
+	if assert.Len(t, quests, 1, "only quests of the period should be loaded") {
+		assert.Equal(t, 2, quests[0].Progress)
+	}
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_8", Name: "heidi"}))
+	assert.NoError(t, s.DeleteAccount("u_8"))
+	_, err = s.Experience("u_8")
+	assert.ErrorIs(t, err, ErrNotFound, "experience should be deleted with the account")
+	quests, err = s.Quests("u_8", "2024-06-03")
+	assert.NoError(t, err)
+	assert.Empty(t, quests)
+
+	assert.NoError(t, s.SaveAuditEntry(&AuditEntry{Id: "a_1", Action: AuditKick, ActorId: "u_1", TargetId: "p_guest", RoomId: "r_1", Created: 1}))
+	assert.NoError(t, s.SaveAuditEntry(&AuditEntry{Id: "a_2", Action: AuditCloseRoom, ActorId: "u_2", TargetId: "r_1", Reason: "spam", Created: 2}))
+	assert.NoError(t, s.SaveAuditEntry(&AuditEntry{Id: "a_3", Action: AuditVoteKick, ActorId: "u_1", TargetId: "u_2", RoomId: "r_1", Created: 3}))
+	entries, err := s.AuditLog(AuditQuery{Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "a_3", entries[0].Id, "the newest entries should come first")
+		assert.Equal(t, "spam", entries[1].Reason)
+	}
+	entries, err = s.AuditLog(AuditQuery{ActorId: "u_1", Before: 3})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, AuditKick, entries[0].Action)
+	}
+	entries, err = s.AuditLog(AuditQuery{TargetId: "r_1", Action: AuditCloseRoom})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.NoError(t, s.SaveAuditEntry(&AuditEntry{Id: "a_4", Action: AuditKick, ActorId: "u_2", TargetId: "p_guest", RoomId: "r_2", Created: 3}))
+	entries, err = s.AuditLog(AuditQuery{Before: 3, BeforeId: "a_3", Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "a_4", entries[0].Id, "entries taken with the cursor should be on the next page")
+	}
+
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_10", Name: "judy"}))
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_11", Name: "mallory"}))
+	assert.NoError(t, s.SaveMatch(&Match{Id: "m_10", RoomId: "r_10", GameType: "classic", Outcome: OutcomeCompleted, Ended: 10,
+		Players: []MatchPlayer{{Id: "p_1", AccountId: "u_10", Name: "judy", Rank: 1}, {Id: "p_2", AccountId: "u_11", Name: "mallory", Rank: 2}}}))
+	assert.NoError(t, s.SaveRatings([]*PlayerRating{{AccountId: "u_10", GameType: "classic", Rating: 1510, Games: 1}},
+		[]*RatingChange{{AccountId: "u_10", GameType: "classic", MatchId: "m_10", Before: 1500, After: 1510, Created: 10}}))
+	assert.NoError(t, s.SaveLeaderboardEntries([]*LeaderboardEntry{{Season: 1, GameType: "classic", AccountId: "u_10", Name: "judy", Score: 1510}}))
+	assert.NoError(t, s.SaveProgression(&Experience{AccountId: "u_10", XP: 50}, []*QuestProgress{
+		{AccountId: "u_10", QuestId: "play_daily", Period: "2024-06-03", Progress: 1},
+		{AccountId: "u_10", QuestId: "win_weekly", Period: "2024-W23", Progress: 1},
+	}))
+	assert.NoError(t, s.SaveChallengeResult(&ChallengeResult{Day: "2024-06-03", GameType: "classic", AccountId: "u_10", Name: "judy"},
+		&ChallengeStreak{AccountId: "u_10", GameType: "classic", Streak: 1, LongestStreak: 1, LastDay: "2024-06-03"}))
+	assert.NoError(t, s.SaveAuditEntry(&AuditEntry{Id: "a_10", Action: AuditKick, ActorId: "u_11", TargetId: "u_10", TargetName: "judy", Created: 10}))
+	data, err := Export(s, "u_10")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "judy", data.Account.Name)
+		assert.Len(t, data.Matches, 1)
+		assert.Len(t, data.RatingChanges, 1)
+		assert.Len(t, data.Leaderboards, 1)
+		assert.Len(t, data.Quests, 2, "quests of every period should be exported")
+		assert.Len(t, data.Challenges, 1)
+		assert.Len(t, data.ChallengeStreaks, 1)
+		assert.Len(t, data.AuditLog, 1)
+	}
+	assert.NoError(t, s.DeleteAccount("u_10"))
+	matches, err = s.Matches(MatchQuery{AccountId: "u_11"})
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1, "matches should stay in the history of the other players") {
+		assert.Equal(t, MatchPlayer{Id: "p_1", Name: DeletedName, Rank: 1}, matches[0].Players[0])
+	}
+	matches, err = s.Matches(MatchQuery{AccountId: "u_10"})
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+	ratings, err = s.Ratings("u_10")
+	assert.NoError(t, err)
+	assert.Empty(t, ratings, "ratings should be deleted with the account")
+	board, err = s.LeaderboardEntries("u_10")
+	assert.NoError(t, err)
+	assert.Empty(t, board, "leaderboard entries should be deleted with the account")
+	audited, err := s.AuditLog(AuditQuery{TargetId: "u_10"})
+	assert.NoError(t, err)
+	if assert.Len(t, audited, 1, "the audit log should be kept") {
+		assert.Equal(t, DeletedName, audited[0].TargetName)
+	}
+
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_20", Name: "gone", Deleted: 5, Created: 1, Updated: 5}))
+	assert.NoError(t, s.SaveAccount(&Account{Id: "u_21", Name: "going", Deleted: 3, Created: 1, Updated: 3}))
+	deleted, err := s.DeletedAccounts(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u_21", "u_20"}, deleted, "oldest deletions should be listed first")
+	deleted, err = s.DeletedAccounts(4)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u_21"}, deleted)
+	got, err = s.Account("u_20")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(5), got.Deleted)
+	}
+}
+
+func matchIds(matches []*Match) []string {
+	ids := []string{}
+	for _, m := range matches {
+		ids = append(ids, m.Id)
+	}
+	return ids
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestPruneReplays(t *testing.T) {
+	s := NewMemory()
+	now := time.Now()
+	s.SaveReplay(&Replay{MatchId: "m_old", Created: now.Add(-48 * time.Hour).UnixMilli()})
+	s.SaveReplay(&Replay{MatchId: "m_new", Created: now.UnixMilli()})
+
+	n, err := PruneReplays(s, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = s.Replay("m_new")
+	assert.NoError(t, err)
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	s := NewMemory()
+	now := time.Now()
+	s.SaveAccount(&Account{Id: "u_old", Name: "old", Deleted: now.Add(-48 * time.Hour).UnixMilli()})
+	s.SaveAccount(&Account{Id: "u_new", Name: "new", Deleted: now.UnixMilli()})
+	s.SaveAccount(&Account{Id: "u_kept", Name: "kept"})
+
+	n, err := PurgeDeletedAccounts(s, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = s.Account("u_old")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Account("u_new")
+	assert.NoError(t, err, "accounts should be kept until the window is over")
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	_, err := Open("mongodb", "")
+	assert.Error(t, err)
+}
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM accounts WHERE id = ? AND name = ?`
+	assert.Equal(t, query, DialectSQLite.rebind(query))
+	assert.Equal(t, `SELECT id FROM accounts WHERE id = $1 AND name = $2`, DialectPostgres.rebind(query))
+}
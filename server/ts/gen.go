@@ -4,14 +4,37 @@ import (
 	"cardgame/card"
 	"cardgame/deck"
 	"cardgame/game"
+	"fmt"
+	"os"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/tkrajina/typescriptify-golang-structs/typescriptify"
 )
 
-func main() {
-	converter := typescriptify.New().WithInterface(true).
+const outputFile = "ts/models.ts"
+
+const header = "/* Do not change, this code is generated from Golang structs */\n\n"
+
+// sortedKeys returns the keys of a message type map in a stable order,
+// so the generated file does not change between runs.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// constructorName turns a Go message type name into a TypeScript function name, like "ClientJoin" -> "clientJoin".
+func constructorName(typeName string) string {
+	return strings.ToLower(typeName[:1]) + typeName[1:]
+}
+
+func newConverter() *typescriptify.TypeScriptify {
+	converter := typescriptify.New().WithInterface(true).WithBackupDir("").
 		Add(game.Room{}).
 		Add(game.Player{}).
 		Add(deck.Deck{}).
@@ -19,44 +42,59 @@ func main() {
 		Add(card.WildCard{}).
 		AddEnum(game.TSAllGamePhases).
 		AddEnum(game.TSAllPlayModes).
+		AddEnum(game.TSAllResumeModes).
+		AddEnum(game.TSAllAfkPolicies).
 		AddEnum(card.TSAllCardTypes)
 
-	for _, typ := range game.ClientMessageTypes {
-		converter = converter.Add(typ)
+	for _, t := range sortedKeys(game.ClientMessageTypes) {
+		converter = converter.Add(game.ClientMessageTypes[t])
 	}
 
-	for _, typ := range game.ServerMessageTypes {
-		converter = converter.Add(typ)
+	for _, t := range sortedKeys(game.ServerMessageTypes) {
+		converter = converter.Add(game.ServerMessageTypes[t])
 	}
 
 	var extras strings.Builder
 	// export type ClientMessage = { type: "join" } & ClientJoin | { type: "leave" } & ClientLeave;
 	extras.WriteString("export type ClientMessage =\n")
-	for t, typ := range game.ClientMessageTypes {
-		typeName := reflect.TypeOf(typ).Name()
-		extras.WriteString("    | ")
-		extras.WriteString("({ type: \"")
-		extras.WriteString(t)
-		extras.WriteString("\" } & ")
-		extras.WriteString(typeName)
-		extras.WriteString(")\n")
+	for _, t := range sortedKeys(game.ClientMessageTypes) {
+		typeName := reflect.TypeOf(game.ClientMessageTypes[t]).Name()
+		fmt.Fprintf(&extras, "    | ({ type: %q } & %s)\n", t, typeName)
 	}
 	extras.WriteString("\n")
 	extras.WriteString("export type ServerMessage =\n")
-	for t, typ := range game.ServerMessageTypes {
-		typeName := reflect.TypeOf(typ).Name()
-		extras.WriteString("    | ")
-		extras.WriteString("({ room: Room; type: \"")
-		extras.WriteString(t)
-		extras.WriteString("\" } & ")
-		extras.WriteString(typeName)
-		extras.WriteString(")\n")
+	for _, t := range sortedKeys(game.ServerMessageTypes) {
+		typeName := reflect.TypeOf(game.ServerMessageTypes[t]).Name()
+		fmt.Fprintf(&extras, "    | ({ room: Room; type: %q } & %s)\n", t, typeName)
+	}
+	extras.WriteString("\n")
+	// export const clientJoin = (m: ClientJoin): ClientMessage => ({ type: "join", ...m });
+	for _, t := range sortedKeys(game.ClientMessageTypes) {
+		typeName := reflect.TypeOf(game.ClientMessageTypes[t]).Name()
+		fmt.Fprintf(&extras, "export const %s = (m: %s): ClientMessage => ({ type: %q, ...m });\n", constructorName(typeName), typeName, t)
 	}
 
-	converter.AddImport(extras.String())
+	converter.AddImport(strings.TrimSuffix(extras.String(), "\n"))
 
-	err := converter.ConvertToFile("ts/models.ts")
+	return converter
+}
+
+// generate returns the contents of the generated TypeScript models file.
+func generate() (string, error) {
+	converted, err := newConverter().Convert(nil)
+	if err != nil {
+		return "", err
+	}
+	return header + converted + "\n", nil
+}
+
+func main() {
+	contents, err := generate()
 	if err != nil {
 		panic(err.Error())
 	}
+
+	if err := os.WriteFile(outputFile, []byte(contents), 0644); err != nil {
+		panic(err.Error())
+	}
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelsUpToDate(t *testing.T) {
+	want, err := generate()
+	assert.NoError(t, err)
+
+	have, err := os.ReadFile("models.ts")
+	assert.NoError(t, err)
+	assert.Equal(t, want, string(have), "ts/models.ts is out of date, run go generate")
+}
+
+func TestGenerateStable(t *testing.T) {
+	first, err := generate()
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		again, err := generate()
+		assert.NoError(t, err)
+		assert.Equal(t, first, again, "generated output should not depend on map iteration order")
+	}
+}
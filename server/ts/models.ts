@@ -2,29 +2,100 @@
 
 export type ClientMessage =
     | ({ type: "change_details" } & ClientChangeDetails)
+    | ({ type: "channel_chat" } & ClientChannelChat)
+    | ({ type: "channel_join" } & ClientChannelJoin)
+    | ({ type: "channel_leave" } & ClientChannelLeave)
+    | ({ type: "chat" } & ClientChat)
+    | ({ type: "draw" } & ClientDraw)
+    | ({ type: "end" } & ClientEnd)
+    | ({ type: "invite" } & ClientInvite)
     | ({ type: "join" } & ClientJoin)
-    | ({ type: "leave" } & ClientLeave)
     | ({ type: "kick" } & ClientKick)
-    | ({ type: "start" } & ClientStart)
-    | ({ type: "draw" } & ClientDraw)
+    | ({ type: "leave" } & ClientLeave)
+    | ({ type: "mute" } & ClientMute)
+    | ({ type: "predict" } & ClientPredict)
+    | ({ type: "replay" } & ClientReplay)
+    | ({ type: "report" } & ClientReport)
+    | ({ type: "resume" } & ClientResume)
     | ({ type: "send" } & ClientSend)
-    | ({ type: "chat" } & ClientChat)
+    | ({ type: "sign_in" } & ClientSignIn)
+    | ({ type: "start" } & ClientStart)
+    | ({ type: "vote" } & ClientVote)
+    | ({ type: "vote_kick" } & ClientVoteKick)
+    | ({ type: "vote_suspend" } & ClientVoteSuspend)
 
 export type ServerMessage =
-    | ({ room: Room; type: "join" } & ServerJoin)
-    | ({ room: Room; type: "leave" } & ServerLeave)
-    | ({ room: Room; type: "draw" } & ServerDraw)
-    | ({ room: Room; type: "wild_card" } & ServerWildCard)
-    | ({ room: Room; type: "chat" } & ServerChat)
+    | ({ room: Room; type: "achievement" } & ServerAchievement)
+    | ({ room: Room; type: "ack" } & ServerAck)
+    | ({ room: Room; type: "afk" } & ServerAfk)
+    | ({ room: Room; type: "catch_up_end" } & ServerCatchUpEnd)
+    | ({ room: Room; type: "catch_up_start" } & ServerCatchUpStart)
+    | ({ room: Room; type: "challenge" } & ServerChallenge)
     | ({ room: Room; type: "change_details" } & ServerChangeDetails)
+    | ({ room: Room; type: "channel_chat" } & ServerChannelChat)
+    | ({ room: Room; type: "channel_join" } & ServerChannelJoin)
+    | ({ room: Room; type: "chat" } & ServerChat)
+    | ({ room: Room; type: "cosmetic" } & ServerCosmetic)
+    | ({ room: Room; type: "draw" } & ServerDraw)
+    | ({ room: Room; type: "end" } & ServerEnd)
+    | ({ room: Room; type: "error" } & ServerError)
+    | ({ room: Room; type: "experience" } & ServerExperience)
+    | ({ room: Room; type: "friend" } & ServerFriend)
+    | ({ room: Room; type: "invite" } & ServerInvite)
+    | ({ room: Room; type: "join" } & ServerJoin)
     | ({ room: Room; type: "kick" } & ServerKick)
-    | ({ room: Room; type: "start" } & ServerStart)
+    | ({ room: Room; type: "leave" } & ServerLeave)
+    | ({ room: Room; type: "pause" } & ServerPause)
+    | ({ room: Room; type: "pause_expired" } & ServerPauseExpired)
+    | ({ room: Room; type: "prediction" } & ServerPrediction)
+    | ({ room: Room; type: "prediction_result" } & ServerPredictionResult)
+    | ({ room: Room; type: "presence" } & ServerPresence)
+    | ({ room: Room; type: "quest" } & ServerQuest)
+    | ({ room: Room; type: "reconnect" } & ServerReconnect)
+    | ({ room: Room; type: "replay_end" } & ServerReplayEnd)
+    | ({ room: Room; type: "replay_event" } & ServerReplayEvent)
+    | ({ room: Room; type: "replay_start" } & ServerReplayStart)
+    | ({ room: Room; type: "reported" } & ServerReported)
     | ({ room: Room; type: "reshuffle" } & ServerReshuffle)
+    | ({ room: Room; type: "resume" } & ServerResume)
+    | ({ room: Room; type: "resume_game" } & ServerResumeGame)
     | ({ room: Room; type: "resync" } & ServerResync)
+    | ({ room: Room; type: "room_closed" } & ServerRoomClosed)
+    | ({ room: Room; type: "send" } & ServerSend)
+    | ({ room: Room; type: "sign_in" } & ServerSignIn)
+    | ({ room: Room; type: "start" } & ServerStart)
+    | ({ room: Room; type: "suspend" } & ServerSuspend)
     | ({ room: Room; type: "turn" } & ServerTurn)
-    | ({ room: Room; type: "error" } & ServerError)
-    | ({ room: Room; type: "ack" } & ServerAck)
+    | ({ room: Room; type: "turn_timeout" } & ServerTurnTimeout)
+    | ({ room: Room; type: "void" } & ServerVoid)
+    | ({ room: Room; type: "vote" } & ServerVote)
+    | ({ room: Room; type: "vote_kick" } & ServerVoteKick)
+    | ({ room: Room; type: "vote_result" } & ServerVoteResult)
+    | ({ room: Room; type: "vote_suspend" } & ServerVoteSuspend)
+    | ({ room: Room; type: "wild_card" } & ServerWildCard)
 
+export const clientChangeDetails = (m: ClientChangeDetails): ClientMessage => ({ type: "change_details", ...m });
+export const clientChannelChat = (m: ClientChannelChat): ClientMessage => ({ type: "channel_chat", ...m });
+export const clientChannelJoin = (m: ClientChannelJoin): ClientMessage => ({ type: "channel_join", ...m });
+export const clientChannelLeave = (m: ClientChannelLeave): ClientMessage => ({ type: "channel_leave", ...m });
+export const clientChat = (m: ClientChat): ClientMessage => ({ type: "chat", ...m });
+export const clientDraw = (m: ClientDraw): ClientMessage => ({ type: "draw", ...m });
+export const clientEnd = (m: ClientEnd): ClientMessage => ({ type: "end", ...m });
+export const clientInvite = (m: ClientInvite): ClientMessage => ({ type: "invite", ...m });
+export const clientJoin = (m: ClientJoin): ClientMessage => ({ type: "join", ...m });
+export const clientKick = (m: ClientKick): ClientMessage => ({ type: "kick", ...m });
+export const clientLeave = (m: ClientLeave): ClientMessage => ({ type: "leave", ...m });
+export const clientMute = (m: ClientMute): ClientMessage => ({ type: "mute", ...m });
+export const clientPredict = (m: ClientPredict): ClientMessage => ({ type: "predict", ...m });
+export const clientReplay = (m: ClientReplay): ClientMessage => ({ type: "replay", ...m });
+export const clientReport = (m: ClientReport): ClientMessage => ({ type: "report", ...m });
+export const clientResume = (m: ClientResume): ClientMessage => ({ type: "resume", ...m });
+export const clientSend = (m: ClientSend): ClientMessage => ({ type: "send", ...m });
+export const clientSignIn = (m: ClientSignIn): ClientMessage => ({ type: "sign_in", ...m });
+export const clientStart = (m: ClientStart): ClientMessage => ({ type: "start", ...m });
+export const clientVote = (m: ClientVote): ClientMessage => ({ type: "vote", ...m });
+export const clientVoteKick = (m: ClientVoteKick): ClientMessage => ({ type: "vote_kick", ...m });
+export const clientVoteSuspend = (m: ClientVoteSuspend): ClientMessage => ({ type: "vote_suspend", ...m });
 
 export enum GamePhase {
     Lobby = 0,
@@ -36,6 +107,14 @@ export enum PlayMode {
     PlayersAndHub = 1,
     HubOnly = 2,
 }
+export enum ResumeMode {
+    Bot = 0,
+    Void = 1,
+}
+export enum AfkPolicy {
+    SeatOpen = 0,
+    BotFill = 1,
+}
 export enum CardType {
     Lines = 0,
     Waves = 1,
@@ -47,6 +126,13 @@ export enum CardType {
     Star = 7,
     Invalid = -1,
 }
+export interface Vote {
+    kind: string;
+    initiatorId: string;
+    targetId: string;
+    votes: {[key: string]: boolean};
+    deadline: number;
+}
 export interface WildCard {
     id: string;
     types: number[];
@@ -71,19 +157,28 @@ export interface AvatarConfig {
 }
 export interface Player {
     id: string;
+    accountId: string;
     avatar: AvatarConfig;
+    avatarUrl: string;
+    cardBack: string;
+    tableTheme: string;
     name: string;
     score: number;
     cards: Card[];
+    disconnected: boolean;
+    bot: boolean;
+    afk: boolean;
 }
 export interface Room {
     id: string;
     timestamp: number;
     name: string;
+    gameType: string;
     description: string;
     maxPlayers: number;
     ownerId: string;
     players: Player[];
+    spectators: Player[];
     decks: Deck[];
     playMode: PlayMode;
     hubDeviceId: string;
@@ -91,70 +186,131 @@ export interface Room {
     gamePhase: GamePhase;
     activeWildCard?: WildCard;
     drawPileSize: number;
+    paused: boolean;
+    disconnectGrace: number;
+    afkPolicy: AfkPolicy;
+    vote?: Vote;
+    turnTimeout: number;
+    afkTurns: number;
+    ranked: boolean;
 }
 
 
 
 
-export interface ClientJoin {
-    name: string;
-    avatar: AvatarConfig;
+export interface ClientChangeDetails {
+    name?: string;
+    description?: string;
+    maxPlayers?: number;
+    password?: string;
+    addDecks: string[];
+    removeDecks: string[];
+    playMode?: PlayMode;
+    hubDeviceId?: string;
+    disconnectGrace?: number;
+    afkPolicy?: AfkPolicy;
+    turnTimeout?: number;
+    afkTurns?: number;
+    ranked?: boolean;
 }
-export interface ClientLeave {
+export interface ClientChannelChat {
+    channel: string;
+    message: string;
+}
+export interface ClientChannelJoin {
+    channel: string;
+}
+export interface ClientChannelLeave {
+    channel: string;
+}
+export interface ClientChat {
+    message: string;
+    recipient?: string;
+}
+export interface ClientDraw {
+
+}
+export interface ClientEnd {
 
+}
+export interface ClientInvite {
+    accountId: string;
+}
+export interface ClientJoin {
+    roomId: string;
+    password: string;
+    spectate: boolean;
+    token: string;
+    name?: string;
+    account: string;
 }
 export interface ClientKick {
     id: string;
+    reason: string;
 }
-export interface ClientStart {
+export interface ClientLeave {
 
 }
-export interface ClientDraw {
-
+export interface ClientMute {
+    id: string;
+    mute: boolean;
+}
+export interface ClientPredict {
+    id: string;
+}
+export interface ClientReplay {
+    matchId: string;
+}
+export interface ClientReport {
+    id: string;
+    reason: string;
+    details: string;
+}
+export interface ClientResume {
+    mode: ResumeMode;
 }
 export interface ClientSend {
     recipientId: string;
 }
-export interface ClientChat {
-    message: string;
-    recipient?: string;
+export interface ClientSignIn {
+    token: string;
 }
-export interface ClientChangeDetails {
-    name?: string;
-    description?: string;
-    maxPlayers?: number;
-    password?: string;
-    addDecks: string[];
-    removeDecks: string[];
-    playMode?: PlayMode;
-    hubDeviceId?: string;
+export interface ClientStart {
+
 }
-export interface ServerTurn {
-    playerId: string;
+export interface ClientVote {
+    yes: boolean;
 }
-export interface ServerError {
-    message: string;
+export interface ClientVoteKick {
+    id: string;
+    reason: string;
 }
-export interface ServerAck {
+export interface ClientVoteSuspend {
 
 }
-export interface ServerKick {
-
+export interface ServerAchievement {
+    id: string;
+    name: string;
+    description: string;
 }
-export interface ServerStart {
-    currentTurn: number;
+export interface ServerAck {
+    token: string;
 }
-export interface ServerReshuffle {
+export interface ServerAfk {
+    playerId: string;
+    afk: boolean;
+    description?: string;
+}
+export interface ServerCatchUpEnd {
 
 }
-export interface ServerResync {
-    topCards: {[key: string]: Card};
+export interface ServerCatchUpStart {
+    events: number;
 }
-export interface ServerChat {
-    timestamp: string;
-    player: string;
-    private: boolean;
-    message: string;
+export interface ServerChallenge {
+    day: string;
+    score: number;
+    streak: number;
 }
 export interface ServerChangeDetails {
     name?: string;
@@ -164,18 +320,201 @@ export interface ServerChangeDetails {
     playMode?: PlayMode;
     hubDeviceId?: string;
 }
+export interface ServerChannelChat {
+    channel: string;
+    timestamp: string;
+    player: string;
+    name: string;
+    message: string;
+}
+export interface ServerChannelJoin {
+    channel: string;
+    history: ServerChannelChat[];
+}
+export interface ServerChat {
+    timestamp: string;
+    player: string;
+    private: boolean;
+    message: string;
+}
+export interface ServerCosmetic {
+    id: string;
+    name: string;
+    kind: string;
+}
+export interface ServerDraw {
+    playerId: string;
+    card?: Card;
+    description?: string;
+}
+export interface MatchPlayer {
+    id: string;
+    accountId: string;
+    name: string;
+    score: number;
+    rank: number;
+}
+export interface ServerEnd {
+    matchId: string;
+    results: MatchPlayer[];
+    description?: string;
+}
+export interface ServerError {
+    message: string;
+    id?: string;
+    params?: {[key: string]: string};
+    code?: string;
+}
+export interface ServerExperience {
+    earned: number;
+    xp: number;
+    level: number;
+    levelUp: boolean;
+}
+export interface ServerFriend {
+    accountId: string;
+    name: string;
+    status: string;
+}
+export interface ServerInvite {
+    roomId: string;
+    roomName: string;
+    accountId: string;
+    name: string;
+}
 export interface ServerJoin {
     id: string;
     player: Player;
+    spectator: boolean;
+    description?: string;
+}
+export interface ServerKick {
+
 }
 export interface ServerLeave {
     id: string;
+    description?: string;
 }
-export interface ServerDraw {
+export interface ServerPause {
+    playerId: string;
+    grace: number;
+    description?: string;
+}
+export interface ServerPauseExpired {
+
+}
+export interface ServerPrediction {
     playerId: string;
+    id: string;
+}
+export interface PredictionScore {
+    playerId: string;
+    name: string;
+    correct: number;
+    predictions: number;
+}
+export interface ServerPredictionResult {
+    winners: string[];
+    scoreboard: PredictionScore[];
+}
+export interface ServerPresence {
+    accountId: string;
+    online: boolean;
+}
+export interface Reward {
+    xp: number;
+    cosmetic?: string;
+}
+export interface ServerQuest {
+    id: string;
+    name: string;
+    reward: Reward;
+}
+export interface ServerReconnect {
+    id: string;
+    description?: string;
+}
+export interface ServerReplayEnd {
+    matchId: string;
+}
+export interface ServerReplayEvent {
+    matchId: string;
+    time: number;
+    event: {[key: string]: any};
+}
+export interface ServerReplayStart {
+    matchId: string;
+    seed: number;
+    events: number;
+}
+export interface ServerReported {
+    reportId: string;
+}
+export interface ServerReshuffle {
+    player?: Player;
+}
+export interface ServerResume {
+    description?: string;
+}
+export interface ServerResumeGame {
+    gameId: string;
+    currentTurn: number;
+}
+export interface ServerResync {
+    topCards: {[key: string]: Card};
+}
+export interface ServerRoomClosed {
+    reason: string;
+}
+export interface ServerSend {
+    senderId: string;
+    recipientId: string;
     card?: Card;
+    description?: string;
+}
+export interface ServerSignIn {
+    accountId: string;
+    online: string[];
+}
+export interface ServerStart {
+    currentTurn: number;
+    description?: string;
+}
+export interface ServerSuspend {
+    gameId: string;
+}
+export interface ServerTurn {
+    playerId: string;
+    description?: string;
+}
+export interface ServerTurnTimeout {
+    playerId: string;
+    missed: number;
+    description?: string;
+}
+export interface ServerVoid {
+
+}
+export interface ServerVote {
+    playerId: string;
+    yes: boolean;
+}
+export interface ServerVoteKick {
+    initiatorId: string;
+    targetId: string;
+    timeout: number;
+}
+export interface ServerVoteResult {
+    kind: string;
+    targetId: string;
+    passed: boolean;
+}
+export interface ServerVoteSuspend {
+    initiatorId: string;
+    timeout: number;
 }
 export interface ServerWildCard {
     playerId: string;
     card?: WildCard;
-}
\ No newline at end of file
+    description?: string;
+}
@@ -12,6 +12,20 @@ func RoomCode() string {
 	return "r" + gonanoid.MustGenerate(alphabet, 6)
 }
 
+// PresetCode returns a code to share a room preset by.
+func PresetCode() string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz"
+	return "p" + gonanoid.MustGenerate(alphabet, 8)
+}
+
+// RecoveryCode returns a code to sign in with when the authenticator app is lost, like
+// "k3mzp-x7q2a". Letters and digits which look alike are left out.
+func RecoveryCode() string {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	code := gonanoid.MustGenerate(alphabet, 10)
+	return code[:5] + "-" + code[5:]
+}
+
 func IdFrom(prefix string, text string) string {
 	h := fmt.Sprintf("%x", sha3.Sum256([]byte(text)))
 	return fmt.Sprintf("%s_%s", prefix, h[:8])
@@ -21,3 +35,7 @@ func LongIdFrom(prefix string, text string) string {
 	h := fmt.Sprintf("%x", sha3.Sum256([]byte(text)))
 	return fmt.Sprintf("%s_%s", prefix, h)
 }
+
+func Token() string {
+	return gonanoid.MustID(32)
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket rate limiter. It allows bursts of up to n events,
+// refilling at a rate of n events per interval.
+type Limiter struct {
+	mu       sync.Mutex
+	n        int
+	interval time.Duration
+	tokens   float64
+	last     time.Time
+}
+
+// New creates a limiter allowing n events per interval.
+func New(n int, interval time.Duration) *Limiter {
+	return &Limiter{
+		n:        n,
+		interval: interval,
+		tokens:   float64(n),
+		last:     time.Now(),
+	}
+}
+
+// Allow reports whether an event may happen now, and uses up a token if so.
+func (l *Limiter) Allow() bool {
+	return l.AllowAt(time.Now())
+}
+
+// AllowAt is like Allow, but uses the given time instead of the current time.
+func (l *Limiter) AllowAt(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += float64(l.n) * float64(elapsed) / float64(l.interval)
+		if l.tokens > float64(l.n) {
+			l.tokens = float64(l.n)
+		}
+		l.last = now
+	}
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Keyed limits events separately for each key, like each address or account, allowing n
+// events per interval for every one. Keys without events for an interval are forgotten once
+// there are many, since their limiters would be full again anyway.
+type Keyed struct {
+	mu       sync.Mutex
+	n        int
+	interval time.Duration
+	limiters map[string]*keyedLimiter
+}
+
+type keyedLimiter struct {
+	*Limiter
+	last time.Time
+}
+
+// maxKeys is how many keys a Keyed limiter holds before it forgets the idle ones.
+const maxKeys = 10000
+
+// NewKeyed creates a limiter allowing n events per interval for each key.
+func NewKeyed(n int, interval time.Duration) *Keyed {
+	return &Keyed{n: n, interval: interval, limiters: map[string]*keyedLimiter{}}
+}
+
+// Allow reports whether an event may happen now for a key, and uses up one of its tokens if so.
+func (k *Keyed) Allow(key string) bool {
+	return k.AllowAt(key, time.Now())
+}
+
+// AllowAt is like Allow, but uses the given time instead of the current time.
+func (k *Keyed) AllowAt(key string, now time.Time) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(k.limiters) >= maxKeys {
+		for key, l := range k.limiters {
+			if now.Sub(l.last) >= k.interval {
+				delete(k.limiters, key)
+			}
+		}
+	}
+	l, ok := k.limiters[key]
+	if !ok {
+		l = &keyedLimiter{Limiter: &Limiter{n: k.n, interval: k.interval, tokens: float64(k.n), last: now}}
+		k.limiters[key] = l
+	}
+	l.last = now
+	return l.AllowAt(now)
+}
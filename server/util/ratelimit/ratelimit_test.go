@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBurst(t *testing.T) {
+	l := New(3, time.Minute)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.AllowAt(now), "event %d should be allowed", i)
+	}
+	assert.False(t, l.AllowAt(now), "burst should be limited")
+}
+
+func TestRefill(t *testing.T) {
+	l := New(2, time.Second)
+	now := time.Now()
+	assert.True(t, l.AllowAt(now))
+	assert.True(t, l.AllowAt(now))
+	assert.False(t, l.AllowAt(now))
+
+	assert.True(t, l.AllowAt(now.Add(500*time.Millisecond)), "one token should be back after half the interval")
+	assert.False(t, l.AllowAt(now.Add(500*time.Millisecond)))
+
+	later := now.Add(time.Hour)
+	assert.True(t, l.AllowAt(later))
+	assert.True(t, l.AllowAt(later))
+	assert.False(t, l.AllowAt(later), "tokens should not exceed the burst size")
+}
+
+func TestKeyed(t *testing.T) {
+	k := NewKeyed(1, time.Minute)
+	now := time.Now()
+	assert.True(t, k.AllowAt("a", now))
+	assert.False(t, k.AllowAt("a", now))
+	assert.True(t, k.AllowAt("b", now), "keys should be limited separately")
+	assert.True(t, k.AllowAt("a", now.Add(time.Minute)))
+
+	for i := 0; i < maxKeys; i++ {
+		k.AllowAt(fmt.Sprint(i), now)
+	}
+	k.AllowAt("c", now.Add(time.Hour))
+	assert.Len(t, k.limiters, 1, "idle keys should be forgotten once there are many")
+}
@@ -282,3 +282,12 @@ func Shuffle[T any](slice []T) {
 		slice[i], slice[j] = slice[j], slice[i]
 	}
 }
+
+// ShuffleWith shuffles the elements of a slice in-place like Shuffle, using the given source
+// of randomness so the order can be reproduced.
+func ShuffleWith[T any](slice []T, r *rand.Rand) {
+	for i := len(slice) - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		slice[i], slice[j] = slice[j], slice[i]
+	}
+}
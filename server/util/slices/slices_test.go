@@ -2,6 +2,7 @@ package slices
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 )
 
@@ -400,3 +401,18 @@ func TestShuffle(t *testing.T) {
 		t.Errorf("Shuffle(%v) = %v, want %v", slice, shuffled, slice)
 	}
 }
+
+func TestShuffleWith(t *testing.T) {
+	slice := []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+	a, b := copy(slice), copy(slice)
+	ShuffleWith(a, rand.New(rand.NewSource(42)))
+	ShuffleWith(b, rand.New(rand.NewSource(42)))
+
+	if !fuzzyEquals(t, a, slice) {
+		t.Errorf("ShuffleWith(%v) = %v, want %v", slice, a, slice)
+	}
+	if !equals(t, a, b) {
+		t.Errorf("ShuffleWith(%v) with the same seed = %v and %v, want the same order", slice, a, b)
+	}
+}
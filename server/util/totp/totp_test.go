@@ -0,0 +1,56 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRFC6238 checks the SHA1 test vectors of RFC 6238, appendix B.
+func TestRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for unix, want := range map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	} {
+		step := Step(time.Unix(unix, 0))
+		assert.Equal(t, want, hotp(key, uint64(step), 8), "at %d", unix)
+	}
+
+	secret := base32.StdEncoding.EncodeToString(key)
+	code, err := Code(secret, Step(time.Unix(59, 0)))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code, "codes should be the last six digits")
+}
+
+func TestCheck(t *testing.T) {
+	secret := NewSecret()
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, Step(now))
+	assert.NoError(t, err)
+
+	step, ok := Check(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+	_, ok = Check(secret, code[:3]+" "+code[3:], now, 0)
+	assert.True(t, ok, "spaces should be ignored")
+	_, ok = Check(secret, code, now.Add(30*time.Second), 0)
+	assert.True(t, ok, "the previous code should still be accepted")
+	_, ok = Check(secret, code, now.Add(90*time.Second), 0)
+	assert.False(t, ok, "old codes should be rejected")
+	_, ok = Check(secret, code, now, step)
+	assert.False(t, ok, "a code should only be accepted once")
+	_, ok = Check(secret, "000000x", now, 0)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	assert.Equal(t, "otpauth://totp/Card%20Game:alice?issuer=Card+Game&secret=ABC",
+		URI("ABC", "Card Game", "alice"))
+}
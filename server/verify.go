@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"cardgame/game"
+	"cardgame/storage"
+)
+
+const verifyUsage = `usage: cardgame-server verify [-update] DIR
+
+verify plays the golden games in DIR, one JSON file each, with the current rules, and fails
+if any of them doesn't produce the events it recorded. Run it after changing the rules to make
+sure games still play out the same.
+
+A golden game holds a seed, its decks, its players and what they did:
+
+  {
+    "seed": 1,
+    "decks": [{"cards": ["=|fruit", "≈|fruit"], "wildCards": ["=|≈"]}],
+    "players": [{"id": "p_a", "name": "a"}, {"id": "p_b", "name": "b"}],
+    "actions": [{"type": "draw", "playerId": "p_a"}, {"type": "end", "playerId": "p_a"}]
+  }
+
+Actions are "draw", "send" (with a "recipientId"), "end" and "timeout". With -update, the
+events of every game are written from the current rules instead, to record new golden games
+or accept a change of the rules.
+
+flags:`
+
+// verify runs the "verify" command. It returns the exit code of the command, which is 1 if a
+// golden game diverged.
+func verify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	update := flags.Bool("update", false, "write the events of the golden games instead of checking them")
+	usage := func() {
+		fmt.Fprintln(os.Stderr, verifyUsage)
+		flags.SetOutput(os.Stderr)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		usage()
+		return 2
+	}
+	files, err := filepath.Glob(filepath.Join(flags.Arg(0), "*.json"))
+	if err != nil || len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "no golden games found in", flags.Arg(0))
+		return 1
+	}
+
+	// games played by verify are recorded like any other, but not kept
+	storage.Default = storage.NewMemory()
+
+	failed := 0
+	for _, file := range files {
+		if err := verifyFile(file, *update); err != nil {
+			fmt.Printf("FAIL %s: %v\n", file, err)
+			failed++
+			continue
+		}
+		fmt.Println("ok  ", file)
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d golden games diverged\n", failed, len(files))
+		return 1
+	}
+	return 0
+}
+
+// verifyFile checks the golden game in a file, or writes its events when updating.
+func verifyFile(file string, update bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var g game.Golden
+	if err := json.Unmarshal(data, &g); err != nil {
+		return err
+	}
+	if !update {
+		return g.Verify()
+	}
+
+	if g.Events, err = g.Play(); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
@@ -0,0 +1,42 @@
+package web
+
+import (
+	"cardgame/achievement"
+	"cardgame/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userAchievement is an achievement with an account's progress towards it.
+type userAchievement struct {
+	achievement.Definition
+	Progress int   `json:"progress"`
+	Unlocked int64 `json:"unlocked"` // unix ms, 0 while locked
+}
+
+func GetAchievements(c *gin.Context) {
+	c.JSON(200, gin.H{"achievements": achievement.Definitions()})
+}
+
+// GetUserAchievements responds with every achievement and how far an account is towards it.
+func GetUserAchievements(c *gin.Context) {
+	saved, err := storage.Default.Achievements(c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, "failed to load achievements")
+		return
+	}
+	progress := map[string]*storage.AchievementProgress{}
+	for _, p := range saved {
+		progress[p.AchievementId] = p
+	}
+
+	achievements := []userAchievement{}
+	for _, d := range achievement.Definitions() {
+		a := userAchievement{Definition: d}
+		if p, ok := progress[d.Id]; ok {
+			a.Progress, a.Unlocked = p.Progress, p.Unlocked
+		}
+		achievements = append(achievements, a)
+	}
+	c.JSON(200, gin.H{"achievements": achievements})
+}
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAchievements(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	storage.Default.SaveAchievements([]*storage.AchievementProgress{
+		{AccountId: "u_1", AchievementId: "first_win", Progress: 1, Unlocked: 5},
+	})
+	api := initTestApi(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/u_1/achievements", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var r struct {
+		Achievements []userAchievement `json:"achievements"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	assert.NotEmpty(t, r.Achievements)
+	for _, a := range r.Achievements {
+		if a.Id == "first_win" {
+			assert.Equal(t, int64(5), a.Unlocked)
+		} else {
+			assert.Zero(t, a.Unlocked)
+		}
+	}
+}
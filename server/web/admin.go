@@ -0,0 +1,123 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admins are the ids of the accounts allowed to use the admin API, set on startup.
+var Admins = map[string]bool{}
+
+// currentAdmin returns the account of the bearer token sent with the request, if it is an
+// admin. Otherwise the request is aborted and nil is returned.
+func currentAdmin(c *gin.Context) *storage.Account {
+	a := currentUser(c)
+	if a == nil {
+		return nil
+	}
+	if !Admins[a.Id] {
+		abortWithError(c, errs.New(errs.ErrPermission, "not an admin"), "")
+		return nil
+	}
+	return a
+}
+
+// GetAuditLog responds with a page of the audit log, newest first. It can be narrowed down
+// with the "actor", "target" and "action" query parameters. Cursors are the time and id of
+// the last entry of a page, as "created_id".
+func GetAuditLog(c *gin.Context) {
+	if currentAdmin(c) == nil {
+		return
+	}
+
+	q := storage.AuditQuery{
+		ActorId:  c.Query("actor"),
+		TargetId: c.Query("target"),
+		Action:   storage.AuditAction(c.Query("action")),
+	}
+	var ok bool
+	if q.Limit, ok = pageLimit(c); !ok {
+		return
+	}
+	if q.Before, q.BeforeId, ok = pageCursor(c); !ok {
+		return
+	}
+
+	entries, err := storage.Default.AuditLog(q)
+	if err != nil {
+		abortWithError(c, err, "failed to load audit log")
+		return
+	}
+
+	var next *string
+	if len(entries) == q.Limit {
+		last := entries[len(entries)-1]
+		next = nextCursor(last.Created, last.Id)
+	}
+	c.JSON(200, gin.H{"entries": entries, "next": next})
+}
+
+// CloseRoom removes a room and everyone in it, voiding any game in progress.
+func CloseRoom(c *gin.Context) {
+	a := currentAdmin(c)
+	if a == nil {
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	reason, err := game.CleanReason(body.Reason)
+	if err != nil {
+		abortWithError(c, err, "invalid reason")
+		return
+	}
+
+	if err := game.HubMain.CloseRoom(c.Param("room"), a, reason); err != nil {
+		abortWithError(c, err, "failed to close room")
+		return
+	}
+	c.JSON(200, gin.H{})
+}
+
+// GetBackup responds with an archive of the database, to be restored with
+// "cardgame-server restore". Of the rooms, it only holds suspended games. Only SQL stores
+// can be backed up.
+func GetBackup(c *gin.Context) {
+	a := currentAdmin(c)
+	if a == nil {
+		return
+	}
+
+	name := fmt.Sprintf("cardgame-%s.backup", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+
+	err := storage.Backup(storage.Default, c.Writer)
+	if err != nil && !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		abortWithError(c, err, "failed to back up storage")
+		return
+	}
+	if err != nil {
+		// the archive was being streamed and is left truncated
+		requestLog(c).Error("failed to back up storage", "err", err)
+		return
+	}
+
+	game.Audit(&storage.AuditEntry{
+		Action:    storage.AuditBackup,
+		ActorId:   a.Id,
+		ActorName: a.Name,
+	})
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"cardgame/game"
+	"cardgame/storage"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func adminRequest(t *testing.T, api *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Add("Authorization", "Bearer "+token)
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminAuditLog(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, admin := userRequest(t, api, "POST", "", `{"name":"admin"}`)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	Admins = map[string]bool{admin.User.Id: true}
+	t.Cleanup(func() { Admins = map[string]bool{} })
+
+	w := adminRequest(t, api, "GET", "/api/admin/audit", alice.Token, "")
+	assert.Equal(t, 403, w.Code, "only admins should see the audit log")
+
+	w = adminRequest(t, api, "POST", "/api/admin/room/r_missing/close", admin.Token, `{}`)
+	assert.Equal(t, 404, w.Code)
+	assert.JSONEq(t, `{"error":"room not found","code":"not_found"}`, w.Body.String())
+
+	r := game.HubMain.NewRoom("")
+	w = adminRequest(t, api, "POST", "/api/admin/room/"+r.Id+"/close", admin.Token, `{"reason":"spam"}`)
+	assert.Equal(t, 200, w.Code)
+	_, ok := game.HubMain.Room(r.Id)
+	assert.False(t, ok, "closed rooms should be removed")
+	w = adminRequest(t, api, "POST", "/api/admin/room/"+r.Id+"/close", admin.Token, `{"reason":"spam"}`)
+	assert.Equal(t, 404, w.Code, "rooms should only be closed once")
+
+	assert.Eventually(t, func() bool {
+		entries, _ := storage.Default.AuditLog(storage.AuditQuery{})
+		return len(entries) == 1
+	}, time.Second, 10*time.Millisecond)
+	w = adminRequest(t, api, "GET", "/api/admin/audit?action=close_room&actor="+admin.User.Id, admin.Token, "")
+	assert.Equal(t, 200, w.Code)
+	var body struct {
+		Entries []*storage.AuditEntry `json:"entries"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	if assert.Len(t, body.Entries, 1) {
+		assert.Equal(t, r.Id, body.Entries[0].TargetId)
+		assert.Equal(t, "spam", body.Entries[0].Reason)
+	}
+}
+
+func TestAdminAuditLogPages(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, admin := userRequest(t, api, "POST", "", `{"name":"admin"}`)
+	Admins = map[string]bool{admin.User.Id: true}
+	t.Cleanup(func() { Admins = map[string]bool{} })
+	for i := 1; i <= 5; i++ {
+		storage.Default.SaveAuditEntry(&storage.AuditEntry{
+			Id:      fmt.Sprintf("a_%d", i),
+			Action:  storage.AuditKick,
+			Created: int64((i + 1) / 2), // actions are taken two at a time
+		})
+	}
+
+	type response struct {
+		Entries []*storage.AuditEntry `json:"entries"`
+		Next    *string               `json:"next"`
+	}
+	get := func(path string) response {
+		t.Helper()
+		w := adminRequest(t, api, "GET", path, admin.Token, "")
+		assert.Equal(t, 200, w.Code)
+		var r response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return r
+	}
+	ids := func(entries []*storage.AuditEntry) []string {
+		ids := []string{}
+		for _, e := range entries {
+			ids = append(ids, e.Id)
+		}
+		return ids
+	}
+
+	first := get("/api/admin/audit?limit=3")
+	assert.Equal(t, []string{"a_5", "a_3", "a_4"}, ids(first.Entries))
+	if assert.NotNil(t, first.Next) {
+		second := get("/api/admin/audit?limit=3&before=" + *first.Next)
+		assert.Equal(t, []string{"a_1", "a_2"}, ids(second.Entries), "actions taken with the last of a page should be on the next one")
+		assert.Nil(t, second.Next, "last page should have no cursor")
+	}
+}
+
+func TestAdminBackup(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, admin := userRequest(t, api, "POST", "", `{"name":"admin"}`)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	Admins = map[string]bool{admin.User.Id: true}
+	t.Cleanup(func() { Admins = map[string]bool{} })
+
+	w := adminRequest(t, api, "GET", "/api/admin/backup", alice.Token, "")
+	assert.Equal(t, 403, w.Code, "only admins should download backups")
+
+	w = adminRequest(t, api, "GET", "/api/admin/backup", admin.Token, "")
+	assert.Equal(t, 501, w.Code, "memory stores can't be backed up")
+	assert.Equal(t, "", w.Header().Get("Content-Disposition"))
+}
@@ -8,6 +8,8 @@ import (
 )
 
 func InitApi(e *gin.RouterGroup) *gin.RouterGroup {
+	e.Use(logRequests)
+
 	e.GET("/info", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"commit":    build.Commit(),
@@ -17,9 +19,19 @@ func InitApi(e *gin.RouterGroup) *gin.RouterGroup {
 		})
 	})
 
+	if Demo {
+		e.POST("/demo", StartDemo)
+		e.GET("/ws/:room", ServeWS)
+		e.GET("/decks", GetDecks)
+		e.GET("/deck/:id", GetDeck)
+		return e
+	}
+
 	e.GET("/rooms", GetRooms)
 	e.GET("/room/:room", GetRoom)
+	e.GET("/room/:room/matches", GetRoomMatches)
 	e.POST("/room", CreateRoom)
+	e.POST("/room/preset/:code", CreateRoomFromPreset)
 	e.GET("/ws/:room", ServeWS)
 
 	e.GET("/decks", GetDecks)
@@ -29,6 +41,72 @@ func InitApi(e *gin.RouterGroup) *gin.RouterGroup {
 	e.POST("/me", CreateUser)
 	e.PUT("/me", UpdateUser)
 	e.DELETE("/me", DeleteUser)
+	e.POST("/me/recover", RecoverUser)
+	e.GET("/me/export", ExportUser)
+	e.GET("/me/sessions", GetSessions)
+	e.POST("/me/sessions", CreateSession)
+	e.DELETE("/me/sessions", RevokeSessions)
+	e.DELETE("/me/sessions/:id", EndSession)
+	e.PUT("/me/email", ChangeEmail)
+	e.POST("/me/email/verify", SendVerification)
+	e.PUT("/me/password", ChangePassword)
+	e.POST("/me/totp", SetUpTotp)
+	e.POST("/me/totp/confirm", ConfirmTotp)
+	e.POST("/me/totp/recovery", NewRecoveryCodes)
+	e.DELETE("/me/totp", DisableTotp)
+	e.POST("/login", Login)
+	e.POST("/email/verify", VerifyEmail)
+	e.POST("/password/reset", RequestPasswordReset)
+	e.POST("/password/reset/confirm", ResetPassword)
+	e.PUT("/me/avatar", UploadAvatar)
+	e.DELETE("/me/avatar", DeleteAvatar)
+	e.GET("/me/friends", GetFriends)
+	e.POST("/me/friends/:id", AddFriend)
+	e.DELETE("/me/friends/:id", RemoveFriend)
+	e.GET("/me/blocks", GetBlocks)
+	e.POST("/me/blocks/:id", BlockUser)
+	e.DELETE("/me/blocks/:id", UnblockUser)
+	e.GET("/me/suspended", GetSuspendedGames)
+	e.POST("/me/suspended/:id/resume", ResumeSuspendedGame)
+	e.DELETE("/me/suspended/:id", DeleteSuspendedGame)
+	e.GET("/me/presets", GetRoomPresets)
+	e.POST("/me/presets", CreateRoomPreset)
+	e.PUT("/me/presets/:id", UpdateRoomPreset)
+	e.DELETE("/me/presets/:id", DeleteRoomPreset)
+	e.GET("/me/challenges", GetChallenges)
+	e.GET("/me/quests", GetQuests)
+	e.GET("/me/cosmetics", GetMyCosmetics)
+	e.POST("/me/challenges/:gameType", StartChallenge)
+	e.GET("/user/:id", GetProfile)
+	e.GET("/user/:id/avatar", GetAvatar)
+	e.GET("/user/:id/matches", GetUserMatches)
+	e.GET("/user/:id/ratings", GetUserRatings)
+	e.GET("/user/:id/ratings/:gameType/history", GetUserRatingHistory)
+	e.GET("/user/:id/achievements", GetUserAchievements)
+	e.GET("/user/:id/stats", GetUserStats)
+	e.GET("/user/:id/level", GetUserLevel)
+	e.GET("/leaderboard", GetLeaderboard)
+	e.GET("/leaderboard/user/:id", GetUserLeaderboard)
+	e.GET("/achievements", GetAchievements)
+	e.GET("/cosmetics", GetCosmetics)
+	e.GET("/challenge/:gameType", GetChallenge)
+	e.GET("/preset/:code", GetSharedPreset)
+	e.GET("/features", GetFeatures)
+
+	e.GET("/admin/audit", GetAuditLog)
+	e.POST("/admin/room/:room/close", CloseRoom)
+	e.GET("/admin/backup", GetBackup)
+	e.GET("/admin/reports", GetReports)
+	e.GET("/admin/reports/:id", GetReport)
+	e.POST("/admin/reports/:id/resolve", ResolveReport)
+	e.GET("/admin/features", GetFeatureFlags)
+	e.GET("/admin/debug/runtime", GetDebugRuntime)
+	e.GET("/admin/debug/pprof/*profile", GetDebugProfile)
+
+	e.GET("/match/:id", GetMatch)
+	e.GET("/replays", GetReplays)
+	e.GET("/replay/:id", GetReplay)
+	e.GET("/replay/:id/export", ExportReplay)
 
 	e.GET("", func(c *gin.Context) {
 		c.JSON(200, gin.H{
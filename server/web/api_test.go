@@ -34,3 +34,17 @@ func TestApiTime(t *testing.T) {
 	// time matches RFC3339 format (from https://regex101.com/r/qH0sU7/1)
 	assert.Regexp(t, `^((?:(\d{4}-\d{2}-\d{2})T(\d{2}:\d{2}:\d{2}(?:\.\d+)?))(Z|[\+-]\d{2}:\d{2})?)$`, r.Time)
 }
+
+func TestRequestId(t *testing.T) {
+	api := initTestApi(t)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api", nil)
+	api.ServeHTTP(w, req)
+	assert.NotEmpty(t, w.Header().Get("X-Request-Id"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-Request-Id", "req_proxy")
+	api.ServeHTTP(w, req)
+	assert.Equal(t, "req_proxy", w.Header().Get("X-Request-Id"), "ids set by a proxy should be kept")
+}
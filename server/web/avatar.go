@@ -0,0 +1,115 @@
+package web
+
+import (
+	"bytes"
+	"cardgame/errs"
+	"cardgame/storage"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxAvatarSize      = 256 << 10 // bytes
+	maxAvatarDimension = 512       // pixels
+)
+
+// avatarTypes are the content types avatar images can be uploaded as.
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+// UploadAvatar replaces the avatar image of the current account with the image in the
+// request body. The type is sniffed from the image itself, not taken from the request.
+func UploadAvatar(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAvatarSize+1))
+	if err != nil {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "failed to read avatar"), "")
+		return
+	}
+	if len(data) > maxAvatarSize {
+		abortWithError(c, errs.New(errs.ErrTooLarge, "avatar must be at most 256 KiB"), "")
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if !avatarTypes[contentType] {
+		abortWithError(c, errs.New(errs.ErrUnsupportedType, "avatar must be a png, jpeg or gif image"), "")
+		return
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "avatar is not a valid image"), "")
+		return
+	}
+	if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "avatar must be at most 512x512 pixels"), "")
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	err = storage.Default.SaveAvatarImage(&storage.AvatarImage{
+		AccountId:   a.Id,
+		ContentType: contentType,
+		Data:        data,
+		Updated:     now,
+	})
+	if err != nil {
+		abortWithError(c, err, "failed to save avatar")
+		return
+	}
+
+	a.AvatarImage = now
+	a.Updated = now
+	if !saveUser(c, a) {
+		return
+	}
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
+
+// DeleteAvatar removes the avatar image of the current account, so its Avatar is shown again.
+func DeleteAvatar(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	err := storage.Default.DeleteAvatarImage(a.Id)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		abortWithError(c, err, "failed to delete avatar")
+		return
+	}
+
+	a.AvatarImage = 0
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
+
+func GetAvatar(c *gin.Context) {
+	img, err := storage.Default.AvatarImage(c.Param("id"))
+	if err != nil {
+		abortWithError(c, notFound(err, "avatar not found"), "failed to load avatar")
+		return
+	}
+
+	// urls of avatar images change when they are replaced
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(200, img.ContentType, img.Data)
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"bytes"
+	"cardgame/storage"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePng(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestAvatarUpload(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, created := userRequest(t, api, "POST", "", `{"name":"card shark"}`)
+
+	upload := func(body []byte) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/api/me/avatar", bytes.NewReader(body))
+		req.Header.Add("Authorization", "Bearer "+created.Token)
+		api.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, 415, upload([]byte("<svg></svg>")))
+	assert.Equal(t, 400, upload(encodePng(t, 1024, 16)), "large images should be rejected")
+	assert.Equal(t, 413, upload(make([]byte, maxAvatarSize+1)))
+	assert.Equal(t, 200, upload(encodePng(t, 64, 64)))
+
+	_, got := userRequest(t, api, "GET", created.Token, "")
+	assert.NotZero(t, got.User.AvatarImage)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/"+created.User.Id+"/avatar", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, encodePng(t, 64, 64), w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/me/avatar", nil)
+	req.Header.Add("Authorization", "Bearer "+created.Token)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/user/"+created.User.Id+"/avatar", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
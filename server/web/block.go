@@ -0,0 +1,118 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockedUser is an account on the current account's block list.
+type blockedUser struct {
+	Id          string         `json:"id"`
+	Name        string         `json:"name"`
+	Avatar      storage.Avatar `json:"avatar"`
+	AvatarImage int64          `json:"avatarImage"`
+	Since       int64          `json:"since"` // unix ms
+}
+
+// GetBlocks responds with the accounts the current account has blocked.
+func GetBlocks(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	blocks, err := storage.Default.Blocks(a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to load blocks")
+		return
+	}
+
+	users := []blockedUser{}
+	for _, b := range blocks {
+		other, err := storage.Default.Account(b.BlockedId)
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			abortWithError(c, err, "failed to load blocks")
+			return
+		}
+		users = append(users, blockedUser{
+			Id:          other.Id,
+			Name:        other.Name,
+			Avatar:      other.Avatar,
+			AvatarImage: other.AvatarImage,
+			Since:       b.Created,
+		})
+	}
+
+	c.JSON(200, gin.H{"blocks": users})
+}
+
+// BlockUser blocks an account, ending any friendship with it.
+func BlockUser(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	id := c.Param("id")
+	if id == a.Id {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "cannot block yourself"), "")
+		return
+	}
+	if _, err := storage.Default.Account(id); err != nil {
+		abortWithError(c, notFound(err, "user not found"), "failed to load account")
+		return
+	}
+
+	if err := storage.Default.SaveBlock(&storage.Block{AccountId: a.Id, BlockedId: id, Created: time.Now().UnixMilli()}); err != nil {
+		abortWithError(c, err, "failed to save block")
+		return
+	}
+	if err := storage.Default.DeleteFriendship(a.Id, id); err != nil && !errors.Is(err, errs.ErrNotFound) {
+		requestLog(c).Error("failed to delete friendship", "err", err)
+	}
+	game.HubMain.SetBlocked(a.Id, id, true)
+
+	c.JSON(200, gin.H{})
+}
+
+// UnblockUser removes an account from the current account's block list.
+func UnblockUser(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	id := c.Param("id")
+	err := storage.Default.DeleteBlock(a.Id, id)
+	if err != nil {
+		abortWithError(c, notFound(err, "user is not blocked"), "failed to delete block")
+		return
+	}
+	game.HubMain.SetBlocked(a.Id, id, false)
+
+	c.JSON(200, gin.H{})
+}
+
+// blockedEither reports whether either of two accounts has blocked the other.
+// If they can't be loaded, the request is aborted and ok is false.
+func blockedEither(c *gin.Context, a, b string) (blocked, ok bool) {
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		blocked, err := storage.Default.Blocked(pair[0], pair[1])
+		if err != nil {
+			abortWithError(c, err, "failed to load block")
+			return false, false
+		}
+		if blocked {
+			return true, true
+		}
+	}
+	return false, true
+}
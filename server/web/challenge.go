@@ -0,0 +1,114 @@
+package web
+
+import (
+	"cardgame/challenge"
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// challengeStatus is how the current account is doing at the daily challenge of a game type.
+type challengeStatus struct {
+	GameType      string                   `json:"gameType"`
+	Result        *storage.ChallengeResult `json:"result"` // today's attempt, if any
+	Streak        int                      `json:"streak"`
+	LongestStreak int                      `json:"longestStreak"`
+}
+
+// challengeGameType reads the "gameType" path parameter.
+// If it isn't a game type the server hosts, the request is aborted and false is returned.
+func challengeGameType(c *gin.Context) (string, bool) {
+	gameType := c.Param("gameType")
+	if gameType == "" || !knownGameType(gameType) {
+		abortWithError(c, errs.New(errs.ErrNotFound, "game type not found"), "")
+		return "", false
+	}
+	return gameType, true
+}
+
+// GetChallenge responds with the leaderboard of a day's challenge of a game type, best score first.
+// "day" picks the day, which is today if it isn't set.
+func GetChallenge(c *gin.Context) {
+	gameType, ok := challengeGameType(c)
+	if !ok {
+		return
+	}
+	today := challenge.Day(time.Now())
+	day := today
+	if d := c.Query("day"); d != "" {
+		if _, err := challenge.ParseDay(d); err != nil || d > today {
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "invalid day"), "")
+			return
+		}
+		day = d
+	}
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+
+	results, err := storage.Default.ChallengeResults(day, gameType, limit)
+	if err != nil {
+		abortWithError(c, err, "failed to load challenge results")
+		return
+	}
+	c.JSON(200, gin.H{"day": day, "gameType": gameType, "results": results})
+}
+
+// GetChallenges responds with the current account's attempts at today's challenges and
+// its streaks, for every game type.
+func GetChallenges(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	day := challenge.Day(time.Now())
+	challenges := []challengeStatus{}
+	for _, t := range game.AllGameTypes {
+		status := challengeStatus{GameType: string(t)}
+
+		result, err := storage.Default.ChallengeResult(day, string(t), a.Id)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			abortWithError(c, err, "failed to load challenges")
+			return
+		}
+		status.Result = result
+
+		streak, err := storage.Default.ChallengeStreak(a.Id, string(t))
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			abortWithError(c, err, "failed to load challenges")
+			return
+		}
+		if streak != nil {
+			status.Streak = challenge.Current(streak, day)
+			status.LongestStreak = streak.LongestStreak
+		}
+		challenges = append(challenges, status)
+	}
+	c.JSON(200, gin.H{"day": day, "challenges": challenges})
+}
+
+// StartChallenge opens a room for the current account's attempt at today's challenge of a
+// game type. The game starts once the account joins the room.
+func StartChallenge(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	gameType, ok := challengeGameType(c)
+	if !ok {
+		return
+	}
+
+	r, err := game.HubMain.DailyChallenge(a, game.GameType(gameType))
+	if err != nil {
+		abortWithError(c, err, "failed to open daily challenge")
+		return
+	}
+	respondWithRoom(c, r)
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"cardgame/challenge"
+	"cardgame/game"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChallenges(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+	today := challenge.Day(time.Now())
+	yesterday := challenge.Day(time.Now().AddDate(0, 0, -1))
+	assert.NoError(t, challenge.Complete(storage.Default, &storage.ChallengeResult{
+		Day: yesterday, GameType: "classic", AccountId: bob.User.Id, Name: "bob", Score: 4, Completed: 1,
+	}))
+	assert.NoError(t, challenge.Complete(storage.Default, &storage.ChallengeResult{
+		Day: today, GameType: "classic", AccountId: bob.User.Id, Name: "bob", Score: 2, Completed: 2,
+	}))
+
+	request := func(method, path, token string) (int, []byte) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		api.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, body := request("GET", "/api/challenge/classic?day="+yesterday, "")
+	assert.Equal(t, 200, code)
+	var leaderboard struct {
+		Day     string                     `json:"day"`
+		Results []*storage.ChallengeResult `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &leaderboard))
+	assert.Equal(t, yesterday, leaderboard.Day)
+	if assert.Len(t, leaderboard.Results, 1) {
+		assert.Equal(t, 4, leaderboard.Results[0].Score)
+	}
+	code, _ = request("GET", "/api/challenge/classic?day=tomorrow", "")
+	assert.Equal(t, 400, code)
+	code, _ = request("GET", "/api/challenge/solitaire", "")
+	assert.Equal(t, 404, code)
+
+	code, body = request("GET", "/api/me/challenges", bob.Token)
+	assert.Equal(t, 200, code)
+	var status struct {
+		Challenges []challengeStatus `json:"challenges"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &status))
+	if assert.Len(t, status.Challenges, len(game.AllGameTypes)) {
+		assert.Equal(t, 2, status.Challenges[0].Streak)
+		assert.NotNil(t, status.Challenges[0].Result)
+	}
+
+	code, _ = request("POST", "/api/me/challenges/classic", bob.Token)
+	assert.Equal(t, 409, code, "today's challenge should only be attempted once")
+	code, body = request("POST", "/api/me/challenges/classic", alice.Token)
+	assert.Equal(t, 200, code)
+	var opened struct {
+		Room struct {
+			Id string `json:"id"`
+		} `json:"room"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &opened))
+	r, ok := game.HubMain.Room(opened.Room.Id)
+	if assert.True(t, ok) {
+		assert.True(t, r.IsPrivate(), "challenge rooms should only be open to their player")
+		game.HubMain.RemoveRoom(r.Id)
+	}
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"cardgame/cosmetic"
+	"cardgame/errs"
+	"cardgame/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ownedCosmetic is a cosmetic item with the time the current account unlocked it.
+type ownedCosmetic struct {
+	cosmetic.Item
+	Unlocked int64 `json:"unlocked"` // unix ms, 0 for items every account has
+}
+
+// GetCosmetics responds with every cosmetic item and how it is unlocked.
+func GetCosmetics(c *gin.Context) {
+	c.JSON(200, gin.H{"cosmetics": cosmetic.Items()})
+}
+
+// GetMyCosmetics responds with the cosmetic items the current account has unlocked, and the
+// ones it has selected.
+func GetMyCosmetics(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	unlocked, err := cosmetic.Unlocked(storage.Default, a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to load cosmetics")
+		return
+	}
+	owned := []ownedCosmetic{}
+	for _, item := range cosmetic.Items() {
+		if t, ok := unlocked[item.Id]; ok {
+			owned = append(owned, ownedCosmetic{Item: item, Unlocked: t})
+		}
+	}
+	c.JSON(200, gin.H{
+		"cosmetics":  owned,
+		"cardBack":   a.CardBack,
+		"tableTheme": a.TableTheme,
+	})
+}
+
+// selectCosmetic checks that an account may select a cosmetic item of a kind. If not, the
+// request is aborted and false is returned.
+func selectCosmetic(c *gin.Context, a *storage.Account, kind cosmetic.Kind, id string) bool {
+	ok, err := cosmetic.CanSelect(storage.Default, a.Id, kind, id)
+	if err != nil {
+		abortWithError(c, err, "failed to load cosmetics")
+		return false
+	}
+	if !ok {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "cosmetic is not unlocked"), "")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"cardgame/cosmetic"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosmetics(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	old := cosmetic.Catalog{Items: cosmetic.Items()}
+	assert.NoError(t, cosmetic.SetCatalog(cosmetic.Catalog{Items: []cosmetic.Item{
+		{Id: "back_plain", Kind: cosmetic.KindCardBack, Default: true},
+		{Id: "back_gold", Kind: cosmetic.KindCardBack, Unlock: cosmetic.Unlock{Level: 20}},
+		{Id: "theme_oak", Kind: cosmetic.KindTableTheme, Unlock: cosmetic.Unlock{Achievement: "first_win"}},
+	}}))
+	t.Cleanup(func() { cosmetic.SetCatalog(old) })
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+
+	code, res := userRequest(t, api, "PUT", alice.Token, `{"cardBack":"back_gold"}`)
+	assert.Equal(t, 400, code)
+	assert.Equal(t, "cosmetic is not unlocked", res.Error)
+	code, _ = userRequest(t, api, "PUT", alice.Token, `{"tableTheme":"back_plain"}`)
+	assert.Equal(t, 400, code, "card backs should not be selectable as table themes")
+
+	assert.NoError(t, cosmetic.Grant(storage.Default, alice.User.Id, "back_gold"))
+	code, res = userRequest(t, api, "PUT", alice.Token, `{"cardBack":"back_gold"}`)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "back_gold", res.User.CardBack)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/me/cosmetics", nil)
+	req.Header.Add("Authorization", "Bearer "+alice.Token)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var body struct {
+		Cosmetics []ownedCosmetic `json:"cosmetics"`
+		CardBack  string          `json:"cardBack"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "back_gold", body.CardBack)
+	if assert.Len(t, body.Cosmetics, 2) {
+		assert.Equal(t, "back_plain", body.Cosmetics[0].Id)
+		assert.Zero(t, body.Cosmetics[0].Unlocked)
+		assert.NotZero(t, body.Cosmetics[1].Unlocked)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/cosmetics", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "theme_oak")
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DebugEndpoints enables the profiling and runtime endpoints of the admin API, set on startup.
+// They are off by default, and answer as if they didn't exist.
+var DebugEndpoints = false
+
+// debugAdmin checks the debug endpoints are enabled and the request is made by an admin.
+// Otherwise the request is aborted and false is returned.
+func debugAdmin(c *gin.Context) bool {
+	if !DebugEndpoints {
+		abortWithError(c, errs.New(errs.ErrNotFound, "not found"), "")
+		return false
+	}
+	return currentAdmin(c) != nil
+}
+
+// GetDebugProfile serves the profiles of net/http/pprof, like /debug/pprof does.
+func GetDebugProfile(c *gin.Context) {
+	if !debugAdmin(c) {
+		return
+	}
+
+	switch name := strings.TrimPrefix(c.Param("profile"), "/"); name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// GetDebugRuntime responds with counts about the running server: goroutines, memory,
+// rooms and connections.
+func GetDebugRuntime(c *gin.Context) {
+	if !debugAdmin(c) {
+		return
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	c.JSON(200, gin.H{
+		"goroutines": runtime.NumGoroutine(),
+		"memory": gin.H{
+			"heapAlloc":   m.HeapAlloc,
+			"heapInuse":   m.HeapInuse,
+			"heapObjects": m.HeapObjects,
+			"sys":         m.Sys,
+			"numGC":       m.NumGC,
+		},
+		"hub": game.HubMain.Stats(),
+	})
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, admin := userRequest(t, api, "POST", "", `{"name":"admin"}`)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	Admins = map[string]bool{admin.User.Id: true}
+	t.Cleanup(func() { Admins = map[string]bool{}; DebugEndpoints = false })
+
+	w := adminRequest(t, api, "GET", "/api/admin/debug/runtime", admin.Token, "")
+	assert.Equal(t, 404, w.Code, "debug endpoints should be off by default")
+
+	DebugEndpoints = true
+	w = adminRequest(t, api, "GET", "/api/admin/debug/runtime", alice.Token, "")
+	assert.Equal(t, 403, w.Code)
+	w = adminRequest(t, api, "GET", "/api/admin/debug/pprof/heap", alice.Token, "")
+	assert.Equal(t, 403, w.Code)
+
+	w = adminRequest(t, api, "GET", "/api/admin/debug/runtime", admin.Token, "")
+	assert.Equal(t, 200, w.Code)
+	var body struct {
+		Goroutines int `json:"goroutines"`
+		Hub        struct {
+			Rooms int `json:"rooms"`
+		} `json:"hub"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Positive(t, body.Goroutines)
+
+	w = adminRequest(t, api, "GET", "/api/admin/debug/pprof/", admin.Token, "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine")
+	w = adminRequest(t, api, "GET", "/api/admin/debug/pprof/goroutine?debug=1", admin.Token, "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine profile")
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"cardgame/deck"
+	"cardgame/errs"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,11 +13,11 @@ func GetDecks(c *gin.Context) {
 }
 
 func GetDeck(c *gin.Context) {
-	id := c.Param("id")
-	if d, ok := deck.Decks()[id]; ok {
-		c.Header("Cache-Control", "public, max-age=31536000") // 1 year
-		c.JSON(200, gin.H{"deck": d})
-	} else {
-		c.JSON(404, gin.H{"error": "deck not found"})
+	d, ok := deck.Decks()[c.Param("id")]
+	if !ok {
+		abortWithError(c, errs.New(errs.ErrNotFound, "deck not found"), "")
+		return
 	}
+	c.Header("Cache-Control", "public, max-age=31536000") // 1 year
+	c.JSON(200, gin.H{"deck": d})
 }
@@ -0,0 +1,19 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDeckMissing(t *testing.T) {
+	api := initTestApi(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/deck/d_missing", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+	assert.JSONEq(t, `{"error":"deck not found","code":"not_found"}`, w.Body.String())
+}
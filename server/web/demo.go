@@ -0,0 +1,70 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/util/ratelimit"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Demo serves the public demo instead of the game, set on startup: guests play instant games
+// against bots, and the rest of the API, accounts included, answers as if it didn't exist.
+var Demo = false
+
+// DemoRate is how many demos an address can start per minute, set on startup.
+var DemoRate = 3
+
+var errDemoRate = errs.New(errs.ErrRateLimited, "too many demos started, try again in a minute")
+
+// demoLimits limits how often each address starts demos. Addresses which haven't started a
+// demo for a while are forgotten, so the map doesn't grow with every visitor.
+var demoLimits = struct {
+	sync.Mutex
+	byAddress map[string]*demoLimit
+}{byAddress: map[string]*demoLimit{}}
+
+type demoLimit struct {
+	limiter *ratelimit.Limiter
+	last    time.Time
+}
+
+// allowDemo reports whether an address may start a demo now.
+func allowDemo(address string) bool {
+	demoLimits.Lock()
+	defer demoLimits.Unlock()
+
+	now := time.Now()
+	if len(demoLimits.byAddress) > 10000 {
+		for a, l := range demoLimits.byAddress {
+			if now.Sub(l.last) > time.Minute {
+				delete(demoLimits.byAddress, a)
+			}
+		}
+	}
+	l, ok := demoLimits.byAddress[address]
+	if !ok {
+		l = &demoLimit{limiter: ratelimit.New(DemoRate, time.Minute)}
+		demoLimits.byAddress[address] = l
+	}
+	l.last = now
+	return l.limiter.AllowAt(now)
+}
+
+// StartDemo opens a room for a guest to try the game against bots. The game starts once the
+// guest joins the room.
+func StartDemo(c *gin.Context) {
+	if !allowDemo(c.ClientIP()) {
+		abortWithError(c, errDemoRate, "")
+		return
+	}
+
+	r, err := game.HubMain.DemoRoom()
+	if err != nil {
+		abortWithError(c, err, "failed to open demo")
+		return
+	}
+	respondWithRoom(c, r)
+}
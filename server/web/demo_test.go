@@ -0,0 +1,47 @@
+package web
+
+import (
+	"cardgame/game"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDemo(t *testing.T) {
+	Demo = true
+	t.Cleanup(func() { Demo = false })
+	api := initTestApi(t)
+	request := func(method, path string) (int, []byte) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		api.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, _ := request("GET", "/api/rooms")
+	assert.Equal(t, 404, code, "the demo should only serve demos")
+	code, _ = request("POST", "/api/room")
+	assert.Equal(t, 404, code)
+	code, _ = request("GET", "/api/me")
+	assert.Equal(t, 404, code)
+
+	for i := 0; i < DemoRate; i++ {
+		code, body := request("POST", "/api/demo")
+		assert.Equal(t, 200, code)
+		var opened struct {
+			Room struct {
+				Id string `json:"id"`
+			} `json:"room"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &opened))
+		if _, ok := game.HubMain.Room(opened.Room.Id); assert.True(t, ok) {
+			game.HubMain.RemoveRoom(opened.Room.Id)
+		}
+	}
+	code, _ = request("POST", "/api/demo")
+	assert.Equal(t, 429, code, "an address should only start a few demos a minute")
+}
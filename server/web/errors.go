@@ -0,0 +1,39 @@
+package web
+
+import (
+	"cardgame/errs"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithError answers a request with the status and code of the kind of an error, see
+// package errs. Errors without a kind are logged, and answered with message instead of their
+// own text, which isn't meant for clients.
+func abortWithError(c *gin.Context, err error, message string) {
+	abortWithDetails(c, err, message, nil)
+}
+
+// abortWithDetails is abortWithError for errors which come with more for clients to know,
+// like when they can try again.
+func abortWithDetails(c *gin.Context, err error, message string, details gin.H) {
+	kind := errs.KindOf(err)
+	body := gin.H{"error": err.Error(), "code": kind.Code()}
+	if kind == errs.ErrInternal {
+		requestLog(c).Error(message, "err", err)
+		body["error"] = message
+	}
+	for k, v := range details {
+		body[k] = v
+	}
+	c.AbortWithStatusJSON(kind.Status(), body)
+}
+
+// notFound names what wasn't found by errors of the kind errs.ErrNotFound, like "user not
+// found", and returns other errors as they are.
+func notFound(err error, text string) error {
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.New(errs.ErrNotFound, text)
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package web
+
+import (
+	"cardgame/feature"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFeatures responds with the names of the feature flags on for the current account, or for
+// guests if the request has no token, so clients can show the features being rolled out.
+func GetFeatures(c *gin.Context) {
+	accountId := ""
+	if c.Request.Header.Get("Authorization") != "" {
+		a := currentUser(c)
+		if a == nil {
+			return
+		}
+		accountId = a.Id
+	}
+	c.JSON(200, gin.H{"features": feature.EnabledFor(accountId)})
+}
+
+// GetFeatureFlags responds with the rollout of every feature flag.
+func GetFeatureFlags(c *gin.Context) {
+	if currentAdmin(c) == nil {
+		return
+	}
+	c.JSON(200, gin.H{"flags": feature.All()})
+}
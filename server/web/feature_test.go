@@ -0,0 +1,52 @@
+package web
+
+import (
+	"cardgame/feature"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatures(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, admin := userRequest(t, api, "POST", "", `{"name":"admin"}`)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	Admins = map[string]bool{admin.User.Id: true}
+	flags, err := feature.Parse("everyone=on,alice_only=" + alice.User.Id + ",nobody=off")
+	require.NoError(t, err)
+	feature.Configure(flags)
+	t.Cleanup(func() { Admins = map[string]bool{}; feature.Configure(feature.Flags{}) })
+
+	features := func(token string) []string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/features", nil)
+		if token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		api.ServeHTTP(w, req)
+		require.Equal(t, 200, w.Code)
+		var body struct {
+			Features []string `json:"features"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Features
+	}
+	assert.Equal(t, []string{"everyone"}, features(""))
+	assert.Equal(t, []string{"alice_only", "everyone"}, features(alice.Token))
+	assert.Equal(t, []string{"everyone"}, features(admin.Token))
+
+	w := adminRequest(t, api, "GET", "/api/features", "bad token", "")
+	assert.Equal(t, 401, w.Code)
+
+	w = adminRequest(t, api, "GET", "/api/admin/features", alice.Token, "")
+	assert.Equal(t, 403, w.Code)
+	w = adminRequest(t, api, "GET", "/api/admin/features", admin.Token, "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"nobody"`)
+}
@@ -0,0 +1,159 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	friendAccepted = "accepted"
+	friendIncoming = "incoming" // the other account sent a request
+	friendOutgoing = "outgoing" // the current account sent a request
+)
+
+// friend is an account on the current account's friends list.
+type friend struct {
+	Id          string         `json:"id"`
+	Name        string         `json:"name"`
+	Avatar      storage.Avatar `json:"avatar"`
+	AvatarImage int64          `json:"avatarImage"`
+	Status      string         `json:"status"`
+	Online      bool           `json:"online"` // only shown for accepted friends
+	Since       int64          `json:"since"`  // unix ms when the request was sent, or accepted
+}
+
+func friendStatus(accountId string, f *storage.Friendship) string {
+	if f.Accepted != 0 {
+		return friendAccepted
+	}
+	if f.AccountId == accountId {
+		return friendOutgoing
+	}
+	return friendIncoming
+}
+
+// GetFriends responds with the friends and pending friend requests of the current account.
+func GetFriends(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	friendships, err := storage.Default.Friendships(a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to load friends")
+		return
+	}
+
+	friends := []friend{}
+	for _, f := range friendships {
+		id := f.FriendId
+		if id == a.Id {
+			id = f.AccountId
+		}
+		other, err := storage.Default.Account(id)
+		if errors.Is(err, errs.ErrNotFound) || err == nil && other.Deleted != 0 {
+			continue
+		}
+		if err != nil {
+			abortWithError(c, err, "failed to load friends")
+			return
+		}
+
+		status := friendStatus(a.Id, f)
+		since := f.Created
+		if f.Accepted != 0 {
+			since = f.Accepted
+		}
+		friends = append(friends, friend{
+			Id:          other.Id,
+			Name:        other.Name,
+			Avatar:      other.Avatar,
+			AvatarImage: other.AvatarImage,
+			Status:      status,
+			Online:      status == friendAccepted && game.HubMain.Online(other.Id),
+			Since:       since,
+		})
+	}
+
+	c.JSON(200, gin.H{"friends": friends})
+}
+
+// AddFriend sends a friend request to an account, or accepts the one it sent.
+func AddFriend(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	id := c.Param("id")
+	if id == a.Id {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "cannot add yourself as a friend"), "")
+		return
+	}
+	other, err := storage.Default.Account(id)
+	if err == nil && other.Deleted != 0 {
+		err = errs.ErrNotFound
+	}
+	if err != nil {
+		abortWithError(c, notFound(err, "user not found"), "failed to load account")
+		return
+	}
+
+	if blocked, ok := blockedEither(c, a.Id, id); !ok {
+		return
+	} else if blocked {
+		abortWithError(c, errs.New(errs.ErrPermission, "cannot add user as a friend"), "")
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	f, err := storage.Default.Friendship(a.Id, id)
+	if errors.Is(err, errs.ErrNotFound) {
+		f = &storage.Friendship{AccountId: a.Id, FriendId: id, Created: now}
+		if !saveFriendship(c, f) {
+			return
+		}
+		game.HubMain.Notify(id, &game.ServerFriend{AccountId: a.Id, Name: a.Name, Status: friendIncoming})
+	} else if err != nil {
+		abortWithError(c, err, "failed to load friendship")
+		return
+	} else if friendStatus(a.Id, f) == friendIncoming {
+		f.Accepted = now
+		if !saveFriendship(c, f) {
+			return
+		}
+		game.HubMain.Notify(id, &game.ServerFriend{AccountId: a.Id, Name: a.Name, Status: friendAccepted})
+	}
+
+	c.JSON(200, gin.H{"id": other.Id, "status": friendStatus(a.Id, f)})
+}
+
+func saveFriendship(c *gin.Context, f *storage.Friendship) bool {
+	if err := storage.Default.SaveFriendship(f); err != nil {
+		abortWithError(c, err, "failed to save friendship")
+		return false
+	}
+	return true
+}
+
+// RemoveFriend removes a friend, or declines or cancels a friend request.
+func RemoveFriend(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	err := storage.Default.DeleteFriendship(a.Id, c.Param("id"))
+	if err != nil {
+		abortWithError(c, notFound(err, "not friends with user"), "failed to delete friendship")
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFriends(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+
+	request := func(method, token, id string) (int, map[string]interface{}) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		url := "/api/me/friends"
+		if id != "" {
+			url += "/" + id
+		}
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		api.ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+	friends := func(token string) []friend {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/me/friends", nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		api.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		var body struct {
+			Friends []friend `json:"friends"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Friends
+	}
+
+	code, body := request("POST", alice.Token, bob.User.Id)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, friendOutgoing, body["status"])
+	if f := friends(bob.Token); assert.Len(t, f, 1) {
+		assert.Equal(t, "alice", f[0].Name)
+		assert.Equal(t, friendIncoming, f[0].Status)
+	}
+
+	code, body = request("POST", bob.Token, alice.User.Id)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, friendAccepted, body["status"], "requesting back should accept the request")
+	if f := friends(alice.Token); assert.Len(t, f, 1) {
+		assert.Equal(t, friendAccepted, f[0].Status)
+		assert.False(t, f[0].Online)
+	}
+
+	code, _ = request("POST", alice.Token, alice.User.Id)
+	assert.Equal(t, 400, code)
+	code, _ = request("POST", alice.Token, "u_missing")
+	assert.Equal(t, 404, code)
+
+	code, _ = request("DELETE", bob.Token, alice.User.Id)
+	assert.Equal(t, 200, code)
+	assert.Empty(t, friends(alice.Token))
+	code, _ = request("DELETE", bob.Token, alice.User.Id)
+	assert.Equal(t, 404, code)
+
+	code, r := userRequest(t, api, "PUT", alice.Token, `{"invites":"everyone"}`)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, storage.InvitesEveryone, r.User.Invites)
+	code, _ = userRequest(t, api, "PUT", alice.Token, `{"invites":"strangers"}`)
+	assert.Equal(t, 400, code)
+}
+
+func TestBlocks(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+
+	request := func(method, path, token string) (int, []byte) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		api.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, _ := request("POST", "/api/me/friends/"+bob.User.Id, alice.Token)
+	assert.Equal(t, 200, code)
+	code, _ = request("POST", "/api/me/blocks/"+alice.User.Id, bob.Token)
+	assert.Equal(t, 200, code)
+	_, err := storage.Default.Friendship(alice.User.Id, bob.User.Id)
+	assert.ErrorIs(t, err, storage.ErrNotFound, "blocking should end the friendship")
+
+	code, body := request("GET", "/api/me/blocks", bob.Token)
+	assert.Equal(t, 200, code)
+	var blocks struct {
+		Blocks []blockedUser `json:"blocks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &blocks))
+	if assert.Len(t, blocks.Blocks, 1) {
+		assert.Equal(t, "alice", blocks.Blocks[0].Name)
+	}
+
+	code, _ = request("POST", "/api/me/friends/"+bob.User.Id, alice.Token)
+	assert.Equal(t, 403, code, "blocked accounts should not be able to send friend requests")
+	code, _ = request("POST", "/api/me/blocks/"+bob.User.Id, bob.Token)
+	assert.Equal(t, 400, code)
+	code, _ = request("POST", "/api/me/blocks/u_missing", bob.Token)
+	assert.Equal(t, 404, code)
+
+	code, _ = request("DELETE", "/api/me/blocks/"+alice.User.Id, bob.Token)
+	assert.Equal(t, 200, code)
+	code, _ = request("DELETE", "/api/me/blocks/"+alice.User.Id, bob.Token)
+	assert.Equal(t, 404, code)
+	code, _ = request("POST", "/api/me/friends/"+bob.User.Id, alice.Token)
+	assert.Equal(t, 200, code)
+}
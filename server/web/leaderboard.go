@@ -0,0 +1,125 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/leaderboard"
+	"cardgame/storage"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxAround = 25
+
+// seasonInfo describes a season. Start and end are unix ms, and missing if the season is unbounded.
+type seasonInfo struct {
+	Season   int    `json:"season"`
+	Start    *int64 `json:"start"`
+	End      *int64 `json:"end"`
+	Archived bool   `json:"archived"`
+}
+
+func unixMilli(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+	ms := t.UnixMilli()
+	return &ms
+}
+
+// season reads the "season" query parameter, which defaults to the current season.
+// If it is invalid, the request is aborted and false is returned.
+func season(c *gin.Context) (seasonInfo, bool) {
+	current := leaderboard.Seasons.Season(time.Now())
+	info := seasonInfo{Season: current}
+	if s := c.Query("season"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > current {
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "invalid season"), "")
+			return info, false
+		}
+		info.Season = n
+	}
+
+	start, end := leaderboard.Seasons.Bounds(info.Season)
+	info.Start, info.End = unixMilli(start), unixMilli(end)
+
+	_, err := storage.Default.SeasonArchive(info.Season)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		abortWithError(c, err, "failed to load season")
+		return info, false
+	}
+	info.Archived = err == nil
+	return info, true
+}
+
+// GetLeaderboard responds with a page of a leaderboard, picked with the "offset" and "limit"
+// query parameters. "gameType" picks the leaderboard, which is the global one if it isn't set.
+func GetLeaderboard(c *gin.Context) {
+	info, ok := season(c)
+	if !ok {
+		return
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		n, err := strconv.Atoi(o)
+		if err != nil || n < 0 {
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "invalid offset"), "")
+			return
+		}
+		offset = n
+	}
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+
+	entries, err := storage.Default.Leaderboard(storage.LeaderboardQuery{
+		Season:   info.Season,
+		GameType: c.Query("gameType"),
+		Offset:   offset,
+		Limit:    limit,
+	})
+	if err != nil {
+		abortWithError(c, err, "failed to load leaderboard")
+		return
+	}
+
+	c.JSON(200, gin.H{"season": info, "entries": entries})
+}
+
+// GetUserLeaderboard responds with an account's entry on a leaderboard, and the entries
+// within "around" places of it.
+func GetUserLeaderboard(c *gin.Context) {
+	info, ok := season(c)
+	if !ok {
+		return
+	}
+
+	around := 5
+	if a := c.Query("around"); a != "" {
+		n, err := strconv.Atoi(a)
+		if err != nil || n < 0 || n > maxAround {
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "invalid around"), "")
+			return
+		}
+		around = n
+	}
+
+	entries, err := leaderboard.Around(storage.Default, info.Season, c.Query("gameType"), c.Param("id"), around)
+	if err != nil {
+		abortWithError(c, notFound(err, "user is not on the leaderboard"), "failed to load leaderboard")
+		return
+	}
+
+	var entry *storage.LeaderboardEntry
+	for _, e := range entries {
+		if e.AccountId == c.Param("id") {
+			entry = e
+		}
+	}
+	c.JSON(200, gin.H{"season": info, "entry": entry, "entries": entries})
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"cardgame/leaderboard"
+	"cardgame/storage"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboard(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	old := leaderboard.Seasons
+	leaderboard.Seasons = leaderboard.Schedule{time.Now().Add(-time.Hour)}
+	t.Cleanup(func() { leaderboard.Seasons = old })
+
+	entries := []*storage.LeaderboardEntry{}
+	for i := 1; i <= 10; i++ {
+		entries = append(entries, &storage.LeaderboardEntry{Season: 1, GameType: "classic", AccountId: fmt.Sprintf("u_%d", i), Score: float64(2000 - i)})
+	}
+	storage.Default.SaveLeaderboardEntries(entries)
+	storage.Default.SaveSeasonArchive(&storage.SeasonArchive{Season: 0, Archived: 1})
+	api := initTestApi(t)
+
+	type response struct {
+		Season  seasonInfo                  `json:"season"`
+		Entry   *storage.LeaderboardEntry   `json:"entry"`
+		Entries []*storage.LeaderboardEntry `json:"entries"`
+	}
+	get := func(url string, code int) response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		api.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Code)
+		var r response
+		json.Unmarshal(w.Body.Bytes(), &r)
+		return r
+	}
+
+	top := get("/api/leaderboard?gameType=classic&limit=3&offset=1", 200)
+	assert.Equal(t, 1, top.Season.Season, "the current season should be the default")
+	assert.NotNil(t, top.Season.Start)
+	assert.Nil(t, top.Season.End)
+	assert.False(t, top.Season.Archived)
+	if assert.Len(t, top.Entries, 3) {
+		assert.Equal(t, "u_2", top.Entries[0].AccountId)
+		assert.Equal(t, 2, top.Entries[0].Rank)
+	}
+
+	me := get("/api/leaderboard/user/u_5?gameType=classic&around=1", 200)
+	if assert.NotNil(t, me.Entry) {
+		assert.Equal(t, 5, me.Entry.Rank)
+	}
+	assert.Len(t, me.Entries, 3)
+
+	past := get("/api/leaderboard?season=0", 200)
+	assert.True(t, past.Season.Archived)
+	assert.Empty(t, past.Entries)
+
+	get("/api/leaderboard?season=2", 400)
+	get("/api/leaderboard/user/u_5?gameType=classic&around=100", 400)
+	get("/api/leaderboard/user/u_missing", 404)
+}
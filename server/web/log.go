@@ -0,0 +1,32 @@
+package web
+
+import (
+	"cardgame/util"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logKey is the key of the request's logger in the gin context.
+const logKey = "log"
+
+// logRequests gives every request a logger carrying a request id, its method and route. The id
+// is taken from the X-Request-Id header if a proxy set one, and sent back in the response.
+func logRequests(c *gin.Context) {
+	id := c.Request.Header.Get("X-Request-Id")
+	if id == "" {
+		id = util.IdFrom("req", util.Token())
+	}
+	c.Header("X-Request-Id", id)
+	c.Set(logKey, slog.With("request", id, "method", c.Request.Method, "route", c.FullPath()))
+	c.Next()
+}
+
+// requestLog returns the logger of a request. Once the account making it is known, it also
+// carries the account id.
+func requestLog(c *gin.Context) *slog.Logger {
+	if l, ok := c.Value(logKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
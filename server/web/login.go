@@ -0,0 +1,429 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/locale"
+	"cardgame/mail"
+	"cardgame/storage"
+	"cardgame/util"
+	"cardgame/util/ratelimit"
+	"errors"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Mailer sends the emails verifying addresses and resetting passwords. Without one, accounts
+// can still have an email and a password, but emails can't be verified or passwords reset.
+var Mailer mail.Mailer
+
+// PublicURL is where players open the game, like "https://cards.example.com". Links in emails
+// point to its /verify-email and /reset-password pages, with the token as a query parameter.
+var PublicURL string
+
+// EmailTokenTTL is how long the links in emails work.
+var EmailTokenTTL = 24 * time.Hour
+
+const (
+	minPasswordLength = 8  // in runes
+	maxPasswordLength = 72 // in bytes, bcrypt ignores the rest
+)
+
+var (
+	errWrongLogin     = errs.New(errs.ErrUnauthorized, "wrong email or password")
+	errWrongPassword  = errs.New(errs.ErrPermission, "wrong password")
+	errLoginRate      = errs.New(errs.ErrRateLimited, "too many attempts, try again later")
+	errMailRate       = errs.New(errs.ErrRateLimited, "too many emails sent, try again later")
+	errNoMailer       = errs.New(errs.ErrUnsupported, "email isn't set up on this server")
+	errEmailLink      = errs.New(errs.ErrInvalidInput, "link is invalid or expired")
+	errInvalidEmail   = errs.New(errs.ErrInvalidInput, "invalid email")
+	errPasswordLength = errs.New(errs.ErrInvalidInput, "password must be 8 to 72 characters")
+)
+
+var (
+	// loginLimits limits password attempts, both by address and by account, so passwords can't
+	// be guessed from many addresses either.
+	loginLimits = ratelimit.NewKeyed(10, 15*time.Minute)
+
+	// mailLimits limits the emails sent, both by address and by account, so no one's inbox is
+	// flooded with links.
+	mailLimits = ratelimit.NewKeyed(3, time.Hour)
+)
+
+// checkPassword reports whether password is the account's, counting the attempt against the
+// account's login limit. If it isn't, or there were too many attempts, the request is aborted
+// with wrong and false is returned.
+func checkPassword(c *gin.Context, a *storage.Account, password string, wrong error) bool {
+	if !loginLimits.Allow("account:" + a.Id) {
+		abortWithError(c, errLoginRate, "")
+		return false
+	}
+	if a.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password)) != nil {
+		abortWithError(c, wrong, "")
+		return false
+	}
+	return true
+}
+
+// setPassword replaces the password of an account. If it is too short or too long, the
+// request is aborted and false is returned.
+func setPassword(c *gin.Context, a *storage.Account, password string) bool {
+	if utf8.RuneCountInString(password) < minPasswordLength || len(password) > maxPasswordLength {
+		abortWithError(c, errPasswordLength, "")
+		return false
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		abortWithError(c, err, "failed to hash password")
+		return false
+	}
+	a.PasswordHash = string(hash)
+	return true
+}
+
+// setEmail changes the email of an account, which has to be verified again, or removes it if
+// email is empty. If it isn't an address or another account has it, the request is aborted
+// and false is returned.
+func setEmail(c *gin.Context, a *storage.Account, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email != "" {
+		if addr, err := netmail.ParseAddress(email); err != nil || addr.Address != email {
+			abortWithError(c, errInvalidEmail, "")
+			return false
+		}
+		other, err := storage.Default.AccountByEmail(email)
+		if err == nil && other.Id != a.Id {
+			abortWithError(c, storage.ErrEmailTaken, "")
+			return false
+		}
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			abortWithError(c, err, "failed to load account")
+			return false
+		}
+	}
+	if email != a.Email {
+		a.Email = email
+		a.EmailVerified = 0
+	}
+	return true
+}
+
+// mailToken mails the account a link with a new token for purpose, replacing the ones mailed
+// for it before.
+func mailToken(c *gin.Context, a *storage.Account, purpose storage.EmailPurpose) error {
+	if Mailer == nil {
+		return errNoMailer
+	}
+	if !mailLimits.Allow("account:" + a.Id) {
+		return errMailRate
+	}
+
+	token := util.Token()
+	now := time.Now()
+	if err := storage.Default.DeleteEmailTokens(a.Id, purpose); err != nil {
+		return err
+	}
+	err := storage.Default.SaveEmailToken(&storage.EmailToken{
+		TokenHash: storage.HashToken(token),
+		AccountId: a.Id,
+		Purpose:   purpose,
+		Email:     a.Email,
+		Created:   now.UnixMilli(),
+		Expires:   now.Add(EmailTokenTTL).UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	subject, body, page := "email_verify_subject", "email_verify_body", "/verify-email"
+	if purpose == storage.EmailResetPassword {
+		subject, body, page = "email_reset_subject", "email_reset_body", "/reset-password"
+	}
+	lang := locale.Match(c.GetHeader("Accept-Language"))
+	return Mailer.Send(mail.Message{
+		To:      a.Email,
+		Subject: locale.Translate(lang, subject, nil),
+		Body: locale.Translate(lang, body, locale.Params{
+			"name":  a.Name,
+			"link":  PublicURL + page + "?token=" + token,
+			"hours": strconv.Itoa(int(EmailTokenTTL.Hours())),
+		}),
+	})
+}
+
+// mailVerification mails a new account, or one that changed its email, the link verifying it.
+// The account is saved already, so if the email can't be sent it is only logged, and the owner
+// can ask for another one.
+func mailVerification(c *gin.Context, a *storage.Account) {
+	if a.Email == "" || Mailer == nil {
+		return
+	}
+	if err := mailToken(c, a, storage.EmailVerify); err != nil {
+		requestLog(c).Warn("failed to mail verification", "err", err)
+	}
+}
+
+// emailAccount returns the account a token was mailed to for purpose, if it hasn't expired.
+// Otherwise the request is aborted and nil is returned.
+func emailAccount(c *gin.Context, token string, purpose storage.EmailPurpose) *storage.Account {
+	t, err := storage.Default.EmailToken(storage.HashToken(token))
+	if err == nil && (t.Purpose != purpose || time.Now().UnixMilli() >= t.Expires) {
+		err = errs.ErrNotFound
+	}
+	var a *storage.Account
+	if err == nil {
+		a, err = storage.Default.Account(t.AccountId)
+	}
+	// links mailed to an address the account has since changed stop working
+	if err == nil && a.Email != t.Email {
+		err = errs.ErrNotFound
+	}
+	if errors.Is(err, errs.ErrNotFound) {
+		err = errEmailLink
+	}
+	if err != nil {
+		abortWithError(c, err, "failed to load email token")
+		return nil
+	}
+	return a
+}
+
+// loginRequest is the body of requests signing in, with either the email or the name of the
+// account, and a two-factor code if it has two-factor authentication on.
+type loginRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"`
+}
+
+// Login signs in to an account with its email or name and its password, on a new session. The
+// token in the response is only ever sent once. Accounts with two-factor authentication on
+// also need a code of their authenticator app, or a recovery code; without one the response
+// is 401 with "totp" set, so the client can ask for it.
+func Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	if !loginLimits.Allow("address:" + c.ClientIP()) {
+		abortWithError(c, errLoginRate, "")
+		return
+	}
+
+	var a *storage.Account
+	var err error
+	switch {
+	case req.Email != "":
+		a, err = storage.Default.AccountByEmail(strings.TrimSpace(req.Email))
+	case req.Name != "":
+		a, err = storage.Default.AccountByName(strings.TrimSpace(req.Name))
+	default:
+		err = errs.New(errs.ErrInvalidInput, "email or name is required")
+	}
+	if errors.Is(err, errs.ErrNotFound) {
+		err = errWrongLogin
+	}
+	if err != nil {
+		abortWithError(c, err, "failed to load account")
+		return
+	}
+	if !checkPassword(c, a, req.Password, errWrongLogin) {
+		return
+	}
+	if a.TotpEnabled != 0 && (!checkCode(c, a, req.Code) || !saveUser(c, a)) {
+		return
+	}
+
+	token, ok := newSession(c, a.Id)
+	if !ok {
+		return
+	}
+	c.JSON(200, gin.H{"user": ownUser(a), "token": token})
+}
+
+// ChangeEmail changes the email of the current account, and mails the new address a link to
+// verify it. Accounts with a password have to send it along.
+func ChangeEmail(c *gin.Context) {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if a.PasswordHash != "" && !checkPassword(c, a, req.Password, errWrongPassword) {
+		return
+	}
+	if !setEmail(c, a, req.Email) {
+		return
+	}
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+	if a.EmailVerified == 0 {
+		mailVerification(c, a)
+	}
+
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
+
+// SendVerification mails the current account another link verifying its email.
+func SendVerification(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if a.Email == "" {
+		abortWithError(c, errs.New(errs.ErrInvalidAction, "account has no email"), "")
+		return
+	}
+	if a.EmailVerified != 0 {
+		abortWithError(c, errs.New(errs.ErrInvalidAction, "email is already verified"), "")
+		return
+	}
+	if err := mailToken(c, a, storage.EmailVerify); err != nil {
+		abortWithError(c, err, "failed to mail verification")
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+// VerifyEmail verifies the email of an account, with the token of a link mailed to it.
+func VerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := emailAccount(c, req.Token, storage.EmailVerify)
+	if a == nil {
+		return
+	}
+
+	if a.EmailVerified == 0 {
+		a.EmailVerified = time.Now().UnixMilli()
+		a.Updated = a.EmailVerified
+		if !saveUser(c, a) {
+			return
+		}
+	}
+	if err := storage.Default.DeleteEmailTokens(a.Id, storage.EmailVerify); err != nil {
+		abortWithError(c, err, "failed to delete email tokens")
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+// ChangePassword sets the password of the current account. Accounts which already have one
+// have to send it along.
+func ChangePassword(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Current  string `json:"current"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if a.PasswordHash != "" && !checkPassword(c, a, req.Current, errWrongPassword) {
+		return
+	}
+	if !setPassword(c, a, req.Password) {
+		return
+	}
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
+
+// RequestPasswordReset mails a link to reset the password to the account with an email, if
+// the email is verified. The response is the same either way, so it doesn't tell which
+// addresses have accounts.
+func RequestPasswordReset(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	if Mailer == nil {
+		abortWithError(c, errNoMailer, "")
+		return
+	}
+	if !mailLimits.Allow("address:" + c.ClientIP()) {
+		abortWithError(c, errMailRate, "")
+		return
+	}
+
+	a, err := storage.Default.AccountByEmail(strings.TrimSpace(req.Email))
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		abortWithError(c, err, "failed to load account")
+		return
+	}
+	if err == nil && a.EmailVerified != 0 && a.Deleted == 0 {
+		if err := mailToken(c, a, storage.EmailResetPassword); err != nil {
+			requestLog(c).Warn("failed to mail password reset", "err", err)
+		}
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+// ResetPassword sets the password of an account, with the token of a link mailed to it. Every
+// session of the account is revoked, so whoever knew the old password is signed out, and the
+// owner signs in again with the new one.
+func ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := emailAccount(c, req.Token, storage.EmailResetPassword)
+	if a == nil || !setPassword(c, a, req.Password) {
+		return
+	}
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+	if err := storage.Default.DeleteEmailTokens(a.Id, storage.EmailResetPassword); err != nil {
+		abortWithError(c, err, "failed to delete email tokens")
+		return
+	}
+	if err := storage.Default.DeleteSessions(a.Id); err != nil {
+		abortWithError(c, err, "failed to revoke sessions")
+		return
+	}
+	game.HubMain.EndSessions(a.Id)
+
+	c.JSON(200, gin.H{})
+}
@@ -0,0 +1,223 @@
+package web
+
+import (
+	"cardgame/mail"
+	"cardgame/storage"
+	"cardgame/util/ratelimit"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMailer keeps the emails sent with it.
+type testMailer struct {
+	sent []mail.Message
+}
+
+func (m *testMailer) Send(msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// mailedToken returns the token in the link of the last email sent.
+func (m *testMailer) mailedToken(t *testing.T) string {
+	t.Helper()
+	if !assert.NotEmpty(t, m.sent) {
+		return ""
+	}
+	match := regexp.MustCompile(`\?token=(\S+)`).FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	if !assert.NotNil(t, match) {
+		return ""
+	}
+	return match[1]
+}
+
+// useTestMailer sends emails with a testMailer until the test ends, with fresh rate limits.
+func useTestMailer(t *testing.T) *testMailer {
+	m := &testMailer{}
+	Mailer, PublicURL = m, "https://cards.example.com"
+	loginLimits = ratelimit.NewKeyed(10, 15*time.Minute)
+	mailLimits = ratelimit.NewKeyed(3, time.Hour)
+	t.Cleanup(func() { Mailer, PublicURL = nil, "" })
+	return m
+}
+
+// ownUserResponse is the account of the current user, as only they see it.
+type ownUserResponse struct {
+	User struct {
+		Id            string `json:"id"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"emailVerified"`
+		HasPassword   bool   `json:"hasPassword"`
+	} `json:"user"`
+	Token string `json:"token"`
+}
+
+func jsonRequest(t *testing.T, api *gin.Engine, method, path, token, body string) (int, []byte) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+	api.ServeHTTP(w, req)
+	return w.Code, w.Body.Bytes()
+}
+
+func TestSignUpWithEmail(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	mailer := useTestMailer(t)
+
+	code, body := jsonRequest(t, api, "POST", "/api/me", "", `{"name":"alice","email":"Alice@Example.com","password":"correct horse"}`)
+	assert.Equal(t, 200, code)
+	var created ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, "alice@example.com", created.User.Email)
+	assert.False(t, created.User.EmailVerified)
+	assert.True(t, created.User.HasPassword)
+	if assert.Len(t, mailer.sent, 1, "a new email should be mailed a link to verify it") {
+		assert.Equal(t, "alice@example.com", mailer.sent[0].To)
+		assert.Contains(t, mailer.sent[0].Body, "https://cards.example.com/verify-email?token=")
+	}
+
+	code, body = jsonRequest(t, api, "GET", "/api/user/"+created.User.Id, "", "")
+	assert.Equal(t, 200, code)
+	assert.NotContains(t, string(body), "alice@example.com", "profiles should not show emails")
+
+	code, _ = jsonRequest(t, api, "POST", "/api/me", "", `{"name":"eve","email":"ALICE@example.com"}`)
+	assert.Equal(t, 409, code, "emails should be unique")
+	code, _ = jsonRequest(t, api, "POST", "/api/me", "", `{"name":"eve","email":"not an email"}`)
+	assert.Equal(t, 400, code)
+	code, _ = jsonRequest(t, api, "POST", "/api/me", "", `{"name":"eve","password":"short"}`)
+	assert.Equal(t, 400, code)
+
+	code, _ = jsonRequest(t, api, "POST", "/api/email/verify", "", `{"token":"made up"}`)
+	assert.Equal(t, 400, code)
+	code, _ = jsonRequest(t, api, "POST", "/api/email/verify", "", `{"token":"`+mailer.mailedToken(t)+`"}`)
+	assert.Equal(t, 200, code)
+	code, body = jsonRequest(t, api, "GET", "/api/me", created.Token, "")
+	assert.Equal(t, 200, code)
+	var got ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &got))
+	assert.True(t, got.User.EmailVerified)
+
+	code, _ = jsonRequest(t, api, "POST", "/api/me/email/verify", created.Token, "")
+	assert.Equal(t, 400, code, "verified emails should not be mailed again")
+}
+
+func TestLogin(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	useTestMailer(t)
+	_, body := jsonRequest(t, api, "POST", "/api/me", "", `{"name":"alice","email":"alice@example.com","password":"correct horse"}`)
+	var created ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &created))
+
+	code, body := jsonRequest(t, api, "POST", "/api/login", "", `{"email":"ALICE@example.com","password":"correct horse"}`)
+	assert.Equal(t, 200, code)
+	var login ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &login))
+	assert.Equal(t, created.User.Id, login.User.Id)
+	assert.NotEqual(t, created.Token, login.Token, "logging in should start another session")
+	code, _ = userRequest(t, api, "GET", login.Token, "")
+	assert.Equal(t, 200, code)
+
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"name":"Alice","password":"correct horse"}`)
+	assert.Equal(t, 200, code, "accounts should also be logged in to by name")
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"wrong horse"}`)
+	assert.Equal(t, 401, code)
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"bob@example.com","password":"correct horse"}`)
+	assert.Equal(t, 401, code, "unknown emails should look like wrong passwords")
+
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"name":"bob","password":"anything at all"}`)
+	assert.Equal(t, 401, code, "accounts without a password should not be logged in to")
+	code, _ = jsonRequest(t, api, "PUT", "/api/me/password", bob.Token, `{"password":"bobs password"}`)
+	assert.Equal(t, 200, code)
+	code, _ = jsonRequest(t, api, "PUT", "/api/me/password", bob.Token, `{"password":"new password","current":"wrong"}`)
+	assert.Equal(t, 403, code, "changing a password should need the current one")
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"name":"bob","password":"bobs password"}`)
+	assert.Equal(t, 200, code)
+
+	for i := 0; i < 10; i++ {
+		jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"guess"}`)
+	}
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"correct horse"}`)
+	assert.Equal(t, 429, code, "attempts should be limited")
+}
+
+func TestChangeEmail(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	mailer := useTestMailer(t)
+	_, body := jsonRequest(t, api, "POST", "/api/me", "", `{"name":"alice","email":"alice@example.com","password":"correct horse"}`)
+	var created ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &created))
+	oldLink := mailer.mailedToken(t)
+
+	code, _ := jsonRequest(t, api, "PUT", "/api/me/email", created.Token, `{"email":"new@example.com","password":"wrong"}`)
+	assert.Equal(t, 403, code, "changing the email should need the password")
+	code, body = jsonRequest(t, api, "PUT", "/api/me/email", created.Token, `{"email":"new@example.com","password":"correct horse"}`)
+	assert.Equal(t, 200, code)
+	var changed ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &changed))
+	assert.Equal(t, "new@example.com", changed.User.Email)
+	assert.Equal(t, "new@example.com", mailer.sent[len(mailer.sent)-1].To)
+
+	code, _ = jsonRequest(t, api, "POST", "/api/email/verify", "", `{"token":"`+oldLink+`"}`)
+	assert.Equal(t, 400, code, "links mailed to the old address should stop working")
+	code, _ = jsonRequest(t, api, "POST", "/api/me/email/verify", created.Token, "")
+	assert.Equal(t, 200, code)
+	code, _ = jsonRequest(t, api, "POST", "/api/me/email/verify", created.Token, "")
+	assert.Equal(t, 429, code, "emails should be limited")
+}
+
+func TestResetPassword(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	mailer := useTestMailer(t)
+	_, body := jsonRequest(t, api, "POST", "/api/me", "", `{"name":"alice","email":"alice@example.com","password":"correct horse"}`)
+	var created ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &created))
+
+	code, _ := jsonRequest(t, api, "POST", "/api/password/reset", "", `{"email":"alice@example.com"}`)
+	assert.Equal(t, 200, code)
+	assert.Len(t, mailer.sent, 1, "unverified emails should not be mailed resets")
+	code, _ = jsonRequest(t, api, "POST", "/api/email/verify", "", `{"token":"`+mailer.mailedToken(t)+`"}`)
+	assert.Equal(t, 200, code)
+
+	code, _ = jsonRequest(t, api, "POST", "/api/password/reset", "", `{"email":"nobody@example.com"}`)
+	assert.Equal(t, 200, code, "unknown emails should get the same response")
+	code, _ = jsonRequest(t, api, "POST", "/api/password/reset", "", `{"email":"Alice@example.com"}`)
+	assert.Equal(t, 200, code)
+	if assert.Len(t, mailer.sent, 2) {
+		assert.Contains(t, mailer.sent[1].Body, "https://cards.example.com/reset-password?token=")
+	}
+	token := mailer.mailedToken(t)
+
+	code, _ = jsonRequest(t, api, "POST", "/api/email/verify", "", `{"token":"`+token+`"}`)
+	assert.Equal(t, 400, code, "reset tokens should not verify emails")
+	code, _ = jsonRequest(t, api, "POST", "/api/password/reset/confirm", "", `{"token":"`+token+`","password":"battery staple"}`)
+	assert.Equal(t, 200, code)
+	code, _ = jsonRequest(t, api, "POST", "/api/password/reset/confirm", "", `{"token":"`+token+`","password":"another one"}`)
+	assert.Equal(t, 400, code, "reset tokens should only work once")
+
+	code, _ = userRequest(t, api, "GET", created.Token, "")
+	assert.Equal(t, 401, code, "resetting the password should sign every session out")
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"correct horse"}`)
+	assert.Equal(t, 401, code)
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"battery staple"}`)
+	assert.Equal(t, 200, code)
+
+	Mailer = nil
+	code, _ = jsonRequest(t, api, "POST", "/api/password/reset", "", `{"email":"alice@example.com"}`)
+	assert.Equal(t, 501, code, "resets should need a mailer")
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/storage"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageLimit reads the "limit" query parameter used to page through lists.
+// If it is invalid, the request is aborted and false is returned.
+func pageLimit(c *gin.Context) (limit int, ok bool) {
+	limit = defaultPageSize
+	if l := c.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxPageSize {
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "invalid limit"), "")
+			return 0, false
+		}
+		limit = n
+	}
+	return limit, true
+}
+
+// pageCursor reads the "before" query parameter of lists paged by time and id. Cursors are the
+// time and id of the last item of a page, as "time_id", since items can share a time; a time
+// alone skips every item of that time. If it is invalid, the request is aborted and false is
+// returned.
+func pageCursor(c *gin.Context) (before int64, beforeId string, ok bool) {
+	b := c.Query("before")
+	if b == "" {
+		return 0, "", true
+	}
+	at, id, _ := strings.Cut(b, "_")
+	n, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "invalid cursor"), "")
+		return 0, "", false
+	}
+	return n, id, true
+}
+
+// nextCursor returns the cursor of the page after one ending with the item of a time and id.
+func nextCursor(at int64, id string) *string {
+	cursor := fmt.Sprintf("%d_%s", at, id)
+	return &cursor
+}
+
+// listMatches responds with a page of matches, newest first. The page is picked with the
+// "before" and "limit" query parameters; "next" is the "before" value of the next page.
+// Cursors are the end time and id of the last match of a page, as "ended_id", since
+// matches can end at the same time; an end time alone skips every match that ended then.
+func listMatches(c *gin.Context, q storage.MatchQuery) {
+	var ok bool
+	if q.Limit, ok = pageLimit(c); !ok {
+		return
+	}
+	if q.Before, q.BeforeId, ok = pageCursor(c); !ok {
+		return
+	}
+
+	matches, err := storage.Default.Matches(q)
+	if err != nil {
+		abortWithError(c, err, "failed to load matches")
+		return
+	}
+
+	var next *string
+	if len(matches) == q.Limit {
+		last := matches[len(matches)-1]
+		next = nextCursor(last.Ended, last.Id)
+	}
+	c.JSON(200, gin.H{"matches": matches, "next": next})
+}
+
+func GetUserMatches(c *gin.Context) {
+	listMatches(c, storage.MatchQuery{AccountId: c.Param("id")})
+}
+
+func GetRoomMatches(c *gin.Context) {
+	listMatches(c, storage.MatchQuery{RoomId: c.Param("room")})
+}
+
+func GetMatch(c *gin.Context) {
+	m, err := storage.Default.Match(c.Param("id"))
+	if err != nil {
+		abortWithError(c, notFound(err, "match not found"), "failed to load match")
+		return
+	}
+
+	c.JSON(200, gin.H{"match": m})
+}
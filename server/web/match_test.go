@@ -0,0 +1,67 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchHistoryPages(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	for i := 1; i <= 5; i++ {
+		storage.Default.SaveMatch(&storage.Match{
+			Id:      fmt.Sprintf("m_%d", i),
+			RoomId:  "r_history",
+			Players: []storage.MatchPlayer{{Id: "p_1", AccountId: "u_1"}},
+			Ended:   int64((i + 1) / 2), // matches end two at a time
+		})
+	}
+	api := initTestApi(t)
+
+	type response struct {
+		Matches []*storage.Match `json:"matches"`
+		Next    *string          `json:"next"`
+	}
+	get := func(url string) response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		api.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		var r response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return r
+	}
+
+	first := get("/api/user/u_1/matches?limit=3")
+	assert.Equal(t, []string{"m_5", "m_3", "m_4"}, matchIds(first.Matches))
+	if assert.NotNil(t, first.Next) {
+		second := get("/api/room/r_history/matches?limit=3&before=" + *first.Next)
+		assert.Equal(t, []string{"m_1", "m_2"}, matchIds(second.Matches), "matches that ended with the last of a page should be on the next one")
+		assert.Nil(t, second.Next, "last page should have no cursor")
+	}
+	assert.Equal(t, []string{"m_1", "m_2"}, matchIds(get("/api/user/u_1/matches?before=2").Matches), "end times alone should still page")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/u_1/matches?limit=1000", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/match/m_missing", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func matchIds(matches []*storage.Match) []string {
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.Id
+	}
+	return ids
+}
@@ -0,0 +1,263 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"cardgame/util"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRoomPresets is the most room presets an account can save.
+const maxRoomPresets = 20
+
+// presetDetails is the body of requests saving a room preset. New presets start from the
+// default settings, the settings of a room the account owns, or a preset shared by its code,
+// and the set settings are applied on top.
+type presetDetails struct {
+	Name     *string         `json:"name"`
+	Settings json.RawMessage `json:"settings"` // only the settings to change
+	RoomId   string          `json:"roomId"`   // copy the settings of a room the account owns
+	Code     string          `json:"code"`     // copy a shared preset
+}
+
+// presetSettings decodes the settings saved in a preset. Settings it was saved without keep
+// their defaults.
+func presetSettings(p *storage.RoomPreset) (game.RoomSettings, error) {
+	s := game.DefaultRoomSettings()
+	err := json.Unmarshal(p.Settings, &s)
+	return s, err
+}
+
+// GetRoomPresets responds with the room presets of the current account, oldest first.
+func GetRoomPresets(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	presets, err := storage.Default.RoomPresets(a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to load room presets")
+		return
+	}
+	c.JSON(200, gin.H{"presets": presets})
+}
+
+// CreateRoomPreset saves a new room preset for the current account.
+func CreateRoomPreset(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	var details presetDetails
+	if err := c.ShouldBindJSON(&details); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+
+	presets, err := storage.Default.RoomPresets(a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to save room preset")
+		return
+	}
+	if len(presets) >= maxRoomPresets {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "too many room presets"), "")
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	p := &storage.RoomPreset{
+		Id:        util.IdFrom("rp", util.Token()),
+		AccountId: a.Id,
+		Code:      util.PresetCode(),
+		Created:   now,
+	}
+	settings := game.DefaultRoomSettings()
+	switch {
+	case details.Code != "":
+		shared := sharedPreset(c, details.Code)
+		if shared == nil {
+			return
+		}
+		if settings, err = presetSettings(shared); err != nil {
+			abortWithError(c, err, "failed to load room preset")
+			return
+		}
+		p.Name = shared.Name
+	case details.RoomId != "":
+		r, ok := game.HubMain.Room(details.RoomId)
+		owned := false
+		if ok {
+			r.Inspect(func() {
+				if owned = ownsRoom(a, r); owned {
+					settings = r.Settings()
+				}
+			})
+		}
+		if !owned {
+			abortWithError(c, game.ErrRoomNotFound, "")
+			return
+		}
+	}
+
+	if details.Name == nil && p.Name == "" {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "missing name"), "")
+		return
+	}
+	if !savePreset(c, p, settings, details) {
+		return
+	}
+	c.JSON(200, gin.H{"preset": p})
+}
+
+// UpdateRoomPreset renames a room preset of the current account or changes its settings.
+func UpdateRoomPreset(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	p := ownPreset(c, a)
+	if p == nil {
+		return
+	}
+
+	var details presetDetails
+	if err := c.ShouldBindJSON(&details); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	settings, err := presetSettings(p)
+	if err != nil {
+		abortWithError(c, err, "failed to load room preset")
+		return
+	}
+	if !savePreset(c, p, settings, details) {
+		return
+	}
+	c.JSON(200, gin.H{"preset": p})
+}
+
+// DeleteRoomPreset deletes a room preset of the current account. Its code stops working.
+func DeleteRoomPreset(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	p := ownPreset(c, a)
+	if p == nil {
+		return
+	}
+
+	if err := storage.Default.DeleteRoomPreset(p.Id); err != nil && !errors.Is(err, errs.ErrNotFound) {
+		abortWithError(c, err, "failed to delete room preset")
+		return
+	}
+	c.JSON(200, gin.H{})
+}
+
+// GetSharedPreset responds with the preset shared by a code.
+func GetSharedPreset(c *gin.Context) {
+	p := sharedPreset(c, c.Param("code"))
+	if p == nil {
+		return
+	}
+	c.JSON(200, gin.H{"preset": p})
+}
+
+// CreateRoomFromPreset creates a room configured by the preset shared by a code.
+// Presets for private rooms need a password, sent like when creating a room.
+func CreateRoomFromPreset(c *gin.Context) {
+	p := sharedPreset(c, c.Param("code"))
+	if p == nil {
+		return
+	}
+	settings, err := presetSettings(p)
+	if err != nil {
+		abortWithError(c, err, "failed to load room preset")
+		return
+	}
+
+	r, err := game.HubMain.NewRoomWithSettings(settings, c.Request.Header.Get("X-Password"))
+	if err != nil {
+		abortWithError(c, err, "failed to create room")
+		return
+	}
+	respondWithRoom(c, r)
+}
+
+// savePreset applies the set fields of a request to a preset and saves it.
+// If they aren't valid or it can't be saved, the request is aborted and false is returned.
+func savePreset(c *gin.Context, p *storage.RoomPreset, settings game.RoomSettings, details presetDetails) bool {
+	if details.Name != nil {
+		name, err := game.CleanName(*details.Name)
+		if err != nil {
+			abortWithError(c, err, "")
+			return false
+		}
+		p.Name = name
+	}
+	if len(details.Settings) > 0 {
+		if err := json.Unmarshal(details.Settings, &settings); err != nil {
+			abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+			return false
+		}
+	}
+	if err := settings.Validate(); err != nil {
+		abortWithError(c, err, "")
+		return false
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		abortWithError(c, err, "failed to save room preset")
+		return false
+	}
+	p.Settings = data
+	p.Updated = time.Now().UnixMilli()
+	if err := storage.Default.SaveRoomPreset(p); err != nil {
+		abortWithError(c, err, "failed to save room preset")
+		return false
+	}
+	return true
+}
+
+// ownPreset loads a room preset of the current account.
+// If it can't be loaded, the request is aborted and nil is returned.
+func ownPreset(c *gin.Context, a *storage.Account) *storage.RoomPreset {
+	p, err := storage.Default.RoomPreset(c.Param("id"))
+	if err == nil && p.AccountId != a.Id {
+		err = errs.ErrNotFound
+	}
+	if err != nil {
+		abortWithError(c, notFound(err, "room preset not found"), "failed to load room preset")
+		return nil
+	}
+	return p
+}
+
+// sharedPreset loads the room preset shared by a code.
+// If it can't be loaded, the request is aborted and nil is returned.
+func sharedPreset(c *gin.Context, code string) *storage.RoomPreset {
+	p, err := storage.Default.RoomPresetByCode(code)
+	if err != nil {
+		abortWithError(c, notFound(err, "room preset not found"), "failed to load room preset")
+		return nil
+	}
+	return p
+}
+
+// ownsRoom reports whether one of an account's players owns a room.
+func ownsRoom(a *storage.Account, r *game.Room) bool {
+	for _, p := range r.Players {
+		if p.Id == r.OwnerId && p.AccountId == a.Id {
+			return true
+		}
+	}
+	return false
+}
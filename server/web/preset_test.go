@@ -0,0 +1,98 @@
+package web
+
+import (
+	"cardgame/game"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoomPresets(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+
+	request := func(method, path, token, body string, headers ...string) (int, []byte) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Add(headers[i], headers[i+1])
+		}
+		api.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+	type presetResponse struct {
+		Preset storage.RoomPreset `json:"preset"`
+	}
+	type roomResponse struct {
+		Room struct {
+			Id string `json:"id"`
+		} `json:"room"`
+	}
+
+	code, _ := request("POST", "/api/me/presets", alice.Token, `{"settings":{"maxPlayers":2}}`)
+	assert.Equal(t, 400, code, "presets should need a name")
+	code, _ = request("POST", "/api/me/presets", alice.Token, `{"name":"duel","settings":{"maxPlayers":99}}`)
+	assert.Equal(t, 400, code)
+
+	code, body := request("POST", "/api/me/presets", alice.Token, `{"name":"duel","settings":{"maxPlayers":2,"turnTimeout":5,"private":true}}`)
+	assert.Equal(t, 200, code)
+	var created presetResponse
+	assert.NoError(t, json.Unmarshal(body, &created))
+	assert.NotEmpty(t, created.Preset.Code)
+
+	code, body = request("PUT", "/api/me/presets/"+created.Preset.Id, alice.Token, `{"settings":{"afkTurns":1}}`)
+	assert.Equal(t, 200, code)
+	var updated presetResponse
+	assert.NoError(t, json.Unmarshal(body, &updated))
+	var settings game.RoomSettings
+	assert.NoError(t, json.Unmarshal(updated.Preset.Settings, &settings))
+	assert.Equal(t, 2, settings.MaxPlayers, "settings left out should be kept")
+	assert.Equal(t, 1, settings.AfkTurns)
+	code, _ = request("PUT", "/api/me/presets/"+created.Preset.Id, bob.Token, `{"name":"mine"}`)
+	assert.Equal(t, 404, code, "only the owner should be able to change a preset")
+
+	code, _ = request("POST", "/api/room/preset/"+created.Preset.Code, "", "")
+	assert.Equal(t, 400, code, "private presets should need a password")
+	code, body = request("POST", "/api/room/preset/"+created.Preset.Code, "", "", "X-Password", "secret")
+	assert.Equal(t, 200, code)
+	var room roomResponse
+	assert.NoError(t, json.Unmarshal(body, &room))
+	r, ok := game.HubMain.Room(room.Room.Id)
+	if assert.True(t, ok) {
+		assert.True(t, r.IsPrivate())
+		assert.Equal(t, 2, r.MaxPlayers)
+		assert.Equal(t, 5, r.TurnTimeout)
+		game.HubMain.RemoveRoom(r.Id)
+	}
+
+	code, body = request("POST", "/api/me/presets", bob.Token, `{"code":"`+created.Preset.Code+`"}`)
+	assert.Equal(t, 200, code)
+	var copied presetResponse
+	assert.NoError(t, json.Unmarshal(body, &copied))
+	assert.Equal(t, "duel", copied.Preset.Name)
+	assert.NotEqual(t, created.Preset.Code, copied.Preset.Code)
+
+	code, body = request("GET", "/api/me/presets", bob.Token, "")
+	assert.Equal(t, 200, code)
+	var list struct {
+		Presets []*storage.RoomPreset `json:"presets"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list.Presets, 1)
+
+	code, _ = request("DELETE", "/api/me/presets/"+created.Preset.Id, alice.Token, "")
+	assert.Equal(t, 200, code)
+	code, _ = request("GET", "/api/preset/"+created.Preset.Code, "", "")
+	assert.Equal(t, 404, code, "codes of deleted presets should stop working")
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/progression"
+	"cardgame/storage"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// level describes how far an account has progressed.
+type level struct {
+	XP          int `json:"xp"`
+	Level       int `json:"level"`
+	LevelXP     int `json:"levelXp"`     // experience the level was reached at
+	NextLevelXP int `json:"nextLevelXp"` // experience needed for the next level
+}
+
+// questStatus is an active quest with the current account's progress towards it.
+type questStatus struct {
+	progression.ActiveQuest
+	Progress  int   `json:"progress"`
+	Completed int64 `json:"completed"` // unix ms, 0 until completed
+}
+
+// loadLevel loads the level of an account. If it can't be loaded, the request is aborted
+// and false is returned.
+func loadLevel(c *gin.Context, accountId string) (level, bool) {
+	xp, err := storage.Default.Experience(accountId)
+	if errors.Is(err, errs.ErrNotFound) {
+		xp = &storage.Experience{AccountId: accountId}
+	} else if err != nil {
+		abortWithError(c, err, "failed to load experience")
+		return level{}, false
+	}
+
+	l := progression.Level(xp.XP)
+	return level{
+		XP:          xp.XP,
+		Level:       l,
+		LevelXP:     progression.LevelXP(l),
+		NextLevelXP: progression.LevelXP(l + 1),
+	}, true
+}
+
+// GetUserLevel responds with an account's experience and level.
+func GetUserLevel(c *gin.Context) {
+	l, ok := loadLevel(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(200, l)
+}
+
+// GetQuests responds with the current account's level and the quests active right now,
+// with its progress towards each of them.
+func GetQuests(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	l, ok := loadLevel(c, a.Id)
+	if !ok {
+		return
+	}
+
+	progress := map[string]map[string]*storage.QuestProgress{} // period -> quest id -> progress
+	quests := []questStatus{}
+	for _, q := range progression.Active(time.Now()) {
+		if _, ok := progress[q.Key]; !ok {
+			saved, err := storage.Default.Quests(a.Id, q.Key)
+			if err != nil {
+				abortWithError(c, err, "failed to load quests")
+				return
+			}
+			progress[q.Key] = map[string]*storage.QuestProgress{}
+			for _, p := range saved {
+				progress[q.Key][p.QuestId] = p
+			}
+		}
+
+		status := questStatus{ActiveQuest: q}
+		if p, ok := progress[q.Key][q.Id]; ok {
+			status.Progress, status.Completed = p.Progress, p.Completed
+		}
+		quests = append(quests, status)
+	}
+	c.JSON(200, gin.H{"level": l, "quests": quests})
+}
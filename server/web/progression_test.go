@@ -0,0 +1,50 @@
+package web
+
+import (
+	"cardgame/progression"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuests(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	assert.NoError(t, progression.SetQuests(progression.QuestFile{Quests: []progression.Quest{
+		{Id: "play_2", Name: "Warming up", Period: progression.PeriodDaily, Counts: progression.CountGames, Goal: 2},
+	}}))
+	t.Cleanup(func() { progression.SetQuests(progression.QuestFile{}) })
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, err := progression.Record(storage.Default, progression.Game{AccountId: alice.User.Id, Won: true, Ended: time.Now()})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/me/quests", nil)
+	req.Header.Add("Authorization", "Bearer "+alice.Token)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var body struct {
+		Level  level         `json:"level"`
+		Quests []questStatus `json:"quests"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, progression.GameXP+progression.WinXP, body.Level.XP)
+	assert.Equal(t, 100, body.Level.NextLevelXP)
+	if assert.Len(t, body.Quests, 1) {
+		assert.Equal(t, "Warming up", body.Quests[0].Name)
+		assert.Equal(t, 1, body.Quests[0].Progress)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/user/u_missing/level", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var l level
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &l))
+	assert.Equal(t, 1, l.Level, "accounts should start at level 1")
+}
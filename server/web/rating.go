@@ -0,0 +1,55 @@
+package web
+
+import (
+	"cardgame/rating"
+	"cardgame/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userRating is a rating, and whether it's still provisional because its placement games aren't over.
+type userRating struct {
+	*storage.PlayerRating
+	Provisional bool `json:"provisional"`
+}
+
+// GetUserRatings responds with an account's ratings for every game type it has played ranked.
+func GetUserRatings(c *gin.Context) {
+	stored, err := storage.Default.Ratings(c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, "failed to load ratings")
+		return
+	}
+
+	ratings := make([]userRating, len(stored))
+	for i, r := range stored {
+		ratings[i] = userRating{r, rating.Provisional(r.Games)}
+	}
+	c.JSON(200, gin.H{"ratings": ratings})
+}
+
+// GetUserRatingHistory responds with a page of an account's rating changes for a game type, newest first.
+// Cursors are the time and match id of the last change of a page, as "created_id".
+func GetUserRatingHistory(c *gin.Context) {
+	q := storage.RatingQuery{AccountId: c.Param("id"), GameType: c.Param("gameType")}
+	var ok bool
+	if q.Limit, ok = pageLimit(c); !ok {
+		return
+	}
+	if q.Before, q.BeforeId, ok = pageCursor(c); !ok {
+		return
+	}
+
+	changes, err := storage.Default.RatingHistory(q)
+	if err != nil {
+		abortWithError(c, err, "failed to load rating history")
+		return
+	}
+
+	var next *string
+	if len(changes) == q.Limit {
+		last := changes[len(changes)-1]
+		next = nextCursor(last.Created, last.MatchId)
+	}
+	c.JSON(200, gin.H{"changes": changes, "next": next})
+}
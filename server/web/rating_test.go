@@ -0,0 +1,66 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRatings(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	storage.Default.SaveRatings(
+		[]*storage.PlayerRating{{AccountId: "u_1", GameType: "classic", Rating: 1550, Deviation: 300, Volatility: 0.06, Games: 2}},
+		[]*storage.RatingChange{
+			{AccountId: "u_1", GameType: "classic", MatchId: "m_1", Before: 1500, After: 1520, Created: 1},
+			{AccountId: "u_1", GameType: "classic", MatchId: "m_2", Before: 1520, After: 1540, Created: 2},
+			{AccountId: "u_1", GameType: "classic", MatchId: "m_3", Before: 1540, After: 1550, Created: 2},
+		},
+	)
+	api := initTestApi(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/u_1/ratings", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var ratings struct {
+		Ratings []*storage.PlayerRating `json:"ratings"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ratings))
+	if assert.Len(t, ratings.Ratings, 1) {
+		assert.Equal(t, 1550.0, ratings.Ratings[0].Rating)
+	}
+
+	type response struct {
+		Changes []*storage.RatingChange `json:"changes"`
+		Next    *string                 `json:"next"`
+	}
+	history := func(url string) response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		api.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		var r response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return r
+	}
+	ids := func(changes []*storage.RatingChange) []string {
+		ids := []string{}
+		for _, c := range changes {
+			ids = append(ids, c.MatchId)
+		}
+		return ids
+	}
+
+	first := history("/api/user/u_1/ratings/classic/history?limit=1")
+	assert.Equal(t, []string{"m_2"}, ids(first.Changes))
+	if assert.NotNil(t, first.Next) {
+		second := history("/api/user/u_1/ratings/classic/history?limit=2&before=" + *first.Next)
+		assert.Equal(t, []string{"m_3", "m_1"}, ids(second.Changes), "changes made with the last of a page should be on the next one")
+		assert.NotNil(t, second.Next)
+	}
+}
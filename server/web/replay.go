@@ -0,0 +1,104 @@
+package web
+
+import (
+	"bytes"
+	"cardgame/game"
+	"cardgame/locale"
+	"cardgame/storage"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReplays responds with a page of replays without their events, newest first. Cursors are
+// the creation time and match id of the last replay of a page, as "created_id".
+func GetReplays(c *gin.Context) {
+	var q storage.ReplayQuery
+	var ok bool
+	if q.Limit, ok = pageLimit(c); !ok {
+		return
+	}
+	if q.Before, q.BeforeId, ok = pageCursor(c); !ok {
+		return
+	}
+
+	replays, err := storage.Default.Replays(q)
+	if err != nil {
+		abortWithError(c, err, "failed to load replays")
+		return
+	}
+
+	var next *string
+	if len(replays) == q.Limit {
+		last := replays[len(replays)-1]
+		next = nextCursor(last.Created, last.MatchId)
+	}
+	c.JSON(200, gin.H{"replays": replays, "next": next})
+}
+
+// GetReplay downloads the replay of a match.
+func GetReplay(c *gin.Context) {
+	r, err := storage.Default.Replay(c.Param("id"))
+	if err != nil {
+		abortWithError(c, notFound(err, "replay not found"), "failed to load replay")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.replay.json"`, r.MatchId))
+	c.JSON(200, gin.H{"replay": r})
+}
+
+//go:embed replay.html
+var replayPage string
+
+// replayTemplate is a page playing back an exported replay on its own, with the replay inlined
+// so the file can be shared and opened without the server.
+var replayTemplate = template.Must(template.New("replay").Parse(replayPage))
+
+// ExportReplay downloads the replay of a match as a page animating it, in the language of the
+// request.
+func ExportReplay(c *gin.Context) {
+	r, err := storage.Default.Replay(c.Param("id"))
+	if err != nil {
+		abortWithError(c, notFound(err, "replay not found"), "failed to load replay")
+		return
+	}
+
+	lang := locale.Match(c.GetHeader("Accept-Language"))
+	export, err := game.ExportReplay(r, lang)
+	if err != nil {
+		abortWithError(c, err, "failed to export replay")
+		return
+	}
+
+	names := []string{}
+	for _, p := range export.Results {
+		names = append(names, p.Name)
+	}
+	t := func(id string) string { return locale.Translate(lang, id, nil) }
+	page := bytes.Buffer{}
+	err = replayTemplate.Execute(&page, gin.H{
+		"Lang":   lang,
+		"Title":  locale.Translate(lang, "replay_export_title", locale.Params{"players": strings.Join(names, ", ")}),
+		"Replay": r,
+		"Export": export,
+		"Labels": gin.H{
+			"Previous":   t("replay_previous"),
+			"Play":       t("replay_play"),
+			"Pause":      t("replay_pause"),
+			"Next":       t("replay_next"),
+			"Highlights": t("replay_highlights"),
+			"Score":      t("replay_score"),
+		},
+	})
+	if err != nil {
+		abortWithError(c, err, "failed to export replay")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.replay.html"`, r.MatchId))
+	c.Data(200, "text/html; charset=utf-8", page.Bytes())
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayDownload(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	storage.Default.SaveReplay(&storage.Replay{MatchId: "m_1", Seed: 42, Events: json.RawMessage(`[]`), Created: 1})
+	api := initTestApi(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/replays", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"matchId":"m_1"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/replay/m_1", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "m_1.replay.json")
+	assert.Contains(t, w.Body.String(), `"seed":42`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/replay/m_missing", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestReplayPages(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	for i := 1; i <= 5; i++ {
+		storage.Default.SaveReplay(&storage.Replay{
+			MatchId: fmt.Sprintf("m_%d", i),
+			Events:  json.RawMessage(`[]`),
+			Created: int64((i + 1) / 2), // replays are created two at a time
+		})
+	}
+	api := initTestApi(t)
+
+	type response struct {
+		Replays []*storage.Replay `json:"replays"`
+		Next    *string           `json:"next"`
+	}
+	get := func(url string) response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		api.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		var r response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return r
+	}
+	ids := func(replays []*storage.Replay) []string {
+		ids := []string{}
+		for _, r := range replays {
+			ids = append(ids, r.MatchId)
+		}
+		return ids
+	}
+
+	first := get("/api/replays?limit=3")
+	assert.Equal(t, []string{"m_5", "m_3", "m_4"}, ids(first.Replays))
+	if assert.NotNil(t, first.Next) {
+		second := get("/api/replays?limit=3&before=" + *first.Next)
+		assert.Equal(t, []string{"m_1", "m_2"}, ids(second.Replays), "replays created with the last of a page should be on the next one")
+		assert.Nil(t, second.Next, "last page should have no cursor")
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/replays?before=soon", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestReplayExport(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	storage.Default.SaveReplay(&storage.Replay{MatchId: "m_1", Seed: 42, Events: json.RawMessage(`[
+		{"time":0,"message":{"type":"start","currentTurn":0}},
+		{"time":500,"message":{"type":"draw","playerId":"p_1","card":{"id":"c_1","type":7,"category":"River"}}},
+		{"time":900,"message":{"type":"end","matchId":"m_1","results":[{"id":"p_1","name":"Ann </script>","score":0,"rank":1}]}}
+	]`), Created: 1})
+	api := initTestApi(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/replay/m_1/export", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "m_1.replay.html")
+	assert.Contains(t, w.Body.String(), `<title>Replay of Ann &lt;/script&gt;</title>`)
+	assert.Contains(t, w.Body.String(), `River (star)`)
+	assert.NotContains(t, w.Body.String(), `Ann </script>`, "names should not end the inlined replay")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/replay/m_missing/export", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
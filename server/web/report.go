@@ -0,0 +1,124 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReports responds with a page of the review queue, newest first. It can be narrowed down
+// with the "status", "reporter" and "target" query parameters. Cursors are the time and id of
+// the last report of a page, as "created_id".
+func GetReports(c *gin.Context) {
+	if currentAdmin(c) == nil {
+		return
+	}
+
+	q := storage.ReportQuery{
+		Status:     storage.ReportStatus(c.Query("status")),
+		ReporterId: c.Query("reporter"),
+		TargetId:   c.Query("target"),
+	}
+	var ok bool
+	if q.Limit, ok = pageLimit(c); !ok {
+		return
+	}
+	if q.Before, q.BeforeId, ok = pageCursor(c); !ok {
+		return
+	}
+
+	reports, err := storage.Default.Reports(q)
+	if err != nil {
+		abortWithError(c, err, "failed to load reports")
+		return
+	}
+
+	var next *string
+	if len(reports) == q.Limit {
+		last := reports[len(reports)-1]
+		next = nextCursor(last.Created, last.Id)
+	}
+	c.JSON(200, gin.H{"reports": reports, "next": next})
+}
+
+// report loads the report of the request.
+// If it can't be loaded, the request is aborted and nil is returned.
+func report(c *gin.Context) *storage.Report {
+	r, err := storage.Default.Report(c.Param("id"))
+	if err != nil {
+		abortWithError(c, notFound(err, "report not found"), "failed to load report")
+		return nil
+	}
+	return r
+}
+
+// GetReport responds with a report, with the chat and game events attached to it.
+func GetReport(c *gin.Context) {
+	if currentAdmin(c) == nil {
+		return
+	}
+	r := report(c)
+	if r == nil {
+		return
+	}
+	c.JSON(200, gin.H{"report": r})
+}
+
+// ResolveReport closes a report as actioned or dismissed, with a note, or reopens it.
+func ResolveReport(c *gin.Context) {
+	a := currentAdmin(c)
+	if a == nil {
+		return
+	}
+
+	var body struct {
+		Status storage.ReportStatus `json:"status"`
+		Note   string               `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	switch body.Status {
+	case storage.ReportOpen, storage.ReportActioned, storage.ReportDismissed:
+	default:
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "unknown report status"), "")
+		return
+	}
+	note, err := game.CleanReason(body.Note)
+	if err != nil {
+		abortWithError(c, err, "")
+		return
+	}
+
+	r := report(c)
+	if r == nil {
+		return
+	}
+	r.Status = body.Status
+	r.Resolution = note
+	r.ResolverId = a.Id
+	r.Resolved = time.Now().UnixMilli()
+	if r.Status == storage.ReportOpen {
+		r.ResolverId = ""
+		r.Resolved = 0
+	}
+	if err := storage.Default.SaveReport(r); err != nil {
+		abortWithError(c, err, "failed to save report")
+		return
+	}
+
+	game.Audit(&storage.AuditEntry{
+		Action:     storage.AuditReport,
+		ActorId:    a.Id,
+		ActorName:  a.Name,
+		TargetId:   r.Id,
+		TargetName: r.TargetName,
+		RoomId:     r.RoomId,
+		Reason:     string(r.Status) + ": " + note,
+	})
+	c.JSON(200, gin.H{"report": r})
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportQueue(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, admin := userRequest(t, api, "POST", "", `{"name":"admin"}`)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	Admins = map[string]bool{admin.User.Id: true}
+	t.Cleanup(func() { Admins = map[string]bool{} })
+	storage.Default.SaveReport(&storage.Report{
+		Id:         "rep_1",
+		Reason:     storage.ReportAfk,
+		ReporterId: alice.User.Id,
+		TargetId:   "p_guest",
+		Chat:       []storage.ReportChat{{PlayerId: "p_guest", Message: "brb"}},
+		Status:     storage.ReportOpen,
+		Created:    1,
+	})
+
+	w := adminRequest(t, api, "GET", "/api/admin/reports", alice.Token, "")
+	assert.Equal(t, 403, w.Code, "only admins should see the review queue")
+
+	w = adminRequest(t, api, "GET", "/api/admin/reports?status=open", admin.Token, "")
+	assert.Equal(t, 200, w.Code)
+	var list struct {
+		Reports []*storage.Report `json:"reports"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Reports, 1)
+
+	w = adminRequest(t, api, "POST", "/api/admin/reports/rep_1/resolve", admin.Token, `{"status":"closed"}`)
+	assert.Equal(t, 400, w.Code)
+	w = adminRequest(t, api, "POST", "/api/admin/reports/rep_missing/resolve", admin.Token, `{"status":"dismissed"}`)
+	assert.Equal(t, 404, w.Code)
+	w = adminRequest(t, api, "POST", "/api/admin/reports/rep_1/resolve", admin.Token, `{"status":"dismissed","note":"came back in time"}`)
+	assert.Equal(t, 200, w.Code)
+
+	w = adminRequest(t, api, "GET", "/api/admin/reports/rep_1", admin.Token, "")
+	assert.Equal(t, 200, w.Code)
+	var got struct {
+		Report storage.Report `json:"report"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, storage.ReportDismissed, got.Report.Status)
+	assert.Equal(t, admin.User.Id, got.Report.ResolverId)
+	assert.Equal(t, "brb", got.Report.Chat[0].Message)
+
+	entries, err := storage.Default.AuditLog(storage.AuditQuery{Action: storage.AuditReport})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "rep_1", entries[0].TargetId)
+	}
+}
+
+func TestReportQueuePages(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, admin := userRequest(t, api, "POST", "", `{"name":"admin"}`)
+	Admins = map[string]bool{admin.User.Id: true}
+	t.Cleanup(func() { Admins = map[string]bool{} })
+	for i := 1; i <= 5; i++ {
+		storage.Default.SaveReport(&storage.Report{
+			Id:      fmt.Sprintf("rep_%d", i),
+			Reason:  storage.ReportAfk,
+			Status:  storage.ReportOpen,
+			Created: int64((i + 1) / 2), // reports are filed two at a time
+		})
+	}
+
+	type response struct {
+		Reports []*storage.Report `json:"reports"`
+		Next    *string           `json:"next"`
+	}
+	get := func(path string) response {
+		t.Helper()
+		w := adminRequest(t, api, "GET", path, admin.Token, "")
+		assert.Equal(t, 200, w.Code)
+		var r response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return r
+	}
+	ids := func(reports []*storage.Report) []string {
+		ids := []string{}
+		for _, r := range reports {
+			ids = append(ids, r.Id)
+		}
+		return ids
+	}
+
+	first := get("/api/admin/reports?limit=3")
+	assert.Equal(t, []string{"rep_5", "rep_3", "rep_4"}, ids(first.Reports))
+	if assert.NotNil(t, first.Next) {
+		second := get("/api/admin/reports?limit=3&before=" + *first.Next)
+		assert.Equal(t, []string{"rep_1", "rep_2"}, ids(second.Reports), "reports filed with the last of a page should be on the next one")
+		assert.Nil(t, second.Next, "last page should have no cursor")
+	}
+}
@@ -1,26 +1,54 @@
 package web
 
 import (
+	"cardgame/errs"
 	"cardgame/game"
+	"encoding/json"
 
 	"github.com/gin-gonic/gin"
 )
 
+// roomState encodes a room on its goroutine, where its state can be read safely.
+func roomState(r *game.Room) (json.RawMessage, error) {
+	var state json.RawMessage
+	var err error
+	if inspectErr := r.Inspect(func() { state, err = json.Marshal(r) }); inspectErr != nil {
+		return nil, inspectErr
+	}
+	return state, err
+}
+
+// respondWithRoom answers a request with the state of a room.
+func respondWithRoom(c *gin.Context, r *game.Room) {
+	state, err := roomState(r)
+	if err != nil {
+		abortWithError(c, err, "failed to encode room")
+		return
+	}
+	c.JSON(200, gin.H{"room": state})
+}
+
 func GetRooms(c *gin.Context) {
-	rooms := []*game.Room{}
-	for _, r := range game.HubMain.Rooms {
+	all := game.HubMain.AllRooms()
+	rooms := []json.RawMessage{}
+	for _, r := range all {
 		if r.IsPrivate() {
 			continue
 		}
-		rooms = append(rooms, r)
+		state, err := roomState(r)
+		if err != nil {
+			// closed in the meantime
+			continue
+		}
+		rooms = append(rooms, state)
 	}
 
 	c.JSON(200, gin.H{
 		"rooms": rooms,
 		"count": gin.H{
 			"public":  len(rooms),
-			"private": len(game.HubMain.Rooms) - len(rooms),
-			"total":   len(game.HubMain.Rooms),
+			"private": len(all) - len(rooms),
+			"total":   len(all),
 		},
 	})
 }
@@ -28,22 +56,22 @@ func GetRooms(c *gin.Context) {
 func GetRoom(c *gin.Context) {
 	id := c.Param("room")
 	password := c.Request.Header.Get("X-Password")
-	r, ok := game.HubMain.Rooms[id]
+	r, ok := game.HubMain.Room(id)
 	if !ok || (r.IsPrivate() && password == "") {
-		c.AbortWithStatusJSON(404, gin.H{"error": "room not found"})
+		abortWithError(c, game.ErrRoomNotFound, "")
 		return
 	}
 	if r.IsPrivate() && !r.CheckPassword(password) {
-		c.AbortWithStatusJSON(403, gin.H{"error": "invalid password"})
+		abortWithError(c, errs.New(errs.ErrPermission, "invalid password"), "")
 		return
 	}
 
-	c.JSON(200, gin.H{"room": r})
+	respondWithRoom(c, r)
 }
 
 func CreateRoom(c *gin.Context) {
 	password := c.Request.Header.Get("X-Password")
 	r := game.HubMain.NewRoom(password)
 
-	c.JSON(200, gin.H{"room": r})
+	respondWithRoom(c, r)
 }
@@ -52,7 +52,8 @@ func TestPublicRoom(t *testing.T) {
 	api := initTestApi(t)
 	rm := makePublicRoom(t, api)
 
-	assert.Contains(t, game.HubMain.Rooms, rm.Id, "should contain public room")
+	_, ok := game.HubMain.Room(rm.Id)
+	assert.True(t, ok, "should contain public room")
 
 	type response struct {
 		Room  *game.Room `json:"room"`
@@ -66,7 +67,7 @@ func TestPublicRoom(t *testing.T) {
 	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
 	assert.Equal(t, rm.Id, r.Room.Id, "should be able to get correct room")
 
-	delete(game.HubMain.Rooms, rm.Id)
+	game.HubMain.RemoveRoom(rm.Id)
 }
 
 func TestRoomList(t *testing.T) {
@@ -86,8 +87,8 @@ func TestRoomList(t *testing.T) {
 	assert.Contains(t, r.Rooms, pubRoom, "should contain public room")
 	assert.NotContains(t, r.Rooms, privRoom, "should not contain private room")
 
-	delete(game.HubMain.Rooms, pubRoom.Id)
-	delete(game.HubMain.Rooms, privRoom.Id)
+	game.HubMain.RemoveRoom(pubRoom.Id)
+	game.HubMain.RemoveRoom(privRoom.Id)
 }
 
 func TestPrivateRoom(t *testing.T) {
@@ -95,7 +96,8 @@ func TestPrivateRoom(t *testing.T) {
 	password := "correct horse battery staple"
 	rm := makePrivateRoom(t, api, password)
 
-	assert.Contains(t, game.HubMain.Rooms, rm.Id, "should contain private room")
+	_, ok := game.HubMain.Room(rm.Id)
+	assert.True(t, ok, "should contain private room")
 
 	type response struct {
 		Error string     `json:"error"`
@@ -123,5 +125,5 @@ func TestPrivateRoom(t *testing.T) {
 	assert.Equal(t, 200, w.Code, "should be able to get private room with correct password")
 	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
 
-	delete(game.HubMain.Rooms, rm.Id)
+	game.HubMain.RemoveRoom(rm.Id)
 }
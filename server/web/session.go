@@ -0,0 +1,96 @@
+package web
+
+import (
+	"cardgame/game"
+	"cardgame/storage"
+	"cardgame/util"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionKey is the key of the id of the session a request was made with in the gin context.
+const sessionKey = "session"
+
+// newSession signs an account in on a new session, made from the device of the request, and
+// returns its token. If it can't be saved, the request is aborted.
+func newSession(c *gin.Context, accountId string) (string, bool) {
+	token := util.Token()
+	s := &storage.Session{
+		Id:        util.IdFrom("s", util.Token()),
+		AccountId: accountId,
+		TokenHash: storage.HashToken(token),
+		UserAgent: c.Request.UserAgent(),
+		Created:   time.Now().UnixMilli(),
+	}
+	if err := storage.Default.SaveSession(s); err != nil {
+		abortWithError(c, err, "failed to create session")
+		return "", false
+	}
+	return token, true
+}
+
+// GetSessions responds with the sessions of the current account, the connections signed in
+// with each, and which one the request was made with.
+func GetSessions(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	sessions, err := game.HubMain.Sessions(a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to load sessions")
+		return
+	}
+	c.JSON(200, gin.H{"sessions": sessions, "current": c.GetString(sessionKey)})
+}
+
+// CreateSession signs the current account in on another device. The token in the response is
+// only ever sent once.
+func CreateSession(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	token, ok := newSession(c, a.Id)
+	if !ok {
+		return
+	}
+	c.JSON(200, gin.H{"token": token})
+}
+
+// EndSession revokes a session of the current account, and disconnects the connections signed
+// in with it. Its token stops working right away; the account's other sessions keep working.
+func EndSession(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if err := storage.Default.DeleteSession(a.Id, c.Param("id")); err != nil {
+		abortWithError(c, notFound(err, "session not found"), "failed to end session")
+		return
+	}
+	game.HubMain.EndSession(a.Id, c.Param("id"))
+	c.JSON(200, gin.H{})
+}
+
+// RevokeSessions revokes every session of the current account and disconnects every connection
+// signed in to it. The old tokens stop working right away, on every device. The request is
+// signed in again on a new session, whose token is only ever sent once, in the response.
+func RevokeSessions(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if err := storage.Default.DeleteSessions(a.Id); err != nil {
+		abortWithError(c, err, "failed to revoke sessions")
+		return
+	}
+	game.HubMain.EndSessions(a.Id)
+
+	token, ok := newSession(c, a.Id)
+	if !ok {
+		return
+	}
+	c.JSON(200, gin.H{"token": token})
+}
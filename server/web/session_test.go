@@ -0,0 +1,89 @@
+package web
+
+import (
+	"cardgame/game"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+
+	request := func(method, path, token string) (int, []byte) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		api.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, body := request("GET", "/api/me/sessions", alice.Token)
+	assert.Equal(t, 200, code)
+	var listed struct {
+		Sessions []game.Session `json:"sessions"`
+		Current  string         `json:"current"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &listed))
+	assert.Len(t, listed.Sessions, 1)
+	first := listed.Current
+	assert.Equal(t, first, listed.Sessions[0].Id)
+
+	var created struct {
+		Token string `json:"token"`
+	}
+	code, body = request("POST", "/api/me/sessions", alice.Token)
+	assert.Equal(t, 200, code)
+	assert.NoError(t, json.Unmarshal(body, &created))
+	phone := created.Token
+	code, body = request("POST", "/api/me/sessions", alice.Token)
+	assert.Equal(t, 200, code)
+	assert.NoError(t, json.Unmarshal(body, &created))
+	tablet := created.Token
+
+	code, body = request("GET", "/api/me/sessions", phone)
+	assert.NoError(t, json.Unmarshal(body, &listed))
+	assert.Len(t, listed.Sessions, 3)
+	assert.NotEqual(t, first, listed.Current)
+
+	code, _ = request("DELETE", "/api/me/sessions/"+listed.Current, alice.Token)
+	assert.Equal(t, 200, code)
+	code, _ = userRequest(t, api, "GET", phone, "")
+	assert.Equal(t, 401, code, "the revoked session's token should stop working")
+	code, _ = userRequest(t, api, "GET", alice.Token, "")
+	assert.Equal(t, 200, code, "the other sessions should keep working")
+	code, _ = userRequest(t, api, "GET", tablet, "")
+	assert.Equal(t, 200, code, "the other sessions should keep working")
+
+	code, _ = request("DELETE", "/api/me/sessions/"+listed.Current, alice.Token)
+	assert.Equal(t, 404, code)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+	code, _ = request("DELETE", "/api/me/sessions/"+first, bob.Token)
+	assert.Equal(t, 404, code, "only the account's own sessions can be revoked")
+
+	code, body = request("DELETE", "/api/me/sessions", alice.Token)
+	assert.Equal(t, 200, code)
+	var revoked struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &revoked))
+	assert.NotEmpty(t, revoked.Token)
+
+	code, _ = userRequest(t, api, "GET", alice.Token, "")
+	assert.Equal(t, 401, code, "the old tokens should stop working")
+	code, _ = userRequest(t, api, "GET", tablet, "")
+	assert.Equal(t, 401, code, "the old tokens should stop working")
+	code, got := userRequest(t, api, "GET", revoked.Token, "")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, alice.User.Id, got.User.Id)
+	code, body = request("GET", "/api/me/sessions", revoked.Token)
+	assert.NoError(t, json.Unmarshal(body, &listed))
+	assert.Len(t, listed.Sessions, 1)
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/stats"
+	"cardgame/storage"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// favoriteOpponents is the default number of opponents listed with an account's stats.
+const favoriteOpponents = 5
+
+// playerStats is an account's stats for a game type, with the averages worked out.
+type playerStats struct {
+	*storage.PlayerStats
+	WinRate      float64 `json:"winRate"`
+	AverageScore float64 `json:"averageScore"`
+}
+
+// GetUserStats responds with an account's stats for every game type it has played,
+// the overall stats first, and the opponents it has played the most games with.
+func GetUserStats(c *gin.Context) {
+	limit := favoriteOpponents
+	if l := c.Query("opponents"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxPageSize {
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "invalid opponents"), "")
+			return
+		}
+		limit = n
+	}
+
+	all, err := storage.Default.Stats(c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, "failed to load stats")
+		return
+	}
+	opponents, err := storage.Default.Opponents(c.Param("id"), limit)
+	if err != nil {
+		abortWithError(c, err, "failed to load opponents")
+		return
+	}
+
+	response := make([]playerStats, len(all))
+	for i, st := range all {
+		response[i] = playerStats{st, stats.WinRate(st), stats.AverageScore(st)}
+	}
+	c.JSON(200, gin.H{"stats": response, "opponents": opponents})
+}
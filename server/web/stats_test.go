@@ -0,0 +1,52 @@
+package web
+
+import (
+	"cardgame/stats"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserStats(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	for _, ended := range []int64{1, 2} {
+		assert.NoError(t, stats.Record(storage.Default, &storage.Match{
+			GameType: "classic",
+			Players: []storage.MatchPlayer{
+				{AccountId: "u_1", Name: "alice", Score: 4, Rank: 1},
+				{AccountId: "u_2", Name: "bob", Score: 1, Rank: 2},
+				{AccountId: "u_3", Name: "carol", Score: 0, Rank: 3},
+			},
+			Ended: ended,
+		}))
+	}
+	api := initTestApi(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/u_2/stats?opponents=1", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var body struct {
+		Stats     []playerStats            `json:"stats"`
+		Opponents []*storage.OpponentStats `json:"opponents"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	if assert.Len(t, body.Stats, 2) {
+		assert.Equal(t, stats.Overall, body.Stats[0].GameType)
+		assert.Equal(t, 2, body.Stats[0].Games)
+		assert.Equal(t, 0.0, body.Stats[0].WinRate)
+		assert.Equal(t, 1.0, body.Stats[0].AverageScore)
+	}
+	if assert.Len(t, body.Opponents, 1) {
+		assert.Equal(t, "alice", body.Opponents[0].Name)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/user/u_2/stats?opponents=none", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSuspendedGames responds with the unfinished games the current account can resume, newest first.
+func GetSuspendedGames(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	games, err := storage.Default.SuspendedGames(a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to load suspended games")
+		return
+	}
+	c.JSON(200, gin.H{"games": games})
+}
+
+// suspendedGame loads a suspended game the current account played in.
+// If it can't be loaded, the request is aborted and nil is returned.
+func suspendedGame(c *gin.Context, a *storage.Account) *storage.SuspendedGame {
+	g, err := storage.Default.SuspendedGame(c.Param("id"))
+	if err != nil {
+		abortWithError(c, notFound(err, "suspended game not found"), "failed to load suspended game")
+		return nil
+	}
+	for _, p := range g.Players {
+		if p.AccountId == a.Id {
+			return g
+		}
+	}
+	abortWithError(c, errs.New(errs.ErrNotFound, "suspended game not found"), "")
+	return nil
+}
+
+// ResumeSuspendedGame opens a room for a suspended game and invites its other players.
+// The game continues once all of them have joined.
+func ResumeSuspendedGame(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	g := suspendedGame(c, a)
+	if g == nil {
+		return
+	}
+
+	r, err := game.HubMain.ResumeGame(g)
+	if err != nil {
+		abortWithError(c, err, "failed to resume game")
+		return
+	}
+	for _, p := range g.Players {
+		if p.AccountId == a.Id {
+			continue
+		}
+		game.HubMain.Notify(p.AccountId, &game.ServerInvite{
+			RoomId:    r.Id,
+			RoomName:  r.Name,
+			AccountId: a.Id,
+			Name:      a.Name,
+		})
+	}
+
+	respondWithRoom(c, r)
+}
+
+// DeleteSuspendedGame abandons a suspended game, for all of its players.
+func DeleteSuspendedGame(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	g := suspendedGame(c, a)
+	if g == nil {
+		return
+	}
+
+	if err := storage.Default.DeleteSuspendedGame(g.Id); err != nil && !errors.Is(err, errs.ErrNotFound) {
+		abortWithError(c, err, "failed to delete suspended game")
+		return
+	}
+	c.JSON(200, gin.H{})
+}
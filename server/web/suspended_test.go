@@ -0,0 +1,72 @@
+package web
+
+import (
+	"cardgame/game"
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuspendedGames(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+	_, carol := userRequest(t, api, "POST", "", `{"name":"carol"}`)
+	storage.Default.SaveSuspendedGame(&storage.SuspendedGame{
+		Id:       "g_1",
+		RoomName: "long game",
+		GameType: "classic",
+		Players: []storage.MatchPlayer{
+			{Id: "p_1", AccountId: alice.User.Id, Name: "alice"},
+			{Id: "p_2", AccountId: bob.User.Id, Name: "bob"},
+		},
+		State:     json.RawMessage(`{"seats":[{"accountId":"` + alice.User.Id + `"},{"accountId":"` + bob.User.Id + `"}]}`),
+		Suspended: 1,
+	})
+
+	request := func(method, path, token string) (int, []byte) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		api.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, body := request("GET", "/api/me/suspended", bob.Token)
+	assert.Equal(t, 200, code)
+	var list struct {
+		Games []*storage.SuspendedGame `json:"games"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	if assert.Len(t, list.Games, 1) {
+		assert.Equal(t, "long game", list.Games[0].RoomName)
+	}
+
+	code, _ = request("POST", "/api/me/suspended/g_1/resume", carol.Token)
+	assert.Equal(t, 404, code, "only the game's players should be able to resume it")
+
+	code, body = request("POST", "/api/me/suspended/g_1/resume", alice.Token)
+	assert.Equal(t, 200, code)
+	var resumed struct {
+		Room struct {
+			Id string `json:"id"`
+		} `json:"room"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &resumed))
+	r, ok := game.HubMain.Room(resumed.Room.Id)
+	if assert.True(t, ok) {
+		assert.True(t, r.IsPrivate(), "resumed games should only be open to their players")
+		game.HubMain.RemoveRoom(r.Id)
+	}
+
+	code, _ = request("DELETE", "/api/me/suspended/g_1", bob.Token)
+	assert.Equal(t, 200, code)
+	code, _ = request("DELETE", "/api/me/suspended/g_1", bob.Token)
+	assert.Equal(t, 404, code)
+}
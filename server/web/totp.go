@@ -0,0 +1,210 @@
+package web
+
+import (
+	"cardgame/errs"
+	"cardgame/storage"
+	"cardgame/util"
+	"cardgame/util/ratelimit"
+	"cardgame/util/totp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TotpIssuer is the name authenticator apps list the codes of accounts under.
+var TotpIssuer = "Card Game"
+
+// recoveryCodeCount is how many recovery codes an account is given at a time.
+const recoveryCodeCount = 10
+
+var (
+	errCodeRequired = errs.New(errs.ErrUnauthorized, "two-factor code required")
+	errWrongCode    = errs.New(errs.ErrUnauthorized, "wrong two-factor code")
+	errCodeRate     = errs.New(errs.ErrRateLimited, "too many two-factor codes tried, try again later")
+	errTotpOn       = errs.New(errs.ErrInvalidAction, "two-factor authentication is already on")
+	errTotpOff      = errs.New(errs.ErrInvalidAction, "two-factor authentication is off")
+	errTotpPassword = errs.New(errs.ErrInvalidAction, "two-factor authentication needs a password")
+)
+
+// codeLimits limits the two-factor codes tried for each account, so the six digits can't be
+// guessed.
+var codeLimits = ratelimit.NewKeyed(10, 15*time.Minute)
+
+// checkCode reports whether code is the current code of the account's authenticator app, or
+// one of its recovery codes, which is used up. The account has to be saved afterwards, so the
+// code isn't accepted again. If it is neither, the request is aborted and false is returned.
+func checkCode(c *gin.Context, a *storage.Account, code string) bool {
+	if code == "" {
+		abortWithDetails(c, errCodeRequired, "", gin.H{"totp": true})
+		return false
+	}
+	if !codeLimits.Allow(a.Id) {
+		abortWithError(c, errCodeRate, "")
+		return false
+	}
+
+	if step, ok := totp.Check(a.TotpSecret, code, time.Now(), a.TotpStep); ok {
+		a.TotpStep = step
+		return true
+	}
+	hash := storage.HashToken(strings.ToLower(strings.TrimSpace(code)))
+	for i, h := range a.RecoveryCodes {
+		if h == hash {
+			a.RecoveryCodes = append(a.RecoveryCodes[:i:i], a.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	abortWithError(c, errWrongCode, "")
+	return false
+}
+
+// newRecoveryCodes replaces the recovery codes of an account, and returns the new ones. Only
+// their hashes are stored, so they can only be shown once.
+func newRecoveryCodes(a *storage.Account) []string {
+	codes := make([]string, recoveryCodeCount)
+	a.RecoveryCodes = make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = util.RecoveryCode()
+		a.RecoveryCodes[i] = storage.HashToken(codes[i])
+	}
+	return codes
+}
+
+// codeRequest is the body of requests which need a two-factor code, or a recovery code.
+type codeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// SetUpTotp starts turning on two-factor authentication for the current account, which needs
+// its password. The response has the secret to add to an authenticator app, and its
+// otpauth:// URI to show as a QR code. It is only turned on once ConfirmTotp gets a code of it.
+func SetUpTotp(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if a.TotpEnabled != 0 {
+		abortWithError(c, errTotpOn, "")
+		return
+	}
+	if a.PasswordHash == "" {
+		abortWithError(c, errTotpPassword, "")
+		return
+	}
+	if !checkPassword(c, a, req.Password, errWrongPassword) {
+		return
+	}
+
+	a.TotpSecret = totp.NewSecret()
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+
+	account := a.Name
+	if a.Email != "" {
+		account = a.Email
+	}
+	c.JSON(200, gin.H{"secret": a.TotpSecret, "uri": totp.URI(a.TotpSecret, TotpIssuer, account)})
+}
+
+// ConfirmTotp turns on two-factor authentication for the current account, with a code of the
+// secret from SetUpTotp, which shows the app was set up. The response has the recovery codes,
+// which are only ever sent once.
+func ConfirmTotp(c *gin.Context) {
+	var req codeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if a.TotpEnabled != 0 {
+		abortWithError(c, errTotpOn, "")
+		return
+	}
+	if a.TotpSecret == "" {
+		abortWithError(c, errs.New(errs.ErrInvalidAction, "two-factor authentication isn't being set up"), "")
+		return
+	}
+	if !checkCode(c, a, req.Code) {
+		return
+	}
+
+	codes := newRecoveryCodes(a)
+	a.TotpEnabled = time.Now().UnixMilli()
+	a.Updated = a.TotpEnabled
+	if !saveUser(c, a) {
+		return
+	}
+
+	c.JSON(200, gin.H{"recoveryCodes": codes})
+}
+
+// NewRecoveryCodes replaces the recovery codes of the current account, with a two-factor
+// code. The new codes are only ever sent once, in the response.
+func NewRecoveryCodes(c *gin.Context) {
+	var req codeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if a.TotpEnabled == 0 {
+		abortWithError(c, errTotpOff, "")
+		return
+	}
+	if !checkCode(c, a, req.Code) {
+		return
+	}
+
+	codes := newRecoveryCodes(a)
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+
+	c.JSON(200, gin.H{"recoveryCodes": codes})
+}
+
+// DisableTotp turns off two-factor authentication for the current account, with a two-factor
+// code or a recovery code.
+func DisableTotp(c *gin.Context) {
+	var req codeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+	if a.TotpEnabled == 0 {
+		abortWithError(c, errTotpOff, "")
+		return
+	}
+	if !checkCode(c, a, req.Code) {
+		return
+	}
+
+	a.TotpSecret, a.TotpEnabled, a.TotpStep, a.RecoveryCodes = "", 0, 0, nil
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"cardgame/storage"
+	"cardgame/util/ratelimit"
+	"cardgame/util/totp"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotp(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	useTestMailer(t)
+	codeLimits = ratelimit.NewKeyed(10, 15*time.Minute)
+	_, body := jsonRequest(t, api, "POST", "/api/me", "", `{"name":"alice","email":"alice@example.com","password":"correct horse"}`)
+	var alice ownUserResponse
+	assert.NoError(t, json.Unmarshal(body, &alice))
+
+	code, _ := jsonRequest(t, api, "POST", "/api/me/totp", alice.Token, `{"password":"wrong"}`)
+	assert.Equal(t, 403, code, "setting up two-factor authentication should need the password")
+	code, body = jsonRequest(t, api, "POST", "/api/me/totp", alice.Token, `{"password":"correct horse"}`)
+	assert.Equal(t, 200, code)
+	var setup struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &setup))
+	uri, err := url.Parse(setup.URI)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "otpauth", uri.Scheme)
+		assert.Equal(t, "totp", uri.Host)
+		assert.Equal(t, setup.Secret, uri.Query().Get("secret"))
+		assert.Equal(t, "Card Game", uri.Query().Get("issuer"))
+		assert.Equal(t, "/Card Game:alice@example.com", uri.Path)
+	}
+
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"correct horse"}`)
+	assert.Equal(t, 200, code, "codes should not be needed until setting up is confirmed")
+
+	now := time.Now()
+	current, _ := totp.Code(setup.Secret, totp.Step(now))
+	next, _ := totp.Code(setup.Secret, totp.Step(now)+1)
+	code, _ = jsonRequest(t, api, "POST", "/api/me/totp/confirm", alice.Token, `{"code":"000000x"}`)
+	assert.Equal(t, 401, code)
+	code, body = jsonRequest(t, api, "POST", "/api/me/totp/confirm", alice.Token, `{"code":"`+current+`"}`)
+	assert.Equal(t, 200, code)
+	var confirmed struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &confirmed))
+	assert.Len(t, confirmed.RecoveryCodes, recoveryCodeCount)
+
+	code, body = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"correct horse"}`)
+	assert.Equal(t, 401, code, "logging in should need a code once two-factor authentication is on")
+	assert.Contains(t, string(body), `"totp":true`)
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"correct horse","code":"`+current+`"}`)
+	assert.Equal(t, 401, code, "codes should only be accepted once")
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"wrong horse","code":"`+next+`"}`)
+	assert.Equal(t, 401, code, "a code should not stand in for the password")
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"correct horse","code":"`+next+`"}`)
+	assert.Equal(t, 200, code, "the next code should be accepted, for clocks running ahead")
+
+	recovery := `{"email":"alice@example.com","password":"correct horse","code":"` + confirmed.RecoveryCodes[0] + `"}`
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", recovery)
+	assert.Equal(t, 200, code, "recovery codes should stand in for the app's")
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", recovery)
+	assert.Equal(t, 401, code, "recovery codes should be used up")
+	code, body = jsonRequest(t, api, "GET", "/api/me", alice.Token, "")
+	assert.Equal(t, 200, code)
+	var me struct {
+		User struct {
+			Totp          bool `json:"totp"`
+			RecoveryCodes int  `json:"recoveryCodes"`
+		} `json:"user"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &me))
+	assert.True(t, me.User.Totp)
+	assert.Equal(t, recoveryCodeCount-1, me.User.RecoveryCodes)
+
+	code, body = jsonRequest(t, api, "POST", "/api/me/totp/recovery", alice.Token, `{"code":"`+confirmed.RecoveryCodes[1]+`"}`)
+	assert.Equal(t, 200, code)
+	var renewed struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &renewed))
+	assert.Len(t, renewed.RecoveryCodes, recoveryCodeCount)
+	code, _ = jsonRequest(t, api, "DELETE", "/api/me/totp", alice.Token, `{"code":"`+confirmed.RecoveryCodes[2]+`"}`)
+	assert.Equal(t, 401, code, "new recovery codes should replace the old ones")
+
+	code, _ = jsonRequest(t, api, "DELETE", "/api/me/totp", alice.Token, `{"code":"`+renewed.RecoveryCodes[0]+`"}`)
+	assert.Equal(t, 200, code)
+	code, _ = jsonRequest(t, api, "POST", "/api/login", "", `{"email":"alice@example.com","password":"correct horse"}`)
+	assert.Equal(t, 200, code, "codes should not be needed once two-factor authentication is off")
+
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+	code, _ = jsonRequest(t, api, "POST", "/api/me/totp", bob.Token, `{"password":"anything"}`)
+	assert.Equal(t, 400, code, "accounts without a password can't log in, so have nothing to protect")
+}
@@ -1,13 +1,369 @@
 package web
 
-import "github.com/gin-gonic/gin"
+import (
+	"cardgame/cosmetic"
+	"cardgame/errs"
+	"cardgame/game"
+	"cardgame/storage"
+	"cardgame/util"
+	"errors"
+	"strings"
+	"time"
 
-// TODO: persistent users
+	"github.com/gin-gonic/gin"
+)
 
-func GetUser(c *gin.Context) {}
+// NameChangeCooldown is how long an account has to wait between changing its name.
+// The name picked when creating the account doesn't count.
+var NameChangeCooldown = 7 * 24 * time.Hour
 
-func CreateUser(c *gin.Context) {}
+// RecoveryWindow is how long a deleted account can be recovered by its owner before it is
+// purged. With no window, accounts are purged right away.
+var RecoveryWindow = 14 * 24 * time.Hour
 
-func UpdateUser(c *gin.Context) {}
+var (
+	errAccountDeleted = errs.New(errs.ErrPermission, "account was deleted")
+	errNameCooldown   = errs.New(errs.ErrRateLimited, "name was changed too recently")
+)
 
-func DeleteUser(c *gin.Context) {}
+// userDetails is the body of requests creating or updating an account.
+type userDetails struct {
+	Name         *string          `json:"name"`
+	Avatar       *storage.Avatar  `json:"avatar"`
+	Bio          *string          `json:"bio"`
+	FavoriteGame *string          `json:"favoriteGame"`
+	Invites      *storage.Invites `json:"invites"`
+	CardBack     *string          `json:"cardBack"`
+	TableTheme   *string          `json:"tableTheme"`
+}
+
+// signupDetails is the body of requests creating an account, which can be signed in to with
+// an email or the name and a password, besides the token of its first session.
+type signupDetails struct {
+	userDetails
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// ownAccount is an account as shown to its owner, with what no one else sees.
+type ownAccount struct {
+	*storage.Account
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	HasPassword   bool   `json:"hasPassword"`
+	Totp          bool   `json:"totp"`          // whether two-factor authentication is on
+	RecoveryCodes int    `json:"recoveryCodes"` // how many recovery codes are left
+}
+
+// ownUser returns an account as shown to its owner.
+func ownUser(a *storage.Account) ownAccount {
+	return ownAccount{
+		Account:       a,
+		Email:         a.Email,
+		EmailVerified: a.EmailVerified != 0,
+		HasPassword:   a.PasswordHash != "",
+		Totp:          a.TotpEnabled != 0,
+		RecoveryCodes: len(a.RecoveryCodes),
+	}
+}
+
+// currentUser returns the account of the bearer token sent with the request.
+// If there is none, or it was deleted, the request is aborted and nil is returned.
+func currentUser(c *gin.Context) *storage.Account {
+	a := tokenAccount(c)
+	if a == nil {
+		return nil
+	}
+	if a.Deleted != 0 {
+		abortWithDetails(c, errAccountDeleted, "", gin.H{"recoverBefore": recoverBefore(a)})
+		return nil
+	}
+	c.Set(logKey, requestLog(c).With("account", a.Id))
+	return a
+}
+
+// tokenAccount returns the account of the bearer token sent with the request, even if it
+// was deleted. If there is none, the request is aborted and nil is returned.
+func tokenAccount(c *gin.Context) *storage.Account {
+	token := strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		abortWithError(c, errs.New(errs.ErrUnauthorized, "missing token"), "")
+		return nil
+	}
+
+	session, err := storage.Default.SessionByToken(storage.HashToken(token))
+	if errors.Is(err, errs.ErrNotFound) {
+		err = errs.New(errs.ErrUnauthorized, "invalid token")
+	}
+	if err != nil {
+		abortWithError(c, err, "failed to load session")
+		return nil
+	}
+	a, err := storage.Default.Account(session.AccountId)
+	if err != nil {
+		abortWithError(c, err, "failed to load account")
+		return nil
+	}
+	c.Set(sessionKey, session.Id)
+	return a
+}
+
+// recoverBefore returns when a deleted account will be purged, in unix ms.
+func recoverBefore(a *storage.Account) int64 {
+	return a.Deleted + RecoveryWindow.Milliseconds()
+}
+
+// applyDetails copies the set fields of a request into an account.
+func applyDetails(c *gin.Context, a *storage.Account, details userDetails) bool {
+	if details.Name != nil {
+		name, err := game.CleanName(*details.Name)
+		if err != nil {
+			abortWithError(c, err, "")
+			return false
+		}
+		if name != a.Name && !changeName(c, a, name) {
+			return false
+		}
+	}
+	if details.Avatar != nil {
+		a.Avatar = *details.Avatar
+	}
+	if details.Bio != nil {
+		bio, err := game.CleanBio(*details.Bio)
+		if err != nil {
+			abortWithError(c, err, "")
+			return false
+		}
+		a.Bio = bio
+	}
+	if details.FavoriteGame != nil {
+		if !knownGameType(*details.FavoriteGame) {
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "unknown game type"), "")
+			return false
+		}
+		a.FavoriteGame = *details.FavoriteGame
+	}
+	if details.Invites != nil {
+		switch *details.Invites {
+		case storage.InvitesFriends, storage.InvitesEveryone, storage.InvitesNobody:
+			a.Invites = *details.Invites
+		default:
+			abortWithError(c, errs.New(errs.ErrInvalidInput, "unknown invites setting"), "")
+			return false
+		}
+	}
+	if details.CardBack != nil {
+		if !selectCosmetic(c, a, cosmetic.KindCardBack, *details.CardBack) {
+			return false
+		}
+		a.CardBack = *details.CardBack
+	}
+	if details.TableTheme != nil {
+		if !selectCosmetic(c, a, cosmetic.KindTableTheme, *details.TableTheme) {
+			return false
+		}
+		a.TableTheme = *details.TableTheme
+	}
+	return true
+}
+
+// changeName renames an account, if the name isn't taken and the cooldown since the last
+// change is over. Otherwise the request is aborted and false is returned.
+func changeName(c *gin.Context, a *storage.Account, name string) bool {
+	now := time.Now()
+	renaming := a.Name != ""
+	if renaming && a.NameChanged != 0 {
+		if allowed := time.UnixMilli(a.NameChanged).Add(NameChangeCooldown); now.Before(allowed) {
+			abortWithDetails(c, errNameCooldown, "", gin.H{"allowed": allowed.UnixMilli()})
+			return false
+		}
+	}
+
+	other, err := storage.Default.AccountByName(name)
+	if err == nil && other.Id != a.Id {
+		abortWithError(c, storage.ErrNameTaken, "")
+		return false
+	}
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		abortWithError(c, err, "failed to load account")
+		return false
+	}
+
+	a.Name = name
+	if renaming {
+		a.NameChanged = now.UnixMilli()
+	}
+	return true
+}
+
+// knownGameType reports whether t is a game type the server hosts, or empty.
+func knownGameType(t string) bool {
+	if t == "" {
+		return true
+	}
+	for _, known := range game.AllGameTypes {
+		if string(known) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// saveUser saves an account, answering 409 if another one took its name or email since they
+// were checked.
+func saveUser(c *gin.Context, a *storage.Account) bool {
+	if err := storage.Default.SaveAccount(a); err != nil {
+		abortWithError(c, err, "failed to save account")
+		return false
+	}
+	return true
+}
+
+func GetUser(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
+
+// CreateUser creates an account, signed in on a first session. The token in the response is
+// only ever sent once, and has to be sent as a bearer token to access the account. Accounts
+// created with an email are mailed a link to verify it.
+func CreateUser(c *gin.Context) {
+	var details signupDetails
+	if err := c.ShouldBindJSON(&details); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	a := &storage.Account{
+		Id:      util.IdFrom("u", util.Token()),
+		Created: now,
+		Updated: now,
+	}
+	if !applyDetails(c, a, details.userDetails) {
+		return
+	}
+	if a.Name == "" {
+		abortWithError(c, errs.New(errs.ErrInvalidInput, "name is required"), "")
+		return
+	}
+	if details.Email != "" && !setEmail(c, a, details.Email) {
+		return
+	}
+	if details.Password != "" && !setPassword(c, a, details.Password) {
+		return
+	}
+	if !saveUser(c, a) {
+		return
+	}
+	token, ok := newSession(c, a.Id)
+	if !ok {
+		return
+	}
+	mailVerification(c, a)
+
+	c.JSON(200, gin.H{"user": ownUser(a), "token": token})
+}
+
+// GetProfile responds with the public profile of an account.
+func GetProfile(c *gin.Context) {
+	a, err := storage.Default.Account(c.Param("id"))
+	if err == nil && a.Deleted != 0 {
+		err = errs.ErrNotFound
+	}
+	if err != nil {
+		abortWithError(c, notFound(err, "user not found"), "failed to load account")
+		return
+	}
+
+	c.JSON(200, gin.H{"user": a})
+}
+
+func UpdateUser(c *gin.Context) {
+	var details userDetails
+	if err := c.ShouldBindJSON(&details); err != nil {
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
+		return
+	}
+	a := currentUser(c)
+	if a == nil || !applyDetails(c, a, details) {
+		return
+	}
+	a.Updated = time.Now().UnixMilli()
+	if !saveUser(c, a) {
+		return
+	}
+
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
+
+// ExportUser responds with everything stored about the current account, as a JSON file to
+// download. Chat is never stored, so there is none to export.
+func ExportUser(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	data, err := storage.Export(storage.Default, a.Id)
+	if err != nil {
+		abortWithError(c, err, "failed to export account")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="cardgame-`+a.Id+`.json"`)
+	c.JSON(200, data)
+}
+
+// DeleteUser deletes the current account. The owner has RecoveryWindow to change their mind,
+// after which everything stored about the account is deleted. Its matches stay in the history
+// of the other players, under DeletedName.
+func DeleteUser(c *gin.Context) {
+	a := currentUser(c)
+	if a == nil {
+		return
+	}
+
+	if RecoveryWindow <= 0 {
+		if err := storage.Default.DeleteAccount(a.Id); err != nil {
+			abortWithError(c, err, "failed to delete account")
+			return
+		}
+		c.JSON(200, gin.H{})
+		return
+	}
+
+	a.Deleted = time.Now().UnixMilli()
+	a.Updated = a.Deleted
+	if err := storage.Default.SaveAccount(a); err != nil {
+		abortWithError(c, err, "failed to delete account")
+		return
+	}
+
+	c.JSON(200, gin.H{"recoverBefore": recoverBefore(a)})
+}
+
+// RecoverUser undoes the deletion of the current account, if it hasn't been purged yet.
+func RecoverUser(c *gin.Context) {
+	a := tokenAccount(c)
+	if a == nil {
+		return
+	}
+	if a.Deleted == 0 {
+		abortWithError(c, errs.New(errs.ErrInvalidAction, "account was not deleted"), "")
+		return
+	}
+
+	a.Deleted = 0
+	a.Updated = time.Now().UnixMilli()
+	if err := storage.Default.SaveAccount(a); err != nil {
+		abortWithError(c, err, "failed to recover account")
+		return
+	}
+
+	c.JSON(200, gin.H{"user": ownUser(a)})
+}
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"cardgame/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type userResponse struct {
+	User  *storage.Account `json:"user"`
+	Token string           `json:"token"`
+	Error string           `json:"error"`
+	Code  string           `json:"code"`
+}
+
+func userRequest(t *testing.T, api *gin.Engine, method, token, body string) (int, userResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/api/me", strings.NewReader(body))
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+	api.ServeHTTP(w, req)
+
+	var r userResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	return w.Code, r
+}
+
+func TestUserLifecycle(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+
+	code, created := userRequest(t, api, "POST", "", `{"name":"card shark","avatar":{"eyes":1}}`)
+	assert.Equal(t, 200, code)
+	assert.NotEmpty(t, created.Token)
+	assert.Equal(t, "card shark", created.User.Name)
+
+	code, got := userRequest(t, api, "GET", created.Token, "")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, created.User.Id, got.User.Id)
+	assert.Equal(t, 1, got.User.Avatar.Eyes)
+
+	code, updated := userRequest(t, api, "PUT", created.Token, `{"name":"shark"}`)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "shark", updated.User.Name)
+	assert.Equal(t, 1, updated.User.Avatar.Eyes, "unset fields should be kept")
+
+	code, _ = userRequest(t, api, "DELETE", created.Token, "")
+	assert.Equal(t, 200, code)
+
+	code, _ = userRequest(t, api, "GET", created.Token, "")
+	assert.Equal(t, 403, code, "deleted account should be unusable")
+
+	_, err := storage.PurgeDeletedAccounts(storage.Default, -time.Minute)
+	assert.NoError(t, err)
+	code, _ = userRequest(t, api, "GET", created.Token, "")
+	assert.Equal(t, 401, code, "purged account should be gone")
+}
+
+func TestRecoverUser(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+
+	recover := func(token string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/me/recover", nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		api.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, 400, recover(alice.Token), "accounts that weren't deleted can't be recovered")
+
+	code, deleted := userRequest(t, api, "DELETE", alice.Token, "")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "", deleted.Error)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/"+alice.User.Id, nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code, "deleted accounts should have no profile")
+	code, _ = userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	assert.Equal(t, 409, code, "the name should be kept until the account is purged")
+
+	assert.Equal(t, 200, recover(alice.Token))
+	code, got := userRequest(t, api, "GET", alice.Token, "")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "alice", got.User.Name)
+
+	RecoveryWindow = 0
+	t.Cleanup(func() { RecoveryWindow = 14 * 24 * time.Hour })
+	code, _ = userRequest(t, api, "DELETE", bob.Token, "")
+	assert.Equal(t, 200, code)
+	code, _ = userRequest(t, api, "GET", bob.Token, "")
+	assert.Equal(t, 401, code, "without a window, accounts should be purged right away")
+}
+
+func TestExportUser(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+	_, alice := userRequest(t, api, "POST", "", `{"name":"alice"}`)
+	_, bob := userRequest(t, api, "POST", "", `{"name":"bob"}`)
+	assert.NoError(t, storage.Default.SaveMatch(&storage.Match{Id: "m_1", Ended: 1, Players: []storage.MatchPlayer{
+		{Id: "p_1", AccountId: alice.User.Id, Name: "alice"},
+		{Id: "p_2", AccountId: bob.User.Id, Name: "bob"},
+	}}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/me/export", nil)
+	req.Header.Add("Authorization", "Bearer "+alice.Token)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	var data storage.AccountData
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &data))
+	assert.Equal(t, "alice", data.Account.Name)
+	assert.Len(t, data.Matches, 1)
+	assert.NotContains(t, w.Body.String(), alice.Token)
+
+	code, _ := userRequest(t, api, "DELETE", alice.Token, "")
+	assert.Equal(t, 200, code)
+	_, err := storage.PurgeDeletedAccounts(storage.Default, -time.Minute)
+	assert.NoError(t, err)
+	m, err := storage.Default.Match("m_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, storage.DeletedName, m.Players[0].Name, "deleted accounts should be anonymized in other players' matches")
+		assert.Equal(t, "bob", m.Players[1].Name)
+	}
+}
+
+func TestUserErrors(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+
+	code, r := userRequest(t, api, "GET", "", "")
+	assert.Equal(t, 401, code)
+	assert.Equal(t, "unauthorized", r.Code)
+
+	code, _ = userRequest(t, api, "GET", "not a token", "")
+	assert.Equal(t, 401, code)
+
+	code, r = userRequest(t, api, "POST", "", `{}`)
+	assert.Equal(t, 400, code)
+	assert.Equal(t, "name is required", r.Error)
+	assert.Equal(t, "invalid_input", r.Code)
+}
+
+func TestUserProfile(t *testing.T) {
+	storage.Default = storage.NewMemory()
+	api := initTestApi(t)
+
+	code, created := userRequest(t, api, "POST", "", `{"name":"card shark","bio":"  shuffling since 99  ","favoriteGame":"classic"}`)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "shuffling since 99", created.User.Bio)
+	assert.Equal(t, "classic", created.User.FavoriteGame)
+
+	code, r := userRequest(t, api, "POST", "", `{"name":"Card Shark"}`)
+	assert.Equal(t, 409, code, "names should be unique ignoring case")
+	assert.Equal(t, "name is taken", r.Error)
+
+	code, _ = userRequest(t, api, "PUT", created.Token, `{"favoriteGame":"checkers"}`)
+	assert.Equal(t, 400, code)
+	code, _ = userRequest(t, api, "PUT", created.Token, `{"bio":"`+strings.Repeat("a", 301)+`"}`)
+	assert.Equal(t, 400, code)
+
+	code, _ = userRequest(t, api, "PUT", created.Token, `{"name":"Card Shark"}`)
+	assert.Equal(t, 200, code, "the first rename should not wait for the cooldown")
+	code, r = userRequest(t, api, "PUT", created.Token, `{"name":"shark"}`)
+	assert.Equal(t, 429, code, "renaming again should wait for the cooldown")
+	code, _ = userRequest(t, api, "PUT", created.Token, `{"name":"Card Shark","bio":"same name"}`)
+	assert.Equal(t, 200, code, "keeping the name should not count as a change")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/"+created.User.Id, nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	assert.Equal(t, "same name", r.User.Bio)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/user/u_missing", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
@@ -1,7 +1,9 @@
 package web
 
 import (
+	"cardgame/errs"
 	"cardgame/game"
+	"cardgame/locale"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -19,15 +21,22 @@ var upgrader = websocket.Upgrader{
 }
 
 func upgrade(c *gin.Context) (*websocket.Conn, error) {
-	return upgrader.Upgrade(c.Writer, c.Request, nil)
+	u := upgrader
+	u.Subprotocols = game.Subprotocols()
+	return u.Upgrade(c.Writer, c.Request, nil)
 }
 
 func ServeWS(c *gin.Context) {
 	conn, err := upgrade(c)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, errs.Wrap(errs.ErrInvalidInput, err), "")
 		return
 	}
 
-	game.NewPlayer(conn)
+	// browsers send their language with the upgrade, apps can pick one with ?locale=
+	language := c.Query("locale")
+	if language == "" {
+		language = c.GetHeader("Accept-Language")
+	}
+	game.NewPlayer(conn, c.Request.UserAgent(), locale.Match(language))
 }